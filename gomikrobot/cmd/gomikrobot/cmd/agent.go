@@ -3,44 +3,224 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/audit"
 	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/calendar"
 	"github.com/kamir/gomikrobot/internal/config"
 	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/tools"
+	"github.com/kamir/gomikrobot/internal/tracing"
 	"github.com/spf13/cobra"
 )
 
 var (
 	agentMessage   string
 	agentSessionID string
+	agentFiles     []string
+	agentOutput    string
 )
 
 var agentCmd = &cobra.Command{
 	Use:   "agent",
 	Short: "Chat with the agent directly in CLI",
-	Run:   runAgent,
+	Long: "Send one message to the agent and print the response. Input piped to stdin is attached as " +
+		"context (or is the message when --message is not given), and --file attaches files, so the agent " +
+		"works in shell pipelines:\n\n  cat notes.md | gomikrobot agent -m \"summarize this\" -o summary.md\n\n" +
+		"Status messages go to stderr; only the response goes to stdout or --output. With --json the " +
+		"response comes with the model, tokens, tool calls, and duration.",
+	Run: runAgent,
 }
 
 func init() {
 	agentCmd.Flags().StringVarP(&agentMessage, "message", "m", "", "Message to send to the agent")
 	agentCmd.Flags().StringVarP(&agentSessionID, "session", "s", "cli:default", "Session ID")
+	agentCmd.Flags().StringArrayVarP(&agentFiles, "file", "f", nil, "File to attach as context (repeatable)")
+	agentCmd.Flags().StringVarP(&agentOutput, "output", "o", "", "Write the response to this file instead of stdout")
+	addJSONFlag(agentCmd)
+}
+
+// agentResult is the --json output.
+type agentResult struct {
+	Response         string          `json:"response"`
+	Error            string          `json:"error,omitempty"`
+	Session          string          `json:"session"`
+	Model            string          `json:"model"`
+	PromptTokens     int             `json:"prompt_tokens"`
+	CompletionTokens int             `json:"completion_tokens"`
+	ToolCalls        []agentToolCall `json:"tool_calls"`
+	DurationMS       int64           `json:"duration_ms"`
+	Refused          bool            `json:"refused,omitempty"`
+}
+
+type agentToolCall struct {
+	Name  string `json:"name"`
+	Error bool   `json:"error,omitempty"`
+}
+
+// agentAttachment is text given to the agent along with the message.
+type agentAttachment struct {
+	name    string
+	content string
 }
 
 func runAgent(cmd *cobra.Command, args []string) {
-	if agentMessage == "" {
-		fmt.Println("Error: --message is required")
+	// Status and tool messages go to stderr, so stdout carries only the
+	// response.
+	out := os.Stdout
+	os.Stdout = os.Stderr
+
+	var attachments []agentAttachment
+	if piped, err := readPipedStdin(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+		os.Exit(1)
+	} else if piped != "" {
+		if agentMessage == "" {
+			agentMessage = piped
+		} else {
+			attachments = append(attachments, agentAttachment{name: "stdin", content: piped})
+		}
+	}
+	for _, path := range agentFiles {
+		a, err := readAttachment(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		attachments = append(attachments, a)
+	}
+	if strings.TrimSpace(agentMessage) == "" {
+		fmt.Fprintln(os.Stderr, "Error: --message is required (or pipe the message to stdin)")
 		os.Exit(1)
 	}
 
 	// Load Config
 	cfg, err := config.Load()
 	if err != nil {
-		fmt.Printf("Config warning: %v (using defaults)\n", err)
+		fmt.Fprintf(os.Stderr, "Config warning: %v (using defaults)\n", err)
+	}
+
+	// Check API Key
+	if cfg.Providers.OpenAI.APIKey == "" {
+		fmt.Fprintln(os.Stderr, "Error: API key not found. Set MIKROBOT_OPENAI_API_KEY, OPENROUTER_API_KEY, or use config.json")
+		os.Exit(1)
+	}
+
+	fmt.Fprintf(os.Stderr, "🤖 GoMikroBot (%s)\n", cfg.Agents.Defaults.Model)
+	fmt.Fprintln(os.Stderr, "Thinking...")
+
+	ctx := context.Background()
+	loop, cleanup := newCLILoop(ctx, cfg)
+	start := time.Now()
+	response, report, err := loop.ProcessDirectReport(ctx, agentPrompt(agentMessage, attachments), agentSessionID)
+	cleanup()
+
+	w := io.Writer(out)
+	if agentOutput != "" {
+		f, ferr := os.Create(agentOutput)
+		if ferr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", agentOutput, ferr)
+			os.Exit(1)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if jsonOutput {
+		res := agentResult{
+			Response:         response,
+			Session:          agentSessionID,
+			Model:            report.Model,
+			PromptTokens:     report.Usage.PromptTokens,
+			CompletionTokens: report.Usage.CompletionTokens,
+			ToolCalls:        []agentToolCall{},
+			DurationMS:       time.Since(start).Milliseconds(),
+			Refused:          report.Refused,
+		}
+		if res.Model == "" {
+			res.Model = cfg.Agents.Defaults.Model
+		}
+		for _, tc := range report.ToolCalls {
+			res.ToolCalls = append(res.ToolCalls, agentToolCall{Name: tc.Name, Error: tc.Error})
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		if werr := writeJSON(w, res); werr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing result: %v\n", werr)
+			os.Exit(1)
+		}
+		if err != nil {
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Setup components
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := fmt.Fprintln(w, response); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", agentOutput, err)
+		os.Exit(1)
+	}
+	if agentOutput != "" {
+		fmt.Fprintf(os.Stderr, "✅ Response written to %s\n", agentOutput)
+	}
+}
+
+// readPipedStdin returns stdin's content when it is a pipe or file rather
+// than a terminal.
+func readPipedStdin() (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return "", nil
+	}
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("input is not text")
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// readAttachment reads a text file to attach to the message.
+func readAttachment(path string) (agentAttachment, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return agentAttachment{}, err
+	}
+	if !utf8.Valid(data) {
+		return agentAttachment{}, fmt.Errorf("%s is not a text file", path)
+	}
+	return agentAttachment{name: path, content: strings.TrimSpace(string(data))}, nil
+}
+
+// agentPrompt appends the attachments to message, each wrapped in a
+// <file> element naming its source.
+func agentPrompt(message string, attachments []agentAttachment) string {
+	if len(attachments) == 0 {
+		return message
+	}
+	var b strings.Builder
+	b.WriteString(message)
+	for _, a := range attachments {
+		fmt.Fprintf(&b, "\n\n<file name=%q>\n%s\n</file>", a.name, a.content)
+	}
+	return b.String()
+}
+
+// newCLILoop returns an agent loop for commands that run without channels,
+// with the configured tools. Call cleanup when done.
+func newCLILoop(ctx context.Context, cfg *config.Config) (loop *agent.Loop, cleanup func()) {
 	msgBus := bus.NewMessageBus()
 	oaProv := provider.NewOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI.APIBase, cfg.Agents.Defaults.Model)
 	var prov provider.LLMProvider = oaProv
@@ -49,29 +229,207 @@ func runAgent(cmd *cobra.Command, args []string) {
 		prov = provider.NewLocalWhisperProvider(cfg.Providers.LocalWhisper, oaProv)
 	}
 
-	// Check API Key
-	if cfg.Providers.OpenAI.APIKey == "" {
-		fmt.Println("Error: API key not found. Set MIKROBOT_OPENAI_API_KEY, OPENROUTER_API_KEY, or use config.json")
-		os.Exit(1)
-	}
-
-	loop := agent.NewLoop(agent.LoopOptions{
+	auditLog := openAuditLog(cfg.Audit)
+	stopTracing := startTracing(cfg.Tracing)
+	loop = agent.NewLoop(agent.LoopOptions{
 		Bus:           msgBus,
 		Provider:      prov,
 		Workspace:     cfg.Agents.Defaults.Workspace,
 		Model:         cfg.Agents.Defaults.Model,
 		MaxIterations: cfg.Agents.Defaults.MaxToolIterations,
+
+		MaxParallelTools:   cfg.Agents.Defaults.MaxParallelTools,
+		ToolTimeout:        cfg.Agents.Defaults.ToolTimeout,
+		MaxToolResultChars: cfg.Agents.Defaults.MaxToolResultChars,
+		MaxToolCalls:       cfg.Agents.Defaults.MaxToolCalls,
+		TurnTimeout:        cfg.Agents.Defaults.TurnTimeout,
+		HistoryMessages:    cfg.Agents.Defaults.HistoryMessages,
+		HistoryTokens:      cfg.Agents.Defaults.HistoryTokens,
+		ContextLength:      cfg.Agents.Defaults.ContextLength,
+		Prompt: agent.PromptOptions{
+			TemplateFile:     cfg.Agents.Defaults.Prompt.TemplateFile,
+			DisabledSections: cfg.Agents.Defaults.Prompt.DisabledSections,
+		},
+		Projects: projectsFromConfig(cfg.Agents.Projects),
+		Admins:   cfg.Agents.Admins,
+		Audit:    auditLog,
+		Sampling: samplingFromConfig(cfg.Agents.Defaults),
+		Critic:   agent.CriticOptions(cfg.Agents.Defaults.Critic),
+		Refusals: agent.RefusalOptions(cfg.Agents.Defaults.Refusals),
 	})
 
-	fmt.Printf("🤖 GoMikroBot (%s)\n", cfg.Agents.Defaults.Model)
-	fmt.Println("Thinking...")
+	loop.RegisterTool(execToolFromConfig(cfg.Tools.Exec, cfg.Agents.Defaults.Workspace, nil))
+	if t := codeToolFromConfig(cfg.Tools); t != nil {
+		loop.RegisterTool(t)
+	}
+	for _, t := range calendarTools(cfg.Tools.Calendar) {
+		loop.RegisterTool(t)
+	}
+	for _, t := range desktopTools(cfg.Tools.Desktop) {
+		loop.RegisterTool(t)
+	}
+	for _, t := range knowledgeTools(cfg) {
+		loop.RegisterTool(t)
+	}
+	registerHTTPTool(loop, cfg.Tools.HTTP)
+	registerRemoteTools(ctx, loop, cfg.Tools.Remote)
+	stopPlugins := registerPlugins(ctx, loop, cfg.Tools.Plugins)
+
+	return loop, func() {
+		stopPlugins()
+		stopTracing()
+		if auditLog != nil {
+			auditLog.Close()
+		}
+	}
+}
 
-	ctx := context.Background()
-	response, err := loop.ProcessDirect(ctx, agentMessage, agentSessionID)
+// samplingFromConfig returns the default generation parameters.
+func samplingFromConfig(d config.AgentDefaults) tools.Sampling {
+	temperature := d.Temperature
+	return tools.Sampling{
+		MaxTokens:       d.MaxTokens,
+		Temperature:     &temperature,
+		TopP:            d.TopP,
+		ReasoningEffort: d.ReasoningEffort,
+	}
+}
+
+// execToolFromConfig returns the exec tool with the configured shell,
+// timeout, and environment policy. jobs enables background commands.
+func execToolFromConfig(cfg config.ExecToolConfig, workspace string, jobs *tools.JobManager) *tools.ExecTool {
+	opts := tools.ExecOptions{
+		Timeout:             cfg.Timeout,
+		RestrictToWorkspace: cfg.RestrictToWorkspace,
+		WorkDir:             workspace,
+		Shell:               cfg.Shell,
+		EnvAllow:            cfg.EnvAllow,
+		EnvDeny:             cfg.EnvDeny,
+		Jobs:                jobs,
+	}
+	opts.Sandbox = sandboxFromConfig(cfg.Sandbox)
+	return tools.NewExecToolWithOptions(opts)
+}
+
+// sandboxFromConfig returns exec's container sandbox, or nil if disabled.
+func sandboxFromConfig(sb config.ExecSandboxConfig) *tools.SandboxOptions {
+	if !sb.Enabled {
+		return nil
+	}
+	return &tools.SandboxOptions{
+		Runtime:  sb.Runtime,
+		Image:    sb.Image,
+		Network:  sb.Network,
+		Memory:   sb.Memory,
+		CPUs:     sb.CPUs,
+		Sessions: sb.Sessions,
+	}
+}
+
+// codeToolFromConfig returns run_code with the configured interpreters
+// and limits, or nil if it is disabled. Sessions that exec runs in its
+// sandbox may not use it.
+func codeToolFromConfig(cfg config.ToolsConfig) tools.Tool {
+	if !cfg.Code.Enabled {
+		return nil
+	}
+	return tools.NewRunCodeTool(tools.RunCodeOptions{
+		Timeout:  cfg.Code.Timeout,
+		Python:   cfg.Code.Python,
+		Node:     cfg.Code.Node,
+		MemoryMB: cfg.Code.MemoryMB,
+		Sandbox:  sandboxFromConfig(cfg.Exec.Sandbox),
+	})
+}
+
+// calendarTools returns the calendar tools if a calendar is configured.
+// Google is used when there is a refresh token and no CalDAV URL.
+func calendarTools(cfg config.CalendarToolConfig) []tools.Tool {
+	client := &calendar.Client{URL: cfg.URL, Username: cfg.Username, Password: cfg.Password}
+	if g := cfg.Google; cfg.URL == "" {
+		if g.RefreshToken == "" {
+			return nil
+		}
+		client.URL = calendar.GoogleCalDAVURL(g.CalendarID)
+		client.Token = calendar.GoogleToken(g.ClientID, g.ClientSecret, g.RefreshToken)
+	}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			fmt.Printf("⚠️ Calendar timezone %q: %v; using the system timezone\n", cfg.Timezone, err)
+		} else {
+			client.Location = loc
+		}
+	}
+	return tools.CalendarTools(client, client.Location)
+}
+
+// desktopTools returns the local desktop tools if they are enabled.
+func desktopTools(cfg config.DesktopToolConfig) []tools.Tool {
+	if !cfg.Enabled {
+		return nil
+	}
+	return tools.DesktopTools()
+}
+
+// registerHTTPTool enables http_request when domains are allowlisted.
+func registerHTTPTool(loop *agent.Loop, cfg config.HTTPToolConfig) {
+	if len(cfg.AllowedDomains) == 0 {
+		return
+	}
+	loop.RegisterTool(tools.NewHTTPRequestTool(tools.HTTPRequestOptions{
+		AllowedDomains:   cfg.AllowedDomains,
+		MaxResponseBytes: cfg.MaxResponseBytes,
+		Timeout:          cfg.Timeout,
+		Headers:          cfg.Headers,
+	}))
+}
+
+// openAuditLog opens the LLM audit log if enabled. Failures are reported
+// and leave auditing off rather than stopping the bot.
+func openAuditLog(cfg config.AuditConfig) *audit.Log {
+	if !cfg.Enabled {
+		return nil
+	}
+	log, err := audit.Open(cfg.Dir, int64(cfg.MaxFileMB)<<20, time.Duration(cfg.RetentionDays)*24*time.Hour)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		fmt.Printf("⚠️ Audit log disabled: %v\n", err)
+		return nil
 	}
+	fmt.Printf("📼 Auditing LLM calls to %s\n", log.Dir())
+	return log
+}
 
-	fmt.Println("\n" + response)
+// startTracing exports spans to the configured OTLP collector. The returned
+// function flushes the pending spans.
+func startTracing(cfg config.TracingConfig) func() {
+	shutdown, err := tracing.Setup(tracing.Config{
+		Endpoint:    cfg.Endpoint,
+		ServiceName: cfg.ServiceName,
+		Headers:     cfg.Headers,
+		SampleRatio: cfg.SampleRatio,
+	})
+	if err != nil {
+		fmt.Printf("⚠️ Tracing disabled: %v\n", err)
+		return func() {}
+	}
+	if cfg.Endpoint != "" {
+		fmt.Printf("🔭 Exporting traces to %s\n", cfg.Endpoint)
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			fmt.Printf("⚠️ Flushing traces: %v\n", err)
+		}
+	}
+}
+
+// projectsFromConfig converts configured projects for the agent loop.
+func projectsFromConfig(projects []config.ProjectConfig) []tools.Project {
+	out := make([]tools.Project, 0, len(projects))
+	for _, p := range projects {
+		out = append(out, tools.Project{Name: p.Name, Path: p.Path, Description: p.Description})
+	}
+	return out
 }
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/backup"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupOutput  string
+	backupEncrypt bool
+	backupForce   bool
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up and restore the config, timeline, sessions, and memory",
+	Long: "A backup is a single tar.zst archive of the config file, the timeline database, the sessions, " +
+		"and the workspace memory. Encrypted backups (.tar.zst.age) use the passphrase in " +
+		backup.PassphraseEnv + " or ask for one, and can also be opened with the age tool.",
+}
+
+var backupCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Write a backup archive",
+	Long:  "Write a backup to --output, or to backup.dir named after the current time. The gateway may keep running.",
+	Args:  cobra.NoArgs,
+	Run:   runBackupCreate,
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Restore a backup archive",
+	Long: "Restore the config, timeline, sessions, and memory from a backup. Stop the gateway first. " +
+		"Existing state is only replaced with --force; files not in the backup are kept.",
+	Args: cobra.ExactArgs(1),
+	Run:  runBackupRestore,
+}
+
+func init() {
+	backupCreateCmd.Flags().StringVarP(&backupOutput, "output", "o", "", "Archive to write (default: <backup.dir>/gomikrobot-<time>.tar.zst)")
+	backupCreateCmd.Flags().BoolVar(&backupEncrypt, "encrypt", false, "Encrypt the archive with a passphrase (default: backup.encrypt)")
+	backupRestoreCmd.Flags().BoolVar(&backupForce, "force", false, "Replace the existing config, timeline, sessions, and memory")
+	addJSONFlag(backupCreateCmd)
+	addJSONFlag(backupRestoreCmd)
+	backupCmd.AddCommand(backupCreateCmd, backupRestoreCmd)
+	rootCmd.AddCommand(backupCmd)
+}
+
+// backupPaths locates the state of the main bot. A Postgres timeline is
+// left to the database's own backups.
+func backupPaths(cfg *config.Config) backup.Paths {
+	home, _ := os.UserHomeDir()
+	configPath, _ := config.ConfigPath()
+	p := backup.Paths{
+		Config:   configPath,
+		Sessions: filepath.Join(home, config.ConfigDir, "sessions"),
+		Memory:   filepath.Join(cfg.Agents.Defaults.Workspace, "memory"),
+	}
+	if cfg.Timeline.Driver == "" || cfg.Timeline.Driver == "sqlite" {
+		p.Timeline = cfg.Timeline.Path
+	}
+	return p
+}
+
+// backupPassphrase returns the passphrase from the environment or asks for
+// it, twice on a terminal when confirm is set.
+func backupPassphrase(confirm bool) (string, error) {
+	if pass := os.Getenv(backup.PassphraseEnv); pass != "" {
+		return pass, nil
+	}
+	pass, err := readSecret("Backup passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", errors.New("the passphrase is empty")
+	}
+	if fi, err := os.Stdin.Stat(); confirm && err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+		again, err := readSecret("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != pass {
+			return "", errors.New("the passphrases do not match")
+		}
+	}
+	return pass, nil
+}
+
+func runBackupCreate(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	encrypt := cfg.Backup.Encrypt
+	if cmd.Flags().Changed("encrypt") {
+		encrypt = backupEncrypt
+	}
+	var pass string
+	if encrypt {
+		if pass, err = backupPassphrase(true); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	dst := backupOutput
+	if dst == "" {
+		dst = filepath.Join(cfg.Backup.Dir, backup.Name(time.Now(), encrypt))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	m, err := backup.Create(ctx, dst, backupPaths(cfg), pass, version)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOutput {
+		_ = writeJSON(os.Stdout, map[string]any{"archive": dst, "manifest": m})
+		return
+	}
+	size := ""
+	if info, err := os.Stat(dst); err == nil {
+		size = fmt.Sprintf(", %.1f MB", float64(info.Size())/(1<<20))
+	}
+	fmt.Printf("✅ Backed up %s (%d files%s) to %s\n", strings.Join(m.Items, ", "), m.Files, size, dst)
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Config warning: %v (using defaults)\n", err)
+		cfg = config.DefaultConfig()
+		cfg.Agents.Defaults.Workspace = expandHome(cfg.Agents.Defaults.Workspace)
+		cfg.Timeline.Path = expandHome(cfg.Timeline.Path)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	paths := backupPaths(cfg)
+	pass := os.Getenv(backup.PassphraseEnv)
+	m, err := backup.Restore(ctx, args[0], paths, pass, backupForce)
+	if errors.Is(err, backup.ErrPassphrase) {
+		if pass, err = backupPassphrase(false); err == nil {
+			m, err = backup.Restore(ctx, args[0], paths, pass, backupForce)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOutput {
+		_ = writeJSON(os.Stdout, m)
+		return
+	}
+	fmt.Printf("✅ Restored %s (%d files) from the backup of %s\n", strings.Join(m.Items, ", "), m.Files, m.Created.Local().Format("2006-01-02 15:04"))
+}
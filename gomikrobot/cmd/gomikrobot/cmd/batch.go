@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/spf13/cobra"
+)
+
+var (
+	batchInput       string
+	batchOutput      string
+	batchConcurrency int
+	batchRPM         int
+	batchRetries     int
+	batchResume      bool
+)
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Run the agent on a JSONL file of prompts",
+	Long: "Run the agent on each line of --input, a JSONL file of {\"id\", \"prompt\", \"session\", \"model\"} " +
+		"objects (only prompt is required), and write one JSON result per line with the response or error " +
+		"and the tokens used. Each prompt gets a fresh session unless it names one. Rate-limited requests " +
+		"are retried with backoff. With --resume, prompts that already succeeded in --output are skipped.",
+	Run: runBatch,
+}
+
+func init() {
+	batchCmd.Flags().StringVarP(&batchInput, "input", "i", "", "JSONL file of prompts (- for stdin)")
+	batchCmd.Flags().StringVarP(&batchOutput, "output", "o", "-", "JSONL file for the results (- for stdout)")
+	batchCmd.Flags().IntVarP(&batchConcurrency, "concurrency", "c", 4, "Prompts run at the same time")
+	batchCmd.Flags().IntVar(&batchRPM, "rpm", 0, "Maximum prompts started per minute (0 = no limit)")
+	batchCmd.Flags().IntVar(&batchRetries, "retries", 5, "Retries of a rate-limited prompt")
+	batchCmd.Flags().BoolVar(&batchResume, "resume", false, "Append to --output and skip prompts that already succeeded there")
+	rootCmd.AddCommand(batchCmd)
+}
+
+// batchItem is one line of the input.
+type batchItem struct {
+	ID      string `json:"id"`
+	Prompt  string `json:"prompt"`
+	Session string `json:"session,omitempty"`
+	Model   string `json:"model,omitempty"`
+}
+
+// batchResult is one line of the output.
+type batchResult struct {
+	ID               string `json:"id"`
+	Response         string `json:"response,omitempty"`
+	Error            string `json:"error,omitempty"`
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	DurationMS       int64  `json:"duration_ms"`
+}
+
+func runBatch(cmd *cobra.Command, args []string) {
+	if batchInput == "" {
+		fmt.Fprintln(os.Stderr, "Error: --input is required")
+		os.Exit(1)
+	}
+	if batchConcurrency < 1 {
+		batchConcurrency = 1
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Config warning: %v (using defaults)\n", err)
+	}
+	if cfg.Providers.OpenAI.APIKey == "" {
+		fmt.Fprintln(os.Stderr, "Error: API key not found. Set MIKROBOT_OPENAI_API_KEY, OPENROUTER_API_KEY, or use config.json")
+		os.Exit(1)
+	}
+
+	items, err := readBatchItems(batchInput)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", batchInput, err)
+		os.Exit(1)
+	}
+	if batchResume && batchOutput != "-" {
+		done, err := batchSucceeded(batchOutput)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", batchOutput, err)
+			os.Exit(1)
+		}
+		todo := items[:0]
+		for _, it := range items {
+			if !done[it.ID] {
+				todo = append(todo, it)
+			}
+		}
+		fmt.Fprintf(os.Stderr, "⏭️ Skipping %d prompts that already succeeded\n", len(items)-len(todo))
+		items = todo
+	}
+
+	out := io.Writer(os.Stdout)
+	// Setup and tool messages are printed to stdout; keep them out of the
+	// results.
+	os.Stdout = os.Stderr
+	if batchOutput != "-" {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		if batchResume {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		}
+		f, err := os.OpenFile(batchOutput, flags, 0600)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening %s: %v\n", batchOutput, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	loop, cleanup := newCLILoop(ctx, cfg)
+
+	fmt.Fprintf(os.Stderr, "🤖 Running %d prompts with %s (concurrency %d)\n", len(items), cfg.Agents.Defaults.Model, batchConcurrency)
+	started := time.Now()
+	runID := strconv.FormatInt(started.Unix(), 36)
+	var (
+		mu     sync.Mutex
+		enc    = json.NewEncoder(out)
+		totals batchResult
+		failed int
+	)
+	queue := make(chan batchItem)
+	var wg sync.WaitGroup
+	for range batchConcurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range queue {
+				res := runBatchItem(ctx, loop, cfg.Agents.Defaults.Model, runID, it)
+				mu.Lock()
+				if err := enc.Encode(res); err != nil {
+					fmt.Fprintf(os.Stderr, "Error writing result %s: %v\n", res.ID, err)
+				}
+				totals.PromptTokens += res.PromptTokens
+				totals.CompletionTokens += res.CompletionTokens
+				if res.Error != "" {
+					failed++
+					fmt.Fprintf(os.Stderr, "❌ %s: %s\n", res.ID, res.Error)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	limit := batchLimiter(ctx, batchRPM)
+feed:
+	for _, it := range items {
+		if limit != nil {
+			select {
+			case <-limit:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+		select {
+		case queue <- it:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	cleanup()
+
+	fmt.Fprintf(os.Stderr, "✅ %d prompts, %d failed, %d prompt + %d completion tokens in %s\n",
+		len(items), failed, totals.PromptTokens, totals.CompletionTokens, time.Since(started).Round(time.Second))
+	if ctx.Err() != nil || failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runBatchItem runs one prompt, retrying with backoff while the provider
+// reports a rate limit. Prompts without a session get one that is deleted
+// afterwards.
+func runBatchItem(ctx context.Context, loop *agent.Loop, defaultModel, runID string, it batchItem) batchResult {
+	res := batchResult{ID: it.ID, Model: defaultModel}
+	sessionKey := it.Session
+	if sessionKey == "" {
+		sessionKey = "batch:" + runID + "-" + it.ID
+		defer loop.Sessions().Delete(sessionKey)
+	}
+	if it.Model != "" {
+		ctx = agent.WithModel(ctx, it.Model)
+		res.Model = it.Model
+	}
+
+	start := time.Now()
+	backoff := 2 * time.Second
+	for attempt := 0; ; attempt++ {
+		response, usage, err := loop.ProcessDirectUsage(ctx, it.Prompt, sessionKey)
+		res.PromptTokens += usage.PromptTokens
+		res.CompletionTokens += usage.CompletionTokens
+		if err == nil {
+			res.Response, res.Error = response, ""
+			break
+		}
+		res.Error = err.Error()
+		if !provider.IsRateLimitError(err) || attempt >= batchRetries {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			res.Error = ctx.Err().Error()
+			res.DurationMS = time.Since(start).Milliseconds()
+			return res
+		}
+		backoff = min(backoff*2, time.Minute)
+	}
+	res.DurationMS = time.Since(start).Milliseconds()
+	return res
+}
+
+// batchLimiter returns a channel that allows rpm receives per minute, or
+// nil for no limit.
+func batchLimiter(ctx context.Context, rpm int) <-chan struct{} {
+	if rpm <= 0 {
+		return nil
+	}
+	ch := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Minute / time.Duration(rpm))
+		defer ticker.Stop()
+		for {
+			select {
+			case ch <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// readBatchItems reads the prompts in path. Blank lines are skipped and
+// items without an id are numbered by line.
+func readBatchItems(path string) ([]batchItem, error) {
+	r := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var items []batchItem
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var it batchItem
+		if err := json.Unmarshal(sc.Bytes(), &it); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if it.Prompt == "" {
+			return nil, fmt.Errorf("line %d: prompt is required", line)
+		}
+		if it.ID == "" {
+			it.ID = strconv.Itoa(line)
+		}
+		if seen[it.ID] {
+			return nil, fmt.Errorf("line %d: duplicate id %q", line, it.ID)
+		}
+		seen[it.ID] = true
+		items = append(items, it)
+	}
+	return items, sc.Err()
+}
+
+// batchSucceeded returns the ids with a result without error in path.
+func batchSucceeded(path string) (map[string]bool, error) {
+	done := make(map[string]bool)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	for sc.Scan() {
+		var res batchResult
+		if json.Unmarshal(sc.Bytes(), &res) == nil && res.Error == "" {
+			done[res.ID] = true
+		}
+	}
+	return done, sc.Err()
+}
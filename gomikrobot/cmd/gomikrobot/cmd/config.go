@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Read and change settings in the config file",
+	Long: "Keys are dotted JSON paths such as gateway.port or channels.webhooks.0.name. " +
+		"A running gateway picks up changes automatically where it can.",
+}
+
+var configGetFile bool
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print a setting (including environment overrides)",
+	Args:  cobra.ExactArgs(1),
+	Run:   runConfigGet,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting and save the config file",
+	Long: "The value is converted to the setting's type: true/false, numbers, durations like 30s or 5m, " +
+		"comma-separated lists, or JSON for lists of objects, maps and whole sections. " +
+		"Put -- before values that start with a dash.",
+	Example: "  gomikrobot config set gateway.port 9000\n" +
+		"  gomikrobot config set channels.telegram.enabled true\n" +
+		"  gomikrobot config set channels.slack.allowFrom U123,U456",
+	Args: cobra.ExactArgs(2),
+	Run:  runConfigSet,
+}
+
+var configPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Print the location of the config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := config.ConfigPath()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(path)
+	},
+}
+
+func init() {
+	configGetCmd.Flags().BoolVar(&configGetFile, "file", false, "Ignore environment overrides and show the file value")
+	configCmd.AddCommand(configGetCmd, configSetCmd, configPathCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) {
+	load := config.Load
+	if configGetFile {
+		load = config.LoadFile
+	}
+	cfg, err := load()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	value, err := config.GetKey(cfg, args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(value)
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) {
+	// Edit the file's own values so environment overrides and secrets are
+	// not saved.
+	cfg, err := config.LoadForEdit()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	before := config.Validate(cfg)
+	if err := config.SetKey(cfg, args[0], args[1]); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Only block on problems this change introduces; others may be fixed
+	// by environment variables.
+	known := make(map[string]bool, len(before))
+	for _, i := range before {
+		known[i.String()] = true
+	}
+	var blocked bool
+	for _, i := range config.Validate(cfg) {
+		if known[i.String()] {
+			continue
+		}
+		icon := "⚠️"
+		if i.Level == config.LevelError {
+			icon, blocked = "❌", true
+		}
+		fmt.Printf("%s %s\n", icon, i)
+		if i.Fix != "" {
+			fmt.Printf("   %s\n", i.Fix)
+		}
+	}
+	if blocked {
+		fmt.Println("Not saved.")
+		os.Exit(1)
+	}
+
+	if err := config.Save(cfg); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	value, _ := config.GetKey(cfg, args[0])
+	fmt.Printf("✅ %s = %s\n", args[0], value)
+}
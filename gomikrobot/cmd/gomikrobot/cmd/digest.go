@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/digest"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/spf13/cobra"
+)
+
+var digestSend bool
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Preview the weekly activity digest",
+	Long:  "Print the digest for the last seven days. With --send, deliver it by email now (chat delivery requires a running gateway).",
+	Run:   runDigest,
+}
+
+func init() {
+	digestCmd.Flags().BoolVar(&digestSend, "send", false, "Send the digest by email instead of printing it")
+	rootCmd.AddCommand(digestCmd)
+}
+
+func runDigest(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	timeSvc, err := timeline.Open(timelineStorage(cfg))
+	if err != nil {
+		fmt.Printf("Failed to open timeline: %v\n", err)
+		os.Exit(1)
+	}
+	defer timeSvc.Close()
+
+	if !digestSend {
+		r, err := digest.Build(timeSvc, cfg.Agents.Defaults.Workspace, time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Print(digest.Render(r, cfg.Digest.Sections))
+		return
+	}
+
+	if cfg.Digest.Channel != "email" {
+		fmt.Println("Error: --send only supports email; chat digests are sent by the gateway.")
+		os.Exit(1)
+	}
+	sched := &digest.Scheduler{Config: cfg.Digest, Timeline: timeSvc, Workspace: cfg.Agents.Defaults.Workspace}
+	if err := sched.SendNow(context.Background(), time.Now()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("📧 Digest sent to %s\n", cfg.Digest.Email.To)
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/channels"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/security"
+	"github.com/kamir/gomikrobot/internal/transcribe"
+	"github.com/spf13/cobra"
+)
+
+var doctorSkipNetwork bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Validate configuration and check the environment",
+	Run:   runDoctor,
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorSkipNetwork, "offline", false, "Skip provider and bridge connectivity checks")
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// doctorReport collects check results and prints them as they arrive.
+type doctorReport struct {
+	errors   int
+	warnings int
+}
+
+func (r *doctorReport) ok(name, detail string) {
+	fmt.Printf("%s %s: %s\n", color.GreenString("✓"), name, detail)
+}
+
+func (r *doctorReport) warn(name, detail, fix string) {
+	r.warnings++
+	fmt.Printf("%s %s: %s\n", color.YellowString("⚠"), name, detail)
+	if fix != "" {
+		fmt.Printf("    → %s\n", fix)
+	}
+}
+
+func (r *doctorReport) fail(name, detail, fix string) {
+	r.errors++
+	fmt.Printf("%s %s: %s\n", color.RedString("✗"), name, detail)
+	if fix != "" {
+		fmt.Printf("    → %s\n", fix)
+	}
+}
+
+func (r *doctorReport) issue(i config.Issue) {
+	if i.Level == config.LevelError {
+		r.fail(i.Field, i.Message, i.Fix)
+	} else {
+		r.warn(i.Field, i.Message, i.Fix)
+	}
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	fmt.Println("🩺 GoMikroBot Doctor")
+	fmt.Println("─────────────────────")
+
+	report := &doctorReport{}
+
+	// 1. Config file
+	path, _ := config.ConfigPath()
+	if _, err := os.Stat(path); err != nil {
+		report.warn("config file", "not found at "+path, "Run 'gomikrobot onboard' to create one (defaults and env vars are used meanwhile).")
+	} else {
+		issues, err := config.ValidateFile(path)
+		if err != nil {
+			report.fail("config file", err.Error(), "Check file permissions on "+path+".")
+		} else if len(issues) == 0 {
+			report.ok("config file", path)
+		}
+		for _, i := range issues {
+			report.issue(i)
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		report.fail("config", err.Error(), "Fix the config file and run doctor again.")
+		finishDoctor(report)
+		return
+	}
+
+	// 2. Schema and value checks
+	issues := config.Validate(cfg)
+	for _, i := range issues {
+		report.issue(i)
+	}
+	if len(issues) == 0 {
+		report.ok("config values", "all checks passed")
+	}
+
+	// 3. Workspace
+	checkWorkspace(report, cfg.Agents.Defaults.Workspace)
+
+	// 4. Local whisper
+	if transcribe.Backend(cfg) == "local" {
+		if p, err := exec.LookPath(cfg.Providers.LocalWhisper.BinaryPath); err != nil {
+			report.fail("whisper", fmt.Sprintf("binary not found: %s", cfg.Providers.LocalWhisper.BinaryPath),
+				"Install openai-whisper or whisper.cpp, or set transcription.backend to openai or groq.")
+		} else {
+			report.ok("whisper", p)
+		}
+	}
+
+	// 5. ffmpeg for audio transcoding
+	if m := cfg.Channels.WhatsApp.Media; cfg.Channels.WhatsApp.Enabled && m.TranscodeAudio {
+		if p, err := exec.LookPath(m.FFmpegPath); err != nil {
+			report.warn("ffmpeg", fmt.Sprintf("not found: %s", m.FFmpegPath),
+				"Install ffmpeg to transcribe AMR/3GP voice notes, or set channels.whatsapp.media.transcodeAudio to false.")
+		} else {
+			report.ok("ffmpeg", p)
+		}
+	}
+
+	// 6. WhatsApp session
+	if wc := cfg.Channels.WhatsApp; wc.Enabled {
+		path := wc.SessionPath
+		if path == "" {
+			path = channels.DefaultWhatsAppSession()
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			report.warn("whatsapp session", "not paired", "Run 'gomikrobot whatsapp login' or scan the QR code on the dashboard.")
+		} else if jid, err := channels.WhatsAppAccount(context.Background(), path); err != nil {
+			report.fail("whatsapp session", err.Error(), "Check that "+path+" is a readable SQLite file, or point channels.whatsapp.sessionPath elsewhere.")
+		} else if jid == "" {
+			report.warn("whatsapp session", "not paired", "Run 'gomikrobot whatsapp login' or scan the QR code on the dashboard.")
+		} else {
+			report.ok("whatsapp session", jid)
+		}
+	}
+
+	if doctorSkipNetwork {
+		finishDoctor(report)
+		return
+	}
+
+	// 7. Provider connectivity
+	if cfg.Providers.OpenAI.APIKey != "" {
+		prov := provider.NewOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI.APIBase, cfg.Agents.Defaults.Model)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err := prov.Ping(ctx)
+		cancel()
+		if err != nil {
+			report.fail("provider", security.SanitizeError(err), "Check the API key, apiBase, and network access.")
+		} else {
+			report.ok("provider", fmt.Sprintf("reachable (key %s)", security.RedactAPIKey(cfg.Providers.OpenAI.APIKey)))
+		}
+	}
+
+	// 8. WhatsApp bridge (legacy)
+	if cfg.Channels.WhatsApp.Enabled && cfg.Channels.WhatsApp.BridgeURL != "" {
+		checkBridge(report, cfg.Channels.WhatsApp.BridgeURL)
+	}
+
+	finishDoctor(report)
+}
+
+func checkWorkspace(report *doctorReport, ws string) {
+	info, err := os.Stat(ws)
+	if err != nil {
+		report.fail("workspace", fmt.Sprintf("%s does not exist", ws), "Run 'gomikrobot workspace init'.")
+		return
+	}
+	if !info.IsDir() {
+		report.fail("workspace", fmt.Sprintf("%s is not a directory", ws), "Point agents.defaults.workspace at a directory.")
+		return
+	}
+
+	probe, err := os.CreateTemp(ws, ".doctor-*")
+	if err != nil {
+		report.fail("workspace", fmt.Sprintf("%s is not writable: %v", ws, err), "Fix ownership/permissions, e.g. chmod 700 "+ws+".")
+		return
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	if info.Mode().Perm()&0o007 != 0 {
+		report.warn("workspace", fmt.Sprintf("%s is accessible by other users (%v)", ws, info.Mode().Perm()), "Restrict it: chmod 700 "+ws+".")
+	} else {
+		report.ok("workspace", ws)
+	}
+	if missing := agent.CheckWorkspace(ws); len(missing) > 0 {
+		report.warn("workspace layout", "missing "+strings.Join(missing, ", "), "Run 'gomikrobot workspace init'.")
+	}
+
+	home, _ := os.UserHomeDir()
+	stateDir := filepath.Join(home, config.ConfigDir)
+	if info, err := os.Stat(stateDir); err == nil && info.Mode().Perm()&0o077 != 0 {
+		report.warn("state dir", fmt.Sprintf("%s is readable by others (%v)", stateDir, info.Mode().Perm()), "Restrict it: chmod 700 "+stateDir+".")
+	}
+}
+
+func checkBridge(report *doctorReport, bridgeURL string) {
+	u, err := url.Parse(bridgeURL)
+	if err != nil || u.Host == "" {
+		report.fail("whatsapp bridge", fmt.Sprintf("invalid bridgeUrl %q", bridgeURL), "Use a URL like ws://localhost:3001.")
+		return
+	}
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "https" || u.Scheme == "wss" {
+			port = "443"
+		}
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	if err != nil {
+		report.fail("whatsapp bridge", fmt.Sprintf("cannot connect to %s: %v", host, err), "Start the bridge (cd bridge && npm start) or fix bridgeUrl.")
+		return
+	}
+	conn.Close()
+	report.ok("whatsapp bridge", host)
+}
+
+func finishDoctor(report *doctorReport) {
+	fmt.Println("─────────────────────")
+	fmt.Printf("%d error(s), %d warning(s)\n", report.errors, report.warnings)
+	if report.errors > 0 {
+		os.Exit(1)
+	}
+}
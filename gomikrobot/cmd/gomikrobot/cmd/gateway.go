@@ -2,25 +2,40 @@ package cmd
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/audit"
 	"github.com/kamir/gomikrobot/internal/bus"
 	"github.com/kamir/gomikrobot/internal/channels"
+	"github.com/kamir/gomikrobot/internal/classify"
 	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/digest"
 	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/media"
+	"github.com/kamir/gomikrobot/internal/metrics"
+	"github.com/kamir/gomikrobot/internal/pricing"
 	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/proxy"
+	"github.com/kamir/gomikrobot/internal/session"
 	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tools"
+	"github.com/kamir/gomikrobot/internal/transcribe"
+	"github.com/kamir/gomikrobot/web"
 	"github.com/spf13/cobra"
 )
 
@@ -41,21 +56,15 @@ func runGateway(cmd *cobra.Command, args []string) {
 	}
 
 	// Defaults (enterprise hardening)
-	if cfg.Gateway.MaxBodyBytes <= 0 {
-		cfg.Gateway.MaxBodyBytes = 10 << 20 // 10 MiB
-	}
-	if cfg.Gateway.RateLimitRPS <= 0 {
-		cfg.Gateway.RateLimitRPS = 5
-	}
-	if cfg.Gateway.RateLimitBurst <= 0 {
-		cfg.Gateway.RateLimitBurst = 10
-	}
-	if cfg.Gateway.ShutdownTimeout <= 0 {
-		cfg.Gateway.ShutdownTimeout = 10 * time.Second
-	}
+	applyGatewayDefaults(cfg)
 
 	// 2. Setup Bus
-	msgBus := bus.NewMessageBus()
+	msgBus, err := newMessageBus(cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	msgBus.SetDedupWindow(cfg.Gateway.DedupWindow)
 
 	// 3. Setup Providers
 	if cfg.Providers.OpenAI.APIKey == "" {
@@ -69,26 +78,76 @@ func runGateway(cmd *cobra.Command, args []string) {
 		prov = provider.NewLocalWhisperProvider(cfg.Providers.LocalWhisper, oaProv)
 	}
 
-	// 4. Setup Loop
-	loop := agent.NewLoop(agent.LoopOptions{
-		Bus:           msgBus,
-		Provider:      prov,
-		Workspace:     cfg.Agents.Defaults.Workspace,
-		Model:         cfg.Agents.Defaults.Model,
-		MaxIterations: cfg.Agents.Defaults.MaxToolIterations,
-	})
-
-	// 5. Setup Timeline (QMD)
-	home, _ := os.UserHomeDir()
-	timelinePath := fmt.Sprintf("%s/.gomikrobot/timeline.db", home)
-	timeSvc, err := timeline.NewTimelineService(timelinePath)
+	// 4. Setup Timeline (QMD)
+	timeSvc, err := timeline.Open(timelineStorage(cfg))
 	if err != nil {
 		fmt.Printf("Failed to init timeline: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Inbound message labels (rules, plus a cheap model if configured)
+	classifier, err := classify.New(cfg.Classification, prov)
+	if err != nil {
+		fmt.Printf("Config error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// 5. Setup Loop
+	stopTracing := startTracing(cfg.Tracing)
+	auditLog := openAuditLog(cfg.Audit)
+	if auditLog != nil {
+		defer auditLog.Close()
+	}
+	loop := agent.NewLoop(gatewayLoopOptions(cfg, msgBus, prov, timeSvc, classifier, auditLog))
+
+	jobs, stopPlugins := registerGatewayTools(loop, cfg, timeSvc)
+	if n, err := timeSvc.FailUnfinishedTasks("interrupted by a restart"); err != nil {
+		fmt.Printf("⚠️ Failed to check background tasks: %v\n", err)
+	} else if n > 0 {
+		fmt.Printf("⚠️ Marked %d unfinished background tasks as failed\n", n)
+	}
+	if n, err := timeSvc.MarkLostJobs(); err == nil && n > 0 {
+		fmt.Printf("⚠️ Marked %d background jobs from before the restart as lost\n", n)
+	}
+
 	// 6. Setup Channels
 	wa := channels.NewWhatsAppChannel(cfg.Channels.WhatsApp, msgBus, prov, timeSvc)
+	channels.SubscribePresence(msgBus, wa)
+	msgBus.SubscribeReactions(func(evt *bus.ReactionEvent) {
+		err := timeSvc.AddReaction(&timeline.Reaction{
+			EventID:   evt.MessageID,
+			Channel:   evt.Channel,
+			SenderID:  evt.SenderID,
+			Emoji:     evt.Emoji,
+			Timestamp: evt.Timestamp,
+		})
+		if err != nil {
+			fmt.Printf("⚠️ Failed to store reaction: %v\n", err)
+		}
+	})
+	voice := channels.NewVoiceChannel(cfg.Channels.Voice, msgBus, prov, timeSvc, filepath.Join(cfg.Agents.Defaults.Workspace, "media"))
+	if tr, err := transcribe.New(cfg); err != nil {
+		fmt.Printf("⚠️ Transcription backend unavailable, using provider: %v\n", err)
+	} else {
+		tc := cfg.Transcription
+		queue := transcribe.NewQueue(tr, tc.Workers, tc.QueueSize, tc.Timeout)
+		wa.SetTranscriber(queue)
+		voice.SetTranscriber(queue)
+		fmt.Printf("🎙️ Transcription: %s (%d workers)\n", tr.Name(), tc.Workers)
+	}
+	hooks, err := channels.NewWebhookChannel(cfg.Channels.Webhooks, msgBus, timeSvc)
+	if err != nil {
+		fmt.Printf("Failed to init webhooks: %v\n", err)
+		os.Exit(1)
+	}
+	feedChannel, err := channels.NewFeedChannel(cfg.Feeds, msgBus, timeSvc)
+	if err != nil {
+		fmt.Printf("Failed to init feeds: %v\n", err)
+		os.Exit(1)
+	}
+	slack := channels.NewSlackChannel(cfg.Channels.Slack, msgBus, timeSvc, filepath.Join(cfg.Agents.Defaults.Workspace, "media"))
+	sig := channels.NewSignalChannel(cfg.Channels.Signal, msgBus, timeSvc, filepath.Join(cfg.Agents.Defaults.Workspace, "media"))
+	wa.Classifier, slack.Classifier, sig.Classifier = classifier, classifier, classifier
 
 	// 7. Start Everything
 	ctx, cancel := context.WithCancel(context.Background())
@@ -107,18 +166,87 @@ func runGateway(cmd *cobra.Command, args []string) {
 	} else {
 		ready.Store(1)
 	}
+	if err := slack.Start(ctx); err != nil {
+		fmt.Printf("Failed to start Slack: %v\n", err)
+	}
+	if err := sig.Start(ctx); err != nil {
+		fmt.Printf("Failed to start Signal: %v\n", err)
+	}
+	_ = voice.Start(ctx)
+	hooks.Start(ctx)
+	_ = feedChannel.Start(ctx)
+	checker := readinessChecks(cfg, &ready, oaProv, timeSvc, wa, sig)
 
 	// Start Bus Dispatcher
-	go msgBus.DispatchOutbound(ctx)
+	applyOutboundPolicies(msgBus, cfg.Channels, filepath.Join(cfg.Agents.Defaults.Workspace, "media"))
+	go func() {
+		if err := msgBus.DispatchOutbound(ctx); err != nil && ctx.Err() == nil {
+			fmt.Printf("❌ Outbound dispatcher stopped: %v\n", err)
+			cancel()
+		}
+	}()
+
+	// Weekly digest
+	if cfg.Digest.Enabled {
+		sched := &digest.Scheduler{Config: cfg.Digest, Timeline: timeSvc, Bus: msgBus, Workspace: cfg.Agents.Defaults.Workspace}
+		go func() {
+			if err := sched.Run(ctx); err != nil {
+				fmt.Printf("⚠️ Digest scheduler stopped: %v\n", err)
+			}
+		}()
+	}
+
+	// Reminders set with remind_me
+	go (&reminderScheduler{timeline: timeSvc, bus: msgBus}).Run(ctx)
+	go (&heldMessageScheduler{timeline: timeSvc, bus: msgBus}).Run(ctx)
+
+	// Cost estimates and monthly budget alert
+	prices := pricing.New(modelPrices(cfg.Pricing))
+	if budget, session := cfg.Pricing.MonthlyBudget, cfg.BudgetAlertSession(); budget > 0 && session != "" {
+		channel, chatID, _ := strings.Cut(session, ":")
+		watcher := &pricing.BudgetWatcher{Registry: prices, Timeline: timeSvc, Bus: msgBus, Budget: budget, Channel: channel, ChatID: chatID}
+		go watcher.Run(ctx)
+	}
+
+	// Tenants: further bots with their own data, selected by API token.
+	var tenants []*tenant
+	for _, tc := range cfg.Tenants {
+		t, err := startTenant(ctx, cfg, tc, prov, classifier)
+		if err != nil {
+			fmt.Printf("❌ Tenant %s failed to start: %v\n", tc.Name, err)
+			continue
+		}
+		tenants = append(tenants, t)
+	}
+	go runTenantMetrics(ctx, mainTenant, loop.Sessions(), timeSvc)
 
 	// Shared middleware
+	if err := httpmw.SetTrustedProxies(cfg.Gateway.TrustedProxies); err != nil {
+		fmt.Printf("⚠️ Ignoring trusted proxies: %v\n", err)
+	}
 	rl := httpmw.NewRateLimiter(cfg.Gateway.RateLimitRPS, cfg.Gateway.RateLimitBurst)
+	applyRateLimitTiers(rl, cfg.Gateway)
 	commonMW := []httpmw.Middleware{
+		httpmw.RequestID(),
+		httpmw.AccessLog("/health", "/ready", "/metrics"),
+		httpmw.Trace("/health", "/ready", "/metrics"),
 		httpmw.Recoverer(),
 		httpmw.MaxBodyBytes(cfg.Gateway.MaxBodyBytes),
 		rl.Middleware(),
 	}
 
+	// Uploaded and generated files live under the workspace media dir.
+	mediaDir := filepath.Join(cfg.Agents.Defaults.Workspace, "media")
+	mediaLib := media.NewLibrary(mediaDir)
+
+	// HTTPS for both servers
+	gwTLS, err := newGatewayTLS(cfg.Gateway)
+	if err != nil {
+		fmt.Printf("❌ TLS setup failed: %v\n", err)
+		os.Exit(1)
+	}
+	gwTLS.startRedirect()
+
 	// API server
 	apiAddr := fmt.Sprintf("%s:%d", cfg.Gateway.Host, cfg.Gateway.Port)
 	apiMux := http.NewServeMux()
@@ -128,16 +256,7 @@ func runGateway(cmd *cobra.Command, args []string) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-	apiMux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain")
-		if ready.Load() == 1 {
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte("ready"))
-			return
-		}
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_, _ = w.Write([]byte("not ready"))
-	})
+	apiMux.HandleFunc("/ready", checker.Handler())
 
 	apiMux.HandleFunc("/chat", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -146,58 +265,66 @@ func runGateway(cmd *cobra.Command, args []string) {
 		}
 
 		// Optional auth token for local-network API.
-		// If configured, require X-API-Token header or token query parameter.
-		if cfg.Gateway.APIToken != "" {
-			tok := r.Header.Get("X-API-Token")
-			if tok == "" {
-				tok = r.URL.Query().Get("token")
-			}
-			if tok != cfg.Gateway.APIToken {
-				http.Error(w, "unauthorized", http.StatusUnauthorized)
-				return
-			}
-		}
-
-		msg := r.URL.Query().Get("message")
-		if msg == "" {
-			http.Error(w, "missing message parameter", http.StatusBadRequest)
+		if !httpmw.TokenOK(r, cfg.Gateway.APIToken) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		session := r.URL.Query().Get("session")
-		if session == "" {
-			session = "local:default"
-		}
-
-		fmt.Printf("🌐 Local Network Request: %s\n", msg)
-		resp, err := loop.ProcessDirect(ctx, msg, session)
-		if err != nil {
-			// Avoid leaking internal errors to clients.
-			fmt.Printf("❌ /chat failed: %v\n", err)
-			http.Error(w, "internal server error", http.StatusInternalServerError)
-			return
-		}
-		_, _ = fmt.Fprint(w, resp)
+		serveChat(ctx, w, r, loop, mediaLib, "local:default")
 	})
 
+	// Machine-readable answers for integrations, same token as /chat.
+	apiMux.HandleFunc("/structured", structuredHandler(ctx, loop, cfg.Gateway.APIToken))
+
+	// OpenAI-compatible API for existing chat clients.
+	registerOpenAIRoutes(apiMux, loop, cfg.Gateway.APIToken)
+
+	// Inbound webhooks (signature-checked per hook, not by API token).
+	apiMux.Handle("/api/v1/hooks/{name}", hooks)
+
+	// Provider pass-through for other local apps, authenticated with proxy keys.
+	var px *proxy.Proxy
+	if cfg.Proxy.Enabled {
+		px = proxy.New(proxy.Options{
+			Upstream: cfg.Providers.OpenAI.APIBase,
+			APIKey:   cfg.Providers.OpenAI.APIKey,
+			Keys:     cfg.Proxy.Keys,
+			Usage:    timeSvc,
+		})
+		apiMux.Handle("/proxy/v1/", http.StripPrefix("/proxy/v1", px))
+		fmt.Printf("🔀 Provider proxy enabled at %s://%s/proxy/v1 (%d keys)\n", gwTLS.scheme(), apiAddr, len(cfg.Proxy.Keys))
+	}
+
 	apiServer := &http.Server{
 		Addr:    apiAddr,
-		Handler: httpmw.Chain(apiMux, commonMW...),
+		Handler: httpmw.Chain(routeTenants(tenants, apiMux), commonMW...),
 	}
 
 	go func() {
-		fmt.Printf("📡 API Server listening on http://%s\n", apiAddr)
-		err := apiServer.ListenAndServe()
+		fmt.Printf("📡 API Server listening on %s://%s\n", gwTLS.scheme(), apiAddr)
+		err := gwTLS.serve(apiServer)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			fmt.Printf("API Server Error: %v\n", err)
 			cancel()
 		}
 	}()
 
-	// Dashboard server
-	if cfg.Gateway.DashboardPort == 0 {
-		cfg.Gateway.DashboardPort = 18791
+	// Session garbage collection
+	gc := &sessionGC{
+		sessions: loop.Sessions(),
+		maxAge:   time.Duration(cfg.Sessions.RetentionDays) * 24 * time.Hour,
 	}
+	go gc.Run(ctx, cfg.Sessions.GCInterval)
+
+	// Timeline retention
+	retention := &timelineRetention{timeline: timeSvc, cfg: cfg.Timeline, workspace: cfg.Agents.Defaults.Workspace}
+	go retention.Run(ctx)
+
+	// Scheduled backups
+	backups := &scheduledBackup{cfg: cfg.Backup, paths: backupPaths(cfg)}
+	go backups.Run(ctx)
+
+	// Dashboard server
 	dashAddr := fmt.Sprintf("%s:%d", cfg.Gateway.Host, cfg.Gateway.DashboardPort)
 	mux := http.NewServeMux()
 
@@ -206,20 +333,10 @@ func runGateway(cmd *cobra.Command, args []string) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/plain")
-		if ready.Load() == 1 {
-			w.WriteHeader(http.StatusOK)
-			_, _ = w.Write([]byte("ready"))
-			return
-		}
-		w.WriteHeader(http.StatusServiceUnavailable)
-		_, _ = w.Write([]byte("not ready"))
-	})
+	mux.HandleFunc("/ready", checker.Handler())
 
 	// API: Timeline
 	mux.HandleFunc("/api/v1/timeline", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Content-Type", "application/json")
 
 		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
@@ -230,29 +347,344 @@ func runGateway(cmd *cobra.Command, args []string) {
 		sender := r.URL.Query().Get("sender")
 
 		events, err := timeSvc.GetEvents(timeline.FilterArgs{
+			Limit:          limit,
+			Offset:         offset,
+			SenderID:       sender,
+			Classification: strings.ToUpper(r.URL.Query().Get("classification")),
+		})
+		if err != nil {
+			fmt.Printf("❌ /api/v1/timeline failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(withMediaURLs(mediaLib, events))
+	})
+
+	// API: Timeline full-text search
+	mux.HandleFunc("/api/v1/timeline/search", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		q := r.URL.Query().Get("q")
+		if strings.TrimSpace(q) == "" {
+			http.Error(w, "missing q", http.StatusBadRequest)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		results, err := timeSvc.Search(q, timeline.FilterArgs{
 			Limit:    limit,
 			Offset:   offset,
-			SenderID: sender,
+			SenderID: r.URL.Query().Get("sender"),
 		})
 		if err != nil {
-			fmt.Printf("❌ /api/v1/timeline failed: %v\n", err)
+			fmt.Printf("❌ /api/v1/timeline/search failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
 			http.Error(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
+		if results == nil {
+			results = []timeline.SearchResult{}
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	})
 
-		_ = json.NewEncoder(w).Encode(events)
+	// API: Timeline statistics for the dashboard cards
+	mux.HandleFunc("/api/v1/timeline/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+		if days <= 0 || days > 365 {
+			days = 7
+		}
+		end := time.Now()
+		stats, err := timeSvc.Stats(end.AddDate(0, 0, -days), end)
+		if err != nil {
+			fmt.Printf("❌ /api/v1/timeline/stats failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(stats)
 	})
 
-	// API: Settings (GET/POST)
-	mux.HandleFunc("/api/v1/settings", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	// API: Reaction feedback on bot replies
+	mux.HandleFunc("/api/v1/feedback", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+		if days <= 0 || days > 365 {
+			days = 30
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := time.Now()
+		report, err := timeSvc.Feedback(end.AddDate(0, 0, -days), end, limit)
+		if err != nil {
+			fmt.Printf("❌ /api/v1/feedback failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+
+	// API: Token usage and estimated cost per model
+	mux.HandleFunc("/api/v1/usage", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		days, _ := strconv.Atoi(r.URL.Query().Get("days"))
+		if days <= 0 || days > 365 {
+			days = 30
+		}
+		end := time.Now()
+		start := end.AddDate(0, 0, -days)
+		totals, err := timeSvc.UsageBetween(start, end)
+		var byModel []timeline.ModelUsage
+		if err == nil {
+			byModel, err = timeSvc.UsageByModel(start, end)
+		}
+		var month *pricing.Report
+		if err == nil {
+			month, err = prices.MonthToDate(timeSvc, end)
+		}
+		if err != nil {
+			fmt.Printf("❌ /api/v1/usage failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"start":          start,
+			"end":            end,
+			"totals":         totals,
+			"cost":           prices.Estimate(byModel),
+			"month_to_date":  month.TotalUSD,
+			"monthly_budget": cfg.Pricing.MonthlyBudget,
+		})
+	})
+
+	// API: LLM-written summary of recent conversations
+	mux.HandleFunc("/api/v1/timeline/summary", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		since := r.URL.Query().Get("since")
+		if since == "" {
+			since = "7d"
+		}
+		end := time.Now()
+		start, err := parseSince(since, end)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Minute)
+		defer cancel()
+		summary, err := digest.Summarize(ctx, prov, timeSvc, digest.SummaryOptions{
+			SenderID: normalizeSender(r.URL.Query().Get("sender")),
+			Start:    start,
+			End:      end,
+			Model:    loop.Model(),
+		})
+		if err != nil {
+			fmt.Printf("❌ /api/v1/timeline/summary failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(summary)
+	})
+
+	// API: Background tasks
+	mux.HandleFunc("/api/v1/tasks", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		tasks, err := timeSvc.ListTasks(r.URL.Query().Get("status"), limit)
+		if err != nil {
+			fmt.Printf("❌ /api/v1/tasks failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if tasks == nil {
+			tasks = []timeline.Task{}
+		}
+		_ = json.NewEncoder(w).Encode(tasks)
+	})
+
+	mux.HandleFunc("/api/v1/tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		task, err := timeSvc.GetTask(r.PathValue("id"))
+		if errors.Is(err, timeline.ErrTaskNotFound) {
+			http.Error(w, "task not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			fmt.Printf("❌ /api/v1/tasks/{id} failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(task)
+	})
+
+	// API: Background jobs started with exec run_in_background
+	mux.HandleFunc("/api/v1/jobs", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		if r.Method == http.MethodOptions {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		list, err := timeSvc.ListJobs(r.URL.Query().Get("status"), limit)
+		if err != nil {
+			fmt.Printf("❌ /api/v1/jobs failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
 			return
 		}
+		// Live jobs report their latest output rather than the stored tail.
+		live := map[string]timeline.Job{}
+		for _, j := range jobs.List("") {
+			live[j.ID] = j
+		}
+		for i, j := range list {
+			if l, ok := live[j.ID]; ok {
+				list[i] = l
+			}
+		}
+		if list == nil {
+			list = []timeline.Job{}
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	})
+
+	mux.HandleFunc("/api/v1/jobs/{id}/kill", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		err := jobs.Kill("", r.PathValue("id"))
+		if errors.Is(err, tools.ErrJobNotFound) {
+			http.Error(w, "job not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// API: Reminders
+	mux.HandleFunc("/api/v1/reminders", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = timeline.ReminderPending
+		} else if status == "all" {
+			status = ""
+		}
+		list, err := timeSvc.ListReminders(r.URL.Query().Get("session"), status, 200)
+		if err != nil {
+			fmt.Printf("❌ /api/v1/reminders failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if list == nil {
+			list = []timeline.Reminder{}
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	})
+
+	mux.HandleFunc("/api/v1/reminders/{id}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		err = timeSvc.CancelReminder("", id)
+		if errors.Is(err, timeline.ErrReminderNotFound) {
+			http.Error(w, "reminder not found or not pending", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			fmt.Printf("❌ /api/v1/reminders/{id}/cancel failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// API: Contact directory
+	mux.HandleFunc("/api/v1/contacts", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodPost {
+			var c timeline.Contact
+			if err := json.NewDecoder(r.Body).Decode(&c); err != nil || c.SenderID == "" {
+				http.Error(w, "invalid body: sender_id is required", http.StatusBadRequest)
+				return
+			}
+			c.SenderID = normalizeSender(c.SenderID)
+			if old, err := timeSvc.GetContact(c.SenderID); err == nil {
+				c.CreatedAt = old.CreatedAt
+			}
+			if err := timeSvc.SaveContact(&c); err != nil {
+				fmt.Printf("❌ /api/v1/contacts POST failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(c)
+			return
+		}
+
+		contacts, err := timeSvc.ListContacts(r.URL.Query().Get("tag"))
+		if err != nil {
+			fmt.Printf("❌ /api/v1/contacts failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(contacts)
+	})
+
+	mux.HandleFunc("/api/v1/contacts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		id := normalizeSender(r.PathValue("id"))
+
+		if r.Method == http.MethodDelete {
+			found, err := timeSvc.DeleteContact(id)
+			if err != nil {
+				fmt.Printf("❌ /api/v1/contacts/{id} DELETE failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			if !found {
+				http.Error(w, "contact not found", http.StatusNotFound)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+			return
+		}
+
+		c, err := timeSvc.GetContact(id)
+		if errors.Is(err, timeline.ErrContactNotFound) {
+			http.Error(w, "contact not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			fmt.Printf("❌ /api/v1/contacts/{id} failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(c)
+	})
+
+	// API: Settings (GET/POST)
+	mux.HandleFunc("/api/v1/settings", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 
 		if r.Method == http.MethodPost {
 			var body struct {
@@ -263,8 +695,14 @@ func runGateway(cmd *cobra.Command, args []string) {
 				http.Error(w, "invalid body", http.StatusBadRequest)
 				return
 			}
+			if body.Key == timeline.QuietHoursSetting && body.Value != "" && body.Value != "off" {
+				if _, err := timeline.ParseQuietHours(body.Value); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+			}
 			if err := timeSvc.SetSetting(body.Key, body.Value); err != nil {
-				fmt.Printf("❌ /api/v1/settings POST failed: %v\n", err)
+				fmt.Printf("❌ /api/v1/settings POST failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
 				http.Error(w, "internal server error", http.StatusInternalServerError)
 				return
 			}
@@ -288,41 +726,325 @@ func runGateway(cmd *cobra.Command, args []string) {
 		_ = json.NewEncoder(w).Encode(map[string]bool{"silent_mode": timeSvc.IsSilentMode()})
 	})
 
-	// Static: Media
-	mediaDir := filepath.Join(cfg.Agents.Defaults.Workspace, "media")
-	fs := http.FileServer(http.Dir(mediaDir))
-	mux.Handle("/media/", http.StripPrefix("/media/", fs))
+	// API: Silent mode and quiet hours per contact
+	mux.HandleFunc("/api/v1/quiet", quietHandler(timeSvc))
 
-	// SPA: Timeline
-	mux.HandleFunc("/timeline", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "web/timeline.html")
+	// API: Per-group reply switch for WhatsApp groups
+	mux.HandleFunc("/api/v1/groups", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodPost {
+			var body struct {
+				ChatID  string `json:"chat_id"`
+				Enabled bool   `json:"enabled"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !strings.HasSuffix(body.ChatID, "@g.us") {
+				http.Error(w, "invalid body: chat_id must be a group JID", http.StatusBadRequest)
+				return
+			}
+			if err := timeSvc.SetGroupEnabled(body.ChatID, body.Enabled); err != nil {
+				fmt.Printf("❌ /api/v1/groups POST failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			fmt.Printf("⚙️ Group %s enabled = %v\n", body.ChatID, body.Enabled)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+			return
+		}
+
+		groups, err := timeSvc.GroupSettings()
+		if err != nil {
+			fmt.Printf("❌ /api/v1/groups failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"default": cfg.Channels.WhatsApp.Groups.Enabled,
+			"groups":  groups,
+		})
 	})
 
+	// API: Human takeover of conversations
+	mux.HandleFunc("/api/v1/sessions/paused", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodPost {
+			var body struct {
+				Session string `json:"session"`
+				Paused  bool   `json:"paused"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !strings.Contains(body.Session, ":") {
+				http.Error(w, "invalid body: session must be a session key", http.StatusBadRequest)
+				return
+			}
+			if err := loop.SetPaused(body.Session, body.Paused); err != nil {
+				fmt.Printf("❌ /api/v1/sessions/paused POST failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			fmt.Printf("🧑 Session %s paused = %v\n", body.Session, body.Paused)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+			return
+		}
+
+		paused, err := loop.PausedSessions()
+		if err != nil {
+			fmt.Printf("❌ /api/v1/sessions/paused failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(paused)
+	})
+
+	// API: Replies held for review
+	mux.HandleFunc("/api/v1/drafts", draftsHandler(loop))
+	mux.HandleFunc("/api/v1/drafts/{id}", draftHandler(loop))
+
+	// API: Pinned messages of a session
+	mux.HandleFunc("/api/v1/sessions/pins", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case http.MethodPost:
+			var body struct {
+				Session string `json:"session"`
+				Content string `json:"content"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !strings.Contains(body.Session, ":") {
+				http.Error(w, "invalid body: session must be a session key", http.StatusBadRequest)
+				return
+			}
+			pin, err := loop.Pin(body.Session, body.Content)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Printf("📌 Pinned #%d in %s\n", pin.ID, body.Session)
+			_ = json.NewEncoder(w).Encode(pin)
+		case http.MethodDelete:
+			key := r.URL.Query().Get("session")
+			id, err := strconv.Atoi(r.URL.Query().Get("id"))
+			if !strings.Contains(key, ":") || err != nil {
+				http.Error(w, "session and id are required", http.StatusBadRequest)
+				return
+			}
+			if err := loop.Unpin(key, id); errors.Is(err, agent.ErrPinNotFound) {
+				http.Error(w, "pin not found", http.StatusNotFound)
+				return
+			} else if err != nil {
+				fmt.Printf("❌ /api/v1/sessions/pins DELETE failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+		default:
+			key := r.URL.Query().Get("session")
+			if !strings.Contains(key, ":") {
+				http.Error(w, "session is required", http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(loop.Pins(key))
+		}
+	})
+
+	// API: WhatsApp pairing (QR login, re-pair, unlink)
+	mux.HandleFunc("/api/v1/whatsapp/pairing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.Method {
+		case http.MethodPost:
+			if !cfg.Channels.WhatsApp.Enabled {
+				http.Error(w, "whatsapp is disabled", http.StatusConflict)
+				return
+			}
+			if wa.Pairing().Status == channels.PairingPaired {
+				http.Error(w, "already paired; unlink first", http.StatusConflict)
+				return
+			}
+			wa.Stop()
+			if err := wa.Start(ctx); err != nil {
+				fmt.Printf("❌ /api/v1/whatsapp/pairing POST failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		case http.MethodDelete:
+			if err := wa.Logout(r.Context()); err != nil {
+				fmt.Printf("❌ /api/v1/whatsapp/pairing DELETE failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				http.Error(w, "logout failed: "+err.Error(), http.StatusBadGateway)
+				return
+			}
+			fmt.Println("📴 WhatsApp device unlinked from the dashboard")
+		}
+
+		state := wa.Pairing()
+		resp := map[string]any{"status": state.Status, "jid": state.JID, "error": state.Error}
+		if state.Code != "" {
+			if png, err := channels.QRPNG(state.Code); err == nil {
+				resp["qr"] = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+				resp["expires"] = state.Expires
+			}
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	// API: Session stats
+	mux.HandleFunc("/api/v1/sessions/stats", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(gc.Stats())
+	})
+
+	// API: Chat from the dashboard, same request format as /chat
+	mux.HandleFunc("/api/v1/chat", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		serveChat(ctx, w, r, loop, mediaLib, "dashboard:default")
+	})
+
+	// API: Sessions, most recently used first
+	mux.HandleFunc("/api/v1/sessions", func(w http.ResponseWriter, r *http.Request) {
+		list := loop.Sessions().List()
+		sort.Slice(list, func(i, j int) bool { return list[i].UpdatedAt.After(list[j].UpdatedAt) })
+		out := make([]map[string]any, 0, len(list))
+		for _, s := range list {
+			item := map[string]any{"key": s.Key, "updated_at": s.UpdatedAt}
+			if s.BranchOf != "" {
+				item["branch_of"] = s.BranchOf
+			}
+			out = append(out, item)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	})
+
+	// API: Recent user and assistant messages of a session. index is the
+	// message's position in the session, e.g. for branching after it.
+	mux.HandleFunc("/api/v1/sessions/history", func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("session")
+		if !strings.Contains(key, ":") {
+			http.Error(w, "session is required", http.StatusBadRequest)
+			return
+		}
+		type indexedMessage struct {
+			session.Message
+			Index int `json:"index"`
+		}
+		var out []indexedMessage
+		all := loop.Sessions().GetOrCreate(key).GetHistory(math.MaxInt)
+		for i := max(0, len(all)-200); i < len(all); i++ {
+			if m := all[i]; m.Role == "user" || m.Role == "assistant" {
+				out = append(out, indexedMessage{m, i})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	})
+
+	// API: Regenerate the last reply of a session, or branch it
+	mux.HandleFunc("/api/v1/sessions/{key}/regenerate", regenerateHandler(ctx, loop))
+	mux.HandleFunc("/api/v1/sessions/{key}/branch", branchHandler(loop))
+
+	// Metrics (Prometheus text format)
+	mux.Handle("/metrics", metrics.Default.Handler())
+	mux.HandleFunc("/api/v1/tenants", tenantsHandler(cfg.Agents.Defaults.Workspace, tenants))
+
+	// API: Media gallery
+	mux.HandleFunc("/api/v1/media", mediaHandler(mediaLib, timeSvc))
+
+	// Media files, only through signed links
+	mux.Handle("/media/", http.StripPrefix("/media/", mediaLib))
+	public := []string{"/health", "/ready", "/media/"}
+	for _, t := range tenants {
+		mux.Handle(t.media.Prefix, http.StripPrefix(t.media.Prefix, t.media))
+		public = append(public, t.media.Prefix)
+	}
+
+	// SPA: Timeline
+	site := web.New(cfg.Gateway.WebDir)
+	if cfg.Gateway.WebDir != "" {
+		fmt.Printf("🛠️  Serving dashboard files from %s\n", cfg.Gateway.WebDir)
+	}
+	mux.HandleFunc("/timeline", site.Page("timeline.html"))
+	mux.HandleFunc("/api/v1/voice", voice.Handler())
+
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path == "/" {
 			http.Redirect(w, r, "/timeline", http.StatusFound)
 		}
 	})
 
+	// Dashboard login; health checks, signed media links and the login page
+	// stay public.
+	dashAuth := httpmw.NewSessionAuth(httpmw.SessionAuthOptions{
+		Password: cfg.Gateway.DashboardPassword,
+		Token:    cfg.Gateway.APIToken,
+		TTL:      cfg.Gateway.SessionTTL,
+		Public:   public,
+	})
+	registerLoginRoutes(mux, dashAuth)
+	if !dashAuth.Enabled() {
+		fmt.Println("⚠️ Dashboard has no password; set gateway.dashboardPassword to require a login")
+	}
+
+	dashMW := append(commonMW, httpmw.CORS(cfg.Gateway.CORSOrigins), dashAuth.Middleware())
 	dashServer := &http.Server{
 		Addr:    dashAddr,
-		Handler: httpmw.Chain(mux, commonMW...),
+		Handler: httpmw.Chain(mux, dashMW...),
 	}
 
 	go func() {
-		fmt.Printf("🖥️  Dashboard listening on http://%s\n", dashAddr)
-		err := dashServer.ListenAndServe()
+		fmt.Printf("🖥️  Dashboard listening on %s://%s\n", gwTLS.scheme(), dashAddr)
+		err := gwTLS.serve(dashServer)
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			fmt.Printf("❌ Dashboard Server FAILED to start: %v\n", err)
 			cancel() // Stop the whole gateway if dashboard fails
 		}
 	}()
 
-	// Start Agent Loop in background
-	go func() {
-		if err := loop.Run(ctx); err != nil {
-			fmt.Printf("Agent loop crashed: %v\n", err)
+	// Start Agent Loop in background, or route sessions to the workers
+	// running it.
+	if cfg.Bus.Workers {
+		if err := msgBus.RouteSessions(ctx); err != nil {
+			fmt.Printf("❌ Routing to workers failed: %v\n", err)
 			cancel()
+		} else {
+			fmt.Printf("🧵 Agent turns run in workers on the %s bus\n", cfg.Bus.Transport)
+		}
+	} else {
+		go func() {
+			if err := loop.Run(ctx); err != nil {
+				fmt.Printf("Agent loop crashed: %v\n", err)
+				cancel()
+			}
+		}()
+	}
+
+	// Hot reload: watch the config file and reload on SIGHUP.
+	reloader := newConfigReloader(ctx, cfg, rl, loop, wa)
+	reloader.proxy = px
+	reloader.hooks = hooks
+	reloader.bus = msgBus
+	reloader.prices = prices
+	reloader.classifier = classifier
+	go config.Watch(ctx, 2*time.Second, reloader.Apply)
+
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hupChan:
+				fmt.Println("🔄 SIGHUP received, reloading config...")
+				next, err := config.Load()
+				if err != nil {
+					fmt.Printf("⚠️ Config reload failed: %v\n", err)
+					continue
+				}
+				reloader.Apply(next)
+			}
 		}
 	}()
 
@@ -345,8 +1067,106 @@ func runGateway(cmd *cobra.Command, args []string) {
 	if err := dashServer.Shutdown(shutdownCtx); err != nil {
 		fmt.Printf("Dashboard server shutdown error: %v\n", err)
 	}
+	if gwTLS != nil && gwTLS.redirect != nil {
+		_ = gwTLS.redirect.Shutdown(shutdownCtx)
+	}
 
+	// Let the current agent turn finish while channels can still deliver it.
+	loopCtx, loopCancel := context.WithTimeout(context.Background(), cfg.Gateway.ShutdownTimeout)
+	defer loopCancel()
+	if err := loop.Shutdown(loopCtx); err != nil {
+		fmt.Printf("⚠️ Agent shutdown interrupted an in-flight turn: %v\n", err)
+	}
+	for _, t := range tenants {
+		t.stop(loopCtx)
+	}
+
+	jobs.KillAll()
+	stopPlugins()
 	wa.Stop()
-	loop.Stop()
+	slack.Stop()
+	sig.Stop()
+	voice.Stop()
+	msgBus.Close()
 	timeSvc.Close()
+	stopTracing()
+}
+
+// registerGatewayTools gives loop the tools configured in cfg. Call
+// stopPlugins on shutdown.
+func registerGatewayTools(loop *agent.Loop, cfg *config.Config, timeSvc *timeline.TimelineService) (jobs *tools.JobManager, stopPlugins func()) {
+	jobs = tools.NewJobManager(timeSvc)
+	loop.RegisterTool(execToolFromConfig(cfg.Tools.Exec, cfg.Agents.Defaults.Workspace, jobs))
+	loop.RegisterTool(tools.NewJobTool(jobs))
+	if t := codeToolFromConfig(cfg.Tools); t != nil {
+		loop.RegisterTool(t)
+	}
+	for _, t := range calendarTools(cfg.Tools.Calendar) {
+		loop.RegisterTool(t)
+	}
+	for _, t := range desktopTools(cfg.Tools.Desktop) {
+		loop.RegisterTool(t)
+	}
+	for _, t := range knowledgeTools(cfg) {
+		loop.RegisterTool(t)
+	}
+	registerHTTPTool(loop, cfg.Tools.HTTP)
+	registerRemoteTools(context.Background(), loop, cfg.Tools.Remote)
+	return jobs, registerPlugins(context.Background(), loop, cfg.Tools.Plugins)
+}
+
+// newMessageBus creates the main bot's message bus, connected to the
+// transport in cfg.Bus so other processes can share it.
+func newMessageBus(cfg *config.Config) (*bus.MessageBus, error) {
+	switch cfg.Bus.Transport {
+	case "", "local":
+		return bus.NewMessageBus(), nil
+	}
+	t, err := bus.OpenTransport(cfg.Bus.Transport, cfg.Bus.URL, cfg.Bus.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("bus transport: %w", err)
+	}
+	return bus.NewMessageBusWith(t), nil
+}
+
+// gatewayLoopOptions returns the agent loop settings from cfg; tenants
+// start from the same settings.
+func gatewayLoopOptions(cfg *config.Config, msgBus *bus.MessageBus, prov provider.LLMProvider, timeSvc *timeline.TimelineService, classifier *classify.Classifier, auditLog *audit.Log) agent.LoopOptions {
+	return agent.LoopOptions{
+		Bus:           msgBus,
+		Provider:      prov,
+		Workspace:     cfg.Agents.Defaults.Workspace,
+		Model:         cfg.Agents.Defaults.Model,
+		Models:        cfg.Agents.Defaults.Models,
+		MaxIterations: cfg.Agents.Defaults.MaxToolIterations,
+
+		MaxParallelTools:   cfg.Agents.Defaults.MaxParallelTools,
+		ToolTimeout:        cfg.Agents.Defaults.ToolTimeout,
+		MaxToolResultChars: cfg.Agents.Defaults.MaxToolResultChars,
+		MaxToolCalls:       cfg.Agents.Defaults.MaxToolCalls,
+		TurnTimeout:        cfg.Agents.Defaults.TurnTimeout,
+		HistoryMessages:    cfg.Agents.Defaults.HistoryMessages,
+		HistoryTokens:      cfg.Agents.Defaults.HistoryTokens,
+		ContextLength:      cfg.Agents.Defaults.ContextLength,
+
+		MaxConcurrentSessions: cfg.Agents.Defaults.MaxConcurrentSessions,
+		Prompt: agent.PromptOptions{
+			TemplateFile:     cfg.Agents.Defaults.Prompt.TemplateFile,
+			DisabledSections: cfg.Agents.Defaults.Prompt.DisabledSections,
+		},
+		Timeline: timeSvc,
+		Projects: projectsFromConfig(cfg.Agents.Projects),
+		Admins:   cfg.Agents.Admins,
+
+		MaxConcurrentTasks: cfg.Agents.Defaults.MaxConcurrentTasks,
+		TaskTimeout:        cfg.Agents.Defaults.TaskTimeout,
+		TaskMaxToolCalls:   cfg.Agents.Defaults.TaskMaxToolCalls,
+		Audit:              auditLog,
+		Sampling:           samplingFromConfig(cfg.Agents.Defaults),
+		Classifier:         classifier,
+		VIPs:               cfg.Agents.VIPs,
+		ReviewSession:      cfg.ReviewSession(),
+		Critic:             agent.CriticOptions(cfg.Agents.Defaults.Critic),
+		Refusals:           agent.RefusalOptions(cfg.Agents.Defaults.Refusals),
+	}
 }
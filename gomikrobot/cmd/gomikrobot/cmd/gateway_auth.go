@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/httpmw"
+)
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>GoMikroBot – Login</title>
+<style>
+body { font-family: system-ui, sans-serif; background: #0f172a; color: #e2e8f0; display: flex; align-items: center; justify-content: center; height: 100vh; margin: 0; }
+form { background: #1e293b; padding: 2rem; border-radius: 12px; width: 280px; }
+h1 { font-size: 1.2rem; margin: 0 0 1rem; }
+input, button { width: 100%; box-sizing: border-box; padding: .6rem; border-radius: 6px; border: 1px solid #334155; margin-top: .5rem; }
+input { background: #0f172a; color: inherit; }
+button { background: #2563eb; color: white; border: none; cursor: pointer; }
+.error { color: #f87171; font-size: .9rem; }
+</style>
+</head>
+<body>
+<form method="post" action="/login">
+<h1>GoMikroBot Dashboard</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<input type="hidden" name="next" value="{{.Next}}">
+<input type="password" name="password" placeholder="Password" autofocus required>
+<button type="submit">Log in</button>
+</form>
+</body>
+</html>
+`))
+
+// registerLoginRoutes serves the dashboard login and logout endpoints.
+func registerLoginRoutes(mux *http.ServeMux, auth *httpmw.SessionAuth) {
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		next := safeRedirect(r.FormValue("next"))
+		if !auth.Enabled() {
+			http.Redirect(w, r, next, http.StatusFound)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			renderLogin(w, http.StatusOK, next, "")
+		case http.MethodPost:
+			// Login forms from other sites would plant a session of ours.
+			if origin := r.Header.Get("Origin"); origin != "" && !sameHost(origin, r.Host) {
+				http.Error(w, "cross-origin login refused", http.StatusForbidden)
+				return
+			}
+			if !auth.CheckPassword(r.PostFormValue("password")) {
+				fmt.Printf("⚠️ Dashboard login failed from %s\n", r.RemoteAddr)
+				renderLogin(w, http.StatusUnauthorized, next, "Wrong password.")
+				return
+			}
+			auth.Login(w, r)
+			http.Redirect(w, r, next, http.StatusSeeOther)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/logout", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		auth.Logout(w)
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+	})
+}
+
+func renderLogin(w http.ResponseWriter, status int, next, errMsg string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = loginPage.Execute(w, map[string]string{"Next": next, "Error": errMsg})
+}
+
+// safeRedirect keeps post-login redirects on this site.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/timeline"
+	}
+	return next
+}
+
+func sameHost(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
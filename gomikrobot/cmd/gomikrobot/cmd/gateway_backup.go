@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/backup"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/metrics"
+)
+
+var backupsFailed = metrics.Default.Counter("gomikrobot_backups_failed_total", "Scheduled backups that failed.")
+
+// scheduledBackup periodically writes a backup to backup.dir and deletes
+// the oldest ones.
+type scheduledBackup struct {
+	cfg   config.BackupConfig
+	paths backup.Paths
+}
+
+// Run backs up on every interval until ctx is cancelled. The first backup
+// is made one interval after start. It does nothing unless enabled.
+func (b *scheduledBackup) Run(ctx context.Context) {
+	if !b.cfg.Enabled {
+		return
+	}
+	interval := b.cfg.Interval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	fmt.Printf("💾 Backups every %s to %s\n", interval, b.cfg.Dir)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.runOnce(ctx)
+		}
+	}
+}
+
+func (b *scheduledBackup) runOnce(ctx context.Context) {
+	var pass string
+	if b.cfg.Encrypt {
+		pass = os.Getenv(backup.PassphraseEnv)
+		if pass == "" {
+			backupsFailed.Inc()
+			fmt.Printf("⚠️ Backup skipped: %s is not set\n", backup.PassphraseEnv)
+			return
+		}
+	}
+	dst := filepath.Join(b.cfg.Dir, backup.Name(time.Now(), b.cfg.Encrypt))
+	if _, err := backup.Create(ctx, dst, b.paths, pass, version); err != nil {
+		backupsFailed.Inc()
+		fmt.Printf("⚠️ Backup failed: %v\n", err)
+		return
+	}
+	removed, err := backup.Prune(b.cfg.Dir, b.cfg.Keep)
+	if err != nil {
+		fmt.Printf("⚠️ Removing old backups failed: %v\n", err)
+	}
+	fmt.Printf("💾 Backup written to %s (%d old removed)\n", dst, len(removed))
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// regenerateHandler serves POST /api/v1/sessions/{key}/regenerate. It
+// answers the last user message of the session again, replacing the last
+// reply; the body may set {model, temperature, top_p, max_tokens} for
+// this answer only.
+func regenerateHandler(ctx context.Context, loop *agent.Loop) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := r.PathValue("key")
+		if !strings.Contains(key, ":") {
+			http.Error(w, "invalid session key", http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			Model string `json:"model"`
+			tools.Sampling
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+		}
+		if t := body.Temperature; t != nil && (*t < 0 || *t > 2) {
+			http.Error(w, "temperature must be between 0 and 2", http.StatusBadRequest)
+			return
+		}
+
+		reply, err := loop.Regenerate(ctx, key, body.Model, body.Sampling)
+		if errors.Is(err, agent.ErrNothingToRegenerate) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			fmt.Printf("❌ /api/v1/sessions/{key}/regenerate failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("🔁 Regenerated last reply of %s\n", key)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"session": key, "content": reply})
+	}
+}
+
+// branchHandler serves POST /api/v1/sessions/{key}/branch with {at}: a new
+// session starting with the first at messages of key.
+func branchHandler(loop *agent.Loop) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key := r.PathValue("key")
+		var body struct {
+			At int `json:"at"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !strings.Contains(key, ":") {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		branch, err := loop.Sessions().Branch(key, body.At)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fmt.Printf("🌿 Branched %s at message %d into %s\n", key, body.At, branch.Key)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{"key": branch.Key, "branch_of": key, "branch_at": body.At})
+	}
+}
@@ -0,0 +1,336 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/media"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// maxUploadMemory is the part of a multipart body kept in memory; the rest
+// spills to temp files. The total size is still bounded by MaxBodyBytes.
+const maxUploadMemory = 8 << 20
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// chatFile describes a file uploaded to or produced by a /chat request.
+type chatFile struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+	Type string `json:"type,omitempty"`
+}
+
+// chatInput is the normalized input of a /chat request.
+type chatInput struct {
+	Message string
+	Session string
+	Files   []chatFile
+	// Cache reuses a response to the same message for this long.
+	Cache time.Duration
+}
+
+// parseChatInput reads message, session, and uploads from a /chat request.
+// Multipart bodies may carry files in any form field; they are stored under
+// uploads/ in the media library. Plain requests keep using query parameters.
+func parseChatInput(r *http.Request, lib *media.Library) (*chatInput, error) {
+	in := &chatInput{
+		Message: r.URL.Query().Get("message"),
+		Session: r.URL.Query().Get("session"),
+	}
+	if v := r.URL.Query().Get("cache"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cache duration: %w", err)
+		}
+		in.Cache = ttl
+	}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return in, nil
+	}
+
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		return nil, fmt.Errorf("invalid multipart body: %w", err)
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	if v := r.FormValue("message"); v != "" {
+		in.Message = v
+	}
+	if v := r.FormValue("session"); v != "" {
+		in.Session = v
+	}
+
+	uploadDir := filepath.Join(lib.Dir, "uploads")
+	for _, headers := range r.MultipartForm.File {
+		for _, fh := range headers {
+			f, err := saveUpload(fh, uploadDir)
+			if err != nil {
+				return nil, err
+			}
+			f.URL = lib.URL(f.Path)
+			in.Files = append(in.Files, *f)
+		}
+	}
+	return in, nil
+}
+
+func saveUpload(fh *multipart.FileHeader, dir string) (*chatFile, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create upload dir: %w", err)
+	}
+
+	src, err := fh.Open()
+	if err != nil {
+		return nil, fmt.Errorf("open upload: %w", err)
+	}
+	defer src.Close()
+
+	name := unsafeFileChars.ReplaceAllString(filepath.Base(fh.Filename), "_")
+	if name == "" || name == "." || name == ".." {
+		name = "upload"
+	}
+	name = fmt.Sprintf("%d-%s", time.Now().UnixNano(), name)
+	path := filepath.Join(dir, name)
+
+	dst, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("create upload file: %w", err)
+	}
+	defer dst.Close()
+
+	n, err := io.Copy(dst, src)
+	if err != nil {
+		return nil, fmt.Errorf("write upload: %w", err)
+	}
+
+	return &chatFile{
+		Name: fh.Filename,
+		Path: path,
+		Size: n,
+		Type: fh.Header.Get("Content-Type"),
+	}, nil
+}
+
+// attachmentPrompt appends references to uploaded files so the agent can
+// open them with its file tools.
+func attachmentPrompt(msg string, files []chatFile) string {
+	if len(files) == 0 {
+		return msg
+	}
+	var sb strings.Builder
+	sb.WriteString(msg)
+	sb.WriteString("\n\n[Attached files]\n")
+	for _, f := range files {
+		fmt.Fprintf(&sb, "- %s (%s, %d bytes", f.Path, f.Name, f.Size)
+		if f.Type != "" {
+			fmt.Fprintf(&sb, ", %s", f.Type)
+		}
+		sb.WriteString(")\n")
+	}
+	return sb.String()
+}
+
+// producedFiles lists the files this request's tools wrote into the media
+// library, excluding the request's own uploads. URLs are signed links to
+// the dashboard media server.
+func producedFiles(lib *media.Library, written *tools.WrittenFiles, uploads []chatFile) []chatFile {
+	skip := make(map[string]bool, len(uploads))
+	for _, u := range uploads {
+		skip[u.Path] = true
+	}
+	root, err := filepath.Abs(lib.Dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []chatFile
+	for _, path := range written.Paths() {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) || skip[path] {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, chatFile{
+			Name: info.Name(),
+			Path: path,
+			URL:  lib.URL(path),
+			Size: info.Size(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// serveChat runs one agent turn for a chat request and answers as plain
+// text, JSON, or Server-Sent Events, depending on the Accept header.
+// Requests without a session use defaultSession.
+func serveChat(ctx context.Context, w http.ResponseWriter, r *http.Request, loop *agent.Loop, lib *media.Library, defaultSession string) {
+	in, err := parseChatInput(r, lib)
+	if err != nil {
+		fmt.Printf("❌ %s upload failed (request %s): %v\n", r.URL.Path, httpmw.RequestIDFrom(r.Context()), err)
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if in.Message == "" {
+		http.Error(w, "missing message parameter", http.StatusBadRequest)
+		return
+	}
+
+	session := in.Session
+	if session == "" {
+		session = defaultSession
+	}
+
+	fmt.Printf("🌐 Chat request in %s: %s (%d attachments)\n", session, in.Message, len(in.Files))
+	prompt := attachmentPrompt(in.Message, in.Files)
+	if in.Cache > 0 {
+		ctx = agent.WithResponseCache(ctx, in.Cache)
+	}
+	// Only files written by this turn's tools are reported; other sessions
+	// share the media library.
+	written := &tools.WrittenFiles{}
+	ctx = tools.WithWrittenFiles(ctx, written)
+
+	if wantsStream(r) {
+		sse, ok := newSSEWriter(w)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+		// Hold the done event until produced files are reported.
+		var done *agent.StreamEvent
+		_, err := loop.ProcessStream(tools.WithWrittenFiles(r.Context(), written), prompt, session, func(evt agent.StreamEvent) {
+			if evt.Type == agent.EventDone {
+				done = &evt
+				return
+			}
+			sse.Send(evt.Type, evt)
+		})
+		if err != nil {
+			fmt.Printf("❌ %s stream failed (request %s): %v\n", r.URL.Path, httpmw.RequestIDFrom(r.Context()), err)
+			sse.Send("error", map[string]string{"error": "internal server error"})
+			return
+		}
+		if produced := producedFiles(lib, written, in.Files); len(produced) > 0 {
+			sse.Send("files", produced)
+		}
+		if done != nil {
+			sse.Send(done.Type, done)
+		}
+		return
+	}
+
+	resp, err := loop.ProcessDirect(ctx, prompt, session)
+	if err != nil {
+		// Avoid leaking internal errors to clients.
+		fmt.Printf("❌ %s failed (request %s): %v\n", r.URL.Path, httpmw.RequestIDFrom(r.Context()), err)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	produced := producedFiles(lib, written, in.Files)
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"session":     session,
+			"response":    resp,
+			"attachments": in.Files,
+			"files":       produced,
+		})
+		return
+	}
+
+	_, _ = fmt.Fprint(w, resp)
+	if len(produced) > 0 {
+		_, _ = fmt.Fprint(w, "\n\nFiles:\n")
+		for _, f := range produced {
+			_, _ = fmt.Fprintf(w, "- %s\n", f.URL)
+		}
+	}
+}
+
+// wantsJSON reports whether the client asked for a JSON response.
+func wantsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// wantsStream reports whether the client asked for Server-Sent Events.
+func wantsStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// sseWriter writes Server-Sent Events. It is safe for concurrent use.
+type sseWriter struct {
+	w  http.ResponseWriter
+	f  http.Flusher
+	mu sync.Mutex
+}
+
+// newSSEWriter prepares w for streaming. It returns false if the
+// ResponseWriter cannot flush.
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+	return &sseWriter{w: w, f: f}, true
+}
+
+// Send writes one event with a JSON-encoded data payload.
+func (s *sseWriter) Send(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	s.f.Flush()
+}
+
+// Data writes an unnamed event, as OpenAI-style streams expect.
+func (s *sseWriter) Data(data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "data: %s\n\n", payload)
+	s.f.Flush()
+}
+
+// Done writes the OpenAI stream terminator.
+func (s *sseWriter) Done() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprint(s.w, "data: [DONE]\n\n")
+	s.f.Flush()
+}
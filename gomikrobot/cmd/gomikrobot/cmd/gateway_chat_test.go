@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamir/gomikrobot/internal/media"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+func TestProducedFilesOnlyListsThisTurnsFiles(t *testing.T) {
+	workspace := t.TempDir()
+	lib := media.NewLibrary(filepath.Join(workspace, "media"))
+
+	// Another session writes into the shared library during the turn.
+	other := filepath.Join(lib.Dir, "documents", "other-session.txt")
+	if err := os.MkdirAll(filepath.Dir(other), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte("private"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	written := &tools.WrittenFiles{}
+	ctx := tools.WithWrittenFiles(context.Background(), written)
+	write := tools.NewWriteFileTool()
+	mine := filepath.Join(lib.Dir, "documents", "report.md")
+	for _, path := range []string{mine, filepath.Join(workspace, "notes.md")} {
+		if _, err := write.Execute(ctx, map[string]any{"path": path, "content": "# Report"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := producedFiles(lib, written, nil)
+	if len(got) != 1 || got[0].Path != mine || got[0].Name != "report.md" || got[0].URL == "" {
+		t.Fatalf("produced %+v, want only %s", got, mine)
+	}
+	if got := producedFiles(lib, written, []chatFile{{Path: mine}}); len(got) != 0 {
+		t.Errorf("uploads were reported as produced: %+v", got)
+	}
+}
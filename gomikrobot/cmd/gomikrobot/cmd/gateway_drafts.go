@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// draftsHandler serves /api/v1/drafts. GET returns the pending drafts and
+// the sessions in review mode; POST takes {session, review} to turn review
+// before send on or off for a session.
+func draftsHandler(loop *agent.Loop) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodPost {
+			var body struct {
+				Session string `json:"session"`
+				Review  bool   `json:"review"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil || !strings.Contains(body.Session, ":") {
+				http.Error(w, "invalid body: session must be a session key", http.StatusBadRequest)
+				return
+			}
+			if err := loop.SetReview(body.Session, body.Review); err != nil {
+				fmt.Printf("❌ /api/v1/drafts POST failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+			fmt.Printf("📝 Session %s review = %v\n", body.Session, body.Review)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+			return
+		}
+
+		drafts, err := loop.PendingDrafts()
+		if err != nil {
+			fmt.Printf("❌ /api/v1/drafts failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		sessions, err := loop.ReviewSessions()
+		if err != nil {
+			fmt.Printf("❌ /api/v1/drafts failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if drafts == nil {
+			drafts = []timeline.Draft{}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"drafts": drafts, "review": sessions})
+	}
+}
+
+// draftHandler serves POST /api/v1/drafts/{id} with {action, content}:
+// approve sends the draft, edit sends content instead, reject drops it.
+func draftHandler(loop *agent.Loop) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		var body struct {
+			Action  string `json:"action"`
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		switch body.Action {
+		case agent.DraftApprove, agent.DraftReject:
+		case agent.DraftEdit:
+			if strings.TrimSpace(body.Content) == "" {
+				http.Error(w, "edit needs content", http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "action must be approve, edit or reject", http.StatusBadRequest)
+			return
+		}
+
+		d, err := loop.ReviewDraft(id, body.Action, body.Content)
+		switch {
+		case errors.Is(err, timeline.ErrDraftNotFound):
+			http.Error(w, "draft not found", http.StatusNotFound)
+			return
+		case errors.Is(err, agent.ErrDraftDecided):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			fmt.Printf("❌ /api/v1/drafts/{id} failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		fmt.Printf("📝 Draft #%d %s\n", d.ID, d.Status)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(d)
+	}
+}
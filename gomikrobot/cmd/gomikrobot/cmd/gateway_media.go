@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/media"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// mediaEvent is a timeline event with signed links to its media file.
+type mediaEvent struct {
+	timeline.TimelineEvent
+	MediaURL string `json:"media_url,omitempty"`
+	ThumbURL string `json:"thumb_url,omitempty"`
+}
+
+func withMediaURLs(lib *media.Library, events []timeline.TimelineEvent) []mediaEvent {
+	out := make([]mediaEvent, len(events))
+	for i, e := range events {
+		out[i] = mediaEvent{TimelineEvent: e}
+		if e.MediaPath != "" {
+			out[i].MediaURL = lib.URL(e.MediaPath)
+			out[i].ThumbURL = lib.ThumbURL(e.MediaPath)
+		}
+	}
+	return out
+}
+
+// galleryItem is a media file with the timeline events that refer to it.
+type galleryItem struct {
+	media.Item
+	Events []timeline.TimelineEvent `json:"events"`
+}
+
+// mediaLinkLimit bounds the timeline events searched for media links.
+const mediaLinkLimit = 5000
+
+// mediaHandler lists media files for the dashboard gallery. Query
+// parameters: kind (image, audio, video, document), limit, offset.
+func mediaHandler(lib *media.Library, timeSvc *timeline.TimelineService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		kind := r.URL.Query().Get("kind")
+		switch kind {
+		case "", media.KindImage, media.KindAudio, media.KindVideo, media.KindDocument:
+		default:
+			http.Error(w, "kind must be image, audio, video or document", http.StatusBadRequest)
+			return
+		}
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if limit <= 0 || limit > 500 {
+			limit = 60
+		}
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		items, err := lib.List(kind)
+		if err != nil {
+			fmt.Printf("❌ /api/v1/media failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		total := len(items)
+		items = items[min(offset, total):min(offset+limit, total)]
+		lib.Describe(items)
+
+		events, err := timeSvc.GetEvents(timeline.FilterArgs{HasMedia: true, Limit: mediaLinkLimit})
+		if err != nil {
+			fmt.Printf("❌ /api/v1/media failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		byPath := make(map[string][]timeline.TimelineEvent)
+		for _, e := range events {
+			if rel, ok := lib.Rel(e.MediaPath); ok {
+				byPath[rel] = append(byPath[rel], e)
+			}
+		}
+
+		out := make([]galleryItem, len(items))
+		for i, it := range items {
+			out[i] = galleryItem{Item: it, Events: byPath[it.Path]}
+			if out[i].Events == nil {
+				out[i].Events = []timeline.TimelineEvent{}
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"items": out, "total": total})
+	}
+}
@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// openAIModelID is the model name advertised to OpenAI-compatible clients.
+const openAIModelID = "gomikrobot"
+
+// openAIMessage is a chat message of an OpenAI-compatible request.
+type openAIMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// openAIChatRequest is the subset of the chat completions request we use.
+type openAIChatRequest struct {
+	Model         string          `json:"model"`
+	Messages      []openAIMessage `json:"messages"`
+	Stream        bool            `json:"stream"`
+	User          string          `json:"user"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+	// Sampling parameters are passed through to the agent's LLM calls.
+	Temperature         *float64 `json:"temperature"`
+	TopP                float64  `json:"top_p"`
+	MaxTokens           int      `json:"max_tokens"`
+	MaxCompletionTokens int      `json:"max_completion_tokens"`
+	ReasoningEffort     string   `json:"reasoning_effort"`
+}
+
+// sampling returns the request's sampling overrides.
+func (r *openAIChatRequest) sampling() tools.Sampling {
+	s := tools.Sampling{Temperature: r.Temperature, TopP: r.TopP, MaxTokens: r.MaxTokens, ReasoningEffort: r.ReasoningEffort}
+	if r.MaxCompletionTokens > 0 {
+		s.MaxTokens = r.MaxCompletionTokens
+	}
+	return s
+}
+
+// registerOpenAIRoutes exposes the agent as an OpenAI-compatible model at
+// /v1/models and /v1/chat/completions. Clients authenticate with the
+// gateway API token as their API key.
+//
+// The agent keeps its own history, so only the last user message is sent to
+// it. Conversations map to sessions via the X-Session-Id header or the
+// "user" field. Without either, the session is named after a hash of the
+// whole conversation and holds only the earlier turns the client sent, so
+// clients that happen to open alike never see each other's history.
+func registerOpenAIRoutes(mux *http.ServeMux, loop *agent.Loop, apiToken string) {
+	authorized := func(w http.ResponseWriter, r *http.Request) bool {
+		if httpmw.TokenOK(r, apiToken) {
+			return true
+		}
+		writeOpenAIError(w, http.StatusUnauthorized, "invalid_api_key", "invalid API key")
+		return false
+	}
+
+	mux.HandleFunc("/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		if !authorized(w, r) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"object": "list",
+			"data": []map[string]any{
+				{"id": openAIModelID, "object": "model", "created": 0, "owned_by": "gomikrobot"},
+			},
+		})
+	})
+
+	mux.HandleFunc("/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeOpenAIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+			return
+		}
+		if !authorized(w, r) {
+			return
+		}
+
+		var req openAIChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON body")
+			return
+		}
+
+		sampling := req.sampling()
+		if err := sampling.Validate(); err != nil {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+			return
+		}
+		ctx := agent.WithSampling(r.Context(), sampling)
+
+		lastUser := -1
+		for i, m := range req.Messages {
+			if m.Role == "user" {
+				lastUser = i
+			}
+		}
+		var last string
+		if lastUser >= 0 {
+			last = messageText(req.Messages[lastUser].Content)
+		}
+		if last == "" {
+			writeOpenAIError(w, http.StatusBadRequest, "invalid_request_error", "no user message")
+			return
+		}
+
+		session := r.Header.Get("X-Session-Id")
+		if session == "" {
+			session = req.User
+		}
+		if session != "" {
+			session = "openai:" + session
+		} else {
+			session = "openai:" + conversationKey(req.Messages[:lastUser+1])
+			seedSession(loop, session, req.Messages[:lastUser])
+		}
+
+		model := req.Model
+		if model == "" {
+			model = openAIModelID
+		}
+		id := fmt.Sprintf("chatcmpl-%d", time.Now().UnixNano())
+		created := time.Now().Unix()
+
+		fmt.Printf("🌐 OpenAI-compatible request (%s): %s\n", session, shorten(last, 80))
+
+		if !req.Stream {
+			var usage provider.Usage
+			resp, err := loop.ProcessStream(ctx, last, session, func(evt agent.StreamEvent) {
+				if evt.Type == agent.EventDone && evt.Usage != nil {
+					usage = *evt.Usage
+				}
+			})
+			if err != nil {
+				fmt.Printf("❌ /v1/chat/completions failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+				writeOpenAIError(w, http.StatusInternalServerError, "server_error", "internal server error")
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"id":      id,
+				"object":  "chat.completion",
+				"created": created,
+				"model":   model,
+				"choices": []map[string]any{{
+					"index":         0,
+					"message":       map[string]string{"role": "assistant", "content": resp},
+					"finish_reason": "stop",
+				}},
+				"usage": usage,
+			})
+			return
+		}
+
+		sse, ok := newSSEWriter(w)
+		if !ok {
+			writeOpenAIError(w, http.StatusInternalServerError, "server_error", "streaming not supported")
+			return
+		}
+		chunk := func(delta map[string]string, finish any) map[string]any {
+			return map[string]any{
+				"id":      id,
+				"object":  "chat.completion.chunk",
+				"created": created,
+				"model":   model,
+				"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+			}
+		}
+
+		sse.Data(chunk(map[string]string{"role": "assistant"}, nil))
+		var usage *provider.Usage
+		_, err := loop.ProcessStream(ctx, last, session, func(evt agent.StreamEvent) {
+			switch evt.Type {
+			case agent.EventDelta:
+				sse.Data(chunk(map[string]string{"content": evt.Content}, nil))
+			case agent.EventDone:
+				usage = evt.Usage
+			}
+		})
+		if err != nil {
+			fmt.Printf("❌ /v1/chat/completions stream failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			sse.Data(map[string]any{"error": map[string]string{"message": "internal server error", "type": "server_error"}})
+			sse.Done()
+			return
+		}
+		sse.Data(chunk(map[string]string{}, "stop"))
+		if req.StreamOptions != nil && req.StreamOptions.IncludeUsage && usage != nil {
+			sse.Data(map[string]any{
+				"id":      id,
+				"object":  "chat.completion.chunk",
+				"created": created,
+				"model":   model,
+				"choices": []any{},
+				"usage":   usage,
+			})
+		}
+		sse.Done()
+	})
+}
+
+// conversationKey hashes the messages of a conversation, so only requests
+// continuing the same conversation share a session.
+func conversationKey(msgs []openAIMessage) string {
+	h := sha256.New()
+	for _, m := range msgs {
+		h.Write([]byte(m.Role))
+		h.Write([]byte{0})
+		h.Write([]byte(messageText(m.Content)))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:12])
+}
+
+// seedSession replaces the history of session key with the user and
+// assistant turns the client sent, so the agent sees exactly the
+// conversation it is asked to continue.
+func seedSession(loop *agent.Loop, key string, msgs []openAIMessage) {
+	loop.Sessions().Delete(key)
+	sess := loop.Sessions().GetOrCreate(key)
+	for _, m := range msgs {
+		if text := messageText(m.Content); text != "" && (m.Role == "user" || m.Role == "assistant") {
+			sess.AddMessage(m.Role, text)
+		}
+	}
+	_ = loop.Sessions().Save(sess)
+}
+
+// messageText extracts text from a string or content-part array.
+func messageText(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return ""
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func writeOpenAIError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{"message": msg, "type": code},
+	})
+}
+
+func shorten(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+// echoProvider answers every request with a numbered reply and records
+// the messages it was sent.
+type echoProvider struct {
+	mu   sync.Mutex
+	reqs [][]provider.Message
+}
+
+func (p *echoProvider) Chat(ctx context.Context, req *provider.ChatRequest) (*provider.ChatResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reqs = append(p.reqs, req.Messages)
+	return &provider.ChatResponse{
+		Content: fmt.Sprintf("reply %d", len(p.reqs)),
+		Usage:   provider.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+	}, nil
+}
+
+func (p *echoProvider) ChatStream(ctx context.Context, req *provider.ChatRequest, onDelta func(string)) (*provider.ChatResponse, error) {
+	resp, err := p.Chat(ctx, req)
+	if err == nil {
+		onDelta("reply ")
+		onDelta(strings.TrimPrefix(resp.Content, "reply "))
+	}
+	return resp, err
+}
+
+func (p *echoProvider) Transcribe(context.Context, *provider.AudioRequest) (*provider.AudioResponse, error) {
+	return nil, nil
+}
+func (p *echoProvider) Speak(context.Context, *provider.TTSRequest) (*provider.TTSResponse, error) {
+	return nil, nil
+}
+func (p *echoProvider) DefaultModel() string { return "test-model" }
+
+// sent returns the user and assistant messages of provider request i.
+func (p *echoProvider) sent(i int) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var out []string
+	for _, m := range p.reqs[i] {
+		if m.Role == "user" || m.Role == "assistant" {
+			out = append(out, m.Role+": "+m.Content)
+		}
+	}
+	return out
+}
+
+func newOpenAITestServer(t *testing.T, token string) (*httptest.Server, *agent.Loop, *echoProvider) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	prov := &echoProvider{}
+	loop := agent.NewLoop(agent.LoopOptions{Provider: prov, Workspace: t.TempDir()})
+	mux := http.NewServeMux()
+	registerOpenAIRoutes(mux, loop, token)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, loop, prov
+}
+
+func openAIRequest(t *testing.T, srv *httptest.Server, token, session, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if session != "" {
+		req.Header.Set("X-Session-Id", session)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestOpenAIRoutesRequireTheAPIToken(t *testing.T) {
+	srv, _, prov := newOpenAITestServer(t, "gateway-token")
+	body := `{"messages":[{"role":"user","content":"hi"}]}`
+	for _, token := range []string{"", "wrong"} {
+		if resp := openAIRequest(t, srv, token, "", body); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("token %q: got %d, want 401", token, resp.StatusCode)
+		}
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/models", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("models with token %q: got %d, want 401", token, resp.StatusCode)
+		}
+	}
+	if len(prov.reqs) != 0 {
+		t.Errorf("unauthorized requests reached the model")
+	}
+	if resp := openAIRequest(t, srv, "gateway-token", "", body); resp.StatusCode != http.StatusOK {
+		t.Errorf("valid token: got %d", resp.StatusCode)
+	}
+}
+
+func TestOpenAIChatCompletion(t *testing.T) {
+	srv, _, _ := newOpenAITestServer(t, "")
+	resp := openAIRequest(t, srv, "", "", `{"model":"gomikrobot","messages":[{"role":"user","content":[{"type":"text","text":"hi"}]}]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+	var out struct {
+		Object  string `json:"object"`
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage provider.Usage `json:"usage"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Object != "chat.completion" || out.Model != "gomikrobot" || len(out.Choices) != 1 {
+		t.Fatalf("unexpected response %+v", out)
+	}
+	if c := out.Choices[0]; c.Message.Role != "assistant" || c.Message.Content != "reply 1" || c.FinishReason != "stop" {
+		t.Errorf("unexpected choice %+v", c)
+	}
+	if out.Usage.CompletionTokens != 2 {
+		t.Errorf("usage = %+v", out.Usage)
+	}
+
+	if resp := openAIRequest(t, srv, "", "", `{"messages":[{"role":"system","content":"be brief"}]}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("request without a user message: got %d", resp.StatusCode)
+	}
+}
+
+func TestOpenAIChatCompletionStream(t *testing.T) {
+	srv, _, _ := newOpenAITestServer(t, "")
+	resp := openAIRequest(t, srv, "", "", `{"stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hi"}]}`)
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	var content strings.Builder
+	var finished, usage, done bool
+	for _, line := range strings.Split(string(data), "\n") {
+		payload, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if payload == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk struct {
+			Object  string `json:"object"`
+			Choices []struct {
+				Delta        map[string]string `json:"delta"`
+				FinishReason *string           `json:"finish_reason"`
+			} `json:"choices"`
+			Usage *provider.Usage `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil || chunk.Object != "chat.completion.chunk" {
+			t.Fatalf("bad chunk %s", payload)
+		}
+		for _, c := range chunk.Choices {
+			content.WriteString(c.Delta["content"])
+			finished = finished || (c.FinishReason != nil && *c.FinishReason == "stop")
+		}
+		usage = usage || chunk.Usage != nil
+	}
+	if content.String() != "reply 1" || !finished || !usage || !done {
+		t.Errorf("stream gave %q, finished %v, usage %v, done %v:\n%s", content.String(), finished, usage, done, data)
+	}
+}
+
+func TestOpenAISessionMapping(t *testing.T) {
+	srv, loop, prov := newOpenAITestServer(t, "")
+	hi := `{"messages":[{"role":"user","content":"hi"}]}`
+
+	// Explicit sessions keep the agent's history.
+	openAIRequest(t, srv, "", "conv-1", hi)
+	openAIRequest(t, srv, "", "conv-1", `{"messages":[{"role":"user","content":"and then?"}]}`)
+	if got := prov.sent(1); strings.Join(got, "|") != "user: hi|assistant: reply 1|user: and then?" {
+		t.Errorf("explicit session sent %q", got)
+	}
+	if len(loop.Sessions().GetOrCreate("openai:conv-1").GetHistory(10)) != 4 {
+		t.Error("X-Session-Id did not name the session")
+	}
+	openAIRequest(t, srv, "", "", `{"user":"bob","messages":[{"role":"user","content":"hi"}]}`)
+	if got := prov.sent(2); len(got) != 1 {
+		t.Errorf("user field shared another session: %q", got)
+	}
+
+	// Two clients opening alike do not see each other's turns.
+	openAIRequest(t, srv, "", "", hi)
+	openAIRequest(t, srv, "", "", hi)
+	if got := prov.sent(4); strings.Join(got, "|") != "user: hi" {
+		t.Errorf("second client saw %q", got)
+	}
+
+	// A client continuing its conversation gets the turns it sent.
+	openAIRequest(t, srv, "", "", `{"messages":[{"role":"user","content":"hi"},{"role":"assistant","content":"hello, Ann"},{"role":"user","content":"my name?"}]}`)
+	if got := prov.sent(5); strings.Join(got, "|") != "user: hi|assistant: hello, Ann|user: my name?" {
+		t.Errorf("continued conversation sent %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// heldMessageScheduler delivers messages held back during quiet hours once
+// the hours end.
+type heldMessageScheduler struct {
+	timeline *timeline.TimelineService
+	bus      *bus.MessageBus
+}
+
+// Run releases due messages until ctx is cancelled, checking as often as
+// reminders.
+func (s *heldMessageScheduler) Run(ctx context.Context) {
+	s.runOnce()
+	ticker := time.NewTicker(reminderInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce()
+		}
+	}
+}
+
+func (s *heldMessageScheduler) runOnce() {
+	due, err := s.timeline.HeldMessages(time.Now())
+	if err != nil {
+		fmt.Printf("⚠️ Held message check failed: %v\n", err)
+		return
+	}
+	for _, m := range due {
+		// Delete first: a message published twice is worse than one lost
+		// to a crash in between.
+		if err := s.timeline.DeleteHeldMessage(m.ID); err != nil {
+			fmt.Printf("⚠️ Failed to release held message %d: %v\n", m.ID, err)
+			continue
+		}
+		s.bus.PublishOutbound(&bus.OutboundMessage{Channel: m.Channel, ChatID: m.ChatID, Content: m.Content, Trace: m.Trace})
+	}
+	if len(due) > 0 {
+		fmt.Printf("🌅 Quiet hours over: released %d held message(s)\n", len(due))
+	}
+}
+
+// quietHandler reads and changes silent mode and quiet hours. GET returns
+// the global settings, per-contact overrides and held messages. POST takes
+// {session, silent, quiet_hours} as described at TimelineService.SetQuiet.
+func quietHandler(timeSvc *timeline.TimelineService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == http.MethodPost {
+			var body struct {
+				Session    string `json:"session"`
+				Silent     string `json:"silent"`
+				QuietHours string `json:"quiet_hours"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, "invalid body", http.StatusBadRequest)
+				return
+			}
+			if err := timeSvc.SetQuiet(body.Session, body.Silent, body.QuietHours); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			fmt.Printf("⚙️ Quiet settings changed for %s: silent=%q quiet_hours=%q\n", cmp.Or(body.Session, "all chats"), body.Silent, body.QuietHours)
+			_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+			return
+		}
+
+		silent, quiet, err := timeSvc.ContactOverrides()
+		if err != nil {
+			fmt.Printf("❌ /api/v1/quiet failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		held, err := timeSvc.HeldMessages(time.Time{})
+		if err != nil {
+			fmt.Printf("❌ /api/v1/quiet failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		hours, _ := timeSvc.GetSetting(timeline.QuietHoursSetting)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"silent_mode": timeSvc.IsSilentMode(),
+			"quiet_hours": hours,
+			"contacts":    map[string]any{"silent_mode": silent, "quiet_hours": quiet},
+			"held":        held,
+		})
+	}
+}
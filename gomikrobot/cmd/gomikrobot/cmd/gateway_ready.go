@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/channels"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/health"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/security"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// readinessChecks builds the checks behind /ready. started is set once the
+// channels have started.
+func readinessChecks(cfg *config.Config, started *atomic.Int32, prov *provider.OpenAIProvider, tl *timeline.TimelineService, wa *channels.WhatsAppChannel, sig *channels.SignalChannel) *health.Checker {
+	rc := cfg.Gateway.Ready
+	checker := &health.Checker{Timeout: rc.Timeout, CacheTTL: rc.CacheTTL}
+
+	checker.Add("startup", health.Flag(func() bool { return started.Load() == 1 }, "channels are still starting"))
+	if !rc.SkipProvider {
+		checker.Add("provider", func(ctx context.Context) error {
+			if err := prov.Ping(ctx); err != nil {
+				// /ready is public; keep keys and response bodies out of it.
+				return errors.New(security.SanitizeError(err))
+			}
+			return nil
+		})
+	}
+	checker.Add("timeline", func(ctx context.Context) error {
+		return tl.SetSetting("health_check", time.Now().Format(time.RFC3339))
+	})
+	if wc := cfg.Channels.WhatsApp; wc.Enabled {
+		checker.Add("whatsapp", health.Flag(wa.Connected, "not connected to WhatsApp"))
+		if wc.BridgeURL != "" {
+			checker.Add("bridge", health.Dial(wc.BridgeURL))
+		}
+	}
+	if cfg.Channels.Signal.Enabled {
+		checker.Add("signal", health.Flag(sig.Connected, "not connected to signal-cli"))
+	}
+	if rc.MinFreeDiskMB > 0 {
+		checker.Add("disk", health.DiskSpace(cfg.Agents.Defaults.Workspace, uint64(rc.MinFreeDiskMB)<<20))
+	}
+	return checker
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/channels"
+	"github.com/kamir/gomikrobot/internal/classify"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/format"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/pricing"
+	"github.com/kamir/gomikrobot/internal/proxy"
+)
+
+// applyGatewayDefaults fills unset gateway hardening values.
+func applyGatewayDefaults(cfg *config.Config) {
+	if cfg.Gateway.MaxBodyBytes <= 0 {
+		cfg.Gateway.MaxBodyBytes = 10 << 20 // 10 MiB
+	}
+	if cfg.Gateway.RateLimitRPS <= 0 {
+		cfg.Gateway.RateLimitRPS = 5
+	}
+	if cfg.Gateway.RateLimitBurst <= 0 {
+		cfg.Gateway.RateLimitBurst = 10
+	}
+	if cfg.Gateway.ShutdownTimeout <= 0 {
+		cfg.Gateway.ShutdownTimeout = 10 * time.Second
+	}
+	if cfg.Gateway.DashboardPort == 0 {
+		cfg.Gateway.DashboardPort = 18791
+	}
+}
+
+// configReloader applies config changes to a running gateway.
+// Only settings that are safe to swap at runtime are applied; the rest
+// are reported as requiring a restart.
+type configReloader struct {
+	ctx  context.Context
+	rl   *httpmw.RateLimiter
+	loop *agent.Loop
+	wa   *channels.WhatsAppChannel
+	// proxy is nil unless the provider proxy is enabled.
+	proxy  *proxy.Proxy
+	hooks  *channels.WebhookChannel
+	bus    *bus.MessageBus
+	prices *pricing.Registry
+	// classifier labels inbound messages; rules and routes are reloadable.
+	classifier *classify.Classifier
+
+	mu  sync.Mutex
+	cur config.Config
+}
+
+func newConfigReloader(ctx context.Context, cfg *config.Config, rl *httpmw.RateLimiter, loop *agent.Loop, wa *channels.WhatsAppChannel) *configReloader {
+	return &configReloader{ctx: ctx, rl: rl, loop: loop, wa: wa, cur: *cfg}
+}
+
+// Apply diffs next against the running config and applies the changes.
+func (r *configReloader) Apply(next *config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	applyGatewayDefaults(next)
+	if issues := config.Validate(next); config.HasErrors(issues) {
+		fmt.Println("⚠️ Config reload rejected:")
+		for _, i := range issues {
+			if i.Level == config.LevelError {
+				fmt.Printf("   - %s\n", i)
+			}
+		}
+		return
+	}
+
+	old := r.cur
+	var applied []string
+
+	if next.Gateway.RateLimitRPS != old.Gateway.RateLimitRPS || next.Gateway.RateLimitBurst != old.Gateway.RateLimitBurst {
+		r.rl.SetLimits(next.Gateway.RateLimitRPS, next.Gateway.RateLimitBurst)
+		applied = append(applied, fmt.Sprintf("rate limit %.1f rps / burst %d", next.Gateway.RateLimitRPS, next.Gateway.RateLimitBurst))
+	}
+	if next.Channels.WhatsApp.Outbound != old.Channels.WhatsApp.Outbound || next.Channels.Slack.Outbound != old.Channels.Slack.Outbound ||
+		next.Channels.Signal.Outbound != old.Channels.Signal.Outbound {
+		applyOutboundPolicies(r.bus, next.Channels, filepath.Join(next.Agents.Defaults.Workspace, "media"))
+		applied = append(applied, "outbound policies")
+	}
+	if !slices.Equal(next.Gateway.TrustedProxies, old.Gateway.TrustedProxies) {
+		if err := httpmw.SetTrustedProxies(next.Gateway.TrustedProxies); err == nil {
+			applied = append(applied, fmt.Sprintf("trusted proxies (%d)", len(next.Gateway.TrustedProxies)))
+		}
+	}
+	if !reflect.DeepEqual(next.Gateway.RateLimitRoutes, old.Gateway.RateLimitRoutes) ||
+		next.Gateway.TokenRateLimit != old.Gateway.TokenRateLimit || next.Gateway.APIToken != old.Gateway.APIToken {
+		applyRateLimitTiers(r.rl, next.Gateway)
+		applied = append(applied, fmt.Sprintf("rate limit tiers (%d routes)", len(next.Gateway.RateLimitRoutes)))
+	}
+
+	if oldS, newS := samplingFromConfig(old.Agents.Defaults), samplingFromConfig(next.Agents.Defaults); !reflect.DeepEqual(oldS, newS) {
+		r.loop.SetSampling(newS)
+		applied = append(applied, "sampling ("+newS.String()+")")
+	}
+	if next.Agents.Defaults.Critic != old.Agents.Defaults.Critic {
+		r.loop.SetCritic(agent.CriticOptions(next.Agents.Defaults.Critic))
+		applied = append(applied, "critic")
+	}
+	if next.Agents.Defaults.Refusals != old.Agents.Defaults.Refusals {
+		r.loop.SetRefusals(agent.RefusalOptions(next.Agents.Defaults.Refusals))
+		applied = append(applied, "refusals")
+	}
+	if next.Agents.Defaults.Model != old.Agents.Defaults.Model {
+		r.loop.SetModel(next.Agents.Defaults.Model)
+		applied = append(applied, "model "+next.Agents.Defaults.Model)
+	}
+
+	if r.proxy != nil && !slices.EqualFunc(old.Proxy.Keys, next.Proxy.Keys, proxyKeyEqual) {
+		r.proxy.SetKeys(next.Proxy.Keys)
+		applied = append(applied, fmt.Sprintf("proxy keys (%d)", len(next.Proxy.Keys)))
+	}
+
+	if r.hooks != nil && !slices.Equal(old.Channels.Webhooks, next.Channels.Webhooks) {
+		if err := r.hooks.SetHooks(next.Channels.Webhooks); err != nil {
+			fmt.Printf("⚠️ Webhooks not reloaded: %v\n", err)
+		} else {
+			applied = append(applied, fmt.Sprintf("webhooks (%d)", len(next.Channels.Webhooks)))
+		}
+	}
+
+	if r.prices != nil && !reflect.DeepEqual(old.Pricing.Models, next.Pricing.Models) {
+		r.prices.SetOverrides(modelPrices(next.Pricing))
+		applied = append(applied, fmt.Sprintf("model prices (%d overrides)", len(next.Pricing.Models)))
+	}
+
+	if r.classifier != nil && !reflect.DeepEqual(old.Classification, next.Classification) {
+		if err := r.classifier.SetConfig(next.Classification); err != nil {
+			fmt.Printf("⚠️ Classification not reloaded: %v\n", err)
+		} else {
+			applied = append(applied, fmt.Sprintf("classification (%d rules)", len(next.Classification.Rules)))
+		}
+	}
+
+	oldWA, newWA := old.Channels.WhatsApp, next.Channels.WhatsApp
+	if !reflect.DeepEqual(oldWA, newWA) {
+		r.wa.SetConfig(newWA)
+		if !slices.Equal(oldWA.AllowFrom, newWA.AllowFrom) {
+			applied = append(applied, fmt.Sprintf("whatsapp allowFrom (%d entries)", len(newWA.AllowFrom)))
+		}
+		if !reflect.DeepEqual(oldWA.Groups, newWA.Groups) {
+			applied = append(applied, "whatsapp groups")
+		}
+	}
+	if newWA.Enabled && !r.wa.Running() {
+		// Start may block on QR pairing; don't hold up reloads.
+		go func() {
+			if err := r.wa.Start(r.ctx); err != nil {
+				fmt.Printf("Failed to start WhatsApp: %v\n", err)
+			}
+		}()
+		applied = append(applied, "whatsapp enabled")
+	} else if !newWA.Enabled && r.wa.Running() {
+		r.wa.Stop()
+		applied = append(applied, "whatsapp disabled")
+	}
+
+	if next.Gateway.Host != old.Gateway.Host || next.Gateway.Port != old.Gateway.Port ||
+		next.Gateway.DashboardPort != old.Gateway.DashboardPort || next.Gateway.APIToken != old.Gateway.APIToken ||
+		next.Gateway.DashboardPassword != old.Gateway.DashboardPassword || !slices.Equal(next.Gateway.CORSOrigins, old.Gateway.CORSOrigins) ||
+		next.Gateway.WebDir != old.Gateway.WebDir || !reflect.DeepEqual(next.Gateway.TLS, old.Gateway.TLS) ||
+		next.Gateway.MaxBodyBytes != old.Gateway.MaxBodyBytes || next.Agents.Defaults.Workspace != old.Agents.Defaults.Workspace ||
+		next.Audit != old.Audit || next.Pricing.MonthlyBudget != old.Pricing.MonthlyBudget ||
+		next.BudgetAlertSession() != old.BudgetAlertSession() || !reflect.DeepEqual(next.Feeds, old.Feeds) ||
+		!reflect.DeepEqual(next.Tenants, old.Tenants) {
+		fmt.Println("⚠️ Config reload: gateway address, credentials, CORS origins, web dir, TLS, body limit, workspace, audit, budget alert, feed, and tenant changes need a restart")
+	}
+
+	r.cur = *next
+	if len(applied) == 0 {
+		fmt.Println("🔄 Config reloaded (no runtime changes)")
+		return
+	}
+	fmt.Printf("🔄 Config reloaded: %v\n", applied)
+}
+
+func proxyKeyEqual(a, b config.ProxyKey) bool {
+	return a.Name == b.Name && a.Key == b.Key && a.MonthlyTokenLimit == b.MonthlyTokenLimit &&
+		slices.Equal(a.AllowedModels, b.AllowedModels)
+}
+
+// applyRateLimitTiers configures the per-route and per-token limits.
+func applyRateLimitTiers(rl *httpmw.RateLimiter, g config.GatewayConfig) {
+	routes := make(map[string]httpmw.Limit, len(g.RateLimitRoutes))
+	for prefix, l := range g.RateLimitRoutes {
+		routes[prefix] = httpmw.Limit{RPS: l.RPS, Burst: l.Burst}
+	}
+	rl.SetRouteLimits(routes)
+
+	tokens := map[string]httpmw.Limit{}
+	if g.APIToken != "" {
+		tokens[g.APIToken] = httpmw.Limit{RPS: g.TokenRateLimit.RPS, Burst: g.TokenRateLimit.Burst}
+	}
+	rl.SetTokenLimits(tokens)
+}
+
+// applyOutboundPolicies configures per-channel outbound throttling and
+// formatting. Long code blocks are written to mediaDir/outbound and sent as
+// files.
+func applyOutboundPolicies(b *bus.MessageBus, ch config.ChannelsConfig, mediaDir string) {
+	for name, o := range map[string]config.OutboundPolicy{"whatsapp": ch.WhatsApp.Outbound, "slack": ch.Slack.Outbound, "signal": ch.Signal.Outbound} {
+		style := format.StyleFor(name)
+		if s, err := format.Parse(o.Format); err == nil {
+			style = s
+		}
+		p := bus.OutboundPolicy{
+			Rate:      o.RatePerSecond,
+			Burst:     o.Burst,
+			MaxChars:  o.MaxChars,
+			Retries:   o.Retries,
+			QueueSize: o.QueueSize,
+			Render:    func(s string) string { return format.Convert(s, style) },
+		}
+		if o.CodeFileChars > 0 {
+			limit, dir := o.CodeFileChars, filepath.Join(mediaDir, "outbound")
+			p.Prepare = func(m *bus.OutboundMessage) {
+				content, files, err := format.ExtractCode(m.Content, limit, dir)
+				if err != nil {
+					fmt.Printf("⚠️ Keeping code inline for %s: %v\n", name, err)
+					return
+				}
+				m.Content, m.Media = content, append(m.Media, files...)
+			}
+		}
+		b.SetOutboundPolicy(name, p)
+	}
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// reminderInterval is how often due reminders are checked.
+const reminderInterval = 30 * time.Second
+
+// reminderScheduler delivers reminders created with remind_me to the chat
+// they were set in.
+type reminderScheduler struct {
+	timeline *timeline.TimelineService
+	bus      *bus.MessageBus
+}
+
+// Run sends due reminders until ctx is cancelled. Reminders that fell due
+// while the gateway was down are sent on startup.
+func (s *reminderScheduler) Run(ctx context.Context) {
+	s.runOnce()
+	ticker := time.NewTicker(reminderInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runOnce()
+		}
+	}
+}
+
+func (s *reminderScheduler) runOnce() {
+	due, err := s.timeline.DueReminders(time.Now())
+	if err != nil {
+		fmt.Printf("⚠️ Reminder check failed: %v\n", err)
+		return
+	}
+	for _, r := range due {
+		content := "⏰ Reminder: " + r.Text
+		if late := time.Since(r.DueAt); late > 10*time.Minute {
+			content += fmt.Sprintf(" (due %s)", r.DueAt.Format("Mon 15:04"))
+		}
+		s.bus.PublishOutbound(&bus.OutboundMessage{Channel: r.Channel, ChatID: r.ChatID, Content: content})
+		if err := s.timeline.MarkReminderSent(r.ID, time.Now()); err != nil {
+			fmt.Printf("⚠️ Failed to mark reminder %d as sent: %v\n", r.ID, err)
+		}
+	}
+}
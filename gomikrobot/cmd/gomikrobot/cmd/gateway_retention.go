@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/metrics"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+var timelinePruned = metrics.Default.Counter("gomikrobot_timeline_pruned_total", "Timeline events removed by retention.")
+
+// timelineRetention periodically archives and removes old timeline events.
+type timelineRetention struct {
+	timeline  *timeline.TimelineService
+	cfg       config.TimelineConfig
+	workspace string
+}
+
+// Run prunes on every interval until ctx is cancelled. It does nothing when
+// no retention is configured.
+func (r *timelineRetention) Run(ctx context.Context) {
+	if r.cfg.RetentionDays <= 0 {
+		return
+	}
+	interval := r.cfg.Interval
+	if interval <= 0 {
+		interval = 24 * time.Hour
+	}
+	r.runOnce()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.runOnce()
+		}
+	}
+}
+
+func (r *timelineRetention) runOnce() {
+	before := time.Now().AddDate(0, 0, -r.cfg.RetentionDays)
+	res, err := r.timeline.Prune(before, timelineArchiveDir(r.cfg, r.workspace))
+	if err != nil {
+		fmt.Printf("⚠️ Timeline retention failed: %v\n", err)
+		return
+	}
+	timelinePruned.Add(float64(res.Removed))
+	if res.Removed > 0 {
+		fmt.Printf("🧹 Timeline retention: removed %d events before %s (archive: %s)\n", res.Removed, before.Format("2006-01-02"), res.Archive)
+	}
+}
+
+// timelineArchiveDir returns where pruned events are archived, or "" if
+// archiving is disabled.
+func timelineArchiveDir(cfg config.TimelineConfig, workspace string) string {
+	if !cfg.Archive {
+		return ""
+	}
+	return filepath.Join(workspace, "archive", "timeline")
+}
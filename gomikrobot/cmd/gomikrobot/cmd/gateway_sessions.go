@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/metrics"
+	"github.com/kamir/gomikrobot/internal/session"
+)
+
+var (
+	sessionsGauge      = metrics.Default.Gauge("gomikrobot_sessions", "Session files on disk.")
+	sessionsBytesGauge = metrics.Default.Gauge("gomikrobot_sessions_bytes", "Total size of session files in bytes.")
+	sessionsPruned     = metrics.Default.Counter("gomikrobot_sessions_pruned_total", "Sessions removed by garbage collection.")
+	sessionsReclaimed  = metrics.Default.Counter("gomikrobot_sessions_reclaimed_bytes_total", "Bytes reclaimed by session garbage collection.")
+)
+
+// sessionGC periodically removes stale sessions and records the outcome.
+type sessionGC struct {
+	sessions *session.Manager
+	maxAge   time.Duration
+
+	mu      sync.RWMutex
+	lastRun *session.PruneResult
+}
+
+// Run prunes on every interval until ctx is cancelled. With maxAge <= 0 it
+// only refreshes the size metrics.
+func (g *sessionGC) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	g.runOnce()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.runOnce()
+		}
+	}
+}
+
+func (g *sessionGC) runOnce() {
+	if g.maxAge > 0 {
+		res, err := g.sessions.Prune(g.maxAge)
+		if err != nil {
+			fmt.Printf("⚠️ Session GC failed: %v\n", err)
+		} else {
+			sessionsPruned.Add(float64(res.Removed))
+			sessionsReclaimed.Add(float64(res.ReclaimedBytes))
+			if res.Removed > 0 {
+				fmt.Printf("🧹 Session GC: removed %d sessions (%d bytes), %d remaining\n", res.Removed, res.ReclaimedBytes, res.Remaining)
+			}
+			g.mu.Lock()
+			g.lastRun = &res
+			g.mu.Unlock()
+		}
+	}
+
+	st := g.sessions.Stats()
+	sessionsGauge.Set(float64(st.Count))
+	sessionsBytesGauge.Set(float64(st.Bytes))
+}
+
+// Stats returns the current session counts and the last GC result.
+func (g *sessionGC) Stats() map[string]any {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return map[string]any{
+		"sessions":              g.sessions.Stats(),
+		"retention_days":        int(g.maxAge / (24 * time.Hour)),
+		"last_gc":               g.lastRun,
+		"pruned_total":          sessionsPruned.Value(),
+		"reclaimed_bytes_total": sessionsReclaimed.Value(),
+	}
+}
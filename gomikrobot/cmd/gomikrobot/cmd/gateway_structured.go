@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// structuredRequest is the body of POST /structured.
+type structuredRequest struct {
+	Prompt string              `json:"prompt"`
+	Schema provider.JSONSchema `json:"schema"`
+	Model  string              `json:"model,omitempty"`
+	// Session, if set, records usage under "structured:<session>".
+	Session string `json:"session,omitempty"`
+}
+
+// structuredHandler serves POST /structured: the prompt is answered with
+// JSON matching the schema, returned as the response body. Replies that
+// still do not match after one correction get 422.
+func structuredHandler(ctx context.Context, loop *agent.Loop, apiToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !httpmw.TokenOK(r, apiToken) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req structuredRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Prompt) == "" {
+			http.Error(w, "invalid body: prompt and schema are required", http.StatusBadRequest)
+			return
+		}
+		if err := req.Schema.Check(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reqCtx := ctx
+		if req.Model != "" {
+			reqCtx = agent.WithModel(reqCtx, req.Model)
+		}
+		if req.Session != "" {
+			reqCtx = tools.WithSessionKey(reqCtx, "structured:"+req.Session)
+		}
+		out, err := loop.ProcessStructured(reqCtx, req.Prompt, req.Schema)
+		if errors.Is(err, agent.ErrInvalidStructured) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if err != nil {
+			fmt.Printf("❌ /structured failed (request %s): %v\n", httpmw.RequestIDFrom(r.Context()), err)
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(out)
+	}
+}
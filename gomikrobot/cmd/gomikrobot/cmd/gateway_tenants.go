@@ -0,0 +1,263 @@
+package cmd
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/audit"
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/channels"
+	"github.com/kamir/gomikrobot/internal/classify"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/media"
+	"github.com/kamir/gomikrobot/internal/metrics"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/session"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+var (
+	tenantSessions = metrics.Default.Gauge("gomikrobot_tenant_sessions", "Session files per tenant.")
+	tenantRequests = metrics.Default.Gauge("gomikrobot_tenant_requests_month", "Agent requests this month per tenant.")
+	tenantTokens   = metrics.Default.Gauge("gomikrobot_tenant_tokens_month", "Prompt and completion tokens this month per tenant.")
+)
+
+// mainTenant is the metrics label of the bot configured at the top level.
+const mainTenant = "default"
+
+// tenant is a bot hosted next to the main one, with its own bus, timeline,
+// sessions, workspace, and channels.
+type tenant struct {
+	name      string
+	token     string
+	workspace string
+	bus       *bus.MessageBus
+	timeline  *timeline.TimelineService
+	loop      *agent.Loop
+	jobs      *tools.JobManager
+	audit     *audit.Log
+	wa        *channels.WhatsAppChannel
+	slack     *channels.SlackChannel
+	sig       *channels.SignalChannel
+	// media serves the tenant's media files on the dashboard server.
+	media *media.Library
+	// api serves the API routes for requests with the tenant's token.
+	api *http.ServeMux
+}
+
+// tenantConfig returns cfg with the tenant's workspace, admins, and
+// channels. Projects and VIPs belong to the main bot and are dropped.
+func tenantConfig(cfg *config.Config, tc config.TenantConfig) *config.Config {
+	tcfg := *cfg
+	tcfg.Agents.Defaults.Workspace = tc.Workspace
+	tcfg.Agents.Admins = tc.Admins
+	tcfg.Agents.ReviewSession = ""
+	tcfg.Agents.Projects = nil
+	tcfg.Agents.VIPs = nil
+	tcfg.Channels = tc.Channels
+	return &tcfg
+}
+
+// startTenant opens the tenant's timeline and sessions and starts its
+// agent loop and channels. Tools that reach shared data (calendars, the
+// knowledge base, remote tools, and run_code, which runs on the host) are
+// not registered for tenants.
+func startTenant(ctx context.Context, cfg *config.Config, tc config.TenantConfig, prov provider.LLMProvider, classifier *classify.Classifier) (*tenant, error) {
+	tcfg := tenantConfig(cfg, tc)
+	if err := os.MkdirAll(tc.Workspace, 0700); err != nil {
+		return nil, fmt.Errorf("create workspace: %w", err)
+	}
+	if err := os.MkdirAll(tc.Dir(), 0700); err != nil {
+		return nil, fmt.Errorf("create data dir: %w", err)
+	}
+	timeSvc, err := timeline.NewTimelineService(filepath.Join(tc.Dir(), "timeline.db"))
+	if err != nil {
+		return nil, fmt.Errorf("open timeline: %w", err)
+	}
+
+	// Tenants audit into their own directory, so one tenant's prompts do
+	// not end up next to another's.
+	auditCfg := cfg.Audit
+	auditCfg.Dir = filepath.Join(tc.Dir(), "audit")
+	auditLog := openAuditLog(auditCfg)
+
+	msgBus := bus.NewMessageBus()
+	msgBus.SetDedupWindow(cfg.Gateway.DedupWindow)
+	opts := gatewayLoopOptions(tcfg, msgBus, prov, timeSvc, classifier, auditLog)
+	opts.SessionsDir = filepath.Join(tc.Dir(), "sessions")
+	t := &tenant{
+		name:      tc.Name,
+		token:     tc.APIToken,
+		workspace: tc.Workspace,
+		bus:       msgBus,
+		timeline:  timeSvc,
+		loop:      agent.NewLoop(opts),
+		jobs:      tools.NewJobManager(timeSvc),
+		audit:     auditLog,
+		api:       http.NewServeMux(),
+	}
+	// A tenant's commands stay in its own workspace, whatever the main
+	// bot allows.
+	execCfg := cfg.Tools.Exec
+	execCfg.RestrictToWorkspace = true
+	t.loop.RegisterTool(execToolFromConfig(execCfg, tc.Workspace, t.jobs))
+	t.loop.RegisterTool(tools.NewJobTool(t.jobs))
+	if _, err := timeSvc.FailUnfinishedTasks("interrupted by a restart"); err != nil {
+		fmt.Printf("⚠️ [%s] Failed to check background tasks: %v\n", t.name, err)
+	}
+	_, _ = timeSvc.MarkLostJobs()
+
+	mediaDir := filepath.Join(tc.Workspace, "media")
+	t.wa = channels.NewWhatsAppChannel(tc.Channels.WhatsApp, msgBus, prov, timeSvc)
+	t.slack = channels.NewSlackChannel(tc.Channels.Slack, msgBus, timeSvc, mediaDir)
+	t.sig = channels.NewSignalChannel(tc.Channels.Signal, msgBus, timeSvc, mediaDir)
+	t.wa.Classifier, t.slack.Classifier, t.sig.Classifier = classifier, classifier, classifier
+	channels.SubscribePresence(msgBus, t.wa)
+	msgBus.SubscribeReactions(func(evt *bus.ReactionEvent) {
+		_ = timeSvc.AddReaction(&timeline.Reaction{
+			EventID:   evt.MessageID,
+			Channel:   evt.Channel,
+			SenderID:  evt.SenderID,
+			Emoji:     evt.Emoji,
+			Timestamp: evt.Timestamp,
+		})
+	})
+	// Start may block on QR pairing; don't hold up the other tenants.
+	go func() {
+		if err := t.wa.Start(ctx); err != nil {
+			fmt.Printf("[%s] Failed to start WhatsApp: %v\n", t.name, err)
+		}
+	}()
+	if err := t.slack.Start(ctx); err != nil {
+		fmt.Printf("[%s] Failed to start Slack: %v\n", t.name, err)
+	}
+	if err := t.sig.Start(ctx); err != nil {
+		fmt.Printf("[%s] Failed to start Signal: %v\n", t.name, err)
+	}
+	applyOutboundPolicies(msgBus, tc.Channels, mediaDir)
+	go msgBus.DispatchOutbound(ctx)
+	go (&reminderScheduler{timeline: timeSvc, bus: msgBus}).Run(ctx)
+	go (&heldMessageScheduler{timeline: timeSvc, bus: msgBus}).Run(ctx)
+	go func() {
+		if err := t.loop.Run(ctx); err != nil {
+			fmt.Printf("[%s] Agent loop crashed: %v\n", t.name, err)
+		}
+	}()
+	go runTenantMetrics(ctx, t.name, t.loop.Sessions(), timeSvc)
+
+	t.media = media.NewLibrary(mediaDir)
+	t.media.Prefix = "/tenants/" + t.name + "/media/"
+	t.api.HandleFunc("/chat", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		serveChat(ctx, w, r, t.loop, t.media, "local:default")
+	})
+	t.api.HandleFunc("/structured", structuredHandler(ctx, t.loop, t.token))
+	registerOpenAIRoutes(t.api, t.loop, t.token)
+
+	fmt.Printf("👥 Tenant %s started (workspace %s)\n", t.name, t.workspace)
+	return t, nil
+}
+
+// stop lets the current turn finish and closes the tenant's channels and
+// timeline.
+func (t *tenant) stop(ctx context.Context) {
+	if err := t.loop.Shutdown(ctx); err != nil {
+		fmt.Printf("⚠️ [%s] Agent shutdown interrupted an in-flight turn: %v\n", t.name, err)
+	}
+	t.jobs.KillAll()
+	t.wa.Stop()
+	t.slack.Stop()
+	t.sig.Stop()
+	t.timeline.Close()
+	if t.audit != nil {
+		t.audit.Close()
+	}
+}
+
+// routeTenants serves requests carrying a tenant's token with that tenant's
+// API routes and everything else with next.
+func routeTenants(tenants []*tenant, next http.Handler) http.Handler {
+	if len(tenants) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tok := httpmw.RequestToken(r); tok != "" {
+			for _, t := range tenants {
+				if subtle.ConstantTimeCompare([]byte(tok), []byte(t.token)) != 1 {
+					continue
+				}
+				if h, pattern := t.api.Handler(r); pattern != "" {
+					h.ServeHTTP(w, r)
+					return
+				}
+				break
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// runTenantMetrics updates the per-tenant gauges every minute.
+func runTenantMetrics(ctx context.Context, name string, sessions *session.Manager, tl *timeline.TimelineService) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		recordTenantMetrics(name, sessions, tl)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func recordTenantMetrics(name string, sessions *session.Manager, tl *timeline.TimelineService) {
+	tenantSessions.Set(float64(sessions.Stats().Count), "tenant", name)
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if usage, err := tl.UsageBetween(start, now.Add(time.Second)); err == nil {
+		tenantRequests.Set(float64(usage.Requests), "tenant", name)
+		tenantTokens.Set(float64(usage.PromptTokens+usage.CompletionTokens), "tenant", name)
+	}
+}
+
+// tenantsHandler lists the tenants with their session count and usage this
+// month. The main bot is listed as "default".
+func tenantsHandler(mainWorkspace string, tenants []*tenant) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		names := []string{mainTenant}
+		workspaces := map[string]string{mainTenant: mainWorkspace}
+		for _, t := range tenants {
+			names = append(names, t.name)
+			workspaces[t.name] = t.workspace
+		}
+		list := make([]map[string]any, 0, len(names))
+		for _, name := range names {
+			list = append(list, map[string]any{
+				"name":           name,
+				"workspace":      workspaces[name],
+				"sessions":       tenantSessions.Value("tenant", name),
+				"requests_month": tenantRequests.Value("tenant", name),
+				"tokens_month":   tenantTokens.Value("tenant", name),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(list)
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteTenantsByHeaderToken(t *testing.T) {
+	anna := &tenant{name: "anna", token: "anna-token", api: http.NewServeMux()}
+	anna.api.HandleFunc("/chat", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "anna") })
+	h := routeTenants([]*tenant{anna}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "main")
+	}))
+
+	tests := []struct {
+		target, header, value, want string
+	}{
+		{"/chat", "X-API-Token", "anna-token", "anna"},
+		{"/chat", "Authorization", "Bearer anna-token", "anna"},
+		{"/chat", "X-API-Token", "other", "main"},
+		// Tokens in the URL leak into logs and do not select a tenant.
+		{"/chat?token=anna-token", "", "", "main"},
+		// Routes the tenant does not serve fall through.
+		{"/health", "X-API-Token", "anna-token", "main"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.target, nil)
+		if tt.header != "" {
+			req.Header.Set(tt.header, tt.value)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("%s with %s %q: served by %s, want %s", tt.target, tt.header, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// gatewayTLS holds the HTTPS setup shared by the API and dashboard servers.
+type gatewayTLS struct {
+	config   *tls.Config
+	redirect *http.Server
+}
+
+// newGatewayTLS loads certificates or sets up ACME. It returns nil when
+// TLS is not configured.
+func newGatewayTLS(cfg config.GatewayConfig) (*gatewayTLS, error) {
+	t := cfg.TLS
+	if !t.Enabled() {
+		return nil, nil
+	}
+
+	var (
+		tlsCfg    *tls.Config
+		challenge http.Handler
+	)
+	if len(t.ACMEHosts) > 0 {
+		cacheDir := t.ACMECacheDir
+		if cacheDir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, err
+			}
+			cacheDir = filepath.Join(home, config.ConfigDir, "certs")
+		}
+		if err := os.MkdirAll(cacheDir, 0700); err != nil {
+			return nil, fmt.Errorf("create ACME cache: %w", err)
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(t.ACMEHosts...),
+			Cache:      autocert.DirCache(cacheDir),
+			Email:      t.ACMEEmail,
+		}
+		// TLSConfig also answers TLS-ALPN challenges on the HTTPS port.
+		tlsCfg = m.TLSConfig()
+		fmt.Printf("🔐 ACME certificates for %v (cache %s)\n", t.ACMEHosts, cacheDir)
+		challenge = m.HTTPHandler(httpsRedirect(cfg.DashboardPort))
+	} else {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS certificate: %w", err)
+		}
+		tlsCfg = &tls.Config{Certificates: []tls.Certificate{cert}}
+		challenge = httpsRedirect(cfg.DashboardPort)
+	}
+	tlsCfg.MinVersion = tls.VersionTLS12
+
+	g := &gatewayTLS{config: tlsCfg}
+	if t.RedirectAddr != "" {
+		g.redirect = &http.Server{Addr: t.RedirectAddr, Handler: challenge}
+	}
+	return g, nil
+}
+
+// serve runs srv over HTTPS when TLS is configured, plain HTTP otherwise.
+func (g *gatewayTLS) serve(srv *http.Server) error {
+	if g == nil {
+		return srv.ListenAndServe()
+	}
+	srv.TLSConfig = g.config
+	return srv.ListenAndServeTLS("", "")
+}
+
+// scheme is the URL scheme the gateway servers use.
+func (g *gatewayTLS) scheme() string {
+	if g == nil {
+		return "http"
+	}
+	return "https"
+}
+
+// startRedirect serves the HTTP→HTTPS redirect if configured.
+func (g *gatewayTLS) startRedirect() {
+	if g == nil || g.redirect == nil {
+		return
+	}
+	go func() {
+		fmt.Printf("↪️  Redirecting http://%s to HTTPS\n", g.redirect.Addr)
+		if err := g.redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("⚠️ HTTPS redirect server stopped: %v\n", err)
+		}
+	}()
+}
+
+// httpsRedirect sends plain HTTP requests to the same host on port.
+func httpsRedirect(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/memory"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/tools"
+	"github.com/spf13/cobra"
+)
+
+var ingestCmd = &cobra.Command{
+	Use:   "ingest <path|url>...",
+	Short: "Add documents to the knowledge base",
+	Long: "Chunk documents (PDF, office files, text, HTML) or web pages, embed them, and store them in the " +
+		"knowledge base searched by kb_search. Directories are read recursively. Ingesting a source again replaces it.",
+	Args: cobra.MinimumNArgs(1),
+	Run:  runIngest,
+}
+
+func init() {
+	rootCmd.AddCommand(ingestCmd)
+}
+
+func runIngest(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	kb := knowledgeBase(cfg)
+	if kb == nil {
+		fmt.Println("Error: the knowledge base needs knowledge.qdrantUrl (MIKROBOT_KNOWLEDGE_QDRANT_URL) and an API key for embeddings.")
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	if err := kb.Store.EnsureCollection(ctx); err != nil {
+		fmt.Printf("Error preparing collection %s: %v\n", cfg.Knowledge.Collection, err)
+		os.Exit(1)
+	}
+
+	var docs, chunks, failed int
+	ingest := func(doc memory.Document) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		n, err := kb.Ingest(ctx, doc)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", doc.Source, err)
+			failed++
+			return
+		}
+		fmt.Printf("📚 %s: %d chunks\n", doc.Source, n)
+		docs++
+		chunks += n
+	}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+			doc, err := memory.FetchDocument(ctx, arg)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", arg, err)
+				failed++
+				continue
+			}
+			ingest(doc)
+			continue
+		}
+		for _, path := range ingestFiles(arg) {
+			doc, err := readIngestFile(ctx, path)
+			if err != nil {
+				fmt.Printf("❌ %s: %v\n", path, err)
+				failed++
+				continue
+			}
+			ingest(doc)
+		}
+	}
+
+	fmt.Printf("\nIngested %d sources (%d chunks)", docs, chunks)
+	if failed > 0 {
+		fmt.Printf(", %d failed\n", failed)
+		os.Exit(1)
+	}
+	fmt.Println()
+}
+
+// ingestFiles returns the documents at path: the file itself, or the
+// supported files below a directory, skipping hidden entries.
+func ingestFiles(path string) []string {
+	if strings.HasPrefix(path, "~") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, path[1:])
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		return []string{path}
+	}
+	var files []string
+	filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if p != path && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() && tools.IsDocument(p) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files
+}
+
+// readIngestFile extracts the text of a local document.
+func readIngestFile(ctx context.Context, path string) (memory.Document, error) {
+	text, err := tools.ExtractDocumentText(ctx, path)
+	if err != nil {
+		return memory.Document{}, err
+	}
+	doc := memory.Document{Source: path, Title: filepath.Base(path), Text: text}
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".html" || ext == ".htm" {
+		title, body := memory.HTMLText(text)
+		if title != "" {
+			doc.Title = title
+		}
+		doc.Text = body
+	}
+	return doc, nil
+}
+
+// knowledgeBase returns the configured knowledge base, or nil if it is not
+// set up.
+func knowledgeBase(cfg *config.Config) *memory.KnowledgeBase {
+	k := cfg.Knowledge
+	embedder := embedderFor(cfg)
+	if k.QdrantURL == "" || embedder == nil {
+		return nil
+	}
+	return &memory.KnowledgeBase{
+		Store:        memory.NewQdrantStore(strings.TrimSuffix(k.QdrantURL, "/"), k.Collection, k.Dimensions),
+		Embedder:     embedder,
+		Model:        k.EmbeddingModel,
+		ChunkSize:    k.ChunkSize,
+		ChunkOverlap: k.ChunkOverlap,
+	}
+}
+
+// embedderFor returns the configured embeddings backend with batching and
+// caching, or nil if the OpenAI backend has no API key.
+func embedderFor(cfg *config.Config) provider.Embedder {
+	e := cfg.Embeddings
+	var embedder provider.Embedder
+	switch e.Provider {
+	case "ollama":
+		embedder = provider.NewOllamaEmbedder(e.BaseURL)
+	default:
+		if cfg.Providers.OpenAI.APIKey == "" {
+			return nil
+		}
+		embedder = provider.NewOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI.APIBase, cfg.Agents.Defaults.Model)
+	}
+	return provider.NewCachedEmbedder(embedder, e.BatchSize, e.CacheSize)
+}
+
+// knowledgeTools returns kb_search if the knowledge base is configured.
+func knowledgeTools(cfg *config.Config) []tools.Tool {
+	kb := knowledgeBase(cfg)
+	if kb == nil {
+		return nil
+	}
+	return []tools.Tool{tools.NewKBSearchTool(kb)}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/kamir/gomikrobot/internal/agent"
 	"github.com/kamir/gomikrobot/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -37,12 +38,11 @@ func runOnboard(cmd *cobra.Command, args []string) {
 		fmt.Printf("✅ Config created at: %s\n", path)
 	}
 
-	// ensure workspace exists
-	if err := config.EnsureDir(cfg.Agents.Defaults.Workspace); err != nil {
-		// It might be ~ path, EnsureDir assumes expanded?
-		// DefaultConfig has "~/...". config.EnsureDir does mkdir. Mkdir doesn't expand ~.
-		// We need to expand it. config.Load expands it.
-		// Let's rely on user to run it or expand it here.
+	ws := expandHome(cfg.Agents.Defaults.Workspace)
+	if _, err := agent.InitWorkspace(ws); err != nil {
+		fmt.Printf("Error creating workspace: %v\n", err)
+	} else {
+		fmt.Printf("✅ Workspace ready at: %s\n", ws)
 	}
 
 	fmt.Println("\nNext steps:")
@@ -0,0 +1,23 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/spf13/cobra"
+)
+
+// jsonOutput makes a command print machine-readable JSON instead of text.
+var jsonOutput bool
+
+// addJSONFlag adds --json to cmd.
+func addJSONFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print machine-readable JSON")
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/secrets"
+	"github.com/spf13/cobra"
+)
+
+var secretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Keep API keys and tokens in the OS keychain or an encrypted file",
+	Long: "Secrets are stored in the backend set by secrets.backend: keychain (macOS Keychain or the " +
+		"Linux Secret Service via secret-tool) or file (encrypted with MIKROBOT_SECRETS_PASSPHRASE). " +
+		"The config file holds a ${secret:NAME} reference that is resolved when the config is loaded.",
+}
+
+var secretSetCmd = &cobra.Command{
+	Use:   "set <key>",
+	Short: "Store a secret and point the config key at it",
+	Long: "Reads the value from the terminal without echoing it, or from stdin when piped. " +
+		"Provider keys may omit the providers. prefix.",
+	Example: "  gomikrobot secret set openai.apiKey\n" +
+		"  echo -n \"$TOKEN\" | gomikrobot secret set channels.telegram.token",
+	Args: cobra.ExactArgs(1),
+	Run:  runSecretSet,
+}
+
+var secretGetCmd = &cobra.Command{
+	Use:   "get <name>",
+	Short: "Print a stored secret",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSecretGet,
+}
+
+var secretDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Remove a stored secret",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSecretDelete,
+}
+
+func init() {
+	secretCmd.AddCommand(secretSetCmd, secretGetCmd, secretDeleteCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+// secretStore opens the configured store. The config is loaded without
+// resolving secrets so a missing one can still be set.
+func secretStore() (*config.Config, secrets.Store) {
+	cfg, err := config.LoadForEdit()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	store, err := config.OpenSecrets(cfg.Secrets)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	return cfg, store
+}
+
+func runSecretSet(cmd *cobra.Command, args []string) {
+	cfg, store := secretStore()
+	name, key := args[0], args[0]
+	if _, err := config.GetKey(cfg, key); err != nil {
+		if _, perr := config.GetKey(cfg, "providers."+key); perr != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		key = "providers." + key
+	}
+
+	value, err := readSecret(fmt.Sprintf("Value for %s: ", key))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if value == "" {
+		fmt.Println("Error: empty value")
+		os.Exit(1)
+	}
+	if err := store.Set(name, value); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := config.SetKey(cfg, key, "${secret:"+name+"}"); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := config.Save(cfg); err != nil {
+		fmt.Printf("Stored %s in %s, but the config was not updated: %v\n", name, store.Name(), err)
+		fmt.Printf("Set %s to ${secret:%s} by hand.\n", key, name)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Stored %s in %s; %s now references it.\n", name, store.Name(), key)
+}
+
+func runSecretGet(cmd *cobra.Command, args []string) {
+	_, store := secretStore()
+	value, err := store.Get(args[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(value)
+}
+
+func runSecretDelete(cmd *cobra.Command, args []string) {
+	_, store := secretStore()
+	if err := store.Delete(args[0]); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Deleted %s from %s. Remove any ${secret:%s} references from the config.\n", args[0], store.Name(), args[0])
+}
+
+// readSecret reads one line from the terminal with echo off, or all of
+// stdin when it is not a terminal.
+func readSecret(prompt string) (string, error) {
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		return strings.TrimRight(string(data), "\r\n"), err
+	}
+	fmt.Print(prompt)
+	echo := func(on bool) {
+		arg := "-echo"
+		if on {
+			arg = "echo"
+		}
+		stty := exec.Command("stty", arg)
+		stty.Stdin = os.Stdin
+		stty.Run()
+	}
+	echo(false)
+	defer func() {
+		echo(true)
+		fmt.Println()
+	}()
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/httpmw"
+	"github.com/kamir/gomikrobot/internal/toolrpc"
+	"github.com/kamir/gomikrobot/internal/tools"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveToolsAddr  string
+	serveToolsToken string
+)
+
+var serveToolsCmd = &cobra.Command{
+	Use:   "serve-tools",
+	Short: "Serve the tool registry over JSON-RPC",
+	Long: "Run only the tools (no LLM, no channels) behind an authenticated JSON-RPC endpoint at /rpc, " +
+		"so a remote gateway can use them via tools.remote.",
+	Run: runServeTools,
+}
+
+func init() {
+	serveToolsCmd.Flags().StringVar(&serveToolsAddr, "addr", "", "Listen address (default tools.serve.addr)")
+	serveToolsCmd.Flags().StringVar(&serveToolsToken, "token", "", "Bearer token clients must send (default tools.serve.token)")
+	rootCmd.AddCommand(serveToolsCmd)
+}
+
+func runServeTools(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	addr := cfg.Tools.Serve.Addr
+	if serveToolsAddr != "" {
+		addr = serveToolsAddr
+	}
+	token := cfg.Tools.Serve.Token
+	if serveToolsToken != "" {
+		token = serveToolsToken
+	}
+	if token == "" {
+		fmt.Println("Error: a token is required; set tools.serve.token, MIKROBOT_TOOLS_SERVE_TOKEN, or --token.")
+		os.Exit(1)
+	}
+
+	registry := tools.NewRegistry()
+	tools.RegisterDefaults(registry, cfg.Agents.Defaults.Workspace)
+	jobs := tools.NewJobManager(nil)
+	registry.Register(execToolFromConfig(cfg.Tools.Exec, cfg.Agents.Defaults.Workspace, jobs))
+	registry.Register(tools.NewJobTool(jobs))
+	if t := codeToolFromConfig(cfg.Tools); t != nil {
+		registry.Register(t)
+	}
+	for _, t := range calendarTools(cfg.Tools.Calendar) {
+		registry.Register(t)
+	}
+	for _, t := range desktopTools(cfg.Tools.Desktop) {
+		registry.Register(t)
+	}
+	for _, t := range knowledgeTools(cfg) {
+		registry.Register(t)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/rpc", toolrpc.NewServer(registry, token))
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+
+	server := &http.Server{
+		Addr:    addr,
+		Handler: httpmw.Chain(mux, httpmw.Recoverer(), httpmw.MaxBodyBytes(cfg.Gateway.MaxBodyBytes)),
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+		jobs.KillAll()
+	}()
+
+	fmt.Printf("🔧 Serving %d tools on http://%s/rpc (workspace %s)\n", len(registry.List()), addr, cfg.Agents.Defaults.Workspace)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// registerRemoteTools adds the tools of each configured tool server to the
+// loop. Unreachable servers are reported and skipped.
+func registerRemoteTools(ctx context.Context, loop *agent.Loop, remotes []config.RemoteToolsConfig) {
+	for _, r := range remotes {
+		listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		remote, err := toolrpc.NewClient(r.URL, r.Token).Tools(listCtx, r.Name)
+		cancel()
+		if err != nil {
+			fmt.Printf("⚠️ Remote tools %s unavailable: %v\n", r.Name, err)
+			continue
+		}
+		for _, t := range remote {
+			loop.RegisterTool(t)
+		}
+		fmt.Printf("🔧 Added %d remote tools from %s\n", len(remote), r.Name)
+	}
+}
+
+// registerPlugins starts the plugins in cfg.Dir and adds their tools to the
+// loop. Plugins that fail to start or list their tools are reported and
+// skipped. The returned function stops the plugins.
+func registerPlugins(ctx context.Context, loop *agent.Loop, cfg config.PluginsConfig) func() {
+	if !cfg.Enabled || cfg.Dir == "" {
+		return func() {}
+	}
+	paths, err := toolrpc.DiscoverPlugins(cfg.Dir)
+	if err != nil {
+		fmt.Printf("⚠️ Plugins unavailable: %v\n", err)
+		return func() {}
+	}
+	var clients []*toolrpc.Client
+	for _, path := range paths {
+		name := toolrpc.PluginName(path)
+		if name == "" || slices.Contains(cfg.Disabled, name) {
+			continue
+		}
+		client := toolrpc.NewPluginClient(path)
+		listCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		plugin, err := client.Tools(listCtx, name)
+		cancel()
+		if err != nil {
+			fmt.Printf("⚠️ Plugin %s unavailable: %v\n", name, err)
+			_ = client.Close()
+			continue
+		}
+		for _, t := range plugin {
+			loop.RegisterTool(t)
+		}
+		clients = append(clients, client)
+		fmt.Printf("🧩 Added %d tools from plugin %s\n", len(plugin), name)
+	}
+	return func() {
+		for _, c := range clients {
+			_ = c.Close()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/session"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tools"
+	"github.com/spf13/cobra"
+)
+
+var sessionsCmd = &cobra.Command{
+	Use:   "sessions",
+	Short: "List conversation sessions",
+	Long:  "List the stored conversation sessions, most recently active first, with their message counts.",
+	Run:   runSessions,
+}
+
+var sessionsResetModelCmd = &cobra.Command{
+	Use:   "reset-model <session>",
+	Short: "Switch a session back to the default model",
+	Long:  "Remove the model override set_model stored for a session, e.g. whatsapp:4915112345678@s.whatsapp.net.",
+	Args:  cobra.ExactArgs(1),
+	Run:   runSessionsResetModel,
+}
+
+func init() {
+	addJSONFlag(sessionsCmd)
+	sessionsCmd.AddCommand(sessionsResetModelCmd)
+	rootCmd.AddCommand(sessionsCmd)
+}
+
+func runSessionsResetModel(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	timeSvc, err := timeline.Open(timelineStorage(cfg))
+	if err != nil {
+		fmt.Printf("Failed to open timeline: %v\n", err)
+		os.Exit(1)
+	}
+	defer timeSvc.Close()
+
+	key := tools.SessionModelKey(args[0])
+	if model, _ := timeSvc.GetSetting(key); model == "" {
+		fmt.Printf("%s already uses the default model.\n", args[0])
+		return
+	}
+	if err := timeSvc.SetSetting(key, ""); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s now uses the default model %s.\n", args[0], cfg.Agents.Defaults.Model)
+}
+
+// sessionSummary is one session in the --json output.
+type sessionSummary struct {
+	Key       string    `json:"key"`
+	Messages  int       `json:"messages"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	BranchOf  string    `json:"branch_of,omitempty"`
+}
+
+func runSessions(cmd *cobra.Command, args []string) {
+	mgr := session.NewManager("")
+	list := mgr.List()
+	sort.Slice(list, func(i, j int) bool { return list[i].UpdatedAt.After(list[j].UpdatedAt) })
+
+	summaries := make([]sessionSummary, 0, len(list))
+	for _, info := range list {
+		summaries = append(summaries, sessionSummary{
+			Key:       info.Key,
+			Messages:  len(mgr.GetOrCreate(info.Key).Messages),
+			CreatedAt: info.CreatedAt,
+			UpdatedAt: info.UpdatedAt,
+			BranchOf:  info.BranchOf,
+		})
+	}
+
+	if jsonOutput {
+		_ = writeJSON(os.Stdout, summaries)
+		return
+	}
+	if len(summaries) == 0 {
+		fmt.Println("No sessions.")
+		return
+	}
+	for _, s := range summaries {
+		branch := ""
+		if s.BranchOf != "" {
+			branch = " (branch of " + s.BranchOf + ")"
+		}
+		fmt.Printf("%-48s %5d messages  %s%s\n", s.Key, s.Messages, s.UpdatedAt.Local().Format("2006-01-02 15:04"), branch)
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kamir/gomikrobot/internal/channels"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var signalCmd = &cobra.Command{
+	Use:   "signal",
+	Short: "Manage the Signal account used by signal-cli",
+	Long: "The Signal channel talks to a signal-cli daemon started with 'signal-cli daemon --http'. " +
+		"Its address is channels.signal.url.",
+}
+
+var signalLinkName string
+
+var signalLinkCmd = &cobra.Command{
+	Use:   "link",
+	Short: "Link signal-cli to your phone as a secondary device",
+	Run:   runSignalLink,
+}
+
+var signalStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the accounts known to signal-cli",
+	Run:   runSignalStatus,
+}
+
+func init() {
+	signalLinkCmd.Flags().StringVar(&signalLinkName, "name", "gomikrobot", "Device name shown on the phone")
+	signalCmd.AddCommand(signalLinkCmd, signalStatusCmd)
+	rootCmd.AddCommand(signalCmd)
+}
+
+func signalURL() string {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Config warning: %v (using defaults)\n", err)
+	}
+	if cfg == nil || cfg.Channels.Signal.URL == "" {
+		return config.DefaultConfig().Channels.Signal.URL
+	}
+	return cfg.Channels.Signal.URL
+}
+
+func runSignalLink(cmd *cobra.Command, args []string) {
+	url := signalURL()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	number, err := channels.LinkSignal(ctx, url, signalLinkName, func(uri string) {
+		fmt.Println("Open Signal on your phone > Settings > Linked devices > Link new device, then scan:")
+		fmt.Println(channels.QRText(uri))
+	})
+	if err != nil {
+		fmt.Printf("Error: linking failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Linked %s. Set channels.signal.account to this number and enable the channel.\n", number)
+}
+
+func runSignalStatus(cmd *cobra.Command, args []string) {
+	url := signalURL()
+	rpc := channels.NewSignalRPC(url)
+	if err := rpc.Check(context.Background()); err != nil {
+		fmt.Printf("Error: signal-cli is not reachable at %s: %v\n", url, err)
+		os.Exit(1)
+	}
+	var accounts []struct {
+		Number string `json:"number"`
+	}
+	if err := rpc.Call(context.Background(), "listAccounts", nil, &accounts); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(accounts) == 0 {
+		fmt.Printf("signal-cli at %s has no accounts. Run 'gomikrobot signal link'.\n", url)
+		return
+	}
+	for _, a := range accounts {
+		fmt.Printf("Linked: %s\n", a.Number)
+	}
+}
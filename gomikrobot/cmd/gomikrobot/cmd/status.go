@@ -21,15 +21,27 @@ var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show system status",
 	Run: func(cmd *cobra.Command, args []string) {
+		// Check config
+		home, _ := os.UserHomeDir()
+		configPath := filepath.Join(home, ".gomikrobot", "config.json")
+		_, err := os.Stat(configPath)
+		configFound := err == nil
+
+		if jsonOutput {
+			_ = writeJSON(os.Stdout, map[string]any{
+				"version":      version,
+				"config_path":  configPath,
+				"config_found": configFound,
+				"status":       "ready",
+			})
+			return
+		}
+
 		fmt.Println(color.CyanString(logo))
 		fmt.Println("📊 GoMikroBot Status")
 		fmt.Println("─────────────────────")
 		fmt.Printf("Version: %s\n", version)
-
-		// Check config
-		home, _ := os.UserHomeDir()
-		configPath := filepath.Join(home, ".gomikrobot", "config.json")
-		if _, err := os.Stat(configPath); err == nil {
+		if configFound {
 			fmt.Println("Config:  ✓ Found (" + configPath + ")")
 		} else {
 			fmt.Println("Config:  ✗ Not found (run 'gomikrobot onboard' first)")
@@ -38,3 +50,7 @@ var statusCmd = &cobra.Command{
 		fmt.Println("Status:  Ready")
 	},
 }
+
+func init() {
+	addJSONFlag(statusCmd)
+}
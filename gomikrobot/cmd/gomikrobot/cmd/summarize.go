@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/digest"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/spf13/cobra"
+)
+
+var (
+	summarizeSender string
+	summarizeSince  string
+	summarizeSave   bool
+)
+
+var summarizeCmd = &cobra.Command{
+	Use:   "summarize",
+	Short: "Summarize recent conversations from the timeline",
+	Long: "Run timeline messages since --since through the model and print a digest. " +
+		"With --save, also write it to <workspace>/summaries.",
+	Run: runSummarize,
+}
+
+func init() {
+	summarizeCmd.Flags().StringVar(&summarizeSender, "sender", "", "Only messages from this sender (phone number or ID)")
+	summarizeCmd.Flags().StringVar(&summarizeSince, "since", "7d", "Start of the period: a duration such as 24h, 7d, 2w, or a date (YYYY-MM-DD)")
+	summarizeCmd.Flags().BoolVar(&summarizeSave, "save", false, "Also write the summary to the workspace")
+	rootCmd.AddCommand(summarizeCmd)
+}
+
+func runSummarize(cmd *cobra.Command, args []string) {
+	now := time.Now()
+	start, err := parseSince(summarizeSince, now)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.Providers.OpenAI.APIKey == "" {
+		fmt.Println("Error: API key not found. Set MIKROBOT_OPENAI_API_KEY, OPENROUTER_API_KEY, or use config.json")
+		os.Exit(1)
+	}
+
+	timeSvc, err := timeline.Open(timelineStorage(cfg))
+	if err != nil {
+		fmt.Printf("Failed to open timeline: %v\n", err)
+		os.Exit(1)
+	}
+	defer timeSvc.Close()
+
+	prov := provider.NewOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI.APIBase, cfg.Agents.Defaults.Model)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	summary, err := digest.Summarize(ctx, prov, timeSvc, digest.SummaryOptions{
+		SenderID: normalizeSender(summarizeSender),
+		Start:    start,
+		End:      now,
+		Model:    cfg.Agents.Defaults.Model,
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	title := summaryTitle(summary)
+	fmt.Printf("%s\n\n%s\n", title, summary.Text)
+
+	if summarizeSave && summary.Events > 0 {
+		path, err := saveSummary(cfg.Agents.Defaults.Workspace, title, summary)
+		if err != nil {
+			fmt.Printf("Error saving summary: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("\nSaved to %s\n", path)
+	}
+}
+
+// parseSince accepts durations with d/w suffixes or a YYYY-MM-DD date.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v <= 0 {
+				break
+			}
+			return now.Add(-time.Duration(v) * unit), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("since %q is not a duration (24h, 7d, 2w) or date (YYYY-MM-DD)", s)
+}
+
+// normalizeSender strips formatting from phone numbers; WhatsApp senders
+// are stored without the leading +.
+func normalizeSender(s string) string {
+	return strings.TrimPrefix(strings.ReplaceAll(s, " ", ""), "+")
+}
+
+func summaryTitle(s *digest.Summary) string {
+	who := "all senders"
+	if s.SenderID != "" {
+		who = s.SenderID
+	}
+	return fmt.Sprintf("# Summary for %s, %s – %s (%d messages)",
+		who, s.Start.Format("2006-01-02"), s.End.Format("2006-01-02"), s.Events)
+}
+
+func saveSummary(workspace, title string, s *digest.Summary) (string, error) {
+	if strings.HasPrefix(workspace, "~") {
+		home, _ := os.UserHomeDir()
+		workspace = filepath.Join(home, workspace[1:])
+	}
+	dir := filepath.Join(workspace, "summaries")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	who := s.SenderID
+	if who == "" {
+		who = "all"
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s.md", s.End.Format("2006-01-02"), who))
+	return path, os.WriteFile(path, []byte(title+"\n\n"+s.Text+"\n"), 0600)
+}
@@ -0,0 +1,326 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/spf13/cobra"
+)
+
+var (
+	timelineSender   string
+	timelineType     string
+	timelineSince    string
+	timelineUntil    string
+	timelineLimit    int
+	timelineFollow   bool
+	timelineInterval time.Duration
+
+	timelinePruneBefore    string
+	timelinePruneNoArchive bool
+
+	timelineDryRun     bool
+	timelineRollbackTo int
+)
+
+var timelineCmd = &cobra.Command{
+	Use:   "timeline",
+	Short: "List timeline events or manage the timeline database",
+	Long: "Print the latest timeline events, oldest first, filtered by sender, type, and date range. " +
+		"With --follow, keep printing new events as they arrive.",
+	Args: cobra.NoArgs,
+	Run:  runTimeline,
+}
+
+var timelinePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Archive and remove timeline events before a date",
+	Long: "Write events older than --before to a gzipped JSONL file under <workspace>/archive/timeline, " +
+		"delete them, and compact the database.",
+	Run: runTimelinePrune,
+}
+
+var timelineMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Show the schema version and apply pending migrations",
+	Long: "Migrations also run automatically whenever the timeline is opened; use --dry-run to see " +
+		"which would run and their SQL.",
+	Args: cobra.NoArgs,
+	Run:  runTimelineMigrate,
+}
+
+var timelineRollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Undo schema migrations, e.g. before going back to an older release",
+	Long: "Roll the schema back to --to (default: the previous version). Stop the gateway first: " +
+		"opening the timeline with this release upgrades it again.",
+	Args: cobra.NoArgs,
+	Run:  runTimelineRollback,
+}
+
+func init() {
+	timelineCmd.Flags().StringVar(&timelineSender, "sender", "", "Only events from this sender (phone number or ID)")
+	timelineCmd.Flags().StringVar(&timelineType, "type", "", "Only events of this type (text, audio, image, system, reply)")
+	timelineCmd.Flags().StringVar(&timelineSince, "since", "", "Only events since a duration such as 24h, 7d, 2w, or a date (YYYY-MM-DD)")
+	timelineCmd.Flags().StringVar(&timelineUntil, "until", "", "Only events before a duration ago or a date (YYYY-MM-DD)")
+	timelineCmd.Flags().IntVarP(&timelineLimit, "limit", "n", 50, "Number of events to print (0 for all)")
+	timelineCmd.Flags().BoolVarP(&timelineFollow, "follow", "f", false, "Keep printing new events until interrupted")
+	timelineCmd.Flags().DurationVar(&timelineInterval, "interval", 2*time.Second, "How often --follow checks for new events")
+	addJSONFlag(timelineCmd)
+
+	timelinePruneCmd.Flags().StringVar(&timelinePruneBefore, "before", "", "Remove events before this date (YYYY-MM-DD)")
+	timelinePruneCmd.Flags().BoolVar(&timelinePruneNoArchive, "no-archive", false, "Delete without writing an archive")
+	_ = timelinePruneCmd.MarkFlagRequired("before")
+	addJSONFlag(timelinePruneCmd)
+	timelineCmd.AddCommand(timelinePruneCmd)
+
+	timelineMigrateCmd.Flags().BoolVar(&timelineDryRun, "dry-run", false, "Print the pending migrations without applying them")
+	timelineRollbackCmd.Flags().BoolVar(&timelineDryRun, "dry-run", false, "Print the migrations that would be undone")
+	timelineRollbackCmd.Flags().IntVar(&timelineRollbackTo, "to", -1, "Schema version to roll back to (default: the previous one)")
+	timelineCmd.AddCommand(timelineMigrateCmd, timelineRollbackCmd)
+	rootCmd.AddCommand(timelineCmd)
+}
+
+func runTimeline(cmd *cobra.Command, args []string) {
+	filter := timeline.FilterArgs{
+		SenderID:  normalizeSender(timelineSender),
+		EventType: strings.ToUpper(timelineType),
+		Limit:     timelineLimit,
+	}
+	now := time.Now()
+	if timelineSince != "" {
+		since, err := parseSince(timelineSince, now)
+		if err != nil {
+			fmt.Printf("Error: --%v\n", err)
+			os.Exit(1)
+		}
+		filter.StartDate = &since
+	}
+	if timelineUntil != "" {
+		until, err := parseSince(timelineUntil, now)
+		if err != nil {
+			fmt.Printf("Error: --until: %v\n", err)
+			os.Exit(1)
+		}
+		filter.EndDate = &until
+	}
+	if timelineFollow && timelineInterval <= 0 {
+		fmt.Println("Error: --interval must be positive")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	timeSvc, err := timeline.Open(timelineStorage(cfg))
+	if err != nil {
+		fmt.Printf("Failed to open timeline: %v\n", err)
+		os.Exit(1)
+	}
+	defer timeSvc.Close()
+
+	events, err := timeSvc.GetEvents(filter)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	last := printTimelineEvents(events, 0)
+	if !timelineFollow {
+		if len(events) == 0 && !jsonOutput {
+			fmt.Println("No events.")
+		}
+		return
+	}
+
+	// New events are newer than anything printed; --until no longer applies
+	// once the range reaches the present.
+	if filter.EndDate != nil && filter.EndDate.Before(now) {
+		return
+	}
+	filter.EndDate = nil
+	filter.Limit = 0
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if last == 0 {
+		// Nothing matched yet; start after the newest event of any kind.
+		if newest, err := timeSvc.GetEvents(timeline.FilterArgs{Limit: 1}); err == nil && len(newest) > 0 {
+			last = newest[0].ID
+		}
+	}
+	ticker := time.NewTicker(timelineInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		filter.AfterID = last
+		events, err := timeSvc.GetEvents(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		last = printTimelineEvents(events, last)
+	}
+}
+
+// printTimelineEvents prints events, which GetEvents returns newest first,
+// in the order they happened, and returns the largest id seen.
+func printTimelineEvents(events []timeline.TimelineEvent, last int64) int64 {
+	slices.Reverse(events)
+	enc := json.NewEncoder(os.Stdout)
+	for _, e := range events {
+		last = max(last, e.ID)
+		if jsonOutput {
+			_ = enc.Encode(e)
+			continue
+		}
+		sender := e.SenderID
+		if e.SenderName != "" && e.SenderName != e.SenderID {
+			sender = fmt.Sprintf("%s (%s)", e.SenderName, e.SenderID)
+		}
+		text := strings.Join(strings.Fields(e.ContentText), " ")
+		if r := []rune(text); len(r) > 120 {
+			text = string(r[:119]) + "…"
+		}
+		if e.MediaPath != "" {
+			text = strings.TrimSpace(text + " [" + filepath.Base(e.MediaPath) + "]")
+		}
+		fmt.Printf("%s  %-6s  %s: %s\n", e.Timestamp.Local().Format("2006-01-02 15:04:05"), e.EventType, sender, text)
+	}
+	return last
+}
+
+func runTimelinePrune(cmd *cobra.Command, args []string) {
+	before, err := time.ParseInLocation("2006-01-02", timelinePruneBefore, time.Local)
+	if err != nil {
+		fmt.Printf("Error: --before must be YYYY-MM-DD: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	timeSvc, err := timeline.Open(timelineStorage(cfg))
+	if err != nil {
+		fmt.Printf("Failed to open timeline: %v\n", err)
+		os.Exit(1)
+	}
+	defer timeSvc.Close()
+
+	tcfg := cfg.Timeline
+	if timelinePruneNoArchive {
+		tcfg.Archive = false
+	}
+	res, err := timeSvc.Prune(before, timelineArchiveDir(tcfg, cfg.Agents.Defaults.Workspace))
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOutput {
+		_ = writeJSON(os.Stdout, map[string]any{
+			"before":  before.Format("2006-01-02"),
+			"removed": res.Removed,
+			"archive": res.Archive,
+		})
+		return
+	}
+	fmt.Printf("Removed %d events before %s.\n", res.Removed, before.Format("2006-01-02"))
+	if res.Archive != "" {
+		fmt.Printf("Archive: %s\n", res.Archive)
+	}
+}
+
+// timelineStorage returns where the main bot's timeline is kept.
+func timelineStorage(cfg *config.Config) timeline.Storage {
+	return timeline.Storage{Driver: cfg.Timeline.Driver, Path: cfg.Timeline.Path, DSN: cfg.Timeline.DSN}
+}
+
+// openTimelineMigrator opens the timeline without upgrading it.
+func openTimelineMigrator() *timeline.Migrator {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	m, err := timeline.OpenMigrator(timelineStorage(cfg))
+	if err != nil {
+		fmt.Printf("Failed to open timeline: %v\n", err)
+		os.Exit(1)
+	}
+	return m
+}
+
+func runTimelineMigrate(cmd *cobra.Command, args []string) {
+	m := openTimelineMigrator()
+	defer m.Close()
+
+	from, err := m.Version()
+	if err == nil && !timelineDryRun {
+		fmt.Printf("Schema version %d of %d.\n", from, m.Latest())
+	}
+	var applied []timeline.Migration
+	if err == nil {
+		applied, err = m.Up(timelineDryRun)
+	}
+	for _, mig := range applied {
+		if timelineDryRun {
+			fmt.Printf("-- Would apply %04d_%s:\n%s\n", mig.Version, mig.Name, strings.TrimSpace(mig.Up))
+		} else {
+			fmt.Printf("✅ Applied %04d_%s\n", mig.Version, mig.Name)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(applied) == 0 {
+		fmt.Println("The schema is up to date.")
+	}
+}
+
+func runTimelineRollback(cmd *cobra.Command, args []string) {
+	m := openTimelineMigrator()
+	defer m.Close()
+
+	target := timelineRollbackTo
+	if target < 0 {
+		v, err := m.Version()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		target = v - 1
+	}
+	undone, err := m.Down(target, timelineDryRun)
+	for _, mig := range undone {
+		if timelineDryRun {
+			fmt.Printf("-- Would undo %04d_%s:\n%s\n", mig.Version, mig.Name, strings.TrimSpace(mig.Down))
+		} else {
+			fmt.Printf("↩️ Undid %04d_%s\n", mig.Version, mig.Name)
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !timelineDryRun {
+		fmt.Printf("Schema version is now %d.\n", target)
+	}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/pricing"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageDays int
+	usageCost bool
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Show LLM token usage",
+	Long: "Show LLM requests and tokens recorded in the timeline. With --cost, estimate spend per model " +
+		"from the built-in pricing table and pricing.models overrides.",
+	Run: runUsage,
+}
+
+func init() {
+	usageCmd.Flags().IntVar(&usageDays, "days", 30, "Number of days to include")
+	usageCmd.Flags().BoolVar(&usageCost, "cost", false, "Estimate spend per model")
+	addJSONFlag(usageCmd)
+	rootCmd.AddCommand(usageCmd)
+}
+
+// usageReport is the --json output.
+type usageReport struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+	timeline.UsageTotals
+	Cost *costReport `json:"cost,omitempty"`
+}
+
+type costReport struct {
+	*pricing.Report
+	MonthToDateUSD   float64 `json:"month_to_date_usd"`
+	MonthlyBudgetUSD float64 `json:"monthly_budget_usd,omitempty"`
+}
+
+// modelPrices converts configured price overrides for the pricing registry.
+func modelPrices(pc config.PricingConfig) map[string]pricing.Price {
+	prices := make(map[string]pricing.Price, len(pc.Models))
+	for model, p := range pc.Models {
+		prices[model] = pricing.Price{Input: p.Input, Output: p.Output}
+	}
+	return prices
+}
+
+func runUsage(cmd *cobra.Command, args []string) {
+	if usageDays <= 0 {
+		fmt.Println("Error: --days must be positive")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	timeSvc, err := timeline.Open(timelineStorage(cfg))
+	if err != nil {
+		fmt.Printf("Failed to open timeline: %v\n", err)
+		os.Exit(1)
+	}
+	defer timeSvc.Close()
+
+	end := time.Now()
+	start := end.AddDate(0, 0, -usageDays)
+	totals, err := timeSvc.UsageBetween(start, end)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jsonOutput {
+		rep := usageReport{Start: start.Format(time.RFC3339), End: end.Format(time.RFC3339), UsageTotals: totals}
+		if usageCost {
+			cost, err := estimateCost(cfg, timeSvc, start, end)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			rep.Cost = cost
+		}
+		_ = writeJSON(os.Stdout, rep)
+		return
+	}
+	fmt.Printf("Usage %s – %s\n", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	fmt.Printf("  Requests:    %d (%d failed, %d refused)\n", totals.Requests, totals.Failed, totals.Refused)
+	fmt.Printf("  Tokens:      %d prompt, %d completion\n", totals.PromptTokens, totals.CompletionTokens)
+	fmt.Printf("  Tool calls:  %d (%d errors)\n", totals.ToolCalls, totals.ToolErrors)
+	if !usageCost {
+		return
+	}
+
+	cost, err := estimateCost(cfg, timeSvc, start, end)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("\nEstimated cost:")
+	for _, m := range cost.Models {
+		price := fmt.Sprintf("$%.2f", m.CostUSD)
+		if !m.Priced {
+			price = "unpriced"
+		}
+		fmt.Printf("  %-32s %10s  (%d in, %d out)\n", m.Model, price, m.PromptTokens, m.CompletionTokens)
+	}
+	fmt.Printf("  %-32s %10s\n", "Total", fmt.Sprintf("$%.2f", cost.TotalUSD))
+	if len(cost.Unpriced) > 0 {
+		fmt.Printf("  Not included: %s (add them under pricing.models)\n", strings.Join(cost.Unpriced, ", "))
+	}
+
+	if budget := cost.MonthlyBudgetUSD; budget > 0 {
+		fmt.Printf("\nThis month: $%.2f of $%.2f budget (%.0f%%)\n", cost.MonthToDateUSD, budget, 100*cost.MonthToDateUSD/budget)
+	} else {
+		fmt.Printf("\nThis month: $%.2f\n", cost.MonthToDateUSD)
+	}
+}
+
+// estimateCost prices the usage between start and end and this month.
+func estimateCost(cfg *config.Config, timeSvc *timeline.TimelineService, start, end time.Time) (*costReport, error) {
+	prices := pricing.New(modelPrices(cfg.Pricing))
+	byModel, err := timeSvc.UsageByModel(start, end)
+	if err != nil {
+		return nil, err
+	}
+	month, err := prices.MonthToDate(timeSvc, end)
+	if err != nil {
+		return nil, err
+	}
+	return &costReport{Report: prices.Estimate(byModel), MonthToDateUSD: month.TotalUSD, MonthlyBudgetUSD: cfg.Pricing.MonthlyBudget}, nil
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kamir/gomikrobot/internal/channels"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var whatsappCmd = &cobra.Command{
+	Use:   "whatsapp",
+	Short: "Manage the linked WhatsApp session",
+	Long: "The WhatsApp channel connects natively as a linked device; its session is stored in " +
+		"channels.whatsapp.sessionPath. Stop the gateway before running these commands.",
+}
+
+var whatsappLoginCmd = &cobra.Command{
+	Use:   "login",
+	Short: "Pair with a phone by scanning a QR code",
+	Run:   runWhatsAppLogin,
+}
+
+var whatsappLogoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Unlink the device and delete the local session",
+	Run:   runWhatsAppLogout,
+}
+
+var whatsappStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which account the session is linked to",
+	Run:   runWhatsAppStatus,
+}
+
+// whatsappTenant selects a tenant's session instead of the main one.
+var whatsappTenant string
+
+func init() {
+	whatsappCmd.PersistentFlags().StringVar(&whatsappTenant, "tenant", "", "Use the session of this tenant")
+	whatsappCmd.AddCommand(whatsappLoginCmd, whatsappLogoutCmd, whatsappStatusCmd)
+	rootCmd.AddCommand(whatsappCmd)
+}
+
+func whatsappSessionPath() string {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Config warning: %v (using defaults)\n", err)
+	}
+	if whatsappTenant != "" {
+		var tenants []config.TenantConfig
+		if cfg != nil {
+			tenants = cfg.Tenants
+		}
+		for _, t := range tenants {
+			if t.Name == whatsappTenant {
+				return t.Channels.WhatsApp.SessionPath
+			}
+		}
+		fmt.Printf("Error: unknown tenant %q\n", whatsappTenant)
+		os.Exit(1)
+	}
+	if cfg == nil || cfg.Channels.WhatsApp.SessionPath == "" {
+		return channels.DefaultWhatsAppSession()
+	}
+	return cfg.Channels.WhatsApp.SessionPath
+}
+
+func runWhatsAppLogin(cmd *cobra.Command, args []string) {
+	path := whatsappSessionPath()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Println("Open WhatsApp on your phone > Settings > Linked devices > Link a device, then scan:")
+	jid, err := channels.PairWhatsApp(ctx, path, func(code string) {
+		fmt.Println(channels.QRText(code))
+	})
+	if errors.Is(err, channels.ErrAlreadyPaired) {
+		fmt.Printf("Already paired as %s. Run 'gomikrobot whatsapp logout' first to link another phone.\n", jid)
+		return
+	}
+	if err != nil {
+		fmt.Printf("Error: pairing failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ Paired as %s (session: %s)\n", jid, path)
+}
+
+func runWhatsAppLogout(cmd *cobra.Command, args []string) {
+	path := whatsappSessionPath()
+	if jid, err := channels.WhatsAppAccount(context.Background(), path); err == nil && jid == "" {
+		fmt.Println("Not paired; nothing to do.")
+		return
+	}
+	remote, err := channels.UnlinkWhatsApp(context.Background(), path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !remote {
+		fmt.Println("Deleted the local session. If the phone still lists this device under Linked devices, remove it there.")
+		return
+	}
+	fmt.Println("✅ Logged out and deleted the local session.")
+}
+
+func runWhatsAppStatus(cmd *cobra.Command, args []string) {
+	path := whatsappSessionPath()
+	jid, err := channels.WhatsAppAccount(context.Background(), path)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if jid == "" {
+		fmt.Printf("Not paired (session: %s). Run 'gomikrobot whatsapp login'.\n", path)
+		return
+	}
+	fmt.Printf("Paired as %s (session: %s)\n", jid, path)
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/classify"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/spf13/cobra"
+)
+
+var workerID string
+
+var workerCmd = &cobra.Command{
+	Use:   "worker",
+	Short: "Run agent turns for a gateway over the shared bus",
+	Long: "A worker takes inbound messages from the NATS or Redis bus in bus.transport and runs the agent loop on them; " +
+		"replies go back over the bus to the gateway's channels. A gateway with bus.workers routes every session to one " +
+		"worker, so several workers can share the load. Workers read the same config as the gateway. Sessions are files, " +
+		"so workers on other machines keep their own; use a Postgres timeline to share it.",
+	Args: cobra.NoArgs,
+	Run:  runWorker,
+}
+
+func init() {
+	workerCmd.Flags().StringVar(&workerID, "id", "", "Worker ID that sessions are routed to; keep it across restarts (default: host name)")
+	rootCmd.AddCommand(workerCmd)
+}
+
+// defaultWorkerID derives a worker ID from the host name.
+func defaultWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return "worker"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '-'
+	}, host)
+}
+
+func runWorker(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Config error: %v\n", err)
+		os.Exit(1)
+	}
+	applyGatewayDefaults(cfg)
+	if cfg.Bus.Transport == "" || cfg.Bus.Transport == "local" {
+		fmt.Println("Error: workers need a shared bus. Set bus.transport to nats or redis and bus.url.")
+		os.Exit(1)
+	}
+	id := workerID
+	if id == "" {
+		id = defaultWorkerID()
+	}
+	if !bus.ValidWorkerID(id) {
+		fmt.Printf("Error: invalid worker ID %q: use letters, digits, - and _\n", id)
+		os.Exit(1)
+	}
+	if cfg.Providers.OpenAI.APIKey == "" {
+		fmt.Println("Error: API key not found. Set MIKROBOT_OPENAI_API_KEY, OPENAI_API_KEY, or OPENROUTER_API_KEY")
+		os.Exit(1)
+	}
+
+	msgBus, err := newMessageBus(cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	oaProv := provider.NewOpenAIProvider(cfg.Providers.OpenAI.APIKey, cfg.Providers.OpenAI.APIBase, cfg.Agents.Defaults.Model)
+	var prov provider.LLMProvider = oaProv
+	if cfg.Providers.LocalWhisper.Enabled {
+		prov = provider.NewLocalWhisperProvider(cfg.Providers.LocalWhisper, oaProv)
+	}
+	timeSvc, err := timeline.Open(timelineStorage(cfg))
+	if err != nil {
+		fmt.Printf("Failed to init timeline: %v\n", err)
+		os.Exit(1)
+	}
+	classifier, err := classify.New(cfg.Classification, prov)
+	if err != nil {
+		fmt.Printf("Config error: %v\n", err)
+		os.Exit(1)
+	}
+
+	stopTracing := startTracing(cfg.Tracing)
+	auditLog := openAuditLog(cfg.Audit)
+	if auditLog != nil {
+		defer auditLog.Close()
+	}
+	loop := agent.NewLoop(gatewayLoopOptions(cfg, msgBus, prov, timeSvc, classifier, auditLog))
+	jobs, stopPlugins := registerGatewayTools(loop, cfg, timeSvc)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := msgBus.JoinWorkers(ctx, id); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("🧵 Worker %s is taking messages from the %s bus\n", id, cfg.Bus.Transport)
+	if err := loop.Run(ctx); err != nil {
+		fmt.Printf("Agent loop crashed: %v\n", err)
+	}
+
+	fmt.Println("Shutting down...")
+	loopCtx, loopCancel := context.WithTimeout(context.Background(), cfg.Gateway.ShutdownTimeout)
+	defer loopCancel()
+	if err := loop.Shutdown(loopCtx); err != nil {
+		fmt.Printf("⚠️ Agent shutdown interrupted an in-flight turn: %v\n", err)
+	}
+	jobs.KillAll()
+	stopPlugins()
+	msgBus.Close()
+	timeSvc.Close()
+	stopTracing()
+}
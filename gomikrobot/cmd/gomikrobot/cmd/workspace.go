@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/agent"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Set up and check the agent workspace",
+	Long: "The workspace (agents.defaults.workspace) holds memory/, skills/, media/ and the " +
+		"AGENTS.md, SOUL.md and USER.md files loaded into the system prompt.",
+}
+
+var workspaceDir string
+
+var workspaceInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create missing workspace directories and template files",
+	Run:   runWorkspaceInit,
+}
+
+var workspaceCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Report missing workspace directories and files",
+	Run:   runWorkspaceCheck,
+}
+
+func init() {
+	workspaceCmd.PersistentFlags().StringVar(&workspaceDir, "dir", "", "Workspace directory (default: agents.defaults.workspace)")
+	workspaceCmd.AddCommand(workspaceInitCmd, workspaceCheckCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
+
+func workspacePath() string {
+	if workspaceDir != "" {
+		return expandHome(workspaceDir)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Config warning: %v (using defaults)\n", err)
+		return expandHome(config.DefaultConfig().Agents.Defaults.Workspace)
+	}
+	return cfg.Agents.Defaults.Workspace
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[1:])
+	}
+	return path
+}
+
+func runWorkspaceInit(cmd *cobra.Command, args []string) {
+	dir := workspacePath()
+	created, err := agent.InitWorkspace(dir)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(created) == 0 {
+		fmt.Printf("✅ Workspace %s is complete.\n", dir)
+		return
+	}
+	fmt.Printf("✅ Workspace %s:\n", dir)
+	for _, p := range created {
+		fmt.Printf("   created %s\n", p)
+	}
+}
+
+func runWorkspaceCheck(cmd *cobra.Command, args []string) {
+	dir := workspacePath()
+	missing := agent.CheckWorkspace(dir)
+	if len(missing) == 0 {
+		fmt.Printf("✅ Workspace %s is complete.\n", dir)
+		return
+	}
+	fmt.Printf("⚠️  Workspace %s is missing:\n", dir)
+	for _, p := range missing {
+		fmt.Printf("   %s\n", p)
+	}
+	fmt.Println("Run 'gomikrobot workspace init' to create them.")
+	os.Exit(1)
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+const (
+	// compactToolChars is the size tool outputs are cut to during compaction.
+	compactToolChars = 2000
+	// summaryInputChars caps each message fed to the summarizer.
+	summaryInputChars = 1500
+)
+
+// contextTooLongReply is returned to the user when compaction was not enough.
+const contextTooLongReply = "This conversation has grown too long for the model, even after summarizing older messages. " +
+	"Please start a new session or ask a shorter question."
+
+// compactMessages shrinks a request that overflowed the context window:
+// large tool outputs are truncated and the history before the current user
+// message is replaced by a summary. The system prompt and the current turn
+// are kept.
+func (l *Loop) compactMessages(ctx context.Context, model string, messages []provider.Message) []provider.Message {
+	out := make([]provider.Message, 0, len(messages))
+
+	// The current turn starts at the last user message.
+	current := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			current = i
+			break
+		}
+	}
+
+	start := 0
+	if len(messages) > 0 && messages[0].Role == "system" {
+		out = append(out, messages[0])
+		start = 1
+	}
+
+	if older := messages[start:current]; len(older) > 0 {
+		out = append(out, provider.Message{
+			Role:    "system",
+			Content: "Summary of the earlier conversation:\n" + l.summarize(ctx, model, older),
+		})
+	}
+
+	for _, m := range messages[current:] {
+		if m.Role == "tool" {
+			m.Content = truncateMiddle(m.Content, compactToolChars)
+		}
+		out = append(out, m)
+	}
+
+	slog.Info("Compacted context", "before", len(messages), "after", len(out))
+	return out
+}
+
+// summarize condenses older turns with the provider. If that fails, it
+// falls back to a note that history was dropped.
+func (l *Loop) summarize(ctx context.Context, model string, msgs []provider.Message) string {
+	var sb strings.Builder
+	for _, m := range msgs {
+		if m.Content == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s\n\n", m.Role, truncateMiddle(m.Content, summaryInputChars))
+	}
+
+	resp, err := l.chat(ctx, &provider.ChatRequest{
+		Model: model,
+		Messages: []provider.Message{
+			{Role: "system", Content: "Summarize this conversation in at most 10 bullet points. Keep facts, decisions, names, file paths, and open questions. Omit pleasantries."},
+			{Role: "user", Content: sb.String()},
+		},
+		MaxTokens:   600,
+		Temperature: 0.2,
+	}, nil)
+	if err != nil || strings.TrimSpace(resp.Content) == "" {
+		slog.Warn("Context summary failed; dropping older history", "error", err)
+		return fmt.Sprintf("[%d earlier messages were omitted to fit the context window.]", len(msgs))
+	}
+	return strings.TrimSpace(resp.Content)
+}
+
+// truncateMiddle keeps the head and tail of s within max characters.
+func truncateMiddle(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	head := max * 2 / 3
+	tail := max - head
+	return s[:head] + fmt.Sprintf("\n\n[... %d characters omitted ...]\n\n", len(s)-max) + s[len(s)-tail:]
+}
@@ -2,14 +2,18 @@ package agent
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	"github.com/kamir/gomikrobot/internal/provider"
 	"github.com/kamir/gomikrobot/internal/session"
+	"github.com/kamir/gomikrobot/internal/tokens"
 	"github.com/kamir/gomikrobot/internal/tools"
 )
 
@@ -21,48 +25,250 @@ var bootstrapFiles = []string{
 	"IDENTITY.md",
 }
 
+// projectFiles are loaded from the active project directory.
+var projectFiles = []string{
+	"AGENTS.md",
+	"README.md",
+}
+
+// projectFileChars caps each project file included in the prompt.
+const projectFileChars = 8000
+
+// projectMetaKey is the session metadata key holding the active project.
+const projectMetaKey = "project"
+
+// SystemPromptFile is the workspace file that overrides the identity template.
+const SystemPromptFile = "SYSTEM.md"
+
+// Prompt sections that can be disabled via PromptOptions.DisabledSections.
+const (
+	SectionBootstrap = "bootstrap"
+	SectionMemory    = "memory"
+	SectionSkills    = "skills"
+)
+
+// PromptOptions customizes system prompt assembly.
+type PromptOptions struct {
+	// TemplateFile overrides the identity template. Relative paths are resolved
+	// against the workspace. Defaults to SYSTEM.md in the workspace if present.
+	TemplateFile string
+	// DisabledSections lists sections to leave out (bootstrap, memory, skills).
+	DisabledSections []string
+}
+
+// PromptData holds the variables available to system prompt templates.
+type PromptData struct {
+	// Time is the current date. The clock time is added after the cached
+	// part of the prompt, so the prompt stays the same for the whole day.
+	Time      string
+	Runtime   string
+	Workspace string
+	Tools     []ToolInfo
+}
+
+// ToolInfo describes a registered tool for templates.
+type ToolInfo struct {
+	Name        string
+	Description string
+}
+
+const defaultIdentityTemplate = `# GoMikroBot 🤖
+
+You are GoMikroBot, a helpful, efficient AI assistant.
+You have access to tools that allow you to:
+- Read, write, and edit files
+- Execute shell commands
+- Search the web and fetch web pages
+- Send messages to users
+
+## Current Date
+{{.Time}}
+
+## Runtime
+{{.Runtime}}
+
+## Workspace
+Your workspace is at: {{.Workspace}}
+- Memory files: {{.Workspace}}/memory/MEMORY.md
+- Daily notes: {{.Workspace}}/memory/YYYY-MM-DD.md
+- Custom skills: {{.Workspace}}/skills/{skill-name}/SKILL.md
+- Custom tools: {{.Workspace}}/skills/{skill-name}/tool.json and tool.lua (Lua, loaded at startup)
+
+IMPORTANT: When responding to direct questions, reply directly with text.
+Only use the 'message' tool when explicitly asked to send a message to a channel.
+Always be helpful, accurate, and concise.
+`
+
+var defaultIdentity = template.Must(template.New("identity").Parse(defaultIdentityTemplate))
+
 // ContextBuilder assembles the system prompt and messages.
 type ContextBuilder struct {
 	workspace string
 	registry  *tools.Registry
+	prompt    PromptOptions
+	projects  []tools.Project
+	// historyMessages and historyTokens bound the history per request.
+	historyMessages int
+	historyTokens   int
+
+	// The assembled system prompt and the fingerprint of its inputs.
+	cacheMu     sync.Mutex
+	cacheKey    string
+	cachePrompt string
 }
 
 // NewContextBuilder creates a new ContextBuilder.
 func NewContextBuilder(workspace string, registry *tools.Registry) *ContextBuilder {
 	return &ContextBuilder{
-		workspace: workspace,
-		registry:  registry,
+		workspace:       workspace,
+		registry:        registry,
+		historyMessages: defaultHistoryMessages,
+	}
+}
+
+// SetPromptOptions configures the template and enabled sections.
+func (b *ContextBuilder) SetPromptOptions(opts PromptOptions) {
+	b.prompt = opts
+}
+
+// SetProjects configures the projects available to switch_project.
+func (b *ContextBuilder) SetProjects(projects []tools.Project) {
+	b.projects = projects
+}
+
+// SetHistoryWindow bounds the history sent with each message to messages
+// (0 = default of 50) and maxTokens (0 = unlimited).
+func (b *ContextBuilder) SetHistoryWindow(messages, maxTokens int) {
+	if messages <= 0 {
+		messages = defaultHistoryMessages
+	}
+	b.historyMessages = messages
+	b.historyTokens = maxTokens
+}
+
+// activeProject returns the project recorded in the session, or nil.
+func (b *ContextBuilder) activeProject(sess *session.Session) *tools.Project {
+	name, _ := sess.GetMeta(projectMetaKey).(string)
+	if name == "" {
+		return nil
+	}
+	for i := range b.projects {
+		if strings.EqualFold(b.projects[i].Name, name) {
+			p := b.projects[i]
+			return &p
+		}
 	}
+	return nil
 }
 
-// BuildSystemPrompt constructs the full system prompt from files and runtime info.
+// projectSection describes the active project and includes its files.
+func (b *ContextBuilder) projectSection(p *tools.Project) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Active Project: %s\n\nYou are working in %s. Shell commands run there by default.", p.Name, p.Path)
+	if p.Description != "" {
+		fmt.Fprintf(&sb, "\n%s", p.Description)
+	}
+	for _, filename := range projectFiles {
+		content, err := os.ReadFile(filepath.Join(p.Path, filename))
+		if err == nil {
+			fmt.Fprintf(&sb, "\n\n## %s\n\n%s", filename, truncateMiddle(string(content), projectFileChars))
+		}
+	}
+	return sb.String()
+}
+
+func (b *ContextBuilder) sectionEnabled(name string) bool {
+	for _, s := range b.prompt.DisabledSections {
+		if strings.EqualFold(s, name) {
+			return false
+		}
+	}
+	return true
+}
+
+// BuildSystemPrompt constructs the full system prompt from files and runtime
+// info. The result is cached until the date, the registered tools, or one
+// of the files it is built from changes.
 func (b *ContextBuilder) BuildSystemPrompt() string {
+	key := b.promptKey()
+	b.cacheMu.Lock()
+	defer b.cacheMu.Unlock()
+	if key != b.cacheKey {
+		b.cachePrompt = b.buildSystemPrompt()
+		b.cacheKey = key
+	}
+	return b.cachePrompt
+}
+
+// promptKey fingerprints the inputs of the system prompt by size and
+// modification time, without reading the files.
+func (b *ContextBuilder) promptKey() string {
+	wsPath := b.promptData().Workspace
+	var sb strings.Builder
+	sb.WriteString(time.Now().Format("2006-01-02"))
+	for _, tool := range b.registry.List() {
+		sb.WriteString("|" + tool.Name())
+	}
+	paths := []string{b.templatePath(wsPath), filepath.Join(wsPath, "memory", "MEMORY.md"), filepath.Join(wsPath, "skills")}
+	for _, filename := range bootstrapFiles {
+		paths = append(paths, filepath.Join(wsPath, filename))
+	}
+	for _, p := range paths {
+		if info, err := os.Stat(p); err == nil {
+			fmt.Fprintf(&sb, "|%s:%d:%d", p, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return sb.String()
+}
+
+func (b *ContextBuilder) buildSystemPrompt() string {
 	var parts []string
 
 	// 1. Core Identity & Runtime Info
 	parts = append(parts, b.getIdentity())
 
 	// 2. Bootstrap Files
-	if bootstrap := b.loadBootstrapFiles(); bootstrap != "" {
-		parts = append(parts, bootstrap)
+	if b.sectionEnabled(SectionBootstrap) {
+		if bootstrap := b.loadBootstrapFiles(); bootstrap != "" {
+			parts = append(parts, bootstrap)
+		}
 	}
 
 	// 3. Memory
-	if memory := b.loadMemory(); memory != "" {
-		parts = append(parts, "# Memory\n\n"+memory)
+	if b.sectionEnabled(SectionMemory) {
+		if memory := b.loadMemory(); memory != "" {
+			parts = append(parts, "# Memory\n\n"+memory)
+		}
 	}
 
 	// 4. Skills (Summary)
-	if skills := b.buildSkillsSummary(); skills != "" {
-		parts = append(parts, "# Skills\n\n"+skills)
+	if b.sectionEnabled(SectionSkills) {
+		if skills := b.buildSkillsSummary(); skills != "" {
+			parts = append(parts, "# Skills\n\n"+skills)
+		}
 	}
 
 	return strings.Join(parts, "\n\n---\n\n")
 }
 
 func (b *ContextBuilder) getIdentity() string {
-	now := time.Now().Format("2006-01-02 15:04 (Monday)")
+	data := b.promptData()
+
+	tmpl := defaultIdentity
+	if custom := b.loadTemplate(data.Workspace); custom != nil {
+		tmpl = custom
+	}
 
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		slog.Warn("System prompt template failed, using default", "error", err)
+		sb.Reset()
+		_ = defaultIdentity.Execute(&sb, data)
+	}
+	return sb.String()
+}
+
+func (b *ContextBuilder) promptData() PromptData {
 	// Expand workspace path
 	wsPath := b.workspace
 	if strings.HasPrefix(wsPath, "~") {
@@ -73,33 +279,51 @@ func (b *ContextBuilder) getIdentity() string {
 		wsPath = abs
 	}
 
-	runtimeInfo := fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version())
-
-	return fmt.Sprintf(`# GoMikroBot 🤖
-
-You are GoMikroBot, a helpful, efficient AI assistant.
-You have access to tools that allow you to:
-- Read, write, and edit files
-- Execute shell commands
-- Search the web and fetch web pages
-- Send messages to users
-
-## Current Time
-%s
+	data := PromptData{
+		Time:      time.Now().Format("2006-01-02 (Monday)"),
+		Runtime:   fmt.Sprintf("%s %s, Go %s", runtime.GOOS, runtime.GOARCH, runtime.Version()),
+		Workspace: wsPath,
+	}
+	for _, tool := range b.registry.List() {
+		data.Tools = append(data.Tools, ToolInfo{Name: tool.Name(), Description: tool.Description()})
+	}
+	return data
+}
 
-## Runtime
-%s
+// templatePath returns the configured or workspace template file.
+func (b *ContextBuilder) templatePath(wsPath string) string {
+	path := b.prompt.TemplateFile
+	if path == "" {
+		path = SystemPromptFile
+	}
+	if strings.HasPrefix(path, "~") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, path[1:])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(wsPath, path)
+	}
+	return path
+}
 
-## Workspace
-Your workspace is at: %s
-- Memory files: %s/memory/MEMORY.md
-- Daily notes: %s/memory/YYYY-MM-DD.md
-- Custom skills: %s/skills/{skill-name}/SKILL.md
+// loadTemplate parses the configured or workspace template file.
+// It returns nil when no override exists or it fails to parse.
+func (b *ContextBuilder) loadTemplate(wsPath string) *template.Template {
+	path := b.templatePath(wsPath)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if b.prompt.TemplateFile != "" {
+			slog.Warn("System prompt template not readable", "path", path, "error", err)
+		}
+		return nil
+	}
 
-IMPORTANT: When responding to direct questions, reply directly with text.
-Only use the 'message' tool when explicitly asked to send a message to a channel.
-Always be helpful, accurate, and concise.
-`, now, runtimeInfo, wsPath, wsPath, wsPath, wsPath)
+	tmpl, err := template.New(filepath.Base(path)).Parse(string(content))
+	if err != nil {
+		slog.Warn("System prompt template invalid, using default", "path", path, "error", err)
+		return nil
+	}
+	return tmpl
 }
 
 func (b *ContextBuilder) loadBootstrapFiles() string {
@@ -186,13 +410,24 @@ func (b *ContextBuilder) BuildMessages(
 ) []provider.Message {
 
 	systemPrompt := b.BuildSystemPrompt()
+	cachePrefix := len(systemPrompt)
+	systemPrompt += "\n\n## Current Time\n" + time.Now().Format("2006-01-02 15:04 (Monday)")
+	if p := b.activeProject(sess); p != nil {
+		systemPrompt += "\n\n---\n\n" + b.projectSection(p)
+	}
 
 	if channel != "" && chatID != "" {
 		systemPrompt += fmt.Sprintf("\n\n## Current Session\nChannel: %s\nChat ID: %s", channel, chatID)
 	}
+	if pins := sess.Pins(); len(pins) > 0 {
+		systemPrompt += "\n\n## Pinned Messages\nPinned in this conversation; they apply until unpinned:\n"
+		for _, p := range pins {
+			systemPrompt += fmt.Sprintf("\n[#%d] %s\n", p.ID, p.Content)
+		}
+	}
 
 	messages := []provider.Message{
-		{Role: "system", Content: systemPrompt},
+		{Role: "system", Content: systemPrompt, CachePrefix: cachePrefix},
 	}
 
 	// Add recent history from session
@@ -204,7 +439,7 @@ func (b *ContextBuilder) BuildMessages(
 	// sess.AddMessage("user", content) -> then calls BuildMessages
 	// So the last message in session IS the current message.
 
-	history := sess.GetHistory(50)
+	history := trimHistory(sess.GetHistory(b.historyMessages), b.historyTokens)
 
 	// We want to format history for the LLM.
 	// If the last message in history is the current message, we should exclude it from the "history" block
@@ -232,3 +467,22 @@ func (b *ContextBuilder) BuildMessages(
 
 	return messages
 }
+
+// defaultHistoryMessages is the history window when none is configured.
+const defaultHistoryMessages = 50
+
+// trimHistory drops the oldest messages until the rest fit in
+// maxTokens (0 = unlimited). The newest message is always kept.
+func trimHistory(history []session.Message, maxTokens int) []session.Message {
+	if maxTokens <= 0 {
+		return history
+	}
+	total := 0
+	for i := len(history) - 1; i >= 0; i-- {
+		total += tokens.Count("", history[i].Content)
+		if total > maxTokens && i < len(history)-1 {
+			return history[i+1:]
+		}
+	}
+	return history
+}
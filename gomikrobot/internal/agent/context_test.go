@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kamir/gomikrobot/internal/session"
 	"github.com/kamir/gomikrobot/internal/tools"
@@ -77,3 +78,144 @@ func TestBuildMessages(t *testing.T) {
 		t.Errorf("Third message content mismatch: %s", msgs[2].Content)
 	}
 }
+
+func TestBuildMessagesHistoryWindowAndPins(t *testing.T) {
+	builder := NewContextBuilder(t.TempDir(), tools.NewRegistry())
+	sess := session.NewSession("test:123")
+	sess.AddPin("Always answer in haiku.")
+	for i := 0; i < 10; i++ {
+		sess.AddMessage("user", strings.Repeat(" hello", 10)) // 10 tokens each
+	}
+	sess.AddMessage("user", "Current msg")
+
+	builder.SetHistoryWindow(6, 0)
+	if msgs := builder.BuildMessages(sess, "Current msg", "cli", "default"); len(msgs) != 7 {
+		t.Errorf("message window: expected 7 messages, got %d", len(msgs))
+	}
+
+	builder.SetHistoryWindow(50, 35)
+	msgs := builder.BuildMessages(sess, "Current msg", "cli", "default")
+	if len(msgs) != 5 {
+		t.Errorf("token window: expected 5 messages, got %d", len(msgs))
+	}
+	if !strings.Contains(msgs[0].Content, "## Pinned Messages") || !strings.Contains(msgs[0].Content, "[#1] Always answer in haiku.") {
+		t.Errorf("system prompt lacks the pin:\n%s", msgs[0].Content)
+	}
+	if last := msgs[len(msgs)-1]; last.Content != "Current msg" {
+		t.Errorf("current message dropped: %q", last.Content)
+	}
+}
+
+func TestSystemPromptCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "AGENTS.md")
+	os.WriteFile(path, []byte("Rule A"), 0644)
+	builder := NewContextBuilder(tmpDir, tools.NewRegistry())
+	if !strings.Contains(builder.BuildSystemPrompt(), "Rule A") {
+		t.Fatal("prompt lacks bootstrap file")
+	}
+
+	// Same size and mtime: the cached prompt is reused without reading.
+	info, _ := os.Stat(path)
+	os.WriteFile(path, []byte("Rule B"), 0644)
+	os.Chtimes(path, info.ModTime(), info.ModTime())
+	if !strings.Contains(builder.BuildSystemPrompt(), "Rule A") {
+		t.Error("expected the cached prompt")
+	}
+
+	// A changed file invalidates the cache.
+	later := info.ModTime().Add(time.Second)
+	os.Chtimes(path, later, later)
+	if !strings.Contains(builder.BuildSystemPrompt(), "Rule B") {
+		t.Error("prompt not rebuilt after the file changed")
+	}
+
+	msgs := builder.BuildMessages(session.NewSession("cli:x"), "hi", "cli", "x")
+	stable := msgs[0].Content[:msgs[0].CachePrefix]
+	if stable != builder.BuildSystemPrompt() || strings.Contains(stable, "## Current Time") {
+		t.Errorf("cache prefix should cover exactly the static prompt:\n%s", stable)
+	}
+}
+
+func TestSystemPromptTemplateOverride(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, SystemPromptFile), []byte("I am Custom. Tools:{{range .Tools}} {{.Name}}{{end}}. WS={{.Workspace}}"), 0644)
+
+	registry := tools.NewRegistry()
+	registry.Register(tools.NewReadFileTool())
+
+	builder := NewContextBuilder(tmpDir, registry)
+	systemPrompt := builder.BuildSystemPrompt()
+
+	if !strings.Contains(systemPrompt, "I am Custom. Tools: read_file.") {
+		t.Errorf("expected rendered custom template, got %q", systemPrompt)
+	}
+	if !strings.Contains(systemPrompt, "WS="+tmpDir) {
+		t.Errorf("expected workspace variable, got %q", systemPrompt)
+	}
+	if strings.Contains(systemPrompt, "You are GoMikroBot") {
+		t.Error("default identity should be replaced by the template")
+	}
+}
+
+func TestSystemPromptInvalidTemplateFallsBack(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.WriteFile(filepath.Join(tmpDir, SystemPromptFile), []byte("{{.Broken"), 0644)
+
+	builder := NewContextBuilder(tmpDir, tools.NewRegistry())
+	if !strings.Contains(builder.BuildSystemPrompt(), "You are GoMikroBot") {
+		t.Error("expected default identity when template is invalid")
+	}
+}
+
+func TestSystemPromptDisabledSections(t *testing.T) {
+	tmpDir := t.TempDir()
+	os.Mkdir(filepath.Join(tmpDir, "memory"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "memory", "MEMORY.md"), []byte("Test Memory"), 0644)
+
+	registry := tools.NewRegistry()
+	registry.Register(tools.NewReadFileTool())
+
+	builder := NewContextBuilder(tmpDir, registry)
+	builder.SetPromptOptions(PromptOptions{DisabledSections: []string{"memory", "skills"}})
+	systemPrompt := builder.BuildSystemPrompt()
+
+	if strings.Contains(systemPrompt, "Test Memory") {
+		t.Error("memory section should be disabled")
+	}
+	if strings.Contains(systemPrompt, "# Skills") {
+		t.Error("skills section should be disabled")
+	}
+}
+
+func TestInitWorkspace(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "ws")
+	if missing := CheckWorkspace(dir); len(missing) != len(workspaceLayout) {
+		t.Errorf("empty workspace: missing %v", missing)
+	}
+	os.MkdirAll(dir, 0700)
+	os.WriteFile(filepath.Join(dir, "SOUL.md"), []byte("mine"), 0600)
+
+	created, err := InitWorkspace(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != len(workspaceLayout)-1 {
+		t.Errorf("created %v", created)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "SOUL.md")); string(data) != "mine" {
+		t.Errorf("existing SOUL.md overwritten: %q", data)
+	}
+	if missing := CheckWorkspace(dir); len(missing) != 0 {
+		t.Errorf("missing after init: %v", missing)
+	}
+	if again, _ := InitWorkspace(dir); len(again) != 0 {
+		t.Errorf("second init created %v", again)
+	}
+
+	os.RemoveAll(filepath.Join(dir, "media"))
+	os.WriteFile(filepath.Join(dir, "media"), nil, 0600)
+	if missing := CheckWorkspace(dir); len(missing) != 1 || missing[0] != "media (should be a directory)" {
+		t.Errorf("expected media to be reported, got %v", missing)
+	}
+}
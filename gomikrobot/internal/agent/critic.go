@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// CriticOptions configures the critic pass, which scores each answer
+// against the user's request and retries once below Threshold.
+type CriticOptions struct {
+	Enabled bool
+	// Model scores the answers (default: the model of the turn).
+	Model string
+	// Threshold is the lowest acceptable score from 1 to 10 (default 6).
+	Threshold int
+}
+
+const (
+	defaultCriticThreshold = 6
+	// criticInputChars caps the answer shown to the critic.
+	criticInputChars = 8000
+)
+
+const criticPrompt = "You review answers of an AI assistant. Score how well the answer addresses the user's request, " +
+	"from 1 (wrong or unhelpful) to 10 (correct and complete). Reply with JSON only: " +
+	`{"score": <1-10>, "critique": "<what is wrong or missing, in one or two sentences>"}`
+
+// critiqueNote asks the model for a better answer; it is not persisted.
+const critiqueNote = "[Critique] A reviewer scored your answer %d/10: %s\n" +
+	"Write an improved answer to the user's last message. Reply with the answer only, without mentioning the review."
+
+// SetCritic changes the critic pass for subsequent turns.
+func (l *Loop) SetCritic(c CriticOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.critic = withCriticDefaults(c)
+}
+
+func withCriticDefaults(c CriticOptions) CriticOptions {
+	if c.Threshold <= 0 {
+		c.Threshold = defaultCriticThreshold
+	}
+	return c
+}
+
+// criticize scores response against request and, below the threshold,
+// runs the turn once more with the critique appended. It returns the
+// answer to keep; if anything fails the original response is kept.
+func (l *Loop) criticize(ctx context.Context, model string, messages []provider.Message, request, response string, stats *turnStats) string {
+	l.mu.RLock()
+	opts := l.critic
+	l.mu.RUnlock()
+	if !opts.Enabled || strings.TrimSpace(response) == "" || response == contextTooLongReply {
+		return response
+	}
+
+	score, critique, err := l.score(ctx, cmp.Or(opts.Model, model), request, response, stats)
+	if err != nil {
+		slog.Warn("Critic pass failed", "error", err)
+		return response
+	}
+	retry := score < opts.Threshold
+	l.logCritique(ctx, score, opts.Threshold, critique, retry)
+	if !retry {
+		return response
+	}
+
+	slog.Info("Retrying low-scored answer", "score", score, "threshold", opts.Threshold)
+	messages = append(messages[:len(messages):len(messages)],
+		provider.Message{Role: "assistant", Content: response},
+		provider.Message{Role: "system", Content: fmt.Sprintf(critiqueNote, score, critique)},
+	)
+	improved, more, err := l.runAgentLoop(ctx, model, messages, nil)
+	stats.Usage.Add(more.Usage)
+	stats.ToolCalls += more.ToolCalls
+	stats.ToolErrors += more.ToolErrors
+	stats.Tools = append(stats.Tools, more.Tools...)
+	if err != nil || strings.TrimSpace(improved) == "" {
+		slog.Warn("Retry after critique failed; keeping the first answer", "error", err)
+		return response
+	}
+	return improved
+}
+
+// score asks the critic model to rate response from 1 to 10.
+func (l *Loop) score(ctx context.Context, model, request, response string, stats *turnStats) (int, string, error) {
+	resp, err := l.chat(ctx, &provider.ChatRequest{
+		Model: model,
+		Messages: []provider.Message{
+			{Role: "system", Content: criticPrompt},
+			{Role: "user", Content: fmt.Sprintf("Request:\n%s\n\nAnswer:\n%s", request, truncateMiddle(response, criticInputChars))},
+		},
+		MaxTokens:   200,
+		Temperature: 0,
+	}, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	stats.Usage.Add(resp.Usage)
+	return parseCritique(resp.Content)
+}
+
+// parseCritique reads the critic's JSON verdict, tolerating text or code
+// fences around it.
+func parseCritique(s string) (int, string, error) {
+	start, end := strings.Index(s, "{"), strings.LastIndex(s, "}")
+	if start < 0 || end < start {
+		return 0, "", fmt.Errorf("critic reply is not JSON: %q", s)
+	}
+	var v struct {
+		Score    int    `json:"score"`
+		Critique string `json:"critique"`
+	}
+	if err := json.Unmarshal([]byte(s[start:end+1]), &v); err != nil {
+		return 0, "", fmt.Errorf("critic reply: %w", err)
+	}
+	if v.Score < 1 || v.Score > 10 {
+		return 0, "", errors.New("critic score out of range")
+	}
+	return v.Score, strings.TrimSpace(v.Critique), nil
+}
+
+// logCritique records the critic's verdict in the timeline.
+func (l *Loop) logCritique(ctx context.Context, score, threshold int, critique string, retried bool) {
+	if l.timeline == nil {
+		return
+	}
+	session := tools.SessionKeyFrom(ctx)
+	text := fmt.Sprintf("Critic scored the answer %d/10 (threshold %d)", score, threshold)
+	if retried {
+		text += ", retried"
+	}
+	if critique != "" {
+		text += ": " + critique
+	}
+	err := l.timeline.AddEvent(&timeline.TimelineEvent{
+		EventID:        fmt.Sprintf("critic:%s:%d", session, time.Now().UnixNano()),
+		Timestamp:      time.Now(),
+		SenderID:       session,
+		SenderName:     "critic",
+		EventType:      "SYSTEM",
+		ContentText:    text,
+		Classification: fmt.Sprintf("CRITIC %d/10", score),
+		Authorized:     true,
+	})
+	if err != nil {
+		slog.Warn("Failed to log critic score", "error", err)
+	}
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// Draft review actions.
+const (
+	DraftApprove = "approve"
+	DraftEdit    = "edit"
+	DraftReject  = "reject"
+)
+
+// ErrDraftDecided is returned when reviewing a draft that was already
+// sent or rejected.
+var ErrDraftDecided = errors.New("draft was already decided")
+
+// Notes telling the model what became of a reviewed reply.
+const (
+	draftEditedNote   = "[Review] An operator edited your previous reply before it was sent. The user received:\n%s"
+	draftRejectedNote = "[Review] An operator rejected your previous reply; the user did not receive it."
+)
+
+// holdDraft stores response for review instead of sending it and tells
+// the review session about it. It reports false if the draft could not
+// be stored, in which case the caller sends the reply as usual.
+func (l *Loop) holdDraft(msg *bus.InboundMessage, response, trace string) bool {
+	d := &timeline.Draft{Channel: msg.Channel, ChatID: msg.ChatID, Content: response, Trace: trace}
+	if err := l.timeline.AddDraft(d); err != nil {
+		slog.Error("Failed to store draft, sending the reply", "session", sessionKeyFor(msg), "error", err)
+		return false
+	}
+	slog.Info("Reply held for review", "session", sessionKeyFor(msg), "draft", d.ID)
+
+	channel, chatID, ok := strings.Cut(l.reviewSession, ":")
+	if !ok {
+		return true
+	}
+	l.bus.PublishOutbound(&bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: fmt.Sprintf("📝 Draft #%d for %s:\n\n%s\n\nApprove, edit or reject it here or on the dashboard.", d.ID, sessionKeyFor(msg), response),
+	})
+	return true
+}
+
+// ReviewRequired reports whether replies to sessionKey are held as drafts.
+func (l *Loop) ReviewRequired(sessionKey string) bool {
+	return l.timeline != nil && l.timeline.ReviewRequired(sessionKey)
+}
+
+// SetReview turns review before send on or off for sessionKey.
+func (l *Loop) SetReview(sessionKey string, on bool) error {
+	if l.timeline == nil {
+		return errors.New("reviewing replies requires the timeline")
+	}
+	return l.timeline.SetReview(sessionKey, on)
+}
+
+// ReviewSessions returns the sessions whose replies are reviewed.
+func (l *Loop) ReviewSessions() ([]string, error) {
+	if l.timeline == nil {
+		return nil, nil
+	}
+	return l.timeline.ReviewSessions()
+}
+
+// PendingDrafts returns the drafts waiting for a decision, newest first.
+func (l *Loop) PendingDrafts() ([]timeline.Draft, error) {
+	if l.timeline == nil {
+		return nil, nil
+	}
+	return l.timeline.Drafts(timeline.DraftPending, 0)
+}
+
+// ReviewDraft approves, edits or rejects a pending draft. Approved and
+// edited drafts are sent; for edited and rejected ones the session history
+// gets a note so the model knows what the user actually saw.
+func (l *Loop) ReviewDraft(id int64, action, content string) (*timeline.Draft, error) {
+	if l.timeline == nil {
+		return nil, errors.New("reviewing replies requires the timeline")
+	}
+	d, err := l.timeline.GetDraft(id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != timeline.DraftPending {
+		return nil, fmt.Errorf("draft #%d was already %s: %w", id, d.Status, ErrDraftDecided)
+	}
+
+	status, note := timeline.DraftSent, ""
+	switch action {
+	case DraftApprove:
+		content = d.Content
+	case DraftEdit:
+		if strings.TrimSpace(content) == "" {
+			return nil, errors.New("edit needs the new text")
+		}
+		note = fmt.Sprintf(draftEditedNote, content)
+	case DraftReject:
+		status, content, note = timeline.DraftRejected, d.Content, draftRejectedNote
+	default:
+		return nil, fmt.Errorf("action must be %s, %s or %s", DraftApprove, DraftEdit, DraftReject)
+	}
+	if err := l.timeline.DecideDraft(id, status, content); errors.Is(err, timeline.ErrDraftNotFound) {
+		return nil, fmt.Errorf("draft #%d: %w", id, ErrDraftDecided) // decided concurrently
+	} else if err != nil {
+		return nil, err
+	}
+	d.Status, d.Content = status, content
+
+	if status == timeline.DraftSent {
+		l.bus.PublishOutbound(&bus.OutboundMessage{Channel: d.Channel, ChatID: d.ChatID, Content: content, Trace: d.Trace})
+	}
+	if note != "" {
+		key := d.Channel + ":" + d.ChatID
+		sess := l.sessions.GetOrCreate(key)
+		sess.AddMessage("system", note)
+		if err := l.sessions.Save(sess); err != nil {
+			slog.Error("Failed to save review note", "session", key, "error", err)
+		}
+	}
+	slog.Info("Draft reviewed", "draft", id, "action", action, "session", d.Channel+":"+d.ChatID)
+	return d, nil
+}
@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+)
+
+// pausedPrefix marks sessions taken over by a human; the setting holds the
+// time of the takeover.
+const pausedPrefix = "paused:"
+
+// handoffNote tells the model on resume that it did not write the replies
+// since the takeover.
+const handoffNote = "[Handoff] A human operator took over this conversation from %s to %s. " +
+	"Replies in that period were sent by them and are not in this history. Continue from here without repeating their answers."
+
+// Paused reports whether a human has taken over sessionKey, and since when.
+func (l *Loop) Paused(sessionKey string) (since time.Time, paused bool) {
+	if l.timeline == nil {
+		return time.Time{}, false
+	}
+	val, err := l.timeline.GetSetting(pausedPrefix + sessionKey)
+	if err != nil || val == "" {
+		return time.Time{}, false
+	}
+	since, _ = time.Parse(time.RFC3339, val)
+	return since, true
+}
+
+// SetPaused hands sessionKey over to a human or back to the bot. While a
+// session is paused its messages are kept in the history but not answered;
+// on resume the model gets a note about the gap.
+func (l *Loop) SetPaused(sessionKey string, paused bool) error {
+	if l.timeline == nil {
+		return errors.New("pausing conversations requires the timeline")
+	}
+	since, was := l.Paused(sessionKey)
+	if paused == was {
+		return nil
+	}
+	if paused {
+		slog.Info("Conversation handed to a human", "session", sessionKey)
+		return l.timeline.SetSetting(pausedPrefix+sessionKey, time.Now().Format(time.RFC3339))
+	}
+
+	if err := l.timeline.SetSetting(pausedPrefix+sessionKey, ""); err != nil {
+		return err
+	}
+	sess := l.sessions.GetOrCreate(sessionKey)
+	sess.AddMessage("system", fmt.Sprintf(handoffNote, since.Format(time.DateTime), time.Now().Format(time.DateTime)))
+	slog.Info("Conversation handed back to the bot", "session", sessionKey)
+	return l.sessions.Save(sess)
+}
+
+// PausedSessions returns the sessions currently handled by a human and
+// when they were taken over.
+func (l *Loop) PausedSessions() (map[string]time.Time, error) {
+	if l.timeline == nil {
+		return map[string]time.Time{}, nil
+	}
+	settings, err := l.timeline.SettingsWithPrefix(pausedPrefix)
+	if err != nil {
+		return nil, err
+	}
+	paused := make(map[string]time.Time, len(settings))
+	for key, val := range settings {
+		paused[key], _ = time.Parse(time.RFC3339, val)
+	}
+	return paused, nil
+}
+
+// holdMessage records a message for a paused session without answering it,
+// so the model knows what was said once the session resumes.
+func (l *Loop) holdMessage(msg *bus.InboundMessage) {
+	key := sessionKeyFor(msg)
+	sess := l.sessions.GetOrCreate(key)
+	sess.AddMessage("user", msg.Content)
+	if err := l.sessions.Save(sess); err != nil {
+		slog.Error("Failed to save paused session", "session", key, "error", err)
+	}
+	slog.Info("Not answering paused conversation", "session", key)
+}
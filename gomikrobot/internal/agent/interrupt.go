@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/classify"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// ErrStopped is returned for a turn the user cancelled.
+var ErrStopped = errors.New("stopped by the user")
+
+// stoppedNote is kept in the history in place of the unfinished reply.
+const stoppedNote = "[Stopped] The user cancelled this request before it was finished."
+
+// stopWords cancel the running turn of a session when sent on their own.
+var stopWords = map[string]bool{
+	"stop": true, "cancel": true, "abort": true,
+	"stopp": true, "abbrechen": true, "abbruch": true, "halt": true,
+	"arrête": true, "arrete": true, "annuler": true,
+	"para": true, "detente": true, "cancelar": true,
+}
+
+// isStopCommand reports whether content asks to cancel the running turn.
+func isStopCommand(content string) bool {
+	word := strings.ToLower(strings.TrimFunc(content, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}))
+	return stopWords[word]
+}
+
+// trackTurn registers the cancel function of a running turn of sessionKey.
+// The returned function unregisters it.
+func (l *Loop) trackTurn(sessionKey string, cancel context.CancelCauseFunc) func() {
+	l.turnsMu.Lock()
+	defer l.turnsMu.Unlock()
+	id := new(int)
+	l.turns[sessionKey] = append(l.turns[sessionKey], runningTurn{id: id, cancel: cancel})
+	return func() {
+		l.turnsMu.Lock()
+		defer l.turnsMu.Unlock()
+		running := l.turns[sessionKey]
+		for i, t := range running {
+			if t.id == id {
+				running = append(running[:i], running[i+1:]...)
+				break
+			}
+		}
+		if len(running) == 0 {
+			delete(l.turns, sessionKey)
+		} else {
+			l.turns[sessionKey] = running
+		}
+	}
+}
+
+// runningTurn is a turn in progress; id tells turns of one session apart.
+type runningTurn struct {
+	id     *int
+	cancel context.CancelCauseFunc
+}
+
+// Interrupt cancels the running turns of sessionKey, including tool calls
+// in progress. It reports whether there was anything to cancel.
+func (l *Loop) Interrupt(sessionKey string) bool {
+	l.turnsMu.Lock()
+	running := l.turns[sessionKey]
+	l.turnsMu.Unlock()
+	for _, t := range running {
+		t.cancel(ErrStopped)
+	}
+	if len(running) > 0 {
+		slog.Info("Turn stopped by the user", "session", sessionKey)
+	}
+	return len(running) > 0
+}
+
+// urgent reports whether msg may skip the queue: its classification is
+// routed as priority or it comes from a VIP session.
+func (l *Loop) urgent(msg *bus.InboundMessage) bool {
+	label, _ := msg.Metadata["classification"].(string)
+	return l.classifier.Action(label) == classify.ActionPriority || tools.IsAdmin(l.vips, sessionKeyFor(msg))
+}
@@ -3,23 +3,91 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/kamir/gomikrobot/internal/audit"
 	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/classify"
+	"github.com/kamir/gomikrobot/internal/i18n"
 	"github.com/kamir/gomikrobot/internal/provider"
 	"github.com/kamir/gomikrobot/internal/session"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tokens"
 	"github.com/kamir/gomikrobot/internal/tools"
+	"github.com/kamir/gomikrobot/internal/tracing"
 )
 
 // LoopOptions contains configuration for the agent loop.
 type LoopOptions struct {
-	Bus           *bus.MessageBus
-	Provider      provider.LLMProvider
-	Workspace     string
-	Model         string
+	Bus       *bus.MessageBus
+	Provider  provider.LLMProvider
+	Workspace string
+	Model     string
+	// Models are the models conversations may switch to with set_model.
+	Models        []string
 	MaxIterations int
+	// MaxParallelTools bounds how many tool calls from a single LLM turn run concurrently.
+	MaxParallelTools int
+	// ToolTimeout caps the wall-clock time of a single tool call.
+	ToolTimeout time.Duration
+	// MaxToolResultChars caps a tool result sent to the model (default
+	// 16000); the full output is saved as a workspace artifact.
+	MaxToolResultChars int
+	// MaxToolCalls and TurnTimeout bound the tool calls and wall-clock time
+	// of one message (0 = unlimited). The model sees what is left.
+	MaxToolCalls int
+	TurnTimeout  time.Duration
+	// MaxConcurrentSessions bounds how many sessions Run processes at once.
+	// Messages of one session are always handled in order.
+	MaxConcurrentSessions int
+	// HistoryMessages (default 50) and HistoryTokens (0 = unlimited) bound
+	// the conversation history sent with each message.
+	HistoryMessages int
+	HistoryTokens   int
+	// ContextLength is the model's context window in tokens; 0 looks it up
+	// by model name. Requests that would not fit are compacted before they
+	// are sent.
+	ContextLength int
+	// Prompt customizes the system prompt template and sections.
+	Prompt PromptOptions
+	// Timeline, if set, receives one usage record per processed message.
+	Timeline *timeline.TimelineService
+	// Projects enables the switch_project tool.
+	Projects []tools.Project
+	// Admins are session keys (or "channel:*") allowed to use privileged
+	// tools such as update_identity.
+	Admins []string
+	// Background tasks (spawn_task, requires Timeline): how many run at
+	// once and the budget of each.
+	MaxConcurrentTasks int
+	TaskTimeout        time.Duration
+	TaskMaxToolCalls   int
+	// Audit, if set, records every LLM request and response.
+	Audit *audit.Log
+	// Sampling holds the default generation parameters; unset max tokens
+	// and temperature fall back to 4096 and 0.7.
+	Sampling tools.Sampling
+	// Classifier routes inbound messages by their "classification"
+	// metadata, e.g. never replying to spam or answering emergencies first.
+	Classifier *classify.Classifier
+	// VIPs are session keys (or "channel:*") whose messages skip the queue.
+	VIPs []string
+	// Critic optionally scores answers and retries low-scored ones once.
+	Critic CriticOptions
+	// Refusals configures retries and the reply when the provider declines.
+	Refusals RefusalOptions
+	// ReviewSession is told about replies held for review (requires
+	// Timeline); admins approve them there with the review tool.
+	ReviewSession string
+	// SessionsDir holds the session files, ~/.gomikrobot/sessions if empty.
+	SessionsDir string
 }
 
 // Loop is the core agent processing engine.
@@ -31,53 +99,203 @@ type Loop struct {
 	contextBuilder *ContextBuilder
 	workspace      string
 	model          string
+	models         []string
 	maxIterations  int
-	running        bool
+	maxParallel    int
+	toolTimeout    time.Duration
+	maxToolResult  int
+	maxToolCalls   int
+	turnTimeout    time.Duration
+	maxSessions    int
+	contextLength  int
+	timeline       *timeline.TimelineService
+	audit          *audit.Log
+	classifier     *classify.Classifier
+	vips           []string
+	reviewSession  string
+	sampling       tools.Sampling // Guarded by mu
+	critic         CriticOptions  // Guarded by mu
+	refusals       RefusalOptions // Guarded by mu
+	mu             sync.RWMutex
+
+	// Background tasks started with spawn_task.
+	taskSem      chan struct{}
+	taskTimeout  time.Duration
+	taskMaxCalls int
+
+	// Running turns per session, cancelled when the user says "stop".
+	turnsMu sync.Mutex
+	turns   map[string][]runningTurn
+
+	// Shutdown state: stopping refuses new turns (guarded by mu), inflight
+	// counts running turns, and abort cancels them once the drain times out.
+	stopping bool
+	inflight sync.WaitGroup
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	abortCtx context.Context
+	abort    context.CancelFunc
 }
 
+// ErrShuttingDown is returned for messages submitted after Shutdown began.
+var ErrShuttingDown = errors.New("agent is shutting down")
+
+// resumeMetaKey is the session metadata key marking a turn cut off by shutdown.
+const resumeMetaKey = "interrupted"
+
 // NewLoop creates a new agent loop.
 func NewLoop(opts LoopOptions) *Loop {
 	maxIter := opts.MaxIterations
 	if maxIter == 0 {
 		maxIter = 20
 	}
+	maxParallel := opts.MaxParallelTools
+	if maxParallel <= 0 {
+		maxParallel = 4
+	}
+	toolTimeout := opts.ToolTimeout
+	if toolTimeout <= 0 {
+		toolTimeout = 120 * time.Second
+	}
+	maxToolResult := opts.MaxToolResultChars
+	if maxToolResult <= 0 {
+		maxToolResult = 16000
+	}
+	maxSessions := opts.MaxConcurrentSessions
+	if maxSessions <= 0 {
+		maxSessions = 4
+	}
+	maxTasks := opts.MaxConcurrentTasks
+	if maxTasks <= 0 {
+		maxTasks = 2
+	}
 
 	registry := tools.NewRegistry()
 
 	// Create context builder
 	ctxBuilder := NewContextBuilder(opts.Workspace, registry)
+	ctxBuilder.SetPromptOptions(opts.Prompt)
+	ctxBuilder.SetProjects(opts.Projects)
+	ctxBuilder.SetHistoryWindow(opts.HistoryMessages, opts.HistoryTokens)
+
+	sessions := session.NewManager(opts.Workspace)
+	if opts.SessionsDir != "" {
+		sessions = session.NewManagerIn(opts.SessionsDir)
+	}
 
 	loop := &Loop{
 		bus:            opts.Bus,
 		provider:       opts.Provider,
 		registry:       registry,
-		sessions:       session.NewManager(opts.Workspace),
+		sessions:       sessions,
 		contextBuilder: ctxBuilder,
 		workspace:      opts.Workspace,
 		model:          opts.Model,
+		models:         opts.Models,
 		maxIterations:  maxIter,
+		maxParallel:    maxParallel,
+		toolTimeout:    toolTimeout,
+		maxToolResult:  maxToolResult,
+		maxToolCalls:   opts.MaxToolCalls,
+		turnTimeout:    opts.TurnTimeout,
+		maxSessions:    maxSessions,
+		contextLength:  opts.ContextLength,
+		timeline:       opts.Timeline,
+		stopCh:         make(chan struct{}),
+		taskSem:        make(chan struct{}, maxTasks),
+		taskTimeout:    opts.TaskTimeout,
+		taskMaxCalls:   opts.TaskMaxToolCalls,
+		audit:          opts.Audit,
+		classifier:     opts.Classifier,
+		vips:           opts.VIPs,
+		reviewSession:  opts.ReviewSession,
+		turns:          make(map[string][]runningTurn),
+		sampling:       withSamplingDefaults(opts.Sampling),
+		critic:         withCriticDefaults(opts.Critic),
+		refusals:       opts.Refusals,
 	}
+	loop.abortCtx, loop.abort = context.WithCancel(context.Background())
 
 	// Register default tools
 	loop.registerDefaultTools()
+	if len(opts.Projects) > 0 {
+		registry.Register(tools.NewSwitchProjectTool(opts.Projects))
+	}
+	registry.Register(tools.NewPinTool(loop))
+	if opts.Bus != nil {
+		registry.Register(tools.NewSendFileTool(loop, opts.Workspace))
+	}
+	if opts.Timeline != nil {
+		registry.Register(tools.NewSetModelTool(opts.Timeline, opts.Models))
+		registry.Register(tools.NewSetSamplingTool(opts.Timeline))
+		registry.Register(tools.NewTimelineSearchTool(opts.Timeline))
+		registry.Register(tools.NewSpawnTaskTool(loop))
+		registry.Register(tools.NewContactTool(opts.Timeline, opts.Admins))
+		registry.Register(tools.NewSetLanguageTool(opts.Timeline, opts.Admins))
+		registry.Register(tools.NewQuietTool(opts.Timeline, opts.Admins))
+		for _, t := range tools.ReminderTools(opts.Timeline) {
+			registry.Register(t)
+		}
+	}
+	if len(opts.Admins) > 0 {
+		var events tools.EventLogger
+		if opts.Timeline != nil {
+			events = opts.Timeline
+		}
+		registry.Register(tools.NewUpdateIdentityTool(opts.Workspace, opts.Admins, events))
+		if opts.Timeline != nil {
+			registry.Register(tools.NewHandoffTool(loop, opts.Admins))
+			registry.Register(tools.NewReviewTool(loop, opts.Admins))
+		}
+	}
+
+	// Lua tools from the workspace skills directory; built-in names win.
+	scripts, errs := tools.LoadScriptTools(filepath.Join(opts.Workspace, "skills"))
+	for _, err := range errs {
+		slog.Warn("Script tool not loaded", "error", err)
+	}
+	for _, t := range scripts {
+		if _, exists := registry.Get(t.Name()); exists {
+			slog.Warn("Script tool shadows a built-in tool, skipped", "tool", t.Name())
+			continue
+		}
+		registry.Register(t)
+	}
 
 	return loop
 }
 
 func (l *Loop) registerDefaultTools() {
-	l.registry.Register(tools.NewReadFileTool())
-	l.registry.Register(tools.NewWriteFileTool())
-	l.registry.Register(tools.NewEditFileTool())
-	l.registry.Register(tools.NewListDirTool())
-	l.registry.Register(tools.NewExecTool(0, true, l.workspace))
+	tools.RegisterDefaults(l.registry, l.workspace)
 }
 
-// Run starts the agent loop, processing messages from the bus.
+// RegisterTool adds a tool, e.g. one served by a remote tool server.
+func (l *Loop) RegisterTool(tool tools.Tool) {
+	l.registry.Register(tool)
+}
+
+// Run starts the agent loop, processing messages from the bus until ctx is
+// cancelled or Stop is called. A turn in progress is not cut off by either;
+// use Shutdown to drain it.
 func (l *Loop) Run(ctx context.Context) error {
-	l.running = true
 	slog.Info("Agent loop started")
 
-	for l.running {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	turnCtx := context.WithoutCancel(ctx)
+
+	workers := newSessionWorkers(l.maxSessions, func(msg *bus.InboundMessage) {
+		l.handleInbound(turnCtx, msg)
+	})
+
+	for {
 		msg, err := l.bus.ConsumeInbound(ctx)
 		if err != nil {
 			if ctx.Err() != nil {
@@ -86,32 +304,179 @@ func (l *Loop) Run(ctx context.Context) error {
 			slog.Error("Failed to consume message", "error", err)
 			continue
 		}
-
-		response, err := l.processMessage(ctx, msg)
-		if err != nil {
-			slog.Error("Failed to process message", "error", err)
-			response = fmt.Sprintf("Error: %v", err)
+		// "stop" cancels the session's running turn instead of queueing
+		// behind it.
+		if isStopCommand(msg.Content) && l.Interrupt(sessionKeyFor(msg)) {
+			continue
 		}
+		workers.submit(sessionKeyFor(msg), msg, l.urgent(msg))
+	}
+}
 
-		if response != "" {
-			l.bus.PublishOutbound(&bus.OutboundMessage{
-				Channel: msg.Channel,
-				ChatID:  msg.ChatID,
-				Content: response,
-			})
-		}
+// handleInbound processes one bus message and publishes the response.
+func (l *Loop) handleInbound(ctx context.Context, msg *bus.InboundMessage) {
+	if label, _ := msg.Metadata["classification"].(string); l.classifier.Action(label) == classify.ActionIgnore {
+		slog.Info("Not replying to message", "classification", label, "channel", msg.Channel, "chat_id", msg.ChatID)
+		return
+	}
+	if _, paused := l.Paused(sessionKeyFor(msg)); paused {
+		l.holdMessage(msg)
+		return
+	}
+	ctx, span := tracing.StartKind(tracing.ContextWithRemote(ctx, msg.Trace), tracing.KindConsumer, "agent.handle "+msg.Channel,
+		"channel", msg.Channel, "chat_id", msg.ChatID, "queue_wait_ms", time.Since(msg.Timestamp))
+	defer span.End()
+	stopTyping := l.showPresence(msg)
+	response, err := l.processMessage(ctx, msg)
+	stopTyping()
+	span.RecordError(err)
+	if errors.Is(err, ErrShuttingDown) {
+		slog.Warn("Dropping message received during shutdown", "channel", msg.Channel, "chat_id", msg.ChatID)
+		return
+	}
+	if err != nil && l.abortCtx.Err() != nil {
+		// Interrupted by shutdown; the session is marked for resumption.
+		return
+	}
+	if errors.Is(err, ErrStopped) {
+		response = i18n.T(l.replyLanguage(sessionKeyFor(msg), msg.SenderID), i18n.MsgStopped)
+	} else if err != nil {
+		slog.Error("Failed to process message", "error", err)
+		response = i18n.T(l.replyLanguage(sessionKeyFor(msg), msg.SenderID), i18n.MsgError, err)
 	}
 
-	return nil
+	if response == "" {
+		return
+	}
+	if l.ReviewRequired(sessionKeyFor(msg)) && l.holdDraft(msg, response, tracing.Traceparent(ctx)) {
+		return
+	}
+	l.bus.PublishOutbound(&bus.OutboundMessage{
+		Channel: msg.Channel,
+		ChatID:  msg.ChatID,
+		Content: response,
+		Trace:   tracing.Traceparent(ctx),
+	})
+}
+
+// Sessions returns the session manager used by the loop.
+func (l *Loop) Sessions() *session.Manager {
+	return l.sessions
+}
+
+// SetModel changes the default model for subsequent LLM calls.
+func (l *Loop) SetModel(model string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.model = model
 }
 
-// Stop signals the agent loop to stop.
+// Model returns the current default model.
+func (l *Loop) Model() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.model
+}
+
+// Stop makes Run return after the current message. It does not wait.
 func (l *Loop) Stop() {
-	l.running = false
+	l.stopOnce.Do(func() { close(l.stopCh) })
+}
+
+// Shutdown stops accepting messages and waits for in-flight turns to finish.
+// If ctx expires first, the remaining turns are cancelled, their sessions are
+// marked as interrupted, and ctx's error is returned.
+func (l *Loop) Shutdown(ctx context.Context) error {
+	l.Stop()
+	l.mu.Lock()
+	l.stopping = true
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.inflight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	slog.Warn("Shutdown timeout reached, cancelling in-flight turns")
+	l.abort()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		slog.Error("In-flight turns did not stop after cancellation")
+	}
+	return ctx.Err()
 }
 
 // ProcessDirect processes a message directly (for CLI usage).
 func (l *Loop) ProcessDirect(ctx context.Context, content, sessionKey string) (string, error) {
+	return l.process(ctx, content, sessionKey, nil)
+}
+
+// ProcessDirectUsage is ProcessDirect that also returns the tokens used,
+// including failed attempts.
+func (l *Loop) ProcessDirectUsage(ctx context.Context, content, sessionKey string) (string, provider.Usage, error) {
+	var stats turnStats
+	response, err := l.process(context.WithValue(ctx, statsKey{}, &stats), content, sessionKey, nil)
+	return response, stats.Usage, err
+}
+
+// TurnReport describes how a message was processed.
+type TurnReport struct {
+	Model     string
+	Usage     provider.Usage
+	ToolCalls []ToolCallReport
+	Duration  time.Duration
+	Refused   bool
+}
+
+// ToolCallReport is one tool call of a turn.
+type ToolCallReport struct {
+	Name  string
+	Error bool
+}
+
+// ProcessDirectReport processes a message like ProcessDirect and also
+// reports the model, tokens, and tool calls of the turn.
+func (l *Loop) ProcessDirectReport(ctx context.Context, content, sessionKey string) (string, TurnReport, error) {
+	var stats turnStats
+	response, err := l.process(context.WithValue(ctx, statsKey{}, &stats), content, sessionKey, nil)
+	return response, TurnReport{
+		Model:     stats.Model,
+		Usage:     stats.Usage,
+		ToolCalls: stats.Tools,
+		Duration:  stats.Duration,
+		Refused:   stats.Refused,
+	}, err
+}
+
+// ProcessStream processes a message like ProcessDirect and reports content
+// deltas, tool activity, and final usage to emit.
+func (l *Loop) ProcessStream(ctx context.Context, content, sessionKey string, emit StreamHandler) (string, error) {
+	return l.process(ctx, content, sessionKey, emit)
+}
+
+func (l *Loop) process(ctx context.Context, content, sessionKey string, emit StreamHandler) (string, error) {
+	l.mu.Lock()
+	if l.stopping {
+		l.mu.Unlock()
+		return "", ErrShuttingDown
+	}
+	l.inflight.Add(1)
+	l.mu.Unlock()
+	defer l.inflight.Done()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	defer context.AfterFunc(l.abortCtx, func() { cancel(l.abortCtx.Err()) })()
+	defer l.trackTurn(sessionKey, cancel)()
+
 	// Extract channel and chatID from key if possible
 	parts := strings.SplitN(sessionKey, ":", 2)
 	channel, chatID := "cli", "default"
@@ -125,45 +490,261 @@ func (l *Loop) ProcessDirect(ctx context.Context, content, sessionKey string) (s
 
 	// Build messages using the context builder
 	messages := l.contextBuilder.BuildMessages(sess, content, channel, chatID)
+	if marker, ok := sess.GetMeta(resumeMetaKey).(map[string]any); ok {
+		messages[0].Content += fmt.Sprintf("\n\n## Interrupted Turn\nYour reply to %q was cut off by a restart. "+
+			"Mention this and address it if it is still relevant.", marker["message"])
+		sess.SetMeta(resumeMetaKey, nil)
+	}
+	if c := l.contact(tools.SenderFrom(ctx)); c != nil {
+		messages[0].Content += "\n\n## Current Contact\n" + c.Profile()
+	}
+	lang := l.replyLanguage(sessionKey, tools.SenderFrom(ctx))
+	if lang != "" {
+		messages[0].Content += fmt.Sprintf("\n\n## Language\nRespond in %s unless the user explicitly asks for another language.", i18n.Name(lang))
+	}
+	ctx = context.WithValue(ctx, languageKey{}, lang)
+
+	// Tools see the session's project; switch_project may change it.
+	project := tools.NewProjectState(l.contextBuilder.activeProject(sess))
+	ctx = tools.WithProject(ctx, project)
+	ctx = tools.WithSessionKey(ctx, sessionKey)
+	ctx = tools.WithTurn(ctx)
 
 	// Run the agentic loop
-	response, err := l.runAgentLoop(ctx, messages)
+	model := l.sessionModel(sessionKey)
+	forced, _ := ctx.Value(modelKey{}).(string)
+	if forced != "" {
+		model = forced
+	}
+	ctx, span := tracing.Start(ctx, "agent.turn", "session", sessionKey, "model", model, "history_messages", len(messages))
+	defer span.End()
+	var cacheKey string
+	cacheTTL := l.responseCacheTTL(ctx)
+	if cacheTTL > 0 {
+		// The key covers the system prompt and this message only, so the
+		// turn runs without the session history; otherwise sessions with
+		// different histories would share answers.
+		messages = []provider.Message{messages[0], messages[len(messages)-1]}
+		cacheKey = cacheKeyFor(model, messages[0].Content, content)
+		if cached, ok := l.cachedResponse(cacheKey); ok {
+			span.SetAttr("cached", true)
+			sess.AddMessage("assistant", cached)
+			l.sessions.Save(sess)
+			emit.emit(StreamEvent{Type: EventDone, Content: cached, Usage: &provider.Usage{}})
+			return cached, nil
+		}
+	}
+	start := time.Now()
+	response, stats, err := l.runAgentLoop(ctx, model, messages, emit)
+	if err != nil && model != l.Model() && forced == "" && stats.ToolCalls == 0 && ctx.Err() == nil &&
+		!errors.Is(err, provider.ErrRateLimited) && !errors.Is(err, provider.ErrContextLength) {
+		// A broken override must not lock the conversation out: answer
+		// with the default model, which can also switch back.
+		slog.Warn("Session model failed, using the default model", "session", sessionKey, "model", model, "error", err)
+		model = l.Model()
+		response, stats, err = l.runAgentLoop(ctx, model, messages, emit)
+	}
+	if err == nil && emit == nil && !stats.Refused {
+		// Streamed answers are already on screen and are not reviewed.
+		response = l.criticize(ctx, model, messages, content, response, &stats)
+	}
+	stats.Duration = time.Since(start)
+	stats.Model = model
+	span.SetAttr("gen_ai.usage.input_tokens", stats.Usage.PromptTokens, "gen_ai.usage.output_tokens", stats.Usage.CompletionTokens,
+		"tool_calls", stats.ToolCalls, "tool_errors", stats.ToolErrors)
+	span.RecordError(err)
+	l.recordUsage(sessionKey, model, stats, err)
+	if sink, ok := ctx.Value(statsKey{}).(*turnStats); ok {
+		*sink = stats
+	}
+	if p := project.Active(); p != nil {
+		sess.SetMeta(projectMetaKey, p.Name)
+	} else {
+		sess.SetMeta(projectMetaKey, nil)
+	}
+	if errors.Is(context.Cause(ctx), ErrStopped) {
+		sess.AddMessage("assistant", stoppedNote)
+		l.sessions.Save(sess)
+		return "", ErrStopped
+	}
 	if err != nil {
+		if l.abortCtx.Err() != nil {
+			sess.SetMeta(resumeMetaKey, map[string]any{
+				"message":        content,
+				"interrupted_at": time.Now().Format(time.RFC3339),
+			})
+			l.sessions.Save(sess)
+			slog.Warn("Turn interrupted by shutdown", "session", sessionKey)
+		}
 		return "", err
 	}
 
 	// Save session with response
 	sess.AddMessage("assistant", response)
 	l.sessions.Save(sess)
+	if cacheKey != "" {
+		l.cacheResponse(cacheKey, response, cacheTTL)
+	}
 
+	emit.emit(StreamEvent{Type: EventDone, Content: response, Usage: &stats.Usage})
 	return response, nil
 }
 
+// turnStats summarizes the work done for one user message.
+type turnStats struct {
+	Model      string
+	Usage      provider.Usage
+	ToolCalls  int
+	ToolErrors int
+	Duration   time.Duration
+	// Tools lists the calls in the order the model made them.
+	Tools []ToolCallReport
+	// Refused is set when the user got the refusal reply.
+	Refused bool
+}
+
+// statsKey carries a *turnStats that receives the stats of the turn.
+type statsKey struct{}
+
+// modelKey carries a model override for a single request.
+type modelKey struct{}
+
+// WithModel makes the request in ctx use model instead of the session's
+// or the default model.
+func WithModel(ctx context.Context, model string) context.Context {
+	return context.WithValue(ctx, modelKey{}, model)
+}
+
+// sessionModel returns the session's model override, or the default model
+// if there is none or it is no longer among the allowed models.
+func (l *Loop) sessionModel(sessionKey string) string {
+	if l.timeline != nil {
+		if m, err := l.timeline.GetSetting(tools.SessionModelKey(sessionKey)); err == nil && slices.Contains(l.models, m) {
+			return m
+		}
+	}
+	return l.Model()
+}
+
+// languageKey carries the reply language of a turn for canned replies.
+type languageKey struct{}
+
+// replyLanguage returns the language to answer sessionKey in, or "".
+func (l *Loop) replyLanguage(sessionKey, senderID string) string {
+	if l.timeline == nil {
+		return ""
+	}
+	return l.timeline.ReplyLanguage(sessionKey, senderID)
+}
+
+// contact returns the stored profile of senderID, or nil.
+func (l *Loop) contact(senderID string) *timeline.Contact {
+	if l.timeline == nil || senderID == "" {
+		return nil
+	}
+	c, err := l.timeline.GetContact(senderID)
+	if err != nil {
+		if !errors.Is(err, timeline.ErrContactNotFound) {
+			slog.Warn("Failed to load contact", "sender", senderID, "error", err)
+		}
+		return nil
+	}
+	return c
+}
+
+// recordUsage writes a usage record to the timeline, if configured.
+func (l *Loop) recordUsage(sessionKey, model string, stats turnStats, runErr error) {
+	if l.timeline == nil {
+		return
+	}
+	err := l.timeline.RecordUsage(&timeline.UsageRecord{
+		Timestamp:        time.Now(),
+		SessionKey:       sessionKey,
+		Model:            model,
+		PromptTokens:     stats.Usage.PromptTokens,
+		CompletionTokens: stats.Usage.CompletionTokens,
+		ToolCalls:        stats.ToolCalls,
+		ToolErrors:       stats.ToolErrors,
+		DurationMs:       stats.Duration.Milliseconds(),
+		Failed:           runErr != nil,
+		Refused:          stats.Refused,
+	})
+	if err != nil {
+		slog.Warn("Failed to record usage", "error", err)
+	}
+}
+
 func (l *Loop) processMessage(ctx context.Context, msg *bus.InboundMessage) (string, error) {
-	sessionKey := fmt.Sprintf("%s:%s", msg.Channel, msg.ChatID)
-	return l.ProcessDirect(ctx, msg.Content, sessionKey)
+	ctx = tools.WithSender(ctx, msg.SenderID)
+	if l.timeline != nil {
+		if err := l.timeline.SetDetectedLanguage(sessionKeyFor(msg), i18n.Detect(msg.Content)); err != nil {
+			slog.Warn("Failed to save detected language", "error", err)
+		}
+	}
+	// Channels mark deterministic prompts with a "cache_ttl" duration.
+	if s, _ := msg.Metadata["cache_ttl"].(string); s != "" {
+		if ttl, err := time.ParseDuration(s); err == nil {
+			ctx = WithResponseCache(ctx, ttl)
+		}
+	}
+	return l.ProcessDirect(ctx, msg.Content, sessionKeyFor(msg))
 }
 
-func (l *Loop) runAgentLoop(ctx context.Context, messages []provider.Message) (string, error) {
+func (l *Loop) runAgentLoop(ctx context.Context, model string, messages []provider.Message, emit StreamHandler) (string, turnStats, error) {
 	toolDefs := l.buildToolDefinitions()
+	sampling := l.samplingFor(ctx)
+	var stats turnStats
+	compacted := false
 
-	for i := 0; i < l.maxIterations; i++ {
+	calls, timeout := l.maxToolCalls, l.turnTimeout
+	if lim, ok := ctx.Value(turnLimitsKey{}).(turnLimits); ok {
+		calls, timeout = lim.calls, lim.timeout
+	}
+	budget := tools.NewBudget(calls, timeout)
+	ctx = tools.WithBudget(ctx, budget)
+
+	for i := 0; i < l.maxIterations && !budget.Exhausted(); i++ {
 		// Call LLM
-		resp, err := l.provider.Chat(ctx, &provider.ChatRequest{
-			Messages:    messages,
-			Tools:       toolDefs,
-			Model:       l.model,
-			MaxTokens:   4096,
-			Temperature: 0.7,
-		})
+		req := applySampling(&provider.ChatRequest{
+			Messages: withBudgetNote(messages, budget, l.maxIterations-i),
+			Tools:    toolDefs,
+			Model:    model,
+		}, sampling)
+		resp, err := l.chat(ctx, req, emit)
+		if provider.IsContextLengthError(err) {
+			if compacted {
+				slog.Warn("Context still too long after compaction", "error", err)
+				return contextTooLongReply, stats, nil
+			}
+			// Compact once and retry.
+			compacted = true
+			messages = l.compactMessages(ctx, model, messages)
+			req.Messages = withBudgetNote(messages, budget, l.maxIterations-i)
+			resp, err = l.chat(ctx, req, emit)
+			if provider.IsContextLengthError(err) {
+				return contextTooLongReply, stats, nil
+			}
+		}
+		if isRefused(resp, err) {
+			if resp != nil {
+				stats.Usage.Add(resp.Usage)
+			}
+			retried, ok := l.retryRefused(ctx, req, emit, &stats)
+			if !ok {
+				stats.Refused = true
+				return l.refusalReply(ctx), stats, nil
+			}
+			resp, err = retried, nil
+		}
 		if err != nil {
-			return "", fmt.Errorf("LLM call failed: %w", err)
+			return "", stats, fmt.Errorf("LLM call failed: %w", err)
 		}
+		stats.Usage.Add(resp.Usage)
 
 		// Check for tool calls
 		if len(resp.ToolCalls) == 0 {
 			// No tool calls, return the response
-			return resp.Content, nil
+			return resp.Content, stats, nil
 		}
 
 		// Add assistant message with tool calls
@@ -173,25 +754,200 @@ func (l *Loop) runAgentLoop(ctx context.Context, messages []provider.Message) (s
 			ToolCalls: resp.ToolCalls,
 		})
 
-		// Execute each tool call
-		for _, tc := range resp.ToolCalls {
-			result, err := l.registry.Execute(ctx, tc.Name, tc.Arguments)
-			if err != nil {
-				result = fmt.Sprintf("Error: %v", err)
+		// Execute tool calls concurrently; results keep the model's ordering.
+		results := l.executeToolCalls(ctx, resp.ToolCalls, emit)
+		stats.ToolCalls += len(results)
+		for i, r := range results {
+			failed := strings.HasPrefix(r.Content, "Error")
+			if failed {
+				stats.ToolErrors++
 			}
+			stats.Tools = append(stats.Tools, ToolCallReport{Name: resp.ToolCalls[i].Name, Error: failed})
+		}
+		messages = append(messages, results...)
+	}
 
-			// Add tool result
-			messages = append(messages, provider.Message{
+	// Out of budget: ask for the best answer without further tool use.
+	resp, err := l.chat(ctx, applySampling(&provider.ChatRequest{
+		Messages: append(messages, provider.Message{
+			Role:    "system",
+			Content: "[Budget] The tool budget for this request is used up. Answer now with what you have found so far and say briefly what is still incomplete.",
+		}),
+		Model: model,
+	}, sampling), emit)
+	if err != nil || resp.Content == "" {
+		lang, _ := ctx.Value(languageKey{}).(string)
+		return i18n.T(lang, i18n.MsgMaxIterations), stats, nil
+	}
+	stats.Usage.Add(resp.Usage)
+	return resp.Content, stats, nil
+}
+
+// withBudgetNote returns messages with a trailing system note describing the
+// remaining budget. The note is not persisted.
+func withBudgetNote(messages []provider.Message, b *tools.Budget, iterations int) []provider.Message {
+	calls, left := b.Remaining()
+	parts := []string{fmt.Sprintf("%d model turns", iterations)}
+	if calls >= 0 {
+		parts = append(parts, fmt.Sprintf("%d tool calls", calls))
+	}
+	if left >= 0 {
+		parts = append(parts, fmt.Sprintf("~%ds", int(left.Seconds())))
+	}
+	note := fmt.Sprintf("[Budget] You have %s left for this request. Prioritize the most important steps and leave room for the final answer.",
+		strings.Join(parts, ", "))
+
+	out := make([]provider.Message, len(messages), len(messages)+1)
+	copy(out, messages)
+	return append(out, provider.Message{Role: "system", Content: note})
+}
+
+// chat calls the provider, streaming content deltas when a handler is set
+// and the provider supports it.
+func (l *Loop) chat(ctx context.Context, req *provider.ChatRequest, emit StreamHandler) (*provider.ChatResponse, error) {
+	if err := l.checkContextLength(req); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var resp *provider.ChatResponse
+	var err error
+	if sp, ok := l.provider.(provider.StreamingProvider); ok && emit != nil {
+		resp, err = sp.ChatStream(ctx, req, func(delta string) {
+			emit.emit(StreamEvent{Type: EventDelta, Content: delta})
+		})
+	} else {
+		resp, err = l.provider.Chat(ctx, req)
+	}
+	if err == nil {
+		estimateUsage(req, resp)
+	}
+	if l.audit != nil {
+		rec := audit.NewRecord(tools.SessionKeyFrom(ctx), req, resp, err, time.Since(start))
+		if werr := l.audit.Write(rec); werr != nil {
+			slog.Warn("Failed to write audit record", "error", werr)
+		}
+	}
+	return resp, err
+}
+
+// checkContextLength fails with provider.ErrContextLength, without calling
+// the provider, when the prompt and the reply's token limit would not fit
+// in the model's context window.
+func (l *Loop) checkContextLength(req *provider.ChatRequest) error {
+	limit := l.contextLength
+	if limit <= 0 {
+		limit = tokens.ContextLength(req.Model)
+	}
+	if limit <= 0 {
+		return nil
+	}
+	if n := tokens.CountRequest(req); n+req.MaxTokens > limit {
+		return fmt.Errorf("prompt of %d tokens plus %d for the reply exceeds the %d-token context of %s: %w",
+			n, req.MaxTokens, limit, req.Model, provider.ErrContextLength)
+	}
+	return nil
+}
+
+// estimateUsage counts the tokens of resp for providers that do not
+// report usage.
+func estimateUsage(req *provider.ChatRequest, resp *provider.ChatResponse) {
+	if resp.Usage.PromptTokens > 0 || resp.Usage.CompletionTokens > 0 {
+		return
+	}
+	resp.Usage.PromptTokens = tokens.CountRequest(req)
+	resp.Usage.CompletionTokens = tokens.CountReply(req.Model, resp.Content, resp.ToolCalls)
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+}
+
+// executeToolCalls runs the tool calls of one LLM turn on a bounded worker pool.
+// The returned tool messages are in the same order as calls.
+func (l *Loop) executeToolCalls(ctx context.Context, calls []provider.ToolCall, emit StreamHandler) []provider.Message {
+	results := make([]provider.Message, len(calls))
+	sem := make(chan struct{}, l.maxParallel)
+	var wg sync.WaitGroup
+
+	for i, tc := range calls {
+		wg.Add(1)
+		go func(i int, tc provider.ToolCall) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			emit.emit(StreamEvent{Type: EventToolStart, Tool: tc.Name, ToolCallID: tc.ID})
+			start := time.Now()
+			results[i] = provider.Message{
 				Role:       "tool",
-				Content:    result,
+				Content:    l.limitToolResult(tc.Name, l.executeTool(ctx, tc)),
 				ToolCallID: tc.ID,
-			})
+			}
+			emit.emit(StreamEvent{Type: EventToolEnd, Tool: tc.Name, ToolCallID: tc.ID, DurationMS: time.Since(start).Milliseconds()})
+		}(i, tc)
+	}
+	wg.Wait()
+
+	return results
+}
 
-			slog.Debug("Tool executed", "name", tc.Name, "result_length", len(result))
+// executeTool runs a single tool call under the per-tool timeout.
+func (l *Loop) executeTool(ctx context.Context, tc provider.ToolCall) string {
+	timeout := l.toolTimeout
+	if b := tools.BudgetFrom(ctx); b != nil {
+		if _, left := b.Remaining(); left > 0 && left < timeout {
+			timeout = left
+		}
+	}
+	ctx, span := tracing.Start(ctx, "tool "+tc.Name, "tool", tc.Name, "tool_call_id", tc.ID)
+	defer span.End()
+	toolCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan string, 1)
+	go func() {
+		result, err := l.registry.Execute(toolCtx, tc.Name, tc.Arguments)
+		if err != nil {
+			result = fmt.Sprintf("Error: %v", err)
 		}
+		done <- result
+	}()
+
+	var result string
+	select {
+	case result = <-done:
+	case <-toolCtx.Done():
+	}
+	if errors.Is(toolCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		result = fmt.Sprintf("Error: tool %s timed out after %v", tc.Name, timeout)
+	} else if result == "" && toolCtx.Err() != nil {
+		result = fmt.Sprintf("Error: %v", toolCtx.Err())
+	}
+
+	span.SetAttr("result_chars", len(result))
+	if strings.HasPrefix(result, "Error") {
+		line, _, _ := strings.Cut(result, "\n")
+		span.Fail(line)
 	}
+	slog.Debug("Tool executed", "name", tc.Name, "result_length", len(result), "duration", time.Since(start))
+	return result
+}
 
-	return "Max iterations reached. Please try a simpler request.", nil
+// limitToolResult returns result unchanged if it fits maxToolResult.
+// Otherwise the full text is saved as an artifact and the model gets the
+// head and tail plus a note on how to read the rest.
+func (l *Loop) limitToolResult(tool, result string) string {
+	if len(result) <= l.maxToolResult {
+		return result
+	}
+	preview := truncateMiddle(result, l.maxToolResult)
+	if tool == "read_artifact" {
+		return preview
+	}
+	path, err := tools.SaveArtifact(l.workspace, tool, result)
+	if err != nil {
+		slog.Warn("Failed to save tool artifact", "tool", tool, "error", err)
+		return preview
+	}
+	return fmt.Sprintf("%s\n\n[Output truncated: %d characters in total. The full output is saved as %s; use read_artifact to page through it.]", preview, len(result), path)
 }
 
 func (l *Loop) buildToolDefinitions() []provider.ToolDefinition {
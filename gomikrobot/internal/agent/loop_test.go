@@ -0,0 +1,1078 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/classify"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// sleepTool sleeps for the requested duration and tracks peak concurrency.
+type sleepTool struct {
+	active atomic.Int32
+	peak   atomic.Int32
+}
+
+func (t *sleepTool) Name() string               { return "sleep" }
+func (t *sleepTool) Description() string        { return "Sleep for a while" }
+func (t *sleepTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+
+func (t *sleepTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	n := t.active.Add(1)
+	defer t.active.Add(-1)
+	for {
+		p := t.peak.Load()
+		if n <= p || t.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+
+	ms, _ := params["ms"].(float64)
+	select {
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return fmt.Sprintf("slept %v", params["id"]), nil
+}
+
+func newTestLoop(t *testing.T, opts LoopOptions) *Loop {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	opts.Workspace = t.TempDir()
+	return NewLoop(opts)
+}
+
+func TestExecuteToolCallsParallelOrdered(t *testing.T) {
+	loop := newTestLoop(t, LoopOptions{MaxParallelTools: 2})
+	tool := &sleepTool{}
+	loop.registry.Register(tool)
+
+	calls := []provider.ToolCall{
+		{ID: "a", Name: "sleep", Arguments: map[string]any{"id": "a", "ms": float64(80)}},
+		{ID: "b", Name: "sleep", Arguments: map[string]any{"id": "b", "ms": float64(10)}},
+		{ID: "c", Name: "sleep", Arguments: map[string]any{"id": "c", "ms": float64(40)}},
+	}
+
+	msgs := loop.executeToolCalls(context.Background(), calls, nil)
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 tool messages, got %d", len(msgs))
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		if msgs[i].ToolCallID != id {
+			t.Errorf("message %d: expected tool_call_id %s, got %s", i, id, msgs[i].ToolCallID)
+		}
+		if msgs[i].Content != "slept "+id {
+			t.Errorf("message %d: unexpected content %q", i, msgs[i].Content)
+		}
+	}
+
+	if peak := tool.peak.Load(); peak != 2 {
+		t.Errorf("expected peak concurrency 2, got %d", peak)
+	}
+}
+
+func TestExecuteToolCallsTimeout(t *testing.T) {
+	loop := newTestLoop(t, LoopOptions{ToolTimeout: 20 * time.Millisecond})
+	loop.registry.Register(&sleepTool{})
+
+	msgs := loop.executeToolCalls(context.Background(), []provider.ToolCall{
+		{ID: "slow", Name: "sleep", Arguments: map[string]any{"id": "slow", "ms": float64(1000)}},
+	}, nil)
+	if !strings.Contains(msgs[0].Content, "timed out") {
+		t.Errorf("expected timeout message, got %q", msgs[0].Content)
+	}
+}
+
+// scriptedProvider returns canned responses in order and records requests.
+type scriptedProvider struct {
+	steps []func(req *provider.ChatRequest) (*provider.ChatResponse, error)
+	reqs  []*provider.ChatRequest
+}
+
+func (p *scriptedProvider) Chat(ctx context.Context, req *provider.ChatRequest) (*provider.ChatResponse, error) {
+	p.reqs = append(p.reqs, req)
+	if len(p.steps) == 0 {
+		return &provider.ChatResponse{Content: "unexpected call"}, nil
+	}
+	step := p.steps[0]
+	p.steps = p.steps[1:]
+	return step(req)
+}
+
+func (p *scriptedProvider) Transcribe(context.Context, *provider.AudioRequest) (*provider.AudioResponse, error) {
+	return nil, nil
+}
+func (p *scriptedProvider) Speak(context.Context, *provider.TTSRequest) (*provider.TTSResponse, error) {
+	return nil, nil
+}
+func (p *scriptedProvider) DefaultModel() string { return "test-model" }
+
+func overflow(*provider.ChatRequest) (*provider.ChatResponse, error) {
+	return nil, fmt.Errorf("API error (status 400): %w", provider.ErrContextLength)
+}
+
+func reply(text string) func(*provider.ChatRequest) (*provider.ChatResponse, error) {
+	return func(*provider.ChatRequest) (*provider.ChatResponse, error) {
+		return &provider.ChatResponse{Content: text}, nil
+	}
+}
+
+func TestProcessDirectUsage(t *testing.T) {
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		func(*provider.ChatRequest) (*provider.ChatResponse, error) {
+			return &provider.ChatResponse{Content: "spam", Usage: provider.Usage{PromptTokens: 120, CompletionTokens: 3}}, nil
+		},
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov})
+
+	out, usage, err := loop.ProcessDirectUsage(context.Background(), "Classify: you won a prize", "batch:1")
+	if err != nil || out != "spam" {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	if usage.PromptTokens != 120 || usage.CompletionTokens != 3 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){reply("summary 1"), reply("summary 2"), reply("summary 3")}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, Timeline: tl})
+	ctx := WithResponseCache(context.Background(), time.Hour)
+
+	for i := range 2 {
+		out, err := loop.ProcessDirect(ctx, "Summarize the build log", "webhook:a")
+		if err != nil || out != "summary 1" {
+			t.Fatalf("call %d: got %q, %v; want the first summary", i, out, err)
+		}
+	}
+	if len(prov.reqs) != 1 {
+		t.Fatalf("expected 1 provider call, got %d", len(prov.reqs))
+	}
+	if n := len(loop.Sessions().GetOrCreate("webhook:a").Messages); n != 4 {
+		t.Errorf("session has %d messages, want 4", n)
+	}
+
+	if out, _ := loop.ProcessDirect(ctx, "Summarize the deploy log", "webhook:a"); out != "summary 2" {
+		t.Errorf("different prompt: got %q", out)
+	}
+	// The key does not cover the history, so cached turns must not see it.
+	for _, m := range prov.reqs[1].Messages {
+		if m.Role != "system" && m.Content != "Summarize the deploy log" {
+			t.Errorf("cached turn sent history: %s %q", m.Role, m.Content)
+		}
+	}
+	if out, _ := loop.ProcessDirect(context.Background(), "Summarize the build log", "webhook:a"); out != "summary 3" {
+		t.Errorf("uncached request: got %q", out)
+	}
+}
+
+func TestBrokenSessionModelFallsBackToDefault(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	var models []string
+	answer := func(req *provider.ChatRequest) (*provider.ChatResponse, error) {
+		models = append(models, req.Model)
+		if req.Model == "retired-model" {
+			return nil, errors.New("API error (status 404): model not found")
+		}
+		return &provider.ChatResponse{Content: "answer from " + req.Model}, nil
+	}
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){answer, answer, answer}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, Timeline: tl, Model: "default-model", Models: []string{"retired-model"}})
+
+	tl.SetSetting(tools.SessionModelKey("wa:1"), "retired-model")
+	if out, err := loop.ProcessDirect(context.Background(), "hi", "wa:1"); err != nil || out != "answer from default-model" {
+		t.Errorf("got %q, %v; want the default model to answer", out, err)
+	}
+
+	// Overrides of models the operator no longer allows are ignored.
+	tl.SetSetting(tools.SessionModelKey("wa:2"), "gpt-typo")
+	if out, _ := loop.ProcessDirect(context.Background(), "hi", "wa:2"); out != "answer from default-model" {
+		t.Errorf("got %q for a model off the list", out)
+	}
+	if want := []string{"retired-model", "default-model", "default-model"}; !reflect.DeepEqual(models, want) {
+		t.Errorf("models called %v, want %v", models, want)
+	}
+}
+
+func TestCacheKeyIgnoresCurrentTime(t *testing.T) {
+	a := cacheKeyFor("m", "sys\n\n## Current Time\n2026-01-05 10:00 (Monday)\n\n## Current Session\nChat ID: 1", "hi")
+	b := cacheKeyFor("m", "sys\n\n## Current Time\n2026-01-06 11:30 (Tuesday)\n\n## Current Session\nChat ID: 1", "hi")
+	if a != b {
+		t.Error("keys differ by the current time")
+	}
+	if c := cacheKeyFor("m", "sys\n\n## Current Time\n2026-01-05 10:00 (Monday)\n\n## Current Session\nChat ID: 2", "hi"); c == a {
+		t.Error("keys ignore the rest of the system prompt")
+	}
+	if d := cacheKeyFor("other", "sys", "hi"); d == cacheKeyFor("m", "sys", "hi") {
+		t.Error("keys ignore the model")
+	}
+}
+
+func TestContextOverflowCompactsAndRetries(t *testing.T) {
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		overflow,
+		reply("- user likes Go"),
+		reply("final answer"),
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov})
+
+	messages := []provider.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "old question"},
+		{Role: "assistant", Content: "old answer"},
+		{Role: "user", Content: "new question"},
+	}
+	resp, _, err := loop.runAgentLoop(context.Background(), loop.Model(), messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != "final answer" {
+		t.Fatalf("unexpected response %q", resp)
+	}
+
+	retry := prov.reqs[2].Messages
+	if len(retry) != 4 || !strings.Contains(retry[1].Content, "user likes Go") || retry[2].Content != "new question" {
+		t.Errorf("unexpected compacted messages %+v", retry)
+	}
+}
+
+func TestOversizedPromptIsCompactedBeforeSending(t *testing.T) {
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		reply("- user pasted a long log"),
+		reply("final answer"),
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, ContextLength: 20000})
+
+	resp, stats, err := loop.runAgentLoop(context.Background(), loop.Model(), []provider.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: strings.Repeat(" hello", 30000)},
+		{Role: "assistant", Content: "old answer"},
+		{Role: "user", Content: "new question"},
+	}, nil)
+	if err != nil || resp != "final answer" {
+		t.Fatalf("unexpected result %q, %v", resp, err)
+	}
+	if len(prov.reqs) != 2 || !strings.HasPrefix(prov.reqs[0].Messages[0].Content, "Summarize") {
+		t.Fatalf("expected a summary and the compacted request, got %d requests", len(prov.reqs))
+	}
+	if stats.Usage.PromptTokens == 0 || stats.Usage.CompletionTokens == 0 {
+		t.Errorf("usage was not estimated: %+v", stats.Usage)
+	}
+}
+
+func TestContextOverflowTwiceIsFriendly(t *testing.T) {
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		overflow, overflow,
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov})
+
+	resp, _, err := loop.runAgentLoop(context.Background(), loop.Model(), []provider.Message{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "q"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != contextTooLongReply {
+		t.Errorf("expected friendly reply, got %q", resp)
+	}
+}
+
+func TestTruncateMiddle(t *testing.T) {
+	s := strings.Repeat("a", 50) + strings.Repeat("b", 50)
+	got := truncateMiddle(s, 30)
+	if !strings.HasPrefix(got, "aaaa") || !strings.HasSuffix(got, "bbbb") || !strings.Contains(got, "70 characters omitted") {
+		t.Errorf("unexpected truncation %q", got)
+	}
+}
+
+func TestProcessDirectReportListsToolCalls(t *testing.T) {
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		func(*provider.ChatRequest) (*provider.ChatResponse, error) {
+			return &provider.ChatResponse{ToolCalls: []provider.ToolCall{
+				{ID: "a", Name: "sleep", Arguments: map[string]any{"id": "a", "ms": float64(1)}},
+				{ID: "b", Name: "missing_tool", Arguments: map[string]any{}},
+			}}, nil
+		},
+		reply("done"),
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov})
+	loop.registry.Register(&sleepTool{})
+
+	resp, report, err := loop.ProcessDirectReport(context.Background(), "go", "cli:report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != "done" || report.Model != loop.Model() {
+		t.Errorf("unexpected result %q from %q", resp, report.Model)
+	}
+	want := []ToolCallReport{{Name: "sleep"}, {Name: "missing_tool", Error: true}}
+	if !reflect.DeepEqual(report.ToolCalls, want) {
+		t.Errorf("tool calls = %+v, want %+v", report.ToolCalls, want)
+	}
+}
+
+func TestBudgetStopsToolsAndForcesAnswer(t *testing.T) {
+	callTool := func(*provider.ChatRequest) (*provider.ChatResponse, error) {
+		return &provider.ChatResponse{ToolCalls: []provider.ToolCall{
+			{ID: "x", Name: "sleep", Arguments: map[string]any{"id": "x", "ms": float64(1)}},
+		}}, nil
+	}
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		callTool, callTool, reply("best effort"),
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, MaxToolCalls: 2})
+	loop.registry.Register(&sleepTool{})
+
+	resp, stats, err := loop.runAgentLoop(context.Background(), loop.Model(), []provider.Message{{Role: "user", Content: "go"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp != "best effort" || stats.ToolCalls != 2 {
+		t.Errorf("unexpected result %q, %d tool calls", resp, stats.ToolCalls)
+	}
+
+	first := prov.reqs[0].Messages
+	if note := first[len(first)-1].Content; !strings.Contains(note, "2 tool calls") {
+		t.Errorf("expected budget note, got %q", note)
+	}
+	if final := prov.reqs[2]; len(final.Tools) != 0 {
+		t.Error("final answer request should not offer tools")
+	}
+}
+
+// blockingProvider blocks every chat call until its context is cancelled.
+type blockingProvider struct {
+	scriptedProvider
+	started chan struct{}
+}
+
+func (p *blockingProvider) Chat(ctx context.Context, req *provider.ChatRequest) (*provider.ChatResponse, error) {
+	close(p.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestShutdownDrainsAndMarksInterrupted(t *testing.T) {
+	prov := &blockingProvider{started: make(chan struct{})}
+	loop := newTestLoop(t, LoopOptions{Provider: prov})
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := loop.ProcessDirect(context.Background(), "long task", "cli:test")
+		errc <- err
+	}()
+	<-prov.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := loop.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if err := <-errc; err == nil {
+		t.Error("expected the interrupted turn to fail")
+	}
+
+	marker, ok := loop.sessions.GetOrCreate("cli:test").GetMeta(resumeMetaKey).(map[string]any)
+	if !ok || marker["message"] != "long task" {
+		t.Errorf("expected interrupted marker, got %v", marker)
+	}
+
+	if _, err := loop.ProcessDirect(context.Background(), "more", "cli:test"); err != ErrShuttingDown {
+		t.Errorf("expected ErrShuttingDown after shutdown, got %v", err)
+	}
+}
+
+// gatedProvider echoes the last user message; messages containing "slow"
+// wait until release is closed.
+type gatedProvider struct {
+	scriptedProvider
+	release chan struct{}
+}
+
+func (p *gatedProvider) Chat(ctx context.Context, req *provider.ChatRequest) (*provider.ChatResponse, error) {
+	var content string
+	for _, m := range req.Messages {
+		if m.Role == "user" {
+			content = m.Content
+		}
+	}
+	if strings.Contains(content, "slow") {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return &provider.ChatResponse{Content: content}, nil
+}
+
+func TestRunProcessesSessionsConcurrently(t *testing.T) {
+	mb := bus.NewMessageBus()
+	prov := &gatedProvider{release: make(chan struct{})}
+	loop := newTestLoop(t, LoopOptions{Bus: mb, Provider: prov, MaxConcurrentSessions: 2})
+
+	out := make(chan string, 3)
+	mb.Subscribe("test", func(m *bus.OutboundMessage) { out <- m.Content })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx)
+
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "a", Content: "slow one"})
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "b", Content: "fast"})
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "a", Content: "second"})
+
+	next := func() string {
+		select {
+		case c := <-out:
+			return c
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a response")
+			return ""
+		}
+	}
+
+	if got := next(); got != "fast" {
+		t.Fatalf("expected the other session to answer first, got %q", got)
+	}
+	close(prov.release)
+	if got := next(); got != "slow one" {
+		t.Errorf("expected session a in order, got %q first", got)
+	}
+	if got := next(); got != "second" {
+		t.Errorf("expected %q, got %q", "second", got)
+	}
+}
+
+func TestSpawnTaskRunsAndNotifiesOrigin(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	mb := bus.NewMessageBus()
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){reply("Report written to reports/x.md")}}
+	loop := newTestLoop(t, LoopOptions{Bus: mb, Provider: prov, Timeline: tl})
+
+	got := make(chan *bus.OutboundMessage, 1)
+	mb.Subscribe("whatsapp", func(m *bus.OutboundMessage) { got <- m })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+
+	id, err := loop.SpawnTask(tools.WithSessionKey(ctx, "whatsapp:123@s.whatsapp.net"), "Research X", "research X and write reports/x.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-got:
+		if m.ChatID != "123@s.whatsapp.net" || !strings.Contains(m.Content, "Task "+id+" finished") || !strings.Contains(m.Content, "reports/x.md") {
+			t.Errorf("unexpected notification %+v", m)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no completion notification")
+	}
+
+	task, err := tl.GetTask(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.Status != timeline.TaskDone || task.StartedAt == nil || task.FinishedAt == nil {
+		t.Errorf("unexpected task record %+v", task)
+	}
+
+	if _, err := loop.SpawnTask(tools.WithSessionKey(ctx, "task:"+id), "nested", "more"); err == nil {
+		t.Error("expected tasks to be unable to spawn tasks")
+	}
+}
+
+func TestConcurrentSpawnTaskIDsAreUnique(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+	loop := newTestLoop(t, LoopOptions{Provider: &scriptedProvider{}, Timeline: tl})
+	// Keep the tasks queued; only their ids matter here.
+	for range cap(loop.taskSem) {
+		loop.taskSem <- struct{}{}
+	}
+
+	const n = 20
+	ids := make(chan string, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := loop.SpawnTask(tools.WithSessionKey(context.Background(), "whatsapp:123@s.whatsapp.net"), "Run", "run it")
+			if err != nil {
+				t.Error(err)
+			}
+			ids <- id
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	seen := map[string]bool{}
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("task id %s was handed out twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestTasksOfSandboxedChatsAreSandboxed(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	// A fake container runtime shows whether exec went through the sandbox.
+	runtime := filepath.Join(t.TempDir(), "runtime")
+	if err := os.WriteFile(runtime, []byte("#!/bin/sh\necho in-sandbox\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan string, 1)
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		func(*provider.ChatRequest) (*provider.ChatResponse, error) {
+			return &provider.ChatResponse{ToolCalls: []provider.ToolCall{
+				{ID: "a", Name: "exec", Arguments: map[string]any{"command": "echo on-host"}},
+			}}, nil
+		},
+		func(req *provider.ChatRequest) (*provider.ChatResponse, error) {
+			for _, m := range req.Messages {
+				if m.Role == "tool" {
+					done <- m.Content
+				}
+			}
+			return &provider.ChatResponse{Content: "done"}, nil
+		},
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, Timeline: tl})
+	loop.registry.Register(tools.NewExecToolWithOptions(tools.ExecOptions{
+		WorkDir: loop.workspace,
+		Sandbox: &tools.SandboxOptions{Runtime: runtime, Sessions: []string{"whatsapp:*"}},
+	}))
+
+	if _, err := loop.SpawnTask(tools.WithSessionKey(context.Background(), "whatsapp:123@s.whatsapp.net"), "Run", "run it"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case out := <-done:
+		if !strings.Contains(out, "in-sandbox") || strings.Contains(out, "on-host") {
+			t.Errorf("the task's exec ran outside the sandbox: %q", out)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the task did not run exec")
+	}
+}
+
+func TestHandleInboundShowsPresence(t *testing.T) {
+	mb := bus.NewMessageBus()
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){reply("hi")}}
+	loop := newTestLoop(t, LoopOptions{Bus: mb, Provider: prov})
+
+	events := make(chan *bus.PresenceEvent, 10)
+	mb.SubscribePresence("whatsapp", func(e *bus.PresenceEvent) { events <- e })
+
+	loop.handleInbound(context.Background(), &bus.InboundMessage{
+		Channel:  "whatsapp",
+		ChatID:   "123@s.whatsapp.net",
+		SenderID: "123",
+		Content:  "hello",
+		Metadata: map[string]any{"event_id": "MSG1", "sender_jid": "123@s.whatsapp.net"},
+	})
+
+	kinds := map[string]*bus.PresenceEvent{}
+	for len(kinds) < 3 {
+		select {
+		case e := <-events:
+			kinds[e.Kind] = e
+		case <-time.After(time.Second):
+			t.Fatalf("got presence events %v", kinds)
+		}
+	}
+	if r := kinds[bus.PresenceRead]; len(r.MessageIDs) != 1 || r.MessageIDs[0] != "MSG1" {
+		t.Errorf("read receipt = %+v", r)
+	}
+	if kinds[bus.PresenceTypingStop] == nil {
+		t.Error("typing indicator was not cleared")
+	}
+}
+
+func TestPausedSessionIsNotAnswered(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+	mb := bus.NewMessageBus()
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){reply("I'm back")}}
+	loop := newTestLoop(t, LoopOptions{Bus: mb, Provider: prov, Timeline: tl})
+
+	const key = "whatsapp:123@s.whatsapp.net"
+	msg := func(text string) *bus.InboundMessage {
+		return &bus.InboundMessage{Channel: "whatsapp", ChatID: "123@s.whatsapp.net", SenderID: "123", Content: text}
+	}
+
+	if err := loop.SetPaused(key, true); err != nil {
+		t.Fatal(err)
+	}
+	loop.handleInbound(context.Background(), msg("can a human help?"))
+	if len(prov.reqs) != 0 || mb.OutboundSize() != 0 {
+		t.Fatalf("paused session was answered: %d requests, %d outbound", len(prov.reqs), mb.OutboundSize())
+	}
+	if paused, _ := loop.PausedSessions(); len(paused) != 1 {
+		t.Errorf("paused sessions = %v", paused)
+	}
+
+	if err := loop.SetPaused(key, false); err != nil {
+		t.Fatal(err)
+	}
+	loop.handleInbound(context.Background(), msg("thanks, bot again?"))
+	if len(prov.reqs) != 1 || mb.OutboundSize() != 1 {
+		t.Fatalf("resumed session: %d requests, %d outbound", len(prov.reqs), mb.OutboundSize())
+	}
+	var sawHeld, sawNote bool
+	for _, m := range prov.reqs[0].Messages {
+		sawHeld = sawHeld || m.Content == "can a human help?"
+		sawNote = sawNote || (m.Role == "system" && strings.Contains(m.Content, "[Handoff]"))
+	}
+	if !sawHeld || !sawNote {
+		t.Errorf("history after resume missing held message (%v) or handoff note (%v)", sawHeld, sawNote)
+	}
+}
+
+func TestReviewedRepliesAreHeldAsDrafts(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+	mb := bus.NewMessageBus()
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){reply("first draft"), reply("second draft"), reply("ok")}}
+	loop := newTestLoop(t, LoopOptions{Bus: mb, Provider: prov, Timeline: tl, ReviewSession: "cli:admin"})
+
+	const key = "whatsapp:123@s.whatsapp.net"
+	msg := func(text string) *bus.InboundMessage {
+		return &bus.InboundMessage{Channel: "whatsapp", ChatID: "123@s.whatsapp.net", SenderID: "123", Content: text}
+	}
+	if err := loop.SetReview(key, true); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := make(chan *bus.OutboundMessage, 4)
+	mb.Subscribe("whatsapp", func(m *bus.OutboundMessage) { sent <- m })
+	notes := make(chan *bus.OutboundMessage, 4)
+	mb.Subscribe("cli", func(m *bus.OutboundMessage) { notes <- m })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	next := func(ch chan *bus.OutboundMessage) *bus.OutboundMessage {
+		select {
+		case m := <-ch:
+			return m
+		case <-time.After(2 * time.Second):
+			t.Fatal("no outbound message")
+			return nil
+		}
+	}
+
+	loop.handleInbound(ctx, msg("hello"))
+	loop.handleInbound(ctx, msg("are you there?"))
+	if n := next(notes); n.ChatID != "admin" || !strings.Contains(n.Content, "first draft") {
+		t.Errorf("review note = %+v", n)
+	}
+	next(notes)
+	drafts, err := loop.PendingDrafts()
+	if err != nil || len(drafts) != 2 {
+		t.Fatalf("pending drafts = %v, %v", drafts, err)
+	}
+	first, second := drafts[1], drafts[0] // newest first
+
+	if _, err := loop.ReviewDraft(first.ID, DraftEdit, "edited answer"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loop.ReviewDraft(first.ID, DraftApprove, ""); !errors.Is(err, ErrDraftDecided) {
+		t.Errorf("deciding a draft twice: err = %v", err)
+	}
+	if _, err := loop.ReviewDraft(second.ID, DraftReject, ""); err != nil {
+		t.Fatal(err)
+	}
+	if m := next(sent); m.Content != "edited answer" {
+		t.Errorf("sent %q, want the edited draft", m.Content)
+	}
+
+	if err := loop.SetReview(key, false); err != nil {
+		t.Fatal(err)
+	}
+	loop.handleInbound(ctx, msg("thanks"))
+	if m := next(sent); m.Content != "ok" {
+		t.Errorf("after review mode ended, sent %q", m.Content)
+	}
+	var sawEdit, sawReject bool
+	for _, m := range prov.reqs[2].Messages {
+		sawEdit = sawEdit || (m.Role == "system" && strings.Contains(m.Content, "edited answer"))
+		sawReject = sawReject || (m.Role == "system" && strings.Contains(m.Content, "rejected"))
+	}
+	if !sawEdit || !sawReject {
+		t.Errorf("history after review missing edit note (%v) or reject note (%v)", sawEdit, sawReject)
+	}
+}
+
+func TestCriticRetriesLowScoredAnswers(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		reply("Paris, probably."),
+		reply("```json\n{\"score\": 3, \"critique\": \"Hedges on a simple fact.\"}\n```"),
+		reply("The capital of France is Paris."),
+		reply("Fine."),
+		reply(`{"score": 9, "critique": ""}`),
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, Timeline: tl, Critic: CriticOptions{Enabled: true, Model: "critic-model"}})
+
+	got, err := loop.ProcessDirect(context.Background(), "What is the capital of France?", "cli:default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "The capital of France is Paris." {
+		t.Errorf("answer = %q, want the retried one", got)
+	}
+	if prov.reqs[1].Model != "critic-model" || !strings.Contains(prov.reqs[1].Messages[1].Content, "Paris, probably.") {
+		t.Errorf("critic request = %+v", prov.reqs[1])
+	}
+	last := prov.reqs[2].Messages[len(prov.reqs[2].Messages)-2]
+	if last.Role != "system" || !strings.Contains(last.Content, "3/10: Hedges on a simple fact.") {
+		t.Errorf("retry should carry the critique, got %+v", last)
+	}
+
+	if got, _ := loop.ProcessDirect(context.Background(), "How are you?", "cli:default"); got != "Fine." || len(prov.reqs) != 5 {
+		t.Errorf("well-scored answer should be kept without retry: %q after %d requests", got, len(prov.reqs))
+	}
+	events, err := tl.GetEvents(timeline.FilterArgs{Limit: 10})
+	if err != nil || len(events) != 2 || !strings.Contains(events[1].ContentText, "retried") {
+		t.Errorf("critic events = %+v, %v", events, err)
+	}
+}
+
+func TestRegenerateReplacesLastReply(t *testing.T) {
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){reply("first"), reply("second")}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, Model: "default-model"})
+
+	if _, err := loop.Regenerate(context.Background(), "cli:regen", "", tools.Sampling{}); !errors.Is(err, ErrNothingToRegenerate) {
+		t.Fatalf("empty session: err = %v", err)
+	}
+	if _, err := loop.ProcessDirect(context.Background(), "tell me a joke", "cli:regen"); err != nil {
+		t.Fatal(err)
+	}
+	temp := 1.2
+	got, err := loop.Regenerate(context.Background(), "cli:regen", "other-model", tools.Sampling{Temperature: &temp})
+	if err != nil || got != "second" {
+		t.Fatalf("Regenerate = %q, %v", got, err)
+	}
+	if req := prov.reqs[1]; req.Model != "other-model" || req.Temperature != 1.2 {
+		t.Errorf("regenerate request used model %q, temperature %v", req.Model, req.Temperature)
+	}
+	history := loop.Sessions().GetOrCreate("cli:regen").GetHistory(10)
+	if len(history) != 2 || history[0].Content != "tell me a joke" || history[1].Content != "second" {
+		t.Errorf("history after regenerate = %+v", history)
+	}
+}
+
+func TestProcessStructuredCorrectsInvalidReplies(t *testing.T) {
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		reply(`{"city": "Paris"}`),
+		reply("```json\n{\"city\": \"Paris\", \"population\": 2100000}\n```"),
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov})
+	schema := provider.JSONSchema{Name: "city", Schema: map[string]any{
+		"type":     "object",
+		"required": []any{"city", "population"},
+		"properties": map[string]any{
+			"city":       map[string]any{"type": "string"},
+			"population": map[string]any{"type": "integer"},
+		},
+	}}
+
+	out, err := loop.ProcessStructured(context.Background(), "Largest city in France?", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"city": "Paris", "population": 2100000}` {
+		t.Errorf("result = %s", out)
+	}
+	if prov.reqs[0].ResponseSchema == nil || len(prov.reqs[0].Tools) != 0 {
+		t.Error("request should carry the schema and no tools")
+	}
+	if last := prov.reqs[1].Messages[len(prov.reqs[1].Messages)-1]; !strings.Contains(last.Content, `"population"`) {
+		t.Errorf("correction should name the problem, got %q", last.Content)
+	}
+
+	prov.steps = append(prov.steps, reply("no idea"), reply("still no idea"))
+	if _, err := loop.ProcessStructured(context.Background(), "Largest city in Atlantis?", schema); !errors.Is(err, ErrInvalidStructured) {
+		t.Errorf("err = %v, want ErrInvalidStructured", err)
+	}
+}
+
+func TestSamplingLayersDefaultsSessionAndRequest(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){reply("a"), reply("b"), reply("c")}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, Timeline: tl, Sampling: tools.Sampling{TopP: 0.9}})
+
+	if _, err := loop.ProcessDirect(context.Background(), "hi", "cli:default"); err != nil {
+		t.Fatal(err)
+	}
+	if r := prov.reqs[0]; r.MaxTokens != 4096 || r.Temperature != 0.7 || r.TopP != 0.9 {
+		t.Errorf("defaults: got max_tokens %d, temperature %v, top_p %v", r.MaxTokens, r.Temperature, r.TopP)
+	}
+
+	ctx := tools.WithSessionKey(context.Background(), "cli:default")
+	if out, _ := tools.NewSetSamplingTool(tl).Execute(ctx, map[string]any{"temperature": float64(0), "max_tokens": float64(500)}); strings.HasPrefix(out, "Error") {
+		t.Fatal(out)
+	}
+	if _, err := loop.ProcessDirect(context.Background(), "hi", "cli:default"); err != nil {
+		t.Fatal(err)
+	}
+	if r := prov.reqs[1]; r.MaxTokens != 500 || r.Temperature != 0 || r.TopP != 0.9 {
+		t.Errorf("session: got max_tokens %d, temperature %v, top_p %v", r.MaxTokens, r.Temperature, r.TopP)
+	}
+
+	temp := 1.2
+	if _, err := loop.ProcessDirect(WithSampling(context.Background(), tools.Sampling{Temperature: &temp, ReasoningEffort: "low"}), "hi", "cli:default"); err != nil {
+		t.Fatal(err)
+	}
+	if r := prov.reqs[2]; r.MaxTokens != 500 || r.Temperature != 1.2 || r.ReasoningEffort != "low" {
+		t.Errorf("request: got max_tokens %d, temperature %v, effort %q", r.MaxTokens, r.Temperature, r.ReasoningEffort)
+	}
+}
+
+func TestLargeToolResultIsStoredAsArtifact(t *testing.T) {
+	loop := newTestLoop(t, LoopOptions{MaxToolResultChars: 100})
+	full := strings.Repeat("0123456789", 50)
+
+	got := loop.limitToolResult("exec", full)
+	if len(got) > 400 || !strings.Contains(got, "read_artifact") {
+		t.Fatalf("expected truncated preview with note, got %q", got)
+	}
+	start := strings.Index(got, "artifacts/")
+	path := strings.Fields(got[start:])[0]
+	path = strings.TrimSuffix(path, ";")
+
+	page, err := loop.registry.Execute(context.Background(), "read_artifact", map[string]any{"path": path, "offset": float64(495), "limit": float64(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(page, "56789") || !strings.Contains(page, "of 500") {
+		t.Errorf("unexpected page %q", page)
+	}
+	if out, _ := loop.registry.Execute(context.Background(), "read_artifact", map[string]any{"path": "../config.json"}); !strings.HasPrefix(out, "Error") {
+		t.Errorf("expected paths outside artifacts to be refused, got %q", out)
+	}
+}
+
+func TestUrgentMessagesSkipTheQueue(t *testing.T) {
+	mb := bus.NewMessageBus()
+	prov := &gatedProvider{release: make(chan struct{})}
+	classifier, err := classify.New(config.ClassificationConfig{Enabled: true, Routes: map[string]string{"emergency": "priority"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loop := newTestLoop(t, LoopOptions{Bus: mb, Provider: prov, MaxConcurrentSessions: 1, Classifier: classifier, VIPs: []string{"test:boss"}})
+
+	out := make(chan string, 4)
+	mb.Subscribe("test", func(m *bus.OutboundMessage) { out <- m.Content })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx)
+
+	next := func() string {
+		select {
+		case c := <-out:
+			return c
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a response")
+			return ""
+		}
+	}
+
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "a", Content: "slow one"})
+	// The slow turn must hold the only slot before the others arrive.
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(time.Millisecond) {
+		loop.turnsMu.Lock()
+		running := len(loop.turns["test:a"]) > 0
+		loop.turnsMu.Unlock()
+		if running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("slow turn did not start")
+		}
+	}
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "b", Content: "normal"})
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "c", Content: "fire!", Metadata: map[string]any{"classification": classify.Emergency}})
+	if got := next(); got != "fire!" {
+		t.Fatalf("expected the emergency to be answered while the slot is busy, got %q", got)
+	}
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "boss", Content: "from the boss"})
+	if got := next(); got != "from the boss" {
+		t.Fatalf("expected the VIP to be answered next, got %q", got)
+	}
+	close(prov.release)
+	if got := next(); got != "slow one" {
+		t.Errorf("expected %q, got %q", "slow one", got)
+	}
+	if got := next(); got != "normal" {
+		t.Errorf("expected %q, got %q", "normal", got)
+	}
+}
+
+func TestStopCancelsRunningTurn(t *testing.T) {
+	mb := bus.NewMessageBus()
+	prov := &gatedProvider{release: make(chan struct{})}
+	loop := newTestLoop(t, LoopOptions{Bus: mb, Provider: prov})
+
+	out := make(chan string, 2)
+	mb.Subscribe("test", func(m *bus.OutboundMessage) { out <- m.Content })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+	go loop.Run(ctx)
+
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "a", Content: "slow research"})
+	for deadline := time.Now().Add(2 * time.Second); ; {
+		loop.turnsMu.Lock()
+		running := len(loop.turns["test:a"])
+		loop.turnsMu.Unlock()
+		if running > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("turn did not start")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mb.PublishInbound(&bus.InboundMessage{Channel: "test", ChatID: "a", Content: "Stop!"})
+
+	select {
+	case got := <-out:
+		if got != "Stopped." {
+			t.Errorf("expected the stop confirmation, got %q", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("turn was not stopped")
+	}
+	if last := loop.sessions.GetOrCreate("test:a").GetHistory(1); len(last) != 1 || last[0].Content != stoppedNote {
+		t.Errorf("expected the stop note in the history, got %+v", last)
+	}
+	if loop.Interrupt("test:a") {
+		t.Error("nothing should be left to interrupt")
+	}
+}
+
+func TestIsStopCommand(t *testing.T) {
+	for _, s := range []string{"stop", " Stop! ", "abbrechen.", "CANCEL"} {
+		if !isStopCommand(s) {
+			t.Errorf("%q should stop", s)
+		}
+	}
+	for _, s := range []string{"stop the music", "don't stop", ""} {
+		if isStopCommand(s) {
+			t.Errorf("%q should not stop", s)
+		}
+	}
+}
+
+func TestRefusalRetriesWithSoftenedPromptAndFallbackModel(t *testing.T) {
+	filtered := func(*provider.ChatRequest) (*provider.ChatResponse, error) {
+		return nil, fmt.Errorf("API error (status 400): %w", provider.ErrContentFiltered)
+	}
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		filtered,
+		reply("I'm sorry, but I can't help with that."),
+		reply("Here is the safe part of the answer."),
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, Refusals: RefusalOptions{Retry: true, FallbackModel: "other-model"}})
+
+	out, err := loop.ProcessDirect(context.Background(), "question", "cli:default")
+	if err != nil || out != "Here is the safe part of the answer." {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	if len(prov.reqs) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(prov.reqs))
+	}
+	if last := prov.reqs[1].Messages[len(prov.reqs[1].Messages)-1]; last.Content != softenNote {
+		t.Errorf("retry lacks the softening note: %q", last.Content)
+	}
+	if prov.reqs[2].Model != "other-model" {
+		t.Errorf("fallback used model %q", prov.reqs[2].Model)
+	}
+}
+
+func TestRefusalReplyIsRecorded(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	prov := &scriptedProvider{steps: []func(*provider.ChatRequest) (*provider.ChatResponse, error){
+		func(*provider.ChatRequest) (*provider.ChatResponse, error) {
+			return &provider.ChatResponse{Refusal: "I can't assist with that."}, nil
+		},
+	}}
+	loop := newTestLoop(t, LoopOptions{Provider: prov, Timeline: tl, Refusals: RefusalOptions{Message: "Not something I can do here."}})
+
+	out, err := loop.ProcessDirect(context.Background(), "question", "cli:default")
+	if err != nil || out != "Not something I can do here." {
+		t.Fatalf("unexpected result %q, %v", out, err)
+	}
+	totals, err := tl.UsageBetween(time.Now().Add(-time.Minute), time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.Requests != 1 || totals.Refused != 1 || totals.Failed != 0 {
+		t.Errorf("unexpected usage totals %+v", totals)
+	}
+}
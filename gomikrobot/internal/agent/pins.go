@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/session"
+)
+
+const (
+	// maxPins bounds the pinned messages of one session.
+	maxPins = 20
+	// maxPinChars bounds the size of one pinned message.
+	maxPinChars = 4000
+)
+
+// ErrPinNotFound is returned when unpinning an unknown pin.
+var ErrPinNotFound = errors.New("pin not found")
+
+// Pin adds content to the pinned messages of sessionKey, which are sent
+// with every request of the session regardless of the history window.
+func (l *Loop) Pin(sessionKey, content string) (session.Pin, error) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return session.Pin{}, errors.New("content is empty")
+	}
+	if len(content) > maxPinChars {
+		return session.Pin{}, fmt.Errorf("pinned messages are limited to %d characters", maxPinChars)
+	}
+	sess := l.sessions.GetOrCreate(sessionKey)
+	if len(sess.Pins()) >= maxPins {
+		return session.Pin{}, fmt.Errorf("at most %d messages can be pinned; unpin one first", maxPins)
+	}
+	pin := sess.AddPin(content)
+	return pin, l.sessions.Save(sess)
+}
+
+// Unpin removes a pinned message of sessionKey.
+func (l *Loop) Unpin(sessionKey string, id int) error {
+	sess := l.sessions.GetOrCreate(sessionKey)
+	if !sess.RemovePin(id) {
+		return ErrPinNotFound
+	}
+	return l.sessions.Save(sess)
+}
+
+// Pins returns the pinned messages of sessionKey.
+func (l *Loop) Pins(sessionKey string) []session.Pin {
+	return l.sessions.GetOrCreate(sessionKey).Pins()
+}
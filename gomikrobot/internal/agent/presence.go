@@ -0,0 +1,59 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+)
+
+// typingRefresh re-sends the typing indicator while a turn runs; WhatsApp
+// clears it after about 25 seconds.
+const typingRefresh = 10 * time.Second
+
+// showPresence marks msg as read and shows a typing indicator in its chat
+// until the returned function is called, so users see the bot working
+// through long tool chains. Channels without presence support ignore it.
+func (l *Loop) showPresence(msg *bus.InboundMessage) (stop func()) {
+	if id, _ := msg.Metadata["event_id"].(string); id != "" {
+		sender, _ := msg.Metadata["sender_jid"].(string)
+		l.bus.PublishPresence(&bus.PresenceEvent{
+			Channel:    msg.Channel,
+			ChatID:     msg.ChatID,
+			Kind:       bus.PresenceRead,
+			MessageIDs: []string{id},
+			SenderID:   sender,
+		})
+	}
+
+	typing := func(kind string) {
+		l.bus.PublishPresence(&bus.PresenceEvent{Channel: msg.Channel, ChatID: msg.ChatID, Kind: kind})
+	}
+	typing(bus.PresenceTyping)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(typingRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				typing(bus.PresenceTyping)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+			typing(bus.PresenceTypingStop)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/kamir/gomikrobot/internal/i18n"
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+// RefusalOptions configures what happens when the provider declines a
+// request or its content filter blocks it.
+type RefusalOptions struct {
+	// Message replaces the refusal sent to the user (default: a localized
+	// request to rephrase).
+	Message string
+	// Retry resends the request once with a note asking the model to help
+	// with what it can.
+	Retry bool
+	// FallbackModel is tried when the request is still refused.
+	FallbackModel string
+}
+
+// softenNote is added to a retried request after a refusal.
+const softenNote = "[Note] The previous reply to this request was declined. If part of the request is acceptable, " +
+	"help with that part. Otherwise briefly say what you can't help with, without boilerplate."
+
+// SetRefusals changes the refusal handling for subsequent turns.
+func (l *Loop) SetRefusals(r RefusalOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refusals = r
+}
+
+// isRefused reports whether a chat result is a refusal or a filtered
+// request.
+func isRefused(resp *provider.ChatResponse, err error) bool {
+	if err != nil {
+		return provider.IsContentFilterError(err)
+	}
+	return provider.IsRefusal(resp)
+}
+
+// retryRefused retries a refused request as configured: once with a
+// softened prompt and then with the fallback model. It returns the first
+// answer that is not refused, or ok false. The usage of refused attempts
+// is added to stats.
+func (l *Loop) retryRefused(ctx context.Context, req *provider.ChatRequest, emit StreamHandler, stats *turnStats) (resp *provider.ChatResponse, ok bool) {
+	l.mu.RLock()
+	opts := l.refusals
+	l.mu.RUnlock()
+	slog.Warn("Provider refused the request", "model", req.Model, "retry", opts.Retry, "fallback", opts.FallbackModel)
+
+	retry := *req
+	if opts.Retry {
+		retry.Messages = append(append([]provider.Message(nil), req.Messages...), provider.Message{Role: "system", Content: softenNote})
+		resp, err := l.chat(ctx, &retry, emit)
+		if err == nil && !isRefused(resp, err) {
+			return resp, true
+		}
+		if resp != nil {
+			stats.Usage.Add(resp.Usage)
+		}
+	}
+	if opts.FallbackModel != "" && opts.FallbackModel != req.Model {
+		retry.Model = opts.FallbackModel
+		resp, err := l.chat(ctx, &retry, emit)
+		if err == nil && !isRefused(resp, err) {
+			return resp, true
+		}
+		if resp != nil {
+			stats.Usage.Add(resp.Usage)
+		}
+	}
+	return nil, false
+}
+
+// refusalReply is sent instead of the provider's refusal.
+func (l *Loop) refusalReply(ctx context.Context) string {
+	l.mu.RLock()
+	msg := l.refusals.Message
+	l.mu.RUnlock()
+	if msg != "" {
+		return msg
+	}
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return i18n.T(lang, i18n.MsgRefused)
+}
@@ -0,0 +1,26 @@
+package agent
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// ErrNothingToRegenerate is returned for sessions without a user message.
+var ErrNothingToRegenerate = errors.New("session has no message to answer again")
+
+// Regenerate discards the last reply of sessionKey and answers the last
+// user message again. model and sampling, if set, apply to this answer
+// only. The new reply is returned, not sent to the chat.
+func (l *Loop) Regenerate(ctx context.Context, sessionKey, model string, sampling tools.Sampling) (string, error) {
+	sess := l.sessions.GetOrCreate(sessionKey)
+	content, ok := sess.RewindLastTurn()
+	if !ok {
+		return "", ErrNothingToRegenerate
+	}
+	if model != "" {
+		ctx = WithModel(ctx, model)
+	}
+	return l.process(WithSampling(ctx, sampling), content, sessionKey, nil)
+}
@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// responseCacheKey carries the TTL of a turn's cached response.
+type responseCacheKey struct{}
+
+// WithResponseCache lets the turn in ctx reuse a response cached within
+// ttl and caches its own response for ttl. It is meant for deterministic
+// requests, such as scheduled or webhook summaries: the cache key covers
+// the model, the system prompt, and the message, and the turn is run
+// without the session history. Caching needs a Timeline; ttl <= 0
+// disables it.
+func WithResponseCache(ctx context.Context, ttl time.Duration) context.Context {
+	return context.WithValue(ctx, responseCacheKey{}, ttl)
+}
+
+// responseCacheTTL returns the cache TTL of the turn, or 0.
+func (l *Loop) responseCacheTTL(ctx context.Context) time.Duration {
+	ttl, _ := ctx.Value(responseCacheKey{}).(time.Duration)
+	if l.timeline == nil || ttl <= 0 {
+		return 0
+	}
+	return ttl
+}
+
+// cacheKeyFor hashes what determines a cached response. The current time
+// in the system prompt is left out so entries survive their first minute.
+func cacheKeyFor(model, systemPrompt, content string) string {
+	if i := strings.Index(systemPrompt, "\n\n## Current Time\n"); i >= 0 {
+		rest := systemPrompt[i+2:]
+		if j := strings.Index(rest, "\n\n"); j >= 0 {
+			systemPrompt = systemPrompt[:i] + rest[j:]
+		} else {
+			systemPrompt = systemPrompt[:i]
+		}
+	}
+	h := sha256.New()
+	for _, s := range []string{model, systemPrompt, content} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedResponse returns the cached response for key, if any.
+func (l *Loop) cachedResponse(key string) (string, bool) {
+	response, ok, err := l.timeline.CachedResponse(key)
+	if err != nil {
+		slog.Warn("Failed to read response cache", "error", err)
+		return "", false
+	}
+	return response, ok
+}
+
+// cacheResponse stores the response of a turn for ttl.
+func (l *Loop) cacheResponse(key, response string, ttl time.Duration) {
+	if err := l.timeline.CacheResponse(key, response, ttl); err != nil {
+		slog.Warn("Failed to cache response", "error", err)
+	}
+}
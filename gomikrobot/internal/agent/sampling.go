@@ -0,0 +1,68 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// Fallbacks for LoopOptions.Sampling fields left unset.
+const (
+	defaultMaxTokens   = 4096
+	defaultTemperature = 0.7
+)
+
+// samplingKey carries per-request sampling overrides.
+type samplingKey struct{}
+
+// WithSampling attaches overrides for a single request to ctx, e.g. the
+// temperature sent by an OpenAI-compatible client. They take precedence
+// over the session's set_sampling overrides and the configured defaults.
+func WithSampling(ctx context.Context, s tools.Sampling) context.Context {
+	return context.WithValue(ctx, samplingKey{}, s)
+}
+
+// SetSampling changes the default sampling for subsequent LLM calls.
+func (l *Loop) SetSampling(s tools.Sampling) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sampling = withSamplingDefaults(s)
+}
+
+func withSamplingDefaults(s tools.Sampling) tools.Sampling {
+	if s.MaxTokens <= 0 {
+		s.MaxTokens = defaultMaxTokens
+	}
+	if s.Temperature == nil {
+		t := defaultTemperature
+		s.Temperature = &t
+	}
+	return s
+}
+
+// samplingFor resolves the sampling of a turn: configured defaults, then
+// the session's overrides, then the request's.
+func (l *Loop) samplingFor(ctx context.Context) tools.Sampling {
+	l.mu.RLock()
+	s := l.sampling
+	l.mu.RUnlock()
+	if key := tools.SessionKeyFrom(ctx); key != "" && l.timeline != nil {
+		s = s.Merge(tools.SessionSampling(l.timeline, key))
+	}
+	if o, ok := ctx.Value(samplingKey{}).(tools.Sampling); ok {
+		s = s.Merge(o)
+	}
+	return s
+}
+
+// applySampling copies s into req.
+func applySampling(req *provider.ChatRequest, s tools.Sampling) *provider.ChatRequest {
+	req.MaxTokens = s.MaxTokens
+	if s.Temperature != nil {
+		req.Temperature = *s.Temperature
+	}
+	req.TopP = s.TopP
+	req.ReasoningEffort = s.ReasoningEffort
+	return req
+}
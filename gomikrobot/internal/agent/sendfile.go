@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/tracing"
+)
+
+// SendFile queues the file at path, with an optional caption, for the chat
+// of sessionKey. Sessions under review cannot receive files, since drafts
+// hold text only.
+func (l *Loop) SendFile(ctx context.Context, sessionKey, path, caption string) error {
+	if l.bus == nil {
+		return errors.New("no channels to send files on")
+	}
+	channel, chatID, ok := strings.Cut(sessionKey, ":")
+	if !ok || chatID == "" {
+		return fmt.Errorf("session %q has no chat", sessionKey)
+	}
+	if l.ReviewRequired(sessionKey) {
+		return errors.New("replies to this chat are reviewed before sending; files cannot be held for review")
+	}
+	slog.Info("Sending file", "session", sessionKey, "path", path)
+	l.bus.PublishOutbound(&bus.OutboundMessage{
+		Channel: channel,
+		ChatID:  chatID,
+		Content: caption,
+		Media:   []string{path},
+		Trace:   tracing.Traceparent(ctx),
+	})
+	return nil
+}
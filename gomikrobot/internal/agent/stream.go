@@ -0,0 +1,31 @@
+package agent
+
+import "github.com/kamir/gomikrobot/internal/provider"
+
+// Stream event types emitted while processing a message.
+const (
+	EventDelta     = "delta"
+	EventToolStart = "tool_start"
+	EventToolEnd   = "tool_end"
+	EventDone      = "done"
+)
+
+// StreamEvent reports progress of a single agent turn.
+type StreamEvent struct {
+	Type       string          `json:"type"`
+	Content    string          `json:"content,omitempty"`
+	Tool       string          `json:"tool,omitempty"`
+	ToolCallID string          `json:"tool_call_id,omitempty"`
+	DurationMS int64           `json:"duration_ms,omitempty"`
+	Usage      *provider.Usage `json:"usage,omitempty"`
+}
+
+// StreamHandler receives stream events. It may be called from several
+// goroutines when tools run in parallel.
+type StreamHandler func(StreamEvent)
+
+func (h StreamHandler) emit(evt StreamEvent) {
+	if h != nil {
+		h(evt)
+	}
+}
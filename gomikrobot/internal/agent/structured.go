@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// ErrInvalidStructured is returned when the model's reply does not match
+// the requested schema, even after one correction.
+var ErrInvalidStructured = errors.New("reply does not match the schema")
+
+const structuredPrompt = "You produce machine-readable results for other programs. " +
+	"Reply with a single JSON value matching the %q schema below, without prose or code fences.\n\n%s"
+
+// ProcessStructured answers prompt with JSON matching schema, without tools
+// and outside any conversation history. The reply is checked against the
+// schema; a mismatch is sent back to the model once for correction.
+// Usage is recorded under the session key in ctx, or "structured:<name>".
+func (l *Loop) ProcessStructured(ctx context.Context, prompt string, schema provider.JSONSchema) (json.RawMessage, error) {
+	if err := schema.Check(); err != nil {
+		return nil, err
+	}
+	schemaJSON, err := json.MarshalIndent(schema.Schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", schema.Name, err)
+	}
+
+	sessionKey := cmp.Or(tools.SessionKeyFrom(ctx), "structured:"+schema.Name)
+	model := l.Model()
+	if m, _ := ctx.Value(modelKey{}).(string); m != "" {
+		model = m
+	}
+	messages := []provider.Message{
+		{Role: "system", Content: fmt.Sprintf(structuredPrompt, schema.Name, schemaJSON)},
+		{Role: "user", Content: prompt},
+	}
+
+	var stats turnStats
+	var result json.RawMessage
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		req := applySampling(&provider.ChatRequest{Messages: messages, Model: model}, l.samplingFor(ctx))
+		req.ResponseSchema = &schema
+		var resp *provider.ChatResponse
+		if resp, err = l.chat(ctx, req, nil); err != nil {
+			err = fmt.Errorf("LLM call failed: %w", err)
+			break
+		}
+		stats.Usage.Add(resp.Usage)
+
+		out := provider.ExtractJSON(resp.Content)
+		verr := schema.Validate([]byte(out))
+		if verr == nil {
+			result, err = json.RawMessage(out), nil
+			break
+		}
+		slog.Warn("Structured reply does not match the schema", "schema", schema.Name, "attempt", attempt, "error", verr)
+		if err = fmt.Errorf("%w: %v", ErrInvalidStructured, verr); attempt == 2 {
+			break
+		}
+		messages = append(messages,
+			provider.Message{Role: "assistant", Content: resp.Content},
+			provider.Message{Role: "user", Content: fmt.Sprintf("That does not match the schema: %v. Reply with corrected JSON only.", verr)},
+		)
+	}
+	stats.Duration = time.Since(start)
+	l.recordUsage(sessionKey, model, stats, err)
+	return result, err
+}
@@ -0,0 +1,128 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// taskSessionPrefix marks sessions that belong to background tasks.
+const taskSessionPrefix = "task:"
+
+// taskNotifyChars caps the result included in a completion notification.
+const taskNotifyChars = 1500
+
+// turnLimits overrides the per-message tool call and time budget.
+type turnLimits struct {
+	calls   int
+	timeout time.Duration
+}
+
+type turnLimitsKey struct{}
+
+// SpawnTask queues a background sub-agent run and returns the task id. It
+// implements tools.TaskSpawner.
+func (l *Loop) SpawnTask(ctx context.Context, title, prompt string) (string, error) {
+	origin := tools.SessionKeyFrom(ctx)
+	if strings.HasPrefix(origin, taskSessionPrefix) {
+		return "", errors.New("background tasks cannot start further tasks")
+	}
+	l.mu.RLock()
+	stopping := l.stopping
+	l.mu.RUnlock()
+	if stopping {
+		return "", ErrShuttingDown
+	}
+
+	task := &timeline.Task{
+		ID:         newTaskID(),
+		SessionKey: origin,
+		Title:      title,
+		Prompt:     prompt,
+		Status:     timeline.TaskQueued,
+		CreatedAt:  time.Now(),
+	}
+	if err := l.timeline.CreateTask(task); err != nil {
+		return "", fmt.Errorf("record task: %w", err)
+	}
+	slog.Info("Task queued", "id", task.ID, "origin", origin, "title", title)
+	go l.runTask(task)
+	return task.ID, nil
+}
+
+// newTaskID returns a random task id, so tasks spawned in the same turn
+// cannot collide.
+func newTaskID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return "t" + hex.EncodeToString(b)
+}
+
+// runTask waits for a free slot, runs the task in its own session, and
+// reports the outcome to the originating chat.
+func (l *Loop) runTask(task *timeline.Task) {
+	l.taskSem <- struct{}{}
+	defer func() { <-l.taskSem }()
+
+	now := time.Now()
+	task.Status = timeline.TaskRunning
+	task.StartedAt = &now
+	l.saveTask(task)
+
+	ctx := context.WithValue(context.Background(), turnLimitsKey{}, turnLimits{calls: l.taskMaxCalls, timeout: l.taskTimeout})
+	// The task acts for its chat: a sandboxed chat's task is sandboxed too.
+	ctx = tools.WithOriginSession(ctx, task.SessionKey)
+	if l.taskTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.taskTimeout)
+		defer cancel()
+	}
+	prompt := fmt.Sprintf("You are running background task %s: %s\n\n%s\n\n"+
+		"Work autonomously; nobody can answer questions. Finish with a short summary of what you did and where the results are.",
+		task.ID, task.Title, task.Prompt)
+	result, err := l.process(ctx, prompt, taskSessionPrefix+task.ID, nil)
+
+	done := time.Now()
+	task.FinishedAt = &done
+	if err != nil {
+		task.Status = timeline.TaskFailed
+		task.Error = err.Error()
+		slog.Warn("Task failed", "id", task.ID, "error", err)
+	} else {
+		task.Status = timeline.TaskDone
+		task.Result = result
+		slog.Info("Task finished", "id", task.ID, "duration", done.Sub(*task.StartedAt))
+	}
+	l.saveTask(task)
+	l.notifyTask(task)
+}
+
+func (l *Loop) saveTask(task *timeline.Task) {
+	if err := l.timeline.UpdateTask(task); err != nil {
+		slog.Warn("Failed to update task", "id", task.ID, "error", err)
+	}
+}
+
+// notifyTask tells the originating chat that a task finished.
+func (l *Loop) notifyTask(task *timeline.Task) {
+	channel, chatID, ok := strings.Cut(task.SessionKey, ":")
+	if !ok || l.bus == nil || channel == "cli" {
+		return
+	}
+	var content string
+	if task.Status == timeline.TaskDone {
+		content = fmt.Sprintf("✅ Task %s finished: %s\n\n%s", task.ID, task.Title, truncateMiddle(task.Result, taskNotifyChars))
+	} else {
+		content = fmt.Sprintf("❌ Task %s failed: %s\n\n%s", task.ID, task.Title, task.Error)
+	}
+	l.bus.PublishOutbound(&bus.OutboundMessage{Channel: channel, ChatID: chatID, Content: content})
+}
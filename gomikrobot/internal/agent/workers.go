@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"sync"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+)
+
+// sessionWorkers runs handlers for different sessions concurrently while
+// keeping the messages of each session in arrival order. A session's worker
+// exists only while it has pending messages.
+//
+// Urgent messages jump ahead of the normal messages of their session, take
+// a free slot before any waiting normal session, and may use one slot beyond
+// the limit so they never wait for long-running turns.
+type sessionWorkers struct {
+	handle func(*bus.InboundMessage)
+	limit  int
+
+	mu      sync.Mutex
+	slots   *sync.Cond // Signalled when a slot is released
+	running int
+	waiting int // Urgent messages waiting for a slot
+	queues  map[string][]queuedMessage
+}
+
+type queuedMessage struct {
+	msg    *bus.InboundMessage
+	urgent bool
+}
+
+func newSessionWorkers(limit int, handle func(*bus.InboundMessage)) *sessionWorkers {
+	w := &sessionWorkers{
+		handle: handle,
+		limit:  limit,
+		queues: make(map[string][]queuedMessage),
+	}
+	w.slots = sync.NewCond(&w.mu)
+	return w
+}
+
+// submit queues msg behind earlier messages of the same session, or behind
+// its earlier urgent messages if urgent is set.
+func (w *sessionWorkers) submit(key string, msg *bus.InboundMessage, urgent bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	q := queuedMessage{msg: msg, urgent: urgent}
+	if pending, busy := w.queues[key]; busy {
+		i := len(pending)
+		if urgent {
+			i = 0
+			for i < len(pending) && pending[i].urgent {
+				i++
+			}
+		}
+		w.queues[key] = append(pending[:i], append([]queuedMessage{q}, pending[i:]...)...)
+		return
+	}
+	w.queues[key] = []queuedMessage{q}
+	go w.drain(key)
+}
+
+// drain handles the session's messages until its queue is empty.
+func (w *sessionWorkers) drain(key string) {
+	for {
+		w.mu.Lock()
+		pending := w.queues[key]
+		if len(pending) == 0 {
+			delete(w.queues, key)
+			w.mu.Unlock()
+			return
+		}
+		next := pending[0]
+		w.queues[key] = pending[1:]
+		w.acquire(next.urgent)
+		w.mu.Unlock()
+
+		w.handle(next.msg)
+
+		w.mu.Lock()
+		w.running--
+		w.slots.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// acquire waits for a worker slot. w.mu must be held.
+func (w *sessionWorkers) acquire(urgent bool) {
+	if urgent {
+		w.waiting++
+		for w.running > w.limit {
+			w.slots.Wait()
+		}
+		w.waiting--
+	} else {
+		for w.running >= w.limit || w.waiting > 0 {
+			w.slots.Wait()
+		}
+	}
+	w.running++
+}
+
+// sessionKeyFor returns the session a bus message belongs to.
+func sessionKeyFor(msg *bus.InboundMessage) string {
+	return msg.SessionKey()
+}
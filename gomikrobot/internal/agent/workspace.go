@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// workspaceEntry is a file or directory the system prompt refers to.
+// Files are created from template; directories have no template.
+type workspaceEntry struct {
+	path     string
+	dir      bool
+	template string
+}
+
+var workspaceLayout = []workspaceEntry{
+	{path: "memory", dir: true},
+	{path: "skills", dir: true},
+	{path: "media", dir: true},
+	{path: "memory/MEMORY.md", template: `# Memory
+
+Long-term facts worth keeping across conversations. Daily notes go in
+memory/YYYY-MM-DD.md.
+`},
+	{path: "AGENTS.md", template: `# Agent Instructions
+
+How you work: conventions, tools to prefer, and things to avoid.
+
+- Keep answers short unless asked for detail.
+- Ask before doing anything that cannot be undone.
+`},
+	{path: "SOUL.md", template: `# Soul
+
+Your personality and values: tone, humour, what you care about.
+`},
+	{path: "USER.md", template: `# User
+
+About the person you help: name, time zone, language, preferences.
+`},
+}
+
+// InitWorkspace creates the workspace directory layout and template files
+// the system prompt mentions. Existing files are left untouched. It returns
+// the paths it created, relative to dir.
+func InitWorkspace(dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	var created []string
+	for _, e := range workspaceLayout {
+		path := filepath.Join(dir, filepath.FromSlash(e.path))
+		if _, err := os.Stat(path); err == nil {
+			continue
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return created, err
+		}
+		if e.dir {
+			if err := os.MkdirAll(path, 0700); err != nil {
+				return created, err
+			}
+		} else if err := os.WriteFile(path, []byte(e.template), 0600); err != nil {
+			return created, err
+		}
+		created = append(created, e.path)
+	}
+	return created, nil
+}
+
+// CheckWorkspace lists the parts of the workspace layout that are missing
+// or have the wrong type, relative to dir.
+func CheckWorkspace(dir string) []string {
+	var missing []string
+	for _, e := range workspaceLayout {
+		info, err := os.Stat(filepath.Join(dir, filepath.FromSlash(e.path)))
+		switch {
+		case err != nil:
+			missing = append(missing, e.path)
+		case info.IsDir() != e.dir:
+			kind := "a file"
+			if e.dir {
+				kind = "a directory"
+			}
+			missing = append(missing, fmt.Sprintf("%s (should be %s)", e.path, kind))
+		}
+	}
+	return missing
+}
@@ -0,0 +1,214 @@
+// Package audit records every LLM request and response, with secrets
+// redacted, to rotating JSONL files. It is meant for debugging why the
+// agent did something without turning on debug logging everywhere.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/security"
+)
+
+// Record is one LLM call.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Session    string    `json:"session,omitempty"`
+	Model      string    `json:"model"`
+	DurationMs int64     `json:"duration_ms"`
+	Request    Request   `json:"request"`
+	Response   *Response `json:"response,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Request is the redacted prompt sent to the model.
+type Request struct {
+	Messages        []Message `json:"messages"`
+	Tools           []string  `json:"tools,omitempty"` // Names only
+	MaxTokens       int       `json:"max_tokens,omitempty"`
+	Temperature     float64   `json:"temperature"`
+	TopP            float64   `json:"top_p,omitempty"`
+	ReasoningEffort string    `json:"reasoning_effort,omitempty"`
+}
+
+// Message is a redacted chat message.
+type Message struct {
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+}
+
+// ToolCall is a redacted tool call; Arguments is the JSON-encoded input.
+type ToolCall struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Response is the redacted model output.
+type Response struct {
+	Content      string         `json:"content"`
+	ToolCalls    []ToolCall     `json:"tool_calls,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty"`
+	Usage        provider.Usage `json:"usage"`
+}
+
+// NewRecord builds a record of one call with all text passed through
+// security.RedactSecrets. resp may be nil if the call failed.
+func NewRecord(session string, req *provider.ChatRequest, resp *provider.ChatResponse, err error, took time.Duration) *Record {
+	r := &Record{
+		Time:       time.Now(),
+		Session:    session,
+		Model:      req.Model,
+		DurationMs: took.Milliseconds(),
+		Request: Request{
+			Messages:        make([]Message, len(req.Messages)),
+			MaxTokens:       req.MaxTokens,
+			Temperature:     req.Temperature,
+			TopP:            req.TopP,
+			ReasoningEffort: req.ReasoningEffort,
+		},
+	}
+	for i, m := range req.Messages {
+		r.Request.Messages[i] = Message{
+			Role:       m.Role,
+			Content:    security.RedactSecrets(m.Content),
+			ToolCalls:  toolCalls(m.ToolCalls),
+			ToolCallID: m.ToolCallID,
+		}
+	}
+	for _, t := range req.Tools {
+		r.Request.Tools = append(r.Request.Tools, t.Function.Name)
+	}
+	if resp != nil {
+		r.Response = &Response{
+			Content:      security.RedactSecrets(resp.Content),
+			ToolCalls:    toolCalls(resp.ToolCalls),
+			FinishReason: resp.FinishReason,
+			Usage:        resp.Usage,
+		}
+	}
+	if err != nil {
+		r.Error = security.SanitizeError(err)
+	}
+	return r
+}
+
+func toolCalls(calls []provider.ToolCall) []ToolCall {
+	if len(calls) == 0 {
+		return nil
+	}
+	out := make([]ToolCall, len(calls))
+	for i, c := range calls {
+		args, _ := json.Marshal(c.Arguments)
+		out[i] = ToolCall{ID: c.ID, Name: c.Name, Arguments: security.RedactSecrets(string(args))}
+	}
+	return out
+}
+
+// Log appends records to JSONL files in a directory. A new file is started
+// each day and whenever the current one reaches the size limit; files older
+// than the retention period are deleted on rotation.
+type Log struct {
+	dir       string
+	maxBytes  int64
+	retention time.Duration
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+	day  string
+}
+
+// Open creates dir if needed and returns a Log writing to it. maxBytes <= 0
+// disables size-based rotation; retention <= 0 keeps all files.
+func Open(dir string, maxBytes int64, retention time.Duration) (*Log, error) {
+	if strings.HasPrefix(dir, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("create audit dir: %w", err)
+	}
+	return &Log{dir: dir, maxBytes: maxBytes, retention: retention}, nil
+}
+
+// Dir returns the directory the log writes to.
+func (l *Log) Dir() string { return l.dir }
+
+// Write appends r as one JSON line.
+func (l *Log) Write(r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	day := r.Time.Format("20060102")
+	if l.f == nil || day != l.day || (l.maxBytes > 0 && l.size+int64(len(line)) > l.maxBytes && l.size > 0) {
+		if err := l.rotate(r.Time); err != nil {
+			return err
+		}
+	}
+	n, err := l.f.Write(line)
+	l.size += int64(n)
+	return err
+}
+
+// Close closes the current file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
+
+// rotate starts a new file and prunes expired ones. Callers hold l.mu.
+func (l *Log) rotate(now time.Time) error {
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+	name := filepath.Join(l.dir, "audit-"+now.Format("20060102-150405.000000")+".jsonl")
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("open audit file: %w", err)
+	}
+	l.f, l.size, l.day = f, 0, now.Format("20060102")
+	l.prune(now, name)
+	return nil
+}
+
+// prune deletes files last written before the retention period, never
+// the current file.
+func (l *Log) prune(now time.Time, current string) {
+	if l.retention <= 0 {
+		return
+	}
+	files, _ := filepath.Glob(filepath.Join(l.dir, "audit-*.jsonl"))
+	for _, path := range files {
+		if path == current {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil && now.Sub(info.ModTime()) > l.retention {
+			os.Remove(path)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+func TestNewRecordRedacts(t *testing.T) {
+	req := &provider.ChatRequest{
+		Model: "gpt-4o",
+		Messages: []provider.Message{
+			{Role: "user", Content: "my api_key=sk-abcdefghijklmnopqrstuvwx please"},
+		},
+		Tools: []provider.ToolDefinition{{Function: provider.FunctionDef{Name: "exec"}}},
+	}
+	resp := &provider.ChatResponse{
+		ToolCalls: []provider.ToolCall{{ID: "1", Name: "exec", Arguments: map[string]any{"command": "curl -H 'Authorization: Bearer abc.def'"}}},
+	}
+	rec := NewRecord("whatsapp:1", req, resp, errors.New("token=hunter2"), time.Second)
+
+	out, _ := json.Marshal(rec)
+	for _, secret := range []string{"sk-abcdef", "abc.def", "hunter2"} {
+		if strings.Contains(string(out), secret) {
+			t.Errorf("record leaks %q: %s", secret, out)
+		}
+	}
+	if rec.Request.Tools[0] != "exec" || rec.Response.ToolCalls[0].Name != "exec" || rec.DurationMs != 1000 {
+		t.Errorf("unexpected record %+v", rec)
+	}
+}
+
+func TestLogRotatesAndPrunes(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "audit-20200101-000000.000000.jsonl")
+	os.WriteFile(stale, []byte("{}\n"), 0600)
+	old := time.Now().Add(-48 * time.Hour)
+	os.Chtimes(stale, old, old)
+
+	log, err := Open(dir, 300, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.Close()
+	req := &provider.ChatRequest{Model: "m", Messages: []provider.Message{{Role: "user", Content: strings.Repeat("x", 100)}}}
+	for range 5 {
+		if err := log.Write(NewRecord("s", req, nil, nil, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("expired file was not pruned")
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "audit-*.jsonl"))
+	if len(files) < 2 {
+		t.Fatalf("expected size rotation, got %v", files)
+	}
+	lines := 0
+	for _, f := range files {
+		fh, _ := os.Open(f)
+		sc := bufio.NewScanner(fh)
+		for sc.Scan() {
+			var r Record
+			if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+				t.Errorf("%s: invalid line: %v", f, err)
+			}
+			lines++
+		}
+		fh.Close()
+	}
+	if lines != 5 {
+		t.Errorf("expected 5 records, got %d", lines)
+	}
+}
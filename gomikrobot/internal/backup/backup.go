@@ -0,0 +1,525 @@
+// Package backup snapshots the bot's state — the config file, the timeline
+// database, sessions, and workspace memory — into a single tar.zst
+// archive, optionally encrypted with a passphrase, and restores it.
+//
+// Encrypted archives use the age format (https://age-encryption.org) with
+// a scrypt passphrase, so they can also be opened with the age tool.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/klauspost/compress/zstd"
+	"modernc.org/sqlite"
+)
+
+// PassphraseEnv holds the passphrase of encrypted backups.
+const PassphraseEnv = "MIKROBOT_BACKUP_PASSPHRASE"
+
+// Archive file extensions.
+const (
+	Ext          = ".tar.zst"
+	EncryptedExt = ".tar.zst.age"
+)
+
+// formatVersion is bumped when the archive layout changes.
+const formatVersion = 1
+
+const manifestName = "manifest.json"
+
+// Archive entries; directories hold the files below them.
+const (
+	configDir     = "config"
+	timelineEntry = "timeline.db"
+	sessionsDir   = "sessions"
+	memoryDir     = "memory"
+)
+
+// ageHeader starts every age-encrypted file.
+var ageHeader = []byte("age-encryption.org/")
+
+// ErrPassphrase is returned by Restore for encrypted archives when no
+// passphrase is given.
+var ErrPassphrase = errors.New("the backup is encrypted; a passphrase is required")
+
+// Paths locates the state to back up or restore. Empty paths are skipped.
+type Paths struct {
+	// Config is the config file. A restored config keeps its file name,
+	// e.g. config.yaml, in the directory of Config.
+	Config   string
+	Timeline string // timeline SQLite database
+	Sessions string // sessions directory
+	Memory   string // workspace memory directory
+}
+
+// Manifest describes an archive. It is its first entry.
+type Manifest struct {
+	Version    int       `json:"version"`
+	Created    time.Time `json:"created"`
+	AppVersion string    `json:"app_version,omitempty"`
+	// Items lists what the archive holds: config, timeline, sessions, memory.
+	Items []string `json:"items"`
+	Files int      `json:"files"`
+	// Config is the file name of the backed-up config.
+	Config string `json:"config,omitempty"`
+}
+
+// Name returns the file name of a backup made at t.
+func Name(t time.Time, encrypted bool) string {
+	if encrypted {
+		return "gomikrobot-" + t.Format("20060102-150405") + EncryptedExt
+	}
+	return "gomikrobot-" + t.Format("20060102-150405") + Ext
+}
+
+// entry is a file to archive.
+type entry struct {
+	name string // slash-separated name in the archive
+	path string
+}
+
+// Create writes a backup of p to dst, encrypted when passphrase is set. The
+// timeline is copied with SQLite's online backup API, so the gateway may
+// keep running. dst is only replaced once the archive is complete.
+func Create(ctx context.Context, dst string, p Paths, passphrase, appVersion string) (*Manifest, error) {
+	m := &Manifest{Version: formatVersion, Created: time.Now().UTC(), AppVersion: appVersion}
+	var entries []entry
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return nil, err
+	}
+
+	if p.Config != "" && exists(p.Config) {
+		m.Items = append(m.Items, "config")
+		m.Config = filepath.Base(p.Config)
+		entries = append(entries, entry{configDir + "/" + m.Config, p.Config})
+	}
+	if p.Timeline != "" && exists(p.Timeline) {
+		tmp, err := os.MkdirTemp(filepath.Dir(dst), ".backup-")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		snap := filepath.Join(tmp, timelineEntry)
+		if err := snapshotSQLite(ctx, p.Timeline, snap); err != nil {
+			return nil, fmt.Errorf("timeline: %w", err)
+		}
+		m.Items = append(m.Items, "timeline")
+		entries = append(entries, entry{timelineEntry, snap})
+	}
+	for _, d := range []struct{ item, dir string }{{sessionsDir, p.Sessions}, {memoryDir, p.Memory}} {
+		if d.dir == "" || !exists(d.dir) {
+			continue
+		}
+		files, err := walk(d.dir, d.item)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", d.item, err)
+		}
+		m.Items = append(m.Items, d.item)
+		entries = append(entries, files...)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("nothing to back up")
+	}
+	m.Files = len(entries)
+
+	tmp := dst + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	err = writeArchive(ctx, f, m, entries, passphrase)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+	return m, os.Rename(tmp, dst)
+}
+
+func writeArchive(ctx context.Context, w io.Writer, m *Manifest, entries []entry, passphrase string) error {
+	var enc io.WriteCloser
+	if passphrase != "" {
+		r, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return err
+		}
+		if enc, err = age.Encrypt(w, r); err != nil {
+			return err
+		}
+		w = enc
+	}
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: manifestName, Mode: 0600, Size: int64(len(manifest)), ModTime: m.Created, Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := addFile(tw, e); err != nil {
+			return fmt.Errorf("%s: %w", e.name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if enc != nil {
+		return enc.Close()
+	}
+	return nil
+}
+
+// addFile archives a file. Files that grow while being read, like session
+// logs, are cut at the size they had when the entry was written.
+func addFile(tw *tar.Writer, e entry) error {
+	f, err := os.Open(e.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hdr := &tar.Header{Name: e.name, Mode: int64(info.Mode().Perm()), Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, info.Size())
+	return err
+}
+
+// walk lists the regular files below dir as entries under prefix.
+func walk(dir, prefix string) ([]entry, error) {
+	var entries []entry
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry{prefix + "/" + filepath.ToSlash(rel), p})
+		return nil
+	})
+	return entries, err
+}
+
+// Restore unpacks the backup at src into p, decrypting it with passphrase
+// if needed. Items whose path in p is empty are skipped. Unless overwrite
+// is set, Restore refuses to replace an existing config or timeline, or to
+// write into non-empty session or memory directories; files there that are
+// not in the backup are kept either way.
+func Restore(ctx context.Context, src string, p Paths, passphrase string, overwrite bool) (*Manifest, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var in io.Reader = r
+	if head, _ := r.Peek(len(ageHeader)); bytes.Equal(head, ageHeader) {
+		if passphrase == "" {
+			return nil, ErrPassphrase
+		}
+		id, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		if in, err = age.Decrypt(r, id); err != nil {
+			return nil, fmt.Errorf("wrong passphrase or corrupted backup: %w", err)
+		}
+	}
+	zr, err := zstd.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != manifestName {
+		return nil, fmt.Errorf("%s is not a gomikrobot backup", src)
+	}
+	var m Manifest
+	if err := json.NewDecoder(tr).Decode(&m); err != nil {
+		return nil, fmt.Errorf("manifest: %w", err)
+	}
+	if m.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported backup version %d", m.Version)
+	}
+	if m.Config != "" && !plainName(m.Config) {
+		return nil, fmt.Errorf("manifest: invalid config file name %q", m.Config)
+	}
+	if !overwrite {
+		if err := checkTargets(&m, p); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return &m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := restoreEntry(ctx, tr, hdr, &m, p); err != nil {
+			return nil, fmt.Errorf("%s: %w", hdr.Name, err)
+		}
+	}
+}
+
+// checkTargets fails if restoring m would replace existing state.
+func checkTargets(m *Manifest, p Paths) error {
+	for _, item := range m.Items {
+		var target string
+		switch item {
+		case "config":
+			if p.Config != "" {
+				target = filepath.Join(filepath.Dir(p.Config), m.Config)
+			}
+		case "timeline":
+			target = p.Timeline
+		case sessionsDir:
+			target = p.Sessions
+		case memoryDir:
+			target = p.Memory
+		}
+		if target == "" {
+			continue
+		}
+		if item == sessionsDir || item == memoryDir {
+			if names, _ := os.ReadDir(target); len(names) == 0 {
+				continue
+			}
+		} else if !exists(target) {
+			continue
+		}
+		return fmt.Errorf("%s already exists; restore with overwrite to replace it", target)
+	}
+	return nil
+}
+
+func restoreEntry(ctx context.Context, r io.Reader, hdr *tar.Header, m *Manifest, p Paths) error {
+	if hdr.Name == timelineEntry {
+		if p.Timeline == "" {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(p.Timeline), 0700); err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(p.Timeline), ".restore-*.db")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		_, err = io.Copy(tmp, r)
+		if cerr := tmp.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		return restoreSQLite(ctx, tmp.Name(), p.Timeline)
+	}
+
+	dir, rel, _ := strings.Cut(hdr.Name, "/")
+	var root string
+	switch dir {
+	case configDir:
+		// Only the config file named in the manifest is restored, and
+		// never as an executable.
+		if p.Config == "" || rel != m.Config || !plainName(rel) {
+			return nil
+		}
+		return writeFile(filepath.Join(filepath.Dir(p.Config), rel), r, 0600)
+	case sessionsDir:
+		root = p.Sessions
+	case memoryDir:
+		root = p.Memory
+	}
+	if root == "" || rel == "" {
+		return nil
+	}
+	// Entries must stay inside their directory.
+	rel = path.Clean(rel)
+	if path.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, "../") {
+		return errors.New("path escapes its directory")
+	}
+	return writeFile(filepath.Join(root, filepath.FromSlash(rel)), r, os.FileMode(hdr.Mode).Perm()&^0111|0600)
+}
+
+// plainName reports whether name is a file name without directories that
+// does not hide or climb, e.g. config.json.
+func plainName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\`) && filepath.Base(name) == name
+}
+
+// writeFile replaces path atomically with the contents of r.
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".restore"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// sqliteBackuper is implemented by modernc.org/sqlite connections.
+type sqliteBackuper interface {
+	NewBackup(dstURI string) (*sqlite.Backup, error)
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// snapshotSQLite copies the database at src to dst with SQLite's online
+// backup API, which gives a consistent copy while others write to src.
+func snapshotSQLite(ctx context.Context, src, dst string) error {
+	return withSQLite(ctx, src, func(c sqliteBackuper) (*sqlite.Backup, error) {
+		return c.NewBackup(dst)
+	})
+}
+
+// restoreSQLite replaces the contents of the database at dst with src.
+// Open connections to dst see the restored data.
+func restoreSQLite(ctx context.Context, src, dst string) error {
+	return withSQLite(ctx, dst, func(c sqliteBackuper) (*sqlite.Backup, error) {
+		return c.NewRestore(src)
+	})
+}
+
+func withSQLite(ctx context.Context, dbPath string, start func(sqliteBackuper) (*sqlite.Backup, error)) error {
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(dc any) error {
+		c, ok := dc.(sqliteBackuper)
+		if !ok {
+			return errors.New("the SQLite driver does not support backups")
+		}
+		b, err := start(c)
+		if err != nil {
+			return err
+		}
+		for {
+			more, err := b.Step(256)
+			if err != nil {
+				b.Finish()
+				return err
+			}
+			if !more {
+				return b.Finish()
+			}
+			if err := ctx.Err(); err != nil {
+				b.Finish()
+				return err
+			}
+		}
+	})
+}
+
+// Prune deletes all but the keep newest backups in dir and returns the
+// removed paths. keep <= 0 keeps everything.
+func Prune(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		n := e.Name()
+		if e.Type().IsRegular() && strings.HasPrefix(n, "gomikrobot-") && (strings.HasSuffix(n, Ext) || strings.HasSuffix(n, EncryptedExt)) {
+			names = append(names, n)
+		}
+	}
+	if len(names) <= keep {
+		return nil, nil
+	}
+	// Names sort by their timestamp.
+	sort.Strings(names)
+	var removed []string
+	for _, n := range names[:len(names)-keep] {
+		p := filepath.Join(dir, n)
+		if err := os.Remove(p); err != nil {
+			return removed, err
+		}
+		removed = append(removed, p)
+	}
+	return removed, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setup creates state to back up in dir.
+func setup(t *testing.T, dir string) Paths {
+	t.Helper()
+	p := Paths{
+		Config:   filepath.Join(dir, "config.json"),
+		Timeline: filepath.Join(dir, "timeline.db"),
+		Sessions: filepath.Join(dir, "sessions"),
+		Memory:   filepath.Join(dir, "workspace", "memory"),
+	}
+	os.MkdirAll(p.Sessions, 0700)
+	os.MkdirAll(filepath.Join(p.Memory, "notes"), 0700)
+	os.WriteFile(p.Config, []byte(`{"agents":{}}`), 0600)
+	os.WriteFile(filepath.Join(p.Sessions, "whatsapp_1.jsonl"), []byte("{}\n"), 0600)
+	os.WriteFile(filepath.Join(p.Memory, "MEMORY.md"), []byte("likes tea"), 0644)
+	os.WriteFile(filepath.Join(p.Memory, "notes", "a.md"), []byte("note"), 0644)
+
+	db, err := sql.Open("sqlite", p.Timeline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE timeline (id INTEGER PRIMARY KEY, content TEXT); INSERT INTO timeline (content) VALUES ('hi'), ('there')"); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func empty(dir string) Paths {
+	return Paths{
+		Config:   filepath.Join(dir, "config.json"),
+		Timeline: filepath.Join(dir, "timeline.db"),
+		Sessions: filepath.Join(dir, "sessions"),
+		Memory:   filepath.Join(dir, "workspace", "memory"),
+	}
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	for _, pass := range []string{"", "correct horse"} {
+		src := setup(t, t.TempDir())
+		archive := filepath.Join(t.TempDir(), Name(time.Now(), pass != ""))
+		m, err := Create(context.Background(), archive, src, pass, "1.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(m.Items, ",") != "config,timeline,sessions,memory" || m.Files != 5 {
+			t.Errorf("manifest = %+v", m)
+		}
+
+		dst := empty(t.TempDir())
+		if pass != "" {
+			if _, err := Restore(context.Background(), archive, dst, "", false); !errors.Is(err, ErrPassphrase) {
+				t.Errorf("restore without passphrase: %v", err)
+			}
+			if _, err := Restore(context.Background(), archive, dst, "wrong", false); err == nil {
+				t.Error("restore accepted a wrong passphrase")
+			}
+		}
+		if _, err := Restore(context.Background(), archive, dst, pass, false); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range []string{dst.Config, filepath.Join(dst.Sessions, "whatsapp_1.jsonl"), filepath.Join(dst.Memory, "notes", "a.md")} {
+			if _, err := os.Stat(f); err != nil {
+				t.Errorf("not restored: %v", err)
+			}
+		}
+		if data, _ := os.ReadFile(filepath.Join(dst.Memory, "MEMORY.md")); string(data) != "likes tea" {
+			t.Errorf("MEMORY.md = %q", data)
+		}
+		db, err := sql.Open("sqlite", dst.Timeline)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM timeline").Scan(&n); err != nil || n != 2 {
+			t.Errorf("restored timeline has %d rows, %v", n, err)
+		}
+		db.Close()
+
+		if _, err := Restore(context.Background(), archive, dst, pass, false); err == nil {
+			t.Error("restore replaced existing state without overwrite")
+		}
+		if _, err := Restore(context.Background(), archive, dst, pass, true); err != nil {
+			t.Errorf("restore with overwrite: %v", err)
+		}
+	}
+}
+
+func TestRestoreSkipsEmptyPaths(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "b"+Ext)
+	if _, err := Create(context.Background(), archive, setup(t, t.TempDir()), "", ""); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	dst := empty(dir)
+	dst.Config, dst.Timeline = "", ""
+	if _, err := Restore(context.Background(), archive, dst, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "config.json")); !os.IsNotExist(err) {
+		t.Error("config was restored")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 4 {
+		os.WriteFile(filepath.Join(dir, Name(start.AddDate(0, 0, i), i%2 == 0)), nil, 0600)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0600)
+
+	removed, err := Prune(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || filepath.Base(removed[0]) != Name(start, true) {
+		t.Errorf("removed %q", removed)
+	}
+	left, _ := os.ReadDir(dir)
+	if len(left) != 3 {
+		t.Errorf("%d files left, want 2 backups and notes.txt", len(left))
+	}
+}
+
+func TestRestoreOnlyWritesTheNamedConfig(t *testing.T) {
+	src := t.TempDir()
+	script := filepath.Join(src, "script")
+	os.WriteFile(script, []byte("#!/bin/sh\n"), 0755)
+	craft := func(m *Manifest, entries ...entry) string {
+		t.Helper()
+		archive := filepath.Join(t.TempDir(), "b"+Ext)
+		f, err := os.Create(archive)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		m.Version, m.Items = formatVersion, []string{"config"}
+		if err := writeArchive(context.Background(), f, m, entries, ""); err != nil {
+			t.Fatal(err)
+		}
+		return archive
+	}
+
+	dir := t.TempDir()
+	dst := empty(dir)
+	archive := craft(&Manifest{Config: "config.json"},
+		entry{configDir + "/config.json", script},
+		entry{configDir + "/autostart.sh", script},
+		entry{configDir + "/workspace/AGENTS.md", script})
+	if _, err := Restore(context.Background(), archive, dst, "", false); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(dst.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0111 != 0 {
+		t.Errorf("config restored with mode %v", info.Mode().Perm())
+	}
+	for _, f := range []string{"autostart.sh", filepath.Join("workspace", "AGENTS.md")} {
+		if _, err := os.Stat(filepath.Join(dir, f)); !os.IsNotExist(err) {
+			t.Errorf("%s was restored", f)
+		}
+	}
+
+	for _, name := range []string{"../config.json", ".bashrc", `sub\config.json`} {
+		archive := craft(&Manifest{Config: name}, entry{configDir + "/" + name, script})
+		if _, err := Restore(context.Background(), archive, empty(t.TempDir()), "", true); err == nil {
+			t.Errorf("restored a config named %q", name)
+		}
+	}
+}
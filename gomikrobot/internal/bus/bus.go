@@ -3,10 +3,16 @@ package bus
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
+
+	"github.com/kamir/gomikrobot/internal/metrics"
+	"github.com/kamir/gomikrobot/internal/tracing"
 )
 
+var inboundDuplicates = metrics.Default.Counter("gomikrobot_inbound_duplicates_total", "Inbound messages dropped as redeliveries.")
+
 // InboundMessage represents a message from a channel to the agent.
 type InboundMessage struct {
 	Channel   string         `json:"channel"`
@@ -16,6 +22,13 @@ type InboundMessage struct {
 	Media     []string       `json:"media,omitempty"`
 	Metadata  map[string]any `json:"metadata,omitempty"`
 	Timestamp time.Time      `json:"timestamp"`
+	// Trace is the W3C traceparent of the span that received the message.
+	Trace string `json:"trace,omitempty"`
+}
+
+// SessionKey identifies the conversation the message belongs to.
+func (m *InboundMessage) SessionKey() string {
+	return m.Channel + ":" + m.ChatID
 }
 
 // OutboundMessage represents a message from the agent to a channel.
@@ -23,15 +36,36 @@ type OutboundMessage struct {
 	Channel string `json:"channel"`
 	ChatID  string `json:"chat_id"`
 	Content string `json:"content"`
+	// Trace is the W3C traceparent of the agent turn that produced it.
+	Trace string `json:"trace,omitempty"`
+	// Media are paths of files sent along with the message.
+	Media []string `json:"media,omitempty"`
 }
 
 // MessageBus decouples channels from the agent core.
 type MessageBus struct {
-	inbound  chan *InboundMessage
-	outbound chan *OutboundMessage
-	subs     map[string][]func(*OutboundMessage)
-	running  bool
-	mu       sync.RWMutex
+	inbound   chan *InboundMessage
+	outbound  chan *OutboundMessage
+	subs      map[string][]func(*OutboundMessage)
+	queues    map[string]*outboundQueue
+	policies  map[string]OutboundPolicy
+	presence  map[string][]func(*PresenceEvent)
+	reactions []func(*ReactionEvent)
+	running   bool
+	mu        sync.RWMutex
+
+	// transport, if set, carries messages to and from other processes.
+	// router sends each session to one worker; joined receives the
+	// messages of a worker.
+	transport Transport
+	router    *router
+	joined    chan *InboundMessage
+
+	// Inbound deduplication by metadata "event_id".
+	dedupMu     sync.Mutex
+	dedupWindow time.Duration
+	seen        map[string]time.Time
+	lastPrune   time.Time
 }
 
 // NewMessageBus creates a new message bus.
@@ -43,16 +77,84 @@ func NewMessageBus() *MessageBus {
 	}
 }
 
+// SetDedupWindow makes PublishInbound drop messages whose metadata
+// "event_id" was already published within d. Zero disables deduplication.
+func (b *MessageBus) SetDedupWindow(d time.Duration) {
+	b.dedupMu.Lock()
+	defer b.dedupMu.Unlock()
+	b.dedupWindow = d
+	if d <= 0 {
+		b.seen = nil
+	}
+}
+
 // PublishInbound sends a message from a channel to the agent.
+// Redelivered messages are dropped, see SetDedupWindow; with a transport,
+// only those published by this process.
 func (b *MessageBus) PublishInbound(msg *InboundMessage) {
 	if msg.Timestamp.IsZero() {
 		msg.Timestamp = time.Now()
 	}
+	if b.duplicate(msg) {
+		inboundDuplicates.Inc("channel", msg.Channel)
+		return
+	}
+	if b.transport != nil {
+		b.send(b.inboundTopic(msg), msg, true)
+		return
+	}
 	b.inbound <- msg
 }
 
+// duplicate records msg's event ID and reports whether it was seen within
+// the dedup window.
+func (b *MessageBus) duplicate(msg *InboundMessage) bool {
+	id, _ := msg.Metadata["event_id"].(string)
+	if id == "" {
+		return false
+	}
+	key := msg.Channel + "|" + id
+
+	b.dedupMu.Lock()
+	defer b.dedupMu.Unlock()
+	if b.dedupWindow <= 0 {
+		return false
+	}
+	now := time.Now()
+	if now.Sub(b.lastPrune) > b.dedupWindow {
+		for k, t := range b.seen {
+			if now.Sub(t) > b.dedupWindow {
+				delete(b.seen, k)
+			}
+		}
+		b.lastPrune = now
+	}
+	if t, ok := b.seen[key]; ok && now.Sub(t) <= b.dedupWindow {
+		return true
+	}
+	if b.seen == nil {
+		b.seen = make(map[string]time.Time)
+	}
+	b.seen[key] = now
+	return false
+}
+
 // ConsumeInbound blocks until a message is available or context is cancelled.
 func (b *MessageBus) ConsumeInbound(ctx context.Context) (*InboundMessage, error) {
+	b.mu.RLock()
+	joined := b.joined
+	b.mu.RUnlock()
+	if joined != nil {
+		select {
+		case msg := <-joined:
+			return msg, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if b.transport != nil {
+		return b.dequeue(ctx, TopicInbound)
+	}
 	select {
 	case msg := <-b.inbound:
 		return msg, nil
@@ -63,6 +165,10 @@ func (b *MessageBus) ConsumeInbound(ctx context.Context) (*InboundMessage, error
 
 // PublishOutbound sends a message from the agent to channels.
 func (b *MessageBus) PublishOutbound(msg *OutboundMessage) {
+	if b.transport != nil {
+		b.send(TopicOutbound, msg, false)
+		return
+	}
 	b.outbound <- msg
 }
 
@@ -75,8 +181,15 @@ func (b *MessageBus) Subscribe(channel string, callback func(*OutboundMessage))
 }
 
 // DispatchOutbound runs the outbound message dispatcher.
-// This should be run as a goroutine.
+// This should be run as a goroutine. With a transport, it also receives
+// the outbound messages, presence events, and reactions of other processes;
+// messages to channels this process does not serve are ignored.
 func (b *MessageBus) DispatchOutbound(ctx context.Context) error {
+	if b.transport != nil {
+		if err := b.subscribeTransport(ctx); err != nil {
+			return fmt.Errorf("bus transport: %w", err)
+		}
+	}
 	b.mu.Lock()
 	b.running = true
 	b.mu.Unlock()
@@ -88,10 +201,18 @@ func (b *MessageBus) DispatchOutbound(ctx context.Context) error {
 		case msg := <-b.outbound:
 			b.mu.RLock()
 			callbacks := b.subs[msg.Channel]
+			q := b.queues[msg.Channel]
 			b.mu.RUnlock()
 
-			for _, cb := range callbacks {
-				cb(msg)
+			if q != nil {
+				q.enqueue(ctx, msg)
+			}
+			if len(callbacks) > 0 {
+				_, span := tracing.StartKind(tracing.ContextWithRemote(ctx, msg.Trace), tracing.KindConsumer, "dispatch "+msg.Channel, "chat_id", msg.ChatID)
+				for _, cb := range callbacks {
+					cb(msg)
+				}
+				span.End()
 			}
 		}
 	}
@@ -104,7 +225,16 @@ func (b *MessageBus) Stop() {
 	b.running = false
 }
 
-// InboundSize returns the number of pending inbound messages.
+// Close disconnects the bus from its transport, if any.
+func (b *MessageBus) Close() error {
+	if b.transport == nil {
+		return nil
+	}
+	return b.transport.Close()
+}
+
+// InboundSize returns the number of pending inbound messages. Messages
+// held by a transport are not counted.
 func (b *MessageBus) InboundSize() int {
 	return len(b.inbound)
 }
@@ -0,0 +1,178 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestPublishInboundDropsRedeliveries(t *testing.T) {
+	b := NewMessageBus()
+	b.SetDedupWindow(time.Minute)
+
+	msg := func(id string) *InboundMessage {
+		return &InboundMessage{Channel: "whatsapp", ChatID: "1", Content: "hi", Metadata: map[string]any{"event_id": id}}
+	}
+	b.PublishInbound(msg("A1"))
+	b.PublishInbound(msg("A1"))
+	b.PublishInbound(msg("B2"))
+	b.PublishInbound(&InboundMessage{Channel: "whatsapp", ChatID: "1", Content: "no id"})
+	b.PublishInbound(&InboundMessage{Channel: "whatsapp", ChatID: "1", Content: "no id"})
+
+	if n := b.InboundSize(); n != 4 {
+		t.Errorf("expected 4 queued messages, got %d", n)
+	}
+
+	b.SetDedupWindow(0)
+	b.PublishInbound(msg("A1"))
+	if n := b.InboundSize(); n != 5 {
+		t.Errorf("expected dedup to be disabled, got %d queued", n)
+	}
+}
+
+func TestOutboundQueueSplitsThrottlesAndRetries(t *testing.T) {
+	b := NewMessageBus()
+	b.SetOutboundPolicy("wa", OutboundPolicy{Rate: 20, Burst: 1, MaxChars: 10, Retries: 2, RetryDelay: time.Millisecond})
+
+	var (
+		mu    sync.Mutex
+		sent  []string
+		times []time.Time
+		fails = 1
+	)
+	done := make(chan struct{})
+	b.SubscribeSender("wa", func(ctx context.Context, msg *OutboundMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if fails > 0 {
+			fails--
+			return errors.New("temporary network error")
+		}
+		sent = append(sent, msg.Content)
+		times = append(times, time.Now())
+		if len(sent) == 4 {
+			close(done)
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.DispatchOutbound(ctx)
+	b.PublishOutbound(&OutboundMessage{Channel: "wa", ChatID: "1", Content: "hello world, how are you"})
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("only sent %q", sent)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(sent, "|") != "hello|world,|how are|you" {
+		t.Errorf("parts = %q", sent)
+	}
+	// 20 messages per second with no burst: parts are at least ~50ms apart.
+	if gap := times[3].Sub(times[0]); gap < 120*time.Millisecond {
+		t.Errorf("parts sent %v apart, expected throttling", gap)
+	}
+}
+
+func TestOutboundPrepareAndRender(t *testing.T) {
+	b := NewMessageBus()
+	b.SetOutboundPolicy("wa", OutboundPolicy{
+		MaxChars: 10,
+		Prepare: func(m *OutboundMessage) {
+			m.Content = strings.Replace(m.Content, "CODE", "see file", 1)
+			m.Media = []string{"code.go"}
+		},
+		Render: strings.ToUpper,
+	})
+	got := make(chan *OutboundMessage, 10)
+	b.SubscribeSender("wa", func(ctx context.Context, msg *OutboundMessage) error {
+		got <- msg
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.DispatchOutbound(ctx)
+	orig := &OutboundMessage{Channel: "wa", ChatID: "1", Content: "hello CODE"}
+	b.PublishOutbound(orig)
+
+	var parts []string
+	var media [][]string
+	for range 2 {
+		select {
+		case m := <-got:
+			parts = append(parts, m.Content)
+			media = append(media, m.Media)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("only got %q", parts)
+		}
+	}
+	if strings.Join(parts, "|") != "HELLO SEE|FILE" {
+		t.Errorf("parts = %q", parts)
+	}
+	if media[0] != nil || len(media[1]) != 1 {
+		t.Errorf("media = %q, want it on the last part only", media)
+	}
+	if orig.Content != "hello CODE" || orig.Media != nil {
+		t.Errorf("original message modified: %+v", orig)
+	}
+}
+
+func TestOutboundPermanentErrorsAreNotRetried(t *testing.T) {
+	b := NewMessageBus()
+	b.SetOutboundPolicy("wa", OutboundPolicy{Retries: 5, RetryDelay: time.Millisecond})
+	calls := make(chan struct{}, 10)
+	b.SubscribeSender("wa", func(ctx context.Context, msg *OutboundMessage) error {
+		calls <- struct{}{}
+		return Permanent(errors.New("invalid chat"))
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.DispatchOutbound(ctx)
+	b.PublishOutbound(&OutboundMessage{Channel: "wa", ChatID: "bad", Content: "hi"})
+
+	<-calls
+	time.Sleep(50 * time.Millisecond)
+	if n := len(calls); n != 0 {
+		t.Errorf("permanent error retried %d times", n)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	if got := SplitMessage("short", 100); len(got) != 1 {
+		t.Errorf("short message split: %q", got)
+	}
+	long := strings.Repeat("ä", 25)
+	got := SplitMessage(long, 10)
+	if len(got) != 3 || got[0] != strings.Repeat("ä", 10) {
+		t.Errorf("rune split = %q", got)
+	}
+	got = SplitMessage("first paragraph\n\nsecond one here", 20)
+	if len(got) != 2 || got[0] != "first paragraph" || got[1] != "second one here" {
+		t.Errorf("paragraph split = %q", got)
+	}
+	code := "Here:\n\n```go\n" + strings.Repeat("x := 1\n", 20) + "```\nDone."
+	got = SplitMessage(code, 60)
+	if len(got) < 2 {
+		t.Fatalf("code split = %q", got)
+	}
+	for i, part := range got {
+		if utf8.RuneCountInString(part) > 60 {
+			t.Errorf("part %d has %d chars", i, utf8.RuneCountInString(part))
+		}
+		if strings.Count(part, "```")%2 != 0 {
+			t.Errorf("part %d leaves a code block open: %q", i, part)
+		}
+	}
+	if !strings.HasPrefix(got[2], "```go\n") {
+		t.Errorf("code block not reopened with its language: %q", got[2])
+	}
+}
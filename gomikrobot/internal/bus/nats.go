@@ -0,0 +1,150 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// natsTransport keeps work queues in a JetStream stream, so queued messages
+// survive restarts of their consumers, and publishes everything else on
+// plain NATS subjects. The server must run with JetStream enabled.
+type natsTransport struct {
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+	stream string
+
+	mu        sync.Mutex
+	consumers map[string]jetstream.Consumer
+}
+
+func newNATSTransport(url, prefix string) (*natsTransport, error) {
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	nc, err := nats.Connect(url, nats.Name("gomikrobot"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("connecting to NATS: %w", err)
+	}
+	js, err := jetstream.New(nc)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	t := &natsTransport{
+		nc:        nc,
+		js:        js,
+		prefix:    prefix,
+		stream:    strings.ToUpper(natsName(prefix)) + "_QUEUES",
+		consumers: make(map[string]jetstream.Consumer),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), transportTimeout)
+	defer cancel()
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      t.stream,
+		Subjects:  []string{prefix + ".queue.>"},
+		Retention: jetstream.WorkQueuePolicy,
+		MaxAge:    24 * time.Hour,
+	})
+	if err != nil {
+		nc.Close()
+		return nil, fmt.Errorf("creating JetStream stream %s (is JetStream enabled?): %w", t.stream, err)
+	}
+	return t, nil
+}
+
+// natsName makes s usable as a stream or consumer name.
+func natsName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '*' || r == '>' || r == ' ' || r == '/' || r == '\\' {
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+func (t *natsTransport) queueSubject(topic string) string { return t.prefix + ".queue." + topic }
+func (t *natsTransport) subject(topic string) string      { return t.prefix + "." + topic }
+
+func (t *natsTransport) Enqueue(ctx context.Context, topic string, data []byte) error {
+	_, err := t.js.Publish(ctx, t.queueSubject(topic), data)
+	return err
+}
+
+// consumer returns the durable consumer shared by all processes reading
+// topic.
+func (t *natsTransport) consumer(ctx context.Context, topic string) (jetstream.Consumer, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c := t.consumers[topic]; c != nil {
+		return c, nil
+	}
+	c, err := t.js.CreateOrUpdateConsumer(ctx, t.stream, jetstream.ConsumerConfig{
+		Durable:       natsName(topic),
+		FilterSubject: t.queueSubject(topic),
+		AckPolicy:     jetstream.AckExplicitPolicy,
+	})
+	if err != nil {
+		return nil, err
+	}
+	t.consumers[topic] = c
+	return c, nil
+}
+
+func (t *natsTransport) Dequeue(ctx context.Context, topic string) ([]byte, error) {
+	c, err := t.consumer(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch, err := c.Fetch(1, jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			return nil, err
+		}
+		for msg := range batch.Messages() {
+			if err := msg.Ack(); err != nil {
+				return nil, err
+			}
+			return msg.Data(), nil
+		}
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			return nil, err
+		}
+	}
+}
+
+func (t *natsTransport) Publish(ctx context.Context, topic string, data []byte) error {
+	return t.nc.Publish(t.subject(topic), data)
+}
+
+func (t *natsTransport) Subscribe(ctx context.Context, topic string, fn func([]byte)) error {
+	sub, err := t.nc.Subscribe(t.subject(topic), func(msg *nats.Msg) { fn(msg.Data) })
+	if err != nil {
+		return err
+	}
+	// Wait until the server knows the subscription, so nothing published
+	// after Subscribe returns is missed.
+	if err := t.nc.Flush(); err != nil {
+		sub.Unsubscribe()
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		sub.Unsubscribe()
+	}()
+	return nil
+}
+
+func (t *natsTransport) Close() error {
+	return t.nc.Drain()
+}
@@ -0,0 +1,308 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kamir/gomikrobot/internal/metrics"
+	"github.com/kamir/gomikrobot/internal/tracing"
+)
+
+var (
+	outboundQueued  = metrics.Default.Gauge("gomikrobot_outbound_queue", "Outbound messages waiting for their channel.")
+	outboundDropped = metrics.Default.Counter("gomikrobot_outbound_dropped_total", "Outbound messages dropped because the channel queue was full.")
+	outboundRetries = metrics.Default.Counter("gomikrobot_outbound_retries_total", "Outbound sends retried after a transient failure.")
+	outboundFailed  = metrics.Default.Counter("gomikrobot_outbound_failed_total", "Outbound messages that could not be delivered.")
+)
+
+const (
+	defaultOutboundQueue = 100
+	outboundSendTimeout  = 30 * time.Second
+	maxRetryDelay        = 30 * time.Second
+)
+
+// OutboundPolicy throttles, formats, and splits the messages sent to one
+// channel.
+type OutboundPolicy struct {
+	// Rate is the sustained messages per second; Burst messages may go out
+	// back to back. A zero Rate disables throttling.
+	Rate  float64
+	Burst int
+	// MaxChars splits longer messages into several (0 keeps them whole).
+	MaxChars int
+	// Retries is how often a transient send failure is retried, with
+	// exponential backoff starting at RetryDelay (default 1s).
+	Retries    int
+	RetryDelay time.Duration
+	// QueueSize bounds messages waiting for the channel (default 100). It
+	// takes effect when the channel subscribes.
+	QueueSize int
+	// Prepare, if set, rewrites a message before it is split, e.g. to move
+	// long code blocks into Media. Render, if set, converts each part to
+	// the channel's markup.
+	Prepare func(*OutboundMessage)
+	Render  func(string) string
+}
+
+// SendFunc delivers one message to a channel. Errors that retrying cannot
+// fix should be wrapped with Permanent.
+type SendFunc func(ctx context.Context, msg *OutboundMessage) error
+
+type permanentError struct{ error }
+
+func (e permanentError) Unwrap() error { return e.error }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// outboundQueue delivers one channel's messages in order, one at a time.
+type outboundQueue struct {
+	channel string
+	send    SendFunc
+	ch      chan *OutboundMessage
+	start   sync.Once
+
+	mu     sync.Mutex
+	policy OutboundPolicy
+	tokens float64
+	last   time.Time
+}
+
+// SetOutboundPolicy sets the throttling policy for channel. It applies to
+// channels subscribed with SubscribeSender.
+func (b *MessageBus) SetOutboundPolicy(channel string, p OutboundPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.policies == nil {
+		b.policies = make(map[string]OutboundPolicy)
+	}
+	b.policies[channel] = p
+	if q := b.queues[channel]; q != nil {
+		q.mu.Lock()
+		q.policy = p
+		q.mu.Unlock()
+	}
+}
+
+// SubscribeSender registers the delivery function for channel. Unlike
+// Subscribe callbacks, messages are queued per channel, sent in order,
+// throttled and split according to the channel's OutboundPolicy, and
+// retried on transient errors.
+func (b *MessageBus) SubscribeSender(channel string, send SendFunc) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := b.policies[channel]
+	size := p.QueueSize
+	if size <= 0 {
+		size = defaultOutboundQueue
+	}
+	if b.queues == nil {
+		b.queues = make(map[string]*outboundQueue)
+	}
+	b.queues[channel] = &outboundQueue{
+		channel: channel,
+		send:    send,
+		ch:      make(chan *OutboundMessage, size),
+		policy:  p,
+		tokens:  float64(max(p.Burst, 1)),
+		last:    time.Now(),
+	}
+}
+
+// enqueue hands msg to the channel's worker without blocking the
+// dispatcher. Messages are dropped when the queue is full.
+func (q *outboundQueue) enqueue(ctx context.Context, msg *OutboundMessage) {
+	q.start.Do(func() { go q.run(ctx) })
+	select {
+	case q.ch <- msg:
+		outboundQueued.Add(1, "channel", q.channel)
+	default:
+		outboundDropped.Inc("channel", q.channel)
+		fmt.Printf("⚠️ Outbound queue for %s is full; dropped message to %s\n", q.channel, msg.ChatID)
+	}
+}
+
+func (q *outboundQueue) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-q.ch:
+			outboundQueued.Add(-1, "channel", q.channel)
+			q.mu.Lock()
+			p := q.policy
+			q.mu.Unlock()
+			if p.Prepare != nil {
+				prepared := *msg
+				p.Prepare(&prepared)
+				msg = &prepared
+			}
+			parts := SplitMessage(msg.Content, p.MaxChars)
+			for i, part := range parts {
+				if !q.wait(ctx) {
+					return
+				}
+				m := *msg
+				m.Content = part
+				if p.Render != nil {
+					m.Content = p.Render(part)
+				}
+				// Attachments go with the last part.
+				if i < len(parts)-1 {
+					m.Media = nil
+				}
+				if err := q.deliver(ctx, &m); err != nil {
+					outboundFailed.Inc("channel", q.channel)
+					fmt.Printf("❌ Sending %s message to %s failed: %v\n", q.channel, msg.ChatID, err)
+					break
+				}
+			}
+		}
+	}
+}
+
+// wait blocks until the rate limit allows another message. It returns
+// false if ctx ends first.
+func (q *outboundQueue) wait(ctx context.Context) bool {
+	for {
+		q.mu.Lock()
+		p := q.policy
+		if p.Rate <= 0 {
+			q.mu.Unlock()
+			return true
+		}
+		now := time.Now()
+		q.tokens = min(q.tokens+now.Sub(q.last).Seconds()*p.Rate, float64(max(p.Burst, 1)))
+		q.last = now
+		if q.tokens >= 1 {
+			q.tokens--
+			q.mu.Unlock()
+			return true
+		}
+		delay := time.Duration((1 - q.tokens) / p.Rate * float64(time.Second))
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(delay):
+		}
+	}
+}
+
+// deliver sends msg, retrying transient failures with backoff.
+func (q *outboundQueue) deliver(ctx context.Context, msg *OutboundMessage) (err error) {
+	ctx, span := tracing.StartKind(tracing.ContextWithRemote(ctx, msg.Trace), tracing.KindProducer, "send "+q.channel,
+		"chat_id", msg.ChatID, "chars", utf8.RuneCountInString(msg.Content))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	q.mu.Lock()
+	retries, delay := q.policy.Retries, q.policy.RetryDelay
+	q.mu.Unlock()
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	for attempt := 0; ; attempt++ {
+		span.SetAttr("attempt", attempt+1)
+		sendCtx, cancel := context.WithTimeout(ctx, outboundSendTimeout)
+		err = q.send(sendCtx, msg)
+		cancel()
+		if err == nil || IsPermanent(err) || attempt >= retries {
+			return err
+		}
+		outboundRetries.Inc("channel", q.channel)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// SplitMessage breaks s into parts of at most limit characters, preferring
+// paragraph, line, and word boundaries. A code block cut in two is closed
+// at the end of one part and reopened in the next. limit <= 0 returns s
+// unchanged.
+func SplitMessage(s string, limit int) []string {
+	if limit <= 0 || utf8.RuneCountInString(s) <= limit {
+		return []string{s}
+	}
+	// Room for closing a code block; tiny limits split without it.
+	fences := strings.Contains(s, "```") && limit >= 4*len(fenceClose)
+	budget := limit
+	if fences {
+		budget -= len(fenceClose)
+	}
+	var parts []string
+	for utf8.RuneCountInString(s) > limit {
+		// Byte offset just past budget runes.
+		cut := len(s)
+		n := 0
+		for i := range s {
+			if n == budget {
+				cut = i
+				break
+			}
+			n++
+		}
+		head := s[:cut]
+		at := cut
+		for _, sep := range []string{"\n\n", "\n", " "} {
+			// Only break at a boundary in the back half, so parts stay large.
+			if i := strings.LastIndex(head, sep); i > 0 && i >= len(head)/2 {
+				at = i
+				break
+			}
+		}
+		part, rest := strings.TrimRight(s[:at], " \n"), strings.TrimLeft(s[at:], " \n")
+		if fences {
+			if open, ok := openFence(part); ok {
+				part += fenceClose
+				if rest != "" {
+					rest = open + "\n" + rest
+				}
+			}
+		}
+		parts = append(parts, part)
+		s = rest
+	}
+	if s != "" {
+		parts = append(parts, s)
+	}
+	return parts
+}
+
+const fenceClose = "\n```"
+
+// openFence returns the opening line of the code block s ends in, if any.
+func openFence(s string) (string, bool) {
+	open, inside := "", false
+	for line := range strings.SplitSeq(s, "\n") {
+		if t := strings.TrimSpace(line); strings.HasPrefix(t, "```") {
+			inside = !inside
+			open = t
+		}
+	}
+	return open, inside
+}
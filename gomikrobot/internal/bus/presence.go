@@ -0,0 +1,49 @@
+package bus
+
+// Presence event kinds.
+const (
+	PresenceTyping     = "typing"
+	PresenceTypingStop = "typing_stop"
+	PresenceRead       = "read"
+)
+
+// PresenceEvent tells a channel to show activity in a chat: a typing
+// indicator while the agent works, or a read receipt for inbound messages.
+type PresenceEvent struct {
+	Channel string `json:"channel"`
+	ChatID  string `json:"chat_id"`
+	Kind    string `json:"kind"`
+	// MessageIDs and SenderID identify the messages being marked read.
+	MessageIDs []string `json:"message_ids,omitempty"`
+	SenderID   string   `json:"sender_id,omitempty"`
+}
+
+// SubscribePresence registers a callback for presence events to channel.
+func (b *MessageBus) SubscribePresence(channel string, callback func(*PresenceEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.presence == nil {
+		b.presence = make(map[string][]func(*PresenceEvent))
+	}
+	b.presence[channel] = append(b.presence[channel], callback)
+}
+
+// PublishPresence delivers a presence event. It never blocks: presence is
+// best effort, so callbacks run in their own goroutines and events for
+// channels without subscribers are discarded.
+func (b *MessageBus) PublishPresence(evt *PresenceEvent) {
+	if b.transport != nil {
+		go b.send(TopicPresence, evt, false)
+		return
+	}
+	b.deliverPresence(evt)
+}
+
+func (b *MessageBus) deliverPresence(evt *PresenceEvent) {
+	b.mu.RLock()
+	callbacks := b.presence[evt.Channel]
+	b.mu.RUnlock()
+	for _, cb := range callbacks {
+		go cb(evt)
+	}
+}
@@ -0,0 +1,40 @@
+package bus
+
+import "time"
+
+// ReactionEvent is an emoji reaction a user put on a message, typically a
+// bot reply. An empty Emoji means the reaction was removed.
+type ReactionEvent struct {
+	Channel   string    `json:"channel"`
+	ChatID    string    `json:"chat_id"`
+	SenderID  string    `json:"sender_id"`
+	MessageID string    `json:"message_id"`
+	Emoji     string    `json:"emoji"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// SubscribeReactions registers a callback for reactions from all channels.
+func (b *MessageBus) SubscribeReactions(callback func(*ReactionEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reactions = append(b.reactions, callback)
+}
+
+// PublishReaction delivers a reaction to all subscribers. Like presence,
+// it is best effort and never blocks the publishing channel.
+func (b *MessageBus) PublishReaction(evt *ReactionEvent) {
+	if b.transport != nil {
+		go b.send(TopicReactions, evt, false)
+		return
+	}
+	b.deliverReaction(evt)
+}
+
+func (b *MessageBus) deliverReaction(evt *ReactionEvent) {
+	b.mu.RLock()
+	callbacks := b.reactions
+	b.mu.RUnlock()
+	for _, cb := range callbacks {
+		go cb(evt)
+	}
+}
@@ -0,0 +1,180 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisGroup is the consumer group all processes dequeue work with.
+	redisGroup = "workers"
+	// Streams are trimmed to about this many entries; broadcasts are only
+	// read as they arrive, so they keep fewer.
+	redisQueueLen   = 10000
+	redisPublishLen = 1000
+	redisBlock      = 5 * time.Second
+)
+
+// redisTransport keeps every topic in a Redis stream. Work queues are read
+// through a consumer group, so each entry goes to one process; broadcasts
+// are read by every subscriber from the entry after the newest one at the
+// time it subscribed.
+type redisTransport struct {
+	rdb      *redis.Client
+	prefix   string
+	consumer string
+
+	mu     sync.Mutex
+	groups map[string]bool
+}
+
+func newRedisTransport(url, prefix string) (*redisTransport, error) {
+	if url == "" {
+		url = "redis://localhost:6379/0"
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	rdb := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), transportTimeout)
+	defer cancel()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		rdb.Close()
+		return nil, fmt.Errorf("connecting to Redis: %w", err)
+	}
+	host, _ := os.Hostname()
+	return &redisTransport{
+		rdb:      rdb,
+		prefix:   prefix,
+		consumer: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		groups:   make(map[string]bool),
+	}, nil
+}
+
+func (t *redisTransport) key(topic string) string { return t.prefix + ":" + topic }
+
+func (t *redisTransport) Enqueue(ctx context.Context, topic string, data []byte) error {
+	return t.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: t.key(topic),
+		MaxLen: redisQueueLen,
+		Approx: true,
+		Values: []any{"data", data},
+	}).Err()
+}
+
+// group creates the consumer group of topic. It starts at the beginning of
+// the stream, so items queued before the first consumer are not lost.
+func (t *redisTransport) group(ctx context.Context, topic string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.groups[topic] {
+		return nil
+	}
+	err := t.rdb.XGroupCreateMkStream(ctx, t.key(topic), redisGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	t.groups[topic] = true
+	return nil
+}
+
+func (t *redisTransport) Dequeue(ctx context.Context, topic string) ([]byte, error) {
+	if err := t.group(ctx, topic); err != nil {
+		return nil, err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		streams, err := t.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    redisGroup,
+			Consumer: t.consumer,
+			Streams:  []string{t.key(topic), ">"},
+			Count:    1,
+			Block:    redisBlock,
+			NoAck:    true,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range streams {
+			for _, m := range s.Messages {
+				return streamData(m), nil
+			}
+		}
+	}
+}
+
+func (t *redisTransport) Publish(ctx context.Context, topic string, data []byte) error {
+	return t.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: t.key(topic),
+		MaxLen: redisPublishLen,
+		Approx: true,
+		Values: []any{"data", data},
+	}).Err()
+}
+
+func (t *redisTransport) Subscribe(ctx context.Context, topic string, fn func([]byte)) error {
+	// Start after the current newest entry rather than at "$", which would
+	// skip entries added before the first read.
+	last := "0-0"
+	newest, err := t.rdb.XRevRangeN(ctx, t.key(topic), "+", "-", 1).Result()
+	if err != nil {
+		return err
+	}
+	if len(newest) > 0 {
+		last = newest[0].ID
+	}
+	go func() {
+		for ctx.Err() == nil {
+			streams, err := t.rdb.XRead(ctx, &redis.XReadArgs{
+				Streams: []string{t.key(topic), last},
+				Block:   redisBlock,
+			}).Result()
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, redis.ErrClosed) {
+					return
+				}
+				transportErrors.Inc("topic", topic)
+				fmt.Printf("⚠️ Bus: reading %s from Redis failed: %v\n", topic, err)
+				select {
+				case <-ctx.Done():
+				case <-time.After(time.Second):
+				}
+				continue
+			}
+			for _, s := range streams {
+				for _, m := range s.Messages {
+					last = m.ID
+					fn(streamData(m))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// streamData returns the payload of a stream entry written by Enqueue or
+// Publish.
+func streamData(m redis.XMessage) []byte {
+	s, _ := m.Values["data"].(string)
+	return []byte(s)
+}
+
+func (t *redisTransport) Close() error {
+	return t.rdb.Close()
+}
@@ -0,0 +1,229 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/metrics"
+)
+
+var routedInbound = metrics.Default.Counter("gomikrobot_inbound_routed_total", "Inbound messages routed to a worker, by worker.")
+
+// TopicWorkers carries the heartbeats of workers.
+const TopicWorkers = "workers"
+
+const (
+	workerHeartbeat = 5 * time.Second
+	// A worker that missed three heartbeats gets no more messages.
+	workerTimeout = 3 * workerHeartbeat
+	// Sessions idle this long are forgotten by the router; their next
+	// message picks a worker by hash again.
+	routeIdle = time.Hour
+)
+
+var workerIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidWorkerID reports whether id can name a worker: letters, digits,
+// dashes, and underscores.
+func ValidWorkerID(id string) bool {
+	return workerIDPattern.MatchString(id)
+}
+
+// WorkerTopic is the work queue of the sessions routed to worker id.
+func WorkerTopic(id string) string {
+	return TopicInbound + "-" + id
+}
+
+// workerBeat announces a live worker, or one shutting down. A beat
+// without ID asks all workers to announce themselves.
+type workerBeat struct {
+	ID      string `json:"id,omitempty"`
+	Leaving bool   `json:"leaving,omitempty"`
+}
+
+// RouteSessions makes PublishInbound send all messages of a session to the
+// same worker (see JoinWorkers), so one process keeps its history and runs
+// its turns in order. A session stays with its worker while the worker is
+// alive; new sessions are spread by rendezvous hashing. Without a live
+// worker, messages go to the shared inbound queue.
+func (b *MessageBus) RouteSessions(ctx context.Context) error {
+	if b.transport == nil {
+		return errors.New("routing sessions to workers needs a bus transport")
+	}
+	r := &router{workers: make(map[string]time.Time), sessions: make(map[string]route)}
+	err := receive(ctx, b.transport, TopicWorkers, func(w *workerBeat) {
+		if w.ID != "" {
+			r.beat(w, time.Now())
+		}
+	})
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	b.router = r
+	b.mu.Unlock()
+	// Learn about running workers now rather than at their next beat.
+	b.send(TopicWorkers, &workerBeat{}, false)
+	return nil
+}
+
+// inboundTopic returns the work queue msg goes to.
+func (b *MessageBus) inboundTopic(msg *InboundMessage) string {
+	b.mu.RLock()
+	r := b.router
+	b.mu.RUnlock()
+	if r == nil {
+		return TopicInbound
+	}
+	id := r.pick(msg.SessionKey(), time.Now())
+	if id == "" {
+		return TopicInbound
+	}
+	routedInbound.Inc("worker", id)
+	return WorkerTopic(id)
+}
+
+// JoinWorkers makes this process worker id. It announces the worker to
+// routing gateways until ctx ends, and ConsumeInbound then returns both the
+// sessions routed to it and messages from the shared inbound queue.
+// Messages still queued for the worker when it stops wait for a worker
+// with the same ID.
+func (b *MessageBus) JoinWorkers(ctx context.Context, id string) error {
+	if b.transport == nil {
+		return errors.New("workers need a bus transport")
+	}
+	if !ValidWorkerID(id) {
+		return fmt.Errorf("invalid worker ID %q: use letters, digits, - and _", id)
+	}
+	beat := func(leaving bool) {
+		b.send(TopicWorkers, &workerBeat{ID: id, Leaving: leaving}, false)
+	}
+	err := receive(ctx, b.transport, TopicWorkers, func(w *workerBeat) {
+		if w.ID == "" {
+			go beat(false)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	joined := make(chan *InboundMessage)
+	for _, topic := range []string{WorkerTopic(id), TopicInbound} {
+		go b.pump(ctx, topic, joined)
+	}
+	b.mu.Lock()
+	b.joined = joined
+	b.mu.Unlock()
+
+	beat(false)
+	go func() {
+		tick := time.NewTicker(workerHeartbeat)
+		defer tick.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				beat(true)
+				return
+			case <-tick.C:
+				beat(false)
+			}
+		}
+	}()
+	return nil
+}
+
+// pump moves the messages of the work queue topic to ch until ctx ends.
+func (b *MessageBus) pump(ctx context.Context, topic string, ch chan<- *InboundMessage) {
+	for {
+		msg, err := b.dequeue(ctx, topic)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			fmt.Printf("⚠️ Bus: receiving %s failed: %v\n", topic, err)
+			continue
+		}
+		select {
+		case ch <- msg:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// router assigns sessions to live workers.
+type router struct {
+	mu        sync.Mutex
+	workers   map[string]time.Time // last heartbeat
+	sessions  map[string]route
+	lastPrune time.Time
+}
+
+type route struct {
+	worker string
+	used   time.Time
+}
+
+func (r *router) beat(w *workerBeat, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if w.Leaving {
+		delete(r.workers, w.ID)
+	} else {
+		r.workers[w.ID] = now
+	}
+}
+
+func (r *router) alive(id string, now time.Time) bool {
+	t, ok := r.workers[id]
+	return ok && now.Sub(t) <= workerTimeout
+}
+
+// pick returns the worker for session key, or "" if no worker is alive.
+func (r *router) pick(key string, now time.Time) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.Sub(r.lastPrune) > routeIdle {
+		for k, rt := range r.sessions {
+			if now.Sub(rt.used) > routeIdle {
+				delete(r.sessions, k)
+			}
+		}
+		for id := range r.workers {
+			if !r.alive(id, now) {
+				delete(r.workers, id)
+			}
+		}
+		r.lastPrune = now
+	}
+	if rt, ok := r.sessions[key]; ok && r.alive(rt.worker, now) {
+		r.sessions[key] = route{worker: rt.worker, used: now}
+		return rt.worker
+	}
+
+	// Rendezvous hashing: the live worker scoring highest for this
+	// session. A worker joining or leaving only moves its own share.
+	var best string
+	var bestScore uint64
+	for id := range r.workers {
+		if !r.alive(id, now) {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(id))
+		if score := h.Sum64(); best == "" || score > bestScore || (score == bestScore && id < best) {
+			best, bestScore = id, score
+		}
+	}
+	if best != "" {
+		r.sessions[key] = route{worker: best, used: now}
+	}
+	return best
+}
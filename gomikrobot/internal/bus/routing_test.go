@@ -0,0 +1,119 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRouterKeepsSessionsOnTheirWorker(t *testing.T) {
+	r := &router{workers: map[string]time.Time{}, sessions: map[string]route{}}
+	now := time.Now()
+	if id := r.pick("wa:1", now); id != "" {
+		t.Errorf("picked %q without workers", id)
+	}
+
+	r.beat(&workerBeat{ID: "a"}, now)
+	r.beat(&workerBeat{ID: "b"}, now)
+	first := map[string]string{}
+	for i := range 20 {
+		key := fmt.Sprintf("wa:%d", i)
+		first[key] = r.pick(key, now)
+	}
+	if n := len(distinct(first)); n != 2 {
+		t.Errorf("sessions went to %d workers, want both", n)
+	}
+
+	// A new worker only gets new sessions.
+	r.beat(&workerBeat{ID: "c"}, now)
+	for key, id := range first {
+		if got := r.pick(key, now); got != id {
+			t.Errorf("%s moved from %s to %s", key, id, got)
+		}
+	}
+
+	// The sessions of a leaving worker move; the others stay.
+	r.beat(&workerBeat{ID: "a", Leaving: true}, now)
+	for key, id := range first {
+		got := r.pick(key, now)
+		if got == "a" || (id != "a" && got != id) {
+			t.Errorf("%s went from %s to %s after a left", key, id, got)
+		}
+	}
+
+	// Workers without heartbeats are dropped.
+	later := now.Add(workerTimeout + time.Second)
+	r.beat(&workerBeat{ID: "c"}, later)
+	for key := range first {
+		if got := r.pick(key, later); got != "c" {
+			t.Errorf("%s went to %s, want the only live worker", key, got)
+		}
+	}
+}
+
+func distinct(m map[string]string) map[string]bool {
+	set := map[string]bool{}
+	for _, v := range m {
+		set[v] = true
+	}
+	return set
+}
+
+func TestWorkersGetWholeSessions(t *testing.T) {
+	tr := newMemTransport()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	gateway := NewMessageBusWith(tr)
+	if err := gateway.RouteSessions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		mu  sync.Mutex
+		got = map[string]string{} // session -> worker
+		n   int
+	)
+	done := make(chan struct{})
+	for _, id := range []string{"w1", "w2"} {
+		w := NewMessageBusWith(tr)
+		if err := w.JoinWorkers(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			for {
+				msg, err := w.ConsumeInbound(ctx)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				if prev, ok := got[msg.SessionKey()]; ok && prev != id {
+					t.Errorf("session %s went to %s and %s", msg.SessionKey(), prev, id)
+				}
+				got[msg.SessionKey()] = id
+				if n++; n == 40 {
+					close(done)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	if err := gateway.JoinWorkers(ctx, "bad id"); err == nil {
+		t.Error("accepted a worker ID with a space")
+	}
+
+	for i := range 40 {
+		gateway.PublishInbound(&InboundMessage{Channel: "wa", ChatID: fmt.Sprint(i % 8), Content: "hi"})
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		t.Fatalf("workers received %d of 40 messages", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(distinct(got)) != 2 {
+		t.Errorf("sessions went to %v, want both workers", got)
+	}
+}
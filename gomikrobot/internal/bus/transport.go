@@ -0,0 +1,141 @@
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/metrics"
+)
+
+var transportErrors = metrics.Default.Counter("gomikrobot_bus_transport_errors_total", "Messages the bus transport failed to send or decode.")
+
+// Topics of the messages a MessageBus sends over its transport.
+const (
+	TopicInbound   = "inbound"
+	TopicOutbound  = "outbound"
+	TopicPresence  = "presence"
+	TopicReactions = "reactions"
+)
+
+// transportTimeout bounds sending one message over a transport.
+const transportTimeout = 10 * time.Second
+
+// Transport carries bus messages between processes, so channels, the agent
+// loop, and workers can run on different machines and still share one bus.
+// A MessageBus without a transport keeps everything in memory.
+type Transport interface {
+	// Enqueue adds data to the work queue topic. Each item is dequeued
+	// once, by one of the processes consuming the topic.
+	Enqueue(ctx context.Context, topic string, data []byte) error
+	// Dequeue blocks until an item of topic is available or ctx ends.
+	Dequeue(ctx context.Context, topic string) ([]byte, error)
+	// Publish sends data to the subscribers of topic in all processes.
+	Publish(ctx context.Context, topic string, data []byte) error
+	// Subscribe calls fn, one message at a time, with everything published
+	// to topic from now until ctx ends.
+	Subscribe(ctx context.Context, topic string, fn func([]byte)) error
+	Close() error
+}
+
+// OpenTransport connects to a NATS server or Redis at url. Subjects and
+// stream keys start with prefix, so several bots can share one server.
+func OpenTransport(kind, url, prefix string) (Transport, error) {
+	if prefix == "" {
+		prefix = "gomikrobot"
+	}
+	switch kind {
+	case "nats":
+		return newNATSTransport(url, prefix)
+	case "redis":
+		return newRedisTransport(url, prefix)
+	}
+	return nil, fmt.Errorf("unknown bus transport %q", kind)
+}
+
+// NewMessageBusWith creates a message bus whose inbound and outbound
+// messages, presence events, and reactions go over t. Callbacks only see
+// messages from the transport while DispatchOutbound runs.
+func NewMessageBusWith(t Transport) *MessageBus {
+	b := NewMessageBus()
+	b.transport = t
+	return b
+}
+
+// send encodes v and hands it to the transport with enqueue or publish.
+// Failures are logged: publishers have nobody to return them to.
+func (b *MessageBus) send(topic string, v any, queue bool) {
+	data, err := json.Marshal(v)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), transportTimeout)
+		if queue {
+			err = b.transport.Enqueue(ctx, topic, data)
+		} else {
+			err = b.transport.Publish(ctx, topic, data)
+		}
+		cancel()
+	}
+	if err != nil {
+		transportErrors.Inc("topic", topic)
+		fmt.Printf("⚠️ Bus: sending %s message failed: %v\n", topic, err)
+	}
+}
+
+// receive subscribes to topic and passes each decoded message to fn.
+func receive[T any](ctx context.Context, t Transport, topic string, fn func(*T)) error {
+	return t.Subscribe(ctx, topic, func(data []byte) {
+		msg := new(T)
+		if err := json.Unmarshal(data, msg); err != nil {
+			transportErrors.Inc("topic", topic)
+			fmt.Printf("⚠️ Bus: dropped undecodable %s message: %v\n", topic, err)
+			return
+		}
+		fn(msg)
+	})
+}
+
+// dequeue returns the next message of the work queue topic. Transport
+// errors are returned after a pause, so callers retrying them do not spin.
+func (b *MessageBus) dequeue(ctx context.Context, topic string) (*InboundMessage, error) {
+	for {
+		data, err := b.transport.Dequeue(ctx, topic)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			transportErrors.Inc("topic", topic)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+			}
+			return nil, err
+		}
+		msg := new(InboundMessage)
+		if err := json.Unmarshal(data, msg); err != nil {
+			transportErrors.Inc("topic", topic)
+			fmt.Printf("⚠️ Bus: dropped undecodable %s message: %v\n", topic, err)
+			continue
+		}
+		return msg, nil
+	}
+}
+
+// subscribeTransport feeds messages from the transport to this process's
+// outbound dispatcher and presence and reaction callbacks.
+func (b *MessageBus) subscribeTransport(ctx context.Context) error {
+	err := receive(ctx, b.transport, TopicOutbound, func(msg *OutboundMessage) {
+		select {
+		case b.outbound <- msg:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := receive(ctx, b.transport, TopicPresence, b.deliverPresence); err != nil {
+		return err
+	}
+	return receive(ctx, b.transport, TopicReactions, b.deliverReaction)
+}
@@ -0,0 +1,184 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memTransport is a Transport shared by buses in one test.
+type memTransport struct {
+	mu     sync.Mutex
+	queues map[string]chan []byte
+	subs   map[string][]func([]byte)
+}
+
+func newMemTransport() *memTransport {
+	return &memTransport{queues: map[string]chan []byte{}, subs: map[string][]func([]byte){}}
+}
+
+func (t *memTransport) queue(topic string) chan []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.queues[topic] == nil {
+		t.queues[topic] = make(chan []byte, 100)
+	}
+	return t.queues[topic]
+}
+
+func (t *memTransport) Enqueue(ctx context.Context, topic string, data []byte) error {
+	t.queue(topic) <- data
+	return nil
+}
+
+func (t *memTransport) Dequeue(ctx context.Context, topic string) ([]byte, error) {
+	select {
+	case data := <-t.queue(topic):
+		return data, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *memTransport) Publish(ctx context.Context, topic string, data []byte) error {
+	t.mu.Lock()
+	subs := t.subs[topic]
+	t.mu.Unlock()
+	for _, fn := range subs {
+		fn(data)
+	}
+	return nil
+}
+
+func (t *memTransport) Subscribe(ctx context.Context, topic string, fn func([]byte)) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.subs[topic] = append(t.subs[topic], fn)
+	return nil
+}
+
+func (t *memTransport) Close() error { return nil }
+
+func TestSharedBusConnectsProcesses(t *testing.T) {
+	tr := newMemTransport()
+	gateway, worker := NewMessageBusWith(tr), NewMessageBusWith(tr)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sent := make(chan *OutboundMessage, 1)
+	gateway.Subscribe("wa", func(msg *OutboundMessage) { sent <- msg })
+	reactions := make(chan *ReactionEvent, 1)
+	worker.SubscribeReactions(func(evt *ReactionEvent) { reactions <- evt })
+	go gateway.DispatchOutbound(ctx)
+	go worker.DispatchOutbound(ctx)
+	time.Sleep(10 * time.Millisecond)
+
+	gateway.PublishInbound(&InboundMessage{Channel: "wa", ChatID: "1", Content: "hi", Metadata: map[string]any{"group": true}})
+	in, err := worker.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.Content != "hi" || in.Metadata["group"] != true || in.Timestamp.IsZero() {
+		t.Errorf("inbound = %+v", in)
+	}
+
+	worker.PublishOutbound(&OutboundMessage{Channel: "wa", ChatID: in.ChatID, Content: "hello"})
+	select {
+	case out := <-sent:
+		if out.Content != "hello" || out.ChatID != "1" {
+			t.Errorf("outbound = %+v", out)
+		}
+	case <-ctx.Done():
+		t.Fatal("outbound message did not reach the gateway")
+	}
+
+	gateway.PublishReaction(&ReactionEvent{Channel: "wa", Emoji: "👍"})
+	select {
+	case evt := <-reactions:
+		if evt.Emoji != "👍" {
+			t.Errorf("reaction = %+v", evt)
+		}
+	case <-ctx.Done():
+		t.Fatal("reaction did not reach the worker")
+	}
+}
+
+// testTransport checks the queue and broadcast semantics of a transport
+// against a real server.
+func testTransport(t *testing.T, kind, url string) {
+	prefix := fmt.Sprintf("test%d", time.Now().UnixNano())
+	a, err := OpenTransport(kind, url, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := OpenTransport(kind, url, prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Items queued before anyone consumes are kept, and each goes to one
+	// consumer.
+	for i := range 4 {
+		if err := a.Enqueue(ctx, "inbound", []byte{byte('0' + i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := map[string]bool{}
+	for i := range 4 {
+		tr := a
+		if i%2 == 1 {
+			tr = b
+		}
+		data, err := tr.Dequeue(ctx, "inbound")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[string(data)] = true
+	}
+	if len(got) != 4 {
+		t.Errorf("dequeued %v, want 4 distinct items", got)
+	}
+
+	recv := make(chan string, 4)
+	for _, tr := range []Transport{a, b} {
+		if err := tr.Subscribe(ctx, "outbound", func(data []byte) { recv <- string(data) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Publish(ctx, "outbound", []byte("reply")); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		select {
+		case s := <-recv:
+			if s != "reply" {
+				t.Errorf("received %q", s)
+			}
+		case <-ctx.Done():
+			t.Fatal("a subscriber missed the broadcast")
+		}
+	}
+}
+
+func TestNATSTransport(t *testing.T) {
+	url := os.Getenv("MIKROBOT_TEST_NATS_URL")
+	if url == "" {
+		t.Skip("set MIKROBOT_TEST_NATS_URL to a JetStream-enabled NATS server to run")
+	}
+	testTransport(t, "nats", url)
+}
+
+func TestRedisTransport(t *testing.T) {
+	url := os.Getenv("MIKROBOT_TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("set MIKROBOT_TEST_REDIS_URL, e.g. redis://localhost:6379/15, to run")
+	}
+	testTransport(t, "redis", url)
+}
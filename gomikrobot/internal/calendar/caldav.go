@@ -0,0 +1,246 @@
+// Package calendar reads and writes events on a CalDAV server, including
+// Google Calendar through its CalDAV endpoint.
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for unknown event UIDs.
+var ErrNotFound = errors.New("event not found")
+
+// ErrConflict is returned when an event changed on the server since it
+// was read.
+var ErrConflict = errors.New("event was changed by someone else; read it again")
+
+// Client talks to one CalDAV calendar collection.
+type Client struct {
+	// URL of the calendar collection, e.g.
+	// https://cloud.example.com/remote.php/dav/calendars/me/personal/ or
+	// https://apidata.googleusercontent.com/caldav/v2/<calendar id>/events/
+	URL      string
+	Username string
+	Password string
+	// Token, if set, returns a bearer token per request instead of basic
+	// auth; see GoogleToken.
+	Token func(ctx context.Context) (string, error)
+	// Location is used for floating times and all-day events.
+	Location *time.Location
+	// HTTP defaults to a client with a 30-second timeout.
+	HTTP *http.Client
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+func (c *Client) loc() *time.Location {
+	if c.Location != nil {
+		return c.Location
+	}
+	return time.Local
+}
+
+func (c *Client) do(ctx context.Context, method, href string, body []byte, headers map[string]string) (*http.Response, []byte, error) {
+	target, err := c.resolve(href)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if c.Token != nil {
+		token, err := c.Token(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("get access token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = defaultHTTPClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return nil, nil, fmt.Errorf("read response: %w", err)
+	}
+	return resp, respBody, nil
+}
+
+// resolve turns a server-relative href into an absolute URL.
+func (c *Client) resolve(href string) (string, error) {
+	base, err := url.Parse(c.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid calendar URL: %w", err)
+	}
+	if href == "" {
+		return base.String(), nil
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// multistatus is the subset of a WebDAV REPORT response this package reads.
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Status string `xml:"status"`
+			Prop   struct {
+				ETag         string `xml:"getetag"`
+				CalendarData string `xml:"calendar-data"`
+			} `xml:"prop"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+// report runs a calendar-query REPORT with the given filter and returns
+// the matching events.
+func (c *Client) report(ctx context.Context, calendarData, filter string) ([]Event, error) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/>` + calendarData + `</D:prop>
+  <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT">` + filter + `</C:comp-filter></C:comp-filter></C:filter>
+</C:calendar-query>`
+	resp, respBody, err := c.do(ctx, "REPORT", "", []byte(body), map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, fmt.Errorf("calendar query failed (status %d): %s", resp.StatusCode, truncate(respBody))
+	}
+
+	var ms multistatus
+	if err := xml.Unmarshal(respBody, &ms); err != nil {
+		return nil, fmt.Errorf("parse calendar response: %w", err)
+	}
+	var events []Event
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstat {
+			if ps.Prop.CalendarData == "" || strings.Contains(ps.Status, " 404 ") {
+				continue
+			}
+			parsed, err := ParseEvents(ps.Prop.CalendarData, c.loc())
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", r.Href, err)
+			}
+			for i := range parsed {
+				parsed[i].Href = r.Href
+				parsed[i].ETag = ps.Prop.ETag
+			}
+			events = append(events, parsed...)
+		}
+	}
+	return events, nil
+}
+
+// List returns the events overlapping [start, end), with recurring events
+// expanded by the server, ordered by start time.
+func (c *Client) List(ctx context.Context, start, end time.Time) ([]Event, error) {
+	rng := fmt.Sprintf(`start="%s" end="%s"`, start.UTC().Format("20060102T150405Z"), end.UTC().Format("20060102T150405Z"))
+	events, err := c.report(ctx,
+		`<C:calendar-data><C:expand `+rng+`/></C:calendar-data>`,
+		`<C:time-range `+rng+`/>`)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	return events, nil
+}
+
+// Get returns the stored event with the given UID. For a recurring event
+// this is the series, not one occurrence.
+func (c *Client) Get(ctx context.Context, uid string) (*Event, error) {
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(uid))
+	events, err := c.report(ctx, `<C:calendar-data/>`,
+		`<C:prop-filter name="UID"><C:text-match collation="i;octet">`+escaped.String()+`</C:text-match></C:prop-filter>`)
+	if err != nil {
+		return nil, err
+	}
+	for i := range events {
+		if events[i].UID == uid {
+			return &events[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Create stores a new event, assigning a UID if it has none.
+func (c *Client) Create(ctx context.Context, e *Event) error {
+	if e.UID == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		e.UID = hex.EncodeToString(b) + "@gomikrobot"
+	}
+	e.Raw = ""
+	e.Href = url.PathEscape(e.UID) + ".ics"
+	return c.put(ctx, e, map[string]string{"If-None-Match": "*"})
+}
+
+// Update replaces an event read with Get. It fails with ErrConflict if the
+// event changed on the server in the meantime.
+func (c *Client) Update(ctx context.Context, e *Event) error {
+	if e.Href == "" {
+		return fmt.Errorf("event %s has no location; read it with Get first", e.UID)
+	}
+	headers := map[string]string{}
+	if e.ETag != "" {
+		headers["If-Match"] = e.ETag
+	}
+	return c.put(ctx, e, headers)
+}
+
+func (c *Client) put(ctx context.Context, e *Event, headers map[string]string) error {
+	headers["Content-Type"] = "text/calendar; charset=utf-8"
+	data := e.Encode()
+	resp, respBody, err := c.do(ctx, http.MethodPut, e.Href, []byte(data), headers)
+	if err != nil {
+		return err
+	}
+	switch {
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return ErrConflict
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return fmt.Errorf("save event failed (status %d): %s", resp.StatusCode, truncate(respBody))
+	}
+	e.Raw = data
+	e.ETag = resp.Header.Get("ETag")
+	return nil
+}
+
+func truncate(b []byte) string {
+	s := strings.TrimSpace(string(b))
+	if len(s) > 300 {
+		s = s[:300] + "..."
+	}
+	return s
+}
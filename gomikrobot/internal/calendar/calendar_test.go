@@ -0,0 +1,125 @@
+package calendar
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:call-1\r\n" +
+	"SUMMARY:Call with Ana\\, re: budget\r\n" +
+	"DESCRIPTION:line one\\nline two that is long enough to be\r\n" +
+	"  folded\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20261016T150000\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"ATTENDEE:mailto:ana@example.com\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents(sampleICS, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	e := events[0]
+	if e.Summary != "Call with Ana, re: budget" || e.Description != "line one\nline two that is long enough to be folded" {
+		t.Errorf("unexpected text: %q / %q", e.Summary, e.Description)
+	}
+	if got := e.Start.UTC().Format(time.RFC3339); got != "2026-10-16T13:00:00Z" {
+		t.Errorf("start = %s", got)
+	}
+	if d := e.End.Sub(e.Start); d != 90*time.Minute {
+		t.Errorf("duration = %v", d)
+	}
+
+	allDay, err := ParseEvents("BEGIN:VEVENT\nUID:x\nDTSTART;VALUE=DATE:20261224\nEND:VEVENT\n", time.UTC)
+	if err != nil || len(allDay) != 1 || !allDay[0].AllDay || allDay[0].End.Sub(allDay[0].Start) != 24*time.Hour {
+		t.Errorf("unexpected all-day event: %+v, %v", allDay, err)
+	}
+}
+
+func TestEncodeKeepsUnmodelledProperties(t *testing.T) {
+	events, _ := ParseEvents(sampleICS, time.UTC)
+	e := events[0]
+	e.Summary = "Moved call"
+	e.Start = e.Start.Add(time.Hour)
+	e.End = e.End.Add(time.Hour)
+
+	out := e.Encode()
+	for _, want := range []string{"SUMMARY:Moved call", "DTSTART:20261016T140000Z", "DTEND:20261016T153000Z", "ATTENDEE:mailto:ana@example.com", "BEGIN:VALARM", "DESCRIPTION:Reminder"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("encoded event lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "DURATION") || strings.Count(out, "SUMMARY:") != 1 {
+		t.Errorf("old properties were not replaced:\n%s", out)
+	}
+}
+
+func TestClientListAndUpdate(t *testing.T) {
+	var put struct {
+		path, ifMatch, body string
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "me" || pass != "pw" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.Method {
+		case "REPORT":
+			body, _ := io.ReadAll(r.Body)
+			if !strings.Contains(string(body), "time-range") && !strings.Contains(string(body), `name="UID"`) {
+				t.Errorf("unexpected query: %s", body)
+			}
+			w.WriteHeader(http.StatusMultiStatus)
+			io.WriteString(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+<d:response><d:href>/cal/call-1.ics</d:href><d:propstat><d:prop><d:getetag>"e1"</d:getetag>
+<cal:calendar-data>`+strings.ReplaceAll(sampleICS, "&", "&amp;")+`</cal:calendar-data></d:prop>
+<d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response></d:multistatus>`)
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			put.path, put.ifMatch, put.body = r.URL.Path, r.Header.Get("If-Match"), string(body)
+			w.Header().Set("ETag", `"e2"`)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{URL: srv.URL + "/cal/", Username: "me", Password: "pw", Location: time.UTC}
+	events, err := c.List(context.Background(), time.Now(), time.Now().Add(24*time.Hour))
+	if err != nil || len(events) != 1 || events[0].Href != "/cal/call-1.ics" {
+		t.Fatalf("List() = %+v, %v", events, err)
+	}
+
+	e, err := c.Get(context.Background(), "call-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.Location = "Zoom"
+	if err := c.Update(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	if put.path != "/cal/call-1.ics" || put.ifMatch != `"e1"` || !strings.Contains(put.body, "LOCATION:Zoom") {
+		t.Errorf("unexpected PUT %+v", put)
+	}
+	if e.ETag != `"e2"` {
+		t.Errorf("etag = %s", e.ETag)
+	}
+	if _, err := c.Get(context.Background(), "other"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GoogleTokenURL is Google's OAuth token endpoint.
+const GoogleTokenURL = "https://oauth2.googleapis.com/token"
+
+// GoogleCalDAVURL returns the CalDAV collection of a Google calendar; the
+// primary calendar's ID is the account's email address.
+func GoogleCalDAVURL(calendarID string) string {
+	return "https://apidata.googleusercontent.com/caldav/v2/" + url.PathEscape(calendarID) + "/events/"
+}
+
+// GoogleToken returns a Client.Token function that exchanges an OAuth
+// refresh token for access tokens, caching each until shortly before it
+// expires. The refresh token needs the
+// https://www.googleapis.com/auth/calendar scope.
+func GoogleToken(clientID, clientSecret, refreshToken string) func(ctx context.Context) (string, error) {
+	var mu sync.Mutex
+	var token string
+	var expires time.Time
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if token != "" && time.Now().Before(expires) {
+			return token, nil
+		}
+
+		form := url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {clientID},
+			"client_secret": {clientSecret},
+			"refresh_token": {refreshToken},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, GoogleTokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		resp, err := defaultHTTPClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+
+		var out struct {
+			AccessToken string `json:"access_token"`
+			ExpiresIn   int    `json:"expires_in"`
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return "", fmt.Errorf("parse token response (status %d): %w", resp.StatusCode, err)
+		}
+		if out.AccessToken == "" {
+			return "", fmt.Errorf("token refresh failed: %s %s", out.Error, out.Description)
+		}
+		token = out.AccessToken
+		expires = time.Now().Add(time.Duration(out.ExpiresIn)*time.Second - time.Minute)
+		return token, nil
+	}
+}
@@ -0,0 +1,238 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Event is one VEVENT, or one occurrence of a recurring event.
+type Event struct {
+	UID         string
+	Summary     string
+	Location    string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+
+	// Href and ETag identify the calendar object on the server; Raw is its
+	// iCalendar text, kept so updates preserve properties this package does
+	// not model (attendees, alarms, recurrence rules).
+	Href string
+	ETag string
+	Raw  string
+}
+
+// property is one unfolded content line.
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfold joins continuation lines (RFC 5545 section 3.1).
+func unfold(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, l := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+func parseProperty(line string) property {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	p := property{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: value}
+	for _, kv := range parts[1:] {
+		k, v, _ := strings.Cut(kv, "=")
+		p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p
+}
+
+// ParseEvents returns the VEVENTs in an iCalendar object. Floating times
+// are read in loc.
+func ParseEvents(data string, loc *time.Location) ([]Event, error) {
+	var events []Event
+	var cur *Event
+	var duration time.Duration
+	depth := 0 // Nesting inside the VEVENT, e.g. VALARM
+	for _, line := range unfold(data) {
+		p := parseProperty(line)
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			cur, duration, depth = &Event{}, 0, 0
+			continue
+		case cur == nil:
+			continue
+		case p.name == "BEGIN":
+			depth++
+			continue
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			if cur.End.IsZero() {
+				switch {
+				case duration > 0:
+					cur.End = cur.Start.Add(duration)
+				case cur.AllDay:
+					cur.End = cur.Start.AddDate(0, 0, 1)
+				default:
+					cur.End = cur.Start
+				}
+			}
+			cur.Raw = data
+			events = append(events, *cur)
+			cur = nil
+			continue
+		case p.name == "END":
+			depth--
+			continue
+		case depth > 0:
+			continue
+		}
+
+		switch p.name {
+		case "UID":
+			cur.UID = p.value
+		case "SUMMARY":
+			cur.Summary = unescapeText(p.value)
+		case "LOCATION":
+			cur.Location = unescapeText(p.value)
+		case "DESCRIPTION":
+			cur.Description = unescapeText(p.value)
+		case "DTSTART", "DTEND":
+			t, allDay, err := parseDateTime(p, loc)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", p.name, err)
+			}
+			if p.name == "DTSTART" {
+				cur.Start, cur.AllDay = t, allDay
+			} else {
+				cur.End = t
+			}
+		case "DURATION":
+			duration = parseDuration(p.value)
+		}
+	}
+	return events, nil
+}
+
+func parseDateTime(p property, loc *time.Location) (time.Time, bool, error) {
+	if p.params["VALUE"] == "DATE" || len(p.value) == 8 {
+		t, err := time.ParseInLocation("20060102", p.value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(p.value, "Z") {
+		t, err := time.Parse("20060102T150405Z", p.value)
+		return t, false, err
+	}
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", p.value, loc)
+	return t, false, err
+}
+
+// parseDuration reads the common forms of an RFC 5545 duration such as
+// PT1H30M or P1D; unknown forms yield 0.
+func parseDuration(v string) time.Duration {
+	v = strings.TrimPrefix(strings.TrimPrefix(v, "+"), "P")
+	var d time.Duration
+	n := 0
+	inTime := false
+	for _, r := range v {
+		switch {
+		case r >= '0' && r <= '9':
+			n = n*10 + int(r-'0')
+			continue
+		case r == 'T':
+			inTime = true
+		case r == 'W':
+			d += time.Duration(n) * 7 * 24 * time.Hour
+		case r == 'D':
+			d += time.Duration(n) * 24 * time.Hour
+		case r == 'H' && inTime:
+			d += time.Duration(n) * time.Hour
+		case r == 'M' && inTime:
+			d += time.Duration(n) * time.Minute
+		case r == 'S' && inTime:
+			d += time.Duration(n) * time.Second
+		default:
+			return 0
+		}
+		n = 0
+	}
+	return d
+}
+
+func unescapeText(s string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(s)
+}
+
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, ",", `\,`, ";", `\;`).Replace(s)
+}
+
+// Encode returns e as an iCalendar object. If e.Raw is set, the modelled
+// properties of its first VEVENT are replaced and everything else is kept.
+func (e *Event) Encode() string {
+	props := []string{
+		"SUMMARY:" + escapeText(e.Summary),
+	}
+	if e.AllDay {
+		props = append(props,
+			"DTSTART;VALUE=DATE:"+e.Start.Format("20060102"),
+			"DTEND;VALUE=DATE:"+e.End.Format("20060102"))
+	} else {
+		props = append(props,
+			"DTSTART:"+e.Start.UTC().Format("20060102T150405Z"),
+			"DTEND:"+e.End.UTC().Format("20060102T150405Z"))
+	}
+	if e.Location != "" {
+		props = append(props, "LOCATION:"+escapeText(e.Location))
+	}
+	if e.Description != "" {
+		props = append(props, "DESCRIPTION:"+escapeText(e.Description))
+	}
+	props = append(props, "DTSTAMP:"+time.Now().UTC().Format("20060102T150405Z"))
+
+	if e.Raw == "" {
+		lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//GoMikroBot//Calendar//EN", "BEGIN:VEVENT", "UID:" + e.UID}
+		lines = append(lines, props...)
+		lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+		return strings.Join(lines, "\r\n") + "\r\n"
+	}
+
+	replaced := map[string]bool{"SUMMARY": true, "DTSTART": true, "DTEND": true, "DURATION": true, "LOCATION": true, "DESCRIPTION": true, "DTSTAMP": true}
+	var out []string
+	inEvent, done := false, false
+	depth := 0
+	for _, line := range unfold(e.Raw) {
+		p := parseProperty(line)
+		switch {
+		case done:
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT"):
+			inEvent = true
+		case inEvent && p.name == "BEGIN":
+			depth++
+		case inEvent && p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			out = append(out, props...)
+			inEvent, done = false, true
+		case inEvent && p.name == "END":
+			depth--
+		case inEvent && depth == 0 && replaced[p.name]:
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\r\n") + "\r\n"
+}
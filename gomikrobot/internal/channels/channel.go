@@ -2,10 +2,17 @@ package channels
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/classify"
+	"github.com/kamir/gomikrobot/internal/timeline"
 )
 
+// presenceTimeout bounds one typing or read-receipt call.
+const presenceTimeout = 10 * time.Second
+
 // Channel defines the interface for chat platforms (Telegram, WhatsApp, etc).
 type Channel interface {
 	// Name returns the channel name (e.g. "telegram").
@@ -21,4 +28,85 @@ type Channel interface {
 // BaseChannel provides common functionality for channels.
 type BaseChannel struct {
 	Bus *bus.MessageBus
+	// Classifier, if set, labels inbound messages before they are logged.
+	Classifier *classify.Classifier
+}
+
+// classify returns the label of an inbound message, or "" without a
+// classifier.
+func (b *BaseChannel) classify(ctx context.Context, content string) string {
+	res := b.Classifier.Classify(ctx, content)
+	if res.Label != "" {
+		fmt.Printf("🏷️ Classified as %s (%s)\n", res.Label, res.Source)
+	}
+	return res.Label
+}
+
+// ChannelCapabilities is implemented by channels that can show presence in
+// a chat.
+type ChannelCapabilities interface {
+	// SendTyping shows (typing=true) or clears the typing indicator.
+	SendTyping(ctx context.Context, chatID string, typing bool) error
+	// MarkRead sends read receipts for messages from sender in chatID.
+	MarkRead(ctx context.Context, chatID string, messageIDs []string, sender string) error
+}
+
+// SubscribePresence routes the bus's presence events for ch to its
+// ChannelCapabilities. Channels without capabilities are skipped.
+func SubscribePresence(b *bus.MessageBus, ch Channel) {
+	caps, ok := ch.(ChannelCapabilities)
+	if !ok {
+		return
+	}
+	b.SubscribePresence(ch.Name(), func(evt *bus.PresenceEvent) {
+		ctx, cancel := context.WithTimeout(context.Background(), presenceTimeout)
+		defer cancel()
+		var err error
+		switch evt.Kind {
+		case bus.PresenceTyping, bus.PresenceTypingStop:
+			err = caps.SendTyping(ctx, evt.ChatID, evt.Kind == bus.PresenceTyping)
+		case bus.PresenceRead:
+			err = caps.MarkRead(ctx, evt.ChatID, evt.MessageIDs, evt.SenderID)
+		}
+		if err != nil {
+			fmt.Printf("⚠️ %s %s for %s failed: %v\n", ch.Name(), evt.Kind, evt.ChatID, err)
+		}
+	})
+}
+
+// replyLanguage returns the language for canned replies to sender in chatID,
+// or "" for English.
+func replyLanguage(tl *timeline.TimelineService, channel, chatID, sender string) string {
+	if tl == nil {
+		return ""
+	}
+	return tl.ReplyLanguage(channel+":"+chatID, sender)
+}
+
+// outboundAllowed reports whether msg may be sent now. In silent mode it is
+// dropped; during the recipient's quiet hours it is held until they end and
+// the gateway delivers it then.
+func outboundAllowed(tl *timeline.TimelineService, msg *bus.OutboundMessage) bool {
+	if tl == nil {
+		return true
+	}
+	if tl.SilentFor(msg.Channel, msg.ChatID) {
+		fmt.Printf("🔇 Silent Mode: suppressed outbound to %s\n", msg.ChatID)
+		return false
+	}
+	q, ok := tl.QuietHoursFor(msg.Channel, msg.ChatID)
+	if !ok {
+		return true
+	}
+	until := q.Until(time.Now())
+	if until.IsZero() {
+		return true
+	}
+	held := &timeline.HeldMessage{Channel: msg.Channel, ChatID: msg.ChatID, Content: msg.Content, Trace: msg.Trace, ReleaseAt: until}
+	if err := tl.HoldMessage(held); err != nil {
+		fmt.Printf("⚠️ Failed to hold message for %s during quiet hours, sending now: %v\n", msg.ChatID, err)
+		return true
+	}
+	fmt.Printf("🌙 Quiet hours: holding outbound to %s until %s\n", msg.ChatID, until.Format("15:04"))
+	return false
 }
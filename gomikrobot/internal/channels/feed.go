@@ -0,0 +1,213 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/feeds"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+const (
+	// feedSkipReply is the response that means "nothing worth forwarding".
+	feedSkipReply = "SKIP"
+	// maxFeedPromptItems caps the items in one prompt.
+	maxFeedPromptItems = 20
+	// feedRecheckWindow ignores items published this long before the last
+	// poll, so entries whose timeline event was pruned do not fire again.
+	feedRecheckWindow = 7 * 24 * time.Hour
+)
+
+// FeedChannel polls RSS and Atom feeds. New items are stored in the
+// timeline and, for feeds with a prompt, sent to the agent; the response is
+// forwarded to the feed's configured chat.
+type FeedChannel struct {
+	BaseChannel
+	timeline *timeline.TimelineService
+	interval time.Duration
+	feeds    map[string]config.FeedConfig
+	prompts  map[string]*template.Template
+	// fetch is feeds.Fetch, replaceable in tests.
+	fetch func(ctx context.Context, url string) ([]feeds.Item, error)
+
+	startOnce sync.Once
+}
+
+// feedPromptData is the template input for a feed prompt.
+type feedPromptData struct {
+	Feed  string
+	Items []feeds.Item
+}
+
+// NewFeedChannel creates a feed channel for the configured subscriptions.
+func NewFeedChannel(cfg config.FeedsConfig, messageBus *bus.MessageBus, tl *timeline.TimelineService) (*FeedChannel, error) {
+	c := &FeedChannel{
+		BaseChannel: BaseChannel{Bus: messageBus},
+		timeline:    tl,
+		interval:    cfg.Interval,
+		feeds:       make(map[string]config.FeedConfig, len(cfg.Feeds)),
+		prompts:     make(map[string]*template.Template),
+		fetch:       feeds.Fetch,
+	}
+	if c.interval <= 0 {
+		c.interval = 30 * time.Minute
+	}
+	for _, f := range cfg.Feeds {
+		c.feeds[f.Name] = f
+		if f.Prompt == "" {
+			continue
+		}
+		t, err := template.New(f.Name).Option("missingkey=zero").Parse(f.Prompt)
+		if err != nil {
+			return nil, fmt.Errorf("feed %s: invalid prompt: %w", f.Name, err)
+		}
+		c.prompts[f.Name] = t
+	}
+	return c, nil
+}
+
+func (c *FeedChannel) Name() string { return "feed" }
+
+// Start subscribes to the agent's responses and polls the feeds until ctx
+// is cancelled.
+func (c *FeedChannel) Start(ctx context.Context) error {
+	if len(c.feeds) == 0 {
+		return nil
+	}
+	c.startOnce.Do(func() {
+		c.Bus.Subscribe(c.Name(), func(msg *bus.OutboundMessage) {
+			if err := c.Send(context.Background(), msg); err != nil {
+				fmt.Printf("Error forwarding feed response: %v\n", err)
+			}
+		})
+		go c.run(ctx)
+	})
+	return nil
+}
+
+func (c *FeedChannel) Stop() error { return nil }
+
+func (c *FeedChannel) run(ctx context.Context) {
+	c.pollAll(ctx)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.pollAll(ctx)
+		}
+	}
+}
+
+func (c *FeedChannel) pollAll(ctx context.Context) {
+	for _, f := range c.feeds {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := c.poll(ctx, f); err != nil {
+			fmt.Printf("⚠️ Feed %s: %v\n", f.Name, err)
+		}
+	}
+}
+
+// poll stores the feed's new matching items and prompts the agent about
+// them. The first poll of a feed only records what is already there.
+func (c *FeedChannel) poll(ctx context.Context, f config.FeedConfig) error {
+	items, err := c.fetch(ctx, f.URL)
+	if err != nil {
+		return err
+	}
+	lastKey := "feed_last_poll:" + f.Name
+	last, _ := c.timeline.GetSetting(lastKey)
+	lastPoll, _ := time.Parse(time.RFC3339, last)
+	firstPoll := lastPoll.IsZero()
+
+	var fresh []feeds.Item
+	for _, it := range items {
+		if !feeds.Match(it, f.Include, f.Exclude) {
+			continue
+		}
+		if !firstPoll && !it.Published.IsZero() && it.Published.Before(lastPoll.Add(-feedRecheckWindow)) {
+			continue
+		}
+		eventID := "feed:" + f.Name + ":" + it.Key()
+		if seen, err := c.timeline.HasEvent(eventID); err != nil {
+			return err
+		} else if seen {
+			continue
+		}
+		ts := it.Published
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		err := c.timeline.AddEvent(&timeline.TimelineEvent{
+			EventID:     eventID,
+			Timestamp:   ts,
+			SenderID:    "feed:" + f.Name,
+			SenderName:  "Feed " + f.Name,
+			EventType:   "FEED",
+			ContentText: strings.TrimSpace(it.Title + "\n" + it.Link + "\n" + it.Summary),
+			Authorized:  true,
+		})
+		if err != nil {
+			return fmt.Errorf("store item: %w", err)
+		}
+		fresh = append(fresh, it)
+	}
+	_ = c.timeline.SetSetting(lastKey, time.Now().Format(time.RFC3339))
+
+	if len(fresh) == 0 {
+		return nil
+	}
+	fmt.Printf("📰 Feed %s: %d new items\n", f.Name, len(fresh))
+	tmpl, ok := c.prompts[f.Name]
+	if !ok || firstPoll {
+		return nil
+	}
+	if len(fresh) > maxFeedPromptItems {
+		fresh = fresh[:maxFeedPromptItems]
+	}
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, feedPromptData{Feed: f.Name, Items: fresh}); err != nil {
+		return fmt.Errorf("prompt template: %w", err)
+	}
+	c.Bus.PublishInbound(&bus.InboundMessage{
+		Channel:  c.Name(),
+		SenderID: f.Name,
+		ChatID:   f.Name,
+		Content:  prompt.String(),
+		Metadata: map[string]any{"event_id": fmt.Sprintf("feed:%s:%d", f.Name, time.Now().UnixNano())},
+	})
+	return nil
+}
+
+// Send forwards the agent's response about a feed to its configured chat.
+// A response of just SKIP is dropped.
+func (c *FeedChannel) Send(ctx context.Context, msg *bus.OutboundMessage) error {
+	f, ok := c.feeds[msg.ChatID]
+	if !ok {
+		return fmt.Errorf("unknown feed %q", msg.ChatID)
+	}
+	if strings.TrimSpace(msg.Content) == feedSkipReply {
+		return nil
+	}
+	if f.ForwardChannel == "" || f.ForwardChatID == "" {
+		fmt.Printf("📰 Feed %s response (not forwarded): %s\n", f.Name, shorten(msg.Content, 200))
+		return nil
+	}
+	c.Bus.PublishOutbound(&bus.OutboundMessage{
+		Channel: f.ForwardChannel,
+		ChatID:  f.ForwardChatID,
+		Content: msg.Content,
+	})
+	return nil
+}
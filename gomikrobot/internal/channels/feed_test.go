@@ -0,0 +1,59 @@
+package channels
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/feeds"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+func TestFeedPromptsOnlyAboutNewMatchingItems(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+	mb := bus.NewMessageBus()
+	f := config.FeedConfig{Name: "golang", URL: "https://example.com/feed", Include: []string{"release"}, Prompt: "{{range .Items}}{{.Title}};{{end}}"}
+	c, err := NewFeedChannel(config.FeedsConfig{Feeds: []config.FeedConfig{f}}, mb, tl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := []feeds.Item{{ID: "1", Title: "Go 1.24 release"}, {ID: "2", Title: "Community survey"}}
+	c.fetch = func(ctx context.Context, url string) ([]feeds.Item, error) { return items, nil }
+
+	// The first poll records existing items without prompting.
+	if err := c.poll(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+	items = append(items, feeds.Item{ID: "3", Title: "Go 1.25 release candidate"}, feeds.Item{ID: "4", Title: "Meetup"})
+	if err := c.poll(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := mb.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "Go 1.25 release candidate;" || msg.Channel != "feed" || msg.ChatID != "golang" {
+		t.Errorf("unexpected prompt %+v", msg)
+	}
+
+	events, _ := tl.GetEvents(timeline.FilterArgs{Limit: 10})
+	if len(events) != 2 {
+		t.Fatalf("expected 2 stored items, got %d", len(events))
+	}
+	for _, e := range events {
+		if e.EventType != "FEED" || !strings.Contains(e.ContentText, "release") {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+}
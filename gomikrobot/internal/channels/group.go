@@ -0,0 +1,80 @@
+package channels
+
+import (
+	"strings"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// contextInfo returns the mention and quote metadata of a message, which
+// WhatsApp attaches to whichever sub-message carries the content.
+func contextInfo(m *waE2E.Message) *waE2E.ContextInfo {
+	switch {
+	case m.GetExtendedTextMessage() != nil:
+		return m.GetExtendedTextMessage().GetContextInfo()
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage().GetContextInfo()
+	case m.GetAudioMessage() != nil:
+		return m.GetAudioMessage().GetContextInfo()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage().GetContextInfo()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage().GetContextInfo()
+	}
+	return nil
+}
+
+// messageText returns the text or caption of a message.
+func messageText(m *waE2E.Message) string {
+	switch {
+	case m.GetConversation() != "":
+		return m.GetConversation()
+	case m.GetExtendedTextMessage() != nil:
+		return m.GetExtendedTextMessage().GetText()
+	case m.GetImageMessage() != nil:
+		return m.GetImageMessage().GetCaption()
+	case m.GetDocumentMessage() != nil:
+		return m.GetDocumentMessage().GetCaption()
+	case m.GetVideoMessage() != nil:
+		return m.GetVideoMessage().GetCaption()
+	}
+	return ""
+}
+
+// addressesBot reports whether a group message is meant for the bot: it
+// mentions one of the bot's JIDs, replies to a message the bot sent, or
+// contains "@name" for one of names.
+func addressesBot(m *waE2E.Message, self []types.JID, names []string) bool {
+	isSelf := func(jid string) bool {
+		parsed, err := types.ParseJID(jid)
+		if err != nil {
+			return false
+		}
+		for _, s := range self {
+			if s.User != "" && parsed.User == s.User && parsed.Server == s.Server {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ci := contextInfo(m); ci != nil {
+		for _, jid := range ci.GetMentionedJID() {
+			if isSelf(jid) {
+				return true
+			}
+		}
+		if ci.GetStanzaID() != "" && isSelf(ci.GetParticipant()) {
+			return true
+		}
+	}
+
+	text := strings.ToLower(messageText(m))
+	for _, name := range names {
+		if name != "" && strings.Contains(text, "@"+strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
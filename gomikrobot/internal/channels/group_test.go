@@ -0,0 +1,41 @@
+package channels
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestAddressesBot(t *testing.T) {
+	self := []types.JID{
+		types.NewJID("4915550001", types.DefaultUserServer),
+		types.NewJID("123456789", types.HiddenUserServer),
+	}
+	text := func(s string, ci *waE2E.ContextInfo) *waE2E.Message {
+		return &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{Text: proto.String(s), ContextInfo: ci}}
+	}
+
+	tests := []struct {
+		name string
+		msg  *waE2E.Message
+		want bool
+	}{
+		{"plain chatter", &waE2E.Message{Conversation: proto.String("dinner at 7?")}, false},
+		{"mention by phone JID", text("@4915550001 weather?", &waE2E.ContextInfo{MentionedJID: []string{"4915550001@s.whatsapp.net"}}), true},
+		{"mention by LID", text("@bot weather?", &waE2E.ContextInfo{MentionedJID: []string{"123456789@lid"}}), true},
+		{"mention of someone else", text("@mum", &waE2E.ContextInfo{MentionedJID: []string{"4915550002@s.whatsapp.net"}}), false},
+		{"reply to the bot", text("thanks!", &waE2E.ContextInfo{StanzaID: proto.String("ABC"), Participant: proto.String("4915550001@s.whatsapp.net")}), true},
+		{"reply to someone else", text("thanks!", &waE2E.ContextInfo{StanzaID: proto.String("ABC"), Participant: proto.String("4915550002@s.whatsapp.net")}), false},
+		{"text mention by name", &waE2E.Message{Conversation: proto.String("@Mikro what's the time?")}, true},
+		{"image caption mention", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: proto.String("@mikro what is this?")}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addressesBot(tt.msg, self, []string{"mikro"}); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
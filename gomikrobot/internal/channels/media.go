@@ -0,0 +1,164 @@
+package channels
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/i18n"
+)
+
+// Media kinds checked by a MediaPolicy.
+const (
+	MediaImage    = "image"
+	MediaAudio    = "audio"
+	MediaDocument = "document"
+)
+
+// transcribableAudio lists audio MIME types Whisper accepts directly.
+var transcribableAudio = map[string]bool{
+	"audio/ogg":   true,
+	"audio/opus":  true,
+	"audio/mpeg":  true,
+	"audio/mp3":   true,
+	"audio/mp4":   true,
+	"audio/m4a":   true,
+	"audio/x-m4a": true,
+	"audio/wav":   true,
+	"audio/x-wav": true,
+	"audio/webm":  true,
+	"audio/flac":  true,
+}
+
+// MediaRejection is returned when inbound media violates the channel's
+// policy. Reply is safe to send back to the user.
+type MediaRejection struct {
+	Kind  string
+	Reply string
+	// msg and args rebuild Reply in another language.
+	msg  string
+	args []any
+}
+
+func (e *MediaRejection) Error() string { return e.Reply }
+
+// rejection builds a MediaRejection from a canned i18n message.
+func rejection(kind, msg string, args ...any) *MediaRejection {
+	return &MediaRejection{Kind: kind, Reply: i18n.T("en", msg, args...), msg: msg, args: args}
+}
+
+// Localized returns Reply in lang.
+func (e *MediaRejection) Localized(lang string) string {
+	if e.msg == "" {
+		return e.Reply
+	}
+	return i18n.T(lang, e.msg, e.args...)
+}
+
+// CheckMedia validates the declared MIME type and size of an inbound
+// attachment against the policy. It returns nil if the media is acceptable.
+func CheckMedia(policy config.MediaPolicy, kind, mimeType string, size int64) *MediaRejection {
+	mimeType = baseMIME(mimeType)
+	if !mimeAllowed(policy.AllowedTypes, mimeType) {
+		return rejection(kind, i18n.MsgMediaType, kind, mimeType, strings.Join(policy.AllowedTypes, ", "))
+	}
+
+	var limit int64
+	switch kind {
+	case MediaImage:
+		limit = policy.MaxImageBytes
+	case MediaAudio:
+		limit = policy.MaxAudioBytes
+	case MediaDocument:
+		limit = policy.MaxDocumentBytes
+	}
+	if limit > 0 && size > limit {
+		return rejection(kind, i18n.MsgMediaTooLarge, kind, humanBytes(size), humanBytes(limit))
+	}
+	return nil
+}
+
+// NeedsTranscode reports whether audio of this MIME type must be converted
+// before transcription.
+func NeedsTranscode(mimeType string) bool {
+	return !transcribableAudio[baseMIME(mimeType)]
+}
+
+// TranscodeAudio converts src to a 16 kHz mono WAV file next to it using
+// ffmpeg and returns the new path.
+func TranscodeAudio(ctx context.Context, ffmpeg, src string) (string, error) {
+	if ffmpeg == "" {
+		ffmpeg = "ffmpeg"
+	}
+	bin, err := exec.LookPath(ffmpeg)
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg not found: %w", err)
+	}
+
+	dst := strings.TrimSuffix(src, filepath.Ext(src)) + ".wav"
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, bin, "-y", "-loglevel", "error", "-i", src, "-vn", "-ac", "1", "-ar", "16000", dst)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("ffmpeg: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return dst, nil
+}
+
+func baseMIME(mimeType string) string {
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+func mimeAllowed(allowed []string, mimeType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if a == mimeType || a == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mimeType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AttachmentType returns the MIME type of the file at path, from its
+// extension or else its content.
+func AttachmentType(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return baseMIME(t)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "application/octet-stream"
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return baseMIME(http.DetectContentType(head[:n]))
+}
+
+func humanBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
@@ -0,0 +1,76 @@
+package channels
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kamir/gomikrobot/internal/config"
+)
+
+func TestCheckMedia(t *testing.T) {
+	policy := config.MediaPolicy{
+		MaxImageBytes: 1024,
+		MaxAudioBytes: 2048,
+		AllowedTypes:  []string{"image/jpeg", "audio/*"},
+	}
+
+	tests := []struct {
+		name   string
+		kind   string
+		mime   string
+		size   int64
+		reject bool
+	}{
+		{"allowed image", MediaImage, "image/jpeg", 512, false},
+		{"image too large", MediaImage, "image/jpeg", 4096, true},
+		{"type not allowed", MediaImage, "image/png", 10, true},
+		{"wildcard audio with params", MediaAudio, "audio/ogg; codecs=opus", 100, false},
+		{"audio too large", MediaAudio, "audio/amr", 4096, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rej := CheckMedia(policy, tt.kind, tt.mime, tt.size)
+			if (rej != nil) != tt.reject {
+				t.Fatalf("CheckMedia() = %v, want reject=%v", rej, tt.reject)
+			}
+			if rej != nil && rej.Reply == "" {
+				t.Error("rejection has no user-facing reply")
+			}
+		})
+	}
+}
+
+func TestCheckMediaEmptyPolicyAllowsAll(t *testing.T) {
+	if rej := CheckMedia(config.MediaPolicy{}, MediaDocument, "application/x-anything", 1<<40); rej != nil {
+		t.Errorf("expected empty policy to allow everything, got %v", rej)
+	}
+}
+
+func TestNeedsTranscode(t *testing.T) {
+	if NeedsTranscode("audio/ogg; codecs=opus") {
+		t.Error("ogg/opus should be transcribed directly")
+	}
+	if !NeedsTranscode("audio/amr") {
+		t.Error("amr should need transcoding")
+	}
+}
+
+func TestAttachmentType(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string][]byte{
+		"report.pdf": []byte("%PDF-1.7"),
+		"photo":      []byte("\xff\xd8\xff\xe0\x00\x10JFIF"),
+		"notes":      []byte("plain words"),
+	}
+	want := map[string]string{"report.pdf": "application/pdf", "photo": "image/jpeg", "notes": "text/plain"}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := AttachmentType(path); got != want[name] {
+			t.Errorf("AttachmentType(%s) = %s, want %s", name, got, want[name])
+		}
+	}
+}
@@ -0,0 +1,516 @@
+package channels
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tracing"
+)
+
+// signalGroupPrefix marks group chat IDs; other chat IDs are numbers.
+const signalGroupPrefix = "group."
+
+// SignalChannel talks to a signal-cli daemon ("signal-cli daemon --http")
+// over its JSON-RPC endpoint and reads incoming messages from its event
+// stream.
+//
+// Chat IDs are the sender's number for direct messages and
+// "group.<groupId>" for groups.
+type SignalChannel struct {
+	BaseChannel
+	config   config.SignalConfig
+	timeline *timeline.TimelineService
+	mediaDir string
+	rpc      *SignalRPC
+
+	mu         sync.Mutex
+	cancel     context.CancelFunc
+	connected  bool
+	subscribed bool
+}
+
+// NewSignalChannel creates a new Signal channel. Attachments are stored
+// under mediaDir/signal.
+func NewSignalChannel(cfg config.SignalConfig, messageBus *bus.MessageBus, tl *timeline.TimelineService, mediaDir string) *SignalChannel {
+	return &SignalChannel{
+		BaseChannel: BaseChannel{Bus: messageBus},
+		config:      cfg,
+		timeline:    tl,
+		mediaDir:    mediaDir,
+		rpc:         NewSignalRPC(cfg.URL),
+	}
+}
+
+func (c *SignalChannel) Name() string { return "signal" }
+
+// Connected reports whether the event stream is open.
+func (c *SignalChannel) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+func (c *SignalChannel) setConnected(v bool) {
+	c.mu.Lock()
+	c.connected = v
+	c.mu.Unlock()
+}
+
+func (c *SignalChannel) Start(ctx context.Context) error {
+	if !c.config.Enabled {
+		return nil
+	}
+	if c.config.Account == "" {
+		return fmt.Errorf("signal: no account configured")
+	}
+	if err := c.rpc.Check(ctx); err != nil {
+		return fmt.Errorf("signal-cli at %s: %w", c.config.URL, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+	fmt.Printf("Signal: Connected to signal-cli as %s\n", c.config.Account)
+	go c.runEvents(ctx)
+
+	c.mu.Lock()
+	subscribed := c.subscribed
+	c.subscribed = true
+	c.mu.Unlock()
+	if subscribed {
+		return nil
+	}
+	c.Bus.SubscribeSender(c.Name(), func(ctx context.Context, msg *bus.OutboundMessage) error {
+		if !outboundAllowed(c.timeline, msg) {
+			return nil
+		}
+		return c.Send(ctx, msg)
+	})
+	return nil
+}
+
+func (c *SignalChannel) Stop() error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+func (c *SignalChannel) Send(ctx context.Context, msg *bus.OutboundMessage) error {
+	if msg.ChatID == "" {
+		return bus.Permanent(fmt.Errorf("signal: empty chat ID"))
+	}
+	params := c.target(msg.ChatID)
+	params["message"] = msg.Content
+	if len(msg.Media) > 0 {
+		// signal-cli reads attachments from its own file system, so this
+		// needs it on the same host.
+		params["attachments"] = msg.Media
+	}
+	err := c.rpc.Call(ctx, "send", params, nil)
+	var rpcErr *SignalRPCError
+	if errors.As(err, &rpcErr) && rpcErr.Permanent() {
+		return bus.Permanent(err)
+	}
+	return err
+}
+
+// target returns the RPC parameters addressing chatID.
+func (c *SignalChannel) target(chatID string) map[string]any {
+	params := map[string]any{"account": c.config.Account}
+	if group, ok := strings.CutPrefix(chatID, signalGroupPrefix); ok {
+		params["groupId"] = group
+	} else {
+		params["recipient"] = []string{chatID}
+	}
+	return params
+}
+
+// runEvents keeps the event stream open, reconnecting with backoff.
+func (c *SignalChannel) runEvents(ctx context.Context) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		start := time.Now()
+		err := c.serveEvents(ctx)
+		c.setConnected(false)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		fmt.Printf("Signal: event stream closed (%v), reconnecting in %s\n", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// serveEvents reads the server-sent event stream until it ends.
+func (c *SignalChannel) serveEvents(ctx context.Context) error {
+	u := c.rpc.base + "/api/v1/events?account=" + url.QueryEscape(c.config.Account)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	// The stream stays open indefinitely, so it gets no client timeout.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("events: status %d", resp.StatusCode)
+	}
+	c.setConnected(true)
+
+	return readSSE(resp.Body, func(data []byte) {
+		var ev struct {
+			Envelope signalEnvelope `json:"envelope"`
+		}
+		if err := json.Unmarshal(data, &ev); err != nil {
+			fmt.Printf("⚠️ Signal: bad event: %v\n", err)
+			return
+		}
+		go c.handleEnvelope(context.Background(), ev.Envelope)
+	})
+}
+
+// readSSE calls fn with the data of every event in a text/event-stream.
+func readSSE(r io.Reader, fn func(data []byte)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64<<10), 4<<20)
+	var data []byte
+	for sc.Scan() {
+		line := sc.Bytes()
+		switch {
+		case len(line) == 0:
+			if len(data) > 0 {
+				fn(data)
+				data = nil
+			}
+		case bytes.HasPrefix(line, []byte("data:")):
+			if data != nil {
+				data = append(data, '\n')
+			}
+			data = append(data, bytes.TrimPrefix(bytes.TrimPrefix(line, []byte("data:")), []byte(" "))...)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+// signalAttachment is a file attached to a message.
+type signalAttachment struct {
+	ID          string `json:"id"`
+	ContentType string `json:"contentType"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+}
+
+// signalEnvelope is the subset of a received envelope we use. Receipts,
+// typing notices and sync messages have no DataMessage.
+type signalEnvelope struct {
+	Source       string `json:"source"`
+	SourceNumber string `json:"sourceNumber"`
+	SourceName   string `json:"sourceName"`
+	Timestamp    int64  `json:"timestamp"`
+	DataMessage  *struct {
+		Message     string             `json:"message"`
+		Attachments []signalAttachment `json:"attachments"`
+		GroupInfo   *struct {
+			GroupID string `json:"groupId"`
+		} `json:"groupInfo"`
+	} `json:"dataMessage"`
+}
+
+// sender returns the number of the sender, falling back to its UUID for
+// accounts that hide their number.
+func (ev signalEnvelope) sender() string {
+	if ev.SourceNumber != "" {
+		return ev.SourceNumber
+	}
+	return ev.Source
+}
+
+func (c *SignalChannel) handleEnvelope(ctx context.Context, ev signalEnvelope) {
+	dm := ev.DataMessage
+	sender := ev.sender()
+	if dm == nil || sender == "" || sender == c.config.Account {
+		return
+	}
+
+	ctx, span := tracing.StartKind(ctx, tracing.KindServer, "signal.receive", "group", dm.GroupInfo != nil, "attachments", len(dm.Attachments))
+	defer span.End()
+
+	chatID := sender
+	if dm.GroupInfo != nil {
+		chatID = signalGroupPrefix + dm.GroupInfo.GroupID
+	}
+	authorized := c.isAllowed(sender)
+	if !authorized {
+		fmt.Printf("🚫 Unauthorized Signal sender: %s\n", sender)
+	}
+
+	content := dm.Message
+	var mediaPaths []string
+	for _, a := range dm.Attachments {
+		kind := mediaKind(a.ContentType)
+		if rej := CheckMedia(c.config.Media, kind, a.ContentType, a.Size); rej != nil {
+			c.logEvent(ev, "REJECTED", fmt.Sprintf("[Rejected %s] %s", kind, rej.Reply), "", "", authorized)
+			if authorized {
+				reply := rej.Localized(replyLanguage(c.timeline, c.Name(), chatID, sender))
+				_ = c.Send(ctx, &bus.OutboundMessage{Channel: c.Name(), ChatID: chatID, Content: reply})
+			}
+			continue
+		}
+		if !authorized {
+			// Don't store files from strangers.
+			continue
+		}
+		path, err := c.download(ctx, chatID, ev.Timestamp, a)
+		if err != nil {
+			fmt.Printf("❌ Signal attachment download error: %v\n", err)
+			continue
+		}
+		mediaPaths = append(mediaPaths, path)
+		name := a.Filename
+		if name == "" {
+			name = a.ContentType
+		}
+		content += fmt.Sprintf("\n[%s: %s (%s)]", strings.ToUpper(kind[:1])+kind[1:], path, name)
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+
+	label := c.classify(ctx, content)
+	c.logEvent(ev, "TEXT", content, strings.Join(mediaPaths, ","), label, authorized)
+
+	if authorized {
+		c.Bus.PublishInbound(&bus.InboundMessage{
+			Channel:   c.Name(),
+			SenderID:  sender,
+			ChatID:    chatID,
+			Content:   content,
+			Media:     mediaPaths,
+			Metadata:  map[string]any{"event_id": sender + ":" + strconv.FormatInt(ev.Timestamp, 10), "classification": label},
+			Timestamp: time.UnixMilli(ev.Timestamp),
+			Trace:     tracing.Traceparent(ctx),
+		})
+	}
+}
+
+func (c *SignalChannel) isAllowed(number string) bool {
+	if len(c.config.AllowFrom) == 0 {
+		return true
+	}
+	for _, allowed := range c.config.AllowFrom {
+		if allowed == number {
+			return true
+		}
+	}
+	return false
+}
+
+// download fetches an attachment from signal-cli and saves it under
+// mediaDir/signal.
+func (c *SignalChannel) download(ctx context.Context, chatID string, ts int64, a signalAttachment) (string, error) {
+	params := c.target(chatID)
+	params["id"] = a.ID
+	var res struct {
+		Data string `json:"data"`
+	}
+	if err := c.rpc.Call(ctx, "getAttachment", params, &res); err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(res.Data)
+	if err != nil {
+		return "", fmt.Errorf("attachment %s: %w", a.ID, err)
+	}
+
+	dir := filepath.Join(c.mediaDir, "signal")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := a.Filename
+	if name == "" {
+		name = a.ID
+	}
+	name = unsafeNameChars.Replace(filepath.Base(name))
+	path := filepath.Join(dir, fmt.Sprintf("%d-%s", ts, name))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+	fmt.Printf("📎 Signal attachment saved to %s\n", path)
+	return path, nil
+}
+
+func (c *SignalChannel) logEvent(ev signalEnvelope, evtType, content, media, classification string, authorized bool) {
+	if c.timeline == nil {
+		return
+	}
+	name := ev.SourceName
+	if name == "" {
+		name = "Signal User"
+	}
+	err := c.timeline.AddEvent(&timeline.TimelineEvent{
+		EventID:        fmt.Sprintf("signal:%s:%d", ev.sender(), ev.Timestamp),
+		Timestamp:      time.Now(),
+		SenderID:       ev.sender(),
+		SenderName:     name,
+		EventType:      evtType,
+		ContentText:    content,
+		MediaPath:      media,
+		Classification: classification,
+		Authorized:     authorized,
+	})
+	if err != nil {
+		fmt.Printf("⚠️ Failed to log timeline event: %v\n", err)
+	}
+}
+
+// SignalRPC is a client for the JSON-RPC endpoint of a signal-cli daemon.
+type SignalRPC struct {
+	base   string
+	client *http.Client
+	nextID atomic.Int64
+}
+
+// NewSignalRPC returns a client for the daemon at baseURL.
+func NewSignalRPC(baseURL string) *SignalRPC {
+	return &SignalRPC{
+		base:   strings.TrimRight(baseURL, "/"),
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// SignalRPCError is an error returned by signal-cli.
+type SignalRPCError struct {
+	Method  string
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *SignalRPCError) Error() string {
+	return fmt.Sprintf("signal-cli %s: %s (code %d)", e.Method, e.Message, e.Code)
+}
+
+// Permanent reports whether retrying cannot help: invalid requests and
+// unknown recipients or groups.
+func (e *SignalRPCError) Permanent() bool {
+	return e.Code == -32600 || e.Code == -32601 || e.Code == -32602 || e.Code == -1
+}
+
+// Check verifies that the daemon is reachable.
+func (r *SignalRPC) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.base+"/api/v1/check", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("check: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Call invokes method and decodes its result into out.
+func (r *SignalRPC) Call(ctx context.Context, method string, params any, out any) error {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      r.nextID.Add(1),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.base+"/api/v1/rpc", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	var res struct {
+		Result json.RawMessage `json:"result"`
+		Error  *SignalRPCError `json:"error"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("%s: status %d: %w", method, resp.StatusCode, err)
+	}
+	if res.Error != nil {
+		res.Error.Method = method
+		return res.Error
+	}
+	if out != nil && len(res.Result) > 0 {
+		return json.Unmarshal(res.Result, out)
+	}
+	return nil
+}
+
+// LinkSignal links signal-cli to an existing Signal account as a secondary
+// device. onURI is called with the sgnl:// URI to show as a QR code; the
+// call blocks until the phone scans it and returns the linked number.
+func LinkSignal(ctx context.Context, baseURL, deviceName string, onURI func(uri string)) (string, error) {
+	r := NewSignalRPC(baseURL)
+	// finishLink waits for the scan, which may take a while.
+	r.client.Timeout = 0
+	var start struct {
+		DeviceLinkURI string `json:"deviceLinkUri"`
+	}
+	if err := r.Call(ctx, "startLink", map[string]any{}, &start); err != nil {
+		return "", err
+	}
+	onURI(start.DeviceLinkURI)
+	var done struct {
+		Number string `json:"number"`
+	}
+	if err := r.Call(ctx, "finishLink", map[string]any{"deviceLinkUri": start.DeviceLinkURI, "deviceName": deviceName}, &done); err != nil {
+		return "", err
+	}
+	return done.Number, nil
+}
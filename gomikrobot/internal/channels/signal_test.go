@@ -0,0 +1,146 @@
+package channels
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+)
+
+type signalCall struct {
+	Method string         `json:"method"`
+	Params map[string]any `json:"params"`
+}
+
+// fakeSignalCLI answers JSON-RPC calls with results[method] and records
+// the calls it received.
+func fakeSignalCLI(t *testing.T, results map[string]any) (*httptest.Server, func() []signalCall) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		calls []signalCall
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req signalCall
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		mu.Lock()
+		calls = append(calls, req)
+		mu.Unlock()
+		res, ok := results[req.Method]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": -1, "message": "Invalid recipient"}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"result": res})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []signalCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]signalCall(nil), calls...)
+	}
+}
+
+func TestSignalSend(t *testing.T) {
+	srv, calls := fakeSignalCLI(t, map[string]any{"send": map[string]any{"timestamp": 1}})
+	c := NewSignalChannel(config.SignalConfig{URL: srv.URL, Account: "+4900"}, nil, nil, "")
+
+	if err := c.Send(context.Background(), &bus.OutboundMessage{ChatID: "+4911", Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(context.Background(), &bus.OutboundMessage{ChatID: "group.abc=", Content: "all"}); err != nil {
+		t.Fatal(err)
+	}
+	got := calls()
+	if len(got) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(got))
+	}
+	if p := got[0].Params; p["account"] != "+4900" || p["message"] != "hi" || p["recipient"].([]any)[0] != "+4911" {
+		t.Errorf("unexpected direct send params: %v", p)
+	}
+	if p := got[1].Params; p["groupId"] != "abc=" || p["recipient"] != nil {
+		t.Errorf("unexpected group send params: %v", p)
+	}
+}
+
+func TestSignalSendRejectedIsPermanent(t *testing.T) {
+	srv, _ := fakeSignalCLI(t, nil)
+	c := NewSignalChannel(config.SignalConfig{URL: srv.URL, Account: "+4900"}, nil, nil, "")
+
+	err := c.Send(context.Background(), &bus.OutboundMessage{ChatID: "+4911", Content: "hi"})
+	var rpcErr *SignalRPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Method != "send" || !bus.IsPermanent(err) {
+		t.Errorf("expected a permanent send error, got %v", err)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	stream := ": keepalive\n\nevent: receive\ndata: {\"a\":1}\n\ndata: line1\ndata: line2\n\n"
+	var got []string
+	readSSE(strings.NewReader(stream), func(data []byte) { got = append(got, string(data)) })
+	if len(got) != 2 || got[0] != `{"a":1}` || got[1] != "line1\nline2" {
+		t.Errorf("unexpected events: %q", got)
+	}
+}
+
+func TestSignalHandleEnvelope(t *testing.T) {
+	srv, calls := fakeSignalCLI(t, map[string]any{
+		"getAttachment": map[string]any{"data": base64.StdEncoding.EncodeToString([]byte("jpeg"))},
+	})
+	mb := bus.NewMessageBus()
+	c := NewSignalChannel(config.SignalConfig{
+		URL: srv.URL, Account: "+4900", AllowFrom: []string{"+4911"}, Media: config.DefaultMediaPolicy(),
+	}, mb, nil, t.TempDir())
+
+	var ev struct {
+		Envelope signalEnvelope `json:"envelope"`
+	}
+	raw := `{"envelope":{"source":"+4911","sourceNumber":"+4911","sourceName":"Ann","timestamp":1700000000000,
+		"dataMessage":{"message":"look","groupInfo":{"groupId":"g1="},
+		"attachments":[{"id":"att1","contentType":"image/jpeg","filename":"cat.jpg","size":4}]}}}`
+	if err := json.Unmarshal([]byte(raw), &ev); err != nil {
+		t.Fatal(err)
+	}
+	c.handleEnvelope(context.Background(), ev.Envelope)
+
+	// Strangers and receipts are ignored.
+	c.handleEnvelope(context.Background(), signalEnvelope{SourceNumber: "+4999", Timestamp: 1, DataMessage: ev.Envelope.DataMessage})
+	c.handleEnvelope(context.Background(), signalEnvelope{SourceNumber: "+4911", Timestamp: 2})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := mb.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ChatID != "group.g1=" || msg.SenderID != "+4911" {
+		t.Errorf("unexpected chat %q / sender %q", msg.ChatID, msg.SenderID)
+	}
+	if len(msg.Media) != 1 || !strings.HasPrefix(msg.Content, "look\n[Image: ") {
+		t.Fatalf("unexpected message: %+v", msg)
+	}
+	if data, err := os.ReadFile(msg.Media[0]); err != nil || string(data) != "jpeg" {
+		t.Errorf("attachment not saved: %q, %v", data, err)
+	}
+	if p := calls()[0].Params; p["id"] != "att1" || p["groupId"] != "g1=" {
+		t.Errorf("unexpected getAttachment params: %v", p)
+	}
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	if extra, err := mb.ConsumeInbound(ctx2); err == nil {
+		t.Errorf("expected only one message, got %+v", extra)
+	}
+}
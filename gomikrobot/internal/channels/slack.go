@@ -0,0 +1,546 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tracing"
+)
+
+const slackAPIBase = "https://slack.com/api"
+
+// SlackChannel connects to one or more Slack workspaces via Socket Mode.
+//
+// Chat IDs have the form "<workspace>:<channel>[:<thread_ts>]" so replies
+// go back to the right workspace and thread.
+type SlackChannel struct {
+	BaseChannel
+	config     config.SlackConfig
+	timeline   *timeline.TimelineService
+	mediaDir   string
+	httpClient *http.Client
+	apiBase    string
+
+	mu         sync.Mutex
+	botTokens  map[string]string // workspace name -> bot token
+	botUsers   map[string]string // workspace name -> bot user ID
+	cancel     context.CancelFunc
+	subscribed bool
+}
+
+// NewSlackChannel creates a new Slack channel. Downloaded files are stored
+// under mediaDir/slack.
+func NewSlackChannel(cfg config.SlackConfig, messageBus *bus.MessageBus, tl *timeline.TimelineService, mediaDir string) *SlackChannel {
+	return &SlackChannel{
+		BaseChannel: BaseChannel{Bus: messageBus},
+		config:      cfg,
+		timeline:    tl,
+		mediaDir:    mediaDir,
+		httpClient:  &http.Client{Timeout: 60 * time.Second},
+		apiBase:     slackAPIBase,
+		botTokens:   make(map[string]string),
+		botUsers:    make(map[string]string),
+	}
+}
+
+func (c *SlackChannel) Name() string { return "slack" }
+
+// workspaces returns the configured workspaces; top-level tokens form a
+// workspace named "default".
+func (c *SlackChannel) workspaces() []config.SlackWorkspaceConfig {
+	var out []config.SlackWorkspaceConfig
+	if c.config.AppToken != "" && c.config.BotToken != "" {
+		out = append(out, config.SlackWorkspaceConfig{Name: "default", AppToken: c.config.AppToken, BotToken: c.config.BotToken})
+	}
+	for i, ws := range c.config.Workspaces {
+		if ws.Name == "" {
+			ws.Name = fmt.Sprintf("ws%d", i+1)
+		}
+		out = append(out, ws)
+	}
+	return out
+}
+
+func (c *SlackChannel) Start(ctx context.Context) error {
+	if !c.config.Enabled {
+		return nil
+	}
+	workspaces := c.workspaces()
+	if len(workspaces) == 0 {
+		return fmt.Errorf("slack: no workspace tokens configured")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.mu.Lock()
+	c.cancel = cancel
+	c.mu.Unlock()
+
+	for _, ws := range workspaces {
+		var auth struct {
+			UserID string `json:"user_id"`
+			Team   string `json:"team"`
+		}
+		if err := c.call(ctx, ws.BotToken, "auth.test", nil, &auth); err != nil {
+			cancel()
+			return fmt.Errorf("slack workspace %s: %w", ws.Name, err)
+		}
+		c.mu.Lock()
+		c.botTokens[ws.Name] = ws.BotToken
+		c.botUsers[ws.Name] = auth.UserID
+		c.mu.Unlock()
+		fmt.Printf("Slack: Connected to %s as %s\n", auth.Team, auth.UserID)
+
+		go c.runSocket(ctx, ws)
+	}
+
+	c.mu.Lock()
+	subscribed := c.subscribed
+	c.subscribed = true
+	c.mu.Unlock()
+	if subscribed {
+		return nil
+	}
+	c.Bus.SubscribeSender(c.Name(), func(ctx context.Context, msg *bus.OutboundMessage) error {
+		if !outboundAllowed(c.timeline, msg) {
+			return nil
+		}
+		return c.Send(ctx, msg)
+	})
+	return nil
+}
+
+func (c *SlackChannel) Stop() error {
+	c.mu.Lock()
+	cancel := c.cancel
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+func (c *SlackChannel) Send(ctx context.Context, msg *bus.OutboundMessage) error {
+	ws, channel, thread, err := parseSlackChatID(msg.ChatID)
+	if err != nil {
+		return bus.Permanent(err)
+	}
+	c.mu.Lock()
+	token := c.botTokens[ws]
+	c.mu.Unlock()
+	if token == "" {
+		return fmt.Errorf("slack: unknown workspace %q", ws)
+	}
+
+	if msg.Content != "" {
+		params := map[string]any{
+			"channel": channel,
+			"text":    msg.Content,
+		}
+		if thread != "" {
+			params["thread_ts"] = thread
+		}
+		if err := c.call(ctx, token, "chat.postMessage", params, nil); err != nil {
+			return err
+		}
+	}
+	// Sent parts are removed, so a retry after a failed upload does not
+	// repeat them.
+	msg.Content = ""
+	for len(msg.Media) > 0 {
+		if err := c.uploadFile(ctx, token, channel, thread, msg.Media[0]); err != nil {
+			return err
+		}
+		msg.Media = msg.Media[1:]
+	}
+	return nil
+}
+
+// uploadFile shares the file at path in channel, using Slack's external
+// upload flow: get an upload URL, post the bytes, then complete the upload.
+func (c *SlackChannel) uploadFile(ctx context.Context, token, channel, thread, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return bus.Permanent(fmt.Errorf("attachment: %w", err))
+	}
+	name := filepath.Base(path)
+	var upload struct {
+		UploadURL string `json:"upload_url"`
+		FileID    string `json:"file_id"`
+	}
+	q := url.Values{"filename": {name}, "length": {strconv.Itoa(len(data))}}
+	if err := c.call(ctx, token, "files.getUploadURLExternal?"+q.Encode(), nil, &upload); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, upload.UploadURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", AttachmentType(path))
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload %s: %w", name, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upload %s: status %d", name, resp.StatusCode)
+	}
+
+	files, _ := json.Marshal([]map[string]string{{"id": upload.FileID, "title": name}})
+	q = url.Values{"files": {string(files)}, "channel_id": {channel}}
+	if thread != "" {
+		q.Set("thread_ts", thread)
+	}
+	return c.call(ctx, token, "files.completeUploadExternal?"+q.Encode(), nil, nil)
+}
+
+// runSocket keeps a Socket Mode connection open, reconnecting with backoff.
+func (c *SlackChannel) runSocket(ctx context.Context, ws config.SlackWorkspaceConfig) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		start := time.Now()
+		err := c.serveSocket(ctx, ws)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		fmt.Printf("Slack: connection to %s closed (%v), reconnecting in %s\n", ws.Name, err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+// slackEnvelope is a Socket Mode frame.
+type slackEnvelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload"`
+	Reason     string          `json:"reason"`
+}
+
+func (c *SlackChannel) serveSocket(ctx context.Context, ws config.SlackWorkspaceConfig) error {
+	var open struct {
+		URL string `json:"url"`
+	}
+	if err := c.call(ctx, ws.AppToken, "apps.connections.open", nil, &open); err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.Dial(ctx, open.URL, nil)
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close(websocket.StatusNormalClosure, "")
+	conn.SetReadLimit(4 << 20)
+
+	for {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			return err
+		}
+		var env slackEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			fmt.Printf("⚠️ Slack: bad frame: %v\n", err)
+			continue
+		}
+
+		// Acknowledge first; Slack retries unacknowledged envelopes.
+		if env.EnvelopeID != "" {
+			ack, _ := json.Marshal(map[string]string{"envelope_id": env.EnvelopeID})
+			if err := conn.Write(ctx, websocket.MessageText, ack); err != nil {
+				return err
+			}
+		}
+
+		switch env.Type {
+		case "events_api":
+			go c.handlePayload(context.Background(), ws.Name, env.Payload)
+		case "disconnect":
+			return fmt.Errorf("server requested disconnect: %s", env.Reason)
+		}
+	}
+}
+
+// slackFile is a file shared in a message.
+type slackFile struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Mimetype           string `json:"mimetype"`
+	Size               int64  `json:"size"`
+	URLPrivateDownload string `json:"url_private_download"`
+}
+
+// slackEvent is the subset of a message event we use.
+type slackEvent struct {
+	Type        string      `json:"type"`
+	Subtype     string      `json:"subtype"`
+	User        string      `json:"user"`
+	BotID       string      `json:"bot_id"`
+	Text        string      `json:"text"`
+	Channel     string      `json:"channel"`
+	ChannelType string      `json:"channel_type"`
+	TS          string      `json:"ts"`
+	ThreadTS    string      `json:"thread_ts"`
+	Files       []slackFile `json:"files"`
+}
+
+func (c *SlackChannel) handlePayload(ctx context.Context, workspace string, raw json.RawMessage) {
+	var payload struct {
+		Event slackEvent `json:"event"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		fmt.Printf("⚠️ Slack: bad event payload: %v\n", err)
+		return
+	}
+	c.handleMessage(ctx, workspace, payload.Event)
+}
+
+func (c *SlackChannel) handleMessage(ctx context.Context, workspace string, ev slackEvent) {
+	if ev.Type != "message" || (ev.Subtype != "" && ev.Subtype != "file_share") {
+		return
+	}
+	c.mu.Lock()
+	self := c.botUsers[workspace]
+	c.mu.Unlock()
+	if ev.BotID != "" || ev.User == "" || ev.User == self {
+		return
+	}
+
+	ctx, span := tracing.StartKind(ctx, tracing.KindServer, "slack.receive", "workspace", workspace, "channel_id", ev.Channel, "files", len(ev.Files))
+	defer span.End()
+
+	chatID := c.chatID(workspace, ev)
+	authorized := c.isAllowed(ev.User)
+	if !authorized {
+		fmt.Printf("🚫 Unauthorized Slack sender: %s\n", ev.User)
+	}
+
+	content := ev.Text
+	var mediaPaths []string
+	for _, f := range ev.Files {
+		kind := mediaKind(f.Mimetype)
+		if rej := CheckMedia(c.config.Media, kind, f.Mimetype, f.Size); rej != nil {
+			c.logEvent(ev, "REJECTED", fmt.Sprintf("[Rejected %s] %s", kind, rej.Reply), "", "", authorized)
+			if authorized {
+				reply := rej.Localized(replyLanguage(c.timeline, c.Name(), chatID, ev.User))
+				_ = c.Send(ctx, &bus.OutboundMessage{Channel: c.Name(), ChatID: chatID, Content: reply})
+			}
+			continue
+		}
+		path, err := c.download(ctx, workspace, ev.TS, f)
+		if err != nil {
+			fmt.Printf("❌ Slack file download error: %v\n", err)
+			continue
+		}
+		mediaPaths = append(mediaPaths, path)
+		content += fmt.Sprintf("\n[%s: %s (%s)]", strings.ToUpper(kind[:1])+kind[1:], path, f.Name)
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return
+	}
+
+	label := c.classify(ctx, content)
+	c.logEvent(ev, "TEXT", content, strings.Join(mediaPaths, ","), label, authorized)
+
+	if authorized {
+		ts := time.Now()
+		if sec, err := parseSlackTS(ev.TS); err == nil {
+			ts = sec
+		}
+		c.Bus.PublishInbound(&bus.InboundMessage{
+			Channel:   c.Name(),
+			SenderID:  ev.User,
+			ChatID:    chatID,
+			Content:   content,
+			Media:     mediaPaths,
+			Metadata:  map[string]any{"workspace": workspace, "ts": ev.TS, "event_id": ev.Channel + ":" + ev.TS, "classification": label},
+			Timestamp: ts,
+			Trace:     tracing.Traceparent(ctx),
+		})
+	}
+}
+
+// chatID picks where replies go: existing threads always continue, channel
+// messages start a thread when ReplyInThread is set, DMs reply inline.
+func (c *SlackChannel) chatID(workspace string, ev slackEvent) string {
+	thread := ev.ThreadTS
+	if thread == "" && c.config.ReplyInThread && ev.ChannelType != "im" {
+		thread = ev.TS
+	}
+	id := workspace + ":" + ev.Channel
+	if thread != "" {
+		id += ":" + thread
+	}
+	return id
+}
+
+func parseSlackChatID(chatID string) (workspace, channel, thread string, err error) {
+	parts := strings.SplitN(chatID, ":", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", "", fmt.Errorf("invalid slack chat ID %q", chatID)
+	}
+	if len(parts) == 3 {
+		thread = parts[2]
+	}
+	return parts[0], parts[1], thread, nil
+}
+
+func parseSlackTS(ts string) (time.Time, error) {
+	var sec, usec int64
+	if _, err := fmt.Sscanf(ts, "%d.%d", &sec, &usec); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(sec, usec*1000), nil
+}
+
+func mediaKind(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return MediaImage
+	case strings.HasPrefix(mimeType, "audio/"):
+		return MediaAudio
+	}
+	return MediaDocument
+}
+
+func (c *SlackChannel) isAllowed(user string) bool {
+	if len(c.config.AllowFrom) == 0 {
+		return true
+	}
+	for _, allowed := range c.config.AllowFrom {
+		if allowed == user {
+			return true
+		}
+	}
+	return false
+}
+
+// download saves a shared file under mediaDir/slack.
+func (c *SlackChannel) download(ctx context.Context, workspace, ts string, f slackFile) (string, error) {
+	c.mu.Lock()
+	token := c.botTokens[workspace]
+	c.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.URLPrivateDownload, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download %s: status %d", f.Name, resp.StatusCode)
+	}
+
+	dir := filepath.Join(c.mediaDir, "slack")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := unsafeNameChars.Replace(filepath.Base(f.Name))
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s", strings.ReplaceAll(ts, ".", ""), name))
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return "", err
+	}
+	fmt.Printf("📎 Slack file saved to %s\n", path)
+	return path, nil
+}
+
+var unsafeNameChars = strings.NewReplacer("/", "_", "\\", "_", " ", "_", ":", "_")
+
+func (c *SlackChannel) logEvent(ev slackEvent, evtType, content, media, classification string, authorized bool) {
+	if c.timeline == nil {
+		return
+	}
+	err := c.timeline.AddEvent(&timeline.TimelineEvent{
+		EventID:        fmt.Sprintf("slack:%s:%s", ev.Channel, ev.TS),
+		Timestamp:      time.Now(),
+		SenderID:       ev.User,
+		SenderName:     "Slack User",
+		EventType:      evtType,
+		ContentText:    content,
+		MediaPath:      media,
+		Classification: classification,
+		Authorized:     authorized,
+	})
+	if err != nil {
+		fmt.Printf("⚠️ Failed to log timeline event: %v\n", err)
+	}
+}
+
+// call invokes a Slack Web API method and decodes the response into out.
+func (c *SlackChannel) call(ctx context.Context, token, method string, params any, out any) error {
+	var body io.Reader
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiBase+"/"+method, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if params != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	} else {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("%s: status %d: %w", method, resp.StatusCode, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("%s: %s", method, status.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
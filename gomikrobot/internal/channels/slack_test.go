@@ -0,0 +1,141 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+)
+
+func newTestSlack(t *testing.T, cfg config.SlackConfig, handler http.HandlerFunc) (*SlackChannel, *bus.MessageBus) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	mb := bus.NewMessageBus()
+	c := NewSlackChannel(cfg, mb, nil, t.TempDir())
+	c.apiBase = srv.URL
+	c.botTokens["default"] = "xoxb-test"
+	c.botUsers["default"] = "UBOT"
+	return c, mb
+}
+
+func TestSlackHandleMessageThreads(t *testing.T) {
+	c, mb := newTestSlack(t, config.SlackConfig{ReplyInThread: true}, nil)
+
+	c.handleMessage(context.Background(), "default", slackEvent{
+		Type: "message", User: "U1", Text: "hello", Channel: "C1", ChannelType: "channel", TS: "1700000000.000100",
+	})
+	// Own messages are ignored.
+	c.handleMessage(context.Background(), "default", slackEvent{
+		Type: "message", User: "UBOT", Text: "echo", Channel: "C1", TS: "1700000001.000100",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := mb.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.ChatID != "default:C1:1700000000.000100" {
+		t.Errorf("unexpected chat ID %q", msg.ChatID)
+	}
+	if msg.Content != "hello" {
+		t.Errorf("unexpected content %q", msg.Content)
+	}
+
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+	if extra, err := mb.ConsumeInbound(ctx2); err == nil {
+		t.Errorf("expected bot message to be ignored, got %+v", extra)
+	}
+}
+
+func TestSlackChatIDDirectMessage(t *testing.T) {
+	c := NewSlackChannel(config.SlackConfig{ReplyInThread: true}, nil, nil, "")
+	got := c.chatID("default", slackEvent{Channel: "D1", ChannelType: "im", TS: "1.2"})
+	if got != "default:D1" {
+		t.Errorf("expected DM reply inline, got %q", got)
+	}
+}
+
+func TestSlackSendThreadReply(t *testing.T) {
+	var got map[string]any
+	c, _ := newTestSlack(t, config.SlackConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat.postMessage" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("missing bot token")
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	})
+
+	err := c.Send(context.Background(), &bus.OutboundMessage{ChatID: "default:C1:123.456", Content: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["channel"] != "C1" || got["thread_ts"] != "123.456" || got["text"] != "hi" {
+		t.Errorf("unexpected request body %v", got)
+	}
+}
+
+func TestSlackCallError(t *testing.T) {
+	c, _ := newTestSlack(t, config.SlackConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+	})
+	err := c.Send(context.Background(), &bus.OutboundMessage{ChatID: "default:CX", Content: "hi"})
+	if err == nil || err.Error() != "chat.postMessage: channel_not_found" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestSlackSendUploadsFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chart.png")
+	if err := os.WriteFile(path, []byte("\x89PNG\r\n\x1a\nfake"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var steps []string
+	var uploaded []byte
+	c, _ := newTestSlack(t, config.SlackConfig{}, func(w http.ResponseWriter, r *http.Request) {
+		steps = append(steps, r.URL.Path)
+		switch r.URL.Path {
+		case "/files.getUploadURLExternal":
+			if r.URL.Query().Get("filename") != "chart.png" || r.URL.Query().Get("length") != "12" {
+				t.Errorf("unexpected upload request %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"upload_url":"http://` + r.Host + `/upload","file_id":"F1"}`))
+		case "/upload":
+			uploaded, _ = io.ReadAll(r.Body)
+		case "/files.completeUploadExternal":
+			q := r.URL.Query()
+			if q.Get("channel_id") != "C1" || q.Get("thread_ts") != "123.456" || !strings.Contains(q.Get("files"), `"id":"F1"`) {
+				t.Errorf("unexpected complete request %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		default:
+			_, _ = w.Write([]byte(`{"ok":true}`))
+		}
+	})
+
+	msg := &bus.OutboundMessage{ChatID: "default:C1:123.456", Content: "Here it is", Media: []string{path}}
+	if err := c.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(steps, " ") != "/chat.postMessage /files.getUploadURLExternal /upload /files.completeUploadExternal" {
+		t.Errorf("steps = %v", steps)
+	}
+	if len(uploaded) != 12 {
+		t.Errorf("uploaded %d bytes", len(uploaded))
+	}
+}
@@ -0,0 +1,322 @@
+package channels
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tracing"
+	"github.com/kamir/gomikrobot/internal/transcribe"
+)
+
+// VoiceChannel is an experimental, turn-based voice call over a WebSocket.
+// The caller records one utterance at a time (push to talk) and sends it
+// as a binary frame; it is transcribed and handed to the agent, and every
+// reply comes back as a text frame followed by its speech as a binary
+// frame.
+//
+// Each connection is one call and its chat ID is "call-<id>". Text frames
+// from the server are JSON objects with a type of ready, transcript, reply
+// or error.
+type VoiceChannel struct {
+	BaseChannel
+	config   config.VoiceConfig
+	provider provider.LLMProvider
+	timeline *timeline.TimelineService
+	mediaDir string
+
+	mu          sync.Mutex
+	transcriber *transcribe.Queue
+	calls       map[string]*voiceCall
+	subscribed  bool
+}
+
+// voiceCall is one connected caller. Writes are serialized because
+// replies and transcripts come from different goroutines.
+type voiceCall struct {
+	id   string
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func (vc *voiceCall) write(ctx context.Context, typ websocket.MessageType, data []byte) error {
+	vc.mu.Lock()
+	defer vc.mu.Unlock()
+	return vc.conn.Write(ctx, typ, data)
+}
+
+func (vc *voiceCall) writeJSON(ctx context.Context, v map[string]any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return vc.write(ctx, websocket.MessageText, data)
+}
+
+// NewVoiceChannel creates a voice channel. Recordings are stored under
+// mediaDir/voice; prov synthesizes replies and transcribes when no
+// transcription queue is set.
+func NewVoiceChannel(cfg config.VoiceConfig, messageBus *bus.MessageBus, prov provider.LLMProvider, tl *timeline.TimelineService, mediaDir string) *VoiceChannel {
+	return &VoiceChannel{
+		BaseChannel: BaseChannel{Bus: messageBus},
+		config:      cfg,
+		provider:    prov,
+		timeline:    tl,
+		mediaDir:    mediaDir,
+		calls:       make(map[string]*voiceCall),
+	}
+}
+
+func (c *VoiceChannel) Name() string { return "voice" }
+
+// SetTranscriber routes recordings through q.
+func (c *VoiceChannel) SetTranscriber(q *transcribe.Queue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.transcriber = q
+}
+
+func (c *VoiceChannel) Start(ctx context.Context) error {
+	if !c.config.Enabled {
+		return nil
+	}
+	c.mu.Lock()
+	subscribed := c.subscribed
+	c.subscribed = true
+	c.mu.Unlock()
+	if subscribed {
+		return nil
+	}
+	c.Bus.SubscribeSender(c.Name(), func(ctx context.Context, msg *bus.OutboundMessage) error {
+		// Quiet hours don't apply: a reply held until morning is useless
+		// on a call.
+		if c.timeline != nil && c.timeline.SilentFor(msg.Channel, msg.ChatID) {
+			fmt.Printf("🔇 Silent Mode: suppressed outbound to %s\n", msg.ChatID)
+			return nil
+		}
+		return c.Send(ctx, msg)
+	})
+	return nil
+}
+
+// Stop hangs up all calls.
+func (c *VoiceChannel) Stop() error {
+	c.mu.Lock()
+	calls := c.calls
+	c.calls = make(map[string]*voiceCall)
+	c.mu.Unlock()
+	for _, vc := range calls {
+		vc.conn.Close(websocket.StatusGoingAway, "shutting down")
+	}
+	return nil
+}
+
+// Send speaks msg to the caller. Replies to calls that have hung up are
+// dropped for good.
+func (c *VoiceChannel) Send(ctx context.Context, msg *bus.OutboundMessage) error {
+	c.mu.Lock()
+	vc := c.calls[msg.ChatID]
+	c.mu.Unlock()
+	if vc == nil {
+		return bus.Permanent(fmt.Errorf("voice: call %s has ended", msg.ChatID))
+	}
+	if err := vc.writeJSON(ctx, map[string]any{"type": "reply", "text": msg.Content}); err != nil {
+		return bus.Permanent(err)
+	}
+	speech, err := c.provider.Speak(ctx, &provider.TTSRequest{Text: msg.Content, Voice: c.config.Voice})
+	if err != nil {
+		// The caller already has the text.
+		fmt.Printf("❌ Voice: speech synthesis failed: %v\n", err)
+		return vc.writeJSON(ctx, map[string]any{"type": "error", "error": "speech synthesis failed"})
+	}
+	return vc.write(ctx, websocket.MessageBinary, speech.AudioData)
+}
+
+// Handler accepts calls. The "format" query parameter names the audio
+// container the caller records in (webm, ogg, wav or m4a; default webm).
+// Plain GET requests get {"enabled": true} so the dashboard can offer
+// calls.
+func (c *VoiceChannel) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !c.config.Enabled {
+			http.Error(w, "voice channel is disabled", http.StatusNotFound)
+			return
+		}
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]bool{"enabled": true})
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "webm"
+		}
+		mimeType, ok := voiceFormats[format]
+		if !ok {
+			http.Error(w, "unsupported audio format", http.StatusBadRequest)
+			return
+		}
+
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		if limit := c.config.Media.MaxAudioBytes; limit > 0 {
+			// Oversized recordings end the call with StatusMessageTooBig.
+			conn.SetReadLimit(limit)
+		} else {
+			conn.SetReadLimit(-1)
+		}
+
+		var id [6]byte
+		rand.Read(id[:])
+		vc := &voiceCall{id: "call-" + hex.EncodeToString(id[:]), conn: conn}
+		c.mu.Lock()
+		c.calls[vc.id] = vc
+		c.mu.Unlock()
+		defer func() {
+			c.mu.Lock()
+			delete(c.calls, vc.id)
+			c.mu.Unlock()
+			conn.CloseNow()
+		}()
+
+		ctx := r.Context()
+		fmt.Printf("📞 Voice: call %s connected\n", vc.id)
+		if err := vc.writeJSON(ctx, map[string]any{"type": "ready", "call": vc.id}); err != nil {
+			return
+		}
+		for turn := 1; ; turn++ {
+			typ, data, err := conn.Read(ctx)
+			if err != nil {
+				fmt.Printf("📞 Voice: call %s ended\n", vc.id)
+				return
+			}
+			if typ != websocket.MessageBinary {
+				continue
+			}
+			c.handleTurn(ctx, vc, turn, format, mimeType, data)
+		}
+	}
+}
+
+// voiceFormats maps the accepted recording formats to MIME types.
+var voiceFormats = map[string]string{
+	"webm": "audio/webm",
+	"ogg":  "audio/ogg",
+	"wav":  "audio/wav",
+	"m4a":  "audio/mp4",
+}
+
+// handleTurn stores one utterance and queues it for transcription.
+func (c *VoiceChannel) handleTurn(ctx context.Context, vc *voiceCall, turn int, format, mimeType string, data []byte) {
+	ctx, span := tracing.StartKind(context.WithoutCancel(ctx), tracing.KindServer, "voice.receive", "call", vc.id, "bytes", len(data))
+
+	policy := c.config.Media
+	if rej := CheckMedia(policy, MediaAudio, mimeType, int64(len(data))); rej != nil {
+		span.Fail(rej.Reply)
+		span.End()
+		_ = vc.writeJSON(ctx, map[string]any{"type": "error", "error": rej.Reply})
+		return
+	}
+
+	dir := filepath.Join(c.mediaDir, "voice")
+	path := filepath.Join(dir, fmt.Sprintf("%s-%03d.%s", vc.id, turn, format))
+	err := os.MkdirAll(dir, 0755)
+	if err == nil {
+		err = os.WriteFile(path, data, 0644)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.End()
+		fmt.Printf("❌ Voice: failed to save recording: %v\n", err)
+		_ = vc.writeJSON(ctx, map[string]any{"type": "error", "error": "could not save the recording"})
+		return
+	}
+
+	transcribePath := path
+	if NeedsTranscode(mimeType) && policy.TranscodeAudio {
+		if converted, err := TranscodeAudio(ctx, policy.FFmpegPath, path); err == nil {
+			transcribePath = converted
+		}
+	}
+
+	done := func(text string, err error) {
+		defer span.End()
+		span.RecordError(err)
+		c.deliver(ctx, vc, path, text, err)
+	}
+	c.mu.Lock()
+	queue := c.transcriber
+	c.mu.Unlock()
+	if queue != nil {
+		if err := queue.Submit(transcribePath, done); err == nil {
+			return
+		}
+	}
+	res, err := c.provider.Transcribe(ctx, &provider.AudioRequest{FilePath: transcribePath})
+	if err != nil {
+		done("", err)
+		return
+	}
+	done(res.Text, nil)
+}
+
+// deliver reports the transcript to the caller and passes it to the agent.
+func (c *VoiceChannel) deliver(ctx context.Context, vc *voiceCall, path, text string, err error) {
+	text = strings.TrimSpace(text)
+	if err != nil || text == "" {
+		if err != nil {
+			fmt.Printf("❌ Voice: transcription failed: %v\n", err)
+		}
+		_ = vc.writeJSON(ctx, map[string]any{"type": "error", "error": "could not understand the recording"})
+		return
+	}
+	fmt.Printf("📝 Voice transcript (%s): %s\n", vc.id, text)
+	_ = vc.writeJSON(ctx, map[string]any{"type": "transcript", "text": text})
+
+	c.logEvent(vc, path, text)
+	c.Bus.PublishInbound(&bus.InboundMessage{
+		Channel:   c.Name(),
+		SenderID:  vc.id,
+		ChatID:    vc.id,
+		Content:   text,
+		Media:     []string{path},
+		Metadata:  map[string]any{"event_id": vc.id + ":" + filepath.Base(path)},
+		Timestamp: time.Now(),
+		Trace:     tracing.Traceparent(ctx),
+	})
+}
+
+func (c *VoiceChannel) logEvent(vc *voiceCall, path, text string) {
+	if c.timeline == nil {
+		return
+	}
+	err := c.timeline.AddEvent(&timeline.TimelineEvent{
+		EventID:     "voice:" + vc.id + ":" + filepath.Base(path),
+		Timestamp:   time.Now(),
+		SenderID:    vc.id,
+		SenderName:  "Voice Caller",
+		EventType:   "TEXT",
+		ContentText: "[Audio Transcript]: " + text,
+		MediaPath:   path,
+		// Calls come through the authenticated dashboard.
+		Authorized: true,
+	})
+	if err != nil {
+		fmt.Printf("⚠️ Failed to log timeline event: %v\n", err)
+	}
+}
@@ -0,0 +1,103 @@
+package channels
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+// fakeVoiceProvider transcribes every recording as its file contents and
+// speaks by prefixing the text with "audio:".
+type fakeVoiceProvider struct{}
+
+func (fakeVoiceProvider) Chat(context.Context, *provider.ChatRequest) (*provider.ChatResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (fakeVoiceProvider) Transcribe(_ context.Context, req *provider.AudioRequest) (*provider.AudioResponse, error) {
+	data, err := os.ReadFile(req.FilePath)
+	return &provider.AudioResponse{Text: string(data)}, err
+}
+
+func (fakeVoiceProvider) Speak(_ context.Context, req *provider.TTSRequest) (*provider.TTSResponse, error) {
+	return &provider.TTSResponse{AudioData: []byte("audio:" + req.Text), Format: "opus"}, nil
+}
+
+func (fakeVoiceProvider) DefaultModel() string { return "fake" }
+
+func readVoiceFrame(t *testing.T, ctx context.Context, conn *websocket.Conn) map[string]any {
+	t.Helper()
+	typ, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != websocket.MessageText {
+		t.Fatalf("expected a text frame, got %q", data)
+	}
+	var frame map[string]any
+	if err := json.Unmarshal(data, &frame); err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+func TestVoiceCallTurn(t *testing.T) {
+	mb := bus.NewMessageBus()
+	c := NewVoiceChannel(config.VoiceConfig{Enabled: true, Media: config.DefaultMediaPolicy()}, mb, fakeVoiceProvider{}, nil, t.TempDir())
+	srv := httptest.NewServer(c.Handler())
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"?format=wav", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	ready := readVoiceFrame(t, ctx, conn)
+	call, _ := ready["call"].(string)
+	if ready["type"] != "ready" || !strings.HasPrefix(call, "call-") {
+		t.Fatalf("unexpected greeting: %v", ready)
+	}
+
+	if err := conn.Write(ctx, websocket.MessageBinary, []byte("what time is it")); err != nil {
+		t.Fatal(err)
+	}
+	if f := readVoiceFrame(t, ctx, conn); f["type"] != "transcript" || f["text"] != "what time is it" {
+		t.Errorf("unexpected transcript frame: %v", f)
+	}
+	msg, err := mb.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Channel != "voice" || msg.ChatID != call || msg.Content != "what time is it" || len(msg.Media) != 1 {
+		t.Errorf("unexpected inbound message: %+v", msg)
+	}
+
+	if err := c.Send(ctx, &bus.OutboundMessage{ChatID: call, Content: "noon"}); err != nil {
+		t.Fatal(err)
+	}
+	if f := readVoiceFrame(t, ctx, conn); f["type"] != "reply" || f["text"] != "noon" {
+		t.Errorf("unexpected reply frame: %v", f)
+	}
+	typ, data, err := conn.Read(ctx)
+	if err != nil || typ != websocket.MessageBinary || string(data) != "audio:noon" {
+		t.Errorf("expected speech, got %v %q %v", typ, data, err)
+	}
+
+	err = c.Send(ctx, &bus.OutboundMessage{ChatID: "call-gone", Content: "hello?"})
+	if !bus.IsPermanent(err) {
+		t.Errorf("expected a permanent error for an ended call, got %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/timeline"
+	"github.com/kamir/gomikrobot/internal/tracing"
+)
+
+const (
+	defaultSignatureHeader = "X-Hub-Signature-256"
+	defaultWebhookTemplate = "Webhook \"{{.Name}}\" received the following payload. Summarize what happened and whether action is needed.\n\n{{.Body}}"
+	// maxWebhookPromptBody caps the raw body included in the prompt.
+	maxWebhookPromptBody = 20000
+)
+
+// WebhookChannel turns inbound HTTP webhooks into agent messages. The
+// agent's response is forwarded to the hook's configured chat, if any.
+type WebhookChannel struct {
+	BaseChannel
+	timeline *timeline.TimelineService
+
+	mu         sync.RWMutex
+	hooks      map[string]config.WebhookConfig
+	templates  map[string]*template.Template
+	subscribed bool
+}
+
+// webhookData is the template input for a webhook prompt.
+type webhookData struct {
+	Name    string
+	Body    string
+	JSON    any
+	Headers map[string]string
+}
+
+// NewWebhookChannel creates a webhook channel for the configured hooks.
+func NewWebhookChannel(hooks []config.WebhookConfig, messageBus *bus.MessageBus, tl *timeline.TimelineService) (*WebhookChannel, error) {
+	c := &WebhookChannel{
+		BaseChannel: BaseChannel{Bus: messageBus},
+		timeline:    tl,
+	}
+	if err := c.SetHooks(hooks); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *WebhookChannel) Name() string { return "webhook" }
+
+// SetHooks replaces the configured hooks, compiling their templates.
+func (c *WebhookChannel) SetHooks(hooks []config.WebhookConfig) error {
+	byName := make(map[string]config.WebhookConfig, len(hooks))
+	tmpls := make(map[string]*template.Template, len(hooks))
+	for _, h := range hooks {
+		src := h.Template
+		if src == "" {
+			src = defaultWebhookTemplate
+		}
+		t, err := template.New(h.Name).Option("missingkey=zero").Parse(src)
+		if err != nil {
+			return fmt.Errorf("webhook %s: invalid template: %w", h.Name, err)
+		}
+		byName[h.Name] = h
+		tmpls[h.Name] = t
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hooks = byName
+	c.templates = tmpls
+	return nil
+}
+
+func (c *WebhookChannel) Start(ctx context.Context) error {
+	c.mu.Lock()
+	subscribed := c.subscribed
+	c.subscribed = true
+	c.mu.Unlock()
+	if subscribed {
+		return nil
+	}
+	c.Bus.Subscribe(c.Name(), func(msg *bus.OutboundMessage) {
+		go func() {
+			if err := c.Send(context.Background(), msg); err != nil {
+				fmt.Printf("Error forwarding webhook response: %v\n", err)
+			}
+		}()
+	})
+	return nil
+}
+
+func (c *WebhookChannel) Stop() error { return nil }
+
+// Send forwards the agent's response for a hook to its configured chat.
+func (c *WebhookChannel) Send(ctx context.Context, msg *bus.OutboundMessage) error {
+	c.mu.RLock()
+	hook, ok := c.hooks[msg.ChatID]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown webhook %q", msg.ChatID)
+	}
+	if hook.ForwardChannel == "" || hook.ForwardChatID == "" {
+		fmt.Printf("🪝 Webhook %s response (not forwarded): %s\n", hook.Name, shorten(msg.Content, 200))
+		return nil
+	}
+	c.Bus.PublishOutbound(&bus.OutboundMessage{
+		Channel: hook.ForwardChannel,
+		ChatID:  hook.ForwardChatID,
+		Content: msg.Content,
+	})
+	return nil
+}
+
+// ServeHTTP handles POST /api/v1/hooks/{name}.
+func (c *WebhookChannel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.PathValue("name")
+	ctx, span := tracing.StartKind(tracing.Extract(r.Context(), r.Header), tracing.KindServer, "webhook.receive", "hook", name)
+	defer span.End()
+
+	c.mu.RLock()
+	hook, ok := c.hooks[name]
+	tmpl := c.templates[name]
+	c.mu.RUnlock()
+	if !ok {
+		http.Error(w, "unknown webhook", http.StatusNotFound)
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if hook.Secret != "" {
+		header := hook.SignatureHeader
+		if header == "" {
+			header = defaultSignatureHeader
+		}
+		if !validSignature(hook.Secret, r.Header.Get(header), body) {
+			fmt.Printf("🚫 Webhook %s: invalid signature\n", name)
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	data := webhookData{
+		Name:    name,
+		Body:    string(body),
+		Headers: make(map[string]string, len(r.Header)),
+	}
+	if len(data.Body) > maxWebhookPromptBody {
+		data.Body = data.Body[:maxWebhookPromptBody] + "\n[truncated]"
+	}
+	_ = json.Unmarshal(body, &data.JSON)
+	for k := range r.Header {
+		data.Headers[k] = r.Header.Get(k)
+	}
+
+	var prompt bytes.Buffer
+	if err := tmpl.Execute(&prompt, data); err != nil {
+		fmt.Printf("❌ Webhook %s: template error: %v\n", name, err)
+		http.Error(w, "template error", http.StatusInternalServerError)
+		return
+	}
+
+	eventID := fmt.Sprintf("hook:%s:%d", name, time.Now().UnixNano())
+	c.logEvent(eventID, name, prompt.String())
+
+	meta := map[string]any{"event_id": eventID}
+	if hook.CacheTTL > 0 {
+		meta["cache_ttl"] = hook.CacheTTL.String()
+	}
+	c.Bus.PublishInbound(&bus.InboundMessage{
+		Channel:  c.Name(),
+		SenderID: name,
+		ChatID:   name,
+		Content:  prompt.String(),
+		Metadata: meta,
+		Trace:    tracing.Traceparent(ctx),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(w).Encode(map[string]string{"status": "accepted", "event_id": eventID})
+}
+
+// validSignature checks a hex HMAC-SHA256 of body, with or without a
+// "sha256=" prefix.
+func validSignature(secret, header string, body []byte) bool {
+	sig, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(header), "sha256="))
+	if err != nil || len(sig) == 0 {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+func (c *WebhookChannel) logEvent(eventID, name, content string) {
+	if c.timeline == nil {
+		return
+	}
+	err := c.timeline.AddEvent(&timeline.TimelineEvent{
+		EventID:     eventID,
+		Timestamp:   time.Now(),
+		SenderID:    name,
+		SenderName:  "Webhook " + name,
+		EventType:   "WEBHOOK",
+		ContentText: content,
+		Authorized:  true,
+	})
+	if err != nil {
+		fmt.Printf("⚠️ Failed to log timeline event: %v\n", err)
+	}
+}
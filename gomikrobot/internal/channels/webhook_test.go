@@ -0,0 +1,109 @@
+package channels
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+)
+
+func newTestWebhook(t *testing.T, hooks ...config.WebhookConfig) (*httptest.Server, *bus.MessageBus, *WebhookChannel) {
+	t.Helper()
+	mb := bus.NewMessageBus()
+	c, err := NewWebhookChannel(hooks, mb, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/api/v1/hooks/{name}", c)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, mb, c
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookRendersTemplate(t *testing.T) {
+	srv, mb, _ := newTestWebhook(t, config.WebhookConfig{
+		Name:     "github",
+		Secret:   "s3cret",
+		Template: "{{.JSON.action}} on {{.JSON.repository.name}}",
+	})
+
+	body := `{"action":"opened","repository":{"name":"gomikrobot"}}`
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/hooks/github", strings.NewReader(body))
+	req.Header.Set("X-Hub-Signature-256", sign("s3cret", body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	msg, err := mb.ConsumeInbound(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content != "opened on gomikrobot" || msg.ChatID != "github" {
+		t.Errorf("unexpected inbound message %+v", msg)
+	}
+}
+
+func TestWebhookRejectsBadSignature(t *testing.T) {
+	srv, _, _ := newTestWebhook(t, config.WebhookConfig{Name: "github", Secret: "s3cret"})
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/api/v1/hooks/github", strings.NewReader(`{}`))
+	req.Header.Set("X-Hub-Signature-256", sign("wrong", `{}`))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", resp.StatusCode)
+	}
+
+	resp, _ = http.Post(srv.URL+"/api/v1/hooks/unknown", "application/json", strings.NewReader(`{}`))
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown hook, got %d", resp.StatusCode)
+	}
+}
+
+func TestWebhookForwardsResponse(t *testing.T) {
+	_, mb, c := newTestWebhook(t, config.WebhookConfig{Name: "grafana", ForwardChannel: "whatsapp", ForwardChatID: "123@s.whatsapp.net"})
+
+	got := make(chan *bus.OutboundMessage, 1)
+	mb.Subscribe("whatsapp", func(m *bus.OutboundMessage) { got <- m })
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go mb.DispatchOutbound(ctx)
+
+	if err := c.Send(context.Background(), &bus.OutboundMessage{Channel: "webhook", ChatID: "grafana", Content: "CPU alert resolved"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case m := <-got:
+		if m.ChatID != "123@s.whatsapp.net" || m.Content != "CPU alert resolved" {
+			t.Errorf("unexpected forwarded message %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("response was not forwarded")
+	}
+}
@@ -2,7 +2,6 @@ package channels
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,9 +11,11 @@ import (
 
 	"github.com/kamir/gomikrobot/internal/bus"
 	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/i18n"
 	"github.com/kamir/gomikrobot/internal/provider"
 	"github.com/kamir/gomikrobot/internal/timeline"
-	"github.com/skip2/go-qrcode"
+	"github.com/kamir/gomikrobot/internal/tracing"
+	"github.com/kamir/gomikrobot/internal/transcribe"
 
 	_ "modernc.org/sqlite"
 
@@ -35,7 +36,14 @@ type WhatsAppChannel struct {
 	container *sqlstore.Container
 	provider  provider.LLMProvider
 	timeline  *timeline.TimelineService
-	mu        sync.Mutex
+	// transcriber handles voice notes off the event goroutine; when nil the
+	// provider transcribes inline.
+	transcriber *transcribe.Queue
+	mu          sync.Mutex
+	// subscribed guards against duplicate outbound subscriptions when the
+	// channel is restarted by a config reload.
+	subscribed bool
+	pairing    PairingState
 }
 
 // NewWhatsAppChannel creates a new WhatsApp channel.
@@ -50,125 +58,300 @@ func NewWhatsAppChannel(cfg config.WhatsAppConfig, messageBus *bus.MessageBus, p
 
 func (c *WhatsAppChannel) Name() string { return "whatsapp" }
 
-func (c *WhatsAppChannel) Start(ctx context.Context) error {
-	if !c.config.Enabled {
-		return nil
-	}
+// SetTranscriber routes voice notes through q.
+func (c *WhatsAppChannel) SetTranscriber(q *transcribe.Queue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.transcriber = q
+}
 
-	// Setup logging
-	dbLog := waLog.Stdout("Database", "WARN", true)
-	clientLog := waLog.Stdout("Client", "INFO", true)
+// SetConfig applies a reloaded configuration. AllowFrom, group, and presence
+// changes take effect immediately; toggling Enabled is handled by the caller
+// via Start/Stop.
+func (c *WhatsAppChannel) SetConfig(cfg config.WhatsAppConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config = cfg
+}
 
-	// Initialize database
-	home, _ := os.UserHomeDir()
-	dbPath := filepath.Join(home, ".gomikrobot", "whatsapp.db")
+// Running reports whether the client is connected or connecting.
+func (c *WhatsAppChannel) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client != nil
+}
 
-	os.MkdirAll(filepath.Dir(dbPath), 0755)
+// Connected reports whether the client is connected to WhatsApp.
+func (c *WhatsAppChannel) Connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.client != nil && c.client.IsConnected()
+}
 
-	// sqlstore.New(ctx, driver, url, log)
-	container, err := sqlstore.New(ctx, "sqlite", "file:"+dbPath+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", dbLog)
-	if err != nil {
-		return fmt.Errorf("failed to init whatsapp db: %w", err)
+func (c *WhatsAppChannel) Start(ctx context.Context) error {
+	c.mu.Lock()
+	enabled, sessionPath := c.config.Enabled, c.config.SessionPath
+	running := c.client != nil
+	c.mu.Unlock()
+	if !enabled || running {
+		return nil
 	}
-	c.container = container
 
-	// Get first device
-	deviceStore, err := container.GetFirstDevice(ctx)
+	container, deviceStore, err := openWhatsAppStore(ctx, sessionPath)
 	if err != nil {
-		return fmt.Errorf("failed to get device: %w", err)
+		return err
 	}
-
-	// Create client
-	c.client = whatsmeow.NewClient(deviceStore, clientLog)
-	c.client.AddEventHandler(c.eventHandler)
-
-	// Login if needed
-	if c.client.Store.ID == nil {
-		// No session, need to pair
-		qrChan, _ := c.client.GetQRChannel(context.Background())
-		err = c.client.Connect()
-		if err != nil {
+	client := whatsmeow.NewClient(deviceStore, waLog.Stdout("Client", "INFO", true))
+	client.AddEventHandler(c.eventHandler)
+	c.mu.Lock()
+	c.client, c.container = client, container
+	c.mu.Unlock()
+
+	// Without a session, pair in the background so the gateway keeps
+	// starting; the QR code is shown in the log and on the dashboard.
+	if client.Store.ID == nil {
+		qrChan, _ := client.GetQRChannel(context.Background())
+		c.setPairing(PairingState{Status: PairingWaiting})
+		if err := client.Connect(); err != nil {
+			c.Stop()
 			return fmt.Errorf("failed to connect: %w", err)
 		}
-
-		fmt.Println("WhatsApp: Scan this QR code to login:")
-		for evt := range qrChan {
-			if evt.Event == "code" {
-				// 1. Save to file
-				home, _ := os.UserHomeDir()
-				qrPath := filepath.Join(home, ".gomikrobot", "whatsapp-qr.png")
-				err := qrcode.WriteFile(evt.Code, qrcode.Medium, 512, qrPath)
-				if err == nil {
-					fmt.Printf("\n🖼️  WhatsApp Login QR Code saved to: %s\n", qrPath)
-					fmt.Println("Please open this file on your computer and scan it with your phone.")
-				}
-			} else {
-				fmt.Println("WhatsApp: Login event:", evt.Event)
-			}
-		}
+		go c.watchPairing(client, qrChan)
 	} else {
-		err = c.client.Connect()
-		if err != nil {
+		if err := client.Connect(); err != nil {
+			c.Stop()
 			return fmt.Errorf("failed to connect: %w", err)
 		}
+		c.setPairing(PairingState{Status: PairingPaired, JID: client.Store.ID.String()})
 		fmt.Println("WhatsApp: Connected")
 	}
 
 	// Subscribe to outbound messages
-	c.Bus.Subscribe(c.Name(), func(msg *bus.OutboundMessage) {
-		go func() {
-			// Check silent mode — never send if enabled
-			if c.timeline != nil && c.timeline.IsSilentMode() {
-				fmt.Printf("🔇 Silent Mode: suppressed outbound to %s\n", msg.ChatID)
-				return
-			}
-			sendCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			defer cancel()
-			if err := c.Send(sendCtx, msg); err != nil {
-				fmt.Printf("Error sending whatsapp message: %v\n", err)
-			}
-		}()
+	c.mu.Lock()
+	subscribed := c.subscribed
+	c.subscribed = true
+	c.mu.Unlock()
+	if subscribed {
+		return nil
+	}
+	c.Bus.SubscribeSender(c.Name(), func(ctx context.Context, msg *bus.OutboundMessage) error {
+		// Never send in silent mode; hold messages during quiet hours
+		if !outboundAllowed(c.timeline, msg) {
+			return nil
+		}
+		return c.Send(ctx, msg)
 	})
 
 	return nil
 }
 
 func (c *WhatsAppChannel) Stop() error {
-	if c.client != nil {
-		c.client.Disconnect()
+	c.mu.Lock()
+	client, container := c.client, c.container
+	c.client, c.container = nil, nil
+	c.mu.Unlock()
+
+	if client != nil {
+		client.Disconnect()
 	}
-	if c.container != nil {
-		c.container.Close()
+	if container != nil {
+		container.Close()
 	}
 	return nil
 }
 
 func (c *WhatsAppChannel) Send(ctx context.Context, msg *bus.OutboundMessage) error {
-	if c.client == nil {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
 		return fmt.Errorf("client not initialized")
 	}
 
 	jid, err := types.ParseJID(msg.ChatID)
 	if err != nil {
-		return fmt.Errorf("invalid JID: %w", err)
+		return bus.Permanent(fmt.Errorf("invalid JID: %w", err))
 	}
 
-	// Use Protobuf message
-	waMsg := &waE2E.Message{
-		Conversation: proto.String(msg.Content),
+	if msg.Content != "" {
+		// Use Protobuf message
+		waMsg := &waE2E.Message{
+			Conversation: proto.String(msg.Content),
+		}
+
+		resp, err := client.SendMessage(ctx, jid, waMsg)
+		if err != nil {
+			return err
+		}
+		c.logReply(resp.ID, jid.User, msg.Content)
 	}
+	// Sent parts are removed, so a retry after a failed upload does not
+	// repeat them.
+	msg.Content = ""
+	for len(msg.Media) > 0 {
+		if err := c.sendAttachment(ctx, client, jid, msg.Media[0]); err != nil {
+			return err
+		}
+		msg.Media = msg.Media[1:]
+	}
+	return nil
+}
 
-	_, err = c.client.SendMessage(ctx, jid, waMsg)
+// waInlineMedia is the largest image, video, or voice file WhatsApp shows
+// inline; larger ones are sent as documents.
+const waInlineMedia = 16 << 20
 
+// sendAttachment uploads the file at path and sends it as an image, video,
+// audio, or document message by its type.
+func (c *WhatsAppChannel) sendAttachment(ctx context.Context, client *whatsmeow.Client, jid types.JID, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return bus.Permanent(fmt.Errorf("attachment: %w", err))
+	}
+	mimeType := AttachmentType(path)
+	kind := whatsmeow.MediaDocument
+	if len(data) <= waInlineMedia {
+		switch mimeType {
+		case "image/jpeg", "image/png":
+			kind = whatsmeow.MediaImage
+		case "video/mp4", "video/3gpp":
+			kind = whatsmeow.MediaVideo
+		case "audio/ogg", "audio/mpeg", "audio/mp4", "audio/aac", "audio/amr":
+			kind = whatsmeow.MediaAudio
+		}
+	}
+	up, err := client.Upload(ctx, data, kind)
+	if err != nil {
+		return fmt.Errorf("upload %s: %w", filepath.Base(path), err)
+	}
+
+	var waMsg *waE2E.Message
+	switch kind {
+	case whatsmeow.MediaImage:
+		waMsg = &waE2E.Message{ImageMessage: &waE2E.ImageMessage{
+			URL: proto.String(up.URL), DirectPath: proto.String(up.DirectPath), MediaKey: up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256, FileSHA256: up.FileSHA256, FileLength: proto.Uint64(up.FileLength),
+			Mimetype: proto.String(mimeType),
+		}}
+	case whatsmeow.MediaVideo:
+		waMsg = &waE2E.Message{VideoMessage: &waE2E.VideoMessage{
+			URL: proto.String(up.URL), DirectPath: proto.String(up.DirectPath), MediaKey: up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256, FileSHA256: up.FileSHA256, FileLength: proto.Uint64(up.FileLength),
+			Mimetype: proto.String(mimeType),
+		}}
+	case whatsmeow.MediaAudio:
+		waMsg = &waE2E.Message{AudioMessage: &waE2E.AudioMessage{
+			URL: proto.String(up.URL), DirectPath: proto.String(up.DirectPath), MediaKey: up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256, FileSHA256: up.FileSHA256, FileLength: proto.Uint64(up.FileLength),
+			Mimetype: proto.String(mimeType),
+		}}
+	default:
+		if strings.HasPrefix(mimeType, "text/") {
+			// Code and other text is sent as plain text so every phone can
+			// open it.
+			mimeType = "text/plain"
+		}
+		waMsg = &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{
+			URL: proto.String(up.URL), DirectPath: proto.String(up.DirectPath), MediaKey: up.MediaKey,
+			FileEncSHA256: up.FileEncSHA256, FileSHA256: up.FileSHA256, FileLength: proto.Uint64(up.FileLength),
+			Mimetype: proto.String(mimeType), FileName: proto.String(filepath.Base(path)),
+		}}
+	}
+	_, err = client.SendMessage(ctx, jid, waMsg)
 	return err
 }
 
+// logReply records a sent reply so reactions to it can be linked back.
+func (c *WhatsAppChannel) logReply(id, recipient, content string) {
+	if c.timeline == nil {
+		return
+	}
+	err := c.timeline.AddEvent(&timeline.TimelineEvent{
+		EventID:     id,
+		Timestamp:   time.Now(),
+		SenderID:    recipient,
+		SenderName:  "GoMikroBot",
+		EventType:   "REPLY",
+		ContentText: content,
+		Authorized:  true,
+	})
+	if err != nil {
+		fmt.Printf("⚠️ Failed to log reply: %v\n", err)
+	}
+}
+
+// silentFor reports whether silent mode applies to chatID.
+func (c *WhatsAppChannel) silentFor(chatID string) bool {
+	return c.timeline != nil && c.timeline.SilentFor(c.Name(), chatID)
+}
+
+// SendTyping shows or clears the "typing…" indicator in a chat. Nothing is
+// shown in silent mode or when typing indicators are disabled.
+func (c *WhatsAppChannel) SendTyping(ctx context.Context, chatID string, typing bool) error {
+	c.mu.Lock()
+	client, enabled := c.client, c.config.TypingIndicator
+	c.mu.Unlock()
+	if client == nil || !enabled || c.silentFor(chatID) {
+		return nil
+	}
+	jid, err := types.ParseJID(chatID)
+	if err != nil {
+		return fmt.Errorf("invalid JID: %w", err)
+	}
+	state := types.ChatPresencePaused
+	if typing {
+		state = types.ChatPresenceComposing
+	}
+	return client.SendChatPresence(ctx, jid, state, types.ChatPresenceMediaText)
+}
+
+// MarkRead sends read receipts (blue ticks) for inbound messages when
+// enabled and not in silent mode.
+func (c *WhatsAppChannel) MarkRead(ctx context.Context, chatID string, messageIDs []string, sender string) error {
+	c.mu.Lock()
+	client, enabled := c.client, c.config.ReadReceipts
+	c.mu.Unlock()
+	if client == nil || !enabled || len(messageIDs) == 0 || c.silentFor(chatID) {
+		return nil
+	}
+	chat, err := types.ParseJID(chatID)
+	if err != nil {
+		return fmt.Errorf("invalid JID: %w", err)
+	}
+	var senderJID types.JID
+	if sender != "" {
+		if senderJID, err = types.ParseJID(sender); err != nil {
+			return fmt.Errorf("invalid sender JID: %w", err)
+		}
+	}
+	ids := make([]types.MessageID, len(messageIDs))
+	for i, id := range messageIDs {
+		ids[i] = types.MessageID(id)
+	}
+	return client.MarkRead(ctx, ids, time.Now(), chat, senderJID)
+}
+
 func (c *WhatsAppChannel) eventHandler(evt interface{}) {
 	// SUPER DEBUG: Log every event type
 	// fmt.Printf("🔔 WhatsApp Event: %T\n", evt)
 
 	switch v := evt.(type) {
+	case *events.LoggedOut:
+		c.setPairing(PairingState{Status: PairingLoggedOut, Error: v.Reason.String()})
+		fmt.Printf("⚠️ WhatsApp: Logged out by the phone or server (%s). Restart the channel to pair again.\n", v.Reason)
 	case *events.Message:
+		if r := v.Message.GetReactionMessage(); r != nil {
+			c.handleReaction(v, r)
+			return
+		}
+		// Ignore group chatter before downloading any media.
+		if v.Info.IsGroup && !c.groupActive(v) {
+			return
+		}
+		ctx, span := tracing.StartKind(context.Background(), tracing.KindServer, "whatsapp.receive",
+			"chat_id", v.Info.Chat.String(), "message_id", v.Info.ID, "delay_ms", time.Since(v.Info.Timestamp))
+		defer span.End()
+
 		// Improved content extraction
 		content := ""
 		mediaPath := "" // Declare outside scope
@@ -180,7 +363,11 @@ func (c *WhatsAppChannel) eventHandler(evt interface{}) {
 		} else if v.Message.GetImageMessage() != nil {
 			content = "[Image Message]"
 			img := v.Message.GetImageMessage()
-			data, err := c.client.Download(context.Background(), img)
+			if rej := CheckMedia(c.mediaPolicy(), MediaImage, img.GetMimetype(), int64(img.GetFileLength())); rej != nil {
+				c.rejectMedia(v, rej)
+				return
+			}
+			data, err := c.client.Download(ctx, img)
 			if err == nil {
 				ext := "jpg"
 				if strings.Contains(img.GetMimetype(), "png") {
@@ -203,7 +390,12 @@ func (c *WhatsAppChannel) eventHandler(evt interface{}) {
 		} else if v.Message.GetAudioMessage() != nil {
 			content = "[Audio Message]"
 			audio := v.Message.GetAudioMessage()
-			data, err := c.client.Download(context.Background(), audio)
+			policy := c.mediaPolicy()
+			if rej := CheckMedia(policy, MediaAudio, audio.GetMimetype(), int64(audio.GetFileLength())); rej != nil {
+				c.rejectMedia(v, rej)
+				return
+			}
+			data, err := c.client.Download(ctx, audio)
 			if err == nil {
 				ext := "ogg"
 				if strings.Contains(audio.GetMimetype(), "mp4") {
@@ -211,23 +403,58 @@ func (c *WhatsAppChannel) eventHandler(evt interface{}) {
 				}
 				fileName := fmt.Sprintf("%s.%s", v.Info.ID, ext)
 				home, _ := os.UserHomeDir()
-				filePath := filepath.Join(home, ".gomikrobot", "workspace", "media", "audio", fileName)
+				dirPath := filepath.Join(home, ".gomikrobot", "workspace", "media", "audio")
+				os.MkdirAll(dirPath, 0755)
+				filePath := filepath.Join(dirPath, fileName)
 				os.WriteFile(filePath, data, 0644)
 
 				mediaPath = filePath // Capture it
 
 				fmt.Printf("🔊 Audio saved to %s\n", filePath)
 
-				// Transcribe
-				transcript, err := c.provider.Transcribe(context.Background(), &provider.AudioRequest{
-					FilePath: filePath,
-				})
-				if err == nil {
-					fmt.Printf("📝 Transcript: %s\n", transcript.Text)
-					content = "[Audio Transcript]: " + transcript.Text
-					// Note: Transcript echo removed - no automatic response
+				// Convert formats the transcriber cannot read
+				transcribePath := filePath
+				if NeedsTranscode(audio.GetMimetype()) {
+					if !policy.TranscodeAudio {
+						c.rejectMedia(v, rejection(MediaAudio, i18n.MsgAudioUnsupported, audio.GetMimetype()))
+						return
+					}
+					converted, err := TranscodeAudio(ctx, policy.FFmpegPath, filePath)
+					if err != nil {
+						fmt.Printf("❌ Transcode error: %v\n", err)
+						c.rejectMedia(v, rejection(MediaAudio, i18n.MsgAudioConvert))
+						return
+					}
+					transcribePath = converted
+				}
+
+				c.mu.Lock()
+				queue := c.transcriber
+				c.mu.Unlock()
+				if queue != nil {
+					audioPath := filePath
+					qctx, qspan := tracing.Start(ctx, "whatsapp.transcribe", "queue", queue.Name())
+					err := queue.Submit(transcribePath, func(text string, err error) {
+						qspan.RecordError(err)
+						c.deliver(qctx, v, audioContent(text, err), audioPath)
+						qspan.End()
+					})
+					if err == nil {
+						fmt.Printf("⏳ Audio queued for transcription (%s)\n", queue.Name())
+						return
+					}
+					qspan.RecordError(err)
+					qspan.End()
+					fmt.Printf("❌ Transcription queue: %v\n", err)
 				} else {
-					fmt.Printf("❌ Transcription error: %v\n", err)
+					transcript, err := c.provider.Transcribe(ctx, &provider.AudioRequest{
+						FilePath: transcribePath,
+					})
+					var text string
+					if err == nil {
+						text = transcript.Text
+					}
+					content = audioContent(text, err)
 				}
 			} else {
 				fmt.Printf("❌ Download error: %v\n", err)
@@ -239,8 +466,12 @@ func (c *WhatsAppChannel) eventHandler(evt interface{}) {
 				docTitle = doc.GetFileName()
 			}
 			content = fmt.Sprintf("[Document: %s]", docTitle)
+			if rej := CheckMedia(c.mediaPolicy(), MediaDocument, doc.GetMimetype(), int64(doc.GetFileLength())); rej != nil {
+				c.rejectMedia(v, rej)
+				return
+			}
 
-			data, err := c.client.Download(context.Background(), doc)
+			data, err := c.client.Download(ctx, doc)
 			if err == nil {
 				// Determine extension from mimetype or filename
 				ext := "bin"
@@ -271,45 +502,135 @@ func (c *WhatsAppChannel) eventHandler(evt interface{}) {
 			fmt.Printf("🔍 Unknown message structure, raw: %s\n", content)
 		}
 
-		fmt.Printf("📩 Message Event from %s (IsFromMe: %v)\n", v.Info.Sender, v.Info.IsFromMe)
-		fmt.Printf("📝 Content: %s\n", content)
-
-		// For testing: allow messages from self (but we should normally block this to avoid loops)
-		// If you want to disable self-chat again later, uncomment the block below.
-		/*
-			if v.Info.IsFromMe {
-				return
-			}
-		*/
+		c.deliver(ctx, v, content, mediaPath)
+	}
+}
 
-		sender := v.Info.Sender.User
-		isAuthorized := c.isAllowed(sender)
+// deliver logs an inbound message and publishes it for authorized senders.
+func (c *WhatsAppChannel) deliver(ctx context.Context, v *events.Message, content, mediaPath string) {
+	fmt.Printf("📩 Message Event from %s (IsFromMe: %v)\n", v.Info.Sender, v.Info.IsFromMe)
+	fmt.Printf("📝 Content: %s\n", content)
 
-		if !isAuthorized {
-			fmt.Printf("🚫 Unauthorized sender: %s\n", sender)
-			// Continue to process and log, but don't respond or publish to bus
-		}
-
-		if content == "" {
+	// For testing: allow messages from self (but we should normally block this to avoid loops)
+	// If you want to disable self-chat again later, uncomment the block below.
+	/*
+		if v.Info.IsFromMe {
 			return
 		}
+	*/
+
+	sender := v.Info.Sender.User
+	isAuthorized := c.isAllowed(sender)
+
+	if !isAuthorized {
+		fmt.Printf("🚫 Unauthorized sender: %s\n", sender)
+		// Continue to process and log, but don't respond or publish to bus
+	}
+
+	if content == "" {
+		return
+	}
+
+	// Classify before logging so routing can act on the label
+	category := c.classify(ctx, content)
 
-		// Classify intent (for logging purposes only - no automatic responses)
-		category, _ := c.classifyMessage(ctx, content)
-
-		// Log Inbound Event (with authorization status)
-		c.logEvent(v.Info.ID, sender, "TEXT", content, mediaPath, category, isAuthorized)
-
-		// Publish to bus only if authorized
-		if isAuthorized {
-			c.Bus.PublishInbound(&bus.InboundMessage{
-				Channel:   c.Name(),
-				SenderID:  sender,
-				ChatID:    v.Info.Chat.String(),
-				Content:   content,
-				Timestamp: v.Info.Timestamp,
-			})
+	// Log Inbound Event (with authorization status)
+	c.logEvent(v.Info.ID, sender, "TEXT", content, mediaPath, category, isAuthorized)
+
+	// Publish to bus only if authorized
+	if isAuthorized {
+		metadata := map[string]any{"event_id": v.Info.ID, "sender_jid": v.Info.Sender.String(), "classification": category}
+		if v.Info.IsGroup {
+			// The group shares one session, so tell the agent who is talking.
+			name := v.Info.PushName
+			if name == "" {
+				name = sender
+			}
+			content = fmt.Sprintf("[%s] %s", name, content)
+			metadata["group"] = true
+			metadata["sender_name"] = name
 		}
+		c.Bus.PublishInbound(&bus.InboundMessage{
+			Channel:   c.Name(),
+			SenderID:  sender,
+			ChatID:    v.Info.Chat.String(),
+			Content:   content,
+			Metadata:  metadata,
+			Timestamp: v.Info.Timestamp,
+			Trace:     tracing.Traceparent(ctx),
+		})
+	}
+}
+
+// groupActive reports whether the bot should handle a group message: the
+// group must be enabled and the message must mention or reply to the bot.
+func (c *WhatsAppChannel) groupActive(v *events.Message) bool {
+	c.mu.Lock()
+	client, policy := c.client, c.config.Groups
+	c.mu.Unlock()
+	if client == nil || client.Store.ID == nil {
+		return false
+	}
+	enabled := policy.Enabled
+	if c.timeline != nil {
+		enabled = c.timeline.GroupEnabled(v.Info.Chat.String(), policy.Enabled)
+	}
+	if !enabled {
+		return false
+	}
+	self := []types.JID{client.Store.ID.ToNonAD(), client.Store.LID.ToNonAD()}
+	return addressesBot(v.Message, self, policy.MentionNames)
+}
+
+// audioContent formats a transcription result as message content.
+func audioContent(text string, err error) string {
+	if err != nil {
+		fmt.Printf("❌ Transcription error: %v\n", err)
+		return "[Audio Message]"
+	}
+	fmt.Printf("📝 Transcript: %s\n", text)
+	return "[Audio Transcript]: " + text
+}
+
+func (c *WhatsAppChannel) mediaPolicy() config.MediaPolicy {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.config.Media
+}
+
+// handleReaction forwards an authorized sender's reaction to the bus as
+// feedback on the message it targets.
+func (c *WhatsAppChannel) handleReaction(v *events.Message, r *waE2E.ReactionMessage) {
+	sender := v.Info.Sender.User
+	if !c.isAllowed(sender) {
+		return
+	}
+	c.Bus.PublishReaction(&bus.ReactionEvent{
+		Channel:   c.Name(),
+		ChatID:    v.Info.Chat.String(),
+		SenderID:  sender,
+		MessageID: r.GetKey().GetID(),
+		Emoji:     r.GetText(),
+		Timestamp: v.Info.Timestamp,
+	})
+}
+
+// rejectMedia logs a policy rejection and tells authorized senders why their
+// attachment was not processed.
+func (c *WhatsAppChannel) rejectMedia(v *events.Message, rej *MediaRejection) {
+	sender := v.Info.Sender.User
+	authorized := c.isAllowed(sender)
+	fmt.Printf("🚫 Rejected %s from %s: %s\n", rej.Kind, sender, rej.Reply)
+	c.logEvent(v.Info.ID, sender, "REJECTED", fmt.Sprintf("[Rejected %s] %s", rej.Kind, rej.Reply), "", "", authorized)
+
+	if !authorized || c.silentFor(v.Info.Chat.String()) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	reply := rej.Localized(replyLanguage(c.timeline, c.Name(), v.Info.Chat.String(), sender))
+	if err := c.Send(ctx, &bus.OutboundMessage{Channel: c.Name(), ChatID: v.Info.Chat.String(), Content: reply}); err != nil {
+		fmt.Printf("Error sending media rejection: %v\n", err)
 	}
 }
 
@@ -340,85 +661,23 @@ func shorten(s string, max int) string {
 	return s
 }
 
-// Simple heuristic to detect English
-func isEnglish(text string) bool {
-	commonEnglish := []string{"the", "and", "is", "in", "to", "for", "with", "you", "are", "what", "how", "why", "who", "hello", "hi"}
-	lower := strings.ToLower(text)
-	score := 0
-	words := strings.Fields(lower)
-	if len(words) == 0 {
-		return false
-	}
-
-	for _, w := range words {
-		for _, eng := range commonEnglish {
-			if w == eng {
-				score++
-			}
-		}
-	}
-
-	// If meaningful percentage of words are common English stop words
-	return float64(score)/float64(len(words)) > 0.15
-}
-
 func (c *WhatsAppChannel) isAllowed(sender string) bool {
-	if len(c.config.AllowFrom) == 0 {
+	c.mu.Lock()
+	allowFrom := c.config.AllowFrom
+	c.mu.Unlock()
+	if len(allowFrom) == 0 {
 		return true
 	}
-	for _, allowed := range c.config.AllowFrom {
+	for _, allowed := range allowFrom {
 		if allowed == sender {
 			return true
 		}
 	}
-	return false
-}
-
-// classifyMessage uses the LLM to classify the intent of the message.
-func (c *WhatsAppChannel) classifyMessage(ctx context.Context, content string) (category string, summary string) {
-	sysPrompt := `You are an intent classifier for a personal agent.
-Classify the user message into one of these categories:
-1. EMERGENCY: Danger, critical system failure, urgent health issue.
-2. APPOINTMENT: Request for a meeting, call, or scheduling.
-3. ASSISTANCE: General questions, research, coding help, or anything else.
-
-Output logic:
-- Return a JSON object: {"category": "...", "summary": "..."}
-- "summary" should be a very short (max 10 words) summary of the request content.
-- If it's a technical question, it's ASSISTANCE.
-- If it's "The server is down!", it's EMERGENCY.
-- If it's "Can we meet at 5?", it's APPOINTMENT.
-`
-
-	resp, err := c.provider.Chat(ctx, &provider.ChatRequest{
-		Model: "",
-		Messages: []provider.Message{
-			{Role: "system", Content: sysPrompt},
-			{Role: "user", Content: content},
-		},
-		MaxTokens: 100,
-	})
-
-	if err != nil {
-		fmt.Printf("⚠️ Classification failed: %v\n", err)
-		return "ASSISTANCE", shorten(content, 30)
-	}
-
-	// Clean code blocks if present
-	txt := strings.TrimSpace(resp.Content)
-	txt = strings.TrimPrefix(txt, "```json")
-	txt = strings.TrimSuffix(txt, "```")
-
-	var result struct {
-		Category string `json:"category"`
-		Summary  string `json:"summary"`
-	}
-
-	// Fallback to simple unmarshal or just default
-	if err := json.Unmarshal([]byte(txt), &result); err != nil {
-		fmt.Printf("⚠️ JSON parse error: %v (raw: %s)\n", err, txt)
-		return "ASSISTANCE", shorten(content, 30)
+	// Contacts authorized from the dashboard or an admin chat.
+	if c.timeline != nil {
+		if contact, err := c.timeline.GetContact(sender); err == nil && contact.Authorized {
+			return true
+		}
 	}
-
-	return strings.ToUpper(result.Category), result.Summary
+	return false
 }
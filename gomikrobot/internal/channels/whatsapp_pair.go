@@ -0,0 +1,237 @@
+package channels
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// Pairing states of the WhatsApp channel.
+const (
+	PairingStopped   = "stopped"    // the channel is not running
+	PairingWaiting   = "waiting"    // a QR code is ready to be scanned
+	PairingPaired    = "paired"     // linked to a phone
+	PairingTimeout   = "timeout"    // no code was scanned in time
+	PairingLoggedOut = "logged_out" // unlinked from the phone
+	PairingError     = "error"
+)
+
+// ErrAlreadyPaired is returned by PairWhatsApp when the session store is
+// already linked to a phone.
+var ErrAlreadyPaired = errors.New("whatsapp session is already paired")
+
+// PairingState is the login state of the WhatsApp channel.
+type PairingState struct {
+	Status string `json:"status"`
+	// Code is the QR payload to scan while Status is waiting.
+	Code    string    `json:"code,omitempty"`
+	Expires time.Time `json:"expires,omitempty"`
+	// JID is the linked account once paired.
+	JID   string `json:"jid,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// DefaultWhatsAppSession is the session store used when none is configured.
+func DefaultWhatsAppSession() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".gomikrobot", "whatsapp.db")
+}
+
+// openWhatsAppStore opens the session database at path and returns its
+// first device, which is new (without ID) until paired.
+func openWhatsAppStore(ctx context.Context, path string) (*sqlstore.Container, *store.Device, error) {
+	if path == "" {
+		path = DefaultWhatsAppSession()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, nil, fmt.Errorf("create session dir: %w", err)
+	}
+	dbLog := waLog.Stdout("Database", "WARN", true)
+	container, err := sqlstore.New(ctx, "sqlite", "file:"+path+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", dbLog)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to init whatsapp db: %w", err)
+	}
+	device, err := container.GetFirstDevice(ctx)
+	if err != nil {
+		container.Close()
+		return nil, nil, fmt.Errorf("failed to get device: %w", err)
+	}
+	return container, device, nil
+}
+
+// Pairing returns the current login state.
+func (c *WhatsAppChannel) Pairing() PairingState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		return PairingState{Status: PairingStopped}
+	}
+	return c.pairing
+}
+
+func (c *WhatsAppChannel) setPairing(p PairingState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pairing = p
+}
+
+// watchPairing follows the QR login of client until it succeeds or fails.
+// Each code is printed to the terminal, saved as a PNG, and exposed through
+// Pairing for the dashboard.
+func (c *WhatsAppChannel) watchPairing(client *whatsmeow.Client, qrChan <-chan whatsmeow.QRChannelItem) {
+	home, _ := os.UserHomeDir()
+	qrPath := filepath.Join(home, ".gomikrobot", "whatsapp-qr.png")
+	for evt := range qrChan {
+		switch evt.Event {
+		case whatsmeow.QRChannelEventCode:
+			c.setPairing(PairingState{Status: PairingWaiting, Code: evt.Code, Expires: time.Now().Add(evt.Timeout)})
+			fmt.Println("WhatsApp: Scan this QR code in WhatsApp > Linked devices:")
+			fmt.Println(QRText(evt.Code))
+			if err := qrcode.WriteFile(evt.Code, qrcode.Medium, 512, qrPath); err == nil {
+				fmt.Printf("🖼️  WhatsApp Login QR Code saved to: %s\n", qrPath)
+			}
+		case whatsmeow.QRChannelSuccess.Event:
+			jid := ""
+			if id := client.Store.ID; id != nil {
+				jid = id.String()
+			}
+			c.setPairing(PairingState{Status: PairingPaired, JID: jid})
+			os.Remove(qrPath)
+			fmt.Printf("✅ WhatsApp: Paired as %s\n", jid)
+		case whatsmeow.QRChannelTimeout.Event:
+			c.setPairing(PairingState{Status: PairingTimeout})
+			fmt.Println("⌛ WhatsApp: QR code expired. Restart pairing from the dashboard or run 'gomikrobot whatsapp login'.")
+		default:
+			msg := evt.Event
+			if evt.Error != nil {
+				msg = evt.Error.Error()
+			}
+			c.setPairing(PairingState{Status: PairingError, Error: msg})
+			fmt.Printf("❌ WhatsApp: Pairing failed: %s\n", msg)
+		}
+	}
+}
+
+// Logout unlinks the device from the phone and deletes the local session.
+// The channel stops; starting it again begins a new pairing.
+func (c *WhatsAppChannel) Logout(ctx context.Context) error {
+	c.mu.Lock()
+	client := c.client
+	c.mu.Unlock()
+	if client == nil {
+		return errors.New("whatsapp is not running")
+	}
+	if err := client.Logout(ctx); err != nil {
+		return err
+	}
+	return c.Stop()
+}
+
+// QRText renders code as a QR code for a terminal.
+func QRText(code string) string {
+	q, err := qrcode.New(code, qrcode.Low)
+	if err != nil {
+		return code
+	}
+	return q.ToSmallString(false)
+}
+
+// QRPNG renders code as a PNG image.
+func QRPNG(code string) ([]byte, error) {
+	return qrcode.Encode(code, qrcode.Medium, 320)
+}
+
+// PairWhatsApp links the session store at path to a phone without starting
+// the gateway. onCode is called with every QR code to show; codes rotate
+// until one is scanned or pairing times out. It returns the linked JID.
+func PairWhatsApp(ctx context.Context, path string, onCode func(code string)) (string, error) {
+	container, device, err := openWhatsAppStore(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer container.Close()
+	if device.ID != nil {
+		return device.ID.String(), ErrAlreadyPaired
+	}
+
+	client := whatsmeow.NewClient(device, waLog.Noop)
+	qrChan, err := client.GetQRChannel(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := client.Connect(); err != nil {
+		return "", fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Disconnect()
+
+	for evt := range qrChan {
+		switch evt.Event {
+		case whatsmeow.QRChannelEventCode:
+			onCode(evt.Code)
+		case whatsmeow.QRChannelSuccess.Event:
+			if client.Store.ID == nil {
+				return "", errors.New("pairing succeeded without a device ID")
+			}
+			return client.Store.ID.String(), nil
+		case whatsmeow.QRChannelTimeout.Event:
+			return "", errors.New("no QR code was scanned in time")
+		default:
+			if evt.Error != nil {
+				return "", evt.Error
+			}
+			return "", errors.New(evt.Event)
+		}
+	}
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	return "", errors.New("pairing ended unexpectedly")
+}
+
+// WhatsAppAccount returns the JID linked in the session store at path, or
+// "" if it is not paired.
+func WhatsAppAccount(ctx context.Context, path string) (string, error) {
+	container, device, err := openWhatsAppStore(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer container.Close()
+	if device.ID == nil {
+		return "", nil
+	}
+	return device.ID.String(), nil
+}
+
+// UnlinkWhatsApp logs the session at path out of WhatsApp and deletes it.
+// If WhatsApp cannot be reached, only the local session is deleted and
+// remote is false: the device must then be removed on the phone.
+func UnlinkWhatsApp(ctx context.Context, path string) (remote bool, err error) {
+	container, device, err := openWhatsAppStore(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	defer container.Close()
+	if device.ID == nil {
+		return false, nil
+	}
+	client := whatsmeow.NewClient(device, waLog.Noop)
+	if err := client.Connect(); err == nil && client.WaitForConnection(15*time.Second) {
+		if err := client.Logout(ctx); err == nil {
+			return true, nil
+		}
+	}
+	client.Disconnect()
+	if err := device.Delete(ctx); err != nil {
+		return false, fmt.Errorf("delete local session: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,217 @@
+// Package classify labels inbound messages so routing can act on them.
+package classify
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+// Labels stored in TimelineEvent.Classification.
+const (
+	Question  = "QUESTION"
+	Task      = "TASK"
+	Spam      = "SPAM"
+	Emergency = "EMERGENCY"
+	Smalltalk = "SMALLTALK"
+)
+
+// Labels lists every label in the order the rules check them.
+var Labels = []string{Emergency, Spam, Smalltalk, Question, Task}
+
+// Routing actions for a label.
+const (
+	// ActionReply hands the message to the agent (the default).
+	ActionReply = "reply"
+	// ActionIgnore only logs the message; the agent never sees it.
+	ActionIgnore = "ignore"
+	// ActionPriority answers the message before queued normal messages.
+	ActionPriority = "priority"
+)
+
+// llmTimeout bounds one classification request.
+const llmTimeout = 15 * time.Second
+
+// Result is the outcome of classifying one message.
+type Result struct {
+	Label string
+	// Source is "rule", "llm", or "default".
+	Source string
+}
+
+// rule assigns Label to messages containing one of keywords (whole words,
+// ignoring case) or matching pattern.
+type rule struct {
+	label    string
+	keywords []string
+	pattern  *regexp.Regexp
+}
+
+func (r rule) match(lower string, words map[string]bool) bool {
+	if r.pattern != nil && r.pattern.MatchString(lower) {
+		return true
+	}
+	for _, k := range r.keywords {
+		if strings.Contains(k, " ") {
+			if strings.Contains(lower, k) {
+				return true
+			}
+		} else if words[k] {
+			return true
+		}
+	}
+	return false
+}
+
+// builtinRules are checked after the configured rules.
+var builtinRules = []rule{
+	{label: Emergency, keywords: []string{"emergency", "urgent", "asap", "sos", "notfall", "dringend", "ambulance",
+		"server is down", "is down", "call me now", "ruf mich an"}},
+	{label: Spam, keywords: []string{"unsubscribe", "click here", "free money", "you have won", "you won", "winner", "lottery",
+		"crypto", "bitcoin", "investment opportunity", "limited offer", "act now", "gewonnen", "gewinnspiel"}},
+	{label: Smalltalk, pattern: regexp.MustCompile(`^\W*(hi|hey|hello|hallo|moin|servus|yo|thanks|thank you|thx|danke|ok|okay|cool|nice|lol|good (morning|night|evening)|guten (morgen|abend)|gute nacht|how are you|wie geht'?s)\W*$`)},
+	{label: Question, pattern: regexp.MustCompile(`\?\s*$|^(what|when|where|who|why|how|which|is|are|do|does|did|can|could|would|should|was|wann|wo|wer|warum|wie|welche|ist|sind|kannst|hast)\b`)},
+	{label: Task, keywords: []string{"please", "bitte", "remind", "schedule", "send", "create", "write", "book", "find", "check",
+		"erinnere", "schreib", "schick", "buche", "finde", "prüfe"}},
+}
+
+// Classifier labels messages with rules first and, if a model is
+// configured, asks it about messages no rule matches.
+type Classifier struct {
+	provider provider.LLMProvider
+
+	mu      sync.RWMutex
+	enabled bool
+	model   string
+	rules   []rule
+	routes  map[string]string
+}
+
+// New creates a classifier from cfg. prov is only used when cfg.Model is set.
+func New(cfg config.ClassificationConfig, prov provider.LLMProvider) (*Classifier, error) {
+	c := &Classifier{provider: prov}
+	if err := c.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetConfig replaces rules, model, and routes, e.g. after a config reload.
+func (c *Classifier) SetConfig(cfg config.ClassificationConfig) error {
+	var rules []rule
+	for i, r := range cfg.Rules {
+		label := strings.ToUpper(r.Label)
+		if !slices.Contains(Labels, label) {
+			return fmt.Errorf("classification rule %d: unknown label %q", i, r.Label)
+		}
+		cr := rule{label: label}
+		for _, k := range r.Keywords {
+			cr.keywords = append(cr.keywords, strings.ToLower(k))
+		}
+		if r.Pattern != "" {
+			re, err := regexp.Compile("(?i)" + r.Pattern)
+			if err != nil {
+				return fmt.Errorf("classification rule %d: %w", i, err)
+			}
+			cr.pattern = re
+		}
+		rules = append(rules, cr)
+	}
+	routes := make(map[string]string, len(cfg.Routes))
+	for label, action := range cfg.Routes {
+		routes[strings.ToUpper(label)] = strings.ToLower(action)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled, c.model = cfg.Enabled, cfg.Model
+	c.rules = append(rules, builtinRules...)
+	c.routes = routes
+	return nil
+}
+
+// Classify labels text. It returns a zero Result when c is nil or
+// classification is disabled.
+func (c *Classifier) Classify(ctx context.Context, text string) Result {
+	if c == nil {
+		return Result{}
+	}
+	c.mu.RLock()
+	enabled, model, rules := c.enabled, c.model, c.rules
+	c.mu.RUnlock()
+	if !enabled || strings.TrimSpace(text) == "" {
+		return Result{}
+	}
+
+	lower := strings.ToLower(strings.TrimSpace(text))
+	words := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(lower, func(r rune) bool {
+		return !(r == '\'' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		words[w] = true
+	}
+	for _, r := range rules {
+		if r.match(lower, words) {
+			return Result{Label: r.label, Source: "rule"}
+		}
+	}
+
+	if model != "" && c.provider != nil {
+		label, err := c.askModel(ctx, model, text)
+		if err == nil {
+			return Result{Label: label, Source: "llm"}
+		}
+		fmt.Printf("⚠️ Classification failed: %v\n", err)
+	}
+	return Result{Label: Task, Source: "default"}
+}
+
+const classifyPrompt = `You label messages sent to a personal assistant. Answer with exactly one word:
+EMERGENCY - danger, health issue, critical failure, needs attention right now
+SPAM - advertising, scams, phishing, mass messages
+SMALLTALK - greetings, thanks, chit-chat without a request
+QUESTION - asks for information or an opinion
+TASK - asks the assistant to do something`
+
+func (c *Classifier) askModel(ctx context.Context, model, text string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, llmTimeout)
+	defer cancel()
+	resp, err := c.provider.Chat(ctx, &provider.ChatRequest{
+		Model: model,
+		Messages: []provider.Message{
+			{Role: "system", Content: classifyPrompt},
+			{Role: "user", Content: text},
+		},
+		MaxTokens: 5,
+	})
+	if err != nil {
+		return "", err
+	}
+	answer := strings.ToUpper(resp.Content)
+	for _, l := range Labels {
+		if strings.Contains(answer, l) {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("unexpected label %q", strings.TrimSpace(resp.Content))
+}
+
+// Action returns the routing action for label.
+func (c *Classifier) Action(label string) string {
+	if c == nil || label == "" {
+		return ActionReply
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if a, ok := c.routes[label]; ok {
+		return a
+	}
+	return ActionReply
+}
@@ -0,0 +1,94 @@
+package classify
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+type labelProvider struct {
+	answer string
+	calls  int
+	model  string
+}
+
+func (p *labelProvider) Chat(ctx context.Context, req *provider.ChatRequest) (*provider.ChatResponse, error) {
+	p.calls++
+	p.model = req.Model
+	return &provider.ChatResponse{Content: p.answer}, nil
+}
+func (p *labelProvider) Transcribe(context.Context, *provider.AudioRequest) (*provider.AudioResponse, error) {
+	return nil, nil
+}
+func (p *labelProvider) Speak(context.Context, *provider.TTSRequest) (*provider.TTSResponse, error) {
+	return nil, nil
+}
+func (p *labelProvider) DefaultModel() string { return "test" }
+
+func TestBuiltinRules(t *testing.T) {
+	c, err := New(config.ClassificationConfig{Enabled: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for text, want := range map[string]string{
+		"URGENT: the server is down":            Emergency,
+		"You have won! Click here to claim":     Spam,
+		"Good morning!":                         Smalltalk,
+		"thanks :)":                             Smalltalk,
+		"What time is the dentist appointment?": Question,
+		"wie spät ist es":                       Question,
+		"Please remind me to call mom":          Task,
+		"the invoice from march":                Task, // default
+	} {
+		if got := c.Classify(context.Background(), text).Label; got != want {
+			t.Errorf("Classify(%q) = %s, want %s", text, got, want)
+		}
+	}
+}
+
+func TestCustomRulesAndModel(t *testing.T) {
+	prov := &labelProvider{answer: "smalltalk."}
+	c, err := New(config.ClassificationConfig{
+		Enabled: true,
+		Model:   "gpt-4o-mini",
+		Rules:   []config.ClassificationRule{{Label: "spam", Keywords: []string{"newsletter"}}, {Label: "emergency", Pattern: `^alarm\b`}},
+		Routes:  map[string]string{"spam": "Ignore"},
+	}, prov)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if r := c.Classify(ctx, "Our weekly newsletter, please read"); r.Label != Spam || r.Source != "rule" {
+		t.Errorf("custom keyword rule should win over built-ins, got %+v", r)
+	}
+	if r := c.Classify(ctx, "Alarm in the basement"); r.Label != Emergency {
+		t.Errorf("custom pattern should ignore case, got %+v", r)
+	}
+	if prov.calls != 0 {
+		t.Errorf("model asked although a rule matched")
+	}
+	if r := c.Classify(ctx, "the invoice from march"); r.Label != Smalltalk || r.Source != "llm" || prov.model != "gpt-4o-mini" {
+		t.Errorf("unmatched message should go to the model, got %+v (model %q)", r, prov.model)
+	}
+	prov.answer = "no idea"
+	if r := c.Classify(ctx, "the invoice from march"); r.Label != Task || r.Source != "default" {
+		t.Errorf("unparseable answer should fall back to the default, got %+v", r)
+	}
+
+	if c.Action(Spam) != ActionIgnore || c.Action(Question) != ActionReply || c.Action("") != ActionReply {
+		t.Error("routes not applied")
+	}
+	var none *Classifier
+	if none.Classify(ctx, "hi").Label != "" || none.Action(Spam) != ActionReply {
+		t.Error("nil classifier should neither label nor route")
+	}
+
+	if err := c.SetConfig(config.ClassificationConfig{Rules: []config.ClassificationRule{{Label: "junk"}}}); err == nil {
+		t.Error("expected an error for an unknown label")
+	}
+	if err := c.SetConfig(config.ClassificationConfig{Enabled: false}); err != nil || c.Classify(ctx, "hi").Label != "" {
+		t.Error("disabled classifier should not label")
+	}
+}
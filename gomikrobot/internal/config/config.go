@@ -1,37 +1,177 @@
 // Package config provides configuration types and loading for gonanobot.
 package config
 
-import "time"
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
 
 // Config is the root configuration struct.
 type Config struct {
-	Agents    AgentsConfig    `json:"agents"`
-	Channels  ChannelsConfig  `json:"channels"`
-	Providers ProvidersConfig `json:"providers"`
-	Gateway   GatewayConfig   `json:"gateway"`
-	Tools     ToolsConfig     `json:"tools"`
+	Agents        AgentsConfig        `json:"agents"`
+	Channels      ChannelsConfig      `json:"channels"`
+	Providers     ProvidersConfig     `json:"providers"`
+	Transcription TranscriptionConfig `json:"transcription"`
+	Gateway       GatewayConfig       `json:"gateway"`
+	Bus           BusConfig           `json:"bus"`
+	Tools         ToolsConfig         `json:"tools"`
+	Sessions      SessionsConfig      `json:"sessions"`
+	Timeline      TimelineConfig      `json:"timeline"`
+	Backup        BackupConfig        `json:"backup"`
+	Digest        DigestConfig        `json:"digest"`
+	Proxy         ProxyConfig         `json:"proxy"`
+	Audit         AuditConfig         `json:"audit"`
+	Pricing       PricingConfig       `json:"pricing"`
+	Feeds         FeedsConfig         `json:"feeds"`
+	Knowledge     KnowledgeConfig     `json:"knowledge"`
+	Embeddings    EmbeddingsConfig    `json:"embeddings"`
+	Tracing       TracingConfig       `json:"tracing"`
+	Secrets       SecretsConfig       `json:"secrets"`
+	// Classification labels inbound messages and routes them by label.
+	Classification ClassificationConfig `json:"classification"`
+	// Tenants are further bots run by the gateway, isolated from the main one.
+	Tenants []TenantConfig `json:"tenants,omitempty"`
+}
+
+// TenantConfig is an isolated bot hosted by the same gateway, e.g. for
+// another family member. It has its own workspace, timeline, sessions,
+// channels, and API token; providers, model, and limits are shared with
+// the main configuration. Its data lives in ~/.gomikrobot/tenants/<name>.
+type TenantConfig struct {
+	Name string `json:"name"`
+	// Workspace defaults to ~/.gomikrobot/tenants/<name>/workspace.
+	Workspace string `json:"workspace,omitempty"`
+	// APIToken selects the tenant on the API server (X-API-Token or
+	// Authorization: Bearer). It must differ from gateway.apiToken.
+	APIToken string         `json:"apiToken"`
+	Admins   []string       `json:"admins,omitempty"`
+	Channels ChannelsConfig `json:"channels"`
+}
+
+// Dir returns the directory holding the tenant's timeline and sessions.
+func (t TenantConfig) Dir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".gomikrobot", "tenants", t.Name)
 }
 
 // AgentsConfig contains agent-related settings.
 type AgentsConfig struct {
 	Defaults AgentDefaults `json:"defaults"`
+	// Projects are named directories the agent can switch to with switch_project.
+	Projects []ProjectConfig `json:"projects,omitempty"`
+	// Admins are session keys ("whatsapp:4917…@s.whatsapp.net", "cli:*")
+	// allowed to use privileged tools such as update_identity.
+	Admins []string `json:"admins,omitempty"`
+	// VIPs are session keys, in the same form as Admins, whose messages
+	// are answered before queued messages of other chats.
+	VIPs []string `json:"vips,omitempty"`
+	// ReviewSession is told about replies held for review, e.g.
+	// "whatsapp:4917…@s.whatsapp.net". Defaults to the first admin session
+	// without a wildcard.
+	ReviewSession string `json:"reviewSession,omitempty"`
+}
+
+// ProjectConfig is a named project directory.
+type ProjectConfig struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Description string `json:"description,omitempty"`
 }
 
 // AgentDefaults contains default agent settings.
 type AgentDefaults struct {
-	Workspace         string  `json:"workspace" envconfig:"WORKSPACE"`
-	Model             string  `json:"model" envconfig:"MODEL"`
-	MaxTokens         int     `json:"maxTokens" envconfig:"MAX_TOKENS"`
-	Temperature       float64 `json:"temperature" envconfig:"TEMPERATURE"`
-	MaxToolIterations int     `json:"maxToolIterations" envconfig:"MAX_TOOL_ITERATIONS"`
+	Workspace string `json:"workspace" envconfig:"WORKSPACE"`
+	Model     string `json:"model" envconfig:"MODEL"`
+	// Models lists the other models set_model may switch a conversation
+	// to; without any, conversations keep Model.
+	Models            []string `json:"models,omitempty" envconfig:"MODELS"`
+	MaxTokens         int      `json:"maxTokens" envconfig:"MAX_TOKENS"`
+	Temperature       float64  `json:"temperature" envconfig:"TEMPERATURE"`
+	MaxToolIterations int      `json:"maxToolIterations" envconfig:"MAX_TOOL_ITERATIONS"`
+
+	// TopP and ReasoningEffort are only sent when set; sessions may
+	// override all sampling settings with set_sampling.
+	TopP            float64 `json:"topP,omitempty" envconfig:"TOP_P"`
+	ReasoningEffort string  `json:"reasoningEffort,omitempty" envconfig:"REASONING_EFFORT"`
+
+	// Tool execution within a single LLM turn.
+	MaxParallelTools int           `json:"maxParallelTools" envconfig:"MAX_PARALLEL_TOOLS"`
+	ToolTimeout      time.Duration `json:"toolTimeout" envconfig:"TOOL_TIMEOUT"`
+	// Larger tool results are stored under <workspace>/artifacts and the
+	// model gets a preview it can page through with read_artifact.
+	MaxToolResultChars int `json:"maxToolResultChars" envconfig:"MAX_TOOL_RESULT_CHARS"`
+	// Per-message budget surfaced to the model (0 = unlimited).
+	MaxToolCalls int           `json:"maxToolCalls" envconfig:"MAX_TOOL_CALLS"`
+	TurnTimeout  time.Duration `json:"turnTimeout" envconfig:"TURN_TIMEOUT"`
+	// Sessions processed at the same time; messages within one session stay ordered.
+	MaxConcurrentSessions int `json:"maxConcurrentSessions" envconfig:"MAX_CONCURRENT_SESSIONS"`
+	// Background tasks started with spawn_task.
+	MaxConcurrentTasks int           `json:"maxConcurrentTasks" envconfig:"MAX_CONCURRENT_TASKS"`
+	TaskTimeout        time.Duration `json:"taskTimeout" envconfig:"TASK_TIMEOUT"`
+	TaskMaxToolCalls   int           `json:"taskMaxToolCalls" envconfig:"TASK_MAX_TOOL_CALLS"`
+	// History sent with each message: at most HistoryMessages messages and,
+	// if HistoryTokens is set, about that many tokens. Pinned messages are
+	// always included.
+	HistoryMessages int `json:"historyMessages" envconfig:"HISTORY_MESSAGES"`
+	HistoryTokens   int `json:"historyTokens,omitempty" envconfig:"HISTORY_TOKENS"`
+	// ContextLength is the model's context window in tokens, for models the
+	// bot does not know (0 = look it up by model name). Prompts that would
+	// not fit are summarized before they are sent.
+	ContextLength int `json:"contextLength,omitempty" envconfig:"CONTEXT_LENGTH"`
+
+	Prompt PromptConfig `json:"prompt"`
+	Critic CriticConfig `json:"critic"`
+	// Refusals controls what happens when the provider declines a request.
+	Refusals RefusalConfig `json:"refusals"`
+}
+
+// RefusalConfig handles provider refusals and content filter blocks. The
+// user gets Message instead of the provider's boilerplate.
+type RefusalConfig struct {
+	// Message is sent instead of the refusal (default: a localized request
+	// to rephrase).
+	Message string `json:"message,omitempty" envconfig:"MESSAGE"`
+	// Retry resends a refused request once, asking the model to help with
+	// the acceptable part.
+	Retry bool `json:"retry" envconfig:"RETRY"`
+	// FallbackModel answers requests the configured model still refuses,
+	// e.g. a model of another vendor through OpenRouter.
+	FallbackModel string `json:"fallbackModel,omitempty" envconfig:"FALLBACK_MODEL"`
+}
+
+// CriticConfig controls the optional critic pass: a short evaluation of
+// each answer against the user's request. Answers scored below Threshold
+// are rewritten once with the critique.
+type CriticConfig struct {
+	Enabled bool `json:"enabled" envconfig:"ENABLED"`
+	// Model scores the answers; a small model is usually enough (default:
+	// the model that answered).
+	Model string `json:"model,omitempty" envconfig:"MODEL"`
+	// Threshold is the lowest acceptable score from 1 to 10 (default 6).
+	Threshold int `json:"threshold" envconfig:"THRESHOLD"`
+}
+
+// PromptConfig customizes the system prompt.
+type PromptConfig struct {
+	// TemplateFile is a text/template file for the identity section.
+	// Relative paths resolve against the workspace (default: SYSTEM.md).
+	TemplateFile string `json:"templateFile,omitempty" envconfig:"PROMPT_TEMPLATE_FILE"`
+	// DisabledSections lists prompt sections to omit: bootstrap, memory, skills.
+	DisabledSections []string `json:"disabledSections,omitempty"`
 }
 
 // ChannelsConfig contains all channel configurations.
 type ChannelsConfig struct {
-	Telegram TelegramConfig `json:"telegram"`
-	Discord  DiscordConfig  `json:"discord"`
-	WhatsApp WhatsAppConfig `json:"whatsapp"`
-	Feishu   FeishuConfig   `json:"feishu"`
+	Telegram TelegramConfig  `json:"telegram"`
+	Discord  DiscordConfig   `json:"discord"`
+	WhatsApp WhatsAppConfig  `json:"whatsapp"`
+	Feishu   FeishuConfig    `json:"feishu"`
+	Slack    SlackConfig     `json:"slack"`
+	Signal   SignalConfig    `json:"signal"`
+	Voice    VoiceConfig     `json:"voice"`
+	Webhooks []WebhookConfig `json:"webhooks,omitempty"`
 }
 
 // TelegramConfig configures the Telegram channel.
@@ -51,9 +191,77 @@ type DiscordConfig struct {
 
 // WhatsAppConfig configures the WhatsApp channel.
 type WhatsAppConfig struct {
-	Enabled   bool     `json:"enabled" envconfig:"WHATSAPP_ENABLED"`
-	BridgeURL string   `json:"bridgeUrl" envconfig:"WHATSAPP_BRIDGE_URL"`
-	AllowFrom []string `json:"allowFrom"`
+	Enabled bool `json:"enabled" envconfig:"WHATSAPP_ENABLED"`
+	// SessionPath is the local database holding the linked-device session
+	// (default ~/.gomikrobot/whatsapp.db). Pair with `gomikrobot whatsapp
+	// login` or the QR code on the dashboard.
+	SessionPath string `json:"sessionPath,omitempty" envconfig:"WHATSAPP_SESSION_PATH"`
+	// BridgeURL is only for the legacy Node.js bridge; the channel connects
+	// to WhatsApp natively. When set, doctor and /ready still probe it.
+	BridgeURL string         `json:"bridgeUrl,omitempty" envconfig:"WHATSAPP_BRIDGE_URL"`
+	AllowFrom []string       `json:"allowFrom"`
+	Media     MediaPolicy    `json:"media"`
+	Outbound  OutboundPolicy `json:"outbound"`
+	// TypingIndicator shows "typing…" while the agent works on a reply.
+	TypingIndicator bool `json:"typingIndicator" envconfig:"WHATSAPP_TYPING_INDICATOR"`
+	// ReadReceipts marks messages from allowed senders as read.
+	ReadReceipts bool `json:"readReceipts" envconfig:"WHATSAPP_READ_RECEIPTS"`
+	// Groups controls when the bot answers in group chats.
+	Groups GroupPolicy `json:"groups"`
+}
+
+// GroupPolicy makes the bot answer in a group only when it is mentioned or
+// a message replies to one of its own. Groups can be switched on or off
+// individually from the dashboard; Enabled is the default for the rest.
+type GroupPolicy struct {
+	Enabled bool `json:"enabled" envconfig:"ENABLED"`
+	// MentionNames also count as a mention when written as "@name", for
+	// clients that send mentions as plain text.
+	MentionNames []string `json:"mentionNames,omitempty" envconfig:"MENTION_NAMES"`
+}
+
+// MediaPolicy limits inbound media before it reaches transcription or vision.
+// Size limits of 0 disable the check; AllowedTypes accepts exact MIME types
+// or wildcards like "audio/*" (empty allows everything).
+type MediaPolicy struct {
+	MaxImageBytes    int64    `json:"maxImageBytes"`
+	MaxAudioBytes    int64    `json:"maxAudioBytes"`
+	MaxDocumentBytes int64    `json:"maxDocumentBytes"`
+	AllowedTypes     []string `json:"allowedTypes"`
+	// TranscodeAudio converts audio formats the transcriber cannot read
+	// (e.g. AMR, 3GP) to WAV using ffmpeg.
+	TranscodeAudio bool   `json:"transcodeAudio"`
+	FFmpegPath     string `json:"ffmpegPath,omitempty"`
+}
+
+// DefaultMediaPolicy returns the media limits applied to every channel.
+func DefaultMediaPolicy() MediaPolicy {
+	return MediaPolicy{
+		MaxImageBytes:    10 << 20, // 10 MiB
+		MaxAudioBytes:    25 << 20, // Whisper API upload limit
+		MaxDocumentBytes: 50 << 20,
+		TranscodeAudio:   true,
+		FFmpegPath:       "ffmpeg",
+	}
+}
+
+// OutboundPolicy throttles the messages the bot sends on a channel, since
+// messengers ban accounts that send too fast.
+type OutboundPolicy struct {
+	// RatePerSecond and Burst limit how fast messages go out (0 disables).
+	RatePerSecond float64 `json:"ratePerSecond"`
+	Burst         int     `json:"burst"`
+	// MaxChars splits longer replies into several messages (0 disables).
+	MaxChars int `json:"maxChars"`
+	// Retries is how often a failed send is retried with backoff.
+	Retries   int `json:"retries"`
+	QueueSize int `json:"queueSize"`
+	// Format is the markup replies are converted to: markdown, whatsapp,
+	// slack, telegram, discord, or plain. Empty uses the channel's own.
+	Format string `json:"format,omitempty"`
+	// CodeFileChars sends code blocks longer than this as files instead of
+	// text (0 keeps them inline).
+	CodeFileChars int `json:"codeFileChars"`
 }
 
 // FeishuConfig configures the Feishu channel.
@@ -66,6 +274,168 @@ type FeishuConfig struct {
 	AllowFrom         []string `json:"allowFrom"`
 }
 
+// SlackConfig configures the Slack channel (Socket Mode).
+// AppToken/BotToken configure a single workspace; Workspaces adds more.
+type SlackConfig struct {
+	Enabled       bool                   `json:"enabled" envconfig:"SLACK_ENABLED"`
+	AppToken      string                 `json:"appToken" envconfig:"SLACK_APP_TOKEN"`
+	BotToken      string                 `json:"botToken" envconfig:"SLACK_BOT_TOKEN"`
+	Workspaces    []SlackWorkspaceConfig `json:"workspaces,omitempty"`
+	AllowFrom     []string               `json:"allowFrom"`
+	ReplyInThread bool                   `json:"replyInThread" envconfig:"SLACK_REPLY_IN_THREAD"`
+	Media         MediaPolicy            `json:"media"`
+	Outbound      OutboundPolicy         `json:"outbound"`
+}
+
+// SlackWorkspaceConfig holds the tokens of one Slack workspace.
+type SlackWorkspaceConfig struct {
+	Name     string `json:"name"`
+	AppToken string `json:"appToken"` // xapp-… (connections:write)
+	BotToken string `json:"botToken"` // xoxb-…
+}
+
+// SignalConfig configures the Signal channel. It talks to a signal-cli
+// daemon started with --http; link it with "gomikrobot signal link".
+type SignalConfig struct {
+	Enabled bool `json:"enabled" envconfig:"SIGNAL_ENABLED"`
+	// URL is the signal-cli HTTP endpoint (default http://127.0.0.1:8080).
+	URL string `json:"url" envconfig:"SIGNAL_URL"`
+	// Account is the bot's own number in E.164 form, e.g. +4915112345678.
+	Account string `json:"account" envconfig:"SIGNAL_ACCOUNT"`
+	// AllowFrom lists sender numbers (E.164) allowed to talk to the bot.
+	AllowFrom []string       `json:"allowFrom"`
+	Media     MediaPolicy    `json:"media"`
+	Outbound  OutboundPolicy `json:"outbound"`
+}
+
+// VoiceConfig configures the experimental voice channel: push-to-talk
+// calls from the dashboard, transcribed with the transcription settings
+// and answered with speech.
+type VoiceConfig struct {
+	Enabled bool `json:"enabled" envconfig:"VOICE_ENABLED"`
+	// Voice is the TTS voice of replies (default nova).
+	Voice string      `json:"voice,omitempty" envconfig:"VOICE_TTS_VOICE"`
+	Media MediaPolicy `json:"media"`
+}
+
+// WebhookConfig defines an inbound webhook at /api/v1/hooks/{name}.
+type WebhookConfig struct {
+	Name string `json:"name"`
+	// Secret enables HMAC-SHA256 verification of the request body.
+	Secret string `json:"secret,omitempty"`
+	// SignatureHeader carries the hex signature (default X-Hub-Signature-256;
+	// a "sha256=" prefix is accepted).
+	SignatureHeader string `json:"signatureHeader,omitempty"`
+	// Template is a text/template rendered into the agent prompt. Fields:
+	// .Name, .Body (raw), .JSON (decoded body), .Headers.
+	Template string `json:"template,omitempty"`
+	// ForwardChannel and ForwardChatID receive the agent's response.
+	ForwardChannel string `json:"forwardChannel,omitempty"`
+	ForwardChatID  string `json:"forwardChatId,omitempty"`
+	// CacheTTL reuses the response to an identical prompt for this long,
+	// e.g. for hooks that resend the same payload (0 disables).
+	CacheTTL time.Duration `json:"cacheTtl,omitempty"`
+}
+
+// FeedsConfig polls RSS and Atom feeds. New items are stored in the
+// timeline as FEED events.
+type FeedsConfig struct {
+	Interval time.Duration `json:"interval" envconfig:"INTERVAL"`
+	Feeds    []FeedConfig  `json:"feeds,omitempty"`
+}
+
+// KnowledgeConfig configures the document knowledge base filled by
+// `gomikrobot ingest` and searched with kb_search. It is enabled when
+// QdrantURL is set; embeddings are computed by the embeddings backend.
+type KnowledgeConfig struct {
+	QdrantURL      string `json:"qdrantUrl,omitempty" envconfig:"QDRANT_URL"`
+	Collection     string `json:"collection" envconfig:"COLLECTION"`
+	EmbeddingModel string `json:"embeddingModel" envconfig:"EMBEDDING_MODEL"`
+	// Dimensions must match the embedding model's vector size.
+	Dimensions int `json:"dimensions" envconfig:"DIMENSIONS"`
+	// ChunkSize and ChunkOverlap are in characters.
+	ChunkSize    int `json:"chunkSize" envconfig:"CHUNK_SIZE"`
+	ChunkOverlap int `json:"chunkOverlap" envconfig:"CHUNK_OVERLAP"`
+}
+
+// EmbeddingsConfig selects the backend that computes text embeddings for
+// the knowledge base and other vector search features.
+type EmbeddingsConfig struct {
+	// Provider is "openai" (the OpenAI-compatible provider, the default) or
+	// "ollama".
+	Provider string `json:"provider" envconfig:"PROVIDER"`
+	// BaseURL is the Ollama server, http://localhost:11434 if empty. It is
+	// ignored for openai, which uses providers.openai.apiBase.
+	BaseURL string `json:"baseUrl,omitempty" envconfig:"BASE_URL"`
+	// BatchSize is the number of texts sent per request; 0 sends all at once.
+	BatchSize int `json:"batchSize" envconfig:"BATCH_SIZE"`
+	// CacheSize is the number of vectors kept in memory; 0 disables caching.
+	CacheSize int `json:"cacheSize" envconfig:"CACHE_SIZE"`
+}
+
+// TracingConfig configures OpenTelemetry trace export. Spans for channel
+// handlers, agent turns, provider calls, and tools are sent as OTLP/HTTP
+// JSON when Endpoint is set; OTEL_EXPORTER_OTLP_ENDPOINT is used otherwise.
+type TracingConfig struct {
+	// Endpoint is the collector base URL, e.g. http://localhost:4318.
+	Endpoint    string            `json:"endpoint,omitempty" envconfig:"ENDPOINT"`
+	ServiceName string            `json:"serviceName" envconfig:"SERVICE_NAME"`
+	Headers     map[string]string `json:"headers,omitempty" envconfig:"HEADERS"`
+	// SampleRatio is the share of traces recorded, from 0 (exclusive) to 1.
+	SampleRatio float64 `json:"sampleRatio" envconfig:"SAMPLE_RATIO"`
+}
+
+// SecretsConfig selects where ${secret:NAME} references in the config
+// file are looked up. The file backend's passphrase is read from
+// MIKROBOT_SECRETS_PASSPHRASE.
+type SecretsConfig struct {
+	// Backend is "keychain" (macOS Keychain or Linux Secret Service),
+	// "file" (passphrase-encrypted file), or empty for none.
+	Backend string `json:"backend,omitempty" envconfig:"BACKEND"`
+	File    string `json:"file" envconfig:"FILE"`
+}
+
+// ClassificationConfig labels inbound chat messages as QUESTION, TASK,
+// SPAM, EMERGENCY, or SMALLTALK. Rules run first; Model, if set, labels the
+// messages no rule matches.
+type ClassificationConfig struct {
+	Enabled bool `json:"enabled" envconfig:"ENABLED"`
+	// Model is a cheap model such as gpt-4o-mini (empty = rules only).
+	Model string `json:"model,omitempty" envconfig:"MODEL"`
+	// Rules are checked before the built-in keyword rules.
+	Rules []ClassificationRule `json:"rules,omitempty"`
+	// Routes maps a label to "reply" (default), "ignore", which logs the
+	// message without passing it to the agent, or "priority", which answers
+	// it before queued messages.
+	Routes map[string]string `json:"routes,omitempty"`
+}
+
+// ClassificationRule labels messages containing one of Keywords (whole
+// words or phrases, ignoring case) or matching the regular expression
+// Pattern.
+type ClassificationRule struct {
+	Label    string   `json:"label"`
+	Keywords []string `json:"keywords,omitempty"`
+	Pattern  string   `json:"pattern,omitempty"`
+}
+
+// FeedConfig is one feed subscription.
+type FeedConfig struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Include keeps only items mentioning one of these words; Exclude drops
+	// items mentioning any. Both match title and summary, ignoring case.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// Prompt, if set, is a text/template sent to the agent with each batch
+	// of new items. Fields: .Feed, .Items (Title, Link, Summary, Published).
+	// A response of just SKIP is not forwarded.
+	Prompt string `json:"prompt,omitempty"`
+	// ForwardChannel and ForwardChatID receive the agent's response.
+	ForwardChannel string `json:"forwardChannel,omitempty"`
+	ForwardChatID  string `json:"forwardChatId,omitempty"`
+}
+
 // ProvidersConfig contains LLM provider configurations.
 type ProvidersConfig struct {
 	Anthropic    ProviderConfig     `json:"anthropic"`
@@ -91,6 +461,22 @@ type LocalWhisperConfig struct {
 	BinaryPath string `json:"binaryPath" envconfig:"WHISPER_BINARY_PATH"`
 }
 
+// TranscriptionConfig selects the backend that turns voice messages into text.
+type TranscriptionConfig struct {
+	// Backend is "local", "openai", or "groq". Empty picks local when
+	// providers.localWhisper is enabled and openai otherwise.
+	Backend string `json:"backend,omitempty" envconfig:"BACKEND"`
+	// Model overrides the backend's default model (whisper-1, whisper-large-v3).
+	// The local backend uses providers.localWhisper.model.
+	Model    string `json:"model,omitempty" envconfig:"MODEL"`
+	Language string `json:"language,omitempty" envconfig:"LANGUAGE"`
+	// Workers transcribe in the background so long voice notes do not block
+	// the channel; QueueSize bounds the jobs waiting for a worker.
+	Workers   int           `json:"workers" envconfig:"WORKERS"`
+	QueueSize int           `json:"queueSize" envconfig:"QUEUE_SIZE"`
+	Timeout   time.Duration `json:"timeout" envconfig:"TIMEOUT"`
+}
+
 // GatewayConfig contains gateway server settings.
 type GatewayConfig struct {
 	Host          string `json:"host" envconfig:"HOST"`
@@ -100,23 +486,362 @@ type GatewayConfig struct {
 	// Optional API token for local-network API.
 	APIToken string `json:"apiToken,omitempty" envconfig:"API_TOKEN"`
 
+	// DashboardPassword enables the dashboard login page. The API token is
+	// accepted as well; with neither set the dashboard is open.
+	DashboardPassword string        `json:"dashboardPassword,omitempty" envconfig:"DASHBOARD_PASSWORD"`
+	SessionTTL        time.Duration `json:"sessionTtl" envconfig:"SESSION_TTL"`
+	// CORSOrigins may call the dashboard API from other sites
+	// ("https://home.example.com"; "*" allows any origin without cookies).
+	CORSOrigins []string `json:"corsOrigins,omitempty" envconfig:"CORS_ORIGINS"`
+	// WebDir serves the dashboard from this directory instead of the files
+	// built into the binary (for dashboard development).
+	WebDir string `json:"webDir,omitempty" envconfig:"WEB_DIR"`
+
 	// Enterprise hardening.
 	RateLimitRPS    float64       `json:"rateLimitRps" envconfig:"RATE_LIMIT_RPS"`
 	RateLimitBurst  int           `json:"rateLimitBurst" envconfig:"RATE_LIMIT_BURST"`
 	MaxBodyBytes    int64         `json:"maxBodyBytes" envconfig:"MAX_BODY_BYTES"`
 	ShutdownTimeout time.Duration `json:"shutdownTimeout" envconfig:"SHUTDOWN_TIMEOUT"`
+
+	// RateLimitRoutes overrides the limit for path prefixes, e.g.
+	// {"/chat": {"rps": 0.5, "burst": 3}, "/health": {"rps": 50, "burst": 100}}.
+	RateLimitRoutes map[string]RateLimit `json:"rateLimitRoutes,omitempty" ignored:"true"`
+	// TokenRateLimit applies to requests carrying the API token; they are
+	// limited per token instead of per client IP.
+	TokenRateLimit RateLimit `json:"tokenRateLimit" ignored:"true"`
+
+	// TrustedProxies are reverse proxies (CIDRs or IPs) whose
+	// X-Forwarded-For and X-Real-IP headers identify the client. Requests
+	// from anywhere else are keyed by their connection address.
+	TrustedProxies []string `json:"trustedProxies" envconfig:"TRUSTED_PROXIES"`
+
+	// DedupWindow drops inbound messages redelivered within this window (0 disables).
+	DedupWindow time.Duration `json:"dedupWindow" envconfig:"DEDUP_WINDOW"`
+
+	// TLS serves the API and dashboard over HTTPS.
+	TLS GatewayTLSConfig `json:"tls"`
+
+	// Ready configures the dependency checks behind /ready.
+	Ready ReadyConfig `json:"ready"`
+}
+
+// BusConfig selects how the message bus connects channels, the agent loop,
+// and workers. With a NATS or Redis transport they can run as separate
+// processes sharing one bus; tenants always keep an in-memory bus.
+type BusConfig struct {
+	// Transport is "local" (default, in memory), "nats", or "redis". NATS
+	// needs JetStream enabled for the inbound queue.
+	Transport string `json:"transport,omitempty" envconfig:"TRANSPORT"`
+	// URL of the server, e.g. nats://nats:4222 or redis://redis:6379/0.
+	URL string `json:"url,omitempty" envconfig:"URL"`
+	// Prefix starts the subjects or stream keys, so several bots can share
+	// a server.
+	Prefix string `json:"prefix,omitempty" envconfig:"PREFIX"`
+	// Workers leaves agent turns to `gomikrobot worker` processes: the
+	// gateway runs the channels and routes each session to one worker.
+	Workers bool `json:"workers,omitempty" envconfig:"WORKERS"`
+}
+
+// ReadyConfig configures the readiness checks: startup, provider, timeline,
+// WhatsApp connectivity, and free disk space in the workspace.
+type ReadyConfig struct {
+	// MinFreeDiskMB fails readiness when less space is left (0 disables).
+	MinFreeDiskMB int `json:"minFreeDiskMb" envconfig:"MIN_FREE_DISK_MB"`
+	// Timeout bounds each check; results are reused for CacheTTL.
+	Timeout  time.Duration `json:"timeout" envconfig:"TIMEOUT"`
+	CacheTTL time.Duration `json:"cacheTtl" envconfig:"CACHE_TTL"`
+	// SkipProvider leaves out the provider check, e.g. to stay ready
+	// through provider outages.
+	SkipProvider bool `json:"skipProvider,omitempty" envconfig:"SKIP_PROVIDER"`
+}
+
+// RateLimit is a token-bucket rate for the gateway rate limiter.
+type RateLimit struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+}
+
+// GatewayTLSConfig enables HTTPS with a certificate from files or from
+// Let's Encrypt.
+type GatewayTLSConfig struct {
+	CertFile string `json:"certFile,omitempty" envconfig:"CERT_FILE"`
+	KeyFile  string `json:"keyFile,omitempty" envconfig:"KEY_FILE"`
+
+	// ACMEHosts are public hostnames to obtain certificates for
+	// automatically. They must resolve to this machine, and port 443 or the
+	// redirect address on port 80 must be reachable from the internet.
+	ACMEHosts []string `json:"acmeHosts,omitempty" envconfig:"ACME_HOSTS"`
+	ACMEEmail string   `json:"acmeEmail,omitempty" envconfig:"ACME_EMAIL"`
+	// ACMECacheDir stores issued certificates (default ~/.gomikrobot/certs).
+	ACMECacheDir string `json:"acmeCacheDir,omitempty" envconfig:"ACME_CACHE_DIR"`
+
+	// RedirectAddr listens for plain HTTP (e.g. ":80"), redirects it to the
+	// dashboard over HTTPS, and answers ACME HTTP challenges.
+	RedirectAddr string `json:"redirectAddr,omitempty" envconfig:"REDIRECT_ADDR"`
+}
+
+// Enabled reports whether HTTPS is configured.
+func (t GatewayTLSConfig) Enabled() bool {
+	return t.CertFile != "" || len(t.ACMEHosts) > 0
+}
+
+// BudgetAlertSession returns the session key that receives budget alerts:
+// pricing.alertSession, or else the first admin session without a wildcard.
+func (c *Config) BudgetAlertSession() string {
+	if c.Pricing.AlertSession != "" {
+		return c.Pricing.AlertSession
+	}
+	return c.firstAdmin()
+}
+
+// ReviewSession returns the session key told about replies held for
+// review: agents.reviewSession, or else the first admin session without a
+// wildcard.
+func (c *Config) ReviewSession() string {
+	if c.Agents.ReviewSession != "" {
+		return c.Agents.ReviewSession
+	}
+	return c.firstAdmin()
+}
+
+func (c *Config) firstAdmin() string {
+	for _, a := range c.Agents.Admins {
+		if !strings.HasSuffix(a, "*") {
+			return a
+		}
+	}
+	return ""
+}
+
+// SessionsConfig controls session persistence and cleanup.
+type SessionsConfig struct {
+	// RetentionDays deletes sessions not touched for this many days (0 keeps all).
+	RetentionDays int           `json:"retentionDays" envconfig:"RETENTION_DAYS"`
+	GCInterval    time.Duration `json:"gcInterval" envconfig:"GC_INTERVAL"`
+}
+
+// TimelineConfig selects the timeline database and controls its retention.
+type TimelineConfig struct {
+	// Driver is "sqlite" (default) or "postgres". Tenants always use SQLite
+	// in their own directory.
+	Driver string `json:"driver,omitempty" envconfig:"DRIVER"`
+	// Path is the SQLite database file.
+	Path string `json:"path" envconfig:"DB_PATH"`
+	// DSN is the Postgres connection string, e.g.
+	// postgres://gomikrobot:${secret:pg}@db:5432/gomikrobot?sslmode=disable.
+	DSN string `json:"dsn,omitempty" envconfig:"DSN"`
+	// RetentionDays keeps this many days online (0 keeps everything).
+	RetentionDays int `json:"retentionDays" envconfig:"RETENTION_DAYS"`
+	// Archive writes pruned rows to gzipped JSONL under <workspace>/archive.
+	Archive  bool          `json:"archive" envconfig:"ARCHIVE"`
+	Interval time.Duration `json:"interval" envconfig:"INTERVAL"`
+}
+
+// BackupConfig makes scheduled backups of the config, timeline, sessions,
+// and workspace memory while the gateway runs. Encrypted backups take their
+// passphrase from MIKROBOT_BACKUP_PASSPHRASE.
+type BackupConfig struct {
+	Enabled  bool          `json:"enabled" envconfig:"ENABLED"`
+	Interval time.Duration `json:"interval" envconfig:"INTERVAL"`
+	Dir      string        `json:"dir" envconfig:"DIR"`
+	// Keep deletes all but this many newest backups (0 keeps all).
+	Keep    int  `json:"keep" envconfig:"KEEP"`
+	Encrypt bool `json:"encrypt" envconfig:"ENCRYPT"`
+}
+
+// AuditConfig records every LLM request and response, with secrets
+// redacted, to rotating JSONL files.
+type AuditConfig struct {
+	Enabled bool   `json:"enabled" envconfig:"ENABLED"`
+	Dir     string `json:"dir" envconfig:"DIR"`
+	// MaxFileMB starts a new file once the current one reaches this size.
+	MaxFileMB int `json:"maxFileMB" envconfig:"MAX_FILE_MB"`
+	// RetentionDays deletes older audit files (0 keeps all).
+	RetentionDays int `json:"retentionDays" envconfig:"RETENTION_DAYS"`
+}
+
+// PricingConfig estimates LLM spend from recorded token usage.
+type PricingConfig struct {
+	// Models adds or overrides prices, keyed by model name or name prefix.
+	Models map[string]ModelPrice `json:"models,omitempty" ignored:"true"`
+	// MonthlyBudget in USD triggers one alert per month when the estimated
+	// spend reaches it (0 disables).
+	MonthlyBudget float64 `json:"monthlyBudget" envconfig:"MONTHLY_BUDGET"`
+	// AlertSession receives budget alerts, e.g. "whatsapp:4917…@s.whatsapp.net".
+	// Defaults to the first admin session without a wildcard.
+	AlertSession string `json:"alertSession" envconfig:"ALERT_SESSION"`
+}
+
+// ModelPrice is a model's price in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// DigestConfig configures the weekly activity digest sent to the owner.
+type DigestConfig struct {
+	Enabled bool   `json:"enabled" envconfig:"ENABLED"`
+	Day     string `json:"day" envconfig:"DAY"`   // Weekday, e.g. "monday"
+	Time    string `json:"time" envconfig:"TIME"` // Local time, "HH:MM"
+	// Sections to include: messages, tools, errors, spend, memory (empty = all).
+	Sections []string `json:"sections,omitempty"`
+	// Channel is "email" or a chat channel name such as "whatsapp" or "slack".
+	Channel string      `json:"channel" envconfig:"CHANNEL"`
+	ChatID  string      `json:"chatId" envconfig:"CHAT_ID"`
+	Email   EmailConfig `json:"email"`
+}
+
+// EmailConfig contains SMTP settings for outgoing mail.
+type EmailConfig struct {
+	To       string `json:"to" envconfig:"EMAIL_TO"`
+	From     string `json:"from" envconfig:"EMAIL_FROM"`
+	SMTPHost string `json:"smtpHost" envconfig:"SMTP_HOST"`
+	SMTPPort int    `json:"smtpPort" envconfig:"SMTP_PORT"`
+	Username string `json:"username,omitempty" envconfig:"SMTP_USERNAME"`
+	Password string `json:"password,omitempty" envconfig:"SMTP_PASSWORD"`
+}
+
+// ProxyConfig configures the provider pass-through proxy (/proxy/v1/).
+type ProxyConfig struct {
+	Enabled bool       `json:"enabled" envconfig:"ENABLED"`
+	Keys    []ProxyKey `json:"keys"`
+}
+
+// ProxyKey is a client credential for the provider proxy.
+type ProxyKey struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// MonthlyTokenLimit caps prompt+completion tokens per calendar month (0 = unlimited).
+	MonthlyTokenLimit int `json:"monthlyTokenLimit,omitempty"`
+	// AllowedModels restricts the models a key may request (empty = any).
+	AllowedModels []string `json:"allowedModels,omitempty"`
 }
 
 // ToolsConfig contains tool-specific settings.
 type ToolsConfig struct {
 	Exec ExecToolConfig `json:"exec"`
 	Web  WebToolConfig  `json:"web"`
+	// Code configures run_code for Python and JavaScript snippets.
+	Code CodeToolConfig `json:"code"`
+	// Calendar enables calendar_list, calendar_create, and calendar_update.
+	Calendar CalendarToolConfig `json:"calendar"`
+	// Desktop enables notify, clipboard, and open on the local machine.
+	Desktop DesktopToolConfig `json:"desktop"`
+	// HTTP configures http_request; it is only enabled with an allowlist.
+	HTTP HTTPToolConfig `json:"http"`
+	// Serve configures the standalone `serve-tools` mode.
+	Serve ServeToolsConfig `json:"serve"`
+	// Remote lists tool servers whose tools are added to the agent.
+	Remote []RemoteToolsConfig `json:"remote,omitempty"`
+	// Plugins are tool executables discovered at startup.
+	Plugins PluginsConfig `json:"plugins"`
+}
+
+// PluginsConfig configures tool plugins: executables in Dir that serve
+// tools as JSON-RPC over stdin and stdout (see internal/toolrpc). Each is
+// started with the gateway; its tools are named "<file name>_<tool>".
+type PluginsConfig struct {
+	Enabled bool   `json:"enabled" envconfig:"ENABLED"`
+	Dir     string `json:"dir" envconfig:"DIR"`
+	// Disabled lists plugin names (file names without extension) to skip.
+	Disabled []string `json:"disabled,omitempty"`
+}
+
+// ServeToolsConfig configures the tool server started by `serve-tools`.
+type ServeToolsConfig struct {
+	Addr  string `json:"addr" envconfig:"ADDR"`
+	Token string `json:"token" envconfig:"TOKEN"`
+}
+
+// RemoteToolsConfig points the agent at a `serve-tools` instance.
+type RemoteToolsConfig struct {
+	// Name prefixes the remote tool names, e.g. "nas" → nas_read_file.
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Token string `json:"token"`
 }
 
 // ExecToolConfig contains shell execution tool settings.
 type ExecToolConfig struct {
 	Timeout             time.Duration `json:"timeout"`
 	RestrictToWorkspace bool          `json:"restrictToWorkspace" envconfig:"EXEC_RESTRICT_WORKSPACE"`
+	// Shell runs commands as `<shell> -c <command>` (default "sh").
+	Shell string `json:"shell,omitempty" envconfig:"SHELL"`
+	// EnvAllow limits the variables commands inherit (empty = all);
+	// EnvDeny removes variables, defaulting to credential-like names such
+	// as *_API_KEY and MIKROBOT_*. Both accept * wildcards.
+	EnvAllow []string `json:"envAllow,omitempty" envconfig:"ENV_ALLOW"`
+	EnvDeny  []string `json:"envDeny,omitempty" envconfig:"ENV_DENY"`
+	// Sandbox runs commands in a container instead of the host shell.
+	Sandbox ExecSandboxConfig `json:"sandbox"`
+}
+
+// ExecSandboxConfig runs exec commands in a Docker or Podman container with
+// the workspace mounted, for chats that should not reach the host.
+type ExecSandboxConfig struct {
+	Enabled bool   `json:"enabled" envconfig:"ENABLED"`
+	Runtime string `json:"runtime" envconfig:"RUNTIME"` // docker or podman
+	Image   string `json:"image" envconfig:"IMAGE"`
+	Network bool   `json:"network" envconfig:"NETWORK"` // Off by default
+	Memory  string `json:"memory,omitempty" envconfig:"MEMORY"`
+	CPUs    string `json:"cpus,omitempty" envconfig:"CPUS"`
+	// Sessions selects the sandboxed sessions ("whatsapp:*"); empty means all.
+	Sessions []string `json:"sessions,omitempty" envconfig:"SESSIONS"`
+}
+
+// CodeToolConfig governs the run_code tool. Snippets run on the host with
+// the gateway's file and network access, so it is off by default, never
+// offered to tenants, and refused in sessions exec sandboxes.
+type CodeToolConfig struct {
+	Enabled bool          `json:"enabled" envconfig:"ENABLED"`
+	Timeout time.Duration `json:"timeout" envconfig:"TIMEOUT"`
+	// Python and Node name the interpreters (default python3 and node).
+	Python   string `json:"python,omitempty" envconfig:"PYTHON"`
+	Node     string `json:"node,omitempty" envconfig:"NODE"`
+	MemoryMB int    `json:"memoryMB" envconfig:"MEMORY_MB"`
+}
+
+// CalendarToolConfig connects the calendar tools to a CalDAV calendar, or
+// to Google Calendar via OAuth. They are enabled when URL or a Google
+// refresh token is set.
+type CalendarToolConfig struct {
+	// URL of the CalDAV calendar collection.
+	URL      string `json:"url,omitempty" envconfig:"URL"`
+	Username string `json:"username,omitempty" envconfig:"USERNAME"`
+	Password string `json:"password,omitempty" envconfig:"PASSWORD"`
+	// Timezone is an IANA name such as "Europe/Berlin" used to read and
+	// show times; empty means the system timezone.
+	Timezone string               `json:"timezone,omitempty" envconfig:"TIMEZONE"`
+	Google   GoogleCalendarConfig `json:"google"`
+}
+
+// GoogleCalendarConfig reaches Google Calendar through its CalDAV endpoint
+// with an OAuth client and a refresh token with the calendar scope.
+type GoogleCalendarConfig struct {
+	ClientID     string `json:"clientId,omitempty" envconfig:"CLIENT_ID"`
+	ClientSecret string `json:"clientSecret,omitempty" envconfig:"CLIENT_SECRET"`
+	RefreshToken string `json:"refreshToken,omitempty" envconfig:"REFRESH_TOKEN"`
+	// CalendarID is the calendar's ID; for the main calendar, the
+	// account's email address.
+	CalendarID string `json:"calendarId,omitempty" envconfig:"CALENDAR_ID"`
+}
+
+// DesktopToolConfig enables tools that act on the computer running the
+// bot. They are off by default, since every chat could use them.
+type DesktopToolConfig struct {
+	Enabled bool `json:"enabled" envconfig:"ENABLED"`
+}
+
+// HTTPToolConfig governs the http_request tool.
+type HTTPToolConfig struct {
+	// AllowedDomains lists callable hosts; "*.example.com" includes subdomains
+	// and "host:port" restricts to one port.
+	AllowedDomains   []string      `json:"allowedDomains,omitempty"`
+	MaxResponseBytes int64         `json:"maxResponseBytes" envconfig:"MAX_RESPONSE_BYTES"`
+	Timeout          time.Duration `json:"timeout" envconfig:"TIMEOUT"`
+	// Headers are added to requests per allowlist entry, e.g. an
+	// Authorization header for Home Assistant.
+	Headers map[string]map[string]string `json:"headers,omitempty"`
 }
 
 // WebToolConfig contains web tool settings.
@@ -140,6 +865,18 @@ func DefaultConfig() *Config {
 				MaxTokens:         8192,
 				Temperature:       0.7,
 				MaxToolIterations: 20,
+				MaxParallelTools:  4,
+				ToolTimeout:       120 * time.Second,
+				MaxToolCalls:      40,
+				TurnTimeout:       5 * time.Minute,
+
+				MaxConcurrentSessions: 4,
+				MaxConcurrentTasks:    2,
+				TaskTimeout:           30 * time.Minute,
+				TaskMaxToolCalls:      100,
+				MaxToolResultChars:    16000,
+				HistoryMessages:       50,
+				Critic:                CriticConfig{Threshold: 6},
 			},
 		},
 		Providers: ProvidersConfig{
@@ -149,25 +886,158 @@ func DefaultConfig() *Config {
 				BinaryPath: "/opt/homebrew/bin/whisper",
 			},
 		},
+		Transcription: TranscriptionConfig{
+			Language:  "de",
+			Workers:   2,
+			QueueSize: 32,
+			Timeout:   5 * time.Minute,
+		},
+		Channels: ChannelsConfig{
+			WhatsApp: WhatsAppConfig{
+				Media:           DefaultMediaPolicy(),
+				TypingIndicator: true,
+				ReadReceipts:    true,
+				Groups:          GroupPolicy{Enabled: true},
+				Outbound: OutboundPolicy{
+					RatePerSecond: 0.5,
+					Burst:         3,
+					MaxChars:      4000,
+					Retries:       3,
+					QueueSize:     100,
+					CodeFileChars: 1500,
+				},
+			},
+			Slack: SlackConfig{
+				ReplyInThread: true,
+				Media:         DefaultMediaPolicy(),
+				// Slack allows about one message per second per channel.
+				Outbound: OutboundPolicy{
+					RatePerSecond: 1,
+					Burst:         3,
+					MaxChars:      3900,
+					Retries:       3,
+					QueueSize:     100,
+				},
+			},
+			Voice: VoiceConfig{
+				Media: DefaultMediaPolicy(),
+			},
+			Signal: SignalConfig{
+				URL:   "http://127.0.0.1:8080",
+				Media: DefaultMediaPolicy(),
+				// Signal rate-limits bursts from linked devices.
+				Outbound: OutboundPolicy{
+					RatePerSecond: 0.5,
+					Burst:         3,
+					MaxChars:      2000,
+					Retries:       3,
+					QueueSize:     100,
+					CodeFileChars: 1500,
+				},
+			},
+		},
 		Gateway: GatewayConfig{
-			Host:           "127.0.0.1", // Secure default
-			Port:           18790,
-			DashboardPort:  18791,
-			RateLimitRPS:   5,            // 5 req/sec per client IP
-			RateLimitBurst: 10,           // allow short bursts
-			MaxBodyBytes:   10 << 20,     // 10 MiB
+			Host:            "127.0.0.1", // Secure default
+			Port:            18790,
+			DashboardPort:   18791,
+			SessionTTL:      7 * 24 * time.Hour,
+			TrustedProxies:  []string{"127.0.0.1", "::1"},
+			RateLimitRPS:    5,                // 5 req/sec per client IP
+			RateLimitBurst:  10,               // allow short bursts
+			MaxBodyBytes:    10 << 20,         // 10 MiB
 			ShutdownTimeout: 10 * time.Second, // graceful drain
+			DedupWindow:     10 * time.Minute, // bridge reconnects redeliver recent messages
+			Ready: ReadyConfig{
+				MinFreeDiskMB: 500,
+				Timeout:       5 * time.Second,
+				CacheTTL:      15 * time.Second,
+			},
+		},
+		Bus: BusConfig{
+			Prefix: "gomikrobot",
+		},
+		Sessions: SessionsConfig{
+			GCInterval: time.Hour,
+		},
+		Timeline: TimelineConfig{
+			Path:     "~/.gomikrobot/timeline.db",
+			Archive:  true,
+			Interval: 24 * time.Hour,
+		},
+		Backup: BackupConfig{
+			Interval: 24 * time.Hour,
+			Dir:      "~/.gomikrobot/backups",
+			Keep:     7,
+		},
+		Digest: DigestConfig{
+			Day:  "monday",
+			Time: "08:00",
+			Email: EmailConfig{
+				SMTPPort: 587,
+			},
+		},
+		Audit: AuditConfig{
+			Dir:           "~/.gomikrobot/audit",
+			MaxFileMB:     10,
+			RetentionDays: 14,
+		},
+		Feeds: FeedsConfig{
+			Interval: 30 * time.Minute,
+		},
+		Knowledge: KnowledgeConfig{
+			Collection:     "knowledge",
+			EmbeddingModel: "text-embedding-3-small",
+			Dimensions:     1536,
+			ChunkSize:      1500,
+			ChunkOverlap:   200,
+		},
+		Embeddings: EmbeddingsConfig{
+			Provider:  "openai",
+			BatchSize: 64,
+			CacheSize: 1000,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "gomikrobot",
+			SampleRatio: 1,
+		},
+		Secrets: SecretsConfig{
+			File: "~/.gomikrobot/secrets.enc",
+		},
+		Classification: ClassificationConfig{
+			Enabled: true,
+			Routes:  map[string]string{"spam": "ignore", "emergency": "priority"},
 		},
 		Tools: ToolsConfig{
 			Exec: ExecToolConfig{
 				Timeout:             60 * time.Second,
 				RestrictToWorkspace: true, // Secure default
+				Sandbox: ExecSandboxConfig{
+					Runtime: "docker",
+					Image:   "alpine:3",
+					Memory:  "512m",
+					CPUs:    "1",
+				},
+			},
+			Code: CodeToolConfig{
+				Timeout:  30 * time.Second,
+				MemoryMB: 256,
+			},
+			HTTP: HTTPToolConfig{
+				MaxResponseBytes: 256 << 10,
+				Timeout:          30 * time.Second,
 			},
 			Web: WebToolConfig{
 				Search: SearchConfig{
 					MaxResults: 10,
 				},
 			},
+			Serve: ServeToolsConfig{
+				Addr: "127.0.0.1:18795",
+			},
+			Plugins: PluginsConfig{
+				Enabled: true,
+				Dir:     "~/.gomikrobot/plugins",
+			},
 		},
 	}
 }
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
@@ -114,3 +115,33 @@ func TestEnvOverride(t *testing.T) {
 		t.Errorf("expected port 8080 from env, got %d", cfg.Gateway.Port)
 	}
 }
+
+func TestWatchReloadsOnChange(t *testing.T) {
+	tmpDir := t.TempDir()
+	configDir := filepath.Join(tmpDir, ".gomikrobot")
+	os.MkdirAll(configDir, 0755)
+	configFile := filepath.Join(configDir, "config.json")
+	os.WriteFile(configFile, []byte(`{"gateway": {"port": 9000}}`), 0600)
+
+	origHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", origHome)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan *Config, 1)
+	go Watch(ctx, 10*time.Millisecond, func(cfg *Config) { changes <- cfg })
+
+	time.Sleep(30 * time.Millisecond)
+	os.WriteFile(configFile, []byte(`{"gateway": {"port": 9001, "rateLimitRps": 2}}`), 0600)
+
+	select {
+	case cfg := <-changes:
+		if cfg.Gateway.Port != 9001 || cfg.Gateway.RateLimitRPS != 2 {
+			t.Errorf("expected reloaded values, got port %d rps %v", cfg.Gateway.Port, cfg.Gateway.RateLimitRPS)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a reload after the config file changed")
+	}
+}
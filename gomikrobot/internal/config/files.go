@@ -0,0 +1,305 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/secrets"
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// configNames are the config file names looked for in ConfigDir, in order
+// of preference.
+var configNames = []string{ConfigFile, "config.yaml", "config.yml", "config.toml"}
+
+// includeKey lists further files to merge into a config file. Paths are
+// relative to the including file; the including file's own values win.
+const includeKey = "include"
+
+// readFile decodes a JSON, YAML or TOML file, chosen by extension, into
+// generic values.
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &out); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		if out, err = parseTOML(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&out); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	if out == nil {
+		out = map[string]any{}
+	}
+	return out, nil
+}
+
+// readTree reads path with its includes merged in and ${VAR} references
+// expanded. ${secret:NAME} references are looked up in the secrets store
+// when resolveSecrets is set and kept as they are otherwise. It also
+// returns every file read, for watching.
+func readTree(path string, resolveSecrets bool) (map[string]any, []string, error) {
+	var files []string
+	tree, err := readIncludes(path, map[string]bool{}, &files)
+	if err != nil {
+		return nil, files, err
+	}
+	var store secrets.Store
+	secret := func(name string) (string, error) {
+		if !resolveSecrets {
+			return secretPrefix + name + "}", nil
+		}
+		if store == nil {
+			if store, err = openSecrets(tree); err != nil {
+				return "", err
+			}
+		}
+		return store.Get(name)
+	}
+	expanded, err := interpolate(tree, "", secret)
+	if err != nil {
+		return nil, files, err
+	}
+	return expanded.(map[string]any), files, nil
+}
+
+// secretPrefix starts a reference to a secret in the secrets store.
+const secretPrefix = "${secret:"
+
+// openSecrets opens the store named in the tree's secrets section.
+func openSecrets(tree map[string]any) (secrets.Store, error) {
+	sc := DefaultConfig().Secrets
+	if section, ok := tree["secrets"]; ok {
+		data, err := json.Marshal(section)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &sc); err != nil {
+			return nil, fmt.Errorf("secrets: %w", err)
+		}
+	}
+	return OpenSecrets(sc)
+}
+
+// OpenSecrets opens the secrets store sc describes, with MIKROBOT_SECRETS_*
+// overrides.
+func OpenSecrets(sc SecretsConfig) (secrets.Store, error) {
+	envconfig.Process("MIKROBOT_SECRETS", &sc)
+	if strings.HasPrefix(sc.File, "~") {
+		home, _ := os.UserHomeDir()
+		sc.File = filepath.Join(home, sc.File[1:])
+	}
+	return secrets.Open(sc.Backend, sc.File)
+}
+
+func readIncludes(path string, seen map[string]bool, files *[]string) (map[string]any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if seen[abs] {
+		return nil, fmt.Errorf("%s is included twice", path)
+	}
+	seen[abs] = true
+	*files = append(*files, abs)
+
+	tree, err := readFile(abs)
+	if err != nil {
+		return nil, err
+	}
+	includes, err := includeList(tree[includeKey])
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	delete(tree, includeKey)
+
+	merged := map[string]any{}
+	for _, inc := range includes {
+		if strings.HasPrefix(inc, "~") {
+			home, _ := os.UserHomeDir()
+			inc = filepath.Join(home, inc[1:])
+		} else if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(abs), inc)
+		}
+		sub, err := readIncludes(inc, seen, files)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", inc, err)
+		}
+		merge(merged, sub)
+	}
+	merge(merged, tree)
+	return merged, nil
+}
+
+func includeList(v any) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must list file paths", includeKey)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("%s must be a path or a list of paths", includeKey)
+}
+
+// merge copies src into dst. Sections are merged key by key; everything
+// else, lists included, is replaced.
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		if sm, ok := v.(map[string]any); ok {
+			if dm, ok := dst[k].(map[string]any); ok {
+				merge(dm, sm)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// interpolate replaces ${VAR} and ${VAR:-default} in string values with
+// environment variables and ${secret:NAME} with the result of secret.
+// "$${" produces a literal "${". A variable that is unset and has no
+// default is an error, so typos do not become empty secrets.
+func interpolate(v any, path string, secret func(name string) (string, error)) (any, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, item := range v {
+			out, err := interpolate(item, joinKey(path, k), secret)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = out
+		}
+		return v, nil
+	case []any:
+		for i, item := range v {
+			out, err := interpolate(item, fmt.Sprintf("%s.%d", path, i), secret)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = out
+		}
+		return v, nil
+	case string:
+		return expandEnv(v, path, secret)
+	}
+	return v, nil
+}
+
+func joinKey(path, k string) string {
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
+
+func expandEnv(s, path string, secret func(name string) (string, error)) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("%s: unterminated ${ in %q", path, s)
+		}
+		b.WriteString(s[:i])
+		if name, ok := strings.CutPrefix(s[i:i+end], secretPrefix); ok {
+			val, err := secret(name)
+			if err != nil {
+				return "", fmt.Errorf("%s: secret %s: %w", path, name, err)
+			}
+			b.WriteString(val)
+			s = s[i+end+1:]
+			continue
+		}
+		name, def, hasDef := strings.Cut(s[i+2:i+end], ":-")
+		val, ok := os.LookupEnv(name)
+		switch {
+		case ok && (val != "" || !hasDef):
+			b.WriteString(val)
+		case hasDef:
+			b.WriteString(def)
+		default:
+			return "", fmt.Errorf("%s: environment variable %s is not set", path, name)
+		}
+		s = s[i+end+1:]
+	}
+}
+
+// decodeTree decodes a generic tree into cfg. With strict set, unknown
+// keys are an error.
+func decodeTree(tree map[string]any, cfg *Config, strict bool) error {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(cfg)
+}
+
+// Editable reports why the config file at path cannot be rewritten by
+// Save without losing information, or nil if it can. Only plain JSON
+// files without includes or ${VAR} references are rewritten; secret
+// references are kept when the config was loaded with LoadForEdit.
+func Editable(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" {
+		return fmt.Errorf("%s is %s; edit it by hand", path, strings.TrimPrefix(ext, "."))
+	}
+	var top map[string]json.RawMessage
+	if json.Unmarshal(data, &top) == nil && top[includeKey] != nil {
+		return fmt.Errorf("%s uses %s; edit it by hand", path, includeKey)
+	}
+	if bytes.Contains(bytes.ReplaceAll(data, []byte(secretPrefix), nil), []byte("${")) {
+		return fmt.Errorf("%s references environment variables; edit it by hand", path)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamir/gomikrobot/internal/secrets"
+)
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadYAMLWithIncludeAndEnv(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TEST_SLACK_BOT", "xoxb-from-env")
+	dir := filepath.Join(home, ConfigDir)
+	os.MkdirAll(dir, 0700)
+
+	writeConfigFile(t, dir, "secrets.toml", `
+# Channel secrets live here.
+[channels.slack]
+appToken = "xapp-secret"
+botToken = "${TEST_SLACK_BOT}"
+
+[gateway]
+port = 1111
+`)
+	writeConfigFile(t, dir, "config.yaml", `
+include: secrets.toml
+gateway:
+  port: 9000
+channels:
+  slack:
+    enabled: true
+    allowFrom: [U1, U2]
+  webhooks:
+    - name: ci
+      template: "cost: $${AMOUNT} for ${MISSING_VAR:-nobody}"
+`)
+
+	if path, _ := ConfigPath(); filepath.Base(path) != "config.yaml" {
+		t.Fatalf("ConfigPath() = %s, want config.yaml", path)
+	}
+	cfg, err := LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl := cfg.Channels.Slack
+	if !sl.Enabled || sl.AppToken != "xapp-secret" || sl.BotToken != "xoxb-from-env" || len(sl.AllowFrom) != 2 {
+		t.Errorf("slack not merged from include: %+v", sl)
+	}
+	if cfg.Gateway.Port != 9000 {
+		t.Errorf("including file should win, got port %d", cfg.Gateway.Port)
+	}
+	if cfg.Gateway.Host != "127.0.0.1" {
+		t.Errorf("defaults lost: host %q", cfg.Gateway.Host)
+	}
+	if w := cfg.Channels.Webhooks; len(w) != 1 || w[0].Template != "cost: ${AMOUNT} for nobody" {
+		t.Errorf("unexpected webhooks: %+v", w)
+	}
+
+	if issues, err := ValidateFile(filepath.Join(dir, "config.yaml")); err != nil || len(issues) != 0 {
+		t.Errorf("ValidateFile() = %v, %v", issues, err)
+	}
+	if err := Save(cfg); err == nil {
+		t.Error("Save should refuse to overwrite a YAML config")
+	}
+}
+
+func TestLoadReportsMissingEnvAndUnknownKeys(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.toml", "[providers.openai]\napiKey = \"${TEST_UNSET_KEY}\"\n")
+	if _, _, err := readTree(path, true); err == nil || !strings.Contains(err.Error(), "TEST_UNSET_KEY") {
+		t.Errorf("expected an unset variable error, got %v", err)
+	}
+
+	path = writeConfigFile(t, dir, "bad.yaml", "gateway:\n  prot: 1\n")
+	issues, err := ValidateFile(path)
+	if err != nil || len(issues) != 1 || !strings.Contains(issues[0].Message, "prot") {
+		t.Errorf("expected an unknown key issue, got %v, %v", issues, err)
+	}
+
+	a := writeConfigFile(t, dir, "a.json", `{"include": "b.json"}`)
+	writeConfigFile(t, dir, "b.json", `{"include": ["a.json"]}`)
+	if _, _, err := readTree(a, true); err == nil || !strings.Contains(err.Error(), "included twice") {
+		t.Errorf("expected an include cycle error, got %v", err)
+	}
+}
+
+func TestParseTOML(t *testing.T) {
+	got, err := parseTOML([]byte(`
+title = 'literal \n kept'
+ratio = 0.5
+big = 1_000
+hex = 0x10
+flags = [true, false,
+  true,]
+
+[agents.defaults]
+model = "gpt-4o" # trailing comment
+"quoted key" = "a\tbé"
+
+[[proxy.keys]]
+name = "one"
+limits = { rps = 2, burst = 5 }
+
+[[proxy.keys]]
+name = "two"
+notes = """
+first line
+second"""
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["title"] != `literal \n kept` || got["ratio"] != 0.5 || got["big"] != int64(1000) || got["hex"] != int64(16) {
+		t.Errorf("unexpected scalars: %v", got)
+	}
+	if flags := got["flags"].([]any); len(flags) != 3 || flags[2] != true {
+		t.Errorf("unexpected array: %v", flags)
+	}
+	defaults := got["agents"].(map[string]any)["defaults"].(map[string]any)
+	if defaults["model"] != "gpt-4o" || defaults["quoted key"] != "a\tbé" {
+		t.Errorf("unexpected table: %v", defaults)
+	}
+	keys := got["proxy"].(map[string]any)["keys"].([]any)
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 keys, got %v", keys)
+	}
+	if k := keys[0].(map[string]any); k["limits"].(map[string]any)["burst"] != int64(5) {
+		t.Errorf("unexpected inline table: %v", k)
+	}
+	if k := keys[1].(map[string]any); k["notes"] != "first line\nsecond" {
+		t.Errorf("unexpected multi-line string: %q", k["notes"])
+	}
+
+	for _, bad := range []string{"a = ", "a = 1\na = 2", "[x\n", `s = "open`, "a = 1 b = 2"} {
+		if _, err := parseTOML([]byte(bad)); err == nil {
+			t.Errorf("parseTOML(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestSecretReferences(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MIKROBOT_SECRETS_PASSPHRASE", "test passphrase")
+	dir := filepath.Join(home, ConfigDir)
+	os.MkdirAll(dir, 0700)
+
+	store := &secrets.File{Path: filepath.Join(dir, "secrets.enc"), Passphrase: "test passphrase"}
+	if err := store.Set("openai.apiKey", "sk-secret"); err != nil {
+		t.Fatal(err)
+	}
+	path := writeConfigFile(t, dir, "config.json", `{
+  "secrets": {"backend": "file"},
+  "providers": {"openai": {"apiKey": "${secret:openai.apiKey}"}},
+  "channels": {"telegram": {"token": "${secret:telegram.token}"}}
+}`)
+	if _, err := LoadFile(); err == nil || !strings.Contains(err.Error(), "telegram.token") {
+		t.Fatalf("expected a missing secret error, got %v", err)
+	}
+	if err := store.Set("telegram.token", "123:abc"); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Providers.OpenAI.APIKey != "sk-secret" || cfg.Channels.Telegram.Token != "123:abc" {
+		t.Errorf("secrets not resolved: %q %q", cfg.Providers.OpenAI.APIKey, cfg.Channels.Telegram.Token)
+	}
+
+	edit, err := LoadForEdit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	edit.Gateway.Port = 9000
+	if err := Save(edit); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "sk-secret") || !strings.Contains(string(data), "${secret:openai.apiKey}") {
+		t.Errorf("Save wrote a resolved secret:\n%s", data)
+	}
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// keyPath resolves a dotted key such as "gateway.port" or
+// "channels.webhooks.0.name" against the Config type. Segments match JSON
+// names case-insensitively; the canonical names are returned along with
+// the type of the value the key refers to.
+func keyPath(key string) ([]string, reflect.Type, error) {
+	if key == "" {
+		return nil, nil, fmt.Errorf("empty key")
+	}
+	t := reflect.TypeOf(Config{})
+	var path []string
+	for _, seg := range strings.Split(key, ".") {
+		switch t.Kind() {
+		case reflect.Struct:
+			name, ft, ok := jsonField(t, seg)
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown key %q", strings.Join(append(path, seg), "."))
+			}
+			path, t = append(path, name), ft
+		case reflect.Slice:
+			if _, err := strconv.Atoi(seg); err != nil {
+				return nil, nil, fmt.Errorf("%s is a list; use a numeric index instead of %q", strings.Join(path, "."), seg)
+			}
+			path, t = append(path, seg), t.Elem()
+		case reflect.Map:
+			path, t = append(path, seg), t.Elem()
+		default:
+			return nil, nil, fmt.Errorf("%s has no field %q", strings.Join(path, "."), seg)
+		}
+	}
+	return path, t, nil
+}
+
+// jsonField finds the struct field with JSON name seg.
+func jsonField(t reflect.Type, seg string) (string, reflect.Type, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.EqualFold(name, seg) {
+			return name, f.Type, true
+		}
+	}
+	return "", nil, false
+}
+
+// tree returns cfg as generic JSON values.
+func tree(cfg *Config) (map[string]any, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return out, dec.Decode(&out)
+}
+
+// GetKey returns the value at a dotted key, formatted for display:
+// strings and durations as text, everything else as JSON.
+func GetKey(cfg *Config, key string) (string, error) {
+	path, t, err := keyPath(key)
+	if err != nil {
+		return "", err
+	}
+	root, err := tree(cfg)
+	if err != nil {
+		return "", err
+	}
+	var v any = root
+	for i, seg := range path {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[seg]
+		case []any:
+			n, _ := strconv.Atoi(seg)
+			if n < 0 || n >= len(node) {
+				return "", fmt.Errorf("%s has %d entries", strings.Join(path[:i], "."), len(node))
+			}
+			v = node[n]
+		default:
+			v = nil
+		}
+		if v == nil {
+			break
+		}
+	}
+
+	switch {
+	case v == nil:
+		return "", nil
+	case t == durationType:
+		n, _ := v.(json.Number).Int64()
+		return time.Duration(n).String(), nil
+	case t.Kind() == reflect.String:
+		return v.(string), nil
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	return string(data), err
+}
+
+// SetKey sets the value at a dotted key. value is coerced to the field's
+// type: "true"/"false" for booleans, "90s" or "5m" for durations, comma
+// separated items or a JSON array for string lists, and JSON for other
+// lists, maps and sections. An index one past the end appends to a list.
+func SetKey(cfg *Config, key, value string) error {
+	path, t, err := keyPath(key)
+	if err != nil {
+		return err
+	}
+	v, err := coerce(t, value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+	}
+	root, err := tree(cfg)
+	if err != nil {
+		return err
+	}
+	if _, err := setPath(root, path, v); err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+	}
+
+	data, err := json.Marshal(root)
+	if err != nil {
+		return err
+	}
+	var next Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&next); err != nil {
+		return fmt.Errorf("%s: %w", strings.Join(path, "."), err)
+	}
+	*cfg = next
+	return nil
+}
+
+// setPath stores v under path in a generic JSON tree and returns the
+// updated node. Missing objects and lists are created on the way.
+func setPath(node any, path []string, v any) (any, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+	seg := path[0]
+	switch n := node.(type) {
+	case nil:
+		if _, err := strconv.Atoi(seg); err == nil {
+			return setPath([]any{}, path, v)
+		}
+		return setPath(map[string]any{}, path, v)
+	case map[string]any:
+		child, err := setPath(n[seg], path[1:], v)
+		if err != nil {
+			return nil, err
+		}
+		n[seg] = child
+		return n, nil
+	case []any:
+		idx, _ := strconv.Atoi(seg)
+		if idx == len(n) {
+			n = append(n, nil)
+		}
+		if idx < 0 || idx >= len(n) {
+			return nil, fmt.Errorf("index %d is out of range (%d entries)", idx, len(n))
+		}
+		child, err := setPath(n[idx], path[1:], v)
+		if err != nil {
+			return nil, err
+		}
+		n[idx] = child
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot set %s inside a plain value", seg)
+}
+
+// coerce converts a command-line value to a JSON value of type t.
+func coerce(t reflect.Type, value string) (any, error) {
+	if t == durationType {
+		if d, err := time.ParseDuration(value); err == nil {
+			return int64(d), nil
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n, nil
+		}
+		return nil, fmt.Errorf("%q is not a duration (e.g. 30s, 5m, 2h)", value)
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return value, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", value)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a whole number", value)
+		}
+		return n, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a non-negative whole number", value)
+		}
+		return n, nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, t.Bits())
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return f, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			items := []any{}
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					items = append(items, s)
+				}
+			}
+			return items, nil
+		}
+	}
+	// Lists, maps and sections take JSON of the right shape.
+	ptr := reflect.New(t)
+	dec := json.NewDecoder(strings.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(ptr.Interface()); err != nil {
+		return nil, fmt.Errorf("expected JSON for %s: %w", t, err)
+	}
+	var v any
+	data, _ := json.Marshal(ptr.Interface())
+	json.Unmarshal(data, &v)
+	return v, nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetKeyCoercesTypes(t *testing.T) {
+	cfg := DefaultConfig()
+	sets := [][2]string{
+		{"gateway.port", "9000"},
+		{"Channels.Telegram.Enabled", "true"},
+		{"tools.exec.timeout", "90s"},
+		{"channels.slack.allowFrom", "U1, U2"},
+		{"tracing.sampleRatio", "0.25"},
+		{"channels.webhooks.0", `{"name":"ci","template":"{{.Body}}"}`},
+		{"channels.webhooks.0.forwardChannel", "slack"},
+		{"pricing.models.gpt-x", `{"input":1,"output":2}`},
+	}
+	for _, s := range sets {
+		if err := SetKey(cfg, s[0], s[1]); err != nil {
+			t.Fatalf("SetKey(%s, %s): %v", s[0], s[1], err)
+		}
+	}
+	if cfg.Gateway.Port != 9000 || !cfg.Channels.Telegram.Enabled || cfg.Tools.Exec.Timeout != 90*time.Second {
+		t.Errorf("scalars not set: port %d, telegram %v, timeout %v", cfg.Gateway.Port, cfg.Channels.Telegram.Enabled, cfg.Tools.Exec.Timeout)
+	}
+	if a := cfg.Channels.Slack.AllowFrom; len(a) != 2 || a[1] != "U2" {
+		t.Errorf("unexpected allowFrom %q", a)
+	}
+	if cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("unexpected sample ratio %v", cfg.Tracing.SampleRatio)
+	}
+	if w := cfg.Channels.Webhooks; len(w) != 1 || w[0].Name != "ci" || w[0].ForwardChannel != "slack" {
+		t.Errorf("unexpected webhooks %+v", w)
+	}
+	if _, ok := cfg.Pricing.Models["gpt-x"]; !ok {
+		t.Errorf("model price not added: %+v", cfg.Pricing.Models)
+	}
+	// Unrelated values survive.
+	if cfg.Gateway.Host != "127.0.0.1" {
+		t.Errorf("gateway host changed to %q", cfg.Gateway.Host)
+	}
+
+	if got, err := GetKey(cfg, "tools.exec.timeout"); err != nil || got != "1m30s" {
+		t.Errorf("GetKey(timeout) = %q, %v", got, err)
+	}
+	if got, err := GetKey(cfg, "channels.webhooks.0.name"); err != nil || got != "ci" {
+		t.Errorf("GetKey(webhook name) = %q, %v", got, err)
+	}
+	if got, err := GetKey(cfg, "gateway.port"); err != nil || got != "9000" {
+		t.Errorf("GetKey(port) = %q, %v", got, err)
+	}
+}
+
+func TestSetKeyRejectsBadInput(t *testing.T) {
+	cfg := DefaultConfig()
+	for _, tc := range []struct{ key, value, want string }{
+		{"gateway.prot", "1", "unknown key"},
+		{"gateway.port", "high", "whole number"},
+		{"channels.telegram.enabled", "yes please", "true or false"},
+		{"tools.exec.timeout", "soon", "duration"},
+		{"channels.webhooks.3.name", "x", "out of range"},
+		{"channels.webhooks.0", `{"nmae":"x"}`, "unknown field"},
+	} {
+		err := SetKey(cfg, tc.key, tc.value)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("SetKey(%s, %s) = %v, want error containing %q", tc.key, tc.value, err, tc.want)
+		}
+	}
+	if cfg.Gateway.Port != 18790 {
+		t.Errorf("failed set changed the config: port %d", cfg.Gateway.Port)
+	}
+}
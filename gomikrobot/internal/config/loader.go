@@ -2,6 +2,8 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -16,44 +18,70 @@ const (
 	ConfigFile = "config.json"
 )
 
-// ConfigPath returns the path to the config file.
+// ConfigPath returns the path to the config file: the first of
+// config.json, config.yaml, config.yml and config.toml that exists, or
+// config.json if there is none.
 func ConfigPath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, ConfigDir, ConfigFile), nil
+	dir := filepath.Join(home, ConfigDir)
+	for _, name := range configNames {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return filepath.Join(dir, name), nil
+		}
+	}
+	return filepath.Join(dir, ConfigFile), nil
 }
 
 // Load loads the configuration from file and environment variables.
 // Priority: environment > file > defaults
 func Load() (*Config, error) {
-	cfg := DefaultConfig()
-
-	// Load from file
-	path, err := ConfigPath()
+	cfg, err := LoadFile()
 	if err != nil {
-		return cfg, nil // Use defaults if we can't find config path
+		return nil, err
 	}
 
-	data, err := os.ReadFile(path)
-	if err == nil {
-		if err := json.Unmarshal(data, cfg); err != nil {
-			return nil, err
-		}
-	}
-	// If file doesn't exist, continue with defaults
-
 	// Override with environment variables for each section
 	envconfig.Process("MIKROBOT_OPENAI", &cfg.Providers.OpenAI)
 	envconfig.Process("MIKROBOT_AGENTS", &cfg.Agents.Defaults)
+	envconfig.Process("MIKROBOT_AGENTS_CRITIC", &cfg.Agents.Defaults.Critic)
+	envconfig.Process("MIKROBOT_AGENTS_REFUSALS", &cfg.Agents.Defaults.Refusals)
 	envconfig.Process("MIKROBOT_CHANNELS_TELEGRAM", &cfg.Channels.Telegram)
 	envconfig.Process("MIKROBOT_CHANNELS_DISCORD", &cfg.Channels.Discord)
 	envconfig.Process("MIKROBOT_CHANNELS_WHATSAPP", &cfg.Channels.WhatsApp)
 	envconfig.Process("MIKROBOT_CHANNELS_FEISHU", &cfg.Channels.Feishu)
+	envconfig.Process("MIKROBOT_CHANNELS_SLACK", &cfg.Channels.Slack)
+	envconfig.Process("MIKROBOT_CHANNELS_SIGNAL", &cfg.Channels.Signal)
+	envconfig.Process("MIKROBOT_CHANNELS_VOICE", &cfg.Channels.Voice)
+	envconfig.Process("MIKROBOT_TRANSCRIPTION", &cfg.Transcription)
 	envconfig.Process("MIKROBOT_GATEWAY", &cfg.Gateway)
+	envconfig.Process("MIKROBOT_GATEWAY_TLS", &cfg.Gateway.TLS)
+	envconfig.Process("MIKROBOT_GATEWAY_READY", &cfg.Gateway.Ready)
+	envconfig.Process("MIKROBOT_BUS", &cfg.Bus)
 	envconfig.Process("MIKROBOT_TOOLS_EXEC", &cfg.Tools.Exec)
+	envconfig.Process("MIKROBOT_TOOLS_CODE", &cfg.Tools.Code)
+	envconfig.Process("MIKROBOT_TOOLS_CALENDAR", &cfg.Tools.Calendar)
+	envconfig.Process("MIKROBOT_TOOLS_CALENDAR_GOOGLE", &cfg.Tools.Calendar.Google)
+	envconfig.Process("MIKROBOT_TOOLS_DESKTOP", &cfg.Tools.Desktop)
 	envconfig.Process("MIKROBOT_TOOLS_WEB_SEARCH", &cfg.Tools.Web.Search)
+	envconfig.Process("MIKROBOT_TOOLS_HTTP", &cfg.Tools.HTTP)
+	envconfig.Process("MIKROBOT_TOOLS_SERVE", &cfg.Tools.Serve)
+	envconfig.Process("MIKROBOT_TOOLS_PLUGINS", &cfg.Tools.Plugins)
+	envconfig.Process("MIKROBOT_SESSIONS", &cfg.Sessions)
+	envconfig.Process("MIKROBOT_TIMELINE", &cfg.Timeline)
+	envconfig.Process("MIKROBOT_BACKUP", &cfg.Backup)
+	envconfig.Process("MIKROBOT_DIGEST", &cfg.Digest)
+	envconfig.Process("MIKROBOT_DIGEST", &cfg.Digest.Email)
+	envconfig.Process("MIKROBOT_AUDIT", &cfg.Audit)
+	envconfig.Process("MIKROBOT_PRICING", &cfg.Pricing)
+	envconfig.Process("MIKROBOT_FEEDS", &cfg.Feeds)
+	envconfig.Process("MIKROBOT_KNOWLEDGE", &cfg.Knowledge)
+	envconfig.Process("MIKROBOT_EMBEDDINGS", &cfg.Embeddings)
+	envconfig.Process("MIKROBOT_TRACING", &cfg.Tracing)
+	envconfig.Process("MIKROBOT_SECRETS", &cfg.Secrets)
+	envconfig.Process("MIKROBOT_CLASSIFICATION", &cfg.Classification)
 
 	// Fallback for API Key
 	if cfg.Providers.OpenAI.APIKey == "" {
@@ -64,21 +92,105 @@ func Load() (*Config, error) {
 		}
 	}
 
+	// Standard OpenTelemetry variables
+	if cfg.Tracing.Endpoint == "" {
+		cfg.Tracing.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" && os.Getenv("MIKROBOT_TRACING_SERVICE_NAME") == "" {
+		cfg.Tracing.ServiceName = name
+	}
+
 	// Expand ~ in workspace path
 	if strings.HasPrefix(cfg.Agents.Defaults.Workspace, "~") {
 		home, _ := os.UserHomeDir()
 		cfg.Agents.Defaults.Workspace = filepath.Join(home, cfg.Agents.Defaults.Workspace[1:])
 	}
+	if strings.HasPrefix(cfg.Channels.WhatsApp.SessionPath, "~") {
+		home, _ := os.UserHomeDir()
+		cfg.Channels.WhatsApp.SessionPath = filepath.Join(home, cfg.Channels.WhatsApp.SessionPath[1:])
+	}
+	if strings.HasPrefix(cfg.Timeline.Path, "~") {
+		home, _ := os.UserHomeDir()
+		cfg.Timeline.Path = filepath.Join(home, cfg.Timeline.Path[1:])
+	}
+	if strings.HasPrefix(cfg.Backup.Dir, "~") {
+		home, _ := os.UserHomeDir()
+		cfg.Backup.Dir = filepath.Join(home, cfg.Backup.Dir[1:])
+	}
+	if strings.HasPrefix(cfg.Tools.Plugins.Dir, "~") {
+		home, _ := os.UserHomeDir()
+		cfg.Tools.Plugins.Dir = filepath.Join(home, cfg.Tools.Plugins.Dir[1:])
+	}
+	for i, p := range cfg.Agents.Projects {
+		if strings.HasPrefix(p.Path, "~") {
+			home, _ := os.UserHomeDir()
+			cfg.Agents.Projects[i].Path = filepath.Join(home, p.Path[1:])
+		}
+	}
+	for i := range cfg.Tenants {
+		t := &cfg.Tenants[i]
+		if t.Workspace == "" {
+			t.Workspace = filepath.Join(t.Dir(), "workspace")
+		} else if strings.HasPrefix(t.Workspace, "~") {
+			home, _ := os.UserHomeDir()
+			t.Workspace = filepath.Join(home, t.Workspace[1:])
+		}
+		if t.Channels.WhatsApp.SessionPath == "" {
+			t.Channels.WhatsApp.SessionPath = filepath.Join(t.Dir(), "whatsapp.db")
+		} else if strings.HasPrefix(t.Channels.WhatsApp.SessionPath, "~") {
+			home, _ := os.UserHomeDir()
+			t.Channels.WhatsApp.SessionPath = filepath.Join(home, t.Channels.WhatsApp.SessionPath[1:])
+		}
+	}
+
+	return cfg, nil
+}
+
+// LoadFile loads the config file, its includes and ${VAR} and
+// ${secret:NAME} references over the defaults, without MIKROBOT_*
+// overrides or path expansion.
+func LoadFile() (*Config, error) {
+	return loadFile(true)
+}
+
+// LoadForEdit is LoadFile with ${secret:NAME} references left in place.
+// Edit this copy before calling Save so neither environment values nor
+// secrets are written to the file.
+func LoadForEdit() (*Config, error) {
+	return loadFile(false)
+}
+
+func loadFile(resolveSecrets bool) (*Config, error) {
+	cfg := DefaultConfig()
 
+	path, err := ConfigPath()
+	if err != nil {
+		return cfg, nil // Use defaults if we can't find config path
+	}
+
+	tree, _, err := readTree(path, resolveSecrets)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil // If file doesn't exist, continue with defaults
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeTree(tree, cfg, false); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
 	return cfg, nil
 }
 
-// Save writes the configuration to the config file.
+// Save writes the configuration to the config file. It refuses files
+// that are not Editable.
 func Save(cfg *Config) error {
 	path, err := ConfigPath()
 	if err != nil {
 		return err
 	}
+	if err := Editable(path); err != nil {
+		return err
+	}
 
 	// Ensure directory exists
 	dir := filepath.Dir(path)
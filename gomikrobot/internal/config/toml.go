@@ -0,0 +1,396 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML decodes the parts of TOML a config file needs: tables, arrays
+// of tables, dotted keys, strings (basic, literal and multi-line),
+// integers, floats, booleans, arrays and inline tables. Dates are kept as
+// strings.
+func parseTOML(data []byte) (map[string]any, error) {
+	p := &tomlParser{src: string(data), line: 1}
+	root := map[string]any{}
+	current := root
+	for {
+		p.skipSpace(true)
+		if p.eof() {
+			return root, nil
+		}
+		var err error
+		switch {
+		case strings.HasPrefix(p.rest(), "[["):
+			p.pos += 2
+			current, err = p.arrayTable(root)
+		case p.peek() == '[':
+			p.pos++
+			current, err = p.table(root)
+		default:
+			err = p.keyValue(current)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("toml line %d: %w", p.line, err)
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, fmt.Errorf("toml line %d: %w", p.line, err)
+		}
+	}
+}
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) eof() bool    { return p.pos >= len(p.src) }
+func (p *tomlParser) rest() string { return p.src[p.pos:] }
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// skipSpace skips blanks and comments, and newlines too if lines is set.
+func (p *tomlParser) skipSpace(lines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && lines:
+			p.pos++
+			p.line++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace(false)
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return fmt.Errorf("unexpected %q after value", p.peek())
+	}
+	return nil
+}
+
+// key reads a possibly dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var parts []string
+	for {
+		p.skipSpace(false)
+		var part string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, fmt.Errorf("expected a key")
+			}
+			part = p.src[start:p.pos]
+		}
+		parts = append(parts, part)
+		p.skipSpace(false)
+		if p.peek() != '.' {
+			return parts, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// descend walks to the table at path below t, creating missing tables.
+// For arrays of tables it enters the last element.
+func descend(t map[string]any, path []string) (map[string]any, error) {
+	for _, k := range path {
+		switch v := t[k].(type) {
+		case nil:
+			next := map[string]any{}
+			t[k] = next
+			t = next
+		case map[string]any:
+			t = v
+		case []any:
+			if len(v) == 0 {
+				return nil, fmt.Errorf("%s is not a table", k)
+			}
+			last, ok := v[len(v)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s is not a table", k)
+			}
+			t = last
+		default:
+			return nil, fmt.Errorf("%s is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+func (p *tomlParser) table(root map[string]any) (map[string]any, error) {
+	path, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ']' {
+		return nil, fmt.Errorf("expected ]")
+	}
+	p.pos++
+	return descend(root, path)
+}
+
+func (p *tomlParser) arrayTable(root map[string]any) (map[string]any, error) {
+	path, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(p.rest(), "]]") {
+		return nil, fmt.Errorf("expected ]]")
+	}
+	p.pos += 2
+	parent, err := descend(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	name := path[len(path)-1]
+	list, _ := parent[name].([]any)
+	if parent[name] != nil && list == nil {
+		return nil, fmt.Errorf("%s is not an array of tables", name)
+	}
+	t := map[string]any{}
+	parent[name] = append(list, t)
+	return t, nil
+}
+
+func (p *tomlParser) keyValue(t map[string]any) error {
+	path, err := p.key()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return fmt.Errorf("expected = after %s", strings.Join(path, "."))
+	}
+	p.pos++
+	p.skipSpace(false)
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	t, err = descend(t, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+	name := path[len(path)-1]
+	if _, dup := t[name]; dup {
+		return fmt.Errorf("duplicate key %s", strings.Join(path, "."))
+	}
+	t[name] = v
+	return nil
+}
+
+func (p *tomlParser) value() (any, error) {
+	switch c := p.peek(); {
+	case c == '"' || c == '\'':
+		return p.str()
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(p.rest(), "true"):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(p.rest(), "false"):
+		p.pos += 5
+		return false, nil
+	}
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.peek())) {
+		p.pos++
+	}
+	raw := p.src[start:p.pos]
+	if raw == "" {
+		return nil, fmt.Errorf("expected a value")
+	}
+	num := strings.ReplaceAll(raw, "_", "")
+	if n, err := strconv.ParseInt(num, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(num, 64); err == nil {
+		return f, nil
+	}
+	if raw[0] >= '0' && raw[0] <= '9' {
+		// Dates and times.
+		return raw, nil
+	}
+	return nil, fmt.Errorf("invalid value %q", raw)
+}
+
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++ // [
+	out := []any{}
+	for {
+		p.skipSpace(true)
+		if p.peek() == ']' {
+			p.pos++
+			return out, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+		p.skipSpace(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected , or ] in array")
+		}
+	}
+}
+
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.pos++ // {
+	t := map[string]any{}
+	p.skipSpace(false)
+	if p.peek() == '}' {
+		p.pos++
+		return t, nil
+	}
+	for {
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipSpace(false)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return t, nil
+		default:
+			return nil, fmt.Errorf("expected , or } in inline table")
+		}
+	}
+}
+
+// str reads a basic, literal or multi-line string.
+func (p *tomlParser) str() (string, error) {
+	quote := p.src[p.pos : p.pos+1]
+	multi := strings.HasPrefix(p.rest(), strings.Repeat(quote, 3))
+	if multi {
+		p.pos += 3
+		// A newline right after the opening quotes is trimmed.
+		if strings.HasPrefix(p.rest(), "\r\n") {
+			p.pos += 2
+			p.line++
+		} else if p.peek() == '\n' {
+			p.pos++
+			p.line++
+		}
+	} else {
+		p.pos++
+	}
+	closing := quote
+	if multi {
+		closing = strings.Repeat(quote, 3)
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.rest(), closing) {
+			p.pos += len(closing)
+			return b.String(), nil
+		}
+		c := p.peek()
+		switch {
+		case c == '\n' && !multi:
+			return "", fmt.Errorf("newline in string")
+		case c == '\\' && quote == `"`:
+			if err := p.escape(&b, multi); err != nil {
+				return "", err
+			}
+			continue
+		case c == '\n':
+			p.line++
+		}
+		r, size := utf8.DecodeRuneInString(p.rest())
+		b.WriteRune(r)
+		p.pos += size
+	}
+}
+
+func (p *tomlParser) escape(b *strings.Builder, multi bool) error {
+	p.pos++ // backslash
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'b':
+		b.WriteByte('\b')
+	case 'f':
+		b.WriteByte('\f')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.src) {
+			return fmt.Errorf("short unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+		if err != nil {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(code))
+		p.pos += n
+	case '\n', ' ', '\t', '\r':
+		if !multi {
+			return fmt.Errorf("invalid escape")
+		}
+		// Line-ending backslash: skip the newline and leading blanks.
+		p.pos--
+		for !p.eof() && strings.ContainsRune(" \t\r\n", rune(p.peek())) {
+			if p.peek() == '\n' {
+				p.line++
+			}
+			p.pos++
+		}
+	default:
+		return fmt.Errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateDefaults(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Providers.OpenAI.APIKey = "sk-abcdefghijklmnopqrstuvwxyz"
+
+	issues := Validate(cfg)
+	if HasErrors(issues) {
+		t.Fatalf("expected no errors for defaults, got %v", issues)
+	}
+}
+
+func TestValidateInvalidValues(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Gateway.Port = 70000
+	cfg.Agents.Defaults.Temperature = 3
+	cfg.Channels.Telegram.Enabled = true
+	cfg.Bus.Transport = "nats"
+
+	issues := Validate(cfg)
+	want := []string{"gateway.port", "agents.defaults.temperature", "channels.telegram.token", "providers.openai.apiKey", "bus.url"}
+	for _, field := range want {
+		found := false
+		for _, i := range issues {
+			if i.Field == field && i.Level == LevelError {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected error for %s, got %v", field, issues)
+		}
+	}
+}
+
+func TestValidateGatewayTLS(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Gateway.TLS.CertFile = "/nonexistent/cert.pem"
+	cfg.Gateway.TLS.ACMEHosts = []string{"bot.example.com"}
+
+	issues := Validate(cfg)
+	var msgs []string
+	for _, i := range issues {
+		if strings.HasPrefix(i.Field, "gateway.tls") && i.Level == LevelError {
+			msgs = append(msgs, i.Message)
+		}
+	}
+	// Missing key, both cert and ACME, unreadable cert.
+	if len(msgs) != 3 {
+		t.Errorf("expected 3 TLS errors, got %v", msgs)
+	}
+}
+
+func TestValidateAPIKeyFormat(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Providers.OpenAI.APIKey = "not-a-key"
+
+	issues := Validate(cfg)
+	for _, i := range issues {
+		if i.Field == "providers.openai.apiKey" && i.Level == LevelWarning {
+			return
+		}
+	}
+	t.Errorf("expected API key format warning, got %v", issues)
+}
+
+func TestValidateFileUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"gateway": {"prot": 9000}}`), 0600)
+
+	issues, err := ValidateFile(path)
+	if err != nil {
+		t.Fatalf("ValidateFile() error: %v", err)
+	}
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "prot") {
+		t.Errorf("expected unknown field issue, got %v", issues)
+	}
+
+	os.WriteFile(path, []byte(`{"gateway": {"port": 9000}}`), 0600)
+	issues, _ = ValidateFile(path)
+	if len(issues) != 0 {
+		t.Errorf("expected no issues, got %v", issues)
+	}
+}
+
+func TestValidateClassification(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Classification.Rules = []ClassificationRule{{Label: "junk", Keywords: []string{"x"}}, {Label: "spam", Pattern: "("}}
+	cfg.Classification.Routes = map[string]string{"spam": "delete"}
+
+	issues := Validate(cfg)
+	for _, field := range []string{"classification.rules[0].label", "classification.rules[1].pattern", "classification.routes.spam"} {
+		found := false
+		for _, i := range issues {
+			if i.Field == field && i.Level == LevelError {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected error for %s, got %v", field, issues)
+		}
+	}
+}
+
+func TestValidateTenants(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Gateway.APIToken = "main-token"
+	cfg.Tenants = []TenantConfig{
+		{Name: "anna", APIToken: "anna-token", Workspace: "/srv/anna"},
+		{Name: "anna", APIToken: "other-token"},
+		{Name: "Ben", APIToken: "ben-token"},
+		{Name: "carl", APIToken: "main-token"},
+		{Name: "dora", APIToken: "dora-token", Workspace: cfg.Agents.Defaults.Workspace, Admins: []string{"dora"}},
+	}
+
+	issues := Validate(cfg)
+	want := []string{"tenants[1].name", "tenants[2].name", "tenants[3].apiToken", "tenants[4].workspace", "tenants[4].admins[0]"}
+	for _, field := range want {
+		found := false
+		for _, i := range issues {
+			if i.Field == field && i.Level == LevelError {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected error for %s, got %v", field, issues)
+		}
+	}
+	for _, i := range issues {
+		if strings.HasPrefix(i.Field, "tenants[0]") {
+			t.Errorf("unexpected issue for a valid tenant: %v", i)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"context"
+	"os"
+	"slices"
+	"time"
+)
+
+// Watch polls the config file and its includes and calls onChange with a
+// freshly loaded config whenever a file's modification time or size
+// changes. It blocks
+// until ctx is cancelled. Polling keeps the package dependency-free and
+// also works on network filesystems where inotify is unreliable.
+func Watch(ctx context.Context, interval time.Duration, onChange func(*Config)) {
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	if _, err := ConfigPath(); err != nil {
+		return
+	}
+
+	files := watchedFiles()
+	last := fileStamps(files)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stamps := fileStamps(files)
+			if slices.Equal(stamps, last) {
+				continue
+			}
+			// Includes may have been added or removed.
+			files = watchedFiles()
+			last = fileStamps(files)
+			cfg, err := Load()
+			if err != nil {
+				// Keep the running config; the next write may fix the file.
+				continue
+			}
+			onChange(cfg)
+		}
+	}
+}
+
+type stamp struct {
+	mod  time.Time
+	size int64
+}
+
+// watchedFiles returns the config file and the files it includes.
+func watchedFiles() []string {
+	path, err := ConfigPath()
+	if err != nil {
+		return nil
+	}
+	if _, files, _ := readTree(path, false); len(files) > 0 {
+		return files
+	}
+	return []string{path}
+}
+
+func fileStamps(files []string) []stamp {
+	out := make([]stamp, len(files))
+	for i, f := range files {
+		out[i] = fileStamp(f)
+	}
+	return out
+}
+
+func fileStamp(path string) stamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	return stamp{mod: info.ModTime(), size: info.Size()}
+}
@@ -0,0 +1,100 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// lastSentKey is the timeline setting holding the end of the last sent period.
+const lastSentKey = "digest_last_sent"
+
+// Scheduler sends the digest on the configured weekday and time.
+type Scheduler struct {
+	Config    config.DigestConfig
+	Timeline  *timeline.TimelineService
+	Bus       *bus.MessageBus
+	Workspace string
+}
+
+// Run blocks until ctx is cancelled, sending one digest per week.
+func (s *Scheduler) Run(ctx context.Context) error {
+	for {
+		next, err := NextRun(time.Now(), s.Config.Day, s.Config.Time)
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Until(next)):
+		}
+
+		// Skip if another process (or a restart) already sent this period.
+		if last, err := s.Timeline.GetSetting(lastSentKey); err == nil && last == next.Format(time.RFC3339) {
+			continue
+		}
+		if err := s.SendNow(ctx, next); err != nil {
+			fmt.Printf("⚠️ Digest failed: %v\n", err)
+			continue
+		}
+		_ = s.Timeline.SetSetting(lastSentKey, next.Format(time.RFC3339))
+	}
+}
+
+// SendNow builds the digest for the week ending at end and delivers it.
+func (s *Scheduler) SendNow(ctx context.Context, end time.Time) error {
+	r, err := Build(s.Timeline, s.Workspace, end)
+	if err != nil {
+		return err
+	}
+	body := Render(r, s.Config.Sections)
+
+	if s.Config.Channel == "email" {
+		subject := fmt.Sprintf("GoMikroBot weekly digest (%s)", end.Format("2006-01-02"))
+		return SendEmail(s.Config.Email, subject, body)
+	}
+	if s.Bus == nil {
+		return fmt.Errorf("no message bus for channel %q", s.Config.Channel)
+	}
+	s.Bus.PublishOutbound(&bus.OutboundMessage{
+		Channel: s.Config.Channel,
+		ChatID:  s.Config.ChatID,
+		Content: body,
+	})
+	return nil
+}
+
+// SendEmail sends a plain-text message over SMTP (STARTTLS when offered).
+func SendEmail(cfg config.EmailConfig, subject, body string) error {
+	if cfg.SMTPHost == "" || cfg.To == "" || cfg.From == "" {
+		return fmt.Errorf("email requires smtpHost, from, and to")
+	}
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.SMTPHost)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", cfg.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	addr := fmt.Sprintf("%s:%d", cfg.SMTPHost, port)
+	return smtp.SendMail(addr, auth, cfg.From, strings.Split(cfg.To, ","), []byte(msg.String()))
+}
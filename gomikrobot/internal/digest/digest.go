@@ -0,0 +1,161 @@
+// Package digest builds and schedules the weekly activity summary.
+package digest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// Report sections.
+const (
+	SectionMessages = "messages"
+	SectionTools    = "tools"
+	SectionErrors   = "errors"
+	SectionSpend    = "spend"
+	SectionMemory   = "memory"
+)
+
+// AllSections lists every section in display order.
+var AllSections = []string{SectionMessages, SectionTools, SectionErrors, SectionSpend, SectionMemory}
+
+// Period is the length of one digest window.
+const Period = 7 * 24 * time.Hour
+
+// Report is the data behind one digest.
+type Report struct {
+	Start  time.Time
+	End    time.Time
+	Events map[string]int
+	Usage  timeline.UsageTotals
+	Memory []MemoryChange
+}
+
+// MemoryChange is a memory file written during the period.
+type MemoryChange struct {
+	Name  string
+	Lines int
+}
+
+// Build collects the report for the Period ending at end.
+func Build(tl *timeline.TimelineService, workspace string, end time.Time) (*Report, error) {
+	r := &Report{Start: end.Add(-Period), End: end}
+
+	var err error
+	if r.Events, err = tl.EventCounts(r.Start, r.End); err != nil {
+		return nil, fmt.Errorf("count events: %w", err)
+	}
+	if r.Usage, err = tl.UsageBetween(r.Start, r.End); err != nil {
+		return nil, fmt.Errorf("sum usage: %w", err)
+	}
+	r.Memory = memoryChanges(filepath.Join(workspace, "memory"), r.Start, r.End)
+	return r, nil
+}
+
+// memoryChanges lists markdown files in dir modified within [start, end).
+func memoryChanges(dir string, start, end time.Time) []MemoryChange {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var changes []MemoryChange
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().Before(start) || !info.ModTime().Before(end) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		changes = append(changes, MemoryChange{Name: e.Name(), Lines: strings.Count(string(data), "\n")})
+	}
+	return changes
+}
+
+// Render formats the report as plain text. Empty sections means all.
+func Render(r *Report, sections []string) string {
+	if len(sections) == 0 {
+		sections = AllSections
+	}
+	has := func(s string) bool { return slices.Contains(sections, s) }
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "📊 Weekly digest %s – %s\n", r.Start.Format("Jan 2"), r.End.Add(-time.Second).Format("Jan 2, 2006"))
+
+	if has(SectionMessages) {
+		total := 0
+		kinds := make([]string, 0, len(r.Events))
+		for k, n := range r.Events {
+			total += n
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		fmt.Fprintf(&sb, "\n💬 Messages: %d inbound, %d answered\n", total, r.Usage.Requests)
+		for _, k := range kinds {
+			fmt.Fprintf(&sb, "  - %s: %d\n", strings.ToLower(k), r.Events[k])
+		}
+	}
+	if has(SectionTools) {
+		fmt.Fprintf(&sb, "\n🛠 Tools: %d calls\n", r.Usage.ToolCalls)
+	}
+	if has(SectionErrors) {
+		fmt.Fprintf(&sb, "\n⚠️ Errors: %d failed requests, %d tool errors\n", r.Usage.Failed, r.Usage.ToolErrors)
+	}
+	if has(SectionSpend) {
+		fmt.Fprintf(&sb, "\n💰 Tokens: %d prompt + %d completion = %d\n",
+			r.Usage.PromptTokens, r.Usage.CompletionTokens, r.Usage.PromptTokens+r.Usage.CompletionTokens)
+	}
+	if has(SectionMemory) {
+		if len(r.Memory) == 0 {
+			sb.WriteString("\n🧠 Memory: no changes\n")
+		} else {
+			sb.WriteString("\n🧠 Memory updated:\n")
+			for _, m := range r.Memory {
+				fmt.Fprintf(&sb, "  - %s (%d lines)\n", m.Name, m.Lines)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// NextRun returns the first time after now that falls on day at hhmm
+// (local time of now).
+func NextRun(now time.Time, day, hhmm string) (time.Time, error) {
+	wd, err := ParseWeekday(day)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse("15:04", hhmm)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (want HH:MM)", hhmm)
+	}
+
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	next = next.AddDate(0, 0, (int(wd)-int(next.Weekday())+7)%7)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 7)
+	}
+	return next, nil
+}
+
+// ParseWeekday parses an English weekday name or its three-letter prefix.
+func ParseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || (len(s) == 3 && strings.HasPrefix(name, s)) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
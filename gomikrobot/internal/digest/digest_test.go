@@ -0,0 +1,128 @@
+package digest
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+func TestNextRun(t *testing.T) {
+	// Wednesday 2026-10-14 10:00
+	now := time.Date(2026, 10, 14, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		day, at string
+		want    time.Time
+	}{
+		{"friday", "09:30", time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC)},
+		{"wed", "11:00", time.Date(2026, 10, 14, 11, 0, 0, 0, time.UTC)},
+		{"Wednesday", "10:00", time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)},
+		{"monday", "08:00", time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := NextRun(now, tt.day, tt.at)
+		if err != nil {
+			t.Fatalf("NextRun(%s, %s): %v", tt.day, tt.at, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("NextRun(%s, %s) = %v, want %v", tt.day, tt.at, got, tt.want)
+		}
+	}
+
+	if _, err := NextRun(now, "someday", "08:00"); err == nil {
+		t.Error("expected error for invalid weekday")
+	}
+	if _, err := NextRun(now, "monday", "8am"); err == nil {
+		t.Error("expected error for invalid time")
+	}
+}
+
+func TestBuildAndRender(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	end := time.Now()
+	in := end.Add(-24 * time.Hour)
+	old := end.Add(-10 * 24 * time.Hour)
+
+	_ = tl.AddEvent(&timeline.TimelineEvent{EventID: "1", Timestamp: in, EventType: "TEXT", Authorized: true})
+	_ = tl.AddEvent(&timeline.TimelineEvent{EventID: "2", Timestamp: in, EventType: "TEXT", Authorized: false})
+	_ = tl.AddEvent(&timeline.TimelineEvent{EventID: "3", Timestamp: old, EventType: "TEXT", Authorized: true})
+	_ = tl.RecordUsage(&timeline.UsageRecord{Timestamp: in, PromptTokens: 100, CompletionTokens: 20, ToolCalls: 3, ToolErrors: 1})
+	_ = tl.RecordUsage(&timeline.UsageRecord{Timestamp: in, Failed: true})
+
+	r, err := Build(tl, t.TempDir(), end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Events["TEXT"] != 1 || r.Events["UNAUTHORIZED"] != 1 {
+		t.Errorf("unexpected event counts %v", r.Events)
+	}
+	if r.Usage.Requests != 2 || r.Usage.ToolCalls != 3 || r.Usage.Failed != 1 {
+		t.Errorf("unexpected usage %+v", r.Usage)
+	}
+
+	out := Render(r, []string{SectionTools, SectionErrors})
+	if !strings.Contains(out, "3 calls") || !strings.Contains(out, "1 failed requests, 1 tool errors") {
+		t.Errorf("unexpected render:\n%s", out)
+	}
+	if strings.Contains(out, "Tokens") {
+		t.Errorf("spend section should be omitted:\n%s", out)
+	}
+}
+
+type recordingProvider struct {
+	req *provider.ChatRequest
+}
+
+func (p *recordingProvider) Chat(ctx context.Context, req *provider.ChatRequest) (*provider.ChatResponse, error) {
+	p.req = req
+	return &provider.ChatResponse{Content: " - Dinner on Friday "}, nil
+}
+func (p *recordingProvider) Transcribe(context.Context, *provider.AudioRequest) (*provider.AudioResponse, error) {
+	return nil, nil
+}
+func (p *recordingProvider) Speak(context.Context, *provider.TTSRequest) (*provider.TTSResponse, error) {
+	return nil, nil
+}
+func (p *recordingProvider) DefaultModel() string { return "test" }
+
+func TestSummarizeSender(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	end := time.Now()
+	_ = tl.AddEvent(&timeline.TimelineEvent{EventID: "1", Timestamp: end.Add(-2 * time.Hour), SenderID: "4917", ContentText: "Dinner Friday?", Authorized: true})
+	_ = tl.AddEvent(&timeline.TimelineEvent{EventID: "2", Timestamp: end.Add(-time.Hour), SenderID: "4917", ContentText: "At 8 then", Authorized: true})
+	_ = tl.AddEvent(&timeline.TimelineEvent{EventID: "3", Timestamp: end.Add(-time.Hour), SenderID: "4930", ContentText: "Other chat", Authorized: true})
+
+	prov := &recordingProvider{}
+	s, err := Summarize(context.Background(), prov, tl, SummaryOptions{SenderID: "4917", Start: end.Add(-24 * time.Hour), End: end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Events != 2 || s.Text != "- Dinner on Friday" {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	input := prov.req.Messages[1].Content
+	if strings.Contains(input, "Other chat") || strings.Index(input, "Dinner Friday?") > strings.Index(input, "At 8 then") {
+		t.Errorf("expected only the sender's messages, oldest first:\n%s", input)
+	}
+
+	prov.req = nil
+	s, _ = Summarize(context.Background(), prov, tl, SummaryOptions{SenderID: "nobody", Start: end.Add(-24 * time.Hour), End: end})
+	if s.Events != 0 || prov.req != nil {
+		t.Error("expected no provider call without events")
+	}
+}
@@ -0,0 +1,108 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+const (
+	// summaryMaxEvents bounds the events fed to the summarizer.
+	summaryMaxEvents = 500
+	// summaryEventChars and summaryInputChars cap each event and the whole
+	// transcript; the newest events are kept.
+	summaryEventChars = 600
+	summaryInputChars = 60000
+)
+
+const summaryPrompt = "You summarize chat history for the owner of a personal assistant. " +
+	"Write a concise digest in Markdown: the main topics, decisions, requests or promises that are still open, " +
+	"and notable dates or numbers. Group by topic, not by message. Reply in the language of the conversation."
+
+// SummaryOptions selects the events to summarize.
+type SummaryOptions struct {
+	// SenderID limits the summary to one sender (empty = everyone).
+	SenderID string
+	Start    time.Time
+	End      time.Time
+	Model    string
+}
+
+// Summary is an LLM-written digest of timeline events.
+type Summary struct {
+	SenderID string    `json:"sender_id,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Events   int       `json:"events"`
+	Text     string    `json:"text"`
+}
+
+// Summarize condenses the timeline events matching opts with prov. With no
+// events it returns a summary saying so without calling the provider.
+func Summarize(ctx context.Context, prov provider.LLMProvider, tl *timeline.TimelineService, opts SummaryOptions) (*Summary, error) {
+	authorized := true
+	events, err := tl.GetEvents(timeline.FilterArgs{
+		SenderID:       opts.SenderID,
+		StartDate:      &opts.Start,
+		EndDate:        &opts.End,
+		AuthorizedOnly: &authorized,
+		Limit:          summaryMaxEvents,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load events: %w", err)
+	}
+
+	s := &Summary{SenderID: opts.SenderID, Start: opts.Start, End: opts.End, Events: len(events)}
+	if len(events) == 0 {
+		s.Text = "No messages in this period."
+		return s, nil
+	}
+
+	resp, err := prov.Chat(ctx, &provider.ChatRequest{
+		Model: opts.Model,
+		Messages: []provider.Message{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: transcript(events)},
+		},
+		MaxTokens:   1200,
+		Temperature: 0.2,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("summarize: %w", err)
+	}
+	s.Text = strings.TrimSpace(resp.Content)
+	return s, nil
+}
+
+// transcript formats events oldest first, dropping the oldest ones once the
+// input cap is reached. events arrive newest first.
+func transcript(events []timeline.TimelineEvent) string {
+	var lines []string
+	total := 0
+	for _, e := range events {
+		text := strings.TrimSpace(e.ContentText)
+		if text == "" {
+			continue
+		}
+		if len(text) > summaryEventChars {
+			text = text[:summaryEventChars] + "…"
+		}
+		name := e.SenderName
+		if name == "" || name == "User" {
+			name = e.SenderID
+		}
+		line := fmt.Sprintf("%s %s: %s", e.Timestamp.Format("2006-01-02 15:04"), name, text)
+		if total+len(line) > summaryInputChars {
+			break
+		}
+		total += len(line) + 1
+		lines = append(lines, line)
+	}
+	slices.Reverse(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,190 @@
+// Package feeds fetches and parses RSS and Atom feeds.
+package feeds
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxSummary caps the text kept per item.
+const maxSummary = 1000
+
+// Item is one entry of a feed.
+type Item struct {
+	ID        string    `json:"id"` // GUID, Atom id, or link
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Summary   string    `json:"summary"` // Plain text
+	Published time.Time `json:"published"`
+}
+
+// Key returns a short stable identifier of the item.
+func (i Item) Key() string {
+	sum := sha256.Sum256([]byte(i.ID))
+	return hex.EncodeToString(sum[:8])
+}
+
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"encoded"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"date"`
+}
+
+// document covers all formats; only the fields of one are filled.
+type document struct {
+	XMLName xml.Name
+	// RSS 2.0 nests items in the channel, RSS 1.0 puts them at the root.
+	Channel struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+	// Atom
+	Entries []struct {
+		ID    string `xml:"id"`
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// Parse reads an RSS 1.0, RSS 2.0, or Atom document.
+func Parse(data []byte) ([]Item, error) {
+	var doc document
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	dec.Strict = false
+	dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse feed: %w", err)
+	}
+
+	var items []Item
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss", "rdf":
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			summary := it.Description
+			if summary == "" {
+				summary = it.Content
+			}
+			item := Item{ID: it.GUID, Title: cleanText(it.Title), Link: strings.TrimSpace(it.Link), Summary: cleanText(summary), Published: parseTime(it.PubDate, it.Date)}
+			items = append(items, item)
+		}
+	case "feed":
+		for _, e := range doc.Entries {
+			item := Item{ID: e.ID, Title: cleanText(e.Title), Summary: cleanText(e.Summary), Published: parseTime(e.Published, e.Updated)}
+			if item.Summary == "" {
+				item.Summary = cleanText(e.Content)
+			}
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					item.Link = l.Href
+					break
+				}
+			}
+			items = append(items, item)
+		}
+	default:
+		return nil, fmt.Errorf("not an RSS or Atom feed (root element %q)", doc.XMLName.Local)
+	}
+
+	for i := range items {
+		if items[i].ID = strings.TrimSpace(items[i].ID); items[i].ID == "" {
+			items[i].ID = items[i].Link + "|" + items[i].Title
+		}
+	}
+	return items, nil
+}
+
+var (
+	tagPattern   = regexp.MustCompile(`<[^>]*>`)
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// cleanText turns an HTML fragment into a single line of plain text.
+func cleanText(s string) string {
+	s = tagPattern.ReplaceAllString(s, " ")
+	s = strings.TrimSpace(spacePattern.ReplaceAllString(html.UnescapeString(s), " "))
+	if r := []rune(s); len(r) > maxSummary {
+		s = string(r[:maxSummary]) + "…"
+	}
+	return s
+}
+
+var timeFormats = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", "2006-01-02T15:04:05Z07:00", "2006-01-02"}
+
+// parseTime returns the first of values that parses, or the zero time.
+func parseTime(values ...string) time.Time {
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		for _, f := range timeFormats {
+			if t, err := time.Parse(f, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// Match reports whether the item mentions one of include (or include is
+// empty) and none of exclude. Matching ignores case.
+func Match(item Item, include, exclude []string) bool {
+	text := strings.ToLower(item.Title + " " + item.Summary)
+	for _, kw := range exclude {
+		if kw != "" && strings.Contains(text, strings.ToLower(kw)) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, kw := range include {
+		if kw != "" && strings.Contains(text, strings.ToLower(kw)) {
+			return true
+		}
+	}
+	return false
+}
+
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// Fetch downloads and parses the feed at url.
+func Fetch(ctx context.Context, url string) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "GoMikroBot feed reader")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.5")
+	resp, err := defaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
@@ -0,0 +1,52 @@
+package feeds
+
+import "testing"
+
+func TestParseRSSAndAtom(t *testing.T) {
+	rss := `<?xml version="1.0"?><rss version="2.0"><channel><title>Blog</title>
+<item><title>Hello &amp; welcome</title><link>https://example.com/1</link><guid>post-1</guid>
+<description>&lt;p&gt;First &lt;b&gt;post&lt;/b&gt;&lt;/p&gt;</description><pubDate>Tue, 10 Jun 2025 08:00:00 +0000</pubDate></item>
+<item><title>No guid</title><link>https://example.com/2</link></item>
+</channel></rss>`
+	items, err := Parse([]byte(rss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].ID != "post-1" || items[0].Title != "Hello & welcome" || items[0].Summary != "First post" || items[0].Published.IsZero() {
+		t.Errorf("unexpected RSS items %+v", items)
+	}
+	if items[1].ID == "" || items[0].Key() == items[1].Key() {
+		t.Errorf("items need distinct ids: %+v", items)
+	}
+
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><entry><id>urn:1</id><title>Release</title>
+<link rel="alternate" href="https://example.com/r"/><updated>2025-06-10T08:00:00Z</updated><content type="html">Notes</content></entry></feed>`
+	items, err = Parse([]byte(atom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Link != "https://example.com/r" || items[0].Summary != "Notes" || items[0].Published.IsZero() {
+		t.Errorf("unexpected Atom items %+v", items)
+	}
+
+	if _, err := Parse([]byte("<html></html>")); err == nil {
+		t.Error("expected error for non-feed document")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	it := Item{Title: "Go 1.25 Released", Summary: "with generic type aliases"}
+	for _, c := range []struct {
+		include, exclude []string
+		want             bool
+	}{
+		{nil, nil, true},
+		{[]string{"released"}, nil, true},
+		{[]string{"rust"}, nil, false},
+		{[]string{"go"}, []string{"ALIASES"}, false},
+	} {
+		if got := Match(it, c.include, c.exclude); got != c.want {
+			t.Errorf("Match(%v, %v) = %v, want %v", c.include, c.exclude, got, c.want)
+		}
+	}
+}
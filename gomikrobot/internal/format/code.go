@@ -0,0 +1,72 @@
+package format
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// codeExtensions maps code block languages to file extensions. Other
+// languages use the language name, and blocks without one use .txt.
+var codeExtensions = map[string]string{
+	"python": "py", "javascript": "js", "typescript": "ts", "golang": "go",
+	"bash": "sh", "shell": "sh", "zsh": "sh", "console": "txt",
+	"yaml": "yml", "markdown": "md", "text": "txt", "plaintext": "txt",
+	"rust": "rs", "ruby": "rb", "kotlin": "kt", "c++": "cpp", "c#": "cs",
+	"csharp": "cs", "powershell": "ps1",
+}
+
+// ExtractCode moves code blocks longer than maxChars out of md into files
+// in dir. Each block is replaced by a note naming its file, and the paths
+// of the written files are returned. maxChars <= 0 returns md unchanged.
+func ExtractCode(md string, maxChars int, dir string) (string, []string, error) {
+	if maxChars <= 0 || !strings.Contains(md, "```") {
+		return md, nil, nil
+	}
+	lines := strings.Split(md, "\n")
+	out := make([]string, 0, len(lines))
+	var files []string
+	stamp := time.Now().Format("20060102-150405")
+	for i := 0; i < len(lines); i++ {
+		m := fenceLine.FindStringSubmatch(lines[i])
+		if m == nil {
+			out = append(out, lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !fenceLine.MatchString(lines[end]) {
+			end++
+		}
+		body := strings.Join(lines[i+1:min(end, len(lines))], "\n")
+		if utf8.RuneCountInString(body) <= maxChars {
+			out = append(out, lines[i:min(end+1, len(lines))]...)
+			i = end
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return md, nil, err
+		}
+		path := filepath.Join(dir, fmt.Sprintf("code-%s-%d.%s", stamp, len(files)+1, codeExtension(m[1])))
+		if err := os.WriteFile(path, []byte(body+"\n"), 0644); err != nil {
+			return md, nil, err
+		}
+		files = append(files, path)
+		out = append(out, fmt.Sprintf("[code attached: %s]", filepath.Base(path)))
+		i = end
+	}
+	return strings.Join(out, "\n"), files, nil
+}
+
+func codeExtension(lang string) string {
+	lang = strings.ToLower(lang)
+	if ext, ok := codeExtensions[lang]; ok {
+		return ext
+	}
+	if lang == "" || strings.ContainsAny(lang, "+#.") {
+		return "txt"
+	}
+	return lang
+}
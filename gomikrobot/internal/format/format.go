@@ -0,0 +1,268 @@
+// Package format adapts the markdown the agent writes to the markup each
+// channel understands.
+package format
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Style is the markup a channel renders.
+type Style string
+
+const (
+	// Markdown leaves the text unchanged.
+	Markdown Style = "markdown"
+	// WhatsApp uses *bold*, _italic_, ~strike~ and ``` blocks.
+	WhatsApp Style = "whatsapp"
+	// Slack uses mrkdwn: like WhatsApp, with <url|text> links and &, <, >
+	// escaped.
+	Slack Style = "slack"
+	// Telegram uses HTML (parse_mode "HTML"), which needs less escaping
+	// than MarkdownV2.
+	Telegram Style = "telegram"
+	// Discord renders markdown itself; only bullets are normalized.
+	Discord Style = "discord"
+	// Plain removes all markup, e.g. for SMS, Signal, and speech.
+	Plain Style = "plain"
+)
+
+// Styles lists the known styles.
+var Styles = []Style{Markdown, WhatsApp, Slack, Telegram, Discord, Plain}
+
+// StyleFor returns the default style of a channel.
+func StyleFor(channel string) Style {
+	switch channel {
+	case "whatsapp":
+		return WhatsApp
+	case "slack":
+		return Slack
+	case "telegram":
+		return Telegram
+	case "discord":
+		return Discord
+	case "signal", "voice", "sms":
+		return Plain
+	}
+	return Markdown
+}
+
+// Parse returns the style named s, or an error for unknown names.
+func Parse(s string) (Style, error) {
+	for _, st := range Styles {
+		if string(st) == s {
+			return st, nil
+		}
+	}
+	return "", fmt.Errorf("unknown format %q", s)
+}
+
+var (
+	fenceLine   = regexp.MustCompile("^\\s*```\\s*([\\w+#.-]*)\\s*$")
+	headingLine = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*\s*$`)
+	bulletLine  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	ruleLine    = regexp.MustCompile(`^\s*([-*_])(\s*([-*_])){2,}\s*$`)
+	// inline matches, in order: code spans, bold, strike, links, italic.
+	inline = regexp.MustCompile("`([^`\\n]+)`" +
+		`|\*\*(.+?)\*\*|__(.+?)__` +
+		`|~~(.+?)~~` +
+		`|\[([^\]\n]+)\]\(([^)\s]+)\)` +
+		`|\*([^*\s](?:[^*\n]*[^*\s])?)\*|\b_([^_\s](?:[^_\n]*[^_\s])?)_\b`)
+)
+
+// Convert rewrites markdown md in style. Code blocks keep their content;
+// headings become bold lines and bullets become "•".
+func Convert(md string, style Style) string {
+	if style == Markdown || style == "" {
+		return md
+	}
+	lines := strings.Split(md, "\n")
+	out := make([]string, 0, len(lines))
+	inFence := false
+	// Telegram's <pre> tags go on the first and last code line, so the
+	// block has no blank lines.
+	pending := ""
+	for _, line := range lines {
+		if m := fenceLine.FindStringSubmatch(line); m != nil {
+			inFence = !inFence
+			switch {
+			case style == Telegram && inFence:
+				pending = "<pre><code>"
+				if m[1] != "" {
+					pending = `<pre><code class="language-` + html.EscapeString(m[1]) + `">`
+				}
+			case style == Telegram:
+				out = closePre(out, &pending)
+			case style == Plain:
+			case style == Discord && inFence:
+				out = append(out, "```"+m[1])
+			default:
+				// WhatsApp and Slack would show the language as code.
+				out = append(out, "```")
+			}
+			continue
+		}
+		if inFence {
+			if style == Telegram {
+				line = pending + html.EscapeString(line)
+				pending = ""
+			}
+			out = append(out, line)
+			continue
+		}
+		out = append(out, convertLine(line, style))
+	}
+	if inFence {
+		switch style {
+		case Telegram:
+			out = closePre(out, &pending)
+		case Plain:
+		default:
+			out = append(out, "```")
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// closePre ends a Telegram code block on its last line.
+func closePre(out []string, pending *string) []string {
+	if *pending != "" {
+		out = append(out, *pending+"</code></pre>")
+		*pending = ""
+		return out
+	}
+	out[len(out)-1] += "</code></pre>"
+	return out
+}
+
+// convertLine converts one line outside code blocks.
+func convertLine(line string, style Style) string {
+	if ruleLine.MatchString(line) && style != Discord {
+		return "———"
+	}
+	if m := headingLine.FindStringSubmatch(line); m != nil && style != Discord {
+		if style == Plain {
+			return convertInline(m[1], style)
+		}
+		return bold(convertInline(m[1], style), style)
+	}
+	if m := bulletLine.FindStringSubmatch(line); m != nil && !ruleLine.MatchString(line) {
+		return m[1] + "• " + convertInline(line[len(m[0]):], style)
+	}
+	return convertInline(line, style)
+}
+
+// convertInline converts the inline marks of s.
+func convertInline(s string, style Style) string {
+	if style == Discord {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range inline.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(escape(s[last:m[0]], style))
+		last = m[1]
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return s[m[2*i]:m[2*i+1]]
+		}
+		switch {
+		case m[2] >= 0:
+			b.WriteString(code(group(1), style))
+		case m[4] >= 0:
+			b.WriteString(bold(convertInline(group(2), style), style))
+		case m[6] >= 0:
+			b.WriteString(bold(convertInline(group(3), style), style))
+		case m[8] >= 0:
+			b.WriteString(strike(convertInline(group(4), style), style))
+		case m[10] >= 0:
+			b.WriteString(link(convertInline(group(5), style), group(6), style))
+		case m[14] >= 0 && inWord(s, m[0], m[1]):
+			// Not emphasis, e.g. 2*3*4.
+			b.WriteString(escape(s[m[0]:m[1]], style))
+		case m[14] >= 0:
+			b.WriteString(italic(convertInline(group(7), style), style))
+		default:
+			b.WriteString(italic(convertInline(group(8), style), style))
+		}
+	}
+	b.WriteString(escape(s[last:], style))
+	return b.String()
+}
+
+// inWord reports whether s[start:end] touches a letter or digit.
+func inWord(s string, start, end int) bool {
+	isWord := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+	}
+	return start > 0 && isWord(s[start-1]) || end < len(s) && isWord(s[end])
+}
+
+func escape(s string, style Style) string {
+	switch style {
+	case Telegram:
+		return html.EscapeString(s)
+	case Slack:
+		return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+	}
+	return s
+}
+
+func code(s string, style Style) string {
+	switch style {
+	case Telegram:
+		return "<code>" + html.EscapeString(s) + "</code>"
+	case Plain:
+		return s
+	case Slack:
+		return "`" + escape(s, style) + "`"
+	}
+	return "`" + s + "`"
+}
+
+func bold(s string, style Style) string {
+	switch style {
+	case Telegram:
+		return "<b>" + s + "</b>"
+	case Plain:
+		return s
+	}
+	return "*" + s + "*"
+}
+
+func italic(s string, style Style) string {
+	switch style {
+	case Telegram:
+		return "<i>" + s + "</i>"
+	case Plain:
+		return s
+	}
+	return "_" + s + "_"
+}
+
+func strike(s string, style Style) string {
+	switch style {
+	case Telegram:
+		return "<s>" + s + "</s>"
+	case Plain:
+		return s
+	}
+	return "~" + s + "~"
+}
+
+func link(text, url string, style Style) string {
+	switch style {
+	case Telegram:
+		return `<a href="` + html.EscapeString(url) + `">` + text + "</a>"
+	case Slack:
+		return "<" + url + "|" + text + ">"
+	}
+	if text == url {
+		return url
+	}
+	return text + " (" + url + ")"
+}
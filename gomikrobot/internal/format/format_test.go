@@ -0,0 +1,101 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sample = "## Plan\n" +
+	"Use **bold**, *italic*, ~~old~~ and `a<b` in [docs](https://example.com).\n" +
+	"- first\n" +
+	"- second\n" +
+	"```go\n" +
+	"if a < b && c {\n" +
+	"```"
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		style Style
+		want  string
+	}{
+		{Markdown, sample},
+		{WhatsApp, "*Plan*\n" +
+			"Use *bold*, _italic_, ~old~ and `a<b` in docs (https://example.com).\n" +
+			"• first\n• second\n" +
+			"```\nif a < b && c {\n```"},
+		{Slack, "*Plan*\n" +
+			"Use *bold*, _italic_, ~old~ and `a&lt;b` in <https://example.com|docs>.\n" +
+			"• first\n• second\n" +
+			"```\nif a < b && c {\n```"},
+		{Telegram, "<b>Plan</b>\n" +
+			`Use <b>bold</b>, <i>italic</i>, <s>old</s> and <code>a&lt;b</code> in <a href="https://example.com">docs</a>.` + "\n" +
+			"• first\n• second\n" +
+			`<pre><code class="language-go">if a &lt; b &amp;&amp; c {</code></pre>`},
+		{Discord, "## Plan\n" +
+			"Use **bold**, *italic*, ~~old~~ and `a<b` in [docs](https://example.com).\n" +
+			"• first\n• second\n" +
+			"```go\nif a < b && c {\n```"},
+		{Plain, "Plan\n" +
+			"Use bold, italic, old and a<b in docs (https://example.com).\n" +
+			"• first\n• second\n" +
+			"if a < b && c {"},
+	}
+	for _, tt := range tests {
+		if got := Convert(sample, tt.style); got != tt.want {
+			t.Errorf("%s:\n got %q\nwant %q", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestConvertLeavesWordsWithUnderscoresAlone(t *testing.T) {
+	in := "Set snake_case_name to 2*3*4."
+	if got := Convert(in, WhatsApp); got != in {
+		t.Errorf("Convert = %q", got)
+	}
+}
+
+func TestConvertClosesUnterminatedCodeBlock(t *testing.T) {
+	if got := Convert("```\nx", Telegram); got != "<pre><code>x</code></pre>" {
+		t.Errorf("Telegram = %q", got)
+	}
+	if got := Convert("```\nx", WhatsApp); got != "```\nx\n```" {
+		t.Errorf("WhatsApp = %q", got)
+	}
+}
+
+func TestStyleFor(t *testing.T) {
+	for channel, want := range map[string]Style{"whatsapp": WhatsApp, "slack": Slack, "signal": Plain, "webhook": Markdown} {
+		if got := StyleFor(channel); got != want {
+			t.Errorf("StyleFor(%s) = %s, want %s", channel, got, want)
+		}
+	}
+	if _, err := Parse("html"); err == nil {
+		t.Error("Parse accepted an unknown style")
+	}
+}
+
+func TestExtractCode(t *testing.T) {
+	dir := t.TempDir()
+	md := "Short:\n```\nok\n```\nLong:\n```python\n" + strings.Repeat("print(1)\n", 10) + "```\nEnd"
+	got, files, err := ExtractCode(md, 50, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Ext(files[0]) != ".py" {
+		t.Fatalf("files = %q", files)
+	}
+	want := "Short:\n```\nok\n```\nLong:\n[code attached: " + filepath.Base(files[0]) + "]\nEnd"
+	if got != want {
+		t.Errorf("ExtractCode = %q, want %q", got, want)
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil || string(data) != strings.Repeat("print(1)\n", 10) {
+		t.Errorf("file = %q, %v", data, err)
+	}
+
+	if same, files, _ := ExtractCode(md, 0, dir); same != md || files != nil {
+		t.Error("ExtractCode changed md with maxChars 0")
+	}
+}
@@ -0,0 +1,182 @@
+// Package health runs readiness checks for the gateway's dependencies.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check verifies one subsystem and returns nil if it is healthy.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check.
+type Result struct {
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of all checks.
+type Report struct {
+	Ready     bool              `json:"ready"`
+	Failing   []string          `json:"failing,omitempty"`
+	Checks    map[string]Result `json:"checks"`
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// Checker runs named checks concurrently and caches the report briefly, so
+// frequent probes do not hammer the provider or the disk.
+type Checker struct {
+	// Timeout bounds each check (default 5s); CacheTTL is how long a report
+	// is reused (0 disables caching).
+	Timeout  time.Duration
+	CacheTTL time.Duration
+
+	mu     sync.Mutex
+	names  []string
+	checks map[string]Check
+	last   *Report
+}
+
+// Add registers a check. A check with the same name is replaced.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.checks == nil {
+		c.checks = map[string]Check{}
+	}
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+	c.last = nil
+}
+
+// Run executes all checks, or returns the cached report if it is recent.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last != nil && time.Since(c.last.CheckedAt) < c.CacheTTL {
+		return *c.last
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	report := Report{Ready: true, Checks: make(map[string]Result, len(c.names)), CheckedAt: time.Now()}
+	var (
+		wg    sync.WaitGroup
+		resMu sync.Mutex
+	)
+	for _, name := range c.names {
+		wg.Add(1)
+		go func(name string, check Check) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			start := time.Now()
+			err := runCheck(ctx, check)
+			res := Result{OK: err == nil, Duration: time.Since(start).Round(time.Millisecond).String()}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			resMu.Lock()
+			report.Checks[name] = res
+			resMu.Unlock()
+		}(name, c.checks[name])
+	}
+	wg.Wait()
+
+	for name, res := range report.Checks {
+		if !res.OK {
+			report.Ready = false
+			report.Failing = append(report.Failing, name)
+		}
+	}
+	sort.Strings(report.Failing)
+	c.last = &report
+	return report
+}
+
+// runCheck returns the check's error, or a timeout error if it does not
+// return in time.
+func runCheck(ctx context.Context, check Check) error {
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out")
+	}
+}
+
+// Handler serves the report as JSON: 200 when ready, 503 otherwise.
+func (c *Checker) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if !report.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Flag returns a check that fails with msg until ok reports true.
+func Flag(ok func() bool, msg string) Check {
+	return func(ctx context.Context) error {
+		if !ok() {
+			return fmt.Errorf("%s", msg)
+		}
+		return nil
+	}
+}
+
+// Dial returns a check that opens a TCP connection to the host of rawURL.
+func Dial(rawURL string) Check {
+	return func(ctx context.Context) error {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid URL %q", rawURL)
+		}
+		host := u.Host
+		if u.Port() == "" {
+			port := "80"
+			if u.Scheme == "https" || u.Scheme == "wss" {
+				port = "443"
+			}
+			host = net.JoinHostPort(u.Hostname(), port)
+		}
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", host)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// DiskSpace returns a check that fails when the file system holding path
+// has less than minFree bytes available.
+func DiskSpace(path string, minFree uint64) Check {
+	return func(ctx context.Context) error {
+		free, err := freeBytes(path)
+		if err != nil {
+			return err
+		}
+		if free < minFree {
+			return fmt.Errorf("%d MB free in %s, need %d MB", free>>20, path, minFree>>20)
+		}
+		return nil
+	}
+}
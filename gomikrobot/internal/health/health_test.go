@@ -0,0 +1,62 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckerReport(t *testing.T) {
+	calls := 0
+	c := &Checker{Timeout: 50 * time.Millisecond, CacheTTL: time.Minute}
+	c.Add("db", func(ctx context.Context) error { calls++; return nil })
+	c.Add("provider", func(ctx context.Context) error { return errors.New("unreachable") })
+	c.Add("slow", func(ctx context.Context) error { time.Sleep(time.Second); return nil })
+
+	rec := httptest.NewRecorder()
+	c.Handler()(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Ready || len(report.Failing) != 2 || report.Failing[0] != "provider" || report.Failing[1] != "slow" {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if !report.Checks["db"].OK || report.Checks["provider"].Error != "unreachable" || report.Checks["slow"].Error != "timed out" {
+		t.Errorf("unexpected checks %+v", report.Checks)
+	}
+
+	// Reports are cached.
+	c.Run(context.Background())
+	if calls != 1 {
+		t.Errorf("db check ran %d times, want 1", calls)
+	}
+}
+
+func TestCheckerReady(t *testing.T) {
+	ok := false
+	c := &Checker{}
+	c.Add("startup", Flag(func() bool { return ok }, "starting"))
+	if r := c.Run(context.Background()); r.Ready || r.Checks["startup"].Error != "starting" {
+		t.Errorf("expected not ready, got %+v", r)
+	}
+	ok = true
+	rec := httptest.NewRecorder()
+	c.Handler()(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d", rec.Code)
+	}
+	if err := DiskSpace(t.TempDir(), 1)(context.Background()); err != nil {
+		t.Errorf("DiskSpace: %v", err)
+	}
+	if err := DiskSpace(t.TempDir(), 1<<62)(context.Background()); err == nil {
+		t.Error("DiskSpace should fail for an impossible minimum")
+	}
+}
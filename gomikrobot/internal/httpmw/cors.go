@@ -0,0 +1,45 @@
+package httpmw
+
+import (
+	"net/http"
+	"strings"
+)
+
+// CORS allows browsers on origins to call the handler with credentials.
+// "*" allows any origin without credentials. Preflight requests from
+// allowed origins are answered directly.
+func CORS(origins []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || len(origins) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Add("Vary", "Origin")
+			allowed := false
+			for _, o := range origins {
+				switch {
+				case o == "*":
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+					allowed = true
+				case strings.EqualFold(strings.TrimSuffix(o, "/"), origin):
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					w.Header().Set("Access-Control-Allow-Credentials", "true")
+					allowed = true
+				}
+				if allowed {
+					break
+				}
+			}
+			if allowed && r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Token, "+CSRFHeader)
+				w.Header().Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,13 +1,22 @@
 package httpmw
 
 import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"fmt"
+	"math"
 	"net"
 	"net/http"
+	"net/netip"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/kamir/gomikrobot/internal/metrics"
 )
 
 // Middleware wraps an http.Handler.
@@ -33,7 +42,7 @@ func Recoverer() Middleware {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if rec := recover(); rec != nil {
-					fmt.Printf("❌ HTTP panic recovered: %v\n%s\n", rec, debug.Stack())
+					fmt.Printf("❌ HTTP panic recovered (request %s): %v\n%s\n", RequestIDFrom(r.Context()), rec, debug.Stack())
 					http.Error(w, "internal server error", http.StatusInternalServerError)
 				}
 			}()
@@ -56,6 +65,17 @@ func MaxBodyBytes(n int64) Middleware {
 	}
 }
 
+// rateLimited counts requests rejected by RateLimiter, labeled by tier.
+var rateLimited = metrics.Default.Counter("gomikrobot_http_rate_limited_total", "HTTP requests rejected by the rate limiter.")
+
+// Limit is a token-bucket rate: rps tokens refill per second, up to burst.
+type Limit struct {
+	RPS   float64
+	Burst int
+}
+
+func (l Limit) valid() bool { return l.RPS > 0 && l.Burst > 0 }
+
 // RateLimiter implements a simple token-bucket rate limiter.
 // Default use is per-client-IP (best effort).
 //
@@ -66,12 +86,19 @@ func MaxBodyBytes(n int64) Middleware {
 // - burst: max tokens stored
 // - cost: each request costs 1 token
 //
-// When out of tokens, responds 429.
+// Routes and tokens can have their own tiers. A request carrying a token
+// with a tier is limited per token rather than per IP; otherwise the
+// longest matching route prefix applies, then the default.
+//
+// When out of tokens, responds 429. Every response carries
+// X-RateLimit-Limit, X-RateLimit-Remaining, and X-RateLimit-Reset.
 type RateLimiter struct {
 	rps   float64
 	burst float64
 
 	mu      sync.Mutex
+	routes  map[string]Limit
+	tokens  map[string]Limit
 	buckets map[string]*bucket
 }
 
@@ -95,12 +122,56 @@ func NewRateLimiter(rps float64, burst int) *RateLimiter {
 	}
 }
 
+// SetLimits changes rps and burst at runtime. Existing buckets keep their
+// tokens, capped to their new burst.
+func (rl *RateLimiter) SetLimits(rps float64, burst int) {
+	if rps <= 0 || burst <= 0 {
+		return
+	}
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.rps = rps
+	rl.burst = float64(burst)
+}
+
+// SetRouteLimits replaces the per-route tiers, keyed by path prefix.
+// Invalid limits are ignored.
+func (rl *RateLimiter) SetRouteLimits(routes map[string]Limit) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.routes = validLimits(routes)
+}
+
+// SetTokenLimits replaces the per-token tiers, keyed by the token sent in
+// X-API-Token or Authorization: Bearer. Invalid limits are ignored.
+func (rl *RateLimiter) SetTokenLimits(tokens map[string]Limit) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.tokens = validLimits(tokens)
+}
+
+func validLimits(in map[string]Limit) map[string]Limit {
+	out := make(map[string]Limit, len(in))
+	for k, l := range in {
+		if k != "" && l.valid() {
+			out[k] = l
+		}
+	}
+	return out
+}
+
 func (rl *RateLimiter) Middleware() Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			key := clientIPKey(r)
-			if !rl.allow(key) {
-				w.Header().Set("Retry-After", "1")
+			ok, lim, remaining, reset, tier := rl.take(r)
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(lim.Burst))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(reset))
+			if !ok {
+				rateLimited.Inc("tier", tier)
+				retry := int(math.Ceil(1 / lim.RPS))
+				h.Set("Retry-After", strconv.Itoa(max(retry, 1)))
 				http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
 				return
 			}
@@ -109,14 +180,18 @@ func (rl *RateLimiter) Middleware() Middleware {
 	}
 }
 
-func (rl *RateLimiter) allow(key string) bool {
+// take charges one token to the request's bucket. It reports whether the
+// request is allowed, the limit applied, the whole tokens left, the
+// seconds until the bucket is full again, and the tier name.
+func (rl *RateLimiter) take(r *http.Request) (bool, Limit, int, int, string) {
 	now := time.Now()
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
+	key, lim, tier := rl.classify(r)
 	b := rl.buckets[key]
 	if b == nil {
-		b = &bucket{tokens: rl.burst, last: now, lastHit: now}
+		b = &bucket{tokens: float64(lim.Burst), last: now, lastHit: now}
 		rl.buckets[key] = b
 	}
 
@@ -130,47 +205,151 @@ func (rl *RateLimiter) allow(key string) bool {
 		}
 	}
 
+	burst := float64(lim.Burst)
 	elapsed := now.Sub(b.last).Seconds()
 	if elapsed > 0 {
-		b.tokens += elapsed * rl.rps
-		if b.tokens > rl.burst {
-			b.tokens = rl.burst
-		}
+		b.tokens += elapsed * lim.RPS
 		b.last = now
 	}
+	if b.tokens > burst {
+		b.tokens = burst
+	}
 	b.lastHit = now
 
-	if b.tokens < 1 {
-		return false
+	ok := b.tokens >= 1
+	if ok {
+		b.tokens -= 1
 	}
-	b.tokens -= 1
-	return true
+	reset := int(math.Ceil((burst - b.tokens) / lim.RPS))
+	return ok, lim, int(b.tokens), reset, tier
 }
 
-func clientIPKey(r *http.Request) string {
-	// If a reverse proxy exists, it should set X-Forwarded-For.
-	// We intentionally take the *left-most* IP.
-	xff := r.Header.Get("X-Forwarded-For")
-	if xff != "" {
-		parts := strings.Split(xff, ",")
-		if len(parts) > 0 {
-			ip := strings.TrimSpace(parts[0])
-			if ip != "" {
-				return ip
-			}
+// classify picks the bucket key, limit, and tier for r. Callers hold rl.mu.
+func (rl *RateLimiter) classify(r *http.Request) (string, Limit, string) {
+	if tok := RequestToken(r); tok != "" {
+		if lim, ok := rl.tokens[tok]; ok {
+			sum := sha256.Sum256([]byte(tok))
+			return "token:" + hex.EncodeToString(sum[:8]), lim, "token"
 		}
 	}
-	rip := r.Header.Get("X-Real-IP")
-	if rip != "" {
-		return strings.TrimSpace(rip)
+	best := ""
+	for prefix := range rl.routes {
+		if strings.HasPrefix(r.URL.Path, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	ip := clientIPKey(r)
+	if best != "" {
+		return "route:" + best + "|" + ip, rl.routes[best], "route:" + best
 	}
+	return ip, Limit{RPS: rl.rps, Burst: int(rl.burst)}, "default"
+}
 
+// RequestToken returns the API token sent with r in the X-API-Token or
+// Authorization bearer header, if any. Tokens in the query string are
+// ignored; they end up in logs and browser history.
+func RequestToken(r *http.Request) string {
+	if tok := r.Header.Get("X-API-Token"); tok != "" {
+		return tok
+	}
+	tok, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return tok
+}
+
+// TokenOK reports whether r carries token, compared in constant time.
+// Without a token every request is allowed.
+func TokenOK(r *http.Request, token string) bool {
+	if token == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(RequestToken(r)), []byte(token)) == 1
+}
+
+// trustedProxies holds the networks whose forwarding headers are honored.
+var trustedProxies atomic.Pointer[[]netip.Prefix]
+
+// SetTrustedProxies sets the reverse proxies whose X-Forwarded-For,
+// X-Real-IP, and X-Forwarded-Proto headers are believed. Entries are CIDRs
+// or single IPs. With none, the headers are ignored and the connection's
+// address is the client.
+func SetTrustedProxies(entries []string) error {
+	prefixes := make([]netip.Prefix, 0, len(entries))
+	for _, e := range entries {
+		p, err := parseProxy(e)
+		if err != nil {
+			return err
+		}
+		prefixes = append(prefixes, p)
+	}
+	trustedProxies.Store(&prefixes)
+	return nil
+}
+
+func parseProxy(e string) (netip.Prefix, error) {
+	e = strings.TrimSpace(e)
+	if strings.Contains(e, "/") {
+		p, err := netip.ParsePrefix(e)
+		return p.Masked(), err
+	}
+	addr, err := netip.ParseAddr(e)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	if ps := trustedProxies.Load(); ps != nil {
+		for _, p := range *ps {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fromTrustedProxy reports whether r arrived through a trusted proxy.
+func fromTrustedProxy(r *http.Request) bool {
+	return trusted(remoteIP(r))
+}
+
+func remoteIP(r *http.Request) string {
 	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err == nil && host != "" {
 		return host
 	}
-	if r.RemoteAddr != "" {
-		return r.RemoteAddr
+	return r.RemoteAddr
+}
+
+// clientIPKey returns the client address of r. Forwarding headers only
+// count when the connection comes from a trusted proxy; X-Forwarded-For is
+// then read right to left, skipping further trusted hops, because only the
+// entries appended by our own proxies can be believed.
+func clientIPKey(r *http.Request) string {
+	remote := remoteIP(r)
+	if remote == "" {
+		return "unknown"
+	}
+	if !trusted(remote) {
+		return remote
+	}
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		parts := strings.Split(xff, ",")
+		for i := len(parts) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(parts[i])
+			if ip != "" && (!trusted(ip) || i == 0) {
+				return ip
+			}
+		}
+	}
+	if rip := strings.TrimSpace(r.Header.Get("X-Real-IP")); rip != "" {
+		return rip
 	}
-	return "unknown"
+	return remote
 }
@@ -0,0 +1,107 @@
+package httpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimiterTiers(t *testing.T) {
+	rl := NewRateLimiter(0.001, 2)
+	rl.SetRouteLimits(map[string]Limit{"/health": {RPS: 0.001, Burst: 5}})
+	rl.SetTokenLimits(map[string]Limit{"secret": {RPS: 0.001, Burst: 3}})
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), rl.Middleware())
+
+	count := func(path string, header map[string]string) (allowed int, last *httptest.ResponseRecorder) {
+		for range 10 {
+			req := httptest.NewRequest("GET", path, nil)
+			for k, v := range header {
+				req.Header.Set(k, v)
+			}
+			last = httptest.NewRecorder()
+			h.ServeHTTP(last, req)
+			if last.Code == http.StatusOK {
+				allowed++
+			}
+		}
+		return allowed, last
+	}
+
+	if n, rec := count("/chat", nil); n != 2 || rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("default tier: %d allowed, headers %v", n, rec.Header())
+	}
+	// Route tiers have their own buckets.
+	if n, _ := count("/health", nil); n != 5 {
+		t.Errorf("route tier: %d allowed, want 5", n)
+	}
+	// Tokens are limited per token, not by the exhausted IP bucket.
+	if n, rec := count("/chat", map[string]string{"X-API-Token": "secret"}); n != 3 || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("token tier: %d allowed, headers %v", n, rec.Header())
+	}
+	// Unknown tokens fall back to the default tier.
+	if n, _ := count("/chat", map[string]string{"Authorization": "Bearer guess"}); n != 0 {
+		t.Errorf("unknown token: %d allowed, want 0", n)
+	}
+	if got := rateLimited.Value("tier", "token"); got < 7 {
+		t.Errorf("rejected metric for token tier = %v", got)
+	}
+}
+
+func TestTokenOK(t *testing.T) {
+	tests := []struct {
+		target string
+		header map[string]string
+		want   bool
+	}{
+		{"/chat", map[string]string{"X-API-Token": "secret"}, true},
+		{"/chat", map[string]string{"Authorization": "Bearer secret"}, true},
+		{"/chat", map[string]string{"X-API-Token": "secret2"}, false},
+		{"/chat", nil, false},
+		// Tokens in the URL leak into logs and are not accepted.
+		{"/chat?token=secret", nil, false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", tt.target, nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+		if got := TokenOK(req, "secret"); got != tt.want {
+			t.Errorf("TokenOK(%s, %v) = %v, want %v", tt.target, tt.header, got, tt.want)
+		}
+	}
+	if !TokenOK(httptest.NewRequest("POST", "/chat", nil), "") {
+		t.Error("requests must be allowed without a configured token")
+	}
+}
+
+func TestClientIPTrustedProxies(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.0/8", "::1"}); err != nil {
+		t.Fatal(err)
+	}
+	defer SetTrustedProxies(nil)
+
+	tests := []struct {
+		remote, xff, want string
+	}{
+		// Spoofed header from an untrusted client is ignored.
+		{"203.0.113.9:5000", "1.2.3.4", "203.0.113.9"},
+		// A trusted proxy's header is used, skipping trusted hops.
+		{"10.0.0.2:443", "1.2.3.4, 198.51.100.7, 10.0.0.3", "198.51.100.7"},
+		{"[::1]:443", "198.51.100.7", "198.51.100.7"},
+		{"10.0.0.2:443", "", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			req.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if got := clientIPKey(req); got != tt.want {
+			t.Errorf("remote %s, XFF %q: got %s, want %s", tt.remote, tt.xff, got, tt.want)
+		}
+	}
+
+	if err := SetTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Error("expected error for invalid entry")
+	}
+}
@@ -0,0 +1,208 @@
+package httpmw
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SessionCookie holds the signed login session.
+	SessionCookie = "mikrobot_session"
+	// CSRFCookie holds the token browsers echo in the X-CSRF-Token header.
+	// It is readable by scripts on the dashboard origin.
+	CSRFCookie = "mikrobot_csrf"
+	// CSRFHeader must carry the CSRF token on state-changing requests.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// SessionAuthOptions configures SessionAuth.
+type SessionAuthOptions struct {
+	// Password is accepted on the login page.
+	Password string
+	// Token is accepted on the login page and, for scripts, in the
+	// X-API-Token or Authorization: Bearer header.
+	Token string
+	// TTL is how long a login lasts (default 7 days).
+	TTL time.Duration
+	// Public lists paths served without a session. A trailing "/" matches
+	// the whole subtree.
+	Public []string
+	// LoginPath is where browsers are redirected to log in (default /login).
+	LoginPath string
+}
+
+// SessionAuth protects a site with a password login and signed session
+// cookies. Cookie sessions must send the CSRF token on POST, PUT, PATCH,
+// and DELETE; header tokens are exempt because browsers never attach them
+// on their own.
+//
+// Sessions are signed with a key derived from the password and token, so
+// they survive restarts and end when either changes.
+type SessionAuth struct {
+	opts SessionAuthOptions
+	key  []byte
+}
+
+// NewSessionAuth creates a SessionAuth. With neither a password nor a token
+// configured it is disabled and lets every request through.
+func NewSessionAuth(opts SessionAuthOptions) *SessionAuth {
+	if opts.TTL <= 0 {
+		opts.TTL = 7 * 24 * time.Hour
+	}
+	if opts.LoginPath == "" {
+		opts.LoginPath = "/login"
+	}
+	sum := sha256.Sum256([]byte("gomikrobot dashboard session\x00" + opts.Password + "\x00" + opts.Token))
+	return &SessionAuth{opts: opts, key: sum[:]}
+}
+
+// Enabled reports whether a password or token is configured.
+func (a *SessionAuth) Enabled() bool {
+	return a.opts.Password != "" || a.opts.Token != ""
+}
+
+// CheckPassword reports whether pw is the configured password or token.
+func (a *SessionAuth) CheckPassword(pw string) bool {
+	if pw == "" {
+		return false
+	}
+	ok := a.opts.Password != "" && subtle.ConstantTimeCompare([]byte(pw), []byte(a.opts.Password)) == 1
+	return ok || (a.opts.Token != "" && subtle.ConstantTimeCompare([]byte(pw), []byte(a.opts.Token)) == 1)
+}
+
+// Login starts a session by setting the session and CSRF cookies.
+func (a *SessionAuth) Login(w http.ResponseWriter, r *http.Request) {
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	expires := time.Now().Add(a.opts.TTL)
+	payload := strconv.FormatInt(expires.Unix(), 10) + "." + hex.EncodeToString(nonce)
+
+	secure := isHTTPS(r)
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    payload + "." + a.sign("session."+payload),
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookie,
+		Value:    a.csrfToken(payload),
+		Path:     "/",
+		Expires:  expires,
+		Secure:   secure,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// Logout clears the session cookies.
+func (a *SessionAuth) Logout(w http.ResponseWriter) {
+	for _, name := range []string{SessionCookie, CSRFCookie} {
+		http.SetCookie(w, &http.Cookie{Name: name, Value: "", Path: "/", MaxAge: -1})
+	}
+}
+
+// Middleware rejects requests without a valid session or token. Browsers
+// asking for a page are redirected to the login page; everything else gets
+// 401.
+func (a *SessionAuth) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !a.Enabled() || a.public(r.URL.Path) || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if a.hasToken(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			payload, ok := a.session(r)
+			if !ok {
+				if r.Method == http.MethodGet && !strings.HasPrefix(r.URL.Path, "/api/") {
+					http.Redirect(w, r, a.opts.LoginPath+"?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+					return
+				}
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if unsafeMethod(r.Method) {
+				got := r.Header.Get(CSRFHeader)
+				if got == "" || subtle.ConstantTimeCompare([]byte(got), []byte(a.csrfToken(payload))) != 1 {
+					http.Error(w, "invalid CSRF token", http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// session returns the payload of a valid, unexpired session cookie.
+func (a *SessionAuth) session(r *http.Request) (string, bool) {
+	c, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return "", false
+	}
+	i := strings.LastIndexByte(c.Value, '.')
+	if i < 0 {
+		return "", false
+	}
+	payload, sig := c.Value[:i], c.Value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(a.sign("session."+payload))) {
+		return "", false
+	}
+	exp, _, _ := strings.Cut(payload, ".")
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || time.Now().Unix() > unix {
+		return "", false
+	}
+	return payload, true
+}
+
+func (a *SessionAuth) hasToken(r *http.Request) bool {
+	if a.opts.Token == "" {
+		return false
+	}
+	return TokenOK(r, a.opts.Token)
+}
+
+func (a *SessionAuth) public(path string) bool {
+	if path == a.opts.LoginPath {
+		return true
+	}
+	for _, p := range a.opts.Public {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *SessionAuth) csrfToken(payload string) string {
+	return a.sign("csrf." + payload)
+}
+
+func (a *SessionAuth) sign(s string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(s))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func unsafeMethod(m string) bool {
+	return m == http.MethodPost || m == http.MethodPut || m == http.MethodPatch || m == http.MethodDelete
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || (fromTrustedProxy(r) && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https"))
+}
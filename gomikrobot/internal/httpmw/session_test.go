@@ -0,0 +1,89 @@
+package httpmw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSessionAuth(t *testing.T) {
+	auth := NewSessionAuth(SessionAuthOptions{Password: "hunter2", Token: "tok", Public: []string{"/health"}})
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), auth.Middleware())
+
+	do := func(method, path string, cookies []*http.Cookie, header map[string]string) int {
+		req := httptest.NewRequest(method, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do("GET", "/health", nil, nil); code != http.StatusOK {
+		t.Errorf("public path: got %d", code)
+	}
+	if code := do("GET", "/timeline", nil, nil); code != http.StatusFound {
+		t.Errorf("page without session: got %d, want redirect", code)
+	}
+	if code := do("GET", "/api/v1/timeline", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("api without session: got %d", code)
+	}
+	if code := do("POST", "/api/v1/settings", nil, map[string]string{"X-API-Token": "tok"}); code != http.StatusOK {
+		t.Errorf("api token header: got %d", code)
+	}
+	if auth.CheckPassword("wrong") || !auth.CheckPassword("hunter2") || !auth.CheckPassword("tok") {
+		t.Error("CheckPassword mismatch")
+	}
+
+	rec := httptest.NewRecorder()
+	auth.Login(rec, httptest.NewRequest("POST", "/login", nil))
+	cookies := rec.Result().Cookies()
+	var csrf string
+	for _, c := range cookies {
+		if c.Name == CSRFCookie {
+			csrf = c.Value
+		}
+	}
+
+	if code := do("GET", "/api/v1/timeline", cookies, nil); code != http.StatusOK {
+		t.Errorf("api with session: got %d", code)
+	}
+	if code := do("POST", "/api/v1/settings", cookies, nil); code != http.StatusForbidden {
+		t.Errorf("post without CSRF token: got %d", code)
+	}
+	if code := do("POST", "/api/v1/settings", cookies, map[string]string{CSRFHeader: csrf}); code != http.StatusOK {
+		t.Errorf("post with CSRF token: got %d", code)
+	}
+
+	// Changing the password invalidates existing sessions.
+	rotated := NewSessionAuth(SessionAuthOptions{Password: "new"})
+	h = Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), rotated.Middleware())
+	if code := do("GET", "/api/v1/timeline", cookies, nil); code != http.StatusUnauthorized {
+		t.Errorf("session after password change: got %d", code)
+	}
+}
+
+func TestCORS(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), CORS([]string{"https://home.example.com"}))
+
+	req := httptest.NewRequest("GET", "/api/v1/timeline", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("disallowed origin got Allow-Origin %q", got)
+	}
+
+	req = httptest.NewRequest("OPTIONS", "/api/v1/settings", nil)
+	req.Header.Set("Origin", "https://home.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent || rec.Header().Get("Access-Control-Allow-Origin") != "https://home.example.com" {
+		t.Errorf("preflight: code %d, headers %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,31 @@
+package httpmw
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/kamir/gomikrobot/internal/tracing"
+)
+
+// Trace records a server span per request, continuing the caller's trace
+// when the request carries a traceparent header. Requests to skip paths
+// (health checks, metrics scrapes) are not traced.
+func Trace(skip ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !tracing.Enabled() || slices.Contains(skip, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, span := tracing.StartKind(tracing.Extract(r.Context(), r.Header), tracing.KindServer, r.Method+" "+r.URL.Path,
+				"http.request.method", r.Method, "url.path", r.URL.Path, "request_id", RequestIDFrom(r.Context()))
+			defer span.End()
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			span.SetAttr("http.response.status_code", rec.status)
+			if rec.status >= 500 {
+				span.Fail(http.StatusText(rec.status))
+			}
+		})
+	}
+}
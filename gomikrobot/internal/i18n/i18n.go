@@ -0,0 +1,183 @@
+// Package i18n detects message languages and localizes the bot's canned
+// replies. The model localizes its own answers; this only covers text the
+// bot sends without asking the model, such as errors.
+package i18n
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Message keys for canned replies.
+const (
+	MsgError            = "error"
+	MsgMaxIterations    = "max_iterations"
+	MsgMediaType        = "media_type"
+	MsgMediaTooLarge    = "media_too_large"
+	MsgAudioUnsupported = "audio_unsupported"
+	MsgAudioConvert     = "audio_convert"
+	MsgStopped          = "stopped"
+	MsgRefused          = "refused"
+)
+
+// catalog holds fmt templates per language code and message key. English
+// is complete; other languages fall back to it per message.
+var catalog = map[string]map[string]string{
+	"en": {
+		MsgError:            "Error: %v",
+		MsgMaxIterations:    "Max iterations reached. Please try a simpler request.",
+		MsgMediaType:        "Sorry, I can't accept %s files (%s). Allowed types: %s.",
+		MsgMediaTooLarge:    "Sorry, that %s is too large (%s). The limit is %s.",
+		MsgAudioUnsupported: "Sorry, I can't transcribe %s audio.",
+		MsgAudioConvert:     "Sorry, I couldn't convert that audio message. Could you send it as text?",
+		MsgStopped:          "Stopped.",
+		MsgRefused:          "Sorry, I can't help with that request. Could you rephrase it or ask something else?",
+	},
+	"de": {
+		MsgError:            "Fehler: %v",
+		MsgMaxIterations:    "Maximale Anzahl an Schritten erreicht. Bitte versuche eine einfachere Anfrage.",
+		MsgMediaType:        "Entschuldigung, %s-Dateien (%s) kann ich nicht annehmen. Erlaubte Typen: %s.",
+		MsgMediaTooLarge:    "Entschuldigung, diese Datei (%s) ist zu groß (%s). Das Limit ist %s.",
+		MsgAudioUnsupported: "Entschuldigung, %s-Audio kann ich nicht transkribieren.",
+		MsgAudioConvert:     "Entschuldigung, ich konnte die Sprachnachricht nicht umwandeln. Kannst du sie als Text schicken?",
+		MsgStopped:          "Abgebrochen.",
+		MsgRefused:          "Entschuldigung, bei dieser Anfrage kann ich nicht helfen. Kannst du sie anders formulieren oder etwas anderes fragen?",
+	},
+	"fr": {
+		MsgError:            "Erreur : %v",
+		MsgMaxIterations:    "Nombre maximal d'étapes atteint. Essaie une demande plus simple.",
+		MsgMediaType:        "Désolé, je ne peux pas accepter les fichiers %s (%s). Types autorisés : %s.",
+		MsgMediaTooLarge:    "Désolé, ce fichier (%s) est trop volumineux (%s). La limite est de %s.",
+		MsgAudioUnsupported: "Désolé, je ne peux pas transcrire l'audio %s.",
+		MsgAudioConvert:     "Désolé, je n'ai pas pu convertir ce message vocal. Peux-tu l'envoyer par écrit ?",
+		MsgStopped:          "Arrêté.",
+		MsgRefused:          "Désolé, je ne peux pas aider avec cette demande. Peux-tu la reformuler ou demander autre chose ?",
+	},
+	"es": {
+		MsgError:            "Error: %v",
+		MsgMaxIterations:    "Se alcanzó el número máximo de pasos. Intenta una petición más sencilla.",
+		MsgMediaType:        "Lo siento, no puedo aceptar archivos %s (%s). Tipos permitidos: %s.",
+		MsgMediaTooLarge:    "Lo siento, ese archivo (%s) es demasiado grande (%s). El límite es %s.",
+		MsgAudioUnsupported: "Lo siento, no puedo transcribir audio %s.",
+		MsgAudioConvert:     "Lo siento, no pude convertir ese mensaje de voz. ¿Puedes enviarlo como texto?",
+		MsgStopped:          "Detenido.",
+		MsgRefused:          "Lo siento, no puedo ayudar con esa petición. ¿Puedes reformularla o preguntar otra cosa?",
+	},
+}
+
+// names maps language codes to English names for prompts, and is used to
+// recognize names in settings.
+var names = map[string]string{
+	"en": "English",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+	"it": "Italian",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+}
+
+// nativeNames lets users set their language in their own words.
+var nativeNames = map[string]string{
+	"deutsch":    "de",
+	"français":   "fr",
+	"francais":   "fr",
+	"español":    "es",
+	"espanol":    "es",
+	"italiano":   "it",
+	"nederlands": "nl",
+	"português":  "pt",
+	"portugues":  "pt",
+}
+
+// T returns the canned message key in lang, formatted with args. Unknown
+// languages and untranslated messages fall back to English.
+func T(lang, key string, args ...any) string {
+	tmpl, ok := catalog[Code(lang)][key]
+	if !ok {
+		tmpl = catalog["en"][key]
+	}
+	if len(args) == 0 {
+		return tmpl
+	}
+	return fmt.Sprintf(tmpl, args...)
+}
+
+// Code normalizes a language setting ("de", "de-DE", "German", "Deutsch")
+// to its code. Unrecognized values are returned lowercased.
+func Code(lang string) string {
+	l := strings.ToLower(strings.TrimSpace(lang))
+	if code, _, ok := strings.Cut(strings.ReplaceAll(l, "_", "-"), "-"); ok && names[code] != "" {
+		return code
+	}
+	if names[l] != "" {
+		return l
+	}
+	if code, ok := nativeNames[l]; ok {
+		return code
+	}
+	for code, name := range names {
+		if strings.EqualFold(name, l) {
+			return code
+		}
+	}
+	return l
+}
+
+// Name returns the English name of lang for use in prompts, or lang itself
+// if it is not a known code.
+func Name(lang string) string {
+	if name := names[Code(lang)]; name != "" {
+		return name
+	}
+	return strings.TrimSpace(lang)
+}
+
+// stopwords are frequent short words that identify a language.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "you", "what", "how", "with", "this", "that", "for", "can", "please", "thanks", "my", "it"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "nicht", "du", "wie", "was", "mit", "ein", "eine", "bitte", "danke", "auf", "für", "mir", "kannst", "hast"},
+	"fr": {"le", "la", "les", "et", "est", "je", "tu", "vous", "pas", "que", "une", "un", "pour", "avec", "merci", "bonjour", "comment", "moi"},
+	"es": {"el", "la", "los", "las", "y", "es", "yo", "tú", "que", "una", "por", "para", "con", "gracias", "hola", "cómo", "qué", "puedes"},
+	"it": {"il", "lo", "gli", "e", "è", "io", "tu", "che", "non", "una", "per", "con", "grazie", "ciao", "come", "sono", "puoi"},
+	"nl": {"de", "het", "een", "en", "is", "ik", "jij", "je", "niet", "wat", "hoe", "met", "voor", "bedankt", "dank", "alsjeblieft", "kun"},
+	"pt": {"o", "os", "as", "e", "é", "eu", "você", "que", "não", "uma", "para", "com", "obrigado", "obrigada", "olá", "como", "pode"},
+}
+
+// Detect guesses the language of text from common words. It returns "" when
+// the text is too short or the result is ambiguous.
+func Detect(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) < 3 {
+		return ""
+	}
+	scores := make(map[string]int)
+	for _, w := range words {
+		for code, list := range stopwords {
+			for _, s := range list {
+				if w == s {
+					scores[code]++
+					break
+				}
+			}
+		}
+	}
+	best, second := "", 0
+	for code, n := range scores {
+		switch {
+		case best == "" || n > scores[best]:
+			second = scores[best]
+			best = code
+		case n > second:
+			second = n
+		}
+	}
+	// Require two hits and a clear lead over the runner-up.
+	if best == "" || scores[best] < 2 || scores[best] <= second {
+		return ""
+	}
+	return best
+}
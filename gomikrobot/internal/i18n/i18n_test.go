@@ -0,0 +1,47 @@
+package i18n
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Kannst du mir bitte sagen, wie das Wetter morgen ist?", "de"},
+		{"Can you tell me what the weather is like tomorrow?", "en"},
+		{"Bonjour, je voudrais savoir comment tu vas", "fr"},
+		{"Hola, ¿cómo estás? Gracias por la ayuda", "es"},
+		{"ok", ""},
+		{"👍 👍 👍", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAndName(t *testing.T) {
+	for _, in := range []string{"de", "de-DE", "German", "deutsch", " DE_at "} {
+		if got := Code(in); got != "de" {
+			t.Errorf("Code(%q) = %q", in, got)
+		}
+	}
+	if got := Name("fr"); got != "French" {
+		t.Errorf("Name(fr) = %q", got)
+	}
+	if got := Name("Klingon"); got != "Klingon" {
+		t.Errorf("unknown language name = %q", got)
+	}
+}
+
+func TestTFallsBackToEnglish(t *testing.T) {
+	if got := T("de", MsgError, "boom"); got != "Fehler: boom" {
+		t.Errorf("German error = %q", got)
+	}
+	if got := T("it", MsgAudioConvert); got != catalog["en"][MsgAudioConvert] {
+		t.Errorf("untranslated message = %q", got)
+	}
+	if got := T("", MsgMediaTooLarge, "image", "12 MB", "10 MB"); got != "Sorry, that image is too large (12 MB). The limit is 10 MB." {
+		t.Errorf("default language = %q", got)
+	}
+}
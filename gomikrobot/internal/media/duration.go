@@ -0,0 +1,160 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration returns the playing time of the audio file at file. WAV and Ogg
+// (Opus, Vorbis) are parsed directly; other formats need ffprobe. Results
+// are cached until the file changes.
+func (l *Library) Duration(file string) (time.Duration, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	c, ok := l.durations[file]
+	l.mu.Unlock()
+	if ok && c.mod.Equal(info.ModTime()) {
+		return c.d, nil
+	}
+
+	var d time.Duration
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".wav":
+		d, err = wavDuration(file)
+	case ".ogg", ".oga", ".opus":
+		d, err = oggDuration(file)
+	default:
+		d, err = l.probeDuration(file)
+	}
+	if err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	l.durations[file] = cachedDuration{mod: info.ModTime(), d: d}
+	l.mu.Unlock()
+	return d, nil
+}
+
+// wavDuration reads the byte rate and data size from a RIFF WAVE header.
+func wavDuration(file string) (time.Duration, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var riff [12]byte
+	if _, err := io.ReadFull(f, riff[:]); err != nil || string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return 0, errors.New("not a WAV file")
+	}
+	var byteRate uint32
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(f, hdr[:]); err != nil {
+			return 0, errors.New("WAV data chunk not found")
+		}
+		size := binary.LittleEndian.Uint32(hdr[4:])
+		switch string(hdr[0:4]) {
+		case "fmt ":
+			var fmtChunk [12]byte
+			if size < 12 {
+				return 0, errors.New("short WAV fmt chunk")
+			}
+			if _, err := io.ReadFull(f, fmtChunk[:]); err != nil {
+				return 0, err
+			}
+			byteRate = binary.LittleEndian.Uint32(fmtChunk[8:])
+			size -= 12
+		case "data":
+			if byteRate == 0 {
+				return 0, errors.New("WAV fmt chunk missing")
+			}
+			return time.Duration(float64(size) / float64(byteRate) * float64(time.Second)), nil
+		}
+		if _, err := f.Seek(int64(size+size%2), io.SeekCurrent); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// oggDuration divides the granule position of the last page by the sample
+// rate from the identification header. Opus always counts at 48 kHz after
+// its pre-skip.
+func oggDuration(file string) (time.Duration, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+	if !bytes.HasPrefix(head, []byte("OggS")) {
+		return 0, errors.New("not an Ogg file")
+	}
+	var rate, preSkip uint64
+	if i := bytes.Index(head, []byte("OpusHead")); i >= 0 && len(head) >= i+12 {
+		rate, preSkip = 48000, uint64(binary.LittleEndian.Uint16(head[i+10:]))
+	} else if i := bytes.Index(head, []byte("\x01vorbis")); i >= 0 && len(head) >= i+16 {
+		rate = uint64(binary.LittleEndian.Uint32(head[i+12:]))
+	}
+	if rate == 0 {
+		return 0, errors.New("unknown Ogg codec")
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	tail := int64(65536)
+	if tail > info.Size() {
+		tail = info.Size()
+	}
+	buf := make([]byte, tail)
+	if _, err := f.ReadAt(buf, info.Size()-tail); err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	i := bytes.LastIndex(buf, []byte("OggS"))
+	if i < 0 || len(buf) < i+14 {
+		return 0, errors.New("no Ogg page found")
+	}
+	granule := binary.LittleEndian.Uint64(buf[i+6:])
+	if granule < preSkip {
+		return 0, nil
+	}
+	return time.Duration(float64(granule-preSkip) / float64(rate) * float64(time.Second)), nil
+}
+
+func (l *Library) probeDuration(file string) (time.Duration, error) {
+	bin := l.FFprobe
+	if bin == "" {
+		bin = "ffprobe"
+	}
+	if _, err := exec.LookPath(bin); err != nil {
+		return 0, fmt.Errorf("ffprobe not found: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, bin, "-v", "error", "-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1", file).Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe: %w", err)
+	}
+	secs, err := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe: unexpected output %q", out)
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
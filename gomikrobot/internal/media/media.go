@@ -0,0 +1,252 @@
+// Package media serves the files channels save under the media directory
+// with signed, expiring URLs instead of an open file server, and lists them
+// for the dashboard gallery with image thumbnails and audio durations.
+package media
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinds of media files, by extension.
+const (
+	KindImage    = "image"
+	KindAudio    = "audio"
+	KindVideo    = "video"
+	KindDocument = "document"
+)
+
+var kinds = map[string]string{
+	".jpg": KindImage, ".jpeg": KindImage, ".png": KindImage, ".gif": KindImage, ".webp": KindImage,
+	".mp3": KindAudio, ".ogg": KindAudio, ".oga": KindAudio, ".opus": KindAudio, ".wav": KindAudio,
+	".m4a": KindAudio, ".aac": KindAudio, ".flac": KindAudio, ".amr": KindAudio, ".webm": KindAudio,
+	".mp4": KindVideo, ".mov": KindVideo, ".3gp": KindVideo,
+}
+
+// KindOf returns the kind of the file at name.
+func KindOf(name string) string {
+	if k, ok := kinds[strings.ToLower(filepath.Ext(name))]; ok {
+		return k
+	}
+	return KindDocument
+}
+
+// Library signs URLs for and serves the files below Dir.
+type Library struct {
+	Dir string
+	// Prefix is the URL path the library is mounted at (default "/media/").
+	Prefix string
+	// TTL is how long a signed URL stays valid (default 24h). Expiry is
+	// rounded up to the hour so URLs are stable between page refreshes.
+	TTL time.Duration
+	// FFprobe measures audio formats not parsed natively (default "ffprobe").
+	FFprobe string
+
+	key []byte
+
+	mu        sync.Mutex
+	durations map[string]cachedDuration
+}
+
+type cachedDuration struct {
+	mod time.Time
+	d   time.Duration
+}
+
+// NewLibrary creates a Library for dir with a random signing key, so URLs
+// stop working when the gateway restarts.
+func NewLibrary(dir string) *Library {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return &Library{Dir: dir, TTL: 24 * time.Hour, FFprobe: "ffprobe", key: key, durations: map[string]cachedDuration{}}
+}
+
+// Rel returns the slash-separated path of p relative to Dir. p may be
+// absolute or already relative; paths outside Dir are rejected.
+func (l *Library) Rel(p string) (string, bool) {
+	if p == "" {
+		return "", false
+	}
+	if filepath.IsAbs(p) {
+		rel, err := filepath.Rel(l.Dir, p)
+		if err != nil {
+			return "", false
+		}
+		p = rel
+	}
+	rel := path.Clean(filepath.ToSlash(p))
+	if rel == "." || rel == ".." || strings.HasPrefix(rel, "../") || strings.HasPrefix(rel, "/") {
+		return "", false
+	}
+	return rel, true
+}
+
+// URL returns a signed URL for the file at p, or "" if p is outside Dir.
+func (l *Library) URL(p string) string {
+	rel, ok := l.Rel(p)
+	if !ok {
+		return ""
+	}
+	return l.sign(rel, "", time.Now())
+}
+
+// ThumbURL returns a signed URL for a thumbnail of the image at p, or ""
+// if no thumbnail can be made.
+func (l *Library) ThumbURL(p string) string {
+	rel, ok := l.Rel(p)
+	if !ok || !thumbnailable(rel) {
+		return ""
+	}
+	return l.sign(rel, variantThumb, time.Now())
+}
+
+const variantThumb = "thumb"
+
+func (l *Library) sign(rel, variant string, now time.Time) string {
+	ttl := l.TTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	exp := now.Truncate(time.Hour).Add(time.Hour + ttl).Unix()
+	q := url.Values{"exp": {strconv.FormatInt(exp, 10)}, "sig": {l.mac(rel, variant, exp)}}
+	if variant != "" {
+		q.Set("v", variant)
+	}
+	segs := strings.Split(rel, "/")
+	for i, s := range segs {
+		segs[i] = url.PathEscape(s)
+	}
+	prefix := l.Prefix
+	if prefix == "" {
+		prefix = "/media/"
+	}
+	return prefix + strings.Join(segs, "/") + "?" + q.Encode()
+}
+
+func (l *Library) mac(rel, variant string, exp int64) string {
+	m := hmac.New(sha256.New, l.key)
+	m.Write([]byte(rel + "\x00" + variant + "\x00" + strconv.FormatInt(exp, 10)))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// ServeHTTP serves a file for a signed URL. Mount it with
+// http.StripPrefix(l.Prefix, ...).
+func (l *Library) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rel, ok := l.Rel(r.URL.Path)
+	q := r.URL.Query()
+	exp, err := strconv.ParseInt(q.Get("exp"), 10, 64)
+	if !ok || err != nil || time.Now().Unix() > exp ||
+		!hmac.Equal([]byte(q.Get("sig")), []byte(l.mac(rel, q.Get("v"), exp))) {
+		http.Error(w, "invalid or expired link", http.StatusForbidden)
+		return
+	}
+
+	file := filepath.Join(l.Dir, filepath.FromSlash(rel))
+	if q.Get("v") == variantThumb {
+		thumb, err := l.thumbnail(file, rel)
+		if err != nil {
+			http.Error(w, "no thumbnail", http.StatusNotFound)
+			return
+		}
+		file = thumb
+	}
+	if info, err := os.Stat(file); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	// Inbound documents come from anyone who can message the bot, so only
+	// images, audio, and video are shown inline, and nothing served here
+	// may run scripts on the dashboard's origin.
+	h := w.Header()
+	h.Set("Cache-Control", "private, max-age=3600")
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("Content-Security-Policy", "sandbox")
+	ctype := mime.TypeByExtension(strings.ToLower(filepath.Ext(file)))
+	if KindOf(file) == KindDocument || ctype == "" {
+		ctype = "application/octet-stream"
+		h.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filepath.Base(file)}))
+	}
+	h.Set("Content-Type", ctype)
+	http.ServeFile(w, r, file)
+}
+
+// Item describes one media file.
+type Item struct {
+	// Path is relative to the library directory.
+	Path     string    `json:"path"`
+	Name     string    `json:"name"`
+	Kind     string    `json:"kind"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mod_time"`
+	URL      string    `json:"url"`
+	ThumbURL string    `json:"thumb_url,omitempty"`
+	// DurationMS is set for audio by Describe.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+}
+
+// List returns the media files of kind (all kinds if empty), newest first.
+// Hidden files and directories, including the thumbnail cache, are skipped.
+func (l *Library) List(kind string) ([]Item, error) {
+	var items []Item
+	err := filepath.WalkDir(l.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == l.Dir {
+				return fs.SkipAll
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") && p != l.Dir {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || (kind != "" && KindOf(p) != kind) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		rel, _ := l.Rel(p)
+		items = append(items, Item{
+			Path:     rel,
+			Name:     d.Name(),
+			Kind:     KindOf(p),
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			URL:      l.URL(rel),
+			ThumbURL: l.ThumbURL(rel),
+		})
+		return nil
+	})
+	sort.SliceStable(items, func(i, j int) bool { return items[i].ModTime.After(items[j].ModTime) })
+	return items, err
+}
+
+// Describe fills in the durations of audio items. It is separate from List
+// because measuring may run ffprobe.
+func (l *Library) Describe(items []Item) {
+	for i := range items {
+		if items[i].Kind != KindAudio {
+			continue
+		}
+		if d, err := l.Duration(filepath.Join(l.Dir, filepath.FromSlash(items[i].Path))); err == nil {
+			items[i].DurationMS = d.Milliseconds()
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, dir, rel string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	os.MkdirAll(filepath.Dir(path), 0700)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func serve(l *Library, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	http.StripPrefix("/media/", l).ServeHTTP(rec, httptest.NewRequest("GET", target, nil))
+	return rec
+}
+
+func TestSignedURLs(t *testing.T) {
+	dir := t.TempDir()
+	abs := writeFile(t, dir, "audio/voice note.ogg", []byte("OggS"))
+	writeFile(t, filepath.Dir(dir), "secret.txt", []byte("outside"))
+	l := NewLibrary(dir)
+
+	u := l.URL(abs)
+	if !strings.HasPrefix(u, "/media/audio/voice%20note.ogg?") {
+		t.Fatalf("unexpected URL %s", u)
+	}
+	if rec := serve(l, u); rec.Code != http.StatusOK || rec.Body.String() != "OggS" {
+		t.Errorf("signed URL: %d %q", rec.Code, rec.Body.String())
+	}
+	for _, bad := range []string{
+		"/media/audio/voice%20note.ogg",
+		strings.Replace(u, "sig=", "sig=0", 1),
+		strings.Replace(u, "voice%20note", "other", 1),
+		strings.Replace(u, "?", "?v=thumb&", 1),
+	} {
+		if rec := serve(l, bad); rec.Code != http.StatusForbidden {
+			t.Errorf("%s: got %d, want 403", bad, rec.Code)
+		}
+	}
+	if l.URL(filepath.Join(filepath.Dir(dir), "secret.txt")) != "" || l.URL("../secret.txt") != "" {
+		t.Error("signed a path outside the library")
+	}
+
+	old := l.sign("audio/voice note.ogg", "", time.Now().Add(-50*time.Hour))
+	if rec := serve(l, old); rec.Code != http.StatusForbidden {
+		t.Errorf("expired URL: got %d", rec.Code)
+	}
+	if l.URL(abs) != u {
+		t.Error("URLs should be stable within the hour")
+	}
+}
+
+func TestURLPrefix(t *testing.T) {
+	dir := t.TempDir()
+	abs := writeFile(t, dir, "images/cat.png", []byte("png"))
+	l := NewLibrary(dir)
+	l.Prefix = "/tenants/anna/media/"
+
+	u := l.URL(abs)
+	if !strings.HasPrefix(u, "/tenants/anna/media/images/cat.png?") {
+		t.Fatalf("unexpected URL %s", u)
+	}
+	rec := httptest.NewRecorder()
+	http.StripPrefix(l.Prefix, l).ServeHTTP(rec, httptest.NewRequest("GET", u, nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "png" {
+		t.Errorf("prefixed URL: %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServeDocumentsAsAttachments(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLibrary(dir)
+	cases := []struct {
+		name, ctype string
+		attachment  bool
+	}{
+		{"documents/page.html", "application/octet-stream", true},
+		{"documents/logo.svg", "application/octet-stream", true},
+		{"images/photo.png", "image/png", false},
+		{"audio/note.ogg", "audio/ogg", false},
+	}
+	for _, c := range cases {
+		rec := serve(l, l.URL(writeFile(t, dir, c.name, []byte("<script>alert(1)</script>"))))
+		h := rec.Header()
+		if rec.Code != http.StatusOK || h.Get("Content-Type") != c.ctype || h.Get("Content-Security-Policy") != "sandbox" {
+			t.Errorf("%s: %d, Content-Type %q, CSP %q", c.name, rec.Code, h.Get("Content-Type"), h.Get("Content-Security-Policy"))
+		}
+		if got := strings.HasPrefix(h.Get("Content-Disposition"), "attachment"); got != c.attachment {
+			t.Errorf("%s: Content-Disposition %q", c.name, h.Get("Content-Disposition"))
+		}
+	}
+}
+
+func TestListAndThumbnails(t *testing.T) {
+	dir := t.TempDir()
+	img := image.NewRGBA(image.Rect(0, 0, 800, 400))
+	for x := 0; x < 800; x++ {
+		for y := 0; y < 400; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), 0, 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	writeFile(t, dir, "images/photo.png", buf.Bytes())
+	writeFile(t, dir, "docs/report.pdf", []byte("%PDF"))
+	l := NewLibrary(dir)
+
+	items, err := l.List("")
+	if err != nil || len(items) != 2 {
+		t.Fatalf("List() = %v, %v", items, err)
+	}
+	images, _ := l.List(KindImage)
+	if len(images) != 1 || images[0].Path != "images/photo.png" || images[0].ThumbURL == "" {
+		t.Fatalf("List(image) = %+v", images)
+	}
+
+	rec := serve(l, images[0].ThumbURL)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("thumbnail: %d %s", rec.Code, rec.Body.String())
+	}
+	thumb, err := jpeg.Decode(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != thumbSize || b.Dy() != thumbSize/2 {
+		t.Errorf("thumbnail is %dx%d", b.Dx(), b.Dy())
+	}
+	if items, _ := l.List(""); len(items) != 2 {
+		t.Errorf("thumbnail cache should be hidden, got %+v", items)
+	}
+}
+
+func TestAudioDurations(t *testing.T) {
+	dir := t.TempDir()
+	l := NewLibrary(dir)
+
+	// 16 kHz mono 16-bit PCM: 32000 bytes per second.
+	var wav bytes.Buffer
+	wav.WriteString("RIFF\x00\x00\x00\x00WAVE")
+	wav.WriteString("fmt ")
+	binary.Write(&wav, binary.LittleEndian, []uint32{16, 1 | 1<<16, 16000, 32000, 2 | 16<<16})
+	wav.WriteString("data")
+	binary.Write(&wav, binary.LittleEndian, uint32(48000))
+	wav.Write(make([]byte, 48000))
+	wavPath := writeFile(t, dir, "a.wav", wav.Bytes())
+	if d, err := l.Duration(wavPath); err != nil || d != 1500*time.Millisecond {
+		t.Errorf("wav duration = %v, %v", d, err)
+	}
+
+	page := func(granule uint64, payload string) []byte {
+		p := []byte("OggS\x00\x00")
+		p = binary.LittleEndian.AppendUint64(p, granule)
+		p = append(p, make([]byte, 13)...)
+		return append(p, payload...)
+	}
+	head := "OpusHead\x01\x01" + string([]byte{0x38, 0x01}) + "\x80\xbb\x00\x00\x00\x00\x00"
+	ogg := append(page(0, head), page(48000*3+312, "")...)
+	oggPath := writeFile(t, dir, "b.ogg", ogg)
+	if d, err := l.Duration(oggPath); err != nil || d != 3*time.Second {
+		t.Errorf("ogg duration = %v, %v", d, err)
+	}
+
+	items, _ := l.List(KindAudio)
+	l.Describe(items)
+	for _, it := range items {
+		if it.DurationMS == 0 {
+			t.Errorf("%s has no duration", it.Path)
+		}
+	}
+}
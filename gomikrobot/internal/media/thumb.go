@@ -0,0 +1,123 @@
+package media
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register decoder
+	"image/jpeg"
+	_ "image/png" // register decoder
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// thumbSize bounds the longer side of a thumbnail in pixels.
+const thumbSize = 320
+
+// maxThumbPixels refuses to decode larger images, which could exhaust
+// memory.
+const maxThumbPixels = 50_000_000
+
+// thumbDir caches thumbnails inside the library, hidden from List.
+const thumbDir = ".thumbs"
+
+func thumbnailable(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+		return true
+	}
+	return false
+}
+
+// thumbnail returns the path of a cached JPEG thumbnail of file, creating
+// it when missing or older than the image.
+func (l *Library) thumbnail(file, rel string) (string, error) {
+	if !thumbnailable(file) {
+		return "", fmt.Errorf("%s: no thumbnail for this type", rel)
+	}
+	src, err := os.Stat(file)
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(l.Dir, thumbDir, filepath.FromSlash(rel)+".jpg")
+	if info, err := os.Stat(out); err == nil && !info.ModTime().Before(src.ModTime()) {
+		return out, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Width*cfg.Height > maxThumbPixels {
+		return "", fmt.Errorf("%s: image too large (%dx%d)", rel, cfg.Width, cfg.Height)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		return "", err
+	}
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(out), 0700); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(out), ".thumb-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if err := jpeg.Encode(tmp, scaleDown(img, thumbSize), &jpeg.Options{Quality: 80}); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return out, os.Rename(tmp.Name(), out)
+}
+
+// scaleDown shrinks img to fit a max×max box by averaging the source pixels
+// behind each target pixel. Smaller images are returned unchanged.
+func scaleDown(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= max && h <= max {
+		return img
+	}
+	tw, th := max, h*max/w
+	if h > w {
+		tw, th = w*max/h, max
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
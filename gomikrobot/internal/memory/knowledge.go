@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+const defaultChunkSize = 1500
+
+// Document is a text to be added to the knowledge base.
+type Document struct {
+	Source string // File path or URL; re-ingesting a source replaces it
+	Title  string
+	Text   string
+}
+
+// Passage is a knowledge base chunk returned by a search.
+type Passage struct {
+	Source string  `json:"source"`
+	Title  string  `json:"title,omitempty"`
+	Chunk  int     `json:"chunk"`
+	Text   string  `json:"text"`
+	Score  float32 `json:"score"`
+}
+
+// KnowledgeBase stores document chunks and their embeddings in a vector
+// store for retrieval.
+type KnowledgeBase struct {
+	Store VectorStore
+	// Embedder receives all chunks of a document at once; wrap it in a
+	// provider.CachedEmbedder to split them into batches.
+	Embedder provider.Embedder
+	Model    string // Embedding model
+	// ChunkSize is the maximum chunk length in bytes; ChunkOverlap is the
+	// amount of text repeated at the start of the next chunk.
+	ChunkSize    int
+	ChunkOverlap int
+}
+
+// Ingest replaces the chunks of doc.Source with those of doc and returns
+// the number of chunks stored.
+func (kb *KnowledgeBase) Ingest(ctx context.Context, doc Document) (int, error) {
+	chunks := Chunk(doc.Text, kb.ChunkSize, kb.ChunkOverlap)
+	if len(chunks) == 0 {
+		return 0, nil
+	}
+	vectors, err := kb.Embedder.Embed(ctx, chunks, kb.Model)
+	if err != nil {
+		return 0, fmt.Errorf("embed: %w", err)
+	}
+
+	if err := kb.Store.DeleteWhere(ctx, "source", doc.Source); err != nil {
+		return 0, fmt.Errorf("remove old chunks: %w", err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, text := range chunks {
+		payload := map[string]interface{}{
+			"source":      doc.Source,
+			"title":       doc.Title,
+			"chunk":       i,
+			"text":        text,
+			"ingested_at": now,
+		}
+		if err := kb.Store.Upsert(ctx, chunkID(doc.Source, i), vectors[i], payload); err != nil {
+			return i, fmt.Errorf("store chunk %d: %w", i, err)
+		}
+	}
+	return len(chunks), nil
+}
+
+// Search returns the limit passages most similar to query.
+func (kb *KnowledgeBase) Search(ctx context.Context, query string, limit int) ([]Passage, error) {
+	vectors, err := kb.Embedder.Embed(ctx, []string{query}, kb.Model)
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	results, err := kb.Store.Search(ctx, vectors[0], limit)
+	if err != nil {
+		return nil, err
+	}
+	passages := make([]Passage, 0, len(results))
+	for _, r := range results {
+		p := Passage{Score: r.Score}
+		p.Source, _ = r.Payload["source"].(string)
+		p.Title, _ = r.Payload["title"].(string)
+		p.Text, _ = r.Payload["text"].(string)
+		if n, ok := r.Payload["chunk"].(float64); ok {
+			p.Chunk = int(n)
+		}
+		passages = append(passages, p)
+	}
+	return passages, nil
+}
+
+// chunkID derives a stable UUID for a chunk, so re-ingesting a source
+// overwrites its points.
+func chunkID(source string, i int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", source, i)))
+	sum[6] = sum[6]&0x0f | 0x50 // Version 5 layout
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// Chunk splits text into pieces of at most size bytes, preferring to break
+// at paragraphs, then lines or sentences, then words. Each chunk after the
+// first starts with about overlap bytes of the previous one.
+func Chunk(text string, size, overlap int) []string {
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+	if overlap < 0 || overlap > size/2 {
+		overlap = size / 2
+	}
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	var chunks []string
+	for len(text) > size {
+		cut := chunkCut(text, size)
+		if c := strings.TrimSpace(text[:cut]); c != "" {
+			chunks = append(chunks, c)
+		}
+		next := cut
+		if cut-overlap > 0 && overlap > 0 {
+			next = cut - overlap
+			// Start the overlap at a word.
+			if j := strings.IndexAny(text[next:cut], " \n"); j >= 0 {
+				next += j + 1
+			} else {
+				next = cut
+			}
+		}
+		text = strings.TrimLeft(text[next:], " \n\t")
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// chunkCut returns where to end a chunk of at most size bytes of text,
+// which is longer than size.
+func chunkCut(text string, size int) int {
+	for _, sep := range []string{"\n\n", "\n", ". ", " "} {
+		if i := strings.LastIndex(text[:size], sep); i > size/2 {
+			return i + len(sep)
+		}
+	}
+	cut := size
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		return size
+	}
+	return cut
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// fakeStore keeps points in memory and ranks them by dot product.
+type fakeStore struct {
+	points map[string]Result
+	vecs   map[string][]float32
+}
+
+func (s *fakeStore) EnsureCollection(ctx context.Context) error { return nil }
+
+func (s *fakeStore) Upsert(ctx context.Context, id string, vector []float32, payload map[string]interface{}) error {
+	// Payloads come back from Qdrant as decoded JSON.
+	if n, ok := payload["chunk"].(int); ok {
+		payload["chunk"] = float64(n)
+	}
+	s.points[id] = Result{ID: id, Payload: payload}
+	s.vecs[id] = vector
+	return nil
+}
+
+func (s *fakeStore) Search(ctx context.Context, vector []float32, limit int) ([]Result, error) {
+	var out []Result
+	for id, r := range s.points {
+		for i, v := range s.vecs[id] {
+			r.Score += v * vector[i]
+		}
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out[:min(limit, len(out))], nil
+}
+
+func (s *fakeStore) DeleteWhere(ctx context.Context, key, value string) error {
+	for id, r := range s.points {
+		if r.Payload[key] == value {
+			delete(s.points, id)
+		}
+	}
+	return nil
+}
+
+// wordEmbedder maps texts to counts of a few topic words.
+type wordEmbedder struct{}
+
+func (wordEmbedder) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		t = strings.ToLower(t)
+		out[i] = []float32{float32(strings.Count(t, "bread")), float32(strings.Count(t, "tax"))}
+	}
+	return out, nil
+}
+
+func TestKnowledgeBaseIngestAndSearch(t *testing.T) {
+	store := &fakeStore{points: map[string]Result{}, vecs: map[string][]float32{}}
+	kb := &KnowledgeBase{Store: store, Embedder: wordEmbedder{}, ChunkSize: 200, ChunkOverlap: 0}
+	ctx := context.Background()
+
+	recipes := strings.Repeat("Sourdough bread needs a starter and time. ", 6) + "\n\n" + strings.Repeat("Unrelated filler text. ", 10)
+	if n, err := kb.Ingest(ctx, Document{Source: "/notes/recipes.md", Title: "recipes.md", Text: recipes}); err != nil || n < 2 {
+		t.Fatalf("Ingest() = %d, %v", n, err)
+	}
+	if _, err := kb.Ingest(ctx, Document{Source: "/notes/tax.md", Text: "The tax return is due in July. Tax ID is in the drawer."}); err != nil {
+		t.Fatal(err)
+	}
+
+	passages, err := kb.Search(ctx, "where is my tax ID?", 1)
+	if err != nil || len(passages) != 1 {
+		t.Fatalf("Search() = %v, %v", passages, err)
+	}
+	if p := passages[0]; p.Source != "/notes/tax.md" || p.Chunk != 0 || !strings.Contains(p.Text, "drawer") {
+		t.Errorf("unexpected passage %+v", p)
+	}
+
+	// Re-ingesting a shorter version drops the old chunks.
+	before := len(store.points)
+	if _, err := kb.Ingest(ctx, Document{Source: "/notes/recipes.md", Text: "Bread: flour, water, salt."}); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.points) >= before {
+		t.Errorf("expected fewer points after re-ingest, %d -> %d", before, len(store.points))
+	}
+}
+
+func TestChunk(t *testing.T) {
+	text := strings.Repeat("alpha beta gamma delta. ", 40) + "\n\nÜberschrift mit Umlauten äöü " + strings.Repeat("x", 300)
+	chunks := Chunk(text, 200, 50)
+	if len(chunks) < 5 {
+		t.Fatalf("expected several chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c) > 200 {
+			t.Errorf("chunk %d has %d bytes", i, len(c))
+		}
+		if !utf8.ValidString(c) {
+			t.Errorf("chunk %d is not valid UTF-8", i)
+		}
+	}
+	// Overlap repeats the end of a chunk at the start of the next.
+	if tail := chunks[0][len(chunks[0])-20:]; !strings.Contains(chunks[1], strings.TrimSpace(tail)) {
+		t.Errorf("chunk 1 does not overlap chunk 0:\n%q\n%q", chunks[0], chunks[1])
+	}
+	if got := Chunk("  short  ", 200, 50); len(got) != 1 || got[0] != "short" {
+		t.Errorf("Chunk(short) = %q", got)
+	}
+	if got := Chunk(" \n ", 200, 50); len(got) != 0 {
+		t.Errorf("Chunk(blank) = %q", got)
+	}
+	if chunkID("a", 1) != chunkID("a", 1) || chunkID("a", 1) == chunkID("a", 2) || len(chunkID("a", 1)) != 36 {
+		t.Errorf("unexpected chunk IDs %s %s", chunkID("a", 1), chunkID("a", 2))
+	}
+}
+
+func TestHTMLText(t *testing.T) {
+	title, text := HTMLText(`<html><head><title>My &amp; Notes</title><style>p{}</style></head>
+<body><nav>Menu</nav><h1>Heading</h1><p>First <b>bold</b> para.</p><script>var x;</script><p>Second</p></body></html>`)
+	if title != "My & Notes" {
+		t.Errorf("title = %q", title)
+	}
+	if text != "Heading\n\nFirst bold para.\n\nSecond" {
+		t.Errorf("text = %q", text)
+	}
+}
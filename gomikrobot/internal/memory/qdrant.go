@@ -142,3 +142,28 @@ func (s *QdrantStore) Search(ctx context.Context, vector []float32, limit int) (
 	}
 	return results, nil
 }
+
+func (s *QdrantStore) DeleteWhere(ctx context.Context, key, value string) error {
+	body := map[string]interface{}{
+		"filter": map[string]interface{}{
+			"must": []map[string]interface{}{
+				{"key": key, "match": map[string]interface{}{"value": value}},
+			},
+		},
+	}
+	jsonBody, _ := json.Marshal(body)
+	req, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/collections/%s/points/delete?wait=true", s.baseURL, s.collection), bytes.NewBuffer(jsonBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		b, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("qdrant delete failed: %s", string(b))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxFetchBytes caps the size of a fetched web page.
+const maxFetchBytes = 10 << 20
+
+var fetchClient = &http.Client{Timeout: 60 * time.Second}
+
+// FetchDocument downloads url as a document. HTML pages are reduced to
+// their text; other text types are kept as they are.
+func FetchDocument(ctx context.Context, url string) (Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Document{}, err
+	}
+	req.Header.Set("User-Agent", "GoMikroBot ingest")
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return Document{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Document{}, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchBytes))
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc := Document{Source: url, Title: url}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		doc.Title, doc.Text = HTMLText(string(data))
+		if doc.Title == "" {
+			doc.Title = url
+		}
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" || mediaType == "":
+		doc.Text = string(data)
+	default:
+		return Document{}, fmt.Errorf("unsupported content type %q", mediaType)
+	}
+	return doc, nil
+}
+
+var (
+	titlePattern    = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	skipPattern     = regexp.MustCompile(`(?is)<(script|style|noscript|svg|head|nav|footer)[^>]*>.*?</(script|style|noscript|svg|head|nav|footer)>|<!--.*?-->`)
+	blockPattern    = regexp.MustCompile(`(?i)<(/?(p|div|section|article|li|tr|h[1-6]|pre|blockquote)|br)[^>]*>`)
+	tagPattern      = regexp.MustCompile(`<[^>]*>`)
+	spacePattern    = regexp.MustCompile(`[ \t\f\v]+`)
+	blankRunPattern = regexp.MustCompile(`\n\s*\n\s*`)
+)
+
+// HTMLText returns the title and the readable text of an HTML page, with
+// block elements on separate paragraphs.
+func HTMLText(page string) (title, text string) {
+	if m := titlePattern.FindStringSubmatch(page); m != nil {
+		title = strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(m[1], "")))
+	}
+	s := skipPattern.ReplaceAllString(page, " ")
+	s = blockPattern.ReplaceAllString(s, "\n\n")
+	s = html.UnescapeString(tagPattern.ReplaceAllString(s, " "))
+	s = spacePattern.ReplaceAllString(s, " ")
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	text = blankRunPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return title, strings.TrimSpace(text)
+}
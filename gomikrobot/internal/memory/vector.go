@@ -11,6 +11,9 @@ type VectorStore interface {
 
 	// EnsureCollection makes sure the storage exists.
 	EnsureCollection(ctx context.Context) error
+
+	// DeleteWhere removes all items whose payload field key equals value.
+	DeleteWhere(ctx context.Context, key, value string) error
 }
 
 type Result struct {
@@ -0,0 +1,181 @@
+// Package metrics provides minimal counters and gauges exposed in the
+// Prometheus text format.
+//
+// This is intentionally dependency-free (no client_golang).
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Registry holds named metrics.
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]*metric
+}
+
+type metric struct {
+	name  string
+	help  string
+	kind  string // counter or gauge
+	mu    sync.RWMutex
+	value map[string]*atomic.Uint64 // label set -> float64 bits
+}
+
+// Default is the process-wide registry.
+var Default = NewRegistry()
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*metric)}
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct{ m *metric }
+
+// Gauge is a value that can go up and down.
+type Gauge struct{ m *metric }
+
+// Counter returns the counter with the given name, creating it if needed.
+func (r *Registry) Counter(name, help string) *Counter {
+	return &Counter{m: r.get(name, help, "counter")}
+}
+
+// Gauge returns the gauge with the given name, creating it if needed.
+func (r *Registry) Gauge(name, help string) *Gauge {
+	return &Gauge{m: r.get(name, help, "gauge")}
+}
+
+func (r *Registry) get(name, help, kind string) *metric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		return m
+	}
+	m := &metric{name: name, help: help, kind: kind, value: make(map[string]*atomic.Uint64)}
+	r.metrics[name] = m
+	return m
+}
+
+// Inc adds 1 to the counter for the given label pairs ("key", "value", ...).
+func (c *Counter) Inc(labels ...string) { c.Add(1, labels...) }
+
+// Add adds v (which must be >= 0) to the counter.
+func (c *Counter) Add(v float64, labels ...string) {
+	if v < 0 {
+		return
+	}
+	c.m.add(v, labels)
+}
+
+// Value returns the current counter value.
+func (c *Counter) Value(labels ...string) float64 { return c.m.load(labels) }
+
+// Set sets the gauge value.
+func (g *Gauge) Set(v float64, labels ...string) {
+	g.m.slot(labelKey(labels)).Store(math.Float64bits(v))
+}
+
+// Add adds v to the gauge.
+func (g *Gauge) Add(v float64, labels ...string) { g.m.add(v, labels) }
+
+// Value returns the current gauge value.
+func (g *Gauge) Value(labels ...string) float64 { return g.m.load(labels) }
+
+func (m *metric) slot(key string) *atomic.Uint64 {
+	m.mu.RLock()
+	s, ok := m.value[key]
+	m.mu.RUnlock()
+	if ok {
+		return s
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok = m.value[key]; !ok {
+		s = &atomic.Uint64{}
+		m.value[key] = s
+	}
+	return s
+}
+
+func (m *metric) add(v float64, labels []string) {
+	s := m.slot(labelKey(labels))
+	for {
+		old := s.Load()
+		next := math.Float64bits(math.Float64frombits(old) + v)
+		if s.CompareAndSwap(old, next) {
+			return
+		}
+	}
+}
+
+func (m *metric) load(labels []string) float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if s, ok := m.value[labelKey(labels)]; ok {
+		return math.Float64frombits(s.Load())
+	}
+	return 0
+}
+
+// labelKey renders label pairs in exposition format: key="value",...
+func labelKey(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		v := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(labels[i+1])
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, labels[i], v))
+	}
+	return strings.Join(parts, ",")
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.metrics))
+	for n := range r.metrics {
+		names = append(names, n)
+	}
+	r.mu.RUnlock()
+	sort.Strings(names)
+
+	for _, n := range names {
+		r.mu.RLock()
+		m := r.metrics[n]
+		r.mu.RUnlock()
+
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		m.mu.RLock()
+		keys := make([]string, 0, len(m.value))
+		for k := range m.value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v := math.Float64frombits(m.value[k].Load())
+			if k == "" {
+				fmt.Fprintf(w, "%s %g\n", m.name, v)
+			} else {
+				fmt.Fprintf(w, "%s{%s} %g\n", m.name, k, v)
+			}
+		}
+		m.mu.RUnlock()
+	}
+}
+
+// Handler serves the registry in the Prometheus text format.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		r.WriteText(w)
+	})
+}
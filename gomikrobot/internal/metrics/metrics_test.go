@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCounterAndGauge(t *testing.T) {
+	r := NewRegistry()
+	c := r.Counter("requests_total", "Total requests.")
+	c.Inc("route", "/chat")
+	c.Add(2, "route", "/chat")
+	c.Inc("route", "/health")
+	c.Add(-5, "route", "/chat") // ignored
+
+	if v := c.Value("route", "/chat"); v != 3 {
+		t.Errorf("expected 3, got %v", v)
+	}
+
+	g := r.Gauge("sessions", "Sessions on disk.")
+	g.Set(10)
+	g.Add(-3)
+	if v := g.Value(); v != 7 {
+		t.Errorf("expected 7, got %v", v)
+	}
+
+	// Same name returns the same metric.
+	if r.Counter("requests_total", "").Value("route", "/health") != 1 {
+		t.Error("expected registry to return the existing counter")
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("b_total", "B help.").Inc("k", `va"l`)
+	r.Gauge("a", "A help.").Set(1.5)
+
+	var sb strings.Builder
+	r.WriteText(&sb)
+	out := sb.String()
+
+	want := "# HELP a A help.\n# TYPE a gauge\na 1.5\n# HELP b_total B help.\n# TYPE b_total counter\nb_total{k=\"va\\\"l\"} 1\n"
+	if out != want {
+		t.Errorf("unexpected output:\n%s\nwant:\n%s", out, want)
+	}
+}
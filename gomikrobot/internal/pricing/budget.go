@@ -0,0 +1,83 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// budgetAlertKey is the timeline setting holding the last month alerted,
+// so restarts don't repeat the alert.
+const budgetAlertKey = "budget_alert_month"
+
+// MonthToDate estimates spend from the start of now's month until now.
+func (r *Registry) MonthToDate(tl *timeline.TimelineService, now time.Time) (*Report, error) {
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	usage, err := tl.UsageByModel(start, now)
+	if err != nil {
+		return nil, err
+	}
+	return r.Estimate(usage), nil
+}
+
+// BudgetWatcher sends one chat alert per month once the estimated spend
+// reaches the monthly budget.
+type BudgetWatcher struct {
+	Registry *Registry
+	Timeline *timeline.TimelineService
+	Bus      *bus.MessageBus
+	Budget   float64 // USD per calendar month
+	Channel  string
+	ChatID   string
+	Interval time.Duration // Default one hour
+}
+
+// Run checks the budget on every interval until ctx is cancelled.
+func (w *BudgetWatcher) Run(ctx context.Context) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := w.Check(time.Now()); err != nil {
+			fmt.Printf("⚠️ Budget check failed: %v\n", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check sends the alert if this month's spend reached the budget and no
+// alert was sent for the month yet. It reports whether it sent one.
+func (w *BudgetWatcher) Check(now time.Time) (bool, error) {
+	if w.Budget <= 0 {
+		return false, nil
+	}
+	month := now.Format("2006-01")
+	if last, err := w.Timeline.GetSetting(budgetAlertKey); err == nil && last == month {
+		return false, nil
+	}
+	rep, err := w.Registry.MonthToDate(w.Timeline, now)
+	if err != nil {
+		return false, err
+	}
+	if rep.TotalUSD < w.Budget {
+		return false, nil
+	}
+
+	msg := fmt.Sprintf("💸 Budget alert: estimated LLM spend for %s is $%.2f, reaching the monthly budget of $%.2f.", month, rep.TotalUSD, w.Budget)
+	if len(rep.Models) > 0 && rep.Models[0].Priced {
+		msg += fmt.Sprintf("\nLargest: %s ($%.2f).", rep.Models[0].Model, rep.Models[0].CostUSD)
+	}
+	w.Bus.PublishOutbound(&bus.OutboundMessage{Channel: w.Channel, ChatID: w.ChatID, Content: msg})
+	fmt.Printf("💸 Monthly budget reached: $%.2f of $%.2f\n", rep.TotalUSD, w.Budget)
+	return true, w.Timeline.SetSetting(budgetAlertKey, month)
+}
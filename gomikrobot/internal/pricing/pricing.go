@@ -0,0 +1,139 @@
+// Package pricing estimates LLM spend from recorded token usage.
+package pricing
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// Price is a model's list price in USD per million tokens.
+type Price struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// defaultPrices are list prices at the time of writing. Keys match model
+// names exactly or as a prefix, so dated snapshots like gpt-4o-2024-08-06
+// use the gpt-4o price. Override them in config when they change.
+var defaultPrices = map[string]Price{
+	"gpt-4o":            {Input: 2.50, Output: 10.00},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.60},
+	"gpt-4.1":           {Input: 2.00, Output: 8.00},
+	"gpt-4.1-mini":      {Input: 0.40, Output: 1.60},
+	"gpt-4.1-nano":      {Input: 0.10, Output: 0.40},
+	"gpt-4-turbo":       {Input: 10.00, Output: 30.00},
+	"gpt-3.5-turbo":     {Input: 0.50, Output: 1.50},
+	"o1":                {Input: 15.00, Output: 60.00},
+	"o1-mini":           {Input: 1.10, Output: 4.40},
+	"o3":                {Input: 2.00, Output: 8.00},
+	"o3-mini":           {Input: 1.10, Output: 4.40},
+	"o4-mini":           {Input: 1.10, Output: 4.40},
+	"claude-3-5-haiku":  {Input: 0.80, Output: 4.00},
+	"claude-3-5-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-3-7-sonnet": {Input: 3.00, Output: 15.00},
+	"claude-3-opus":     {Input: 15.00, Output: 75.00},
+	"llama-3.1-8b":      {Input: 0.05, Output: 0.08},
+	"llama-3.3-70b":     {Input: 0.59, Output: 0.79},
+}
+
+// Registry resolves model names to prices.
+type Registry struct {
+	mu     sync.RWMutex
+	prices map[string]Price
+}
+
+// New returns a registry with the built-in prices plus overrides, which
+// replace or add entries.
+func New(overrides map[string]Price) *Registry {
+	r := &Registry{}
+	r.SetOverrides(overrides)
+	return r
+}
+
+// SetOverrides replaces the configured prices, e.g. after a config reload.
+func (r *Registry) SetOverrides(overrides map[string]Price) {
+	prices := make(map[string]Price, len(defaultPrices)+len(overrides))
+	for m, p := range defaultPrices {
+		prices[m] = p
+	}
+	for m, p := range overrides {
+		prices[strings.ToLower(m)] = p
+	}
+	r.mu.Lock()
+	r.prices = prices
+	r.mu.Unlock()
+}
+
+// Lookup returns the price of model. Provider prefixes such as "openai/"
+// are ignored, and the longest matching name prefix wins.
+func (r *Registry) Lookup(model string) (Price, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	m := strings.ToLower(model)
+	candidates := []string{m}
+	if _, rest, ok := strings.Cut(m, "/"); ok {
+		candidates = append(candidates, rest)
+	}
+	for _, c := range candidates {
+		if p, ok := r.prices[c]; ok {
+			return p, true
+		}
+	}
+	best, found := "", false
+	for _, c := range candidates {
+		for name := range r.prices {
+			if len(name) > len(best) && strings.HasPrefix(c, name) && boundary(c, len(name)) {
+				best, found = name, true
+			}
+		}
+	}
+	return r.prices[best], found
+}
+
+// boundary reports whether s[:n] ends at a name separator, so "o1" does not
+// price "o1x" but does price "o1-2024-12-17".
+func boundary(s string, n int) bool {
+	return n == len(s) || strings.ContainsRune("-:@.", rune(s[n]))
+}
+
+// Cost returns the estimated USD cost of the given tokens on model.
+func (r *Registry) Cost(model string, promptTokens, completionTokens int) (float64, bool) {
+	p, ok := r.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6, true
+}
+
+// ModelCost is one model's usage with its estimated cost.
+type ModelCost struct {
+	timeline.ModelUsage
+	CostUSD float64 `json:"cost_usd"`
+	Priced  bool    `json:"priced"` // False if the model has no known price
+}
+
+// Report is the estimated spend over a period.
+type Report struct {
+	TotalUSD float64     `json:"total_usd"`
+	Models   []ModelCost `json:"models"`
+	// Unpriced lists models whose tokens are not included in TotalUSD.
+	Unpriced []string `json:"unpriced,omitempty"`
+}
+
+// Estimate prices per-model usage, most expensive first.
+func (r *Registry) Estimate(usage []timeline.ModelUsage) *Report {
+	rep := &Report{Models: []ModelCost{}}
+	for _, u := range usage {
+		cost, ok := r.Cost(u.Model, u.PromptTokens, u.CompletionTokens)
+		rep.Models = append(rep.Models, ModelCost{ModelUsage: u, CostUSD: cost, Priced: ok})
+		rep.TotalUSD += cost
+		if !ok {
+			rep.Unpriced = append(rep.Unpriced, u.Model)
+		}
+	}
+	sort.SliceStable(rep.Models, func(i, j int) bool { return rep.Models[i].CostUSD > rep.Models[j].CostUSD })
+	return rep
+}
@@ -0,0 +1,82 @@
+package pricing
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/bus"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+func TestLookup(t *testing.T) {
+	r := New(map[string]Price{"My-Local": {Input: 1, Output: 2}, "gpt-4o": {Input: 5, Output: 15}})
+
+	tests := []struct {
+		model string
+		want  Price
+		ok    bool
+	}{
+		{"gpt-4o-mini", Price{0.15, 0.60}, true},
+		{"gpt-4o-2024-08-06", Price{5, 15}, true}, // Override applies to snapshots
+		{"openai/gpt-4.1-nano", Price{0.10, 0.40}, true},
+		{"o1-2024-12-17", Price{15, 60}, true},
+		{"o1x", Price{}, false},
+		{"my-local", Price{1, 2}, true},
+		{"unknown-model", Price{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := r.Lookup(tt.model)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Lookup(%q) = %v, %v; want %v, %v", tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	r := New(nil)
+	rep := r.Estimate([]timeline.ModelUsage{
+		{Model: "gpt-4o-mini", PromptTokens: 1_000_000, CompletionTokens: 1_000_000},
+		{Model: "gpt-4o", PromptTokens: 1_000_000},
+		{Model: "homebrew", PromptTokens: 500},
+	})
+	if rep.TotalUSD < 3.249 || rep.TotalUSD > 3.251 {
+		t.Errorf("TotalUSD = %v, want 3.25", rep.TotalUSD)
+	}
+	if rep.Models[0].Model != "gpt-4o" {
+		t.Errorf("most expensive model = %q, want gpt-4o", rep.Models[0].Model)
+	}
+	if len(rep.Unpriced) != 1 || rep.Unpriced[0] != "homebrew" {
+		t.Errorf("Unpriced = %v, want [homebrew]", rep.Unpriced)
+	}
+}
+
+func TestBudgetWatcherAlertsOncePerMonth(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+	if err := tl.RecordUsage(&timeline.UsageRecord{Timestamp: now.Add(-time.Hour), SessionKey: "cli:default", Model: "gpt-4o", PromptTokens: 2_000_000}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := bus.NewMessageBus()
+	w := &BudgetWatcher{Registry: New(nil), Timeline: tl, Bus: b, Budget: 10, Channel: "whatsapp", ChatID: "admin"}
+	if sent, err := w.Check(now); err != nil || sent {
+		t.Fatalf("under budget: sent=%v err=%v", sent, err)
+	}
+
+	w.Budget = 4
+	if sent, err := w.Check(now); err != nil || !sent {
+		t.Fatalf("over budget: sent=%v err=%v", sent, err)
+	}
+	if sent, _ := w.Check(now.Add(time.Hour)); sent {
+		t.Error("alert repeated within the same month")
+	}
+	if b.OutboundSize() != 1 {
+		t.Errorf("outbound messages = %d, want 1", b.OutboundSize())
+	}
+}
@@ -0,0 +1,77 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/kamir/gomikrobot/internal/tracing"
+)
+
+// Embedder is implemented by providers that can compute text embeddings.
+type Embedder interface {
+	// Embed returns one vector per input text, in order.
+	Embed(ctx context.Context, texts []string, model string) ([][]float32, error)
+}
+
+// Embed computes embeddings with the OpenAI-compatible /embeddings API.
+func (p *OpenAIProvider) Embed(ctx context.Context, texts []string, model string) (_ [][]float32, err error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if model == "" {
+		model = "text-embedding-3-small"
+	}
+	ctx, span := tracing.StartKind(ctx, tracing.KindClient, "embeddings "+model,
+		"gen_ai.operation.name", "embeddings", "gen_ai.request.model", model, "inputs", len(texts))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	jsonBody, err := json.Marshal(map[string]any{"model": model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/embeddings", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp.StatusCode, respBody)
+	}
+
+	var apiResp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	if len(apiResp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(apiResp.Data))
+	}
+	sort.Slice(apiResp.Data, func(i, j int) bool { return apiResp.Data[i].Index < apiResp.Data[j].Index })
+	vectors := make([][]float32, len(apiResp.Data))
+	for i, d := range apiResp.Data {
+		vectors[i] = d.Embedding
+	}
+	return vectors, nil
+}
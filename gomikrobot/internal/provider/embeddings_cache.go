@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"sync"
+)
+
+// CachedEmbedder wraps an Embedder, splitting large inputs into batches and
+// keeping recently computed vectors in memory. Repeated texts, such as a
+// re-ingested document or a common query, are not sent again.
+type CachedEmbedder struct {
+	embedder  Embedder
+	batchSize int
+	size      int
+
+	mu      sync.Mutex
+	order   *list.List // Front is the most recently used
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type cachedVector struct {
+	key    [sha256.Size]byte
+	vector []float32
+}
+
+// NewCachedEmbedder returns e sending at most batchSize texts per request
+// (all at once if batchSize <= 0) and caching up to cacheSize vectors
+// (none if cacheSize <= 0).
+func NewCachedEmbedder(e Embedder, batchSize, cacheSize int) *CachedEmbedder {
+	return &CachedEmbedder{
+		embedder:  e,
+		batchSize: batchSize,
+		size:      cacheSize,
+		order:     list.New(),
+		entries:   make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// Embed returns one vector per text, computing only those not cached.
+func (c *CachedEmbedder) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	keys := make([][sha256.Size]byte, len(texts))
+	// missing maps each text still to be embedded to its positions.
+	missing := make(map[string][]int)
+	var pending []string
+	for i, text := range texts {
+		keys[i] = sha256.Sum256([]byte(model + "\x00" + text))
+		if v, ok := c.get(keys[i]); ok {
+			vectors[i] = v
+			continue
+		}
+		if _, ok := missing[text]; !ok {
+			pending = append(pending, text)
+		}
+		missing[text] = append(missing[text], i)
+	}
+
+	batch := c.batchSize
+	if batch <= 0 {
+		batch = len(pending)
+	}
+	for start := 0; start < len(pending); start += batch {
+		end := min(start+batch, len(pending))
+		computed, err := c.embedder.Embed(ctx, pending[start:end], model)
+		if err != nil {
+			return nil, err
+		}
+		for j, v := range computed {
+			positions := missing[pending[start+j]]
+			for _, i := range positions {
+				vectors[i] = v
+			}
+			c.put(keys[positions[0]], v)
+		}
+	}
+	return vectors, nil
+}
+
+func (c *CachedEmbedder) get(key [sha256.Size]byte) ([]float32, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cachedVector).vector, true
+}
+
+func (c *CachedEmbedder) put(key [sha256.Size]byte, vector []float32) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cachedVector{key: key, vector: vector})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedVector).key)
+	}
+}
@@ -0,0 +1,81 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/tracing"
+)
+
+// OllamaEmbedder computes embeddings with a local Ollama server.
+type OllamaEmbedder struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOllamaEmbedder creates an embedder for the Ollama server at baseURL,
+// http://localhost:11434 if empty.
+func NewOllamaEmbedder(baseURL string) *OllamaEmbedder {
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+	return &OllamaEmbedder{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+// Embed computes embeddings with the /api/embed endpoint.
+func (p *OllamaEmbedder) Embed(ctx context.Context, texts []string, model string) (_ [][]float32, err error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+	ctx, span := tracing.StartKind(ctx, tracing.KindClient, "embeddings "+model,
+		"gen_ai.operation.name", "embeddings", "gen_ai.request.model", model, "inputs", len(texts))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	jsonBody, err := json.Marshal(map[string]any{"model": model, "input": texts})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/embed", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, apiError(resp.StatusCode, respBody)
+	}
+
+	var apiResp struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return nil, fmt.Errorf("parse response: %w", err)
+	}
+	if len(apiResp.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(apiResp.Embeddings))
+	}
+	return apiResp.Embeddings, nil
+}
@@ -0,0 +1,111 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrContextLength is wrapped by provider errors caused by a request that
+// exceeds the model's context window.
+var ErrContextLength = errors.New("context length exceeded")
+
+// ErrRateLimited is wrapped by provider errors for HTTP 429 responses.
+var ErrRateLimited = errors.New("rate limited")
+
+// ErrContentFiltered is wrapped by provider errors for requests rejected
+// by the provider's content filter.
+var ErrContentFiltered = errors.New("content filtered")
+
+// contextLengthMarkers are substrings providers use for context overflows.
+var contextLengthMarkers = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"context window",
+	"prompt is too long",
+	"too many tokens",
+}
+
+// contentFilterMarkers are substrings providers use for filtered requests.
+var contentFilterMarkers = []string{
+	"content_filter",
+	"content_policy_violation",
+	"responsibleaipolicyviolation",
+	"content management policy",
+}
+
+// apiError builds the error for a non-200 API response.
+func apiError(status int, body []byte) error {
+	lower := strings.ToLower(string(body))
+	for _, m := range contextLengthMarkers {
+		if strings.Contains(lower, m) {
+			return fmt.Errorf("API error (status %d): %w: %s", status, ErrContextLength, string(body))
+		}
+	}
+	for _, m := range contentFilterMarkers {
+		if strings.Contains(lower, m) {
+			return fmt.Errorf("API error (status %d): %w: %s", status, ErrContentFiltered, string(body))
+		}
+	}
+	if status == http.StatusTooManyRequests {
+		return fmt.Errorf("API error (status %d): %w: %s", status, ErrRateLimited, string(body))
+	}
+	return fmt.Errorf("API error (status %d): %s", status, string(body))
+}
+
+// IsContextLengthError reports whether err was caused by a context overflow.
+func IsContextLengthError(err error) bool {
+	return errors.Is(err, ErrContextLength)
+}
+
+// IsRateLimitError reports whether err was caused by a rate limit.
+func IsRateLimitError(err error) bool {
+	return errors.Is(err, ErrRateLimited)
+}
+
+// IsContentFilterError reports whether err was caused by a content filter.
+func IsContentFilterError(err error) bool {
+	return errors.Is(err, ErrContentFiltered)
+}
+
+// refusalPrefixes start the boilerplate models answer with when they
+// decline, for providers that send refusals as plain content.
+var refusalPrefixes = []string{
+	"i'm sorry, but i can't",
+	"i'm sorry, but i cannot",
+	"i'm sorry, i can't help with",
+	"i'm sorry, i can't assist with",
+	"i can't help with that",
+	"i can't assist with that",
+	"i cannot help with that",
+	"i cannot assist with that",
+	"i'm unable to help with that",
+	"i'm not able to help with that",
+}
+
+// maxRefusalChars bounds plain-content refusals: longer answers that start
+// with an apology usually go on to help.
+const maxRefusalChars = 200
+
+// IsRefusal reports whether resp declines the request: it carries a
+// refusal, was cut by the content filter, or is a short reply made only
+// of refusal boilerplate.
+func IsRefusal(resp *ChatResponse) bool {
+	if resp == nil {
+		return false
+	}
+	if resp.Refusal != "" || resp.FinishReason == "content_filter" {
+		return true
+	}
+	if len(resp.ToolCalls) > 0 || len(resp.Content) > maxRefusalChars {
+		return false
+	}
+	lower := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(resp.Content), "’", "'"))
+	for _, p := range refusalPrefixes {
+		if strings.HasPrefix(lower, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,12 +6,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
+
+	"github.com/kamir/gomikrobot/internal/tracing"
+	"github.com/kamir/gomikrobot/internal/transcribe"
 )
 
 // OpenAIProvider implements LLMProvider using the OpenAI-compatible API.
@@ -46,40 +46,39 @@ func (p *OpenAIProvider) DefaultModel() string {
 	return p.defaultModel
 }
 
-// Chat sends a completion request to the OpenAI-compatible API.
-func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
-	model := req.Model
-	if model == "" {
-		model = p.defaultModel
+// Ping checks that the API is reachable and accepts the configured key.
+func (p *OpenAIProvider) Ping(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", p.apiBase+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
 	}
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
 
-	// Build request body
-	body := map[string]any{
-		"model":       model,
-		"messages":    p.convertMessages(req.Messages),
-		"max_tokens":  req.MaxTokens,
-		"temperature": req.Temperature,
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
 	}
+	defer resp.Body.Close()
 
-	if len(req.Tools) > 0 {
-		body["tools"] = req.Tools
-		body["tool_choice"] = "auto"
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
 	}
+	return nil
+}
 
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		return nil, fmt.Errorf("marshal request: %w", err)
-	}
+// Chat sends a completion request to the OpenAI-compatible API.
+func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (_ *ChatResponse, err error) {
+	body := p.buildChatBody(req)
+	ctx, span := startChatSpan(ctx, body)
+	var result *ChatResponse
+	defer func() { endChatSpan(span, result, err) }()
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonBody))
+	httpReq, err := p.newChatRequest(ctx, body)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, err
 	}
 
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
-
 	// Execute request
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -93,7 +92,7 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRespo
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+		return nil, apiError(resp.StatusCode, respBody)
 	}
 
 	// Parse response
@@ -102,17 +101,113 @@ func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRespo
 		return nil, fmt.Errorf("parse response: %w", err)
 	}
 
-	return p.parseResponse(&apiResp)
+	result, err = p.parseResponse(&apiResp)
+	return result, err
+}
+
+// startChatSpan starts the client span of a completion request.
+func startChatSpan(ctx context.Context, body map[string]any) (context.Context, *tracing.Span) {
+	model, _ := body["model"].(string)
+	msgs, _ := body["messages"].([]map[string]any)
+	tools, _ := body["tools"].([]ToolDefinition)
+	return tracing.StartKind(ctx, tracing.KindClient, "chat "+model,
+		"gen_ai.operation.name", "chat", "gen_ai.request.model", model, "messages", len(msgs), "tools", len(tools))
+}
+
+// endChatSpan records the usage and outcome of a completion request.
+func endChatSpan(span *tracing.Span, resp *ChatResponse, err error) {
+	span.RecordError(err)
+	if resp != nil {
+		span.SetAttr("gen_ai.usage.input_tokens", resp.Usage.PromptTokens, "gen_ai.usage.output_tokens", resp.Usage.CompletionTokens,
+			"gen_ai.usage.cached_tokens", resp.Usage.CachedTokens, "gen_ai.response.finish_reasons", resp.FinishReason,
+			"tool_calls", len(resp.ToolCalls))
+	}
+	span.End()
+}
+
+// buildChatBody builds the JSON body for a chat completion request.
+func (p *OpenAIProvider) buildChatBody(req *ChatRequest) map[string]any {
+	model := req.Model
+	if model == "" {
+		model = p.defaultModel
+	}
+
+	body := map[string]any{
+		"model":       model,
+		"messages":    p.convertMessages(req.Messages, cacheHints(model)),
+		"max_tokens":  req.MaxTokens,
+		"temperature": req.Temperature,
+	}
+
+	if req.TopP > 0 {
+		body["top_p"] = req.TopP
+	}
+	if req.ReasoningEffort != "" {
+		body["reasoning_effort"] = req.ReasoningEffort
+	}
+
+	if len(req.Tools) > 0 {
+		body["tools"] = req.Tools
+		body["tool_choice"] = "auto"
+	}
+	if s := req.ResponseSchema; s != nil {
+		format := map[string]any{"name": s.Name, "schema": s.Schema, "strict": s.Strict}
+		if s.Description != "" {
+			format["description"] = s.Description
+		}
+		body["response_format"] = map[string]any{"type": "json_schema", "json_schema": format}
+	}
+	return body
+}
+
+// newChatRequest creates the HTTP request for the chat completions endpoint.
+func (p *OpenAIProvider) newChatRequest(ctx context.Context, body map[string]any) (*http.Request, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/chat/completions", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	tracing.Inject(ctx, httpReq.Header)
+	return httpReq, nil
+}
+
+// cacheHints reports whether model takes explicit cache_control markers.
+// Anthropic models (directly or through OpenRouter) only cache marked
+// blocks; OpenAI caches long stable prefixes automatically and rejects
+// unknown fields.
+func cacheHints(model string) bool {
+	model = strings.ToLower(model)
+	return strings.HasPrefix(model, "anthropic/") || strings.HasPrefix(model, "claude")
 }
 
-// convertMessages converts our Message type to OpenAI API format.
-func (p *OpenAIProvider) convertMessages(messages []Message) []map[string]any {
+// convertMessages converts our Message type to OpenAI API format. With
+// cacheHints, a message's cacheable prefix is sent as a separate content
+// part marked for caching.
+func (p *OpenAIProvider) convertMessages(messages []Message, cacheHints bool) []map[string]any {
 	result := make([]map[string]any, len(messages))
 	for i, msg := range messages {
 		m := map[string]any{
 			"role":    msg.Role,
 			"content": msg.Content,
 		}
+		if cacheHints && msg.CachePrefix > 0 && msg.CachePrefix <= len(msg.Content) {
+			parts := []map[string]any{{
+				"type":          "text",
+				"text":          msg.Content[:msg.CachePrefix],
+				"cache_control": map[string]string{"type": "ephemeral"},
+			}}
+			if rest := msg.Content[msg.CachePrefix:]; rest != "" {
+				parts = append(parts, map[string]any{"type": "text", "text": rest})
+			}
+			m["content"] = parts
+		}
 		if msg.ToolCallID != "" {
 			m["tool_call_id"] = msg.ToolCallID
 		}
@@ -146,11 +241,8 @@ func (p *OpenAIProvider) parseResponse(resp *openAIResponse) (*ChatResponse, err
 	result := &ChatResponse{
 		Content:      choice.Message.Content,
 		FinishReason: choice.FinishReason,
-		Usage: Usage{
-			PromptTokens:     resp.Usage.PromptTokens,
-			CompletionTokens: resp.Usage.CompletionTokens,
-			TotalTokens:      resp.Usage.TotalTokens,
-		},
+		Usage:        resp.Usage.usage(),
+		Refusal:      choice.Message.Refusal,
 	}
 
 	// Parse tool calls
@@ -174,11 +266,27 @@ func (p *OpenAIProvider) parseResponse(resp *openAIResponse) (*ChatResponse, err
 // OpenAI API response types
 type openAIResponse struct {
 	Choices []openAIChoice `json:"choices"`
-	Usage   struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage   openAIUsage    `json:"usage"`
+}
+
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	// Anthropic-style cache reads, as passed through by some gateways.
+	CacheReadInputTokens int `json:"cache_read_input_tokens"`
+}
+
+func (u openAIUsage) usage() Usage {
+	return Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+		CachedTokens:     max(u.PromptTokensDetails.CachedTokens, u.CacheReadInputTokens),
+	}
 }
 
 type openAIChoice struct {
@@ -189,6 +297,7 @@ type openAIChoice struct {
 type openAIMessage struct {
 	Role      string           `json:"role"`
 	Content   string           `json:"content"`
+	Refusal   string           `json:"refusal,omitempty"`
 	ToolCalls []openAIToolCall `json:"tool_calls,omitempty"`
 }
 
@@ -202,66 +311,22 @@ type openAIToolCall struct {
 }
 
 // Transcribe converts audio to text using OpenAI Whisper API.
-func (p *OpenAIProvider) Transcribe(ctx context.Context, req *AudioRequest) (*AudioResponse, error) {
+func (p *OpenAIProvider) Transcribe(ctx context.Context, req *AudioRequest) (_ *AudioResponse, err error) {
 	model := req.Model
 	if model == "" {
 		model = "whisper-1"
 	}
-
-	file, err := os.Open(req.FilePath)
-	if err != nil {
-		return nil, fmt.Errorf("open audio file: %w", err)
-	}
-	defer file.Close()
-
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("file", filepath.Base(req.FilePath))
-	if err != nil {
-		return nil, fmt.Errorf("create form file: %w", err)
-	}
-	_, err = io.Copy(part, file)
+	ctx, span := tracing.StartKind(ctx, tracing.KindClient, "transcribe "+model, "gen_ai.request.model", model)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+	api := &transcribe.API{Provider: "openai", BaseURL: p.apiBase, APIKey: p.apiKey, Model: model, Client: p.httpClient}
+	text, err := api.Transcribe(ctx, req.FilePath)
 	if err != nil {
-		return nil, fmt.Errorf("copy file to form: %w", err)
+		return nil, err
 	}
-
-	_ = writer.WriteField("model", model)
-	err = writer.Close()
-	if err != nil {
-		return nil, fmt.Errorf("close form writer: %w", err)
-	}
-
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.apiBase+"/audio/transcriptions", body)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	httpReq.Header.Set("Content-Type", writer.FormDataContentType())
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
-
-	resp, err := p.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	respBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Whisper API error (status %d): %s", resp.StatusCode, string(respBody))
-	}
-
-	var audioResp struct {
-		Text string `json:"text"`
-	}
-	if err := json.Unmarshal(respBody, &audioResp); err != nil {
-		return nil, fmt.Errorf("parse response: %w", err)
-	}
-
-	return &AudioResponse{Text: audioResp.Text}, nil
+	return &AudioResponse{Text: text}, nil
 }
 
 // Speak converts text to audio using OpenAI TTS API.
@@ -270,6 +335,8 @@ func (p *OpenAIProvider) Speak(ctx context.Context, req *TTSRequest) (*TTSRespon
 		req.Voice = "nova" // Default to a neutral, professional voice
 	}
 	model := "tts-1"
+	ctx, span := tracing.StartKind(ctx, tracing.KindClient, "speech "+model, "gen_ai.request.model", model, "chars", len(req.Text))
+	defer span.End()
 
 	reqBody := map[string]interface{}{
 		"model":           model,
@@ -0,0 +1,160 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChatStream sends a streaming completion request and reports content deltas
+// as they arrive. Tool call fragments are assembled into the final response.
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string)) (_ *ChatResponse, err error) {
+	body := p.buildChatBody(req)
+	body["stream"] = true
+	body["stream_options"] = map[string]any{"include_usage": true}
+	ctx, span := startChatSpan(ctx, body)
+	var result *ChatResponse
+	defer func() { endChatSpan(span, result, err) }()
+	start, first := time.Now(), true
+	deltas := func(delta string) {
+		if first {
+			first = false
+			span.SetAttr("time_to_first_chunk_ms", time.Since(start))
+		}
+		onDelta(delta)
+	}
+
+	httpReq, err := p.newChatRequest(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, apiError(resp.StatusCode, respBody)
+	}
+
+	result, err = parseStream(resp.Body, deltas)
+	return result, err
+}
+
+// openAIStreamChunk is one "data:" payload of a streamed completion.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			Refusal   string `json:"refusal"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+type partialToolCall struct {
+	id   string
+	name string
+	args strings.Builder
+}
+
+// parseStream reads an OpenAI-style SSE stream until [DONE] or EOF.
+func parseStream(r io.Reader, onDelta func(string)) (*ChatResponse, error) {
+	result := &ChatResponse{}
+	var content, refusal strings.Builder
+	calls := map[int]*partialToolCall{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("parse stream chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage.usage()
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				content.WriteString(choice.Delta.Content)
+				if onDelta != nil {
+					onDelta(choice.Delta.Content)
+				}
+			}
+			refusal.WriteString(choice.Delta.Refusal)
+			for _, tc := range choice.Delta.ToolCalls {
+				pc := calls[tc.Index]
+				if pc == nil {
+					pc = &partialToolCall{}
+					calls[tc.Index] = pc
+				}
+				if tc.ID != "" {
+					pc.id = tc.ID
+				}
+				if tc.Function.Name != "" {
+					pc.name = tc.Function.Name
+				}
+				pc.args.WriteString(tc.Function.Arguments)
+			}
+			if choice.FinishReason != "" {
+				result.FinishReason = choice.FinishReason
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read stream: %w", err)
+	}
+
+	result.Content = content.String()
+	result.Refusal = refusal.String()
+
+	indexes := make([]int, 0, len(calls))
+	for i := range calls {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		pc := calls[i]
+		var args map[string]any
+		if raw := pc.args.String(); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &args); err != nil {
+				args = map[string]any{"raw": raw}
+			}
+		}
+		result.ToolCalls = append(result.ToolCalls, ToolCall{
+			ID:        pc.id,
+			Name:      pc.name,
+			Arguments: args,
+		})
+	}
+
+	return result, nil
+}
@@ -17,6 +17,13 @@ type LLMProvider interface {
 	DefaultModel() string
 }
 
+// StreamingProvider is implemented by providers that can stream completions.
+type StreamingProvider interface {
+	// ChatStream behaves like Chat but calls onDelta with each content fragment
+	// as it arrives. The returned response contains the assembled result.
+	ChatStream(ctx context.Context, req *ChatRequest, onDelta func(delta string)) (*ChatResponse, error)
+}
+
 // TTSRequest contains parameters for speech synthesis.
 type TTSRequest struct {
 	Text  string
@@ -48,6 +55,13 @@ type ChatRequest struct {
 	Model       string
 	MaxTokens   int
 	Temperature float64
+	// TopP and ReasoningEffort are sent only when set; not every model
+	// accepts them.
+	TopP            float64
+	ReasoningEffort string // "low", "medium", or "high"
+	// ResponseSchema, if set, constrains the reply to JSON matching the
+	// schema. Not every model enforces it; check the reply with Validate.
+	ResponseSchema *JSONSchema
 }
 
 // ChatResponse contains the response from a chat completion request.
@@ -56,14 +70,20 @@ type ChatResponse struct {
 	ToolCalls    []ToolCall
 	FinishReason string
 	Usage        Usage
+	// Refusal is the model's explanation when it declines to answer, as
+	// sent by providers that report refusals separately from the content.
+	Refusal string
 }
 
 // Message represents a chat message.
 type Message struct {
-	Role       string     `json:"role"`
-	Content    string     `json:"content"`
-	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
-	ToolCallID string     `json:"tool_call_id,omitempty"`
+	Role    string `json:"role"`
+	Content string `json:"content"`
+	// CachePrefix marks Content[:CachePrefix] as identical across requests,
+	// so providers with explicit prompt caching can cache it.
+	CachePrefix int        `json:"-"`
+	ToolCalls   []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID  string     `json:"tool_call_id,omitempty"`
 }
 
 // ToolCall represents a tool call from the LLM.
@@ -91,4 +111,15 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	// CachedTokens is the part of PromptTokens served from the provider's
+	// prompt cache.
+	CachedTokens int `json:"cached_tokens,omitempty"`
+}
+
+// Add accumulates another usage record.
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.CompletionTokens += o.CompletionTokens
+	u.TotalTokens += o.TotalTokens
+	u.CachedTokens += o.CachedTokens
 }
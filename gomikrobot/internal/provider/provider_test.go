@@ -5,13 +5,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
 func TestOpenAIProvider_DefaultModel(t *testing.T) {
 	p := NewOpenAIProvider("test-key", "", "")
-	if p.DefaultModel() != "anthropic/claude-sonnet-4-5" {
-		t.Errorf("expected default model anthropic/claude-sonnet-4-5, got %s", p.DefaultModel())
+	if p.DefaultModel() != "gpt-4o" {
+		t.Errorf("expected default model gpt-4o, got %s", p.DefaultModel())
 	}
 
 	p = NewOpenAIProvider("test-key", "", "openai/gpt-4")
@@ -30,11 +31,7 @@ func TestOpenAIProvider_ParseSimpleResponse(t *testing.T) {
 					FinishReason: "stop",
 				},
 			},
-			Usage: struct {
-				PromptTokens     int `json:"prompt_tokens"`
-				CompletionTokens int `json:"completion_tokens"`
-				TotalTokens      int `json:"total_tokens"`
-			}{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+			Usage: openAIUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
 		}
 		json.NewEncoder(w).Encode(resp)
 	}))
@@ -145,3 +142,244 @@ func TestOpenAIProvider_APIError(t *testing.T) {
 		t.Error("expected error for unauthorized request")
 	}
 }
+
+func TestOpenAIProvider_ChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["stream"] != true {
+			t.Errorf("expected stream=true in request")
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := []string{
+			`{"choices":[{"delta":{"content":"Hel"}}]}`,
+			`{"choices":[{"delta":{"content":"lo"}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"read_file","arguments":"{\"pa"}}]}}]}`,
+			`{"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\": \"/tmp/x\"}"}}]},"finish_reason":"tool_calls"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":3,"total_tokens":10}}`,
+		}
+		for _, c := range chunks {
+			w.Write([]byte("data: " + c + "\n\n"))
+		}
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("test-key", server.URL, "test-model")
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), &ChatRequest{
+		Messages: []Message{{Role: "user", Content: "Hello"}},
+	}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream() error: %v", err)
+	}
+
+	if resp.Content != "Hello" || len(deltas) != 2 {
+		t.Errorf("expected content 'Hello' from 2 deltas, got %q from %v", resp.Content, deltas)
+	}
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Arguments["path"] != "/tmp/x" {
+		t.Fatalf("expected assembled tool call, got %+v", resp.ToolCalls)
+	}
+	if resp.FinishReason != "tool_calls" {
+		t.Errorf("expected finish_reason tool_calls, got %s", resp.FinishReason)
+	}
+	if resp.Usage.TotalTokens != 10 {
+		t.Errorf("expected total_tokens 10, got %d", resp.Usage.TotalTokens)
+	}
+}
+
+func TestOpenAIProvider_Embed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/embeddings" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "embed-small" || len(req.Input) != 2 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		// Out of order on purpose; results are sorted by index.
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("test-key", server.URL, "")
+	vectors, err := p.Embed(context.Background(), []string{"a", "b"}, "embed-small")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("unexpected vectors %v", vectors)
+	}
+}
+
+func TestOllamaEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "nomic-embed-text" || len(req.Input) != 2 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		w.Write([]byte(`{"model":"nomic-embed-text","embeddings":[[1,0],[0,1]]}`))
+	}))
+	defer server.Close()
+
+	vectors, err := NewOllamaEmbedder(server.URL+"/").Embed(context.Background(), []string{"a", "b"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 2 || vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("unexpected vectors %v", vectors)
+	}
+}
+
+// countingEmbedder returns the length of each text and records the batches.
+type countingEmbedder struct{ batches [][]string }
+
+func (e *countingEmbedder) Embed(ctx context.Context, texts []string, model string) ([][]float32, error) {
+	e.batches = append(e.batches, texts)
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = []float32{float32(len(text))}
+	}
+	return vectors, nil
+}
+
+func TestCachedEmbedder(t *testing.T) {
+	inner := &countingEmbedder{}
+	e := NewCachedEmbedder(inner, 2, 3)
+	ctx := context.Background()
+
+	vectors, err := e.Embed(ctx, []string{"a", "bb", "a", "ccc", "dddd"}, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 5 || vectors[2][0] != 1 || vectors[4][0] != 4 {
+		t.Errorf("unexpected vectors %v", vectors)
+	}
+	if len(inner.batches) != 2 || len(inner.batches[0]) != 2 || len(inner.batches[1]) != 2 {
+		t.Errorf("expected two batches of two distinct texts, got %v", inner.batches)
+	}
+
+	// "a" was evicted by the cache size of 3; "dddd" is still cached.
+	inner.batches = nil
+	if _, err := e.Embed(ctx, []string{"dddd", "a"}, "m"); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.batches) != 1 || len(inner.batches[0]) != 1 || inner.batches[0][0] != "a" {
+		t.Errorf("expected only the evicted text to be embedded, got %v", inner.batches)
+	}
+
+	// The model is part of the cache key.
+	inner.batches = nil
+	e.Embed(ctx, []string{"dddd"}, "other")
+	if len(inner.batches) != 1 {
+		t.Errorf("cached vector of another model reused")
+	}
+}
+
+func TestConvertMessagesCacheHints(t *testing.T) {
+	p := NewOpenAIProvider("test-key", "", "")
+	msgs := []Message{{Role: "system", Content: "static prompt\n\ndynamic", CachePrefix: len("static prompt")}, {Role: "user", Content: "hi"}}
+
+	plain := p.convertMessages(msgs, false)
+	if plain[0]["content"] != msgs[0].Content {
+		t.Errorf("without hints the content should be a string, got %v", plain[0]["content"])
+	}
+
+	hinted := p.convertMessages(msgs, cacheHints("anthropic/claude-sonnet-4"))
+	parts, ok := hinted[0]["content"].([]map[string]any)
+	if !ok || len(parts) != 2 || parts[0]["text"] != "static prompt" || parts[0]["cache_control"] == nil || parts[1]["cache_control"] != nil {
+		t.Errorf("unexpected system parts %v", hinted[0]["content"])
+	}
+	if hinted[1]["content"] != "hi" {
+		t.Errorf("user message changed: %v", hinted[1]["content"])
+	}
+	if cacheHints("gpt-4o") {
+		t.Error("OpenAI models must not get cache_control")
+	}
+}
+
+func TestResponseSchema(t *testing.T) {
+	schema := &JSONSchema{Name: "event", Strict: true, Schema: map[string]any{
+		"type":                 "object",
+		"required":             []any{"title", "priority"},
+		"additionalProperties": false,
+		"properties": map[string]any{
+			"title":    map[string]any{"type": "string"},
+			"priority": map[string]any{"type": "string", "enum": []any{"low", "high"}},
+			"tags":     map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"count":    map[string]any{"type": []any{"integer", "null"}},
+		},
+	}}
+
+	body := NewOpenAIProvider("test-key", "", "").buildChatBody(&ChatRequest{ResponseSchema: schema})
+	format, _ := body["response_format"].(map[string]any)
+	if format["type"] != "json_schema" || format["json_schema"].(map[string]any)["name"] != "event" {
+		t.Errorf("response_format = %v", body["response_format"])
+	}
+
+	for data, ok := range map[string]bool{
+		`{"title": "Launch", "priority": "high", "tags": ["a"], "count": 2}`: true,
+		`{"title": "Launch", "priority": "high", "count": null}`:             true,
+		`{"title": "Launch"}`:                              false, // missing required
+		`{"title": "Launch", "priority": "urgent"}`:        false, // not in enum
+		`{"title": 1, "priority": "low"}`:                  false, // wrong type
+		`{"title": "x", "priority": "low", "tags": [1]}`:   false, // wrong item type
+		`{"title": "x", "priority": "low", "extra": true}`: false, // additional property
+		`{"title": "x", "priority": "low", "count": 1.5}`:  false, // not an integer
+		`not json`: false,
+	} {
+		if err := schema.Validate([]byte(data)); (err == nil) != ok {
+			t.Errorf("Validate(%s) = %v, want ok=%v", data, err, ok)
+		}
+	}
+
+	if got := ExtractJSON("Here you go:\n```json\n{\"a\": 1}\n```"); got != `{"a": 1}` {
+		t.Errorf("ExtractJSON = %q", got)
+	}
+	if err := (&JSONSchema{Name: "bad name", Schema: map[string]any{"type": "object"}}).Check(); err == nil {
+		t.Error("expected an error for an invalid schema name")
+	}
+}
+
+func TestAPIErrorKinds(t *testing.T) {
+	if err := apiError(429, []byte(`{"error":{"message":"Rate limit reached"}}`)); !IsRateLimitError(err) || IsContextLengthError(err) {
+		t.Errorf("429 should be a rate limit error: %v", err)
+	}
+	if err := apiError(400, []byte(`{"error":{"code":"context_length_exceeded"}}`)); !IsContextLengthError(err) || IsRateLimitError(err) {
+		t.Errorf("expected a context length error: %v", err)
+	}
+	if err := apiError(400, []byte(`{"error":{"code":"content_filter","message":"The response was filtered"}}`)); !IsContentFilterError(err) || IsContextLengthError(err) {
+		t.Errorf("expected a content filter error: %v", err)
+	}
+}
+
+func TestIsRefusal(t *testing.T) {
+	for _, tc := range []struct {
+		resp *ChatResponse
+		want bool
+	}{
+		{&ChatResponse{Refusal: "I can't help with that."}, true},
+		{&ChatResponse{Content: "partial", FinishReason: "content_filter"}, true},
+		{&ChatResponse{Content: "I’m sorry, but I can’t assist with that request."}, true},
+		{&ChatResponse{Content: "I'm sorry, but I can't find that file. " + strings.Repeat("Here is what I found instead. ", 10)}, false},
+		{&ChatResponse{Content: "Sure, here you go."}, false},
+		{&ChatResponse{Content: "I can't help with that", ToolCalls: []ToolCall{{Name: "web_search"}}}, false},
+		{nil, false},
+	} {
+		if got := IsRefusal(tc.resp); got != tc.want {
+			t.Errorf("IsRefusal(%+v) = %v, want %v", tc.resp, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// JSONSchema describes the structured output requested with
+// ChatRequest.ResponseSchema.
+type JSONSchema struct {
+	// Name identifies the schema, e.g. "invoice" ([a-zA-Z0-9_-], max 64).
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema"`
+	// Strict asks the provider to enforce the schema exactly. OpenAI then
+	// requires every property to be listed in "required" and
+	// "additionalProperties": false on every object.
+	Strict bool `json:"strict,omitempty"`
+}
+
+var schemaNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// Check reports whether the schema is usable in a request.
+func (s *JSONSchema) Check() error {
+	if !schemaNameRe.MatchString(s.Name) {
+		return fmt.Errorf("schema name %q: use 1-64 letters, digits, _ or -", s.Name)
+	}
+	if len(s.Schema) == 0 {
+		return fmt.Errorf("schema %s is empty", s.Name)
+	}
+	return nil
+}
+
+// Validate checks data against the schema. It covers the keywords models
+// are asked to follow: type, properties, required, additionalProperties,
+// items and enum.
+func (s *JSONSchema) Validate(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return validateValue(v, s.Schema, "$")
+}
+
+func validateValue(v any, schema map[string]any, path string) error {
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(e any) bool { return jsonEqual(e, v) }) {
+		return fmt.Errorf("%s: %v is not one of %v", path, v, enum)
+	}
+	if t, ok := schema["type"]; ok && !matchesType(v, t) {
+		return fmt.Errorf("%s: expected %v, got %s", path, t, jsonType(v))
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		if required, ok := schema["required"].([]any); ok {
+			for _, r := range required {
+				if name, _ := r.(string); name != "" {
+					if _, ok := v[name]; !ok {
+						return fmt.Errorf("%s: missing required property %q", path, name)
+					}
+				}
+			}
+		}
+		for name, val := range v {
+			sub, ok := props[name].(map[string]any)
+			if !ok {
+				if schema["additionalProperties"] == false {
+					return fmt.Errorf("%s: unexpected property %q", path, name)
+				}
+				continue
+			}
+			if err := validateValue(val, sub, path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateValue(item, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether v has the schema type t, a name or a list.
+func matchesType(v any, t any) bool {
+	switch t := t.(type) {
+	case string:
+		got := jsonType(v)
+		return got == t || (t == "number" && got == "integer")
+	case []any:
+		return slices.ContainsFunc(t, func(e any) bool { return matchesType(v, e) })
+	}
+	return true
+}
+
+func jsonType(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func jsonEqual(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+// ExtractJSON returns the JSON value in a model reply, dropping code
+// fences and text around it.
+func ExtractJSON(reply string) string {
+	s := strings.TrimSpace(reply)
+	if strings.HasPrefix(s, "```") {
+		if i := strings.Index(s, "\n"); i >= 0 {
+			s = s[i+1:]
+		}
+		s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+	}
+	if json.Valid([]byte(s)) {
+		return s
+	}
+	start := strings.IndexAny(s, "{[")
+	end := strings.LastIndexAny(s, "}]")
+	if start >= 0 && end > start {
+		return s[start : end+1]
+	}
+	return s
+}
@@ -2,13 +2,10 @@ package provider
 
 import (
 	"context"
-	"fmt"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
 
 	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/tracing"
+	"github.com/kamir/gomikrobot/internal/transcribe"
 )
 
 // LocalWhisperProvider implements transcription using a local Whisper binary.
@@ -29,6 +26,10 @@ func (p *LocalWhisperProvider) Chat(ctx context.Context, req *ChatRequest) (*Cha
 	return p.openai.Chat(ctx, req)
 }
 
+func (p *LocalWhisperProvider) ChatStream(ctx context.Context, req *ChatRequest, onDelta func(string)) (*ChatResponse, error) {
+	return p.openai.ChatStream(ctx, req, onDelta)
+}
+
 func (p *LocalWhisperProvider) Speak(ctx context.Context, req *TTSRequest) (*TTSResponse, error) {
 	return p.openai.Speak(ctx, req)
 }
@@ -47,44 +48,13 @@ func (p *LocalWhisperProvider) Transcribe(ctx context.Context, req *AudioRequest
 	if model == "" {
 		model = p.config.Model
 	}
-
-	// Create a temporary directory for output
-	tmpDir, err := os.MkdirTemp("", "whisper-")
+	local := &transcribe.Local{Binary: p.config.BinaryPath, Model: model, Language: "de"}
+	ctx, span := tracing.Start(ctx, "transcribe local", "model", model)
+	text, err := local.Transcribe(ctx, req.FilePath)
+	span.RecordError(err)
+	span.End()
 	if err != nil {
-		return nil, fmt.Errorf("create temp dir: %w", err)
+		return nil, err
 	}
-	defer os.RemoveAll(tmpDir)
-
-	// Command: whisper <file> --model <model> --output_dir <tmpDir> --output_format txt --language de
-	args := []string{
-		req.FilePath,
-		"--model", model,
-		"--output_dir", tmpDir,
-		"--output_format", "txt",
-		"--language", "de", // Enforce German
-		"--verbose", "False",
-	}
-
-	cmd := exec.CommandContext(ctx, p.config.BinaryPath, args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("whisper command failed: %w (output: %s)", err, string(output))
-	}
-
-	// Read the output txt file
-	// Whisper usually outputs <filename>.txt
-	base := filepath.Base(req.FilePath)
-	// Remove extension
-	ext := filepath.Ext(base)
-	name := strings.TrimSuffix(base, ext)
-	txtPath := filepath.Join(tmpDir, name+".txt")
-
-	txtData, err := os.ReadFile(txtPath)
-	if err != nil {
-		return nil, fmt.Errorf("read transcription output: %w", err)
-	}
-
-	return &AudioResponse{
-		Text: strings.TrimSpace(string(txtData)),
-	}, nil
+	return &AudioResponse{Text: text}, nil
 }
@@ -0,0 +1,310 @@
+// Package proxy exposes the configured LLM provider as an authenticated,
+// metered pass-through for other local applications.
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+	"github.com/kamir/gomikrobot/internal/security"
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// UsageStore persists and sums proxy usage per client key.
+type UsageStore interface {
+	RecordUsage(rec *timeline.UsageRecord) error
+	SessionUsageBetween(sessionKey string, start, end time.Time) (timeline.UsageTotals, error)
+}
+
+// Options configures a Proxy.
+type Options struct {
+	// Upstream is the provider API base, e.g. https://api.openai.com/v1.
+	Upstream string
+	// APIKey is the real provider credential; clients never see it.
+	APIKey string
+	Keys   []config.ProxyKey
+	Usage  UsageStore
+	Client *http.Client
+}
+
+// Proxy forwards OpenAI-compatible requests to the upstream provider.
+type Proxy struct {
+	upstream string
+	apiKey   string
+	usage    UsageStore
+	client   *http.Client
+
+	mu   sync.RWMutex
+	keys []config.ProxyKey
+}
+
+// New creates a proxy. Mount it with http.StripPrefix so request paths are
+// relative to the upstream base (e.g. /chat/completions).
+func New(opts Options) *Proxy {
+	if opts.Upstream == "" {
+		opts.Upstream = "https://api.openai.com/v1"
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 5 * time.Minute}
+	}
+	return &Proxy{
+		upstream: strings.TrimSuffix(opts.Upstream, "/"),
+		apiKey:   opts.APIKey,
+		usage:    opts.Usage,
+		client:   opts.Client,
+		keys:     opts.Keys,
+	}
+}
+
+// SetKeys replaces the accepted client keys.
+func (p *Proxy) SetKeys(keys []config.ProxyKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+}
+
+// forwardedHeaders are the client request headers passed upstream. The
+// transport negotiates compression itself, so responses arrive decoded for
+// metering and redaction.
+var forwardedHeaders = []string{"Content-Type", "Accept", "Openai-Beta"}
+
+// hopHeaders are not copied from upstream responses.
+var hopHeaders = []string{
+	"Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "Connection", "Keep-Alive",
+	"Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length",
+}
+
+// routes are the upstream endpoints clients may call, with their method.
+// Everything else the real key could reach (files, fine-tuning, batches)
+// stays closed.
+var routes = map[string]string{
+	"/chat/completions": http.MethodPost,
+	"/completions":      http.MethodPost,
+	"/embeddings":       http.MethodPost,
+	"/models":           http.MethodGet,
+}
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	key, ok := p.authenticate(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "invalid_api_key", "invalid proxy key")
+		return
+	}
+	method, ok := routes[r.URL.Path]
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "unknown endpoint")
+		return
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	sessionKey := "proxy:" + key.Name
+
+	if key.MonthlyTokenLimit > 0 && p.usage != nil {
+		now := time.Now()
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		used, err := p.usage.SessionUsageBetween(sessionKey, monthStart, now.Add(time.Second))
+		if err == nil && used.PromptTokens+used.CompletionTokens >= key.MonthlyTokenLimit {
+			fmt.Printf("🔀 Proxy [%s] blocked: monthly limit of %d tokens reached\n", key.Name, key.MonthlyTokenLimit)
+			writeError(w, http.StatusTooManyRequests, "insufficient_quota", "monthly token limit reached for this key")
+			return
+		}
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "could not read request body")
+		return
+	}
+
+	// Every request that carries a body must name an allowed model, and
+	// streams are asked to report usage.
+	var model string
+	if method == http.MethodPost {
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid_request_error", "request body must be a JSON object")
+			return
+		}
+		model, _ = payload["model"].(string)
+		if len(key.AllowedModels) > 0 && !slices.Contains(key.AllowedModels, model) {
+			writeError(w, http.StatusForbidden, "model_not_allowed", fmt.Sprintf("model %q is not allowed for this key", model))
+			return
+		}
+		if stream, _ := payload["stream"].(bool); stream {
+			payload["stream_options"] = map[string]any{"include_usage": true}
+			if b, err := json.Marshal(payload); err == nil {
+				body = b
+			}
+		}
+	} else {
+		body = nil
+	}
+
+	target := p.upstream + r.URL.Path
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	upReq, err := http.NewRequestWithContext(r.Context(), r.Method, target, bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid request")
+		return
+	}
+	for _, k := range forwardedHeaders {
+		if v := r.Header.Values(k); len(v) > 0 {
+			upReq.Header[k] = v
+		}
+	}
+	upReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(upReq)
+	if err != nil {
+		fmt.Printf("🔀 Proxy [%s] upstream error: %s\n", key.Name, security.SanitizeError(err))
+		writeError(w, http.StatusBadGateway, "upstream_error", "provider unavailable")
+		return
+	}
+	defer resp.Body.Close()
+
+	copyHeaders(w.Header(), resp.Header)
+
+	var usage usageFields
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		w.WriteHeader(resp.StatusCode)
+		usage = copyStream(w, resp.Body)
+	} else {
+		data, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode >= 400 {
+			// Upstream errors may echo the real credential.
+			redacted := string(data)
+			if p.apiKey != "" {
+				redacted = strings.ReplaceAll(redacted, p.apiKey, "[REDACTED]")
+			}
+			data = []byte(security.RedactSecrets(redacted))
+		} else {
+			usage = extractUsage(data)
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = w.Write(data)
+	}
+
+	if usage.Model != "" {
+		model = usage.Model
+	}
+	fmt.Printf("🔀 Proxy [%s] %s %s model=%s → %d (%d+%d tokens, %s)\n",
+		key.Name, r.Method, r.URL.Path, model, resp.StatusCode,
+		usage.Usage.PromptTokens, usage.Usage.CompletionTokens, time.Since(start).Round(time.Millisecond))
+
+	if p.usage != nil {
+		err := p.usage.RecordUsage(&timeline.UsageRecord{
+			Timestamp:        time.Now(),
+			SessionKey:       sessionKey,
+			Model:            model,
+			PromptTokens:     usage.Usage.PromptTokens,
+			CompletionTokens: usage.Usage.CompletionTokens,
+			Failed:           resp.StatusCode >= 400,
+		})
+		if err != nil {
+			fmt.Printf("⚠️ Proxy usage not recorded: %v\n", err)
+		}
+	}
+}
+
+// authenticate matches the request's bearer token against the client keys.
+func (p *Proxy) authenticate(r *http.Request) (config.ProxyKey, bool) {
+	tok := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if tok == "" {
+		tok = r.Header.Get("X-Api-Key")
+	}
+	if tok == "" {
+		return config.ProxyKey{}, false
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for _, k := range p.keys {
+		if k.Key != "" && subtle.ConstantTimeCompare([]byte(k.Key), []byte(tok)) == 1 {
+			return k, true
+		}
+	}
+	return config.ProxyKey{}, false
+}
+
+type usageFields struct {
+	Model string `json:"model"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func extractUsage(data []byte) usageFields {
+	var u usageFields
+	_ = json.Unmarshal(data, &u)
+	return u
+}
+
+// copyStream relays an SSE body line by line, flushing each event, and
+// returns the last usage block seen.
+func copyStream(w http.ResponseWriter, body io.Reader) usageFields {
+	flusher, _ := w.(http.Flusher)
+	var usage usageFields
+
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			_, _ = w.Write(line)
+			if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+				if u := extractUsage(bytes.TrimSpace(data)); u.Usage.PromptTokens+u.Usage.CompletionTokens > 0 {
+					usage = u
+				} else if usage.Model == "" && u.Model != "" {
+					usage.Model = u.Model
+				}
+			}
+			if flusher != nil && len(bytes.TrimSpace(line)) == 0 {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return usage
+}
+
+func copyHeaders(dst, src http.Header) {
+	for k, vv := range src {
+		if slices.Contains(hopHeaders, http.CanonicalHeaderKey(k)) {
+			continue
+		}
+		for _, v := range vv {
+			dst.Add(k, v)
+		}
+	}
+}
+
+// writeError writes an OpenAI-style error body.
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]string{"message": msg, "type": code, "code": code},
+	})
+}
@@ -0,0 +1,154 @@
+package secrets
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// File stores secrets in a single file encrypted with XChaCha20-Poly1305
+// under a key derived from Passphrase with scrypt. The whole set is
+// rewritten on every change.
+type File struct {
+	Path       string
+	Passphrase string
+
+	mu sync.Mutex
+}
+
+// fileVersion is bumped when the on-disk format changes.
+const fileVersion = 1
+
+type sealedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func (f *File) Name() string { return "encrypted file " + f.Path }
+
+func deriveKey(pass string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(pass), salt, 1<<15, 8, 1, chacha20poly1305.KeySize)
+}
+
+// load decrypts the file. A missing file is an empty set.
+func (f *File) load() (map[string]string, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sealed sealedFile
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	if sealed.Version != fileVersion {
+		return nil, fmt.Errorf("%s: unsupported version %d", f.Path, sealed.Version)
+	}
+	key, err := deriveKey(f.Passphrase, sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%s: bad nonce", f.Path)
+	}
+	plain, err := aead.Open(nil, sealed.Nonce, sealed.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: wrong passphrase or corrupted file", f.Path)
+	}
+	out := map[string]string{}
+	if err := json.Unmarshal(plain, &out); err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Path, err)
+	}
+	return out, nil
+}
+
+// save encrypts all secrets with a fresh salt and nonce and replaces the
+// file atomically.
+func (f *File) save(all map[string]string) error {
+	plain, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	sealed := sealedFile{Version: fileVersion, Salt: make([]byte, 16), Nonce: make([]byte, chacha20poly1305.NonceSizeX)}
+	if _, err := rand.Read(sealed.Salt); err != nil {
+		return err
+	}
+	if _, err := rand.Read(sealed.Nonce); err != nil {
+		return err
+	}
+	key, err := deriveKey(f.Passphrase, sealed.Salt)
+	if err != nil {
+		return err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return err
+	}
+	sealed.Data = aead.Seal(nil, sealed.Nonce, plain, nil)
+	data, err := json.MarshalIndent(sealed, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+func (f *File) Get(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	v, ok := all[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+func (f *File) Set(name, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all, err := f.load()
+	if err != nil {
+		return err
+	}
+	all[name] = value
+	return f.save(all)
+}
+
+func (f *File) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	all, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := all[name]; !ok {
+		return nil
+	}
+	delete(all, name)
+	return f.save(all)
+}
@@ -0,0 +1,123 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// keychainTool describes the command-line client of an OS keychain.
+type keychainTool struct {
+	name string
+	bin  string
+	// Arguments for each operation. set passes the value on stdin when
+	// stdinValue is true and as the last argument otherwise.
+	get, set, del func(name string) []string
+	stdinValue    bool
+	// notFound reports whether a failed command means the secret is absent.
+	notFound func(exitCode int, stderr string) bool
+}
+
+// macKeychain uses the security tool. It only accepts the password as an
+// argument, so it is briefly visible to other processes of the same user.
+var macKeychain = keychainTool{
+	name: "macOS Keychain",
+	bin:  "security",
+	get: func(n string) []string {
+		return []string{"find-generic-password", "-s", Service, "-a", n, "-w"}
+	},
+	set: func(n string) []string {
+		return []string{"add-generic-password", "-U", "-s", Service, "-a", n, "-w"}
+	},
+	del: func(n string) []string {
+		return []string{"delete-generic-password", "-s", Service, "-a", n}
+	},
+	notFound: func(code int, _ string) bool { return code == 44 },
+}
+
+// secretService uses secret-tool from libsecret (GNOME Keyring, KWallet).
+var secretService = keychainTool{
+	name: "Secret Service",
+	bin:  "secret-tool",
+	get: func(n string) []string {
+		return []string{"lookup", "service", Service, "account", n}
+	},
+	set: func(n string) []string {
+		return []string{"store", "--label", Service + " " + n, "service", Service, "account", n}
+	},
+	del: func(n string) []string {
+		return []string{"clear", "service", Service, "account", n}
+	},
+	stdinValue: true,
+	// lookup exits 1 without output for unknown attributes.
+	notFound: func(code int, stderr string) bool { return code == 1 && strings.TrimSpace(stderr) == "" },
+}
+
+type keychain struct {
+	tool keychainTool
+	// run executes the tool; tests replace it.
+	run func(bin string, args []string, stdin string) (stdout, stderr string, code int, err error)
+}
+
+func (k *keychain) Name() string { return k.tool.name }
+
+func (k *keychain) exec(args []string, stdin string) (string, string, int, error) {
+	if k.run != nil {
+		return k.run(k.tool.bin, args, stdin)
+	}
+	cmd := exec.Command(k.tool.bin, args...)
+	cmd.Stdin = strings.NewReader(stdin)
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return out.String(), errOut.String(), exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return "", "", -1, fmt.Errorf("%s: %w", k.tool.bin, err)
+	}
+	return out.String(), errOut.String(), 0, nil
+}
+
+func (k *keychain) Get(name string) (string, error) {
+	out, errOut, code, err := k.exec(k.tool.get(name), "")
+	if err != nil {
+		return "", err
+	}
+	if code != 0 {
+		if k.tool.notFound(code, errOut) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("%s: %s", k.tool.bin, strings.TrimSpace(errOut))
+	}
+	return strings.TrimRight(out, "\r\n"), nil
+}
+
+func (k *keychain) Set(name, value string) error {
+	args, stdin := k.tool.set(name), value
+	if !k.tool.stdinValue {
+		args, stdin = append(args, value), ""
+	}
+	_, errOut, code, err := k.exec(args, stdin)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return fmt.Errorf("%s: %s", k.tool.bin, strings.TrimSpace(errOut))
+	}
+	return nil
+}
+
+func (k *keychain) Delete(name string) error {
+	_, errOut, code, err := k.exec(k.tool.del(name), "")
+	if err != nil {
+		return err
+	}
+	if code != 0 && !k.tool.notFound(code, errOut) {
+		return fmt.Errorf("%s: %s", k.tool.bin, strings.TrimSpace(errOut))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package secrets keeps API keys and tokens out of the config file. A
+// config value of "${secret:NAME}" is replaced at load time with the
+// secret NAME from the configured store: the OS keychain (macOS Keychain
+// or the Linux Secret Service) or a passphrase-encrypted file.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+)
+
+// Service is the keychain service name secrets are stored under.
+const Service = "gomikrobot"
+
+// PassphraseEnv holds the passphrase of the encrypted file store.
+const PassphraseEnv = "MIKROBOT_SECRETS_PASSPHRASE"
+
+// ErrNotFound is returned by Get for unknown names.
+var ErrNotFound = errors.New("secret not found")
+
+// Store reads and writes named secrets.
+type Store interface {
+	Get(name string) (string, error)
+	Set(name, value string) error
+	Delete(name string) error
+	// Name identifies the backend in messages.
+	Name() string
+}
+
+// Open returns the store for backend: "keychain" or "file". path is the
+// encrypted file used by the file backend; its passphrase comes from
+// MIKROBOT_SECRETS_PASSPHRASE.
+func Open(backend, path string) (Store, error) {
+	switch backend {
+	case "keychain":
+		switch runtime.GOOS {
+		case "darwin":
+			return &keychain{tool: macKeychain}, nil
+		case "linux":
+			return &keychain{tool: secretService}, nil
+		}
+		return nil, fmt.Errorf("no keychain support on %s; use the file backend", runtime.GOOS)
+	case "file":
+		if path == "" {
+			return nil, errors.New("secrets.file is not set")
+		}
+		pass := os.Getenv(PassphraseEnv)
+		if pass == "" {
+			return nil, fmt.Errorf("set %s to unlock %s", PassphraseEnv, path)
+		}
+		return &File{Path: path, Passphrase: pass}, nil
+	case "":
+		return nil, errors.New("no secrets backend configured; set secrets.backend to keychain or file")
+	}
+	return nil, fmt.Errorf("unknown secrets backend %q", backend)
+}
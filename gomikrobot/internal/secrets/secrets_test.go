@@ -0,0 +1,102 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	f := &File{Path: path, Passphrase: "correct horse"}
+	if _, err := f.Get("openai.apiKey"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get on a missing file = %v, want ErrNotFound", err)
+	}
+	if err := f.Set("openai.apiKey", "sk-123"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("slack.botToken", "xoxb-1"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "sk-123") {
+		t.Error("secret stored in plaintext")
+	}
+
+	again := &File{Path: path, Passphrase: "correct horse"}
+	if v, err := again.Get("openai.apiKey"); err != nil || v != "sk-123" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if err := again.Delete("slack.botToken"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := again.Get("slack.botToken"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted secret still present: %v", err)
+	}
+
+	wrong := &File{Path: path, Passphrase: "battery staple"}
+	if _, err := wrong.Get("openai.apiKey"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("expected a passphrase error, got %v", err)
+	}
+}
+
+func TestKeychainCommands(t *testing.T) {
+	type call struct {
+		bin   string
+		args  []string
+		stdin string
+	}
+	var calls []call
+	store := map[string]string{}
+	k := &keychain{tool: secretService, run: func(bin string, args []string, stdin string) (string, string, int, error) {
+		calls = append(calls, call{bin, args, stdin})
+		name := args[len(args)-1]
+		switch args[0] {
+		case "store":
+			store[name] = stdin
+		case "lookup":
+			v, ok := store[name]
+			if !ok {
+				return "", "", 1, nil
+			}
+			return v + "\n", "", 0, nil
+		case "clear":
+			delete(store, name)
+		}
+		return "", "", 0, nil
+	}}
+
+	if err := k.Set("telegram.token", "123:abc"); err != nil {
+		t.Fatal(err)
+	}
+	want := call{"secret-tool", []string{"store", "--label", "gomikrobot telegram.token", "service", "gomikrobot", "account", "telegram.token"}, "123:abc"}
+	if !reflect.DeepEqual(calls[0], want) {
+		t.Errorf("store call = %+v, want %+v", calls[0], want)
+	}
+	if v, err := k.Get("telegram.token"); err != nil || v != "123:abc" {
+		t.Errorf("Get = %q, %v", v, err)
+	}
+	if err := k.Delete("telegram.token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Get("telegram.token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after delete = %v, want ErrNotFound", err)
+	}
+
+	mac := &keychain{tool: macKeychain, run: func(bin string, args []string, stdin string) (string, string, int, error) {
+		calls = append(calls, call{bin, args, stdin})
+		return "", "security: The specified item could not be found in the keychain.", 44, nil
+	}}
+	if _, err := mac.Get("x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("exit 44 should mean not found, got %v", err)
+	}
+	if err := mac.Set("x", "v"); err == nil {
+		t.Error("expected Set to fail")
+	}
+	if last := calls[len(calls)-1]; last.args[len(last.args)-1] != "v" || last.stdin != "" {
+		t.Errorf("security should receive the value as an argument: %+v", last)
+	}
+}
@@ -11,7 +11,8 @@ var (
 	//  1) key name
 	//  2) separator (":", "=", or whitespace)
 	//  3) value
-	keyValueSecretRegex = regexp.MustCompile(`(?i)\b(api[_-]?key|token|secret|password|auth)\b(\s*[:=]\s*|\s+)([^\s"']+)`)
+	// The key may carry a prefix such as OPENAI_API_KEY or GITHUB_TOKEN.
+	keyValueSecretRegex = regexp.MustCompile(`(?i)\b(\w*(?:api[_-]?key|token|secret|password|auth))\b(\s*[:=]\s*|\s+)([^\s"']+)`)
 
 	// bearerRegex matches Authorization header values.
 	bearerRegex = regexp.MustCompile(`(?i)\bBearer\s+([A-Za-z0-9\-_\.]+)`)
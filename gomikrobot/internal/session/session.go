@@ -18,10 +18,19 @@ type Message struct {
 	Timestamp time.Time `json:"timestamp,omitempty"`
 }
 
+// Pin is a message kept in the model's context regardless of the history
+// window, such as project instructions given once.
+type Pin struct {
+	ID        int       `json:"id"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // Session represents a conversation session.
 type Session struct {
 	Key       string         `json:"key"`
 	Messages  []Message      `json:"messages"`
+	Pinned    []Pin          `json:"pinned,omitempty"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	Metadata  map[string]any `json:"metadata,omitempty"`
@@ -68,7 +77,47 @@ func (s *Session) GetHistory(maxMessages int) []Message {
 	return result
 }
 
-// Clear removes all messages from the session.
+// AddPin pins content to the session and returns the new pin.
+func (s *Session) AddPin(content string) Pin {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := 1
+	for _, p := range s.Pinned {
+		id = max(id, p.ID+1)
+	}
+	pin := Pin{ID: id, Content: content, CreatedAt: time.Now()}
+	s.Pinned = append(s.Pinned, pin)
+	s.UpdatedAt = time.Now()
+	return pin
+}
+
+// RemovePin unpins the pin with the given ID and reports whether it existed.
+func (s *Session) RemovePin(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, p := range s.Pinned {
+		if p.ID == id {
+			s.Pinned = append(s.Pinned[:i:i], s.Pinned[i+1:]...)
+			s.UpdatedAt = time.Now()
+			return true
+		}
+	}
+	return false
+}
+
+// Pins returns the pinned messages, oldest first.
+func (s *Session) Pins() []Pin {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Pin, len(s.Pinned))
+	copy(result, s.Pinned)
+	return result
+}
+
+// Clear removes all messages from the session. Pins are kept.
 func (s *Session) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -77,6 +126,45 @@ func (s *Session) Clear() {
 	s.UpdatedAt = time.Now()
 }
 
+// RewindLastTurn removes the last user message and everything after it,
+// such as the reply to it, and returns that message's content. ok is false
+// if the session has no user message.
+func (s *Session) RewindLastTurn() (content string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == "user" {
+			content = s.Messages[i].Content
+			s.Messages = s.Messages[:i:i]
+			s.UpdatedAt = time.Now()
+			return content, true
+		}
+	}
+	return "", false
+}
+
+// GetMeta returns the metadata value for key, or nil if unset.
+func (s *Session) GetMeta(key string) any {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Metadata[key]
+}
+
+// SetMeta sets a metadata value. A nil value removes the key.
+func (s *Session) SetMeta(key string, value any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value == nil {
+		delete(s.Metadata, key)
+		return
+	}
+	if s.Metadata == nil {
+		s.Metadata = map[string]any{}
+	}
+	s.Metadata[key] = value
+}
+
 // Manager manages session persistence.
 type Manager struct {
 	sessionsDir string
@@ -90,11 +178,14 @@ func NewManager(workspace string) *Manager {
 	// NOTE: We intentionally do NOT store session history in the workspace directory,
 	// because workspaces may be shared/synced and can contain sensitive conversational data.
 	home, _ := os.UserHomeDir()
-	sessionsDir := filepath.Join(home, ".gomikrobot", "sessions")
-	_ = os.MkdirAll(sessionsDir, 0700)
+	return NewManagerIn(filepath.Join(home, ".gomikrobot", "sessions"))
+}
 
+// NewManagerIn creates a session manager storing its files in dir.
+func NewManagerIn(dir string) *Manager {
+	_ = os.MkdirAll(dir, 0700)
 	return &Manager{
-		sessionsDir: sessionsDir,
+		sessionsDir: dir,
 		cache:       make(map[string]*Session),
 	}
 }
@@ -142,6 +233,9 @@ func (m *Manager) Save(session *Session) error {
 		"updated_at": session.UpdatedAt.Format(time.RFC3339),
 		"metadata":   session.Metadata,
 	}
+	if len(session.Pinned) > 0 {
+		meta["pinned"] = session.Pinned
+	}
 	metaLine, _ := json.Marshal(meta)
 	file.WriteString(string(metaLine) + "\n")
 
@@ -155,6 +249,65 @@ func (m *Manager) Save(session *Session) error {
 	return nil
 }
 
+// Metadata keys linking branches to the session they were created from.
+const (
+	BranchOfKey = "branch_of"
+	BranchAtKey = "branch_at"
+	BranchesKey = "branches"
+)
+
+// Branch copies the first n messages of session key, with its pins and
+// metadata, into a new session "<key>~<i>" and saves both. The branch
+// records where it came from under BranchOfKey and BranchAtKey; the
+// original lists its branches under BranchesKey.
+func (m *Manager) Branch(key string, n int) (*Session, error) {
+	src := m.GetOrCreate(key)
+
+	src.mu.RLock()
+	if n < 0 || n > len(src.Messages) {
+		src.mu.RUnlock()
+		return nil, fmt.Errorf("branch point %d is outside the %d messages of %s", n, len(src.Messages), key)
+	}
+	branch := NewSession(m.freeBranchKey(key))
+	branch.Messages = append(branch.Messages, src.Messages[:n]...)
+	branch.Pinned = append([]Pin(nil), src.Pinned...)
+	for k, v := range src.Metadata {
+		if k != BranchesKey {
+			branch.Metadata[k] = v
+		}
+	}
+	src.mu.RUnlock()
+
+	branch.Metadata[BranchOfKey] = key
+	branch.Metadata[BranchAtKey] = n
+	if err := m.Save(branch); err != nil {
+		return nil, err
+	}
+
+	var branches []any
+	if existing, ok := src.GetMeta(BranchesKey).([]any); ok {
+		branches = existing
+	}
+	src.SetMeta(BranchesKey, append(branches[:len(branches):len(branches)], branch.Key))
+	return branch, m.Save(src)
+}
+
+// freeBranchKey returns the first "<key>~<i>" not used by a session.
+func (m *Manager) freeBranchKey(key string) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s~%d", key, i)
+		if _, cached := m.cache[candidate]; cached {
+			continue
+		}
+		if _, err := os.Stat(m.sessionPath(candidate)); err == nil {
+			continue
+		}
+		return candidate
+	}
+}
+
 // Delete removes a session.
 func (m *Manager) Delete(key string) bool {
 	m.mu.Lock()
@@ -175,6 +328,8 @@ type SessionInfo struct {
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Path      string
+	// BranchOf is the session this one was branched from, if any.
+	BranchOf string
 }
 
 // List returns information about all sessions.
@@ -195,8 +350,7 @@ func (m *Manager) List() []SessionInfo {
 		}
 
 		path := filepath.Join(m.sessionsDir, entry.Name())
-		key := strings.TrimSuffix(entry.Name(), ".jsonl")
-		key = strings.ReplaceAll(key, "_", ":")
+		key := keyFromFileName(entry.Name())
 
 		// Read metadata from first line
 		info := SessionInfo{
@@ -224,6 +378,9 @@ func (m *Manager) List() []SessionInfo {
 				if updated, ok := meta["updated_at"].(string); ok {
 					info.UpdatedAt, _ = time.Parse(time.RFC3339, updated)
 				}
+				if md, ok := meta["metadata"].(map[string]any); ok {
+					info.BranchOf, _ = md[BranchOfKey].(string)
+				}
 			}
 		}
 
@@ -233,9 +390,21 @@ func (m *Manager) List() []SessionInfo {
 	return sessions
 }
 
+// Session keys can come from API clients, so path separators are escaped
+// and a key never names a file outside the sessions directory.
+var (
+	fileNameEscaper   = strings.NewReplacer("%", "%25", "/", "%2F", "\\", "%5C", ":", "_")
+	fileNameUnescaper = strings.NewReplacer("%25", "%", "%2F", "/", "%5C", "\\", "_", ":")
+)
+
 func (m *Manager) sessionPath(key string) string {
-	safeKey := strings.ReplaceAll(key, ":", "_")
-	return filepath.Join(m.sessionsDir, safeKey+".jsonl")
+	return filepath.Join(m.sessionsDir, fileNameEscaper.Replace(key)+".jsonl")
+}
+
+// keyFromFileName returns the session key of a session file name. Keys
+// containing "_" come back with ":" instead.
+func keyFromFileName(name string) string {
+	return fileNameUnescaper.Replace(strings.TrimSuffix(name, ".jsonl"))
 }
 
 func (m *Manager) load(key string) *Session {
@@ -269,6 +438,12 @@ func (m *Manager) load(key string) *Session {
 				if meta, ok := check["metadata"].(map[string]any); ok {
 					session.Metadata = meta
 				}
+				var pins struct {
+					Pinned []Pin `json:"pinned"`
+				}
+				if json.Unmarshal(raw, &pins) == nil {
+					session.Pinned = pins.Pinned
+				}
 				continue
 			}
 		}
@@ -282,3 +457,97 @@ func (m *Manager) load(key string) *Session {
 
 	return session
 }
+
+// Stats summarizes the sessions stored on disk.
+type Stats struct {
+	Count int   `json:"count"`
+	Bytes int64 `json:"bytes"`
+}
+
+// PruneResult reports the outcome of a Prune run.
+type PruneResult struct {
+	Removed        int       `json:"removed"`
+	ReclaimedBytes int64     `json:"reclaimed_bytes"`
+	Remaining      int       `json:"remaining"`
+	RemainingBytes int64     `json:"remaining_bytes"`
+	RanAt          time.Time `json:"ran_at"`
+}
+
+// Stats returns the number and total size of session files.
+func (m *Manager) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var st Stats
+	entries, err := os.ReadDir(m.sessionsDir)
+	if err != nil {
+		return st
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			st.Count++
+			st.Bytes += info.Size()
+		}
+	}
+	return st
+}
+
+// Prune deletes session files not modified within maxAge and evicts them
+// from the cache. Sessions updated in memory within maxAge are kept even if
+// their file is older.
+func (m *Manager) Prune(maxAge time.Duration) (PruneResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	res := PruneResult{RanAt: now}
+	cutoff := now.Add(-maxAge)
+
+	entries, err := os.ReadDir(m.sessionsDir)
+	if err != nil {
+		return res, fmt.Errorf("read sessions dir: %w", err)
+	}
+	// File names do not map back to keys containing "_", so match cached
+	// sessions by their path.
+	cachedByFile := make(map[string]string, len(m.cache))
+	for key := range m.cache {
+		cachedByFile[filepath.Base(m.sessionPath(key))] = key
+	}
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".jsonl") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		key, inCache := cachedByFile[entry.Name()]
+		active := inCache && m.cache[key].lastUpdate().After(cutoff)
+
+		if info.ModTime().Before(cutoff) && !active {
+			if err := os.Remove(filepath.Join(m.sessionsDir, entry.Name())); err == nil {
+				if inCache {
+					delete(m.cache, key)
+				}
+				res.Removed++
+				res.ReclaimedBytes += info.Size()
+				continue
+			}
+		}
+		res.Remaining++
+		res.RemainingBytes += info.Size()
+	}
+
+	return res, nil
+}
+
+func (s *Session) lastUpdate() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.UpdatedAt
+}
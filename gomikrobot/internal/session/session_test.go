@@ -0,0 +1,157 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManagerPrune(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewManager("")
+
+	old := m.GetOrCreate("whatsapp:old")
+	old.AddMessage("user", "hello")
+	m.Save(old)
+	fresh := m.GetOrCreate("whatsapp:fresh")
+	fresh.AddMessage("user", "hi")
+	m.Save(fresh)
+
+	// Age the old session on disk and in memory.
+	past := time.Now().Add(-48 * time.Hour)
+	old.UpdatedAt = past
+	os.Chtimes(m.sessionPath("whatsapp:old"), past, past)
+
+	if st := m.Stats(); st.Count != 2 || st.Bytes == 0 {
+		t.Fatalf("expected 2 sessions with data, got %+v", st)
+	}
+
+	res, err := m.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Prune() error: %v", err)
+	}
+	if res.Removed != 1 || res.Remaining != 1 || res.ReclaimedBytes == 0 {
+		t.Errorf("unexpected prune result: %+v", res)
+	}
+	if _, err := os.Stat(filepath.Join(m.sessionsDir, "whatsapp_old.jsonl")); !os.IsNotExist(err) {
+		t.Error("expected old session file to be removed")
+	}
+	if _, ok := m.cache["whatsapp:old"]; ok {
+		t.Error("expected old session to be evicted from cache")
+	}
+}
+
+func TestManagerPruneKeepsActiveCachedSession(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewManager("")
+
+	// Keys with "_" cannot be recovered from their file names.
+	past := time.Now().Add(-48 * time.Hour)
+	for _, key := range []string{"cli:default", "openai:my_user"} {
+		s := m.GetOrCreate(key)
+		s.AddMessage("user", "still here")
+		m.Save(s)
+		os.Chtimes(m.sessionPath(key), past, past)
+	}
+
+	res, _ := m.Prune(24 * time.Hour)
+	if res.Removed != 0 {
+		t.Errorf("expected active sessions to be kept, got %+v", res)
+	}
+
+	stale := m.GetOrCreate("openai:my_user")
+	stale.UpdatedAt = past
+	res, _ = m.Prune(24 * time.Hour)
+	if _, ok := m.cache["openai:my_user"]; res.Removed != 1 || ok {
+		t.Errorf("expected the idle session to be pruned and evicted, got %+v", res)
+	}
+}
+
+func TestPinsPersist(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewManager("")
+
+	sess := m.GetOrCreate("cli:pins")
+	first := sess.AddPin("Use metric units.")
+	second := sess.AddPin("The project is called Aurora.")
+	sess.Clear()
+	m.Save(sess)
+
+	loaded := NewManager("").load("cli:pins")
+	if pins := loaded.Pins(); len(pins) != 2 || pins[1].Content != "The project is called Aurora." {
+		t.Fatalf("pins not persisted: %+v", pins)
+	}
+	if !loaded.RemovePin(first.ID) || loaded.RemovePin(first.ID) {
+		t.Error("RemovePin should succeed once")
+	}
+	if third := loaded.AddPin("Third"); third.ID != second.ID+1 {
+		t.Errorf("new pin ID = %d, want %d", third.ID, second.ID+1)
+	}
+}
+
+func TestBranchAndRewind(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewManager("")
+
+	sess := m.GetOrCreate("dashboard:main")
+	sess.AddMessage("user", "one")
+	sess.AddMessage("assistant", "1")
+	sess.AddMessage("user", "two")
+	sess.AddMessage("assistant", "2")
+	sess.AddPin("Be brief.")
+	m.Save(sess)
+
+	branch, err := m.Branch("dashboard:main", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if branch.Key != "dashboard:main~1" || len(branch.GetHistory(10)) != 2 || len(branch.Pins()) != 1 {
+		t.Fatalf("branch = %s with %d messages", branch.Key, len(branch.GetHistory(10)))
+	}
+	if second, _ := m.Branch("dashboard:main", 0); second.Key != "dashboard:main~2" {
+		t.Errorf("second branch key = %s", second.Key)
+	}
+	if _, err := m.Branch("dashboard:main", 5); err == nil {
+		t.Error("expected an error for a branch point past the end")
+	}
+
+	loaded := NewManager("")
+	if b := loaded.GetOrCreate("dashboard:main").GetMeta(BranchesKey).([]any); len(b) != 2 {
+		t.Errorf("branches = %v", b)
+	}
+	for _, info := range loaded.List() {
+		if info.Key == "dashboard:main~1" && info.BranchOf != "dashboard:main" {
+			t.Errorf("BranchOf = %q", info.BranchOf)
+		}
+	}
+
+	if content, ok := sess.RewindLastTurn(); !ok || content != "two" || len(sess.GetHistory(10)) != 2 {
+		t.Errorf("RewindLastTurn = %q, %v; %d messages left", content, ok, len(sess.GetHistory(10)))
+	}
+	if _, ok := NewSession("cli:empty").RewindLastTurn(); ok {
+		t.Error("empty session has nothing to rewind")
+	}
+}
+
+func TestSessionKeysStayInTheSessionsDir(t *testing.T) {
+	root := t.TempDir()
+	m := NewManagerIn(filepath.Join(root, "alice", "sessions"))
+	for _, key := range []string{"openai:/../../bob/sessions/local:default", `local:..\..\bob`, "local:../x%2Fy"} {
+		path := m.sessionPath(key)
+		if filepath.Dir(path) != m.sessionsDir {
+			t.Errorf("%q maps to %s, outside the sessions dir", key, path)
+		}
+		if got := keyFromFileName(filepath.Base(path)); got != key {
+			t.Errorf("%q comes back as %q", key, got)
+		}
+		s := m.GetOrCreate(key)
+		s.AddMessage("user", "hi")
+		if err := m.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "bob")); !os.IsNotExist(err) {
+		t.Error("a session was written outside the sessions dir")
+	}
+}
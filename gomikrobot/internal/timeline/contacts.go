@@ -0,0 +1,128 @@
+package timeline
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Contact is what the bot knows about a sender.
+type Contact struct {
+	SenderID string   `json:"sender_id"` // Phone number or channel user ID
+	Name     string   `json:"name"`
+	Tags     []string `json:"tags"`
+	Notes    string   `json:"notes"`
+	// Language is the preferred reply language, e.g. "German" or "de".
+	Language string `json:"language"`
+	// Authorized senders may talk to the bot even if not in allowFrom.
+	Authorized bool      `json:"authorized"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ErrContactNotFound is returned by GetContact for unknown senders.
+var ErrContactNotFound = errors.New("contact not found")
+
+// SaveContact creates or replaces a contact.
+func (s *TimelineService) SaveContact(c *Contact) error {
+	if c.SenderID == "" {
+		return errors.New("contact needs a sender_id")
+	}
+	c.Tags = splitTags(strings.Join(c.Tags, ","))
+	now := time.Now()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+	c.UpdatedAt = now
+	_, err := s.db.Exec(`
+	INSERT INTO contacts (sender_id, name, tags, notes, language, authorized, created_at, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(sender_id) DO UPDATE SET name = excluded.name, tags = excluded.tags, notes = excluded.notes,
+		language = excluded.language, authorized = excluded.authorized, updated_at = excluded.updated_at
+	`, c.SenderID, c.Name, strings.Join(c.Tags, ","), c.Notes, c.Language, c.Authorized, c.CreatedAt, c.UpdatedAt)
+	return err
+}
+
+// GetContact returns the contact for senderID.
+func (s *TimelineService) GetContact(senderID string) (*Contact, error) {
+	contacts, err := s.queryContacts("WHERE sender_id = ?", senderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(contacts) == 0 {
+		return nil, ErrContactNotFound
+	}
+	return &contacts[0], nil
+}
+
+// ListContacts returns all contacts, optionally only those with tag.
+func (s *TimelineService) ListContacts(tag string) ([]Contact, error) {
+	if tag != "" {
+		return s.queryContacts("WHERE ',' || tags || ',' LIKE ? ORDER BY name, sender_id", "%,"+tag+",%")
+	}
+	return s.queryContacts("ORDER BY name, sender_id")
+}
+
+// DeleteContact removes a contact. It reports whether one existed.
+func (s *TimelineService) DeleteContact(senderID string) (bool, error) {
+	res, err := s.db.Exec("DELETE FROM contacts WHERE sender_id = ?", senderID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Profile renders the contact for the system prompt.
+func (c *Contact) Profile() string {
+	var sb strings.Builder
+	if c.Name != "" {
+		fmt.Fprintf(&sb, "Name: %s\n", c.Name)
+	}
+	fmt.Fprintf(&sb, "Sender ID: %s\n", c.SenderID)
+	if len(c.Tags) > 0 {
+		fmt.Fprintf(&sb, "Tags: %s\n", strings.Join(c.Tags, ", "))
+	}
+	if c.Language != "" {
+		fmt.Fprintf(&sb, "Preferred language: %s\n", c.Language)
+	}
+	if c.Notes != "" {
+		fmt.Fprintf(&sb, "Notes:\n%s\n", c.Notes)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func (s *TimelineService) queryContacts(clause string, args ...any) ([]Contact, error) {
+	rows, err := s.db.Query(`
+	SELECT sender_id, COALESCE(name, ''), COALESCE(tags, ''), COALESCE(notes, ''), COALESCE(language, ''),
+		COALESCE(authorized, 0), created_at, updated_at
+	FROM contacts `+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	contacts := []Contact{}
+	for rows.Next() {
+		var c Contact
+		var tags string
+		if err := rows.Scan(&c.SenderID, &c.Name, &tags, &c.Notes, &c.Language, &c.Authorized, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		c.Tags = splitTags(tags)
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
+
+// splitTags parses a comma-separated tag list, dropping empty entries.
+func splitTags(s string) []string {
+	tags := []string{}
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,146 @@
+package timeline
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Draft states.
+const (
+	DraftPending  = "pending"
+	DraftSent     = "sent"
+	DraftRejected = "rejected"
+)
+
+// reviewSettingPrefix marks sessions ("<channel>:<chatID>") whose replies
+// are held as drafts until an admin approves them.
+const reviewSettingPrefix = "review:"
+
+// ErrDraftNotFound is returned for unknown drafts or drafts already decided.
+var ErrDraftNotFound = errors.New("draft not found")
+
+// Draft is a reply held back for review.
+type Draft struct {
+	ID        int64      `json:"id"`
+	Channel   string     `json:"channel"`
+	ChatID    string     `json:"chat_id"`
+	Content   string     `json:"content"`
+	Trace     string     `json:"trace,omitempty"`
+	Status    string     `json:"status"`
+	CreatedAt time.Time  `json:"created_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// ReviewRequired reports whether replies to session are held as drafts.
+func (s *TimelineService) ReviewRequired(session string) bool {
+	val, err := s.GetSetting(reviewSettingPrefix + session)
+	return err == nil && val == "true"
+}
+
+// SetReview turns review before send on or off for session.
+func (s *TimelineService) SetReview(session string, on bool) error {
+	if channel, chatID, ok := strings.Cut(session, ":"); !ok || channel == "" || chatID == "" {
+		return errors.New("session must look like <channel>:<chat>")
+	}
+	val := ""
+	if on {
+		val = "true"
+	}
+	return s.SetSetting(reviewSettingPrefix+session, val)
+}
+
+// ReviewSessions returns the sessions in review mode, sorted.
+func (s *TimelineService) ReviewSessions() ([]string, error) {
+	settings, err := s.SettingsWithPrefix(reviewSettingPrefix)
+	if err != nil {
+		return nil, err
+	}
+	sessions := []string{}
+	for session, val := range settings {
+		if val == "true" {
+			sessions = append(sessions, session)
+		}
+	}
+	slices.Sort(sessions)
+	return sessions, nil
+}
+
+// AddDraft stores a pending draft and sets its ID.
+func (s *TimelineService) AddDraft(d *Draft) error {
+	d.Status = DraftPending
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now()
+	}
+	var err error
+	d.ID, err = s.db.insert(`
+	INSERT INTO drafts (channel, chat_id, content, trace, status, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`, d.Channel, d.ChatID, d.Content, d.Trace, d.Status, d.CreatedAt.UTC())
+	return err
+}
+
+// Drafts returns drafts with status ("" for all), newest first.
+func (s *TimelineService) Drafts(status string, limit int) ([]Draft, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	clause, args := "", []any{}
+	if status != "" {
+		clause, args = "WHERE status = ?", append(args, status)
+	}
+	return s.queryDrafts(clause+" ORDER BY id DESC LIMIT ?", append(args, limit)...)
+}
+
+// GetDraft returns a draft by ID.
+func (s *TimelineService) GetDraft(id int64) (*Draft, error) {
+	drafts, err := s.queryDrafts("WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(drafts) == 0 {
+		return nil, ErrDraftNotFound
+	}
+	return &drafts[0], nil
+}
+
+// DecideDraft moves a pending draft to status (DraftSent or DraftRejected)
+// with its final content. It returns ErrDraftNotFound if the draft was
+// already decided, so a draft is never sent twice.
+func (s *TimelineService) DecideDraft(id int64, status, content string) error {
+	res, err := s.db.Exec("UPDATE drafts SET status = ?, content = ?, decided_at = ? WHERE id = ? AND status = ?",
+		status, content, time.Now().UTC(), id, DraftPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrDraftNotFound
+	}
+	return nil
+}
+
+func (s *TimelineService) queryDrafts(clause string, args ...any) ([]Draft, error) {
+	rows, err := s.db.Query(`
+	SELECT id, channel, chat_id, content, trace, status, created_at, decided_at
+	FROM drafts `+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drafts []Draft
+	for rows.Next() {
+		var d Draft
+		var decided sql.NullTime
+		if err := rows.Scan(&d.ID, &d.Channel, &d.ChatID, &d.Content, &d.Trace, &d.Status, &d.CreatedAt, &decided); err != nil {
+			return nil, err
+		}
+		if decided.Valid {
+			d.DecidedAt = &decided.Time
+		}
+		drafts = append(drafts, d)
+	}
+	return drafts, rows.Err()
+}
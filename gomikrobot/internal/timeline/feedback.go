@@ -0,0 +1,137 @@
+package timeline
+
+import (
+	"strings"
+	"time"
+)
+
+// Reaction is an emoji reaction to a timeline event, usually a bot reply.
+type Reaction struct {
+	EventID   string    `json:"event_id"` // The message reacted to
+	Channel   string    `json:"channel"`
+	SenderID  string    `json:"sender_id"`
+	Emoji     string    `json:"emoji"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Feedback sentiments.
+const (
+	FeedbackPositive = "positive"
+	FeedbackNegative = "negative"
+	FeedbackNeutral  = "neutral"
+)
+
+var (
+	positiveEmoji = []string{"👍", "❤", "🙏", "😍", "👏", "🎉", "✅", "💯", "🔥", "😂"}
+	negativeEmoji = []string{"👎", "😡", "😠", "😢", "❌", "🤦", "😕", "🙄"}
+)
+
+// Sentiment classifies a reaction emoji. Skin tones and variation
+// selectors are ignored.
+func Sentiment(emoji string) string {
+	base := strings.Map(func(r rune) rune {
+		if r == 0xFE0F || (r >= 0x1F3FB && r <= 0x1F3FF) {
+			return -1
+		}
+		return r
+	}, emoji)
+	for _, e := range positiveEmoji {
+		if base == e {
+			return FeedbackPositive
+		}
+	}
+	for _, e := range negativeEmoji {
+		if base == e {
+			return FeedbackNegative
+		}
+	}
+	return FeedbackNeutral
+}
+
+// AddReaction records a reaction. A sender has one reaction per event, so
+// a new one replaces the old; an empty emoji removes it.
+func (s *TimelineService) AddReaction(r *Reaction) error {
+	if r.Emoji == "" {
+		_, err := s.db.Exec(`DELETE FROM reactions WHERE event_id = ? AND sender_id = ?`, r.EventID, r.SenderID)
+		return err
+	}
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+	_, err := s.db.Exec(`
+	INSERT INTO reactions (event_id, channel, sender_id, emoji, timestamp) VALUES (?, ?, ?, ?, ?)
+	ON CONFLICT(event_id, sender_id) DO UPDATE SET emoji = excluded.emoji, timestamp = excluded.timestamp
+	`, r.EventID, r.Channel, r.SenderID, r.Emoji, r.Timestamp)
+	return err
+}
+
+// RatedEvent is a reaction together with the event it rates.
+type RatedEvent struct {
+	Reaction
+	Sentiment string `json:"sentiment"`
+	// Content is the rated message, empty if it is not in the timeline.
+	Content   string `json:"content"`
+	EventType string `json:"event_type"`
+}
+
+// FeedbackReport summarizes reactions in a time range.
+type FeedbackReport struct {
+	Start    time.Time      `json:"start"`
+	End      time.Time      `json:"end"`
+	Total    int            `json:"total"`
+	Positive int            `json:"positive"`
+	Negative int            `json:"negative"`
+	Neutral  int            `json:"neutral"`
+	PerEmoji map[string]int `json:"per_emoji"`
+	// Score is (positive - negative) / (positive + negative), or 0.
+	Score  float64      `json:"score"`
+	Recent []RatedEvent `json:"recent"`
+}
+
+// Feedback reports reactions with start <= timestamp < end, listing up to
+// limit of the most recent ones with the rated messages.
+func (s *TimelineService) Feedback(start, end time.Time, limit int) (*FeedbackReport, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(`
+	SELECT r.event_id, COALESCE(r.channel, ''), r.sender_id, r.emoji, r.timestamp,
+		COALESCE(t.content_text, ''), COALESCE(t.event_type, '')
+	FROM reactions r LEFT JOIN timeline t ON t.event_id = r.event_id
+	WHERE r.timestamp >= ? AND r.timestamp < ?
+	ORDER BY r.timestamp DESC
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rep := &FeedbackReport{Start: start, End: end, PerEmoji: map[string]int{}, Recent: []RatedEvent{}}
+	for rows.Next() {
+		var ev RatedEvent
+		if err := rows.Scan(&ev.EventID, &ev.Channel, &ev.SenderID, &ev.Emoji, &ev.Timestamp, &ev.Content, &ev.EventType); err != nil {
+			return nil, err
+		}
+		ev.Sentiment = Sentiment(ev.Emoji)
+		rep.Total++
+		rep.PerEmoji[ev.Emoji]++
+		switch ev.Sentiment {
+		case FeedbackPositive:
+			rep.Positive++
+		case FeedbackNegative:
+			rep.Negative++
+		default:
+			rep.Neutral++
+		}
+		if len(rep.Recent) < limit {
+			rep.Recent = append(rep.Recent, ev)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if rated := rep.Positive + rep.Negative; rated > 0 {
+		rep.Score = float64(rep.Positive-rep.Negative) / float64(rated)
+	}
+	return rep, nil
+}
@@ -0,0 +1,106 @@
+package timeline
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Job states.
+const (
+	JobRunning = "running"
+	JobExited  = "exited"
+	JobKilled  = "killed"
+	JobLost    = "lost" // The gateway restarted while the job ran
+)
+
+// Job is a shell command started with exec's run_in_background.
+type Job struct {
+	ID         string     `json:"id"`
+	SessionKey string     `json:"session_key"`
+	Command    string     `json:"command"`
+	WorkDir    string     `json:"work_dir,omitempty"`
+	PID        int        `json:"pid"`
+	Status     string     `json:"status"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	OutputTail string     `json:"output_tail,omitempty"` // Last few KB of output
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ErrJobNotFound is returned by GetJob for unknown ids.
+var ErrJobNotFound = errors.New("job not found")
+
+// SaveJob inserts or updates a job.
+func (s *TimelineService) SaveJob(j *Job) error {
+	_, err := s.db.Exec(`
+	INSERT INTO jobs (id, session_key, command, work_dir, pid, status, exit_code, output_tail, started_at, finished_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(id) DO UPDATE SET status = excluded.status, exit_code = excluded.exit_code,
+		output_tail = excluded.output_tail, finished_at = excluded.finished_at
+	`, j.ID, j.SessionKey, j.Command, j.WorkDir, j.PID, j.Status, j.ExitCode, j.OutputTail, j.StartedAt, j.FinishedAt)
+	return err
+}
+
+// GetJob returns the job with id.
+func (s *TimelineService) GetJob(id string) (*Job, error) {
+	jobs, err := s.queryJobs("WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, ErrJobNotFound
+	}
+	return &jobs[0], nil
+}
+
+// ListJobs returns the newest jobs, optionally filtered by status.
+func (s *TimelineService) ListJobs(status string, limit int) ([]Job, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if status != "" {
+		return s.queryJobs("WHERE status = ? ORDER BY started_at DESC LIMIT ?", status, limit)
+	}
+	return s.queryJobs("ORDER BY started_at DESC LIMIT ?", limit)
+}
+
+// MarkLostJobs marks jobs still recorded as running as lost, e.g. after a
+// restart orphaned them. It returns the number of jobs changed.
+func (s *TimelineService) MarkLostJobs() (int, error) {
+	res, err := s.db.Exec(`UPDATE jobs SET status = ?, finished_at = ? WHERE status = ?`, JobLost, time.Now(), JobRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *TimelineService) queryJobs(clause string, args ...any) ([]Job, error) {
+	rows, err := s.db.Query(`
+	SELECT id, session_key, command, COALESCE(work_dir, ''), COALESCE(pid, 0), status, exit_code, COALESCE(output_tail, ''), started_at, finished_at
+	FROM jobs `+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var j Job
+		var code sql.NullInt64
+		var finished sql.NullTime
+		if err := rows.Scan(&j.ID, &j.SessionKey, &j.Command, &j.WorkDir, &j.PID, &j.Status, &code, &j.OutputTail, &j.StartedAt, &finished); err != nil {
+			return nil, err
+		}
+		if code.Valid {
+			c := int(code.Int64)
+			j.ExitCode = &c
+		}
+		if finished.Valid {
+			j.FinishedAt = &finished.Time
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
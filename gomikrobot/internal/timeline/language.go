@@ -0,0 +1,48 @@
+package timeline
+
+import "errors"
+
+// Language settings per session key: an explicit choice made with
+// set_language, and the language last detected in the user's messages.
+const (
+	languagePrefix         = "lang:"
+	detectedLanguagePrefix = "lang_detected:"
+)
+
+// SetSessionLanguage pins the reply language of a session. An empty lang
+// goes back to the contact's language or auto-detection.
+func (s *TimelineService) SetSessionLanguage(sessionKey, lang string) error {
+	return s.SetSetting(languagePrefix+sessionKey, lang)
+}
+
+// SetDetectedLanguage records the language detected in a session's latest
+// messages. It only writes when the language changed.
+func (s *TimelineService) SetDetectedLanguage(sessionKey, lang string) error {
+	if lang == "" {
+		return nil
+	}
+	if old, _ := s.GetSetting(detectedLanguagePrefix + sessionKey); old == lang {
+		return nil
+	}
+	return s.SetSetting(detectedLanguagePrefix+sessionKey, lang)
+}
+
+// ReplyLanguage returns the language to answer a session in: the session's
+// explicit setting, else the sender's contact language, else the detected
+// language. It returns "" if none is known.
+func (s *TimelineService) ReplyLanguage(sessionKey, senderID string) string {
+	if lang, _ := s.GetSetting(languagePrefix + sessionKey); lang != "" {
+		return lang
+	}
+	if senderID != "" {
+		c, err := s.GetContact(senderID)
+		if err == nil && c.Language != "" {
+			return c.Language
+		}
+		if err != nil && !errors.Is(err, ErrContactNotFound) {
+			return ""
+		}
+	}
+	lang, _ := s.GetSetting(detectedLanguagePrefix + sessionKey)
+	return lang
+}
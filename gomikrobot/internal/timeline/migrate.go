@@ -0,0 +1,314 @@
+package timeline
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Schema changes are SQL files in migrations/<driver>/, named
+// NNNN_description.up.sql with an optional NNNN_description.down.sql that
+// undoes them. Applied versions are recorded in the schema_version table.
+// Add a new file with the next number for every change, for each driver;
+// never edit one that has been released.
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+var migrationName = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	// Down undoes Up; empty if the migration cannot be rolled back.
+	Down string
+}
+
+// Migrations returns the schema migrations of driver ("sqlite" or
+// "postgres") in version order.
+func Migrations(driver string) ([]Migration, error) {
+	dir := "migrations/" + driver
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %q", driver)
+	}
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		m := migrationName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration %s: name must be NNNN_name.up.sql or NNNN_name.down.sql", e.Name())
+		}
+		data, err := migrationFiles.ReadFile(dir + "/" + e.Name())
+		if err != nil {
+			return nil, err
+		}
+		v, _ := strconv.Atoi(m[1])
+		mig := byVersion[v]
+		if mig == nil {
+			mig = &Migration{Version: v, Name: m[2]}
+			byVersion[v] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", v, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(data)
+		} else {
+			mig.Down = string(data)
+		}
+	}
+
+	list := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	for i, m := range list {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %d is missing", i+1)
+		}
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up.sql", m.Version)
+		}
+	}
+	return list, nil
+}
+
+// Migrator applies and rolls back schema migrations.
+type Migrator struct {
+	db         *db
+	migrations []Migration
+}
+
+// OpenMigrator opens the timeline in st without upgrading it.
+func OpenMigrator(st Storage) (*Migrator, error) {
+	db, err := openDB(st)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open timeline db: %w", err)
+	}
+	m, err := newMigrator(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func newMigrator(db *db) (*Migrator, error) {
+	list, err := Migrations(db.dialect.name())
+	if err != nil {
+		return nil, err
+	}
+	tx, err := lockedTx(db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT,
+		applied_at TIMESTAMP
+	)`)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: list}, nil
+}
+
+// Close closes the database.
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Version returns the schema version of the database, 0 if no migration
+// has been applied.
+func (m *Migrator) Version() (int, error) {
+	var v sql.NullInt64
+	err := m.db.QueryRow("SELECT MAX(version) FROM schema_version").Scan(&v)
+	return int(v.Int64), err
+}
+
+// Latest returns the newest schema version this build knows.
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Pending returns the migrations not yet applied.
+func (m *Migrator) Pending() ([]Migration, error) {
+	v, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if v >= len(m.migrations) {
+		return nil, nil
+	}
+	return m.migrations[v:], nil
+}
+
+// Up applies all pending migrations, each in its own transaction, and
+// returns them. With dryRun, it only returns what it would apply. On
+// Postgres, processes starting together wait for each other's migrations.
+func (m *Migrator) Up(dryRun bool) ([]Migration, error) {
+	v, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if v > len(m.migrations) {
+		return nil, fmt.Errorf("the database has schema version %d, newer than this build's %d; upgrade gomikrobot or roll back with the newer version", v, len(m.migrations))
+	}
+	pending := m.migrations[v:]
+	if dryRun || len(pending) == 0 {
+		return pending, nil
+	}
+
+	legacy := false
+	if v == 0 && m.db.dialect.name() == "sqlite" {
+		// Databases created before migrations have the tables but no
+		// schema_version rows.
+		var n int
+		if err := m.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'timeline'").Scan(&n); err != nil {
+			return nil, err
+		}
+		legacy = n > 0
+	}
+	for i, mig := range pending {
+		var after func(*tx) error
+		if legacy && mig.Version == 1 {
+			after = adoptLegacy
+		}
+		if err := m.apply(mig, mig.Up, true, after); err != nil {
+			return pending[:i], fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Name, err)
+		}
+	}
+	return pending, nil
+}
+
+// Down rolls back the migrations after version target, newest first, and
+// returns them. With dryRun, it only returns what it would roll back.
+// Migrations without a down.sql, such as the initial schema, stop it.
+func (m *Migrator) Down(target int, dryRun bool) ([]Migration, error) {
+	v, err := m.Version()
+	if err != nil {
+		return nil, err
+	}
+	if v > len(m.migrations) {
+		return nil, fmt.Errorf("the database has schema version %d, newer than this build's %d; roll back with the newer version", v, len(m.migrations))
+	}
+	if target < 0 || target >= v {
+		return nil, fmt.Errorf("target version %d must be below the current version %d", target, v)
+	}
+	var steps []Migration
+	for i := v; i > target; i-- {
+		mig := m.migrations[i-1]
+		if mig.Down == "" {
+			return nil, fmt.Errorf("migration %d (%s) cannot be rolled back", mig.Version, mig.Name)
+		}
+		steps = append(steps, mig)
+	}
+	if dryRun {
+		return steps, nil
+	}
+	for i, mig := range steps {
+		if err := m.apply(mig, mig.Down, false, nil); err != nil {
+			return steps[:i], fmt.Errorf("rolling back migration %d (%s): %w", mig.Version, mig.Name, err)
+		}
+	}
+	return steps, nil
+}
+
+// lockedTx begins a transaction that holds the migration lock of db.
+func lockedTx(db *db) (*tx, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if q := db.dialect.lockMigrations(); q != "" {
+		if _, err := tx.Exec(q); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("locking the schema: %w", err)
+		}
+	}
+	return tx, nil
+}
+
+// apply runs one migration's SQL, then after if set, and records it in
+// schema_version, all in one transaction. A migration that another process
+// applied or rolled back while this one waited for the lock is skipped.
+func (m *Migrator) apply(mig Migration, stmts string, up bool, after func(*tx) error) error {
+	tx, err := lockedTx(m.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var n int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM schema_version WHERE version = ?", mig.Version).Scan(&n); err != nil {
+		return err
+	}
+	if applied := n > 0; applied == up {
+		return nil
+	}
+	if _, err := tx.Exec(stmts); err != nil {
+		return err
+	}
+	if after != nil {
+		if err := after(tx); err != nil {
+			return fmt.Errorf("upgrading the pre-migration schema: %w", err)
+		}
+	}
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)", mig.Version, mig.Name, time.Now())
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_version WHERE version = ?", mig.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// adoptLegacy brings SQLite databases from before migrations to the initial
+// schema: columns added later and the search index of older events.
+func adoptLegacy(db *tx) error {
+	if err := ensureColumn(db, "usage", "duration_ms", "INTEGER DEFAULT 0"); err != nil {
+		return err
+	}
+	if err := ensureColumn(db, "usage", "refused", "BOOLEAN DEFAULT 0"); err != nil {
+		return err
+	}
+	// Index events written before the search table existed. The table's
+	// row count cannot tell: it reports the rows of timeline.
+	_, err := db.Exec("INSERT INTO timeline_fts(timeline_fts) VALUES ('rebuild')")
+	return err
+}
+
+// ensureColumn adds a column to databases created before it existed.
+func ensureColumn(db *tx, table, column, decl string) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl))
+	return err
+}
@@ -0,0 +1,144 @@
+-- The Postgres schema matching SQLite's initial migration. Search uses an
+-- expression index instead of an FTS table.
+CREATE TABLE IF NOT EXISTS timeline (
+	id BIGSERIAL PRIMARY KEY,
+	event_id TEXT UNIQUE,
+	timestamp TIMESTAMPTZ,
+	sender_id TEXT,
+	sender_name TEXT,
+	event_type TEXT,
+	content_text TEXT,
+	media_path TEXT,
+	vector_id TEXT,
+	classification TEXT,
+	authorized BOOLEAN DEFAULT TRUE
+);
+
+CREATE INDEX IF NOT EXISTS idx_timeline_timestamp ON timeline(timestamp);
+CREATE INDEX IF NOT EXISTS idx_timeline_sender ON timeline(sender_id);
+CREATE INDEX IF NOT EXISTS idx_timeline_authorized ON timeline(authorized);
+CREATE INDEX IF NOT EXISTS idx_timeline_search ON timeline
+	USING GIN (to_tsvector('simple', coalesce(content_text, '') || ' ' || coalesce(sender_name, '')));
+
+CREATE TABLE IF NOT EXISTS usage (
+	id BIGSERIAL PRIMARY KEY,
+	timestamp TIMESTAMPTZ,
+	session_key TEXT,
+	model TEXT,
+	prompt_tokens INTEGER DEFAULT 0,
+	completion_tokens INTEGER DEFAULT 0,
+	tool_calls INTEGER DEFAULT 0,
+	tool_errors INTEGER DEFAULT 0,
+	failed BOOLEAN DEFAULT FALSE,
+	duration_ms BIGINT DEFAULT 0,
+	refused BOOLEAN DEFAULT FALSE
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_timestamp ON usage(timestamp);
+
+CREATE TABLE IF NOT EXISTS settings (
+	key TEXT PRIMARY KEY,
+	value TEXT,
+	updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	id TEXT PRIMARY KEY,
+	session_key TEXT,
+	title TEXT,
+	prompt TEXT,
+	status TEXT,
+	result TEXT,
+	error TEXT,
+	created_at TIMESTAMPTZ,
+	started_at TIMESTAMPTZ,
+	finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	session_key TEXT,
+	command TEXT,
+	work_dir TEXT,
+	pid INTEGER,
+	status TEXT,
+	exit_code INTEGER,
+	output_tail TEXT,
+	started_at TIMESTAMPTZ,
+	finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+
+CREATE TABLE IF NOT EXISTS reminders (
+	id BIGSERIAL PRIMARY KEY,
+	session_key TEXT,
+	channel TEXT,
+	chat_id TEXT,
+	text TEXT,
+	due_at TIMESTAMPTZ,
+	status TEXT,
+	created_at TIMESTAMPTZ,
+	sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_due ON reminders(status, due_at);
+
+CREATE TABLE IF NOT EXISTS reactions (
+	id BIGSERIAL PRIMARY KEY,
+	event_id TEXT,
+	channel TEXT,
+	sender_id TEXT,
+	emoji TEXT,
+	timestamp TIMESTAMPTZ,
+	UNIQUE(event_id, sender_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_timestamp ON reactions(timestamp);
+
+CREATE TABLE IF NOT EXISTS contacts (
+	sender_id TEXT PRIMARY KEY,
+	name TEXT,
+	tags TEXT,
+	notes TEXT,
+	language TEXT,
+	authorized BOOLEAN DEFAULT FALSE,
+	created_at TIMESTAMPTZ,
+	updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS held_messages (
+	id BIGSERIAL PRIMARY KEY,
+	channel TEXT,
+	chat_id TEXT,
+	content TEXT,
+	trace TEXT,
+	release_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_held_messages_release ON held_messages(release_at);
+
+CREATE TABLE IF NOT EXISTS drafts (
+	id BIGSERIAL PRIMARY KEY,
+	channel TEXT,
+	chat_id TEXT,
+	content TEXT,
+	trace TEXT,
+	status TEXT,
+	created_at TIMESTAMPTZ,
+	decided_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_drafts_status ON drafts(status);
+
+CREATE TABLE IF NOT EXISTS response_cache (
+	key TEXT PRIMARY KEY,
+	response TEXT,
+	created_at TIMESTAMPTZ,
+	expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_response_cache_expires ON response_cache(expires_at);
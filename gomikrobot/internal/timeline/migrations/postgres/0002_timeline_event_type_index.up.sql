@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_timeline_event_type ON timeline(event_type);
@@ -0,0 +1,162 @@
+-- The schema before versioned migrations. Every statement is idempotent so
+-- databases created by earlier releases can adopt it.
+CREATE TABLE IF NOT EXISTS timeline (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id TEXT UNIQUE,
+	timestamp DATETIME,
+	sender_id TEXT,
+	sender_name TEXT,
+	event_type TEXT,
+	content_text TEXT,
+	media_path TEXT,
+	vector_id TEXT,
+	classification TEXT,
+	authorized BOOLEAN DEFAULT 1
+);
+
+CREATE INDEX IF NOT EXISTS idx_timeline_timestamp ON timeline(timestamp);
+CREATE INDEX IF NOT EXISTS idx_timeline_sender ON timeline(sender_id);
+CREATE INDEX IF NOT EXISTS idx_timeline_authorized ON timeline(authorized);
+
+CREATE TABLE IF NOT EXISTS usage (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp DATETIME,
+	session_key TEXT,
+	model TEXT,
+	prompt_tokens INTEGER DEFAULT 0,
+	completion_tokens INTEGER DEFAULT 0,
+	tool_calls INTEGER DEFAULT 0,
+	tool_errors INTEGER DEFAULT 0,
+	failed BOOLEAN DEFAULT 0,
+	duration_ms INTEGER DEFAULT 0,
+	refused BOOLEAN DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_timestamp ON usage(timestamp);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS timeline_fts USING fts5(
+	content_text,
+	sender_name,
+	content='timeline',
+	content_rowid='id'
+);
+
+CREATE TRIGGER IF NOT EXISTS timeline_fts_insert AFTER INSERT ON timeline BEGIN
+	INSERT INTO timeline_fts(rowid, content_text, sender_name) VALUES (new.id, new.content_text, new.sender_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS timeline_fts_delete AFTER DELETE ON timeline BEGIN
+	INSERT INTO timeline_fts(timeline_fts, rowid, content_text, sender_name) VALUES ('delete', old.id, old.content_text, old.sender_name);
+END;
+
+CREATE TRIGGER IF NOT EXISTS timeline_fts_update AFTER UPDATE OF content_text, sender_name ON timeline BEGIN
+	INSERT INTO timeline_fts(timeline_fts, rowid, content_text, sender_name) VALUES ('delete', old.id, old.content_text, old.sender_name);
+	INSERT INTO timeline_fts(rowid, content_text, sender_name) VALUES (new.id, new.content_text, new.sender_name);
+END;
+
+CREATE TABLE IF NOT EXISTS settings (
+	key TEXT PRIMARY KEY,
+	value TEXT,
+	updated_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS tasks (
+	id TEXT PRIMARY KEY,
+	session_key TEXT,
+	title TEXT,
+	prompt TEXT,
+	status TEXT,
+	result TEXT,
+	error TEXT,
+	created_at DATETIME,
+	started_at DATETIME,
+	finished_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasks_status ON tasks(status);
+
+CREATE TABLE IF NOT EXISTS jobs (
+	id TEXT PRIMARY KEY,
+	session_key TEXT,
+	command TEXT,
+	work_dir TEXT,
+	pid INTEGER,
+	status TEXT,
+	exit_code INTEGER,
+	output_tail TEXT,
+	started_at DATETIME,
+	finished_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs(status);
+
+CREATE TABLE IF NOT EXISTS reminders (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	session_key TEXT,
+	channel TEXT,
+	chat_id TEXT,
+	text TEXT,
+	due_at DATETIME,
+	status TEXT,
+	created_at DATETIME,
+	sent_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_due ON reminders(status, due_at);
+
+CREATE TABLE IF NOT EXISTS reactions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id TEXT,
+	channel TEXT,
+	sender_id TEXT,
+	emoji TEXT,
+	timestamp DATETIME,
+	UNIQUE(event_id, sender_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reactions_timestamp ON reactions(timestamp);
+
+CREATE TABLE IF NOT EXISTS contacts (
+	sender_id TEXT PRIMARY KEY,
+	name TEXT,
+	tags TEXT,
+	notes TEXT,
+	language TEXT,
+	authorized BOOLEAN DEFAULT 0,
+	created_at DATETIME,
+	updated_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS held_messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	channel TEXT,
+	chat_id TEXT,
+	content TEXT,
+	trace TEXT,
+	release_at DATETIME,
+	created_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_held_messages_release ON held_messages(release_at);
+
+CREATE TABLE IF NOT EXISTS drafts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	channel TEXT,
+	chat_id TEXT,
+	content TEXT,
+	trace TEXT,
+	status TEXT,
+	created_at DATETIME,
+	decided_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_drafts_status ON drafts(status);
+
+CREATE TABLE IF NOT EXISTS response_cache (
+	key TEXT PRIMARY KEY,
+	response TEXT,
+	created_at DATETIME,
+	expires_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_response_cache_expires ON response_cache(expires_at);
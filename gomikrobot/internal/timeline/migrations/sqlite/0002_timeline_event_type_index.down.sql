@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_timeline_event_type;
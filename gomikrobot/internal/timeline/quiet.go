@@ -0,0 +1,245 @@
+package timeline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Setting keys for silent mode and quiet hours. The per-contact variants
+// append "<channel>:<chatID>" and override the global value.
+const (
+	SilentModeSetting   = "silent_mode"
+	QuietHoursSetting   = "quiet_hours"
+	silentSettingPrefix = SilentModeSetting + ":"
+	quietSettingPrefix  = QuietHoursSetting + ":"
+)
+
+// SilentFor reports whether silent mode applies to chatID on channel: the
+// contact's own setting if it has one, the global flag otherwise.
+func (s *TimelineService) SilentFor(channel, chatID string) bool {
+	if val, err := s.GetSetting(silentSettingPrefix + channel + ":" + chatID); err == nil && val != "" {
+		return val == "true"
+	}
+	return s.IsSilentMode()
+}
+
+// SetSilentFor stores silent mode for one contact. nil removes the
+// override so the global flag applies again.
+func (s *TimelineService) SetSilentFor(channel, chatID string, silent *bool) error {
+	val := ""
+	if silent != nil {
+		val = strconv.FormatBool(*silent)
+	}
+	return s.SetSetting(silentSettingPrefix+channel+":"+chatID, val)
+}
+
+// QuietHours is a daily window, in the gateway's local time, during which
+// outbound messages are held back. The window may wrap past midnight.
+type QuietHours struct {
+	// Start and End are minutes after midnight; End is exclusive.
+	Start, End int
+}
+
+// ParseQuietHours parses "22:00-07:00".
+func ParseQuietHours(s string) (QuietHours, error) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "–", "-")
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return QuietHours{}, fmt.Errorf("quiet hours %q: use HH:MM-HH:MM", s)
+	}
+	var q QuietHours
+	var err error
+	if q.Start, err = parseClock(from); err != nil {
+		return QuietHours{}, err
+	}
+	if q.End, err = parseClock(to); err != nil {
+		return QuietHours{}, err
+	}
+	if q.Start == q.End {
+		return QuietHours{}, fmt.Errorf("quiet hours %q: start and end are equal", s)
+	}
+	return q, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q: use HH:MM", strings.TrimSpace(s))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (q QuietHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", q.Start/60, q.Start%60, q.End/60, q.End%60)
+}
+
+// Until returns when the window containing t ends, or the zero time if t
+// is outside the window.
+func (q QuietHours) Until(t time.Time) time.Time {
+	m := t.Hour()*60 + t.Minute()
+	end := time.Date(t.Year(), t.Month(), t.Day(), q.End/60, q.End%60, 0, 0, t.Location())
+	switch {
+	case q.Start < q.End && m >= q.Start && m < q.End:
+		return end
+	case q.Start > q.End && m >= q.Start:
+		return end.AddDate(0, 0, 1)
+	case q.Start > q.End && m < q.End:
+		return end
+	}
+	return time.Time{}
+}
+
+// QuietHoursFor returns the quiet hours of chatID on channel: the
+// contact's own setting if it has one ("off" disables them), the global
+// setting otherwise. ok is false when none apply.
+func (s *TimelineService) QuietHoursFor(channel, chatID string) (q QuietHours, ok bool) {
+	val, err := s.GetSetting(quietSettingPrefix + channel + ":" + chatID)
+	if err != nil || val == "" {
+		val, _ = s.GetSetting(QuietHoursSetting)
+	}
+	if val == "" || val == "off" {
+		return QuietHours{}, false
+	}
+	q, err = ParseQuietHours(val)
+	return q, err == nil
+}
+
+// SetQuietHoursFor stores quiet hours for one contact: a window, "off", or
+// "" to fall back to the global setting.
+func (s *TimelineService) SetQuietHoursFor(channel, chatID, value string) error {
+	if value != "" && value != "off" {
+		q, err := ParseQuietHours(value)
+		if err != nil {
+			return err
+		}
+		value = q.String()
+	}
+	return s.SetSetting(quietSettingPrefix+channel+":"+chatID, value)
+}
+
+// SetQuiet changes silent mode and quiet hours for session
+// ("<channel>:<chatID>"), or globally when session is empty. silent is "on",
+// "off" or "default" (contacts only: follow the global flag); quietHours is
+// a window like "22:00-07:00", "off" or "default" (contacts only). Empty
+// arguments leave the setting unchanged.
+func (s *TimelineService) SetQuiet(session, silent, quietHours string) error {
+	silent = strings.ToLower(strings.TrimSpace(silent))
+	quietHours = strings.ToLower(strings.TrimSpace(quietHours))
+	var channel, chatID string
+	if session != "" {
+		var ok bool
+		if channel, chatID, ok = strings.Cut(session, ":"); !ok || channel == "" || chatID == "" {
+			return fmt.Errorf("session %q: use <channel>:<chat>", session)
+		}
+	}
+	if quietHours != "" && quietHours != "off" && quietHours != "default" {
+		q, err := ParseQuietHours(quietHours)
+		if err != nil {
+			return err
+		}
+		quietHours = q.String()
+	}
+
+	switch silent {
+	case "":
+	case "on", "off":
+		on := silent == "on"
+		var err error
+		if session == "" {
+			err = s.SetSetting(SilentModeSetting, strconv.FormatBool(on))
+		} else {
+			err = s.SetSilentFor(channel, chatID, &on)
+		}
+		if err != nil {
+			return err
+		}
+	case "default":
+		if session == "" {
+			return fmt.Errorf("silent mode must be on or off")
+		}
+		if err := s.SetSilentFor(channel, chatID, nil); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("silent mode %q: use on, off or default", silent)
+	}
+
+	switch {
+	case quietHours == "":
+		return nil
+	case session == "" && quietHours == "default":
+		return s.SetSetting(QuietHoursSetting, "")
+	case session == "":
+		return s.SetSetting(QuietHoursSetting, quietHours)
+	case quietHours == "default":
+		return s.SetQuietHoursFor(channel, chatID, "")
+	}
+	return s.SetQuietHoursFor(channel, chatID, quietHours)
+}
+
+// ContactOverrides returns the per-contact silent mode and quiet hours
+// settings keyed by "<channel>:<chatID>".
+func (s *TimelineService) ContactOverrides() (silent, quiet map[string]string, err error) {
+	if silent, err = s.SettingsWithPrefix(silentSettingPrefix); err != nil {
+		return nil, nil, err
+	}
+	quiet, err = s.SettingsWithPrefix(quietSettingPrefix)
+	return silent, quiet, err
+}
+
+// HeldMessage is an outbound message held back during quiet hours.
+type HeldMessage struct {
+	ID        int64     `json:"id"`
+	Channel   string    `json:"channel"`
+	ChatID    string    `json:"chat_id"`
+	Content   string    `json:"content"`
+	Trace     string    `json:"trace,omitempty"`
+	ReleaseAt time.Time `json:"release_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HoldMessage queues m until m.ReleaseAt and sets its ID.
+func (s *TimelineService) HoldMessage(m *HeldMessage) error {
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	var err error
+	m.ID, err = s.db.insert(`
+	INSERT INTO held_messages (channel, chat_id, content, trace, release_at, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`, m.Channel, m.ChatID, m.Content, m.Trace, m.ReleaseAt.UTC(), m.CreatedAt.UTC())
+	return err
+}
+
+// HeldMessages returns held messages due at or before until (all if zero),
+// oldest first.
+func (s *TimelineService) HeldMessages(until time.Time) ([]HeldMessage, error) {
+	query := "SELECT id, channel, chat_id, content, trace, release_at, created_at FROM held_messages"
+	var args []any
+	if !until.IsZero() {
+		query += " WHERE release_at <= ?"
+		args = append(args, until.UTC())
+	}
+	rows, err := s.db.Query(query+" ORDER BY id", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []HeldMessage
+	for rows.Next() {
+		var m HeldMessage
+		if err := rows.Scan(&m.ID, &m.Channel, &m.ChatID, &m.Content, &m.Trace, &m.ReleaseAt, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// DeleteHeldMessage removes a held message once it was delivered.
+func (s *TimelineService) DeleteHeldMessage(id int64) error {
+	_, err := s.db.Exec("DELETE FROM held_messages WHERE id = ?", id)
+	return err
+}
@@ -0,0 +1,120 @@
+package timeline
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Reminder states.
+const (
+	ReminderPending   = "pending"
+	ReminderSent      = "sent"
+	ReminderCancelled = "cancelled"
+)
+
+// Reminder is a one-shot message created with remind_me and delivered to
+// the chat it was created in.
+type Reminder struct {
+	ID         int64      `json:"id"`
+	SessionKey string     `json:"session_key"`
+	Channel    string     `json:"channel"`
+	ChatID     string     `json:"chat_id"`
+	Text       string     `json:"text"`
+	DueAt      time.Time  `json:"due_at"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+}
+
+// ErrReminderNotFound is returned for unknown reminders, or reminders of
+// another session.
+var ErrReminderNotFound = errors.New("reminder not found")
+
+// AddReminder stores a pending reminder and sets its ID.
+func (s *TimelineService) AddReminder(r *Reminder) error {
+	if r.Status == "" {
+		r.Status = ReminderPending
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	var err error
+	r.ID, err = s.db.insert(`
+	INSERT INTO reminders (session_key, channel, chat_id, text, due_at, status, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, r.SessionKey, r.Channel, r.ChatID, r.Text, r.DueAt.UTC().Truncate(time.Second), r.Status, r.CreatedAt)
+	return err
+}
+
+// ListReminders returns reminders of sessionKey ("" for all), optionally
+// filtered by status, soonest first.
+func (s *TimelineService) ListReminders(sessionKey, status string, limit int) ([]Reminder, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	clause, args := "WHERE 1 = 1", []any{}
+	if sessionKey != "" {
+		clause += " AND session_key = ?"
+		args = append(args, sessionKey)
+	}
+	if status != "" {
+		clause += " AND status = ?"
+		args = append(args, status)
+	}
+	return s.queryReminders(clause+" ORDER BY due_at LIMIT ?", append(args, limit)...)
+}
+
+// DueReminders returns pending reminders due at or before now.
+func (s *TimelineService) DueReminders(now time.Time) ([]Reminder, error) {
+	return s.queryReminders("WHERE status = ? AND due_at <= ? ORDER BY due_at", ReminderPending, now.UTC())
+}
+
+// CancelReminder cancels a pending reminder of sessionKey ("" for any).
+func (s *TimelineService) CancelReminder(sessionKey string, id int64) error {
+	query := "UPDATE reminders SET status = ? WHERE id = ? AND status = ?"
+	args := []any{ReminderCancelled, id, ReminderPending}
+	if sessionKey != "" {
+		query += " AND session_key = ?"
+		args = append(args, sessionKey)
+	}
+	res, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrReminderNotFound
+	}
+	return nil
+}
+
+// MarkReminderSent records that a reminder was delivered.
+func (s *TimelineService) MarkReminderSent(id int64, at time.Time) error {
+	_, err := s.db.Exec("UPDATE reminders SET status = ?, sent_at = ? WHERE id = ?", ReminderSent, at, id)
+	return err
+}
+
+func (s *TimelineService) queryReminders(clause string, args ...any) ([]Reminder, error) {
+	rows, err := s.db.Query(`
+	SELECT id, session_key, channel, chat_id, text, due_at, status, created_at, sent_at
+	FROM reminders `+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []Reminder
+	for rows.Next() {
+		var r Reminder
+		var sent sql.NullTime
+		if err := rows.Scan(&r.ID, &r.SessionKey, &r.Channel, &r.ChatID, &r.Text, &r.DueAt, &r.Status, &r.CreatedAt, &sent); err != nil {
+			return nil, err
+		}
+		r.DueAt = r.DueAt.Local()
+		if sent.Valid {
+			r.SentAt = &sent.Time
+		}
+		reminders = append(reminders, r)
+	}
+	return reminders, rows.Err()
+}
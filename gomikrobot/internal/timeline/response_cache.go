@@ -0,0 +1,33 @@
+package timeline
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CachedResponse returns the response stored for key unless it has expired.
+func (s *TimelineService) CachedResponse(key string) (string, bool, error) {
+	var response string
+	err := s.db.QueryRow("SELECT response FROM response_cache WHERE key = ? AND expires_at > ?", key, time.Now()).Scan(&response)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return response, true, nil
+}
+
+// CacheResponse stores response for key for ttl, replacing an earlier one,
+// and drops expired entries.
+func (s *TimelineService) CacheResponse(key, response string, ttl time.Duration) error {
+	now := time.Now()
+	if _, err := s.db.Exec("DELETE FROM response_cache WHERE expires_at <= ?", now); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`
+	INSERT INTO response_cache (key, response, created_at, expires_at) VALUES (?, ?, ?, ?)
+	ON CONFLICT(key) DO UPDATE SET response = excluded.response, created_at = excluded.created_at, expires_at = excluded.expires_at
+	`, key, response, now, now.Add(ttl))
+	return err
+}
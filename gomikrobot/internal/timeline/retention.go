@@ -0,0 +1,130 @@
+package timeline
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// PruneResult describes one retention run.
+type PruneResult struct {
+	Before  time.Time `json:"before"`
+	Removed int       `json:"removed"`
+	// Archive is the gzipped JSONL file holding the removed events, if any.
+	Archive string `json:"archive,omitempty"`
+}
+
+// Prune removes events older than before and compacts the database. If
+// archiveDir is set, the events are first written to a gzipped JSONL file
+// there and only the archived events are deleted; nothing is deleted if
+// archiving fails.
+func (s *TimelineService) Prune(before time.Time, archiveDir string) (PruneResult, error) {
+	res := PruneResult{Before: before}
+
+	var n int64
+	if archiveDir != "" {
+		path, ids, err := s.archive(before, archiveDir)
+		if err != nil {
+			return res, fmt.Errorf("archive: %w", err)
+		}
+		if len(ids) == 0 {
+			return res, nil
+		}
+		res.Archive = path
+		if n, err = s.deleteEvents(ids); err != nil {
+			return res, err
+		}
+	} else {
+		out, err := s.db.Exec("DELETE FROM timeline WHERE timestamp < ?", before)
+		if err != nil {
+			return res, err
+		}
+		n, _ = out.RowsAffected()
+	}
+	res.Removed = int(n)
+	if n == 0 {
+		return res, nil
+	}
+
+	if _, err := s.db.Exec(s.db.dialect.vacuum()); err != nil {
+		return res, fmt.Errorf("vacuum: %w", err)
+	}
+	return res, nil
+}
+
+// deleteEvents deletes the events with ids in one transaction and returns
+// how many were removed. Events stored since they were archived, even with
+// an old timestamp, stay for the next run.
+func (s *TimelineService) deleteEvents(ids []int64) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var n int64
+	for _, id := range ids {
+		out, err := tx.Exec("DELETE FROM timeline WHERE id = ?", id)
+		if err != nil {
+			return 0, err
+		}
+		affected, _ := out.RowsAffected()
+		n += affected
+	}
+	return n, tx.Commit()
+}
+
+// archive writes events older than before to a new file in dir and returns
+// its path and the ids of the events written. No file is left when there
+// is nothing to archive.
+func (s *TimelineService) archive(before time.Time, dir string) (string, []int64, error) {
+	events, err := s.GetEvents(FilterArgs{EndDate: &before})
+	if err != nil {
+		return "", nil, err
+	}
+	// GetEvents' end date is inclusive; Prune's cutoff is not.
+	kept := events[:0]
+	for _, e := range events {
+		if e.Timestamp.Before(before) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == 0 {
+		return "", nil, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", nil, err
+	}
+	path := filepath.Join(dir, fmt.Sprintf("timeline-before-%s-%d.jsonl.gz", before.Format("2006-01-02"), time.Now().Unix()))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", nil, err
+	}
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	// Oldest first, as the events happened.
+	for i := len(kept) - 1; i >= 0; i-- {
+		if err = enc.Encode(kept[i]); err != nil {
+			break
+		}
+	}
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return "", nil, err
+	}
+	ids := make([]int64, len(kept))
+	for i, e := range kept {
+		ids[i] = e.ID
+	}
+	return path, ids, nil
+}
@@ -11,7 +11,7 @@ type TimelineEvent struct {
 	Timestamp      time.Time `json:"timestamp"`      // When it happened
 	SenderID       string    `json:"sender_id"`      // Phone number
 	SenderName     string    `json:"sender_name"`    // Display name
-	EventType      string    `json:"event_type"`     // TEXT, AUDIO, IMAGE, SYSTEM
+	EventType      string    `json:"event_type"`     // TEXT, AUDIO, IMAGE, SYSTEM, REPLY
 	ContentText    string    `json:"content_text"`   // The text or transcript
 	MediaPath      string    `json:"media_path"`     // Path to local file if any
 	VectorID       string    `json:"vector_id"`      // Qdrant ID
@@ -19,28 +19,17 @@ type TimelineEvent struct {
 	Authorized     bool      `json:"authorized"`     // Whether sender is in AllowFrom list
 }
 
-const Schema = `
-CREATE TABLE IF NOT EXISTS timeline (
-	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	event_id TEXT UNIQUE,
-	timestamp DATETIME,
-	sender_id TEXT,
-	sender_name TEXT,
-	event_type TEXT,
-	content_text TEXT,
-	media_path TEXT,
-	vector_id TEXT,
-	classification TEXT,
-	authorized BOOLEAN DEFAULT 1
-);
-
-CREATE INDEX IF NOT EXISTS idx_timeline_timestamp ON timeline(timestamp);
-CREATE INDEX IF NOT EXISTS idx_timeline_sender ON timeline(sender_id);
-CREATE INDEX IF NOT EXISTS idx_timeline_authorized ON timeline(authorized);
-
-CREATE TABLE IF NOT EXISTS settings (
-	key TEXT PRIMARY KEY,
-	value TEXT,
-	updated_at DATETIME
-);
-`
+// UsageRecord captures the cost of processing one message.
+type UsageRecord struct {
+	ID               int64     `json:"id"`
+	Timestamp        time.Time `json:"timestamp"`
+	SessionKey       string    `json:"session_key"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	ToolCalls        int       `json:"tool_calls"`
+	ToolErrors       int       `json:"tool_errors"`
+	Failed           bool      `json:"failed"`      // The LLM call or loop failed
+	DurationMs       int64     `json:"duration_ms"` // Time from receiving the message to the reply
+	Refused          bool      `json:"refused"`     // The provider declined or filtered the request
+}
@@ -0,0 +1,96 @@
+package timeline
+
+import (
+	"strings"
+)
+
+// SearchResult is a timeline event matching a full-text query.
+type SearchResult struct {
+	TimelineEvent
+	// Snippet is the matching part of the content with hits in [brackets].
+	Snippet string `json:"snippet"`
+}
+
+// Search finds events whose content or sender name contain all words of
+// query, best matches first. SenderID, dates, and AuthorizedOnly of filter
+// narrow the results; Limit defaults to 20.
+func (s *TimelineService) Search(query string, filter FilterArgs) ([]SearchResult, error) {
+	q, args, order := s.db.dialect.search(query)
+	if q == "" {
+		return nil, nil
+	}
+
+	if filter.SenderID != "" {
+		q += " AND t.sender_id = ?"
+		args = append(args, filter.SenderID)
+	}
+	if filter.StartDate != nil {
+		q += " AND t.timestamp >= ?"
+		args = append(args, *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		q += " AND t.timestamp <= ?"
+		args = append(args, *filter.EndDate)
+	}
+	if filter.AuthorizedOnly != nil {
+		q += " AND t.authorized = ?"
+		args = append(args, *filter.AuthorizedOnly)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	q += " ORDER BY " + order + " LIMIT ? OFFSET ?"
+	args = append(args, limit, filter.Offset)
+
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		err := rows.Scan(
+			&r.ID,
+			&r.EventID,
+			&r.Timestamp,
+			&r.SenderID,
+			&r.SenderName,
+			&r.EventType,
+			&r.ContentText,
+			&r.MediaPath,
+			&r.VectorID,
+			&r.Classification,
+			&r.Authorized,
+			&r.Snippet,
+		)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// ftsQuery turns free text into an FTS5 query that matches all words,
+// quoting each so user input cannot use FTS syntax. A trailing * on a word
+// is kept as a prefix match.
+func ftsQuery(text string) string {
+	var terms []string
+	for _, w := range strings.Fields(text) {
+		prefix := strings.HasSuffix(w, "*")
+		w = strings.Trim(w, `*"`)
+		if w == "" {
+			continue
+		}
+		term := `"` + strings.ReplaceAll(w, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " ")
+}
@@ -1,29 +1,37 @@
 package timeline
 
 import (
-	"database/sql"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
-
-	_ "modernc.org/sqlite"
 )
 
 type TimelineService struct {
-	db *sql.DB
+	db *db
 }
 
+// NewTimelineService opens the SQLite timeline at dbPath.
 func NewTimelineService(dbPath string) (*TimelineService, error) {
-	db, err := sql.Open("sqlite", "file:"+dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	return Open(Storage{Path: dbPath})
+}
+
+// Open opens the timeline in st and upgrades its schema.
+func Open(st Storage) (*TimelineService, error) {
+	db, err := openDB(st)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open timeline db: %w", err)
 	}
 
-	// Apply schema
-	if _, err := db.Exec(Schema); err != nil {
+	// Upgrade the schema
+	m, err := newMigrator(db)
+	if err == nil {
+		_, err = m.Up(false)
+	}
+	if err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to apply schema: %w", err)
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
-
 	return &TimelineService{db: db}, nil
 }
 
@@ -51,6 +59,13 @@ func (s *TimelineService) AddEvent(evt *TimelineEvent) error {
 	return err
 }
 
+// HasEvent reports whether an event with eventID is stored.
+func (s *TimelineService) HasEvent(eventID string) (bool, error) {
+	var n int
+	err := s.db.QueryRow("SELECT COUNT(*) FROM timeline WHERE event_id = ?", eventID).Scan(&n)
+	return n > 0, err
+}
+
 type FilterArgs struct {
 	SenderID       string
 	Limit          int
@@ -58,6 +73,10 @@ type FilterArgs struct {
 	StartDate      *time.Time
 	EndDate        *time.Time
 	AuthorizedOnly *bool // nil = all, true = authorized only, false = unauthorized only
+	HasMedia       bool  // only events with a media file
+	Classification string
+	EventType      string // e.g. TEXT or AUDIO
+	AfterID        int64  // only events added after the one with this id
 }
 
 func (s *TimelineService) GetEvents(filter FilterArgs) ([]TimelineEvent, error) {
@@ -80,6 +99,21 @@ func (s *TimelineService) GetEvents(filter FilterArgs) ([]TimelineEvent, error)
 		query += " AND authorized = ?"
 		args = append(args, *filter.AuthorizedOnly)
 	}
+	if filter.HasMedia {
+		query += " AND media_path != ''"
+	}
+	if filter.Classification != "" {
+		query += " AND classification = ?"
+		args = append(args, filter.Classification)
+	}
+	if filter.EventType != "" {
+		query += " AND event_type = ?"
+		args = append(args, filter.EventType)
+	}
+	if filter.AfterID > 0 {
+		query += " AND id > ?"
+		args = append(args, filter.AfterID)
+	}
 
 	query += " ORDER BY timestamp DESC"
 
@@ -135,17 +169,67 @@ func (s *TimelineService) GetSetting(key string) (string, error) {
 // SetSetting persists a setting value.
 func (s *TimelineService) SetSetting(key, value string) error {
 	_, err := s.db.Exec(`
-		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, datetime('now'))
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, ?)
 		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
-	`, key, value)
+	`, key, value, time.Now().UTC())
 	return err
 }
 
 // IsSilentMode checks if silent mode is enabled. Defaults to true (safe default).
 func (s *TimelineService) IsSilentMode() bool {
-	val, err := s.GetSetting("silent_mode")
+	val, err := s.GetSetting(SilentModeSetting)
 	if err != nil {
 		return true // Safe default: silent
 	}
 	return val == "true"
 }
+
+const groupSettingPrefix = "group_enabled:"
+
+// GroupEnabled reports whether the bot may answer in the group chat chatID,
+// falling back to def for groups without a setting.
+func (s *TimelineService) GroupEnabled(chatID string, def bool) bool {
+	val, err := s.GetSetting(groupSettingPrefix + chatID)
+	if err != nil {
+		return def
+	}
+	return val == "true"
+}
+
+// SetGroupEnabled switches the bot on or off for the group chat chatID.
+func (s *TimelineService) SetGroupEnabled(chatID string, enabled bool) error {
+	return s.SetSetting(groupSettingPrefix+chatID, strconv.FormatBool(enabled))
+}
+
+// GroupSettings returns the per-group settings keyed by chat ID.
+func (s *TimelineService) GroupSettings() (map[string]bool, error) {
+	settings, err := s.SettingsWithPrefix(groupSettingPrefix)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[string]bool, len(settings))
+	for chatID, val := range settings {
+		groups[chatID] = val == "true"
+	}
+	return groups, nil
+}
+
+// SettingsWithPrefix returns the non-empty settings whose key starts with
+// prefix, keyed by the rest of the key.
+func (s *TimelineService) SettingsWithPrefix(prefix string) (map[string]string, error) {
+	rows, err := s.db.Query("SELECT key, value FROM settings WHERE substr(key, 1, ?) = ? AND value != ''", len(prefix), prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[string]string)
+	for rows.Next() {
+		var key, val string
+		if err := rows.Scan(&key, &val); err != nil {
+			return nil, err
+		}
+		settings[strings.TrimPrefix(key, prefix)] = val
+	}
+	return settings, rows.Err()
+}
@@ -0,0 +1,91 @@
+package timeline
+
+import "time"
+
+// Stats summarizes timeline activity over a time range.
+type Stats struct {
+	Start         time.Time      `json:"start"`
+	End           time.Time      `json:"end"`
+	Total         int            `json:"total"`
+	PerDay        []DayCount     `json:"per_day"`
+	PerSender     []SenderCount  `json:"per_sender"`
+	PerType       map[string]int `json:"per_type"`
+	AvgResponseMs float64        `json:"avg_response_ms"`
+	Usage         UsageTotals    `json:"usage"`
+}
+
+// DayCount is the number of events on one day (YYYY-MM-DD, local time).
+type DayCount struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+// SenderCount is the number of events from one sender.
+type SenderCount struct {
+	SenderID   string `json:"sender_id"`
+	SenderName string `json:"sender_name"`
+	Count      int    `json:"count"`
+}
+
+// maxStatsSenders caps the senders listed in Stats.
+const maxStatsSenders = 10
+
+// Stats aggregates events and usage with start <= timestamp < end.
+func (s *TimelineService) Stats(start, end time.Time) (*Stats, error) {
+	st := &Stats{Start: start, End: end, PerDay: []DayCount{}, PerSender: []SenderCount{}}
+
+	rows, err := s.db.Query(`
+	SELECT `+s.db.dialect.day("timestamp")+` AS day, COUNT(*)
+	FROM timeline WHERE timestamp >= ? AND timestamp < ?
+	GROUP BY day ORDER BY day
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var d DayCount
+		if err := rows.Scan(&d.Day, &d.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		st.PerDay = append(st.PerDay, d)
+		st.Total += d.Count
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+	SELECT sender_id, MAX(COALESCE(sender_name, '')), COUNT(*) AS n
+	FROM timeline
+	WHERE timestamp >= ? AND timestamp < ? AND event_type NOT IN ('SYSTEM', 'REPLY') AND COALESCE(sender_id, '') != ''
+	GROUP BY sender_id ORDER BY n DESC LIMIT ?
+	`, start, end, maxStatsSenders)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c SenderCount
+		if err := rows.Scan(&c.SenderID, &c.SenderName, &c.Count); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		st.PerSender = append(st.PerSender, c)
+	}
+	rows.Close()
+
+	if st.PerType, err = s.EventCounts(start, end); err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRow(`
+	SELECT COALESCE(AVG(duration_ms), 0) FROM usage
+	WHERE timestamp >= ? AND timestamp < ? AND duration_ms > 0 AND NOT failed
+	`, start, end).Scan(&st.AvgResponseMs)
+	if err != nil {
+		return nil, err
+	}
+
+	if st.Usage, err = s.UsageBetween(start, end); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
@@ -0,0 +1,235 @@
+package timeline
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// Storage selects the database a TimelineService keeps its data in.
+type Storage struct {
+	// Driver is "sqlite" (the default) or "postgres".
+	Driver string
+	// Path is the SQLite database file.
+	Path string
+	// DSN is the Postgres connection string, e.g.
+	// postgres://user:pass@db:5432/gomikrobot?sslmode=disable.
+	DSN string
+}
+
+// dialect is what differs between the databases the timeline runs on.
+// Queries are written for SQLite, with ? placeholders, and rewritten by
+// rebind where needed.
+type dialect interface {
+	name() string
+	// rebind rewrites ? placeholders for the driver.
+	rebind(query string) string
+	// day is an expression for the local date (YYYY-MM-DD) of column.
+	day(column string) string
+	// vacuum compacts the timeline after large deletes.
+	vacuum() string
+	// search returns the start of a full-text query for the words in
+	// query, selecting timeline t's columns and a snippet, and the
+	// expression to order hits by. It returns "" if query has no words.
+	search(query string) (q string, args []any, order string)
+	// lockMigrations is run first in every migration transaction so
+	// processes sharing the database migrate one at a time; "" if the
+	// database already serializes them.
+	lockMigrations() string
+}
+
+// openDB opens the database selected by st.
+func openDB(st Storage) (*db, error) {
+	switch st.Driver {
+	case "", "sqlite":
+		if st.Path == "" {
+			return nil, fmt.Errorf("no timeline database path")
+		}
+		conn, err := sql.Open("sqlite", "file:"+st.Path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+		if err != nil {
+			return nil, err
+		}
+		return &db{DB: conn, dialect: sqliteDialect{}}, nil
+	case "postgres":
+		if st.DSN == "" {
+			return nil, fmt.Errorf("no Postgres DSN for the timeline")
+		}
+		conn, err := sql.Open("pgx", st.DSN)
+		if err != nil {
+			return nil, err
+		}
+		if err := conn.Ping(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return &db{DB: conn, dialect: postgresDialect{}}, nil
+	}
+	return nil, fmt.Errorf("unknown timeline driver %q", st.Driver)
+}
+
+// db runs queries written for SQLite on the database of its dialect.
+type db struct {
+	*sql.DB
+	dialect dialect
+}
+
+func (d *db) Exec(query string, args ...any) (sql.Result, error) {
+	return d.DB.Exec(d.dialect.rebind(query), args...)
+}
+
+func (d *db) Query(query string, args ...any) (*sql.Rows, error) {
+	return d.DB.Query(d.dialect.rebind(query), args...)
+}
+
+func (d *db) QueryRow(query string, args ...any) *sql.Row {
+	return d.DB.QueryRow(d.dialect.rebind(query), args...)
+}
+
+func (d *db) Begin() (*tx, error) {
+	t, err := d.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, dialect: d.dialect}, nil
+}
+
+// insert runs an INSERT into a table with an id column and returns the id
+// of the new row. Postgres does not report it without RETURNING.
+func (d *db) insert(query string, args ...any) (int64, error) {
+	if d.dialect.name() == "postgres" {
+		var id int64
+		err := d.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+	res, err := d.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// tx is a transaction on a db.
+type tx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+func (t *tx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.Tx.Exec(t.dialect.rebind(query), args...)
+}
+
+func (t *tx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.Query(t.dialect.rebind(query), args...)
+}
+
+func (t *tx) QueryRow(query string, args ...any) *sql.Row {
+	return t.Tx.QueryRow(t.dialect.rebind(query), args...)
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) name() string               { return "sqlite" }
+func (sqliteDialect) rebind(query string) string { return query }
+
+// Timestamps are stored as local time strings, so the first ten characters
+// are the local date.
+func (sqliteDialect) day(column string) string { return "substr(" + column + ", 1, 10)" }
+func (sqliteDialect) vacuum() string           { return "VACUUM" }
+
+// SQLite databases are files of one host; the migration transaction takes
+// the write lock at its first statement.
+func (sqliteDialect) lockMigrations() string { return "" }
+
+func (sqliteDialect) search(query string) (string, []any, string) {
+	match := ftsQuery(query)
+	if match == "" {
+		return "", nil, ""
+	}
+	return `SELECT t.id, t.event_id, t.timestamp, t.sender_id, t.sender_name, t.event_type, t.content_text,
+		t.media_path, t.vector_id, t.classification, t.authorized,
+		snippet(timeline_fts, 0, '[', ']', '…', 16)
+	FROM timeline_fts JOIN timeline t ON t.id = timeline_fts.rowid
+	WHERE timeline_fts MATCH ?`, []any{match}, "bm25(timeline_fts)"
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) name() string { return "postgres" }
+
+// migrationLockID names the advisory lock held while migrating; any
+// constant works as long as all versions agree on it.
+const migrationLockID = 0x6d696b726f // "mikro"
+
+// Several gateways and workers may start against one Postgres database.
+// The lock is released when the transaction ends.
+func (postgresDialect) lockMigrations() string {
+	return fmt.Sprintf("SELECT pg_advisory_xact_lock(%d)", migrationLockID)
+}
+
+// rebind numbers the ? placeholders as $1, $2, … and leaves question marks
+// in string literals alone.
+func (postgresDialect) rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	inString := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+		case r == '?' && !inString:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (postgresDialect) day(column string) string {
+	return "to_char(" + column + ", 'YYYY-MM-DD')"
+}
+
+func (postgresDialect) vacuum() string { return "VACUUM ANALYZE timeline" }
+
+// searchDocument is the text Postgres searches; it matches the expression
+// index of the initial migration.
+const searchDocument = "to_tsvector('simple', coalesce(t.content_text, '') || ' ' || coalesce(t.sender_name, ''))"
+
+func (postgresDialect) search(query string) (string, []any, string) {
+	match := tsQuery(query)
+	if match == "" {
+		return "", nil, ""
+	}
+	return `SELECT t.id, t.event_id, t.timestamp, t.sender_id, t.sender_name, t.event_type, t.content_text,
+		t.media_path, t.vector_id, t.classification, t.authorized,
+		ts_headline('simple', coalesce(t.content_text, ''), q, 'StartSel=[, StopSel=], MaxWords=16, MinWords=4')
+	FROM timeline t, to_tsquery('simple', ?) q
+	WHERE ` + searchDocument + ` @@ q`, []any{match}, "ts_rank(" + searchDocument + ", q) DESC"
+}
+
+// tsQuery turns free text into a Postgres tsquery that matches all words,
+// keeping a trailing * as a prefix match like ftsQuery.
+func tsQuery(text string) string {
+	var terms []string
+	for _, w := range strings.Fields(text) {
+		prefix := strings.HasSuffix(w, "*")
+		w = strings.Trim(w, `*"'`)
+		if w == "" {
+			continue
+		}
+		term := "'" + strings.ReplaceAll(strings.ReplaceAll(w, `\`, `\\`), "'", "''") + "'"
+		if prefix {
+			term += ":*"
+		}
+		terms = append(terms, term)
+	}
+	return strings.Join(terms, " & ")
+}
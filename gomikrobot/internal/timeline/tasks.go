@@ -0,0 +1,114 @@
+package timeline
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Task states.
+const (
+	TaskQueued  = "queued"
+	TaskRunning = "running"
+	TaskDone    = "done"
+	TaskFailed  = "failed"
+)
+
+// Task is a background job started with spawn_task.
+type Task struct {
+	ID         string     `json:"id"`
+	SessionKey string     `json:"session_key"` // Session that started the task
+	Title      string     `json:"title"`
+	Prompt     string     `json:"prompt"`
+	Status     string     `json:"status"`
+	Result     string     `json:"result,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// ErrTaskNotFound is returned by GetTask for unknown ids.
+var ErrTaskNotFound = errors.New("task not found")
+
+// CreateTask stores a new task.
+func (s *TimelineService) CreateTask(t *Task) error {
+	_, err := s.db.Exec(`
+	INSERT INTO tasks (id, session_key, title, prompt, status, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)
+	`, t.ID, t.SessionKey, t.Title, t.Prompt, t.Status, t.CreatedAt)
+	return err
+}
+
+// UpdateTask saves a task's status, result, and timestamps.
+func (s *TimelineService) UpdateTask(t *Task) error {
+	_, err := s.db.Exec(`
+	UPDATE tasks SET status = ?, result = ?, error = ?, started_at = ?, finished_at = ?
+	WHERE id = ?
+	`, t.Status, t.Result, t.Error, t.StartedAt, t.FinishedAt, t.ID)
+	return err
+}
+
+// GetTask returns the task with id.
+func (s *TimelineService) GetTask(id string) (*Task, error) {
+	rows, err := s.queryTasks("WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, ErrTaskNotFound
+	}
+	return &rows[0], nil
+}
+
+// ListTasks returns the newest tasks, optionally filtered by status.
+func (s *TimelineService) ListTasks(status string, limit int) ([]Task, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if status != "" {
+		return s.queryTasks("WHERE status = ? ORDER BY created_at DESC LIMIT ?", status, limit)
+	}
+	return s.queryTasks("ORDER BY created_at DESC LIMIT ?", limit)
+}
+
+// FailUnfinishedTasks marks queued and running tasks as failed, e.g. after a
+// restart interrupted them. It returns the number of tasks changed.
+func (s *TimelineService) FailUnfinishedTasks(reason string) (int, error) {
+	res, err := s.db.Exec(`
+	UPDATE tasks SET status = ?, error = ?, finished_at = ?
+	WHERE status IN (?, ?)
+	`, TaskFailed, reason, time.Now(), TaskQueued, TaskRunning)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (s *TimelineService) queryTasks(clause string, args ...any) ([]Task, error) {
+	rows, err := s.db.Query(`
+	SELECT id, session_key, title, prompt, status, COALESCE(result, ''), COALESCE(error, ''), created_at, started_at, finished_at
+	FROM tasks `+clause, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		var started, finished sql.NullTime
+		if err := rows.Scan(&t.ID, &t.SessionKey, &t.Title, &t.Prompt, &t.Status, &t.Result, &t.Error, &t.CreatedAt, &started, &finished); err != nil {
+			return nil, err
+		}
+		if started.Valid {
+			t.StartedAt = &started.Time
+		}
+		if finished.Valid {
+			t.FinishedAt = &finished.Time
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
@@ -0,0 +1,612 @@
+package timeline
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearch(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	for i, text := range []string{
+		"Can you book the dentist appointment for Friday?",
+		"The blog deploy failed again",
+		"Reminder: dentist moved to Monday",
+	} {
+		err := svc.AddEvent(&TimelineEvent{
+			EventID:     string(rune('a' + i)),
+			Timestamp:   time.Now(),
+			SenderID:    "123",
+			SenderName:  "Alice",
+			EventType:   "TEXT",
+			ContentText: text,
+			Authorized:  i != 2,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := svc.Search("dentist", FilterArgs{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Snippet == "" {
+		t.Error("expected a snippet")
+	}
+
+	authorized := true
+	results, _ = svc.Search("dentist", FilterArgs{AuthorizedOnly: &authorized})
+	if len(results) != 1 {
+		t.Errorf("expected 1 authorized result, got %d", len(results))
+	}
+
+	results, _ = svc.Search(`blog "deploy`, FilterArgs{})
+	if len(results) != 1 {
+		t.Errorf("expected quoted input to be safe and match, got %d", len(results))
+	}
+
+	results, _ = svc.Search("dent*", FilterArgs{})
+	if len(results) != 2 {
+		t.Errorf("expected prefix match, got %d", len(results))
+	}
+}
+
+func TestPruneArchivesOldEvents(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	now := time.Now()
+	for i, age := range []time.Duration{200 * 24 * time.Hour, 100 * 24 * time.Hour, time.Hour} {
+		svc.AddEvent(&TimelineEvent{EventID: string(rune('a' + i)), Timestamp: now.Add(-age), ContentText: "old news", EventType: "TEXT"})
+	}
+
+	dir := t.TempDir()
+	res, err := svc.Prune(now.Add(-90*24*time.Hour), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Removed != 2 || res.Archive == "" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+
+	f, err := os.Open(res.Archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(zr)
+	if lines := strings.Count(string(data), "\n"); lines != 2 {
+		t.Errorf("expected 2 archived events, got %d", lines)
+	}
+
+	events, _ := svc.GetEvents(FilterArgs{})
+	if len(events) != 1 {
+		t.Errorf("expected 1 remaining event, got %d", len(events))
+	}
+	if results, _ := svc.Search("news", FilterArgs{}); len(results) != 1 {
+		t.Errorf("expected the search index to drop pruned events, got %d", len(results))
+	}
+}
+
+func TestPruneKeepsEventsStoredAfterArchiving(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	old := time.Now().Add(-200 * 24 * time.Hour)
+	svc.AddEvent(&TimelineEvent{EventID: "archived", Timestamp: old, EventType: "TEXT"})
+	before := time.Now().Add(-90 * 24 * time.Hour)
+	_, ids, err := svc.archive(before, t.TempDir())
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("archive: %v %v", ids, err)
+	}
+	// A backdated event arrives between archiving and deleting.
+	svc.AddEvent(&TimelineEvent{EventID: "late", Timestamp: old, EventType: "TEXT"})
+
+	if n, err := svc.deleteEvents(ids); err != nil || n != 1 {
+		t.Fatalf("deleteEvents: %d %v", n, err)
+	}
+	if ok, _ := svc.HasEvent("late"); !ok {
+		t.Error("an event that was not archived was deleted")
+	}
+	if ok, _ := svc.HasEvent("archived"); ok {
+		t.Error("the archived event was kept")
+	}
+}
+
+func TestStats(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	svc.AddEvent(&TimelineEvent{EventID: "1", Timestamp: yesterday, SenderID: "alice", SenderName: "Alice", EventType: "TEXT", Authorized: true})
+	svc.AddEvent(&TimelineEvent{EventID: "2", Timestamp: now, SenderID: "alice", SenderName: "Alice", EventType: "AUDIO", Authorized: true})
+	svc.AddEvent(&TimelineEvent{EventID: "3", Timestamp: now, SenderID: "bob", EventType: "TEXT", Authorized: true})
+	svc.RecordUsage(&UsageRecord{Timestamp: now, PromptTokens: 100, CompletionTokens: 20, DurationMs: 1000})
+	svc.RecordUsage(&UsageRecord{Timestamp: now, PromptTokens: 50, DurationMs: 3000})
+
+	st, err := svc.Stats(now.AddDate(0, 0, -7), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Total != 3 || len(st.PerDay) != 2 {
+		t.Errorf("expected 3 events over 2 days, got %d over %v", st.Total, st.PerDay)
+	}
+	if st.PerDay[0].Day != yesterday.Format("2006-01-02") {
+		t.Errorf("expected first day %s, got %s", yesterday.Format("2006-01-02"), st.PerDay[0].Day)
+	}
+	if len(st.PerSender) != 2 || st.PerSender[0].SenderID != "alice" || st.PerSender[0].Count != 2 {
+		t.Errorf("unexpected senders %+v", st.PerSender)
+	}
+	if st.PerType["TEXT"] != 2 || st.PerType["AUDIO"] != 1 {
+		t.Errorf("unexpected types %v", st.PerType)
+	}
+	if st.AvgResponseMs != 2000 {
+		t.Errorf("expected 2000ms average, got %v", st.AvgResponseMs)
+	}
+	if st.Usage.PromptTokens != 150 {
+		t.Errorf("expected 150 prompt tokens, got %d", st.Usage.PromptTokens)
+	}
+}
+
+func TestGetEventsByTypeAndAfterID(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	now := time.Now()
+	svc.AddEvent(&TimelineEvent{EventID: "1", Timestamp: now, SenderID: "alice", EventType: "TEXT"})
+	svc.AddEvent(&TimelineEvent{EventID: "2", Timestamp: now, SenderID: "alice", EventType: "AUDIO"})
+	svc.AddEvent(&TimelineEvent{EventID: "3", Timestamp: now, SenderID: "bob", EventType: "TEXT"})
+
+	texts, err := svc.GetEvents(FilterArgs{EventType: "TEXT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 2 {
+		t.Errorf("expected 2 TEXT events, got %d", len(texts))
+	}
+
+	all, _ := svc.GetEvents(FilterArgs{})
+	first := all[0].ID
+	for _, e := range all {
+		first = min(first, e.ID)
+	}
+	newer, err := svc.GetEvents(FilterArgs{AfterID: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newer) != 2 {
+		t.Fatalf("expected 2 events after id %d, got %d", first, len(newer))
+	}
+	for _, e := range newer {
+		if e.ID <= first {
+			t.Errorf("event %d is not after %d", e.ID, first)
+		}
+	}
+}
+
+func TestFeedback(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	now := time.Now()
+	svc.AddEvent(&TimelineEvent{EventID: "r1", Timestamp: now, SenderID: "alice", EventType: "REPLY", ContentText: "It is sunny.", Authorized: true})
+	svc.AddEvent(&TimelineEvent{EventID: "r2", Timestamp: now, SenderID: "bob", EventType: "REPLY", ContentText: "No idea.", Authorized: true})
+
+	for _, r := range []*Reaction{
+		{EventID: "r1", SenderID: "alice", Emoji: "👍🏽", Timestamp: now},
+		{EventID: "r1", SenderID: "carol", Emoji: "😮", Timestamp: now},
+		{EventID: "r2", SenderID: "bob", Emoji: "👍", Timestamp: now},
+		// A newer reaction from the same sender replaces the old one.
+		{EventID: "r2", SenderID: "bob", Emoji: "👎", Timestamp: now.Add(time.Second)},
+		// A removed reaction is forgotten.
+		{EventID: "r2", SenderID: "dave", Emoji: "❤️", Timestamp: now},
+		{EventID: "r2", SenderID: "dave", Emoji: ""},
+	} {
+		if err := svc.AddReaction(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rep, err := svc.Feedback(now.Add(-time.Hour), now.Add(time.Hour), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Total != 3 || rep.Positive != 1 || rep.Negative != 1 || rep.Neutral != 1 || rep.Score != 0 {
+		t.Errorf("unexpected counts %+v", rep)
+	}
+	if len(rep.Recent) != 3 || rep.Recent[0].EventID != "r2" || rep.Recent[0].Content != "No idea." || rep.Recent[0].Sentiment != FeedbackNegative {
+		t.Errorf("unexpected recent reactions %+v", rep.Recent)
+	}
+}
+
+func TestJobsAreMarkedLostAfterRestart(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	code := 0
+	done := time.Now()
+	for _, j := range []*Job{
+		{ID: "a", SessionKey: "cli:default", Command: "make build", Status: JobRunning, StartedAt: done.Add(-time.Minute)},
+		{ID: "b", SessionKey: "cli:default", Command: "true", Status: JobExited, ExitCode: &code, StartedAt: done.Add(-time.Hour), FinishedAt: &done},
+	} {
+		if err := svc.SaveJob(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := svc.MarkLostJobs(); err != nil || n != 1 {
+		t.Fatalf("MarkLostJobs = %d, %v; want 1", n, err)
+	}
+	jobs, err := svc.ListJobs("", 10)
+	if err != nil || len(jobs) != 2 {
+		t.Fatalf("ListJobs = %v, %v", jobs, err)
+	}
+	if jobs[0].ID != "a" || jobs[0].Status != JobLost || jobs[0].FinishedAt == nil {
+		t.Errorf("running job not marked lost: %+v", jobs[0])
+	}
+	if jobs[1].ExitCode == nil || *jobs[1].ExitCode != 0 {
+		t.Errorf("exit code not kept: %+v", jobs[1])
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	q, err := ParseQuietHours("22:00–07:00")
+	if err != nil || q.String() != "22:00-07:00" {
+		t.Fatalf("ParseQuietHours = %v, %v", q, err)
+	}
+	for _, bad := range []string{"22:00", "25:00-07:00", "07:00-07:00"} {
+		if _, err := ParseQuietHours(bad); err == nil {
+			t.Errorf("ParseQuietHours(%q) should fail", bad)
+		}
+	}
+
+	at := func(h, m int) time.Time { return time.Date(2025, 3, 10, h, m, 0, 0, time.UTC) }
+	for _, tc := range []struct {
+		now, want time.Time
+	}{
+		{at(21, 59), time.Time{}},
+		{at(22, 0), time.Date(2025, 3, 11, 7, 0, 0, 0, time.UTC)},
+		{at(3, 30), at(7, 0)},
+		{at(7, 0), time.Time{}},
+	} {
+		if got := q.Until(tc.now); !got.Equal(tc.want) {
+			t.Errorf("Until(%s) = %s, want %s", tc.now.Format("15:04"), got, tc.want)
+		}
+	}
+	day, _ := ParseQuietHours("12:00-14:00")
+	if !day.Until(at(13, 0)).Equal(at(14, 0)) || !day.Until(at(15, 0)).IsZero() {
+		t.Error("daytime window computed wrongly")
+	}
+}
+
+func TestSilentAndQuietPerContact(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	if err := svc.SetQuiet("", "off", "22:00-07:00"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.SetQuiet("whatsapp:anna", "on", "off"); err != nil {
+		t.Fatal(err)
+	}
+	if !svc.SilentFor("whatsapp", "anna") || svc.SilentFor("whatsapp", "bob") {
+		t.Error("per-contact silent mode should override the global flag")
+	}
+	if _, ok := svc.QuietHoursFor("whatsapp", "anna"); ok {
+		t.Error("anna opted out of quiet hours")
+	}
+	if q, ok := svc.QuietHoursFor("whatsapp", "bob"); !ok || q.String() != "22:00-07:00" {
+		t.Errorf("bob should get the global quiet hours, got %v %v", q, ok)
+	}
+	if err := svc.SetQuiet("whatsapp:anna", "default", "default"); err != nil {
+		t.Fatal(err)
+	}
+	if svc.SilentFor("whatsapp", "anna") {
+		t.Error("default should fall back to the global flag")
+	}
+	if err := svc.SetQuiet("", "default", ""); err == nil {
+		t.Error("global silent mode cannot be default")
+	}
+	if err := svc.SetQuiet("anna", "on", ""); err == nil {
+		t.Error("expected an error for a session without channel")
+	}
+
+	now := time.Now()
+	for i, release := range []time.Time{now.Add(-time.Minute), now.Add(time.Hour)} {
+		if err := svc.HoldMessage(&HeldMessage{Channel: "whatsapp", ChatID: "bob", Content: fmt.Sprint(i), ReleaseAt: release}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	due, err := svc.HeldMessages(now)
+	if err != nil || len(due) != 1 || due[0].Content != "0" {
+		t.Fatalf("HeldMessages(now) = %+v, %v", due, err)
+	}
+	if err := svc.DeleteHeldMessage(due[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if all, _ := svc.HeldMessages(time.Time{}); len(all) != 1 || all[0].Content != "1" {
+		t.Errorf("remaining held messages = %+v", all)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	svc, err := NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	if _, ok, err := svc.CachedResponse("k"); err != nil || ok {
+		t.Fatalf("empty cache: ok=%v err=%v", ok, err)
+	}
+	if err := svc.CacheResponse("k", "first", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CacheResponse("k", "second", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok, err := svc.CachedResponse("k"); err != nil || !ok || got != "second" {
+		t.Fatalf("CachedResponse = %q, %v, %v; want second", got, ok, err)
+	}
+
+	if err := svc.CacheResponse("old", "stale", -time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := svc.CachedResponse("old"); ok {
+		t.Error("expired entry was returned")
+	}
+}
+
+func TestMigrationsUpgradeLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeline.db")
+	// A database from before migrations: no schema_version, usage without
+	// the later columns, and no search index.
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`CREATE TABLE timeline (id INTEGER PRIMARY KEY AUTOINCREMENT, event_id TEXT UNIQUE, timestamp DATETIME,
+		sender_id TEXT, sender_name TEXT, event_type TEXT, content_text TEXT, media_path TEXT, vector_id TEXT,
+		classification TEXT, authorized BOOLEAN DEFAULT 1);
+	CREATE TABLE usage (id INTEGER PRIMARY KEY AUTOINCREMENT, timestamp DATETIME, session_key TEXT, model TEXT,
+		prompt_tokens INTEGER DEFAULT 0, completion_tokens INTEGER DEFAULT 0, tool_calls INTEGER DEFAULT 0,
+		tool_errors INTEGER DEFAULT 0, failed BOOLEAN DEFAULT 0);
+	INSERT INTO timeline (event_id, timestamp, sender_id, sender_name, event_type, content_text, media_path, vector_id, classification)
+		VALUES ('e1', '2026-01-01 10:00:00', 'alice', 'Alice', 'TEXT', 'old weather report', '', '', '');`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := NewTimelineService(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.RecordUsage(&UsageRecord{Timestamp: time.Now(), DurationMs: 10, Refused: true}); err != nil {
+		t.Errorf("usage columns were not added: %v", err)
+	}
+	if res, err := svc.Search("weather", FilterArgs{}); err != nil || len(res) != 1 {
+		t.Errorf("old events are not searchable: %v, %v", res, err)
+	}
+	svc.Close()
+
+	m, err := OpenMigrator(Storage{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if v, _ := m.Version(); v != m.Latest() {
+		t.Errorf("version %d, want %d", v, m.Latest())
+	}
+}
+
+func TestMigratorRollback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeline.db")
+	svc, err := NewTimelineService(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.Close()
+
+	m, err := OpenMigrator(Storage{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	latest := m.Latest()
+	if latest < 2 {
+		t.Skip("needs a migration after the initial schema")
+	}
+	hasIndex := func() bool {
+		var n int
+		m.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = 'idx_timeline_event_type'").Scan(&n)
+		return n == 1
+	}
+
+	steps, err := m.Down(1, true)
+	if err != nil || len(steps) != latest-1 || !hasIndex() {
+		t.Fatalf("dry run: %d steps, %v, index kept %v", len(steps), err, hasIndex())
+	}
+	if _, err := m.Down(1, false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Version(); v != 1 || hasIndex() {
+		t.Errorf("after rollback: version %d, index %v", v, hasIndex())
+	}
+	if _, err := m.Down(0, false); err == nil {
+		t.Error("rolled back the initial schema")
+	}
+
+	if pending, _ := m.Up(true); len(pending) != latest-1 {
+		t.Errorf("%d pending migrations, want %d", len(pending), latest-1)
+	}
+	if _, err := m.Up(false); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := m.Version(); v != latest || !hasIndex() {
+		t.Errorf("after upgrade: version %d, index %v", v, hasIndex())
+	}
+}
+
+func TestMigrationsAppliedMeanwhileAreSkipped(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timeline.db")
+	a, err := OpenMigrator(Storage{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	b, err := OpenMigrator(Storage{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+
+	// b saw the migrations pending before a applied them.
+	pending, _ := b.Pending()
+	if _, err := a.Up(false); err != nil {
+		t.Fatal(err)
+	}
+	for _, mig := range pending {
+		if err := b.apply(mig, mig.Up, true, nil); err != nil {
+			t.Errorf("migration %d applied twice: %v", mig.Version, err)
+		}
+	}
+	if v, _ := b.Version(); v != b.Latest() {
+		t.Errorf("version %d, want %d", v, b.Latest())
+	}
+}
+
+func TestMigrationsAreNumberedInOrder(t *testing.T) {
+	sqlite, err := Migrations("sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	postgres, err := Migrations("postgres")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sqlite) != len(postgres) {
+		t.Errorf("%d SQLite migrations but %d for Postgres", len(sqlite), len(postgres))
+	}
+	for i, m := range append(sqlite, postgres...) {
+		if m.Version != i%len(sqlite)+1 || m.Up == "" {
+			t.Errorf("migration %d: %+v", i%len(sqlite)+1, m)
+		}
+	}
+}
+
+func TestPostgresRebind(t *testing.T) {
+	q := postgresDialect{}.rebind("SELECT * FROM t WHERE a = ? AND b LIKE '?%' AND c IN (?, ?)")
+	if want := "SELECT * FROM t WHERE a = $1 AND b LIKE '?%' AND c IN ($2, $3)"; q != want {
+		t.Errorf("rebind = %q, want %q", q, want)
+	}
+	if got := tsQuery(`rain* O'Brien ""`); got != `'rain':* & 'O''Brien'` {
+		t.Errorf("tsQuery = %q", got)
+	}
+}
+
+// TestPostgres runs against the database in MIKROBOT_TEST_POSTGRES_DSN,
+// which it may change; it is skipped when that is not set.
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv("MIKROBOT_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("MIKROBOT_TEST_POSTGRES_DSN is not set")
+	}
+	svc, err := Open(Storage{Driver: "postgres", DSN: dsn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	id := fmt.Sprintf("pgtest%d", time.Now().UnixNano())
+	now := time.Now()
+	if err := svc.AddEvent(&TimelineEvent{EventID: id, Timestamp: now, SenderID: "alice", SenderName: "Alice", EventType: "TEXT", ContentText: "postgres weather " + id, Authorized: true}); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := svc.Search(id, FilterArgs{}); err != nil || len(res) != 1 {
+		t.Errorf("search: %v, %v", res, err)
+	}
+	if err := svc.RecordUsage(&UsageRecord{Timestamp: now, PromptTokens: 5, Failed: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Stats(now.Add(-time.Hour), now.Add(time.Hour)); err != nil {
+		t.Errorf("stats: %v", err)
+	}
+	d := &Draft{Channel: "whatsapp", ChatID: "1", Content: "hi", Status: "pending"}
+	if err := svc.AddDraft(d); err != nil || d.ID == 0 {
+		t.Errorf("draft id %d, %v", d.ID, err)
+	}
+	if err := svc.SetSetting("pg_test", "1"); err != nil {
+		t.Error(err)
+	}
+
+	// Processes starting together take turns migrating.
+	m, err := OpenMigrator(Storage{Driver: "postgres", DSN: dsn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if _, err := m.Down(1, false); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 3)
+	for range 3 {
+		go func() {
+			m, err := OpenMigrator(Storage{Driver: "postgres", DSN: dsn})
+			if err == nil {
+				_, err = m.Up(false)
+				m.Close()
+			}
+			errs <- err
+		}()
+	}
+	for range 3 {
+		if err := <-errs; err != nil {
+			t.Errorf("concurrent upgrade: %v", err)
+		}
+	}
+	if v, _ := m.Version(); v != m.Latest() {
+		t.Errorf("version %d after concurrent upgrades, want %d", v, m.Latest())
+	}
+}
@@ -0,0 +1,115 @@
+package timeline
+
+import "time"
+
+// RecordUsage stores a usage record.
+func (s *TimelineService) RecordUsage(rec *UsageRecord) error {
+	_, err := s.db.Exec(`
+	INSERT INTO usage (timestamp, session_key, model, prompt_tokens, completion_tokens, tool_calls, tool_errors, failed, duration_ms, refused)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		rec.Timestamp,
+		rec.SessionKey,
+		rec.Model,
+		rec.PromptTokens,
+		rec.CompletionTokens,
+		rec.ToolCalls,
+		rec.ToolErrors,
+		rec.Failed,
+		rec.DurationMs,
+		rec.Refused,
+	)
+	return err
+}
+
+// UsageTotals aggregates usage records over a time range.
+type UsageTotals struct {
+	Requests         int `json:"requests"`
+	Failed           int `json:"failed"`
+	Refused          int `json:"refused"`
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	ToolCalls        int `json:"tool_calls"`
+	ToolErrors       int `json:"tool_errors"`
+}
+
+// UsageBetween sums usage records with start <= timestamp < end.
+func (s *TimelineService) UsageBetween(start, end time.Time) (UsageTotals, error) {
+	return s.sumUsage("timestamp >= ? AND timestamp < ?", start, end)
+}
+
+// SessionUsageBetween is UsageBetween restricted to one session key.
+func (s *TimelineService) SessionUsageBetween(sessionKey string, start, end time.Time) (UsageTotals, error) {
+	return s.sumUsage("session_key = ? AND timestamp >= ? AND timestamp < ?", sessionKey, start, end)
+}
+
+func (s *TimelineService) sumUsage(where string, args ...any) (UsageTotals, error) {
+	var t UsageTotals
+	err := s.db.QueryRow(`
+	SELECT COUNT(*),
+		COALESCE(SUM(CASE WHEN failed THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN refused THEN 1 ELSE 0 END), 0),
+		COALESCE(SUM(prompt_tokens), 0),
+		COALESCE(SUM(completion_tokens), 0),
+		COALESCE(SUM(tool_calls), 0),
+		COALESCE(SUM(tool_errors), 0)
+	FROM usage WHERE `+where, args...).Scan(&t.Requests, &t.Failed, &t.Refused, &t.PromptTokens, &t.CompletionTokens, &t.ToolCalls, &t.ToolErrors)
+	return t, err
+}
+
+// EventCounts returns the number of timeline events per event type with
+// start <= timestamp < end. Unauthorized events are counted under
+// "UNAUTHORIZED".
+func (s *TimelineService) EventCounts(start, end time.Time) (map[string]int, error) {
+	rows, err := s.db.Query(`
+	SELECT CASE WHEN authorized THEN event_type ELSE 'UNAUTHORIZED' END AS kind, COUNT(*)
+	FROM timeline WHERE timestamp >= ? AND timestamp < ?
+	GROUP BY kind
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var kind string
+		var n int
+		if err := rows.Scan(&kind, &n); err != nil {
+			return nil, err
+		}
+		counts[kind] = n
+	}
+	return counts, rows.Err()
+}
+
+// ModelUsage is the token usage of one model.
+type ModelUsage struct {
+	Model            string `json:"model"`
+	Requests         int    `json:"requests"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+}
+
+// UsageByModel sums usage records with start <= timestamp < end per model.
+func (s *TimelineService) UsageByModel(start, end time.Time) ([]ModelUsage, error) {
+	rows, err := s.db.Query(`
+	SELECT COALESCE(model, ''), COUNT(*), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0)
+	FROM usage WHERE timestamp >= ? AND timestamp < ?
+	GROUP BY model ORDER BY model
+	`, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []ModelUsage{}
+	for rows.Next() {
+		var u ModelUsage
+		if err := rows.Scan(&u.Model, &u.Requests, &u.PromptTokens, &u.CompletionTokens); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
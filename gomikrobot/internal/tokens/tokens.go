@@ -0,0 +1,157 @@
+// Package tokens counts tokens the way OpenAI's tiktoken does, so prompts
+// can be sized before they are sent.
+package tokens
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+	"github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+// Encodings. Models of other vendors are counted with cl100k_base, which is
+// close enough for sizing.
+const (
+	o200k  = "o200k_base"
+	cl100k = "cl100k_base"
+)
+
+// Chat format overhead per message and for priming the reply, as in
+// OpenAI's token counting guide.
+const (
+	messageOverhead = 3
+	replyOverhead   = 3
+)
+
+func init() {
+	// Use the vocabularies compiled into the binary instead of downloading
+	// them on first use.
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+var (
+	mu        sync.Mutex
+	encodings = map[string]*tiktoken.Tiktoken{}
+)
+
+// encoding returns the tokenizer for name, or nil if it cannot be loaded.
+func encoding(name string) *tiktoken.Tiktoken {
+	mu.Lock()
+	defer mu.Unlock()
+	if enc, ok := encodings[name]; ok {
+		return enc
+	}
+	enc, err := tiktoken.GetEncoding(name)
+	if err != nil {
+		enc = nil
+	}
+	encodings[name] = enc
+	return enc
+}
+
+// baseModel strips a vendor prefix such as "openai/" (OpenRouter).
+func baseModel(model string) string {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return strings.ToLower(model)
+}
+
+// encodingFor returns the encoding of model. An empty model uses o200k_base,
+// the encoding of current OpenAI models.
+func encodingFor(model string) string {
+	m := baseModel(model)
+	if m == "" {
+		return o200k
+	}
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "chatgpt-4o", "o1", "o3", "o4"} {
+		if strings.HasPrefix(m, prefix) {
+			return o200k
+		}
+	}
+	return cl100k
+}
+
+// Count returns the number of tokens in text for model. If the tokenizer is
+// unavailable it estimates four characters per token.
+func Count(model, text string) int {
+	if text == "" {
+		return 0
+	}
+	enc := encoding(encodingFor(model))
+	if enc == nil {
+		return (utf8.RuneCountInString(text) + 3) / 4
+	}
+	return len(enc.EncodeOrdinary(text))
+}
+
+// CountMessages returns the prompt tokens of msgs, including tool calls and
+// the chat format overhead.
+func CountMessages(model string, msgs []provider.Message) int {
+	n := replyOverhead
+	for _, m := range msgs {
+		n += messageOverhead + Count(model, m.Role) + CountReply(model, m.Content, m.ToolCalls)
+	}
+	return n
+}
+
+// CountReply returns the completion tokens of a reply with content and
+// tool calls.
+func CountReply(model, content string, calls []provider.ToolCall) int {
+	n := Count(model, content)
+	for _, tc := range calls {
+		args, _ := json.Marshal(tc.Arguments)
+		n += Count(model, tc.Name) + Count(model, string(args))
+	}
+	return n
+}
+
+// CountRequest returns the prompt tokens of req: its messages and tool
+// definitions.
+func CountRequest(req *provider.ChatRequest) int {
+	n := CountMessages(req.Model, req.Messages)
+	if len(req.Tools) > 0 {
+		defs, _ := json.Marshal(req.Tools)
+		n += Count(req.Model, string(defs))
+	}
+	return n
+}
+
+// contextLengths are the context windows of known model families, longest
+// prefix first.
+var contextLengths = []struct {
+	prefix string
+	tokens int
+}{
+	{"gpt-4.1", 1047576},
+	{"gpt-4.5", 128000},
+	{"gpt-4o", 128000},
+	{"gpt-4-turbo", 128000},
+	{"gpt-4-32k", 32768},
+	{"gpt-4", 8192},
+	{"gpt-5", 400000},
+	{"gpt-3.5-turbo", 16385},
+	{"chatgpt-4o", 128000},
+	{"o1-mini", 128000},
+	{"o1", 200000},
+	{"o3", 200000},
+	{"o4", 200000},
+	{"claude", 200000},
+	{"gemini", 1048576},
+}
+
+// ContextLength returns the context window of model in tokens, or 0 if the
+// model is unknown.
+func ContextLength(model string) int {
+	m := baseModel(model)
+	for _, c := range contextLengths {
+		if strings.HasPrefix(m, c.prefix) {
+			return c.tokens
+		}
+	}
+	return 0
+}
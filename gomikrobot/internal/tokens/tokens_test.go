@@ -0,0 +1,67 @@
+package tokens
+
+import (
+	"testing"
+
+	"github.com/kamir/gomikrobot/internal/provider"
+)
+
+func TestCount(t *testing.T) {
+	for _, tc := range []struct {
+		model, text string
+		want        int
+	}{
+		{"gpt-4o", "hello world", 2},
+		{"gpt-4", "tiktoken is great!", 6},
+		{"openai/gpt-4o-mini", "tiktoken is great!", 6},
+		{"gpt-4o", "", 0},
+	} {
+		if got := Count(tc.model, tc.text); got != tc.want {
+			t.Errorf("Count(%q, %q) = %d, want %d", tc.model, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestEncodingFor(t *testing.T) {
+	for model, want := range map[string]string{
+		"openai/gpt-4o-mini": o200k,
+		"o3-mini":            o200k,
+		"":                   o200k,
+		"gpt-3.5-turbo":      cl100k,
+		"llama3":             cl100k,
+	} {
+		if got := encodingFor(model); got != want {
+			t.Errorf("encodingFor(%q) = %s, want %s", model, got, want)
+		}
+	}
+}
+
+func TestCountMessages(t *testing.T) {
+	msgs := []provider.Message{
+		{Role: "system", Content: "hello world"},
+		{Role: "user", Content: "hello world"},
+	}
+	// Two messages of 3 overhead + 1 role + 2 content, plus 3 for the reply.
+	if got := CountMessages("gpt-4o", msgs); got != 15 {
+		t.Errorf("CountMessages = %d, want 15", got)
+	}
+	req := &provider.ChatRequest{Model: "gpt-4o", Messages: msgs, Tools: []provider.ToolDefinition{{Type: "function"}}}
+	if got := CountRequest(req); got <= 15 {
+		t.Errorf("CountRequest = %d, want the tool definitions counted", got)
+	}
+}
+
+func TestContextLength(t *testing.T) {
+	for model, want := range map[string]int{
+		"gpt-4o-mini":                 128000,
+		"openai/gpt-4.1-nano":         1047576,
+		"gpt-4-0613":                  8192,
+		"gpt-4-turbo-preview":         128000,
+		"anthropic/claude-3.5-sonnet": 200000,
+		"llama3":                      0,
+	} {
+		if got := ContextLength(model); got != want {
+			t.Errorf("ContextLength(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
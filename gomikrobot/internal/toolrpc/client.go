@@ -0,0 +1,145 @@
+package toolrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// Client calls a remote tool server or a plugin.
+type Client struct {
+	// send delivers one encoded request and returns the encoded response.
+	send   func(ctx context.Context, id int64, body []byte) ([]byte, error)
+	close  func() error
+	nextID atomic.Int64
+}
+
+// NewClient creates a client for the server at url.
+func NewClient(url, token string) *Client {
+	client := &http.Client{Timeout: 5 * time.Minute}
+	send := func(ctx context.Context, _ int64, body []byte) ([]byte, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := client.Do(httpReq)
+		if err != nil {
+			return nil, fmt.Errorf("tool server: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+			return nil, fmt.Errorf("tool server: status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+		}
+		return io.ReadAll(resp.Body)
+	}
+	return &Client{send: send}
+}
+
+// Close stops the plugin process of a plugin client; it does nothing for
+// remote servers.
+func (c *Client) Close() error {
+	if c.close == nil {
+		return nil
+	}
+	return c.close()
+}
+
+// List returns the tools offered by the server.
+func (c *Client) List(ctx context.Context) ([]ToolInfo, error) {
+	var out struct {
+		Tools []ToolInfo `json:"tools"`
+	}
+	if err := c.call(ctx, MethodList, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tools, nil
+}
+
+// Call runs a remote tool.
+func (c *Client) Call(ctx context.Context, name string, args map[string]any) (string, error) {
+	var out CallResult
+	if err := c.call(ctx, MethodCall, CallParams{Name: name, Arguments: args}, &out); err != nil {
+		return "", err
+	}
+	return out.Content, nil
+}
+
+// Tools returns the remote tools as local tools named "<prefix>_<name>".
+func (c *Client) Tools(ctx context.Context, prefix string) ([]tools.Tool, error) {
+	infos, err := c.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]tools.Tool, 0, len(infos))
+	for _, info := range infos {
+		out = append(out, &remoteTool{client: c, prefix: prefix, info: info})
+	}
+	return out, nil
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result any) error {
+	id := c.nextID.Add(1)
+	req := map[string]any{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	data, err := c.send(ctx, id, body)
+	if err != nil {
+		return err
+	}
+
+	var rpcResp struct {
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	if err := json.Unmarshal(data, &rpcResp); err != nil {
+		return fmt.Errorf("tool server: invalid response: %w", err)
+	}
+	if rpcResp.Error != nil {
+		return fmt.Errorf("tool server: %s", rpcResp.Error.Message)
+	}
+	return json.Unmarshal(rpcResp.Result, result)
+}
+
+// remoteTool forwards calls to a tool on a remote server.
+type remoteTool struct {
+	client *Client
+	prefix string
+	info   ToolInfo
+}
+
+func (t *remoteTool) Name() string {
+	if t.prefix == "" {
+		return t.info.Name
+	}
+	return t.prefix + "_" + t.info.Name
+}
+
+func (t *remoteTool) Description() string {
+	return fmt.Sprintf("[%s] %s", t.prefix, t.info.Description)
+}
+
+func (t *remoteTool) Parameters() map[string]any { return t.info.Parameters }
+
+func (t *remoteTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	out, err := t.client.Call(ctx, t.info.Name, params)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return out, nil
+}
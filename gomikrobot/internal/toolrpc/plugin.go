@@ -0,0 +1,214 @@
+package toolrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// ErrPluginExited is returned for calls cut short by the plugin exiting.
+var ErrPluginExited = errors.New("plugin exited")
+
+// NewPluginClient returns a client for the plugin executable at path. The
+// process is started on the first call, restarted by the next call after
+// it exits, and stopped by Close.
+func NewPluginClient(path string) *Client {
+	p := &pluginProcess{path: path, pending: make(map[int64]chan []byte)}
+	return &Client{send: p.send, close: p.stop}
+}
+
+// pluginProcess is a running plugin and the calls waiting for its answer.
+type pluginProcess struct {
+	path string
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	exited  chan struct{} // Closed when cmd has exited
+	pending map[int64]chan []byte
+
+	writeMu sync.Mutex
+}
+
+func (p *pluginProcess) send(ctx context.Context, id int64, body []byte) ([]byte, error) {
+	p.mu.Lock()
+	if err := p.startLocked(); err != nil {
+		p.mu.Unlock()
+		return nil, err
+	}
+	reply := make(chan []byte, 1)
+	p.pending[id] = reply
+	stdin, exited := p.stdin, p.exited
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	p.writeMu.Lock()
+	_, err := stdin.Write(append(body, '\n'))
+	p.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %w", err)
+	}
+	select {
+	case data := <-reply:
+		return data, nil
+	case <-exited:
+		return nil, ErrPluginExited
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startLocked starts the process unless it is running.
+func (p *pluginProcess) startLocked() error {
+	if p.exited != nil {
+		select {
+		case <-p.exited:
+		default:
+			return nil
+		}
+	}
+	cmd := exec.Command(p.path)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start plugin: %w", err)
+	}
+	p.cmd, p.stdin, p.exited = cmd, stdin, make(chan struct{})
+	go p.read(cmd, stdout, p.exited)
+	return nil
+}
+
+// read hands each response line to the call waiting for its id.
+func (p *pluginProcess) read(cmd *exec.Cmd, stdout io.Reader, exited chan struct{}) {
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 64*1024), maxRequestBytes)
+	for sc.Scan() {
+		var head struct {
+			ID int64 `json:"id"`
+		}
+		if json.Unmarshal(sc.Bytes(), &head) != nil {
+			continue
+		}
+		p.mu.Lock()
+		reply := p.pending[head.ID]
+		p.mu.Unlock()
+		if reply != nil {
+			reply <- append([]byte(nil), sc.Bytes()...)
+		}
+	}
+	_ = cmd.Wait()
+	close(exited)
+}
+
+// stop closes stdin, which asks the plugin to exit, and kills it if it is
+// still running after a grace period.
+func (p *pluginProcess) stop() error {
+	p.mu.Lock()
+	cmd, stdin, exited := p.cmd, p.stdin, p.exited
+	p.mu.Unlock()
+	if cmd == nil {
+		return nil
+	}
+	_ = stdin.Close()
+	select {
+	case <-exited:
+	case <-time.After(2 * time.Second):
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+	return nil
+}
+
+// pluginNameChars are replaced in plugin names, which prefix tool names.
+var pluginNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// PluginName returns the name of the plugin at path: its lowercased file
+// name without extension, e.g. "weather" for weather.exe.
+func PluginName(path string) string {
+	name := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	return strings.Trim(pluginNameChars.ReplaceAllString(name, "_"), "_")
+}
+
+// DiscoverPlugins returns the executables in dir, sorted by name. Hidden
+// files and subdirectories are skipped; a missing dir has no plugins.
+func DiscoverPlugins(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// ServeStdio serves registry as a plugin on r and w until r is closed.
+// Plugins written in Go within this module can use it as their main loop.
+func ServeStdio(ctx context.Context, registry *tools.Registry, r io.Reader, w io.Writer) error {
+	s := &Server{registry: registry}
+	enc := json.NewEncoder(w) // Encode ends each message with a newline
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxRequestBytes)
+	for sc.Scan() {
+		var req request
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			mu.Lock()
+			_ = enc.Encode(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: codeParse, Message: "invalid JSON"}})
+			mu.Unlock()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := s.handle(ctx, req)
+			resp.JSONRPC = "2.0"
+			if resp.ID == nil {
+				resp.ID = json.RawMessage("null")
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			_ = enc.Encode(resp)
+		}()
+	}
+	return sc.Err()
+}
@@ -0,0 +1,154 @@
+// Package toolrpc serves a tool registry over authenticated JSON-RPC 2.0 and
+// exposes remote registries as local tools.
+//
+// Plugins use the same protocol over stdin and stdout: a plugin is an
+// executable that reads one JSON-RPC 2.0 request per line and writes one
+// response per line, with the tools/list and tools/call methods and no
+// token. Requests may be pipelined; responses are matched by id. Anything
+// the plugin writes to stderr is passed through to the gateway log.
+package toolrpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// Method names.
+const (
+	MethodList = "tools/list"
+	MethodCall = "tools/call"
+)
+
+// JSON-RPC error codes.
+const (
+	codeParse          = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeToolError      = -32000
+)
+
+// maxRequestBytes caps a single RPC request body.
+const maxRequestBytes = 4 << 20
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ToolInfo describes a served tool.
+type ToolInfo struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// CallParams are the parameters of tools/call.
+type CallParams struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// CallResult is the result of tools/call.
+type CallResult struct {
+	Content string `json:"content"`
+}
+
+// Server serves a tool registry. Every request must carry the token as a
+// bearer credential.
+type Server struct {
+	registry *tools.Registry
+	token    string
+	logCalls bool // Print each call; off for stdio, where stdout is the protocol
+}
+
+// NewServer creates a server for registry.
+func NewServer(registry *tools.Registry, token string) *Server {
+	return &Server{registry: registry, token: token, logCalls: true}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tok := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if s.token == "" || subtle.ConstantTimeCompare([]byte(tok), []byte(s.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeResponse(w, response{Error: &rpcError{Code: codeParse, Message: "invalid JSON"}})
+		return
+	}
+	writeResponse(w, s.handle(r.Context(), req))
+}
+
+// handle runs a single request.
+func (s *Server) handle(ctx context.Context, req request) response {
+	resp := response{ID: req.ID}
+	if req.JSONRPC != "2.0" {
+		resp.Error = &rpcError{Code: codeInvalidRequest, Message: `jsonrpc must be "2.0"`}
+		return resp
+	}
+
+	switch req.Method {
+	case MethodList:
+		list := []ToolInfo{}
+		for _, t := range s.registry.List() {
+			list = append(list, ToolInfo{Name: t.Name(), Description: t.Description(), Parameters: t.Parameters()})
+		}
+		resp.Result = map[string]any{"tools": list}
+
+	case MethodCall:
+		var p CallParams
+		if err := json.Unmarshal(req.Params, &p); err != nil || p.Name == "" {
+			resp.Error = &rpcError{Code: codeInvalidParams, Message: "params must include a tool name"}
+			break
+		}
+		if s.logCalls {
+			fmt.Printf("🔧 Remote tool call: %s\n", p.Name)
+		}
+		out, err := s.registry.Execute(ctx, p.Name, p.Arguments)
+		if err != nil {
+			resp.Error = &rpcError{Code: codeToolError, Message: err.Error()}
+			break
+		}
+		resp.Result = CallResult{Content: out}
+
+	default:
+		resp.Error = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+	}
+	return resp
+}
+
+func writeResponse(w http.ResponseWriter, resp response) {
+	resp.JSONRPC = "2.0"
+	if resp.ID == nil {
+		resp.ID = json.RawMessage("null")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(resp)
+}
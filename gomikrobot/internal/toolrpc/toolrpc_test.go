@@ -0,0 +1,144 @@
+package toolrpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kamir/gomikrobot/internal/tools"
+)
+
+// TestMain lets the test binary act as a plugin serving read_file.
+func TestMain(m *testing.M) {
+	if os.Getenv("TOOLRPC_TEST_PLUGIN") == "1" {
+		reg := tools.NewRegistry()
+		reg.Register(tools.NewReadFileTool())
+		if err := ServeStdio(context.Background(), reg, os.Stdin, os.Stdout); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	reg := tools.NewRegistry()
+	reg.Register(tools.NewReadFileTool())
+	srv := httptest.NewServer(NewServer(reg, "secret-token"))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRemoteToolRoundTrip(t *testing.T) {
+	srv := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "note.txt")
+	if err := os.WriteFile(path, []byte("hello from the NAS"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := NewClient(srv.URL, "secret-token").Tools(context.Background(), "nas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(remote) != 1 || remote[0].Name() != "nas_read_file" {
+		t.Fatalf("unexpected remote tools: %v", remote)
+	}
+
+	out, err := remote[0].Execute(context.Background(), map[string]any{"path": path})
+	if err != nil || out != "hello from the NAS" {
+		t.Errorf("unexpected result %q, %v", out, err)
+	}
+
+	if _, err := NewClient(srv.URL, "secret-token").Call(context.Background(), "missing", nil); err == nil || !strings.Contains(err.Error(), "tool not found") {
+		t.Errorf("expected tool not found, got %v", err)
+	}
+}
+
+func TestServerRequiresToken(t *testing.T) {
+	srv := newTestServer(t)
+
+	if _, err := NewClient(srv.URL, "wrong").List(context.Background()); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected 401 with wrong token, got %v", err)
+	}
+
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", resp.StatusCode)
+	}
+}
+
+func TestPluginRoundTrip(t *testing.T) {
+	t.Setenv("TOOLRPC_TEST_PLUGIN", "1")
+	path := filepath.Join(t.TempDir(), "note.txt")
+	if err := os.WriteFile(path, []byte("hello from a plugin"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewPluginClient(os.Args[0])
+	defer client.Close()
+	plugin, err := client.Tools(context.Background(), "files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plugin) != 1 || plugin[0].Name() != "files_read_file" {
+		t.Fatalf("unexpected plugin tools: %v", plugin)
+	}
+
+	// Concurrent calls are matched to their responses by id.
+	errs := make(chan error, 5)
+	for range 5 {
+		go func() {
+			out, err := plugin[0].Execute(context.Background(), map[string]any{"path": path})
+			if err == nil && out != "hello from a plugin" {
+				err = fmt.Errorf("unexpected result %q", out)
+			}
+			errs <- err
+		}()
+	}
+	for range 5 {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	// A stopped plugin is started again by the next call.
+	client.Close()
+	if _, err := client.List(context.Background()); err != nil {
+		t.Errorf("plugin not restarted: %v", err)
+	}
+}
+
+func TestDiscoverPlugins(t *testing.T) {
+	dir := t.TempDir()
+	for name, mode := range map[string]os.FileMode{"weather": 0755, "Home-Lights.sh": 0700, "README.md": 0644, ".hidden": 0755} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.Mkdir(filepath.Join(dir, "lib"), 0755)
+
+	paths, err := DiscoverPlugins(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range paths {
+		names = append(names, PluginName(p))
+	}
+	if strings.Join(names, ",") != "home_lights,weather" {
+		t.Errorf("unexpected plugins %v", names)
+	}
+	if paths, err := DiscoverPlugins(filepath.Join(dir, "missing")); err != nil || paths != nil {
+		t.Errorf("missing dir should have no plugins, got %v, %v", paths, err)
+	}
+}
@@ -0,0 +1,138 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ArtifactDir is the workspace subdirectory holding full tool outputs that
+// were too large to return to the model.
+const ArtifactDir = "artifacts"
+
+// artifactRetention is how long artifacts are kept before SaveArtifact
+// removes them.
+const artifactRetention = 7 * 24 * time.Hour
+
+// Paging limits of read_artifact, in bytes.
+const (
+	artifactPageDefault = 8000
+	artifactPageMax     = 32000
+)
+
+var artifactName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// SaveArtifact stores the full output of a tool call under
+// <workspace>/artifacts and returns its path relative to the workspace.
+// Artifacts older than a week are removed on the way.
+func SaveArtifact(workspace, tool, content string) (string, error) {
+	dir := filepath.Join(workspace, ArtifactDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	pruneArtifacts(dir, time.Now().Add(-artifactRetention))
+
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	name := fmt.Sprintf("%s-%s-%s.txt", time.Now().Format("20060102-150405"), artifactName.ReplaceAllString(tool, "_"), hex.EncodeToString(suffix))
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(filepath.Join(ArtifactDir, name)), nil
+}
+
+func pruneArtifacts(dir string, before time.Time) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && !e.IsDir() && info.ModTime().Before(before) {
+			_ = os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// ReadArtifactTool pages through a stored tool output.
+type ReadArtifactTool struct {
+	workspace string
+}
+
+// NewReadArtifactTool creates a read_artifact tool for artifacts in workspace.
+func NewReadArtifactTool(workspace string) *ReadArtifactTool {
+	return &ReadArtifactTool{workspace: workspace}
+}
+
+func (t *ReadArtifactTool) Name() string { return "read_artifact" }
+
+func (t *ReadArtifactTool) Description() string {
+	return "Read part of a large tool output that was truncated and saved as an artifact. " +
+		"Pass the artifact path from the truncation note and page with offset/limit (in bytes)."
+}
+
+func (t *ReadArtifactTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Artifact path, e.g. artifacts/20250101-120000-exec-a1b2c3.txt",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Byte offset to start at (default 0)",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Bytes to return (default %d, max %d)", artifactPageDefault, artifactPageMax),
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *ReadArtifactTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(GetString(params, "path", "")))
+	if rel == "." || filepath.IsAbs(rel) || filepath.Dir(rel) != ArtifactDir {
+		return "Error: path must be an artifact such as artifacts/<name>.txt", nil
+	}
+	data, err := os.ReadFile(filepath.Join(t.workspace, rel))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("Error: artifact not found: %s", rel), nil
+		}
+		return fmt.Sprintf("Error reading artifact: %v", err), nil
+	}
+
+	offset := GetInt(params, "offset", 0)
+	limit := GetInt(params, "limit", artifactPageDefault)
+	if limit <= 0 || limit > artifactPageMax {
+		limit = artifactPageMax
+	}
+	if offset < 0 || offset >= len(data) {
+		return fmt.Sprintf("Error: offset %d is outside the artifact (%d bytes)", offset, len(data)), nil
+	}
+	end := min(offset+limit, len(data))
+	// Keep pages on character boundaries.
+	for offset > 0 && !utf8.RuneStart(data[offset]) {
+		offset--
+	}
+	for end < len(data) && !utf8.RuneStart(data[end]) {
+		end--
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Bytes %d–%d of %d]\n", offset, end, len(data))
+	sb.Write(data[offset:end])
+	if end < len(data) {
+		fmt.Fprintf(&sb, "\n[More: read_artifact with offset %d]", end)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Budget errors returned by Registry.Execute.
+var (
+	ErrBudgetCalls = errors.New("tool call budget exhausted")
+	ErrBudgetTime  = errors.New("time budget exhausted")
+)
+
+// Budget limits the tool calls and wall-clock time available to one agent
+// turn. It is shared by all tool calls of the turn and safe for concurrent use.
+type Budget struct {
+	mu        sync.Mutex
+	deadline  time.Time
+	callsLeft int
+}
+
+// NewBudget creates a budget of calls tool calls (<= 0 means unlimited)
+// ending after d (<= 0 means no deadline).
+func NewBudget(calls int, d time.Duration) *Budget {
+	b := &Budget{callsLeft: calls}
+	if calls <= 0 {
+		b.callsLeft = -1
+	}
+	if d > 0 {
+		b.deadline = time.Now().Add(d)
+	}
+	return b
+}
+
+// Remaining returns the calls left (-1 if unlimited) and the time left
+// (-1 if there is no deadline).
+func (b *Budget) Remaining() (calls int, left time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	left = -1
+	if !b.deadline.IsZero() {
+		left = max(time.Until(b.deadline), 0)
+	}
+	return b.callsLeft, left
+}
+
+// Exhausted reports whether no calls or no time remain.
+func (b *Budget) Exhausted() bool {
+	calls, left := b.Remaining()
+	return calls == 0 || left == 0
+}
+
+// take reserves one tool call.
+func (b *Budget) take() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.deadline.IsZero() && !time.Now().Before(b.deadline) {
+		return ErrBudgetTime
+	}
+	if b.callsLeft == 0 {
+		return ErrBudgetCalls
+	}
+	if b.callsLeft > 0 {
+		b.callsLeft--
+	}
+	return nil
+}
+
+type budgetKey struct{}
+
+// WithBudget attaches a budget to ctx; Registry.Execute enforces it.
+func WithBudget(ctx context.Context, b *Budget) context.Context {
+	return context.WithValue(ctx, budgetKey{}, b)
+}
+
+// BudgetFrom returns the budget attached to ctx, or nil.
+func BudgetFrom(ctx context.Context) *Budget {
+	b, _ := ctx.Value(budgetKey{}).(*Budget)
+	return b
+}
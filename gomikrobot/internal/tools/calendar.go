@@ -0,0 +1,284 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/calendar"
+)
+
+// CalendarBackend stores calendar events, e.g. a *calendar.Client.
+type CalendarBackend interface {
+	List(ctx context.Context, start, end time.Time) ([]calendar.Event, error)
+	Get(ctx context.Context, uid string) (*calendar.Event, error)
+	Create(ctx context.Context, e *calendar.Event) error
+	Update(ctx context.Context, e *calendar.Event) error
+}
+
+// CalendarTools returns calendar_list, calendar_create, and calendar_update
+// for cal. Times without an offset are read, and all times shown, in loc.
+func CalendarTools(cal CalendarBackend, loc *time.Location) []Tool {
+	if loc == nil {
+		loc = time.Local
+	}
+	return []Tool{&CalendarListTool{cal, loc}, &CalendarCreateTool{cal, loc}, &CalendarUpdateTool{cal, loc}}
+}
+
+// calendarTimeFormats are accepted for start and end, in order.
+var calendarTimeFormats = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04"}
+
+// parseCalendarTime reads a time in loc; a plain date means an all-day
+// value.
+func parseCalendarTime(s string, loc *time.Location) (time.Time, bool, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.ParseInLocation("2006-01-02", s, loc); err == nil {
+		return t, true, nil
+	}
+	for _, f := range calendarTimeFormats {
+		if t, err := time.ParseInLocation(f, s, loc); err == nil {
+			return t.In(loc), false, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("invalid time %q; use YYYY-MM-DD HH:MM or YYYY-MM-DD", s)
+}
+
+func formatEvent(e calendar.Event, loc *time.Location) string {
+	var when string
+	start, end := e.Start.In(loc), e.End.In(loc)
+	switch {
+	case e.AllDay && e.End.Sub(e.Start) > 24*time.Hour:
+		when = fmt.Sprintf("%s – %s (all day)", e.Start.Format("Mon 2006-01-02"), e.End.AddDate(0, 0, -1).Format("Mon 2006-01-02"))
+	case e.AllDay:
+		when = e.Start.Format("Mon 2006-01-02") + " (all day)"
+	case start.Format("2006-01-02") == end.Format("2006-01-02"):
+		when = fmt.Sprintf("%s–%s", start.Format("Mon 2006-01-02 15:04"), end.Format("15:04"))
+	default:
+		when = fmt.Sprintf("%s – %s", start.Format("Mon 2006-01-02 15:04"), end.Format("Mon 2006-01-02 15:04"))
+	}
+	line := fmt.Sprintf("- %s %s", when, e.Summary)
+	if e.Location != "" {
+		line += " @ " + e.Location
+	}
+	return line + fmt.Sprintf(" [uid: %s]", e.UID)
+}
+
+// CalendarListTool lists events in a time range.
+type CalendarListTool struct {
+	cal CalendarBackend
+	loc *time.Location
+}
+
+func (t *CalendarListTool) Name() string { return "calendar_list" }
+
+func (t *CalendarListTool) Description() string {
+	return fmt.Sprintf("List calendar events between start and end (default: today). Times are in %s.", t.loc)
+}
+
+func (t *CalendarListTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"start": map[string]any{
+				"type":        "string",
+				"description": "Start date or time, e.g. 2026-03-14 or 2026-03-14 09:00 (default: today)",
+			},
+			"end": map[string]any{
+				"type":        "string",
+				"description": "End date or time; a date includes that whole day (default: end of the start day)",
+			},
+		},
+	}
+}
+
+func (t *CalendarListTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	now := time.Now().In(t.loc)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, t.loc)
+	if s := GetString(params, "start", ""); s != "" {
+		var err error
+		if start, _, err = parseCalendarTime(s, t.loc); err != nil {
+			return "Error: " + err.Error(), nil
+		}
+	}
+	end := time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, t.loc)
+	if s := GetString(params, "end", ""); s != "" {
+		e, allDay, err := parseCalendarTime(s, t.loc)
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		if end = e; allDay {
+			end = e.AddDate(0, 0, 1)
+		}
+	}
+	if !end.After(start) {
+		return "Error: end must be after start", nil
+	}
+
+	events, err := t.cal.List(ctx, start, end)
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	if len(events) == 0 {
+		return fmt.Sprintf("No events between %s and %s.", start.Format("2006-01-02 15:04"), end.Format("2006-01-02 15:04")), nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Events (%s):\n", t.loc)
+	for _, e := range events {
+		sb.WriteString(formatEvent(e, t.loc) + "\n")
+	}
+	return sb.String(), nil
+}
+
+// CalendarCreateTool adds an event.
+type CalendarCreateTool struct {
+	cal CalendarBackend
+	loc *time.Location
+}
+
+func (t *CalendarCreateTool) Name() string { return "calendar_create" }
+
+func (t *CalendarCreateTool) Description() string {
+	return fmt.Sprintf("Create a calendar event. Times are in %s; a date without a time creates an all-day event. "+
+		"Check calendar_list for conflicts first when booking something.", t.loc)
+}
+
+func (t *CalendarCreateTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"summary":          map[string]any{"type": "string", "description": "Event title"},
+			"start":            map[string]any{"type": "string", "description": "YYYY-MM-DD HH:MM, or YYYY-MM-DD for all day"},
+			"end":              map[string]any{"type": "string", "description": "End time, or last day of an all-day event"},
+			"duration_minutes": map[string]any{"type": "integer", "description": "Length if end is not given (default 60)"},
+			"location":         map[string]any{"type": "string"},
+			"description":      map[string]any{"type": "string"},
+		},
+		"required": []string{"summary", "start"},
+	}
+}
+
+func (t *CalendarCreateTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	e := &calendar.Event{
+		Summary:     GetString(params, "summary", ""),
+		Location:    GetString(params, "location", ""),
+		Description: GetString(params, "description", ""),
+	}
+	if e.Summary == "" {
+		return "Error: summary is required", nil
+	}
+	var err error
+	if e.Start, e.AllDay, err = parseCalendarTime(GetString(params, "start", ""), t.loc); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	if e.End, err = eventEnd(e, GetString(params, "end", ""), GetInt(params, "duration_minutes", 60), t.loc); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+
+	if err := t.cal.Create(ctx, e); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	return "Created " + strings.TrimPrefix(formatEvent(*e, t.loc), "- "), nil
+}
+
+// eventEnd computes the end of e from an explicit end or a duration. The
+// end of an all-day event names its last day.
+func eventEnd(e *calendar.Event, end string, minutes int, loc *time.Location) (time.Time, error) {
+	if end == "" {
+		if e.AllDay {
+			return e.Start.AddDate(0, 0, 1), nil
+		}
+		if minutes <= 0 {
+			return time.Time{}, fmt.Errorf("duration_minutes must be positive")
+		}
+		return e.Start.Add(time.Duration(minutes) * time.Minute), nil
+	}
+	t, allDay, err := parseCalendarTime(end, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if allDay != e.AllDay {
+		return time.Time{}, fmt.Errorf("start and end must both be dates or both be times")
+	}
+	if allDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	if !t.After(e.Start) {
+		return time.Time{}, fmt.Errorf("end must be after start")
+	}
+	return t, nil
+}
+
+// CalendarUpdateTool changes an existing event.
+type CalendarUpdateTool struct {
+	cal CalendarBackend
+	loc *time.Location
+}
+
+func (t *CalendarUpdateTool) Name() string { return "calendar_update" }
+
+func (t *CalendarUpdateTool) Description() string {
+	return fmt.Sprintf("Change a calendar event found with calendar_list; only the given fields change. Times are in %s. "+
+		"Moving a recurring event moves the whole series.", t.loc)
+}
+
+func (t *CalendarUpdateTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"uid":         map[string]any{"type": "string", "description": "Event uid from calendar_list"},
+			"summary":     map[string]any{"type": "string"},
+			"start":       map[string]any{"type": "string", "description": "New start; the duration is kept unless end is given"},
+			"end":         map[string]any{"type": "string"},
+			"location":    map[string]any{"type": "string"},
+			"description": map[string]any{"type": "string"},
+		},
+		"required": []string{"uid"},
+	}
+}
+
+func (t *CalendarUpdateTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	uid := GetString(params, "uid", "")
+	if uid == "" {
+		return "Error: uid is required", nil
+	}
+	e, err := t.cal.Get(ctx, uid)
+	if errors.Is(err, calendar.ErrNotFound) {
+		return fmt.Sprintf("Error: no event with uid %s", uid), nil
+	} else if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+
+	for key, field := range map[string]*string{"summary": &e.Summary, "location": &e.Location, "description": &e.Description} {
+		if v, ok := params[key].(string); ok {
+			*field = v
+		}
+	}
+	duration, wasAllDay := e.End.Sub(e.Start), e.AllDay
+	if s := GetString(params, "start", ""); s != "" {
+		if e.Start, e.AllDay, err = parseCalendarTime(s, t.loc); err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		// Switching between all-day and timed resets the length.
+		if e.AllDay && !wasAllDay {
+			duration = 24 * time.Hour
+		} else if !e.AllDay && wasAllDay {
+			duration = time.Hour
+		}
+		e.End = e.Start.Add(duration)
+		if e.AllDay {
+			e.End = e.Start.AddDate(0, 0, int((duration+12*time.Hour)/(24*time.Hour)))
+		}
+	}
+	if s := GetString(params, "end", ""); s != "" {
+		if e.End, err = eventEnd(e, s, 0, t.loc); err != nil {
+			return "Error: " + err.Error(), nil
+		}
+	}
+
+	if err := t.cal.Update(ctx, e); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	return "Updated " + strings.TrimPrefix(formatEvent(*e, t.loc), "- "), nil
+}
@@ -0,0 +1,284 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	chart "github.com/wcharczuk/go-chart/v2"
+)
+
+const (
+	chartDir       = "media/charts"
+	chartWidth     = 1024
+	chartHeight    = 576
+	chartMaxPoints = 500
+)
+
+// chartFileName keeps only safe characters of a requested file name.
+var chartFileName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// RenderChartTool draws data as a PNG chart in the workspace, ready to be
+// sent with send_file.
+type RenderChartTool struct {
+	workspace string
+}
+
+// NewRenderChartTool creates a render_chart tool writing to workspace/media/charts.
+func NewRenderChartTool(workspace string) *RenderChartTool {
+	return &RenderChartTool{workspace: workspace}
+}
+
+// chartSpec is the tool's arguments.
+type chartSpec struct {
+	Type   string        `json:"type"`
+	Title  string        `json:"title"`
+	Labels []string      `json:"labels"`
+	Series []chartSeries `json:"series"`
+	XLabel string        `json:"x_label"`
+	YLabel string        `json:"y_label"`
+	Name   string        `json:"name"`
+}
+
+type chartSeries struct {
+	Name   string    `json:"name"`
+	Values []float64 `json:"values"`
+}
+
+func (t *RenderChartTool) Name() string { return "render_chart" }
+
+func (t *RenderChartTool) Description() string {
+	return "Draw data as a PNG chart (line, bar, or pie) and save it in the workspace. " +
+		"Returns the file's path; send it to the chat with send_file."
+}
+
+func (t *RenderChartTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"type": map[string]any{
+				"type":        "string",
+				"enum":        []string{"line", "bar", "pie"},
+				"description": "Kind of chart",
+			},
+			"title": map[string]any{
+				"type":        "string",
+				"description": "Title shown above the chart",
+			},
+			"labels": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Category of each value, e.g. days of the week or pie slices",
+			},
+			"series": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"name":   map[string]any{"type": "string"},
+						"values": map[string]any{"type": "array", "items": map[string]any{"type": "number"}},
+					},
+					"required": []string{"values"},
+				},
+				"description": "Data, one value per label. Line charts take several series; bar and pie charts one",
+			},
+			"x_label": map[string]any{
+				"type":        "string",
+				"description": "Name of the x axis (line)",
+			},
+			"y_label": map[string]any{
+				"type":        "string",
+				"description": "Name of the y axis (line and bar)",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "File name without extension, e.g. screen-time-week",
+			},
+		},
+		"required": []string{"type", "labels", "series"},
+	}
+}
+
+func (t *RenderChartTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	var spec chartSpec
+	raw, _ := json.Marshal(params)
+	if err := json.Unmarshal(raw, &spec); err != nil {
+		return fmt.Sprintf("Error: invalid chart data: %v", err), nil
+	}
+	if err := spec.validate(); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+
+	var buf bytes.Buffer
+	if err := spec.render(&buf); err != nil {
+		return fmt.Sprintf("Error rendering chart: %v", err), nil
+	}
+
+	name := strings.Trim(chartFileName.ReplaceAllString(spec.Name, "-"), "-")
+	if name == "" {
+		name = "chart"
+	}
+	if err := os.MkdirAll(filepath.Join(t.workspace, chartDir), 0755); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	rel, err := saveChart(t.workspace, name, buf.Bytes())
+	if err != nil {
+		return fmt.Sprintf("Error saving chart: %v", err), nil
+	}
+	recordWritten(ctx, filepath.Join(t.workspace, rel))
+	return fmt.Sprintf("Chart saved to %s (%s). Send it with send_file.", rel, formatSize(int64(buf.Len()))), nil
+}
+
+// saveChart writes data to a new file in workspace/media/charts named after
+// name and the time, numbering files made in the same second.
+func saveChart(workspace, name string, data []byte) (string, error) {
+	base := fmt.Sprintf("%s-%s", name, time.Now().Format("20060102-150405"))
+	for n := 1; ; n++ {
+		file := base + ".png"
+		if n > 1 {
+			file = fmt.Sprintf("%s-%d.png", base, n)
+		}
+		rel := filepath.Join(chartDir, file)
+		f, err := os.OpenFile(filepath.Join(workspace, rel), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return "", err
+		}
+		return rel, f.Close()
+	}
+}
+
+func (s *chartSpec) validate() error {
+	switch s.Type {
+	case "line", "bar", "pie":
+	default:
+		return fmt.Errorf("type must be line, bar, or pie")
+	}
+	if len(s.Labels) == 0 {
+		return fmt.Errorf("labels are required")
+	}
+	if len(s.Labels) > chartMaxPoints {
+		return fmt.Errorf("at most %d labels can be drawn", chartMaxPoints)
+	}
+	if len(s.Series) == 0 {
+		return fmt.Errorf("series are required")
+	}
+	if s.Type != "line" && len(s.Series) > 1 {
+		return fmt.Errorf("%s charts take one series; use a line chart to compare several", s.Type)
+	}
+	for i, ser := range s.Series {
+		if len(ser.Values) != len(s.Labels) {
+			return fmt.Errorf("series %d has %d values for %d labels", i+1, len(ser.Values), len(s.Labels))
+		}
+		for _, v := range ser.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("series %d has a value that is not a number", i+1)
+			}
+			if s.Type == "pie" && v < 0 {
+				return fmt.Errorf("pie charts cannot show negative values")
+			}
+		}
+	}
+	if s.Type == "pie" {
+		sum := 0.0
+		for _, v := range s.Series[0].Values {
+			sum += v
+		}
+		if sum == 0 {
+			return fmt.Errorf("pie charts need a value above zero")
+		}
+	}
+	return nil
+}
+
+// yRange starts the y axis at zero when no value is negative, so bars and
+// lines are not exaggerated. It returns nil to let the chart fit the data.
+func (s *chartSpec) yRange() chart.Range {
+	top := 0.0
+	for _, ser := range s.Series {
+		for _, v := range ser.Values {
+			if v < 0 {
+				return nil
+			}
+			top = max(top, v)
+		}
+	}
+	if top == 0 {
+		return nil
+	}
+	return &chart.ContinuousRange{Min: 0, Max: top * 1.05}
+}
+
+// render draws the chart as PNG into buf.
+func (s *chartSpec) render(buf *bytes.Buffer) error {
+	switch s.Type {
+	case "pie":
+		values := make([]chart.Value, len(s.Labels))
+		for i, label := range s.Labels {
+			values[i] = chart.Value{Label: label, Value: s.Series[0].Values[i]}
+		}
+		pie := chart.PieChart{Title: s.Title, Width: chartHeight, Height: chartHeight, Values: values}
+		return pie.Render(chart.PNG, buf)
+
+	case "bar":
+		bars := make([]chart.Value, len(s.Labels))
+		for i, label := range s.Labels {
+			bars[i] = chart.Value{Label: label, Value: s.Series[0].Values[i]}
+		}
+		bar := chart.BarChart{
+			Title:    s.Title,
+			Width:    chartWidth,
+			Height:   chartHeight,
+			BarWidth: max(8, (chartWidth-100)/len(bars)/2),
+			Background: chart.Style{
+				Padding: chart.Box{Top: 40, Bottom: 20},
+			},
+			YAxis: chart.YAxis{Name: s.YLabel, Range: s.yRange()},
+			Bars:  bars,
+		}
+		return bar.Render(chart.PNG, buf)
+	}
+
+	xs := make([]float64, len(s.Labels))
+	ticks := make([]chart.Tick, len(s.Labels))
+	// Label at most about 20 ticks so they stay readable.
+	step := max(1, len(s.Labels)/20)
+	for i, label := range s.Labels {
+		xs[i] = float64(i)
+		if i%step == 0 {
+			ticks[i] = chart.Tick{Value: float64(i), Label: label}
+		} else {
+			ticks[i] = chart.Tick{Value: float64(i)}
+		}
+	}
+	graph := chart.Chart{
+		Title:  s.Title,
+		Width:  chartWidth,
+		Height: chartHeight,
+		Background: chart.Style{
+			Padding: chart.Box{Top: 40, Left: 20, Right: 20, Bottom: 20},
+		},
+		XAxis: chart.XAxis{Name: s.XLabel, Ticks: ticks},
+		YAxis: chart.YAxis{Name: s.YLabel, Range: s.yRange()},
+	}
+	for _, ser := range s.Series {
+		graph.Series = append(graph.Series, chart.ContinuousSeries{Name: ser.Name, XValues: xs, YValues: ser.Values})
+	}
+	if len(s.Series) > 1 {
+		graph.Elements = []chart.Renderable{chart.Legend(&graph)}
+	}
+	return graph.Render(chart.PNG, buf)
+}
@@ -0,0 +1,58 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRenderChart(t *testing.T) {
+	workspace := t.TempDir()
+	tool := NewRenderChartTool(workspace)
+	days := []any{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+	for _, params := range []map[string]any{
+		{"type": "line", "title": "Screen time", "labels": days, "name": "screen time/week", "y_label": "hours",
+			"series": []any{
+				map[string]any{"name": "Phone", "values": []any{2.5, 3.0, 1.5, 4.0, 2.0, 5.5, 6.0}},
+				map[string]any{"name": "Laptop", "values": []any{6.0, 7.0, 6.5, 8.0, 5.0, 1.0, 0.5}},
+			}},
+		{"type": "bar", "labels": days, "series": []any{map[string]any{"values": []any{1, 2, 3, 4, 5, 6, 7}}}},
+		{"type": "pie", "labels": []any{"Work", "Social"}, "series": []any{map[string]any{"values": []any{3, 1}}}},
+	} {
+		out, _ := tool.Execute(context.Background(), params)
+		rel := regexp.MustCompile(`media/charts/\S+\.png`).FindString(out)
+		if rel == "" {
+			t.Fatalf("%s: %s", params["type"], out)
+		}
+		data, err := os.ReadFile(filepath.Join(workspace, rel))
+		if err != nil || !bytes.HasPrefix(data, []byte("\x89PNG")) {
+			t.Errorf("%s: %s is not a PNG: %v", params["type"], rel, err)
+		}
+		if params["name"] != nil && !strings.HasPrefix(filepath.Base(rel), "screen-time-week-") {
+			t.Errorf("file name %s not taken from name", rel)
+		}
+	}
+}
+
+func TestRenderChartValidates(t *testing.T) {
+	tool := NewRenderChartTool(t.TempDir())
+	one := []any{map[string]any{"values": []any{1}}}
+	for _, tt := range []struct {
+		params map[string]any
+		want   string
+	}{
+		{map[string]any{"type": "radar", "labels": []any{"a"}, "series": one}, "type must be"},
+		{map[string]any{"type": "line", "labels": []any{"a", "b"}, "series": one}, "1 values for 2 labels"},
+		{map[string]any{"type": "bar", "labels": []any{"a"}, "series": []any{one[0], one[0]}}, "one series"},
+		{map[string]any{"type": "pie", "labels": []any{"a"}, "series": []any{map[string]any{"values": []any{-1}}}}, "negative"},
+		{map[string]any{"type": "line", "labels": []any{"a"}, "series": []any{map[string]any{"values": []any{"x"}}}}, "invalid chart data"},
+	} {
+		if out, _ := tool.Execute(context.Background(), tt.params); !strings.Contains(out, tt.want) {
+			t.Errorf("Execute(%v) = %q, want %q", tt.params, out, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// fileLocks serializes checksum-guarded writes to the same path within the
+// process, so two sessions cannot both pass the check and then overwrite
+// each other.
+var fileLocks sync.Map // Absolute path -> *sync.Mutex
+
+// lockFile locks path until the returned function is called.
+func lockFile(path string) (unlock func()) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	mu, _ := fileLocks.LoadOrStore(path, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// fileSHA256 returns the hex SHA-256 of the file at path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func contentSHA256(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// checkExpectedSHA256 returns an error message if expected is set and the
+// file at path no longer has that checksum, or "" if the write may proceed.
+func checkExpectedSHA256(path, expected string) string {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if expected == "" {
+		return ""
+	}
+	actual, err := fileSHA256(path)
+	if os.IsNotExist(err) {
+		return fmt.Sprintf("Error: %s no longer exists; it was removed after you read it", path)
+	}
+	if err != nil {
+		return fmt.Sprintf("Error reading file: %v", err)
+	}
+	if actual != expected {
+		return changedSinceRead(path, actual)
+	}
+	return ""
+}
+
+func changedSinceRead(path, actual string) string {
+	return fmt.Sprintf("Error: %s changed since it was read (sha256 is now %s). Read it again and reapply your change.", path, actual)
+}
+
+// expectedSHA256Param is the shared schema of the expected_sha256 parameter.
+var expectedSHA256Param = map[string]any{
+	"type":        "string",
+	"description": "SHA-256 of the file as you last read it (from read_file with include_sha256). The write is refused if the file changed since.",
+}
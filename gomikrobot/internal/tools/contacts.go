@@ -0,0 +1,159 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+type senderKey struct{}
+
+// WithSender attaches the sender of the message being processed to ctx.
+func WithSender(ctx context.Context, senderID string) context.Context {
+	return context.WithValue(ctx, senderKey{}, senderID)
+}
+
+// SenderFrom returns the sender attached to ctx, or "".
+func SenderFrom(ctx context.Context) string {
+	id, _ := ctx.Value(senderKey{}).(string)
+	return id
+}
+
+// ContactStore persists contact profiles.
+type ContactStore interface {
+	GetContact(senderID string) (*timeline.Contact, error)
+	SaveContact(c *timeline.Contact) error
+	ListContacts(tag string) ([]timeline.Contact, error)
+}
+
+// ContactTool reads and updates the profile of the person the bot is
+// talking to. Admin chats may also manage other contacts.
+type ContactTool struct {
+	store  ContactStore
+	admins []string
+}
+
+// NewContactTool creates a contact tool backed by store.
+func NewContactTool(store ContactStore, admins []string) *ContactTool {
+	return &ContactTool{store: store, admins: admins}
+}
+
+func (t *ContactTool) Name() string { return "contact" }
+
+func (t *ContactTool) Description() string {
+	return "Read or update what you know about the person you are talking to: name, tags, notes, preferred language. " +
+		"Use add_note to remember lasting facts or preferences they tell you. " +
+		"Admin chats may pass sender_id to manage other contacts, list contacts, or set authorized."
+}
+
+func (t *ContactTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"get", "update", "list"},
+				"description": "get or update a contact, or list contacts (admin)",
+			},
+			"sender_id": map[string]any{
+				"type":        "string",
+				"description": "Contact to manage (admin only, default: current sender)",
+			},
+			"name":     map[string]any{"type": "string", "description": "Display name (update)"},
+			"tags":     map[string]any{"type": "string", "description": "Comma-separated tags, replacing the current ones (update); tag filter (list)"},
+			"notes":    map[string]any{"type": "string", "description": "Replace all notes (update)"},
+			"add_note": map[string]any{"type": "string", "description": "Append one note line (update)"},
+			"language": map[string]any{"type": "string", "description": "Preferred reply language (update)"},
+			"authorized": map[string]any{
+				"type":        "boolean",
+				"description": "Allow this contact to talk to the bot (update, admin only)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ContactTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	admin := IsAdmin(t.admins, PolicySessionFrom(ctx))
+	action := GetString(params, "action", "")
+
+	if action == "list" {
+		if !admin {
+			return "Error: listing contacts is only available in admin chats", nil
+		}
+		contacts, err := t.store.ListContacts(GetString(params, "tags", ""))
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		if len(contacts) == 0 {
+			return "No contacts.", nil
+		}
+		var sb strings.Builder
+		for _, c := range contacts {
+			fmt.Fprintf(&sb, "- %s %s", c.SenderID, c.Name)
+			if len(c.Tags) > 0 {
+				fmt.Fprintf(&sb, " [%s]", strings.Join(c.Tags, ", "))
+			}
+			sb.WriteString("\n")
+		}
+		return sb.String(), nil
+	}
+
+	id := SenderFrom(ctx)
+	if other := strings.TrimSpace(GetString(params, "sender_id", "")); other != "" && other != id {
+		if !admin {
+			return "Error: you can only manage the contact you are talking to", nil
+		}
+		id = other
+	}
+	if id == "" {
+		return "Error: no sender to look up; pass sender_id", nil
+	}
+
+	c, err := t.store.GetContact(id)
+	if errors.Is(err, timeline.ErrContactNotFound) {
+		c, err = &timeline.Contact{SenderID: id}, nil
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	switch action {
+	case "get":
+		if c.CreatedAt.IsZero() {
+			return fmt.Sprintf("No profile for %s yet.", id), nil
+		}
+		return fmt.Sprintf("%s\nAuthorized: %v", c.Profile(), c.Authorized), nil
+	case "update":
+		if _, ok := params["authorized"]; ok {
+			if !admin {
+				return "Error: only admin chats can change authorization", nil
+			}
+			c.Authorized = GetBool(params, "authorized", c.Authorized)
+		}
+		if v, ok := params["name"].(string); ok {
+			c.Name = strings.TrimSpace(v)
+		}
+		if v, ok := params["tags"].(string); ok {
+			c.Tags = strings.Split(v, ",")
+		}
+		if v, ok := params["notes"].(string); ok {
+			c.Notes = strings.TrimSpace(v)
+		}
+		if v := strings.TrimSpace(GetString(params, "add_note", "")); v != "" {
+			c.Notes = strings.TrimSpace(c.Notes + "\n- " + v)
+		}
+		if v, ok := params["language"].(string); ok {
+			c.Language = strings.TrimSpace(v)
+		}
+		if err := t.store.SaveContact(c); err != nil {
+			return fmt.Sprintf("Error: failed to save contact: %v", err), nil
+		}
+		return fmt.Sprintf("Saved contact %s.\n%s", id, c.Profile()), nil
+	default:
+		return "Error: action must be get, update, or list", nil
+	}
+}
@@ -0,0 +1,256 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// desktopTimeout bounds each helper program; they should return at once.
+const desktopTimeout = 10 * time.Second
+
+// DesktopTools returns the notify, clipboard, and open tools for the local
+// desktop. They shell out to osascript, pbcopy, and open on macOS and to
+// notify-send, wl-clipboard or xclip/xsel, and xdg-open on Linux.
+func DesktopTools() []Tool {
+	return []Tool{&NotifyTool{goos: runtime.GOOS}, &ClipboardTool{goos: runtime.GOOS}, &OpenTool{goos: runtime.GOOS}}
+}
+
+// runDesktop runs a helper program with optional stdin and returns its
+// output.
+func runDesktop(ctx context.Context, argv []string, stdin string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, desktopTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("%s: %s", argv[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("%s: %v", argv[0], err)
+	}
+	return string(out), nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// NotifyTool shows a system notification.
+type NotifyTool struct {
+	goos string
+}
+
+func (t *NotifyTool) Name() string { return "notify" }
+
+func (t *NotifyTool) Description() string {
+	return "Show a desktop notification on the computer running the bot."
+}
+
+func (t *NotifyTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"title":   map[string]any{"type": "string", "description": "Notification title"},
+			"message": map[string]any{"type": "string", "description": "Notification body"},
+		},
+		"required": []string{"message"},
+	}
+}
+
+func (t *NotifyTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	title := GetString(params, "title", "GoMikroBot")
+	message := GetString(params, "message", "")
+	if message == "" {
+		return "Error: message is required", nil
+	}
+	argv, err := notifyCommand(t.goos, title, message)
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	if _, err := runDesktop(ctx, argv, ""); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	return "Notification shown.", nil
+}
+
+func notifyCommand(goos, title, message string) ([]string, error) {
+	switch goos {
+	case "darwin":
+		return []string{"osascript", "-e", "display notification " + appleScriptString(message) + " with title " + appleScriptString(title)}, nil
+	case "linux":
+		return []string{"notify-send", "--", title, message}, nil
+	}
+	return nil, fmt.Errorf("notifications are not supported on %s", goos)
+}
+
+// ClipboardTool reads or replaces the clipboard contents.
+type ClipboardTool struct {
+	goos string
+}
+
+func (t *ClipboardTool) Name() string { return "clipboard" }
+
+func (t *ClipboardTool) Description() string {
+	return "Read the clipboard of the computer running the bot, or copy text to it."
+}
+
+func (t *ClipboardTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type": "string",
+				"enum": []string{"read", "write"},
+			},
+			"text": map[string]any{
+				"type":        "string",
+				"description": "Text to copy (write)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ClipboardTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	action := GetString(params, "action", "")
+	if action != "read" && action != "write" {
+		return "Error: action must be read or write", nil
+	}
+	argv, err := clipboardCommand(t.goos, action == "write")
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+
+	if action == "read" {
+		out, err := runDesktop(ctx, argv, "")
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		if out == "" {
+			return "(clipboard is empty)", nil
+		}
+		return out, nil
+	}
+	text := GetString(params, "text", "")
+	if text == "" {
+		return "Error: text is required", nil
+	}
+	if _, err := runDesktop(ctx, argv, text); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	return fmt.Sprintf("Copied %d characters to the clipboard.", len([]rune(text))), nil
+}
+
+// clipboardCommand picks the clipboard program available on goos.
+func clipboardCommand(goos string, write bool) ([]string, error) {
+	switch goos {
+	case "darwin":
+		if write {
+			return []string{"pbcopy"}, nil
+		}
+		return []string{"pbpaste"}, nil
+	case "linux":
+		var candidates [][]string
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			if write {
+				candidates = append(candidates, []string{"wl-copy"})
+			} else {
+				candidates = append(candidates, []string{"wl-paste", "--no-newline"})
+			}
+		}
+		if write {
+			candidates = append(candidates, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"})
+		} else {
+			candidates = append(candidates, []string{"xclip", "-selection", "clipboard", "-o"}, []string{"xsel", "--clipboard", "--output"})
+		}
+		for _, c := range candidates {
+			if _, err := exec.LookPath(c[0]); err == nil {
+				return c, nil
+			}
+		}
+		return nil, fmt.Errorf("no clipboard program found; install wl-clipboard, xclip, or xsel")
+	}
+	return nil, fmt.Errorf("the clipboard is not supported on %s", goos)
+}
+
+// OpenTool opens a URL in the browser or a file in its default application.
+type OpenTool struct {
+	goos string
+}
+
+func (t *OpenTool) Name() string { return "open" }
+
+func (t *OpenTool) Description() string {
+	return "Open a web page in the browser, or a file in its default application, on the computer running the bot."
+}
+
+func (t *OpenTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"target": map[string]any{
+				"type":        "string",
+				"description": "An http(s) or mailto URL, or a file path",
+			},
+		},
+		"required": []string{"target"},
+	}
+}
+
+func (t *OpenTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	target, err := openTarget(GetString(params, "target", ""))
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	var argv []string
+	switch t.goos {
+	case "darwin":
+		argv = []string{"open", target}
+	case "linux":
+		argv = []string{"xdg-open", target}
+	default:
+		return fmt.Sprintf("Error: opening is not supported on %s", t.goos), nil
+	}
+	if _, err := runDesktop(ctx, argv, ""); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	return "Opened " + target, nil
+}
+
+// openTarget accepts web and mail URLs and existing files; other schemes
+// could launch arbitrary handlers.
+func openTarget(target string) (string, error) {
+	if target == "" {
+		return "", fmt.Errorf("target is required")
+	}
+	if u, err := url.Parse(target); err == nil && u.Scheme != "" && len(u.Scheme) > 1 {
+		switch strings.ToLower(u.Scheme) {
+		case "http", "https", "mailto":
+			return target, nil
+		}
+		return "", fmt.Errorf("only http, https, and mailto URLs can be opened")
+	}
+	if strings.HasPrefix(target, "~") {
+		home, _ := os.UserHomeDir()
+		target = filepath.Join(home, target[1:])
+	}
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(abs); err != nil {
+		return "", fmt.Errorf("file not found: %s", target)
+	}
+	return abs, nil
+}
@@ -0,0 +1,35 @@
+package tools
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNotifyCommandQuotesAppleScript(t *testing.T) {
+	got, err := notifyCommand("darwin", `Say "hi"`, `a\b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"osascript", "-e", `display notification "a\\b" with title "Say \"hi\""`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if _, err := notifyCommand("windows", "t", "m"); err == nil {
+		t.Error("expected unsupported platform error")
+	}
+}
+
+func TestOpenTarget(t *testing.T) {
+	dir := t.TempDir()
+	for _, ok := range []string{"https://example.com/a?b=c", "mailto:me@example.com", dir} {
+		if _, err := openTarget(ok); err != nil {
+			t.Errorf("%q: unexpected error %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "javascript:alert(1)", "smb://host/share", filepath.Join(dir, "missing.txt")} {
+		if _, err := openTarget(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
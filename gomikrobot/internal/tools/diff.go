@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is the number of unchanged lines shown around a change.
+	diffContext = 2
+	// maxDiffCells bounds the LCS table; larger inputs are shown as a
+	// whole-file replacement.
+	maxDiffCells = 4_000_000
+)
+
+// lineDiff returns a unified-style diff of old and new, or "" if they are
+// equal.
+func lineDiff(oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	a := splitLines(oldText)
+	b := splitLines(newText)
+	if len(a)*len(b) > maxDiffCells {
+		var sb strings.Builder
+		for _, l := range a {
+			sb.WriteString("-" + l + "\n")
+		}
+		for _, l := range b {
+			sb.WriteString("+" + l + "\n")
+		}
+		return sb.String()
+	}
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type op struct {
+		kind byte
+		text string
+		line int // position in the old text
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, op{' ', a[i], i})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			ops = append(ops, op{'+', b[j], i})
+			j++
+		default:
+			ops = append(ops, op{'-', a[i], i})
+			i++
+		}
+	}
+
+	// Keep changed lines plus context; mark skipped runs.
+	keep := make([]bool, len(ops))
+	for k, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		for c := max(0, k-diffContext); c <= min(len(ops)-1, k+diffContext); c++ {
+			keep[c] = true
+		}
+	}
+	var sb strings.Builder
+	skipped := false
+	for k, o := range ops {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped || k == 0 {
+			fmt.Fprintf(&sb, "@@ line %d @@\n", o.line+1)
+			skipped = false
+		}
+		sb.WriteByte(o.kind)
+		sb.WriteString(o.text)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
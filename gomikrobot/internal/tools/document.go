@@ -0,0 +1,461 @@
+package tools
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// documentChunkChars is the amount of extracted text returned per call.
+const documentChunkChars = 12000
+
+// ReadDocumentTool extracts text from PDFs and office documents, such as
+// attachments saved under the workspace media directory.
+type ReadDocumentTool struct {
+	workspace string
+	// pdftotext is the poppler binary used for PDFs.
+	pdftotext string
+}
+
+// NewReadDocumentTool creates a read_document tool. Relative paths resolve
+// against workspace.
+func NewReadDocumentTool(workspace string) *ReadDocumentTool {
+	return &ReadDocumentTool{workspace: workspace, pdftotext: "pdftotext"}
+}
+
+func (t *ReadDocumentTool) Name() string { return "read_document" }
+
+func (t *ReadDocumentTool) Description() string {
+	return "Extract the text of a document (PDF, DOCX, ODT, XLSX, PPTX, or plain text) with page markers. " +
+		"Attachments are saved under media/documents in the workspace. Long documents are returned in chunks; pass chunk to continue."
+}
+
+func (t *ReadDocumentTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path to the document, absolute or relative to the workspace",
+			},
+			"chunk": map[string]any{
+				"type":        "integer",
+				"description": "Chunk number to return, starting at 1 (default 1)",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *ReadDocumentTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	path := GetString(params, "path", "")
+	if path == "" {
+		return "Error: path is required", nil
+	}
+	path = t.resolve(path)
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("Error: file not found: %s", path), nil
+		}
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	pages, err := t.extract(ctx, path)
+	if err != nil {
+		return fmt.Sprintf("Error reading document: %v", err), nil
+	}
+
+	text := joinPages(pages)
+	if text == "" {
+		return fmt.Sprintf("No text found in %s (it may be a scanned image).", filepath.Base(path)), nil
+	}
+
+	chunks := chunkText(text, documentChunkChars)
+	n := GetInt(params, "chunk", 1)
+	if n < 1 || n > len(chunks) {
+		return fmt.Sprintf("Error: chunk must be between 1 and %d", len(chunks)), nil
+	}
+	header := fmt.Sprintf("%s (%d pages)", filepath.Base(path), len(pages))
+	if len(chunks) > 1 {
+		header += fmt.Sprintf(" [chunk %d/%d]", n, len(chunks))
+	}
+	out := header + "\n\n" + chunks[n-1]
+	if n < len(chunks) {
+		out += fmt.Sprintf("\n\n[Continue with chunk=%d]", n+1)
+	}
+	return out, nil
+}
+
+func (t *ReadDocumentTool) resolve(path string) string {
+	if strings.HasPrefix(path, "~") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, path[1:])
+	}
+	if filepath.IsAbs(path) || t.workspace == "" {
+		return path
+	}
+	ws := t.workspace
+	if strings.HasPrefix(ws, "~") {
+		home, _ := os.UserHomeDir()
+		ws = filepath.Join(home, ws[1:])
+	}
+	return filepath.Join(ws, path)
+}
+
+// ExtractDocumentText returns the text of the document at path with page
+// markers, as read_document shows it.
+func ExtractDocumentText(ctx context.Context, path string) (string, error) {
+	pages, err := NewReadDocumentTool("").extract(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return joinPages(pages), nil
+}
+
+// IsDocument reports whether path has an extension read_document supports.
+func IsDocument(path string) bool {
+	return slices.Contains(documentExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+var documentExtensions = []string{".pdf", ".docx", ".odt", ".odp", ".ods", ".pptx", ".xlsx",
+	".txt", ".md", ".csv", ".tsv", ".json", ".xml", ".html", ".htm", ".log"}
+
+// joinPages joins extracted pages, marking page starts if there are several.
+func joinPages(pages []string) string {
+	var sb strings.Builder
+	for i, p := range pages {
+		if len(pages) > 1 {
+			fmt.Fprintf(&sb, "--- Page %d ---\n", i+1)
+		}
+		sb.WriteString(strings.TrimSpace(p))
+		sb.WriteString("\n\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// extract returns the document text split into pages (or slides/sheets).
+func (t *ReadDocumentTool) extract(ctx context.Context, path string) ([]string, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".pdf":
+		return t.extractPDF(ctx, path)
+	case ".docx":
+		return extractZipXML(path, "word/document.xml", "p", "t")
+	case ".odt", ".odp", ".ods":
+		return extractZipXML(path, "content.xml", "p|h", "")
+	case ".pptx":
+		return extractSlides(path)
+	case ".xlsx":
+		return extractSheets(path)
+	case ".txt", ".md", ".csv", ".tsv", ".json", ".xml", ".html", ".htm", ".log":
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return []string{string(data)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q (supported: pdf, docx, odt, xlsx, pptx, text)", ext)
+	}
+}
+
+// extractPDF runs pdftotext, which separates pages with form feeds.
+func (t *ReadDocumentTool) extractPDF(ctx context.Context, path string) ([]string, error) {
+	bin, err := exec.LookPath(t.pdftotext)
+	if err != nil {
+		return nil, fmt.Errorf("pdftotext not found; install poppler (brew install poppler) to read PDFs")
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, bin, "-layout", "-enc", "UTF-8", path, "-")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("pdftotext failed: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	pages := strings.Split(string(out), "\f")
+	if len(pages) > 1 && strings.TrimSpace(pages[len(pages)-1]) == "" {
+		pages = pages[:len(pages)-1]
+	}
+	return pages, nil
+}
+
+// extractZipXML reads one XML part of an office archive.
+func extractZipXML(path, part, para, text string) ([]string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name == part {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			return xmlText(rc, para, text)
+		}
+	}
+	return nil, fmt.Errorf("%s not found in archive", part)
+}
+
+// xmlText collects the text of paragraph elements (names separated by "|"),
+// one per line. If text is set, only character data inside that element is
+// kept (e.g. w:t in DOCX). Explicit page breaks start a new page.
+func xmlText(r io.Reader, para, text string) ([]string, error) {
+	paras := strings.Split(para, "|")
+	dec := xml.NewDecoder(r)
+	var pages []string
+	var page, line strings.Builder
+	inText := text == ""
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse xml: %w", err)
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case text:
+				inText = true
+			case "tab":
+				line.WriteByte('\t')
+			case "br":
+				if xmlAttr(el, "type") == "page" {
+					page.WriteString(line.String())
+					line.Reset()
+					pages = append(pages, page.String())
+					page.Reset()
+				} else {
+					line.WriteByte('\n')
+				}
+			}
+			if slices.Contains(paras, el.Name.Local) {
+				depth++
+			}
+		case xml.EndElement:
+			if el.Name.Local == text {
+				inText = false
+			}
+			if slices.Contains(paras, el.Name.Local) {
+				depth--
+				if depth == 0 {
+					page.WriteString(line.String())
+					page.WriteByte('\n')
+					line.Reset()
+				}
+			}
+		case xml.CharData:
+			if depth > 0 && inText {
+				line.Write(el)
+			}
+		}
+	}
+	page.WriteString(line.String())
+	return append(pages, page.String()), nil
+}
+
+func xmlAttr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// extractSlides returns one page per slide of a PPTX file.
+func extractSlides(path string) ([]string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer zr.Close()
+
+	slides := numberedParts(zr.File, "ppt/slides/slide", ".xml")
+	pages := make([]string, 0, len(slides))
+	for _, f := range slides {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		p, err := xmlText(rc, "p", "t")
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, strings.Join(p, "\n"))
+	}
+	return pages, nil
+}
+
+// extractSheets returns one page per worksheet of an XLSX file, with cells
+// separated by tabs.
+func extractSheets(path string) ([]string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	defer zr.Close()
+
+	var shared []string
+	for _, f := range zr.File {
+		if f.Name == "xl/sharedStrings.xml" {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			shared, err = sharedStrings(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var pages []string
+	for _, f := range numberedParts(zr.File, "xl/worksheets/sheet", ".xml") {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		p, err := sheetText(rc, shared)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		pages = append(pages, p)
+	}
+	return pages, nil
+}
+
+// sharedStrings parses the XLSX string table. Rich text entries are split
+// into runs, which are joined.
+func sharedStrings(r io.Reader) ([]string, error) {
+	var sst struct {
+		Items []struct {
+			T    string `xml:"t"`
+			Runs []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"si"`
+	}
+	if err := xml.NewDecoder(r).Decode(&sst); err != nil {
+		return nil, fmt.Errorf("parse shared strings: %w", err)
+	}
+	out := make([]string, len(sst.Items))
+	for i, si := range sst.Items {
+		out[i] = si.T
+		for _, run := range si.Runs {
+			out[i] += run.T
+		}
+	}
+	return out, nil
+}
+
+func sheetText(r io.Reader, shared []string) (string, error) {
+	dec := xml.NewDecoder(r)
+	var sb strings.Builder
+	var cells []string
+	var cellType string
+	var inValue bool
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("parse xml: %w", err)
+		}
+		switch el := tok.(type) {
+		case xml.StartElement:
+			switch el.Name.Local {
+			case "row":
+				cells = cells[:0]
+			case "c":
+				cellType = xmlAttr(el, "t")
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch el.Name.Local {
+			case "row":
+				sb.WriteString(strings.Join(cells, "\t"))
+				sb.WriteByte('\n')
+			case "v", "t":
+				inValue = false
+			}
+		case xml.CharData:
+			if !inValue {
+				continue
+			}
+			v := string(el)
+			if cellType == "s" {
+				if i, err := strconv.Atoi(v); err == nil && i >= 0 && i < len(shared) {
+					v = shared[i]
+				}
+			}
+			cells = append(cells, v)
+		}
+	}
+	return sb.String(), nil
+}
+
+// numberedParts returns archive entries named prefix<N>suffix in numeric order.
+func numberedParts(files []*zip.File, prefix, suffix string) []*zip.File {
+	type part struct {
+		n int
+		f *zip.File
+	}
+	var parts []part
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, prefix) || !strings.HasSuffix(f.Name, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(f.Name, prefix), suffix))
+		if err != nil {
+			continue
+		}
+		parts = append(parts, part{n, f})
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].n < parts[j].n })
+	out := make([]*zip.File, len(parts))
+	for i, p := range parts {
+		out[i] = p.f
+	}
+	return out
+}
+
+// chunkText splits s into pieces of at most size bytes, preferring to break
+// at page markers or blank lines.
+func chunkText(s string, size int) []string {
+	var chunks []string
+	for len(s) > size {
+		cut := strings.LastIndex(s[:size], "\n--- Page ")
+		if cut <= size/2 {
+			cut = strings.LastIndex(s[:size], "\n\n")
+		}
+		if cut <= size/2 {
+			cut = size
+			for cut > 0 && !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+		}
+		chunks = append(chunks, strings.TrimSpace(s[:cut]))
+		s = s[cut:]
+	}
+	return append(chunks, strings.TrimSpace(s))
+}
@@ -1,10 +1,13 @@
 package tools
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -14,7 +17,8 @@ type ReadFileTool struct{}
 func (t *ReadFileTool) Name() string { return "read_file" }
 
 func (t *ReadFileTool) Description() string {
-	return "Read the contents of a file at the specified path."
+	return "Read the contents of a file at the specified path. For large files and logs, read a line range " +
+		"with start_line/end_line or only the first or last lines with head/tail; these return numbered lines."
 }
 
 func (t *ReadFileTool) Parameters() map[string]any {
@@ -25,6 +29,26 @@ func (t *ReadFileTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "The path to the file to read",
 			},
+			"start_line": map[string]any{
+				"type":        "integer",
+				"description": "First line to read, starting at 1",
+			},
+			"end_line": map[string]any{
+				"type":        "integer",
+				"description": "Last line to read (inclusive); defaults to start_line + 199",
+			},
+			"head": map[string]any{
+				"type":        "integer",
+				"description": "Read only the first N lines",
+			},
+			"tail": map[string]any{
+				"type":        "integer",
+				"description": "Read only the last N lines",
+			},
+			"include_sha256": map[string]any{
+				"type":        "boolean",
+				"description": "Also return the file's SHA-256, to pass as expected_sha256 when editing it",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -42,7 +66,36 @@ func (t *ReadFileTool) Execute(ctx context.Context, params map[string]any) (stri
 		path = filepath.Join(home, path[1:])
 	}
 
-	content, err := os.ReadFile(path)
+	start, end := GetInt(params, "start_line", 0), GetInt(params, "end_line", 0)
+	head, tail := GetInt(params, "head", 0), GetInt(params, "tail", 0)
+	if start < 0 || end < 0 || head < 0 || tail < 0 {
+		return "Error: line numbers and counts must be positive", nil
+	}
+	if (head > 0 || tail > 0) && (start > 0 || end > 0) || head > 0 && tail > 0 {
+		return "Error: use either start_line/end_line, head, or tail", nil
+	}
+
+	var content string
+	var err error
+	switch {
+	case head > 0:
+		content, err = readLineRange(path, 1, head, 0)
+	case tail > 0:
+		content, err = readLineRange(path, 0, 0, tail)
+	case start > 0 || end > 0:
+		start = max(start, 1)
+		if end == 0 {
+			end = start + defaultLineRange - 1
+		}
+		if end < start {
+			return "Error: end_line must not be before start_line", nil
+		}
+		content, err = readLineRange(path, start, end, 0)
+	default:
+		var data []byte
+		data, err = os.ReadFile(path)
+		content = string(data)
+	}
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Sprintf("Error: file not found: %s", path), nil
@@ -53,7 +106,68 @@ func (t *ReadFileTool) Execute(ctx context.Context, params map[string]any) (stri
 		return fmt.Sprintf("Error reading file: %v", err), nil
 	}
 
-	return string(content), nil
+	if GetBool(params, "include_sha256", false) {
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return fmt.Sprintf("Error reading file: %v", err), nil
+		}
+		content = fmt.Sprintf("[sha256 %s]\n%s", sum, content)
+	}
+	return content, nil
+}
+
+// defaultLineRange is how many lines read_file returns when only
+// start_line is given.
+const defaultLineRange = 200
+
+// readLineRange returns lines start..end (1-based, inclusive) of path, or
+// its last tail lines if tail > 0, prefixed with line numbers and a header
+// giving the total line count. The file is streamed, not loaded whole.
+func readLineRange(path string, start, end, tail int) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	type line struct {
+		n    int
+		text string
+	}
+	var lines []line
+	r := bufio.NewReader(f)
+	total := 0
+	for {
+		text, err := r.ReadString('\n')
+		if text == "" && err != nil {
+			if err == io.EOF {
+				break
+			}
+			return "", err
+		}
+		total++
+		text = strings.TrimRight(text, "\r\n")
+		switch {
+		case tail > 0:
+			lines = append(lines, line{total, text})
+			if len(lines) > tail {
+				lines = lines[1:]
+			}
+		case total >= start && total <= end:
+			lines = append(lines, line{total, text})
+		}
+	}
+
+	if len(lines) == 0 {
+		return fmt.Sprintf("[No lines in range; the file has %d lines]", total), nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[Lines %d–%d of %d]\n", lines[0].n, lines[len(lines)-1].n, total)
+	width := len(strconv.Itoa(lines[len(lines)-1].n))
+	for _, l := range lines {
+		fmt.Fprintf(&sb, "%*d| %s\n", width, l.n, l.text)
+	}
+	return sb.String(), nil
 }
 
 // WriteFileTool writes content to a file.
@@ -62,7 +176,8 @@ type WriteFileTool struct{}
 func (t *WriteFileTool) Name() string { return "write_file" }
 
 func (t *WriteFileTool) Description() string {
-	return "Write content to a file at the specified path. Creates parent directories if needed."
+	return "Write content to a file at the specified path. Creates parent directories if needed. " +
+		"When overwriting a file you read earlier, pass expected_sha256 so concurrent changes are not lost."
 }
 
 func (t *WriteFileTool) Parameters() map[string]any {
@@ -77,6 +192,7 @@ func (t *WriteFileTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "The content to write to the file",
 			},
+			"expected_sha256": expectedSHA256Param,
 		},
 		"required": []string{"path", "content"},
 	}
@@ -102,6 +218,12 @@ func (t *WriteFileTool) Execute(ctx context.Context, params map[string]any) (str
 		return fmt.Sprintf("Error creating directory: %v", err), nil
 	}
 
+	unlock := lockFile(path)
+	defer unlock()
+	if msg := checkExpectedSHA256(path, GetString(params, "expected_sha256", "")); msg != "" {
+		return msg, nil
+	}
+
 	// Write files as user-private by default (agents may write sensitive content).
 	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
 		if os.IsPermission(err) {
@@ -109,8 +231,9 @@ func (t *WriteFileTool) Execute(ctx context.Context, params map[string]any) (str
 		}
 		return fmt.Sprintf("Error writing file: %v", err), nil
 	}
+	recordWritten(ctx, path)
 
-	return fmt.Sprintf("Successfully wrote %d bytes to %s", len(content), path), nil
+	return fmt.Sprintf("Successfully wrote %d bytes to %s (sha256 %s)", len(content), path, contentSHA256([]byte(content))), nil
 }
 
 // EditFileTool replaces text in a file.
@@ -119,7 +242,8 @@ type EditFileTool struct{}
 func (t *EditFileTool) Name() string { return "edit_file" }
 
 func (t *EditFileTool) Description() string {
-	return "Edit a file by replacing text. Useful for making targeted changes."
+	return "Edit a file by replacing text. Useful for making targeted changes. " +
+		"Pass expected_sha256 to refuse the edit if the file changed since you read it."
 }
 
 func (t *EditFileTool) Parameters() map[string]any {
@@ -138,6 +262,7 @@ func (t *EditFileTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "The replacement text",
 			},
+			"expected_sha256": expectedSHA256Param,
 		},
 		"required": []string{"path", "old_text", "new_text"},
 	}
@@ -161,6 +286,9 @@ func (t *EditFileTool) Execute(ctx context.Context, params map[string]any) (stri
 		path = filepath.Join(home, path[1:])
 	}
 
+	unlock := lockFile(path)
+	defer unlock()
+
 	content, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -168,6 +296,11 @@ func (t *EditFileTool) Execute(ctx context.Context, params map[string]any) (stri
 		}
 		return fmt.Sprintf("Error reading file: %v", err), nil
 	}
+	if expected := strings.ToLower(strings.TrimSpace(GetString(params, "expected_sha256", ""))); expected != "" {
+		if actual := contentSHA256(content); actual != expected {
+			return changedSinceRead(path, actual), nil
+		}
+	}
 
 	contentStr := string(content)
 	if !strings.Contains(contentStr, oldText) {
@@ -179,8 +312,9 @@ func (t *EditFileTool) Execute(ctx context.Context, params map[string]any) (stri
 	if err := os.WriteFile(path, []byte(newContent), 0644); err != nil {
 		return fmt.Sprintf("Error writing file: %v", err), nil
 	}
+	recordWritten(ctx, path)
 
-	return fmt.Sprintf("Successfully edited %s", path), nil
+	return fmt.Sprintf("Successfully edited %s (sha256 %s)", path, contentSHA256([]byte(newContent))), nil
 }
 
 // ListDirTool lists directory contents.
@@ -0,0 +1,307 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// gitOutputChars caps output returned by the git tools.
+	gitOutputChars = 20000
+	gitTimeout     = 30 * time.Second
+
+	// Commits made by the agent are attributed to it so they stand out in
+	// the log.
+	gitAuthorName  = "GoMikroBot"
+	gitAuthorEmail = "gomikrobot@localhost"
+)
+
+// gitRepo runs git in the active project or the workspace.
+type gitRepo struct {
+	workspace string
+}
+
+func (g gitRepo) dir(ctx context.Context) string {
+	if dir := projectDir(ctx); dir != "" {
+		return dir
+	}
+	ws := g.workspace
+	if strings.HasPrefix(ws, "~") {
+		home, _ := os.UserHomeDir()
+		ws = filepath.Join(home, ws[1:])
+	}
+	return ws
+}
+
+// run executes git with args and returns combined output.
+func (g gitRepo) run(ctx context.Context, env []string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitTimeout)
+	defer cancel()
+
+	dir := g.dir(ctx)
+	cmd := exec.CommandContext(ctx, "git", append([]string{"--no-pager"}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Env = append(cmd.Env, env...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(out.String())
+		if strings.Contains(msg, "not a git repository") {
+			return "", fmt.Errorf("%s is not a git repository; run `git init` there first", dir)
+		}
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	s := out.String()
+	if len(s) > gitOutputChars {
+		s = s[:gitOutputChars] + fmt.Sprintf("\n[output truncated, %d more characters]", len(s)-gitOutputChars)
+	}
+	return s, nil
+}
+
+// gitPaths reads a list of repository-relative paths, rejecting ones that
+// leave the repository.
+func gitPaths(params map[string]any, key string) ([]string, error) {
+	var paths []string
+	switch v := params[key].(type) {
+	case nil:
+	case string:
+		if v != "" {
+			paths = []string{v}
+		}
+	case []any:
+		for _, p := range v {
+			if s, ok := p.(string); ok && s != "" {
+				paths = append(paths, s)
+			}
+		}
+	}
+	for _, p := range paths {
+		if !filepath.IsLocal(p) {
+			return nil, fmt.Errorf("path %q must be relative to the repository", p)
+		}
+	}
+	return paths, nil
+}
+
+// GitStatusTool shows the working tree status.
+type GitStatusTool struct{ gitRepo }
+
+// NewGitStatusTool creates a git_status tool for workspace.
+func NewGitStatusTool(workspace string) *GitStatusTool {
+	return &GitStatusTool{gitRepo{workspace}}
+}
+
+func (t *GitStatusTool) Name() string { return "git_status" }
+
+func (t *GitStatusTool) Description() string {
+	return "Show the branch and changed files of the git repository in the active project or workspace."
+}
+
+func (t *GitStatusTool) Parameters() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+func (t *GitStatusTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	out, err := t.run(ctx, nil, "status", "--short", "--branch")
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	return out, nil
+}
+
+// GitDiffTool shows uncommitted changes.
+type GitDiffTool struct{ gitRepo }
+
+// NewGitDiffTool creates a git_diff tool for workspace.
+func NewGitDiffTool(workspace string) *GitDiffTool {
+	return &GitDiffTool{gitRepo{workspace}}
+}
+
+func (t *GitDiffTool) Name() string { return "git_diff" }
+
+func (t *GitDiffTool) Description() string {
+	return "Show uncommitted changes as a unified diff, optionally limited to paths or to staged changes."
+}
+
+func (t *GitDiffTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"paths": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Limit the diff to these repository-relative paths",
+			},
+			"staged": map[string]any{
+				"type":        "boolean",
+				"description": "Show staged changes instead of unstaged ones",
+			},
+			"stat": map[string]any{
+				"type":        "boolean",
+				"description": "Only show a per-file summary",
+			},
+		},
+	}
+}
+
+func (t *GitDiffTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	paths, err := gitPaths(params, "paths")
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	args := []string{"diff"}
+	if GetBool(params, "staged", false) {
+		args = append(args, "--cached")
+	}
+	if GetBool(params, "stat", false) {
+		args = append(args, "--stat")
+	}
+	args = append(append(args, "--"), paths...)
+	out, err := t.run(ctx, nil, args...)
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	if out == "" {
+		return "(no changes)", nil
+	}
+	return out, nil
+}
+
+// GitLogTool lists recent commits.
+type GitLogTool struct{ gitRepo }
+
+// NewGitLogTool creates a git_log tool for workspace.
+func NewGitLogTool(workspace string) *GitLogTool {
+	return &GitLogTool{gitRepo{workspace}}
+}
+
+func (t *GitLogTool) Name() string { return "git_log" }
+
+func (t *GitLogTool) Description() string {
+	return "List recent commits with hash, date, author, and subject."
+}
+
+func (t *GitLogTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Number of commits (default 10, max 100)",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Only commits touching this repository-relative path",
+			},
+		},
+	}
+}
+
+func (t *GitLogTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	paths, err := gitPaths(params, "path")
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	limit := GetInt(params, "limit", 10)
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	args := []string{"log", fmt.Sprintf("-n%d", limit), "--date=short", "--pretty=format:%h %ad %an: %s", "--"}
+	out, err := t.run(ctx, nil, append(args, paths...)...)
+	if err != nil {
+		if strings.Contains(err.Error(), "does not have any commits") {
+			return "(no commits yet)", nil
+		}
+		return "Error: " + err.Error(), nil
+	}
+	if out == "" {
+		return "(no commits)", nil
+	}
+	return out, nil
+}
+
+// GitCommitTool stages and commits changes.
+type GitCommitTool struct{ gitRepo }
+
+// NewGitCommitTool creates a git_commit tool for workspace.
+func NewGitCommitTool(workspace string) *GitCommitTool {
+	return &GitCommitTool{gitRepo{workspace}}
+}
+
+func (t *GitCommitTool) Name() string { return "git_commit" }
+
+func (t *GitCommitTool) Description() string {
+	return "Stage and commit changes with a message. Commits all changes unless paths are given. " +
+		"Commits are authored by GoMikroBot."
+}
+
+func (t *GitCommitTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"message": map[string]any{
+				"type":        "string",
+				"description": "Commit message describing the change",
+			},
+			"paths": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Repository-relative paths to commit (default: all changes)",
+			},
+		},
+		"required": []string{"message"},
+	}
+}
+
+func (t *GitCommitTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	message := strings.TrimSpace(GetString(params, "message", ""))
+	if message == "" {
+		return "Error: message is required", nil
+	}
+	paths, err := gitPaths(params, "paths")
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+
+	add := []string{"add", "-A", "--"}
+	if len(paths) > 0 {
+		add = append(add, paths...)
+	}
+	if _, err := t.run(ctx, nil, add...); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+
+	// The committer stays the repository owner when configured; otherwise
+	// git would refuse to commit without an identity.
+	env := []string{"GIT_AUTHOR_NAME=" + gitAuthorName, "GIT_AUTHOR_EMAIL=" + gitAuthorEmail}
+	if email, _ := t.run(ctx, nil, "config", "user.email"); strings.TrimSpace(email) == "" {
+		env = append(env, "GIT_COMMITTER_NAME="+gitAuthorName, "GIT_COMMITTER_EMAIL="+gitAuthorEmail)
+	}
+	commit := []string{"commit", "-m", message}
+	if len(paths) > 0 {
+		commit = append(append(commit, "--"), paths...)
+	}
+	if _, err := t.run(ctx, env, commit...); err != nil {
+		if strings.Contains(err.Error(), "nothing to commit") || strings.Contains(err.Error(), "no changes added") {
+			return "Nothing to commit.", nil
+		}
+		return "Error: " + err.Error(), nil
+	}
+
+	out, err := t.run(ctx, nil, "log", "-1", "--stat", "--pretty=format:%h %s")
+	if err != nil {
+		return "Committed.", nil
+	}
+	return "Committed " + out, nil
+}
@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SessionPauser hands conversations between the bot and a human.
+type SessionPauser interface {
+	SetPaused(sessionKey string, paused bool) error
+	PausedSessions() (map[string]time.Time, error)
+}
+
+// HandoffTool lets admins take over another conversation: while paused the
+// bot reads along but does not reply.
+type HandoffTool struct {
+	pauser SessionPauser
+	admins []string
+}
+
+// NewHandoffTool creates a handoff tool for the given admin sessions.
+func NewHandoffTool(pauser SessionPauser, admins []string) *HandoffTool {
+	return &HandoffTool{pauser: pauser, admins: admins}
+}
+
+func (t *HandoffTool) Name() string { return "handoff" }
+
+func (t *HandoffTool) Description() string {
+	return "Take over another conversation as a human (pause: the bot stops replying there), hand it back (resume), " +
+		"or list taken-over conversations. Admin chats only."
+}
+
+func (t *HandoffTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"pause", "resume", "list"},
+				"description": "pause or resume a conversation, or list paused ones",
+			},
+			"session": map[string]any{
+				"type":        "string",
+				"description": "Conversation session key, e.g. whatsapp:4917…@s.whatsapp.net (pause, resume)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *HandoffTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	current := SessionKeyFrom(ctx)
+	if !IsAdmin(t.admins, PolicySessionFrom(ctx)) {
+		return "Error: handoff is only available in admin chats", nil
+	}
+
+	action := GetString(params, "action", "")
+	if action == "list" {
+		paused, err := t.pauser.PausedSessions()
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		if len(paused) == 0 {
+			return "No conversations are taken over.", nil
+		}
+		keys := make([]string, 0, len(paused))
+		for k := range paused {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		var b strings.Builder
+		b.WriteString("Taken over by a human:\n")
+		for _, k := range keys {
+			fmt.Fprintf(&b, "- %s (since %s)\n", k, paused[k].Format(time.DateTime))
+		}
+		return b.String(), nil
+	}
+
+	session := strings.TrimSpace(GetString(params, "session", ""))
+	if !strings.Contains(session, ":") {
+		return "Error: session must be a session key like whatsapp:4917…@s.whatsapp.net", nil
+	}
+	switch action {
+	case "pause":
+		if session == current {
+			return "Error: this conversation can't pause itself; use another admin chat or the dashboard", nil
+		}
+		if err := t.pauser.SetPaused(session, true); err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		return fmt.Sprintf("Took over %s. The bot will log but not answer messages there until resumed.", session), nil
+	case "resume":
+		if err := t.pauser.SetPaused(session, false); err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		return fmt.Sprintf("Handed %s back to the bot.", session), nil
+	default:
+		return "Error: action must be pause, resume, or list", nil
+	}
+}
@@ -0,0 +1,220 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPRequestOptions configures the http_request tool.
+type HTTPRequestOptions struct {
+	// AllowedDomains lists the hosts that may be called. "*.example.com"
+	// also matches subdomains; an entry with a port matches only that port.
+	AllowedDomains []string
+	// MaxResponseBytes caps the body returned to the model.
+	MaxResponseBytes int64
+	Timeout          time.Duration
+	// Headers are added per allowlist entry, so credentials such as a Home
+	// Assistant token never pass through the conversation.
+	Headers map[string]map[string]string
+}
+
+// HTTPRequestTool calls HTTP APIs on allowlisted domains.
+type HTTPRequestTool struct {
+	opts   HTTPRequestOptions
+	client *http.Client
+}
+
+// NewHTTPRequestTool creates an http_request tool.
+func NewHTTPRequestTool(opts HTTPRequestOptions) *HTTPRequestTool {
+	if opts.MaxResponseBytes <= 0 {
+		opts.MaxResponseBytes = 256 << 10
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	t := &HTTPRequestTool{opts: opts}
+	t.client = &http.Client{
+		Timeout: opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			entry := t.match(req.URL)
+			if entry == "" {
+				return fmt.Errorf("redirect to %s is not in the allowlist", req.URL.Host)
+			}
+			// Credentials belong to the entry of the first request; another
+			// allowlisted host must not receive them.
+			if from := t.match(via[0].URL); from != entry {
+				for k := range t.opts.Headers[from] {
+					req.Header.Del(k)
+				}
+			}
+			return nil
+		},
+	}
+	return t
+}
+
+func (t *HTTPRequestTool) Name() string { return "http_request" }
+
+func (t *HTTPRequestTool) Description() string {
+	return "Send a GET or POST request to an allowlisted API and return the status and body. " +
+		"Allowed domains: " + strings.Join(t.opts.AllowedDomains, ", ")
+}
+
+func (t *HTTPRequestTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"url": map[string]any{
+				"type":        "string",
+				"description": "Full http(s) URL on an allowed domain",
+			},
+			"method": map[string]any{
+				"type":        "string",
+				"enum":        []string{"GET", "POST"},
+				"description": "HTTP method (default GET)",
+			},
+			"headers": map[string]any{
+				"type":                 "object",
+				"additionalProperties": map[string]any{"type": "string"},
+				"description":          "Extra request headers",
+			},
+			"body": map[string]any{
+				"description": "Request body for POST; objects and arrays are sent as JSON",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+func (t *HTTPRequestTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	rawURL := GetString(params, "url", "")
+	if rawURL == "" {
+		return "Error: url is required", nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "Error: url must be an absolute http or https URL", nil
+	}
+	entry := t.match(u)
+	if entry == "" {
+		return fmt.Sprintf("Error: %s is not in the allowlist (allowed: %s)", u.Host, strings.Join(t.opts.AllowedDomains, ", ")), nil
+	}
+
+	method := strings.ToUpper(GetString(params, "method", http.MethodGet))
+	if method != http.MethodGet && method != http.MethodPost {
+		return "Error: method must be GET or POST", nil
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch b := params["body"].(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(b)
+		contentType = "text/plain; charset=utf-8"
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return fmt.Sprintf("Error: invalid body: %v", err), nil
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+	if body != nil && method == http.MethodGet {
+		return "Error: GET requests cannot have a body", nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	req.Header.Set("User-Agent", "GoMikroBot")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if h, ok := params["headers"].(map[string]any); ok {
+		for k, v := range h {
+			req.Header.Set(k, fmt.Sprint(v))
+		}
+	}
+	for k, v := range t.opts.Headers[entry] {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Sprintf("Error: request failed: %v", err), nil
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, t.opts.MaxResponseBytes+1))
+	if err != nil {
+		return fmt.Sprintf("Error reading response: %v", err), nil
+	}
+	truncated := int64(len(data)) > t.opts.MaxResponseBytes
+	if truncated {
+		data = data[:t.opts.MaxResponseBytes]
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "HTTP %s\n", resp.Status)
+	ct := resp.Header.Get("Content-Type")
+	if ct != "" {
+		fmt.Fprintf(&sb, "Content-Type: %s\n", ct)
+	}
+	sb.WriteString("\n")
+	if !textual(ct) {
+		fmt.Fprintf(&sb, "[binary body omitted, %d bytes read]", len(data))
+		return sb.String(), nil
+	}
+	sb.Write(data)
+	if truncated {
+		fmt.Fprintf(&sb, "\n\n[response truncated at %d bytes]", t.opts.MaxResponseBytes)
+	}
+	return sb.String(), nil
+}
+
+// match returns the allowlist entry covering u, or "" if none does.
+func (t *HTTPRequestTool) match(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	for _, entry := range t.opts.AllowedDomains {
+		e := strings.ToLower(entry)
+		switch {
+		case strings.Contains(e, ":"):
+			if e == strings.ToLower(u.Host) {
+				return entry
+			}
+		case strings.HasPrefix(e, "*."):
+			if host == e[2:] || strings.HasSuffix(host, e[1:]) {
+				return entry
+			}
+		case host == e:
+			return entry
+		}
+	}
+	return ""
+}
+
+// textual reports whether a content type is safe to show as text.
+func textual(ct string) bool {
+	if ct == "" {
+		return true
+	}
+	ct = strings.ToLower(ct)
+	for _, s := range []string{"text/", "json", "xml", "javascript", "x-www-form-urlencoded", "yaml"} {
+		if strings.Contains(ct, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// identityFiles are the bootstrap files update_identity may change.
+var identityFiles = []string{"SOUL.md", "USER.md", "AGENTS.md"}
+
+// identityProposalTTL is how long a proposal waits for approval.
+const identityProposalTTL = time.Hour
+
+// EventLogger records timeline events.
+type EventLogger interface {
+	AddEvent(event *timeline.TimelineEvent) error
+}
+
+// IsAdmin reports whether sessionKey matches an admin entry. Entries are
+// session keys ("whatsapp:4917…@s.whatsapp.net") or "channel:*".
+func IsAdmin(admins []string, sessionKey string) bool {
+	if sessionKey == "" {
+		return false
+	}
+	for _, a := range admins {
+		if a == sessionKey {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "*"); ok && strings.HasPrefix(sessionKey, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type identityProposal struct {
+	file    string
+	content string
+	reason  string
+	diff    string
+	session string
+	// turn is the agent turn that proposed the change; it cannot also
+	// approve it.
+	turn      uint64
+	createdAt time.Time
+}
+
+// UpdateIdentityTool lets admin sessions change the agent's bootstrap files.
+// Changes are proposed as a diff first and only written once applied.
+type UpdateIdentityTool struct {
+	workspace string
+	admins    []string
+	events    EventLogger
+
+	mu      sync.Mutex
+	pending map[string]*identityProposal
+	seq     int
+}
+
+// NewUpdateIdentityTool creates an update_identity tool. events may be nil.
+func NewUpdateIdentityTool(workspace string, admins []string, events EventLogger) *UpdateIdentityTool {
+	return &UpdateIdentityTool{
+		workspace: workspace,
+		admins:    admins,
+		events:    events,
+		pending:   make(map[string]*identityProposal),
+	}
+}
+
+func (t *UpdateIdentityTool) Name() string { return "update_identity" }
+
+func (t *UpdateIdentityTool) Description() string {
+	return "Propose a change to your own SOUL.md, USER.md, or AGENTS.md. " +
+		"First call with action=propose and the complete new file content; show the returned diff to the user. " +
+		"Only call action=apply with the proposal id after the user explicitly approves it. Admin chats only."
+}
+
+func (t *UpdateIdentityTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"propose", "apply", "discard"},
+				"description": "propose a change, apply an approved proposal, or discard it",
+			},
+			"file": map[string]any{
+				"type":        "string",
+				"enum":        identityFiles,
+				"description": "File to change (propose)",
+			},
+			"content": map[string]any{
+				"type":        "string",
+				"description": "Complete new file content (propose)",
+			},
+			"reason": map[string]any{
+				"type":        "string",
+				"description": "Why the change is needed (propose)",
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Proposal id (apply, discard)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *UpdateIdentityTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	session := SessionKeyFrom(ctx)
+	if !IsAdmin(t.admins, PolicySessionFrom(ctx)) {
+		return "Error: update_identity is only available in admin chats", nil
+	}
+
+	switch GetString(params, "action", "") {
+	case "propose":
+		return t.propose(ctx, session, params), nil
+	case "apply":
+		return t.apply(ctx, session, GetString(params, "id", "")), nil
+	case "discard":
+		if p := t.take(session, GetString(params, "id", "")); p == nil {
+			return "Error: unknown or expired proposal", nil
+		}
+		return "Proposal discarded.", nil
+	default:
+		return "Error: action must be propose, apply, or discard", nil
+	}
+}
+
+func (t *UpdateIdentityTool) propose(ctx context.Context, session string, params map[string]any) string {
+	file := GetString(params, "file", "")
+	if !validIdentityFile(file) {
+		return fmt.Sprintf("Error: file must be one of %s", strings.Join(identityFiles, ", "))
+	}
+	content := GetString(params, "content", "")
+	if strings.TrimSpace(content) == "" {
+		return "Error: content is required and must be the complete new file"
+	}
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	current, err := os.ReadFile(t.path(file))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Sprintf("Error reading %s: %v", file, err)
+	}
+	diff := lineDiff(string(current), content)
+	if diff == "" {
+		return fmt.Sprintf("%s already has this content; nothing to change.", file)
+	}
+
+	t.mu.Lock()
+	t.prune()
+	t.seq++
+	id := fmt.Sprintf("id-%d", t.seq)
+	t.pending[id] = &identityProposal{
+		file:      file,
+		content:   content,
+		reason:    GetString(params, "reason", ""),
+		diff:      diff,
+		session:   session,
+		turn:      TurnFrom(ctx),
+		createdAt: time.Now(),
+	}
+	t.mu.Unlock()
+
+	return fmt.Sprintf("Proposal %s for %s:\n\n%s\nShow this diff to the user and ask for approval. "+
+		"Apply it with id %s only after they agree; it expires in %s.", id, file, diff, id, identityProposalTTL)
+}
+
+func (t *UpdateIdentityTool) apply(ctx context.Context, session, id string) string {
+	// The user's approval arrives as a new message. Applying in the
+	// proposing turn would let injected text, such as a fetched page,
+	// approve its own change.
+	t.mu.Lock()
+	p, ok := t.pending[id]
+	sameTurn := ok && p.session == session && p.turn != 0 && p.turn == TurnFrom(ctx)
+	t.mu.Unlock()
+	if sameTurn {
+		return "Error: a proposal can only be applied after the user approves it in a later message"
+	}
+	p = t.take(session, id)
+	if p == nil {
+		return "Error: unknown or expired proposal"
+	}
+	path := t.path(p.file)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Sprintf("Error creating workspace: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(p.content), 0600); err != nil {
+		return fmt.Sprintf("Error writing %s: %v", p.file, err)
+	}
+
+	if t.events != nil {
+		text := fmt.Sprintf("Updated %s", p.file)
+		if p.reason != "" {
+			text += ": " + p.reason
+		}
+		err := t.events.AddEvent(&timeline.TimelineEvent{
+			EventID:     fmt.Sprintf("identity:%s:%d", p.file, time.Now().UnixNano()),
+			Timestamp:   time.Now(),
+			SenderID:    session,
+			SenderName:  "update_identity",
+			EventType:   "SYSTEM",
+			ContentText: text + "\n\n" + p.diff,
+			Authorized:  true,
+		})
+		if err != nil {
+			return fmt.Sprintf("Updated %s, but recording it in the timeline failed: %v", p.file, err)
+		}
+	}
+	return fmt.Sprintf("Updated %s. The change applies from the next message.", p.file)
+}
+
+// take removes and returns the session's proposal with id.
+func (t *UpdateIdentityTool) take(session, id string) *identityProposal {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune()
+	p, ok := t.pending[id]
+	if !ok || p.session != session {
+		return nil
+	}
+	delete(t.pending, id)
+	return p
+}
+
+// prune drops expired proposals. Callers hold t.mu.
+func (t *UpdateIdentityTool) prune() {
+	for id, p := range t.pending {
+		if time.Since(p.createdAt) > identityProposalTTL {
+			delete(t.pending, id)
+		}
+	}
+}
+
+func (t *UpdateIdentityTool) path(file string) string {
+	ws := t.workspace
+	if strings.HasPrefix(ws, "~") {
+		home, _ := os.UserHomeDir()
+		ws = filepath.Join(home, ws[1:])
+	}
+	return filepath.Join(ws, file)
+}
+
+func validIdentityFile(name string) bool {
+	for _, f := range identityFiles {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
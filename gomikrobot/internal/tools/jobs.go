@@ -0,0 +1,349 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// Limits of background jobs.
+const (
+	jobOutputMax   = 1 << 20 // Output kept in memory per job
+	jobTailBytes   = 4 << 10 // Output persisted with the job state
+	jobPageBytes   = 16000   // Output returned per poll
+	maxRunningJobs = 8
+)
+
+// JobStore persists background job state, e.g. for the dashboard.
+type JobStore interface {
+	SaveJob(j *timeline.Job) error
+}
+
+// ErrJobNotFound is returned for unknown jobs or jobs of another session.
+var ErrJobNotFound = errors.New("job not found")
+
+// JobManager runs shell commands in the background and keeps their output.
+type JobManager struct {
+	store JobStore // May be nil
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// job is one background process. Its output is a bounded buffer; offsets
+// are absolute, so dropped counts the bytes discarded from the front.
+type job struct {
+	mu      sync.Mutex
+	info    timeline.Job
+	out     []byte
+	dropped int
+	stdin   io.WriteCloser
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewJobManager creates a manager that records job state in store, if set.
+func NewJobManager(store JobStore) *JobManager {
+	return &JobManager{store: store, jobs: make(map[string]*job)}
+}
+
+// Start runs the command built by newCmd as a background job of
+// sessionKey. newCmd must use ctx, which is cancelled by Kill.
+func (m *JobManager) Start(sessionKey, command, dir string, newCmd func(ctx context.Context) *exec.Cmd) (*timeline.Job, error) {
+	m.mu.Lock()
+	running := 0
+	for _, j := range m.jobs {
+		if j.snapshot().Status == timeline.JobRunning {
+			running++
+		}
+	}
+	m.mu.Unlock()
+	if running >= maxRunningJobs {
+		return nil, fmt.Errorf("%d jobs are already running; kill one first", running)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := newCmd(ctx)
+	j := &job{cancel: cancel, done: make(chan struct{})}
+	cmd.Stdout = j
+	cmd.Stderr = j
+	cmd.WaitDelay = 2 * time.Second
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	j.stdin = stdin
+	j.info = timeline.Job{
+		ID:         newJobID(),
+		SessionKey: sessionKey,
+		Command:    command,
+		WorkDir:    dir,
+		PID:        cmd.Process.Pid,
+		Status:     timeline.JobRunning,
+		StartedAt:  time.Now(),
+	}
+
+	m.mu.Lock()
+	m.jobs[j.info.ID] = j
+	m.mu.Unlock()
+	m.save(j)
+
+	go func() {
+		err := cmd.Wait()
+		cancel()
+		now := time.Now()
+		j.mu.Lock()
+		if j.info.Status == timeline.JobRunning {
+			j.info.Status = timeline.JobExited
+		}
+		code := cmd.ProcessState.ExitCode()
+		if err != nil && code < 0 {
+			code = -1
+		}
+		j.info.ExitCode = &code
+		j.info.FinishedAt = &now
+		j.mu.Unlock()
+		close(j.done)
+		m.save(j)
+	}()
+
+	info := j.snapshot()
+	return &info, nil
+}
+
+// Write appends process output, dropping the oldest bytes past the limit.
+func (j *job) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.out = append(j.out, p...)
+	if over := len(j.out) - jobOutputMax; over > 0 {
+		j.out = append(j.out[:0], j.out[over:]...)
+		j.dropped += over
+	}
+	return len(p), nil
+}
+
+func (j *job) snapshot() timeline.Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := j.info
+	tail := j.out[max(0, len(j.out)-jobTailBytes):]
+	info.OutputTail = string(tail)
+	return info
+}
+
+func (m *JobManager) save(j *job) {
+	if m.store == nil {
+		return
+	}
+	info := j.snapshot()
+	_ = m.store.SaveJob(&info)
+}
+
+// get returns job id if sessionKey may see it; "" sees all jobs.
+func (m *JobManager) get(sessionKey, id string) (*job, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok || sessionKey != "" && j.snapshot().SessionKey != sessionKey {
+		return nil, ErrJobNotFound
+	}
+	return j, nil
+}
+
+// List returns the jobs of sessionKey ("" for all), newest first.
+func (m *JobManager) List(sessionKey string) []timeline.Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var jobs []timeline.Job
+	for _, j := range m.jobs {
+		if info := j.snapshot(); sessionKey == "" || info.SessionKey == sessionKey {
+			jobs = append(jobs, info)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].StartedAt.After(jobs[b].StartedAt) })
+	return jobs
+}
+
+// Output returns up to jobPageBytes of output starting at offset, the
+// offset to continue from, and the job's state.
+func (m *JobManager) Output(sessionKey, id string, offset int) (string, int, timeline.Job, error) {
+	j, err := m.get(sessionKey, id)
+	if err != nil {
+		return "", 0, timeline.Job{}, err
+	}
+	info := j.snapshot()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	offset = max(offset, j.dropped)
+	start := min(offset-j.dropped, len(j.out))
+	end := min(start+jobPageBytes, len(j.out))
+	return string(j.out[start:end]), j.dropped + end, info, nil
+}
+
+// Input writes text to the job's stdin and optionally closes it.
+func (m *JobManager) Input(sessionKey, id, text string, closeStdin bool) error {
+	j, err := m.get(sessionKey, id)
+	if err != nil {
+		return err
+	}
+	if j.snapshot().Status != timeline.JobRunning {
+		return fmt.Errorf("job %s is not running", id)
+	}
+	if text != "" {
+		if _, err := io.WriteString(j.stdin, text); err != nil {
+			return err
+		}
+	}
+	if closeStdin {
+		return j.stdin.Close()
+	}
+	return nil
+}
+
+// Kill stops a running job and waits briefly for it to exit.
+func (m *JobManager) Kill(sessionKey, id string) error {
+	j, err := m.get(sessionKey, id)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	if j.info.Status != timeline.JobRunning {
+		j.mu.Unlock()
+		return fmt.Errorf("job %s is not running", id)
+	}
+	j.info.Status = timeline.JobKilled
+	j.mu.Unlock()
+	j.cancel()
+	select {
+	case <-j.done:
+	case <-time.After(5 * time.Second):
+	}
+	return nil
+}
+
+// KillAll stops every running job, e.g. on shutdown.
+func (m *JobManager) KillAll() {
+	for _, info := range m.List("") {
+		if info.Status == timeline.JobRunning {
+			_ = m.Kill("", info.ID)
+		}
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// JobTool inspects and controls background jobs started by exec.
+type JobTool struct {
+	jobs *JobManager
+}
+
+// NewJobTool creates a job tool for the jobs of manager.
+func NewJobTool(jobs *JobManager) *JobTool {
+	return &JobTool{jobs: jobs}
+}
+
+func (t *JobTool) Name() string { return "job" }
+
+func (t *JobTool) Description() string {
+	return "Manage commands started with exec run_in_background. Actions: list (this conversation's jobs), " +
+		"output (new output since offset, plus status), input (write to stdin), kill."
+}
+
+func (t *JobTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type": "string",
+				"enum": []string{"list", "output", "input", "kill"},
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Job ID (output, input, kill)",
+			},
+			"offset": map[string]any{
+				"type":        "integer",
+				"description": "Output offset to continue from; use the next offset of the previous call",
+			},
+			"input": map[string]any{
+				"type":        "string",
+				"description": "Text to write to stdin; include \\n to send a line",
+			},
+			"close_stdin": map[string]any{
+				"type":        "boolean",
+				"description": "Close stdin after writing (signals end of input)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *JobTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	session := SessionKeyFrom(ctx)
+	id := GetString(params, "id", "")
+	action := GetString(params, "action", "")
+	if action != "list" && id == "" {
+		return "Error: id is required", nil
+	}
+
+	switch action {
+	case "list":
+		jobs := t.jobs.List(session)
+		if len(jobs) == 0 {
+			return "No background jobs.", nil
+		}
+		var sb strings.Builder
+		for _, j := range jobs {
+			fmt.Fprintf(&sb, "- %s [%s] %s (started %s)\n", j.ID, jobStatus(j), j.Command, j.StartedAt.Format("15:04:05"))
+		}
+		return sb.String(), nil
+	case "output":
+		out, next, info, err := t.jobs.Output(session, id, GetInt(params, "offset", 0))
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		if out == "" {
+			out = "(no new output)"
+		}
+		return fmt.Sprintf("Job %s [%s]: %s\n%s\n[Next offset %d]", id, jobStatus(info), info.Command, out, next), nil
+	case "input":
+		if err := t.jobs.Input(session, id, GetString(params, "input", ""), GetBool(params, "close_stdin", false)); err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		return fmt.Sprintf("Sent input to job %s.", id), nil
+	case "kill":
+		if err := t.jobs.Kill(session, id); err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		return fmt.Sprintf("Killed job %s.", id), nil
+	default:
+		return "Error: action must be list, output, input, or kill", nil
+	}
+}
+
+func jobStatus(j timeline.Job) string {
+	if j.ExitCode != nil && j.Status == timeline.JobExited {
+		return fmt.Sprintf("exited %d", *j.ExitCode)
+	}
+	return j.Status
+}
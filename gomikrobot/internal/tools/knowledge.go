@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/memory"
+)
+
+// KnowledgeSearcher finds passages of ingested documents.
+type KnowledgeSearcher interface {
+	Search(ctx context.Context, query string, limit int) ([]memory.Passage, error)
+}
+
+// KBSearchTool searches the documents added with `gomikrobot ingest`.
+type KBSearchTool struct {
+	kb KnowledgeSearcher
+}
+
+// NewKBSearchTool creates a kb_search tool.
+func NewKBSearchTool(kb KnowledgeSearcher) *KBSearchTool {
+	return &KBSearchTool{kb: kb}
+}
+
+func (t *KBSearchTool) Name() string { return "kb_search" }
+
+func (t *KBSearchTool) Description() string {
+	return "Search the user's personal knowledge base of ingested documents and web pages. " +
+		"Use it to ground answers in their notes and files; cite the source of passages you rely on."
+}
+
+func (t *KBSearchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "What to look for, phrased as a question or topic",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Number of passages to return (default 5, max 20)",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *KBSearchTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	query := strings.TrimSpace(GetString(params, "query", ""))
+	if query == "" {
+		return "Error: query is required", nil
+	}
+	limit := GetInt(params, "limit", 5)
+	if limit < 1 || limit > 20 {
+		limit = 5
+	}
+	passages, err := t.kb.Search(ctx, query, limit)
+	if err != nil {
+		return fmt.Sprintf("Error searching knowledge base: %v", err), nil
+	}
+	if len(passages) == 0 {
+		return "No matching passages in the knowledge base.", nil
+	}
+	var sb strings.Builder
+	for i, p := range passages {
+		fmt.Fprintf(&sb, "[%d] %s", i+1, p.Source)
+		if p.Title != "" && p.Title != p.Source {
+			fmt.Fprintf(&sb, " (%s)", p.Title)
+		}
+		fmt.Fprintf(&sb, ", chunk %d, score %.2f\n%s\n\n", p.Chunk+1, p.Score, p.Text)
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// LanguageStore persists per-session reply languages.
+type LanguageStore interface {
+	SetSessionLanguage(sessionKey, lang string) error
+}
+
+// SetLanguageTool pins the language the bot replies in for a conversation.
+type SetLanguageTool struct {
+	store  LanguageStore
+	admins []string
+}
+
+// NewSetLanguageTool creates a set_language tool backed by store.
+func NewSetLanguageTool(store LanguageStore, admins []string) *SetLanguageTool {
+	return &SetLanguageTool{store: store, admins: admins}
+}
+
+func (t *SetLanguageTool) Name() string { return "set_language" }
+
+func (t *SetLanguageTool) Description() string {
+	return "Set the language you reply in for this conversation, e.g. when the user asks you to always answer in German. " +
+		"Use \"auto\" to follow the language of the user's messages again. Admin chats may pass session to set it for another conversation."
+}
+
+func (t *SetLanguageTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"language": map[string]any{
+				"type":        "string",
+				"description": "Language name or code (e.g. German, de), or \"auto\"",
+			},
+			"session": map[string]any{
+				"type":        "string",
+				"description": "Conversation session key (admin only, default: this conversation)",
+			},
+		},
+		"required": []string{"language"},
+	}
+}
+
+func (t *SetLanguageTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	lang := strings.TrimSpace(GetString(params, "language", ""))
+	if lang == "" {
+		return "Error: language is required", nil
+	}
+	sessionKey := SessionKeyFrom(ctx)
+	if other := strings.TrimSpace(GetString(params, "session", "")); other != "" && other != sessionKey {
+		if !IsAdmin(t.admins, PolicySessionFrom(ctx)) {
+			return "Error: only admin chats can set the language of other conversations", nil
+		}
+		sessionKey = other
+	}
+	if sessionKey == "" {
+		return "Error: no conversation to apply the language to", nil
+	}
+
+	value := lang
+	if strings.EqualFold(lang, "auto") {
+		value = ""
+	}
+	if err := t.store.SetSessionLanguage(sessionKey, value); err != nil {
+		return fmt.Sprintf("Error: failed to save language: %v", err), nil
+	}
+	if value == "" {
+		return "Replies now follow the language of the user's messages.", nil
+	}
+	return fmt.Sprintf("Replies in %s are now set to %s.", sessionKey, value), nil
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// SettingStore persists key/value settings.
+type SettingStore interface {
+	GetSetting(key string) (string, error)
+	SetSetting(key, value string) error
+}
+
+// SessionModelKey is the settings key holding a session's model override.
+func SessionModelKey(sessionKey string) string {
+	return "model:" + sessionKey
+}
+
+type sessionKeyKey struct{}
+
+// WithSessionKey attaches the session being processed to ctx.
+func WithSessionKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, sessionKeyKey{}, key)
+}
+
+// SessionKeyFrom returns the session key attached to ctx, or "".
+func SessionKeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(sessionKeyKey{}).(string)
+	return key
+}
+
+type turnKey struct{}
+
+var turnSeq atomic.Uint64
+
+// WithTurn marks ctx as a new agent turn, the handling of one message.
+func WithTurn(ctx context.Context) context.Context {
+	return context.WithValue(ctx, turnKey{}, turnSeq.Add(1))
+}
+
+// TurnFrom returns the turn of ctx, or 0 outside a turn.
+func TurnFrom(ctx context.Context) uint64 {
+	turn, _ := ctx.Value(turnKey{}).(uint64)
+	return turn
+}
+
+type originSessionKey struct{}
+
+// WithOriginSession records the chat that started a background task, so
+// the task runs with that chat's admin rights and sandbox.
+func WithOriginSession(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, originSessionKey{}, key)
+}
+
+// PolicySessionFrom returns the session whose admin rights and sandbox
+// apply under ctx: the originating chat of a background task, otherwise
+// the session being processed.
+func PolicySessionFrom(ctx context.Context) string {
+	if key, _ := ctx.Value(originSessionKey{}).(string); key != "" {
+		return key
+	}
+	return SessionKeyFrom(ctx)
+}
+
+// SetModelTool overrides the model used for the current conversation.
+type SetModelTool struct {
+	store  SettingStore
+	models []string
+}
+
+// NewSetModelTool creates a set_model tool backed by store that switches
+// between the default model and models.
+func NewSetModelTool(store SettingStore, models []string) *SetModelTool {
+	return &SetModelTool{store: store, models: models}
+}
+
+func (t *SetModelTool) Name() string { return "set_model" }
+
+func (t *SetModelTool) Description() string {
+	available := "none; the operator has not enabled other models"
+	if len(t.models) > 0 {
+		available = strings.Join(t.models, ", ")
+	}
+	return "Switch the LLM model for this conversation only, e.g. when the user asks to use gpt-4o-mini for this chat. " +
+		"Use \"default\" to go back to the configured model. Takes effect from the next message. Available models: " + available + "."
+}
+
+func (t *SetModelTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"model": map[string]any{
+				"type":        "string",
+				"description": "Model name, or \"default\"",
+			},
+		},
+		"required": []string{"model"},
+	}
+}
+
+func (t *SetModelTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	model := strings.TrimSpace(GetString(params, "model", ""))
+	if model == "" {
+		return "Error: model is required", nil
+	}
+	sessionKey := SessionKeyFrom(ctx)
+	if sessionKey == "" {
+		return "Error: no conversation to apply the model to", nil
+	}
+
+	value := model
+	if strings.EqualFold(model, "default") {
+		value = ""
+	} else if !slices.Contains(t.models, model) {
+		if len(t.models) == 0 {
+			return "Error: no other models are enabled; conversations use the default model", nil
+		}
+		return fmt.Sprintf("Error: %s is not available; choose one of %s, or default", model, strings.Join(t.models, ", ")), nil
+	}
+	if err := t.store.SetSetting(SessionModelKey(sessionKey), value); err != nil {
+		return fmt.Sprintf("Error: failed to save model override: %v", err), nil
+	}
+	if value == "" {
+		return "This conversation now uses the default model from the next message on.", nil
+	}
+	return fmt.Sprintf("This conversation now uses %s from the next message on.", model), nil
+}
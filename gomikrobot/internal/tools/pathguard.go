@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// systemPaths may be referenced by workspace-restricted commands.
+var systemPaths = []string{"/dev/null", "/dev/zero", "/dev/stdin", "/dev/stdout", "/dev/stderr", "/dev/urandom", "/dev/random"}
+
+// binDirs hold executables that may be invoked by absolute path, e.g.
+// /usr/bin/env; only the command word may point there.
+var binDirs = []string{"/bin", "/sbin", "/usr/bin", "/usr/sbin", "/usr/local/bin", "/opt/homebrew/bin"}
+
+// shellWord is one word of a command line and whether it is in command
+// position (the first word of a pipeline stage).
+type shellWord struct {
+	text    string
+	command bool
+}
+
+// splitShellWords splits command into words, honoring quotes and
+// backslashes and breaking at operators such as ; | & < > ( ). It is not a
+// full shell parser; it only needs to find the words that may be paths.
+func splitShellWords(command string) []shellWord {
+	var words []shellWord
+	var cur strings.Builder
+	inWord, atCommand := false, true
+	var quote rune
+	flush := func() {
+		if inWord {
+			words = append(words, shellWord{text: cur.String(), command: atCommand})
+			atCommand = false
+		}
+		cur.Reset()
+		inWord = false
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else if r == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				cur.WriteRune(runes[i])
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\' && i+1 < len(runes):
+			i++
+			cur.WriteRune(runes[i])
+			inWord = true
+		case r == ' ' || r == '\t':
+			flush()
+		case strings.ContainsRune(";|&\n()`", r):
+			flush()
+			atCommand = true
+		case r == '<' || r == '>':
+			flush()
+		case r == '$' && i+1 < len(runes) && runes[i+1] == '(':
+			flush()
+			atCommand = true
+			i++
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	flush()
+	return words
+}
+
+// pathCandidates returns the parts of word that may name files: the word
+// itself and the value of --flag=value or VAR=value forms.
+func pathCandidates(word string) []string {
+	var out []string
+	for _, c := range []string{word, afterEquals(word)} {
+		if c == "" || strings.Contains(c, "://") {
+			continue
+		}
+		if c == "." || c == ".." || c == "~" || strings.ContainsAny(c, "/") {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func afterEquals(word string) string {
+	if _, v, ok := strings.Cut(word, "="); ok {
+		return v
+	}
+	return ""
+}
+
+// resolveCommandPath turns a path as written in a command into an
+// absolute, symlink-free path. Shell variables other than HOME and PWD
+// expand to nothing, which makes them resolve outside the workspace.
+func resolveCommandPath(p, workingDir string) string {
+	home, _ := os.UserHomeDir()
+	p = os.Expand(p, func(name string) string {
+		switch name {
+		case "HOME":
+			return home
+		case "PWD":
+			return workingDir
+		}
+		return ""
+	})
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		p = home + p[1:]
+	}
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(workingDir, p)
+	}
+	return realPath(filepath.Clean(p))
+}
+
+// realPath resolves symlinks in the longest existing prefix of p, so paths
+// that do not exist yet (output files) are still checked.
+func realPath(p string) string {
+	var rest []string
+	for cur := p; ; cur = filepath.Dir(cur) {
+		if resolved, err := filepath.EvalSymlinks(cur); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...)
+		}
+		if filepath.Dir(cur) == cur {
+			return p
+		}
+		rest = append([]string{filepath.Base(cur)}, rest...)
+	}
+}
+
+// withinDir reports whether p is root or inside it. Both must be absolute
+// and symlink-free.
+func withinDir(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// guardPaths refuses commands whose path arguments or working directory
+// resolve outside root, following symlinks.
+func guardPaths(command, workingDir, root string) error {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("Error: invalid workspace: %v", err)
+	}
+	absRoot = realPath(absRoot)
+	dir := absRoot
+	if workingDir != "" {
+		if dir, err = filepath.Abs(workingDir); err != nil {
+			return fmt.Errorf("Error: invalid working directory: %v", err)
+		}
+		dir = realPath(dir)
+		if !withinDir(absRoot, dir) {
+			return fmt.Errorf("Error: working directory must be within workspace")
+		}
+	}
+
+	for _, w := range splitShellWords(command) {
+		for _, c := range pathCandidates(w.text) {
+			p := resolveCommandPath(c, dir)
+			if withinDir(absRoot, p) || isSystemPath(p, w.command && c == w.text) {
+				continue
+			}
+			return fmt.Errorf("Error: %s is outside the workspace", c)
+		}
+	}
+	return nil
+}
+
+func isSystemPath(p string, commandWord bool) bool {
+	for _, s := range systemPaths {
+		if p == s {
+			return true
+		}
+	}
+	if commandWord {
+		for _, d := range binDirs {
+			if filepath.Dir(p) == d || filepath.Dir(p) == realPath(d) {
+				return true
+			}
+		}
+	}
+	return false
+}
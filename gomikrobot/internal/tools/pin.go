@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/session"
+)
+
+// PinStore manages the pinned messages of sessions.
+type PinStore interface {
+	Pin(sessionKey, content string) (session.Pin, error)
+	Unpin(sessionKey string, id int) error
+	Pins(sessionKey string) []session.Pin
+}
+
+// PinTool pins messages that stay in the context of the current
+// conversation, such as standing instructions.
+type PinTool struct {
+	store PinStore
+}
+
+// NewPinTool creates a pin tool.
+func NewPinTool(store PinStore) *PinTool {
+	return &PinTool{store: store}
+}
+
+func (t *PinTool) Name() string { return "pin" }
+
+func (t *PinTool) Description() string {
+	return "Pin a message so it stays in context for the rest of this conversation, even after older messages drop out " +
+		"(e.g. project instructions or preferences the user states once). Also lists or removes pins."
+}
+
+func (t *PinTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"pin", "unpin", "list"},
+				"description": "pin a message, unpin one by number, or list pins",
+			},
+			"content": map[string]any{
+				"type":        "string",
+				"description": "Text to pin, written so it stands on its own (pin)",
+			},
+			"id": map[string]any{
+				"type":        "integer",
+				"description": "Pin number (unpin)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *PinTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	key := SessionKeyFrom(ctx)
+	if key == "" {
+		return "Error: no conversation", nil
+	}
+	switch GetString(params, "action", "") {
+	case "pin":
+		pin, err := t.store.Pin(key, GetString(params, "content", ""))
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		return fmt.Sprintf("Pinned #%d.", pin.ID), nil
+	case "unpin":
+		id := GetInt(params, "id", 0)
+		if id <= 0 {
+			return "Error: id is required", nil
+		}
+		if err := t.store.Unpin(key, id); err != nil {
+			return fmt.Sprintf("Error: cannot unpin #%d: %v", id, err), nil
+		}
+		return fmt.Sprintf("Unpinned #%d.", id), nil
+	case "list":
+		pins := t.store.Pins(key)
+		if len(pins) == 0 {
+			return "No pinned messages.", nil
+		}
+		var sb strings.Builder
+		for _, p := range pins {
+			fmt.Fprintf(&sb, "- #%d (%s): %s\n", p.ID, p.CreatedAt.Format("2006-01-02"), p.Content)
+		}
+		return sb.String(), nil
+	default:
+		return "Error: action must be pin, unpin, or list", nil
+	}
+}
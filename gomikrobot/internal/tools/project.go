@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Project is a named directory the agent can work in.
+type Project struct {
+	Name        string
+	Path        string
+	Description string
+}
+
+// ProjectState holds the active project of one session while a message is
+// processed. A nil active project means the default workspace.
+type ProjectState struct {
+	mu     sync.RWMutex
+	active *Project
+}
+
+// NewProjectState creates a state with p active (nil for the workspace).
+func NewProjectState(p *Project) *ProjectState {
+	return &ProjectState{active: p}
+}
+
+// Active returns the active project, or nil for the workspace.
+func (s *ProjectState) Active() *Project {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+func (s *ProjectState) set(p *Project) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = p
+}
+
+type projectKey struct{}
+
+// WithProject attaches a project state to ctx.
+func WithProject(ctx context.Context, s *ProjectState) context.Context {
+	return context.WithValue(ctx, projectKey{}, s)
+}
+
+// ProjectFrom returns the project state attached to ctx, if any.
+func ProjectFrom(ctx context.Context) *ProjectState {
+	s, _ := ctx.Value(projectKey{}).(*ProjectState)
+	return s
+}
+
+// projectDir returns the active project's directory, or "" when the
+// workspace is active.
+func projectDir(ctx context.Context) string {
+	if s := ProjectFrom(ctx); s != nil {
+		if p := s.Active(); p != nil {
+			return p.Path
+		}
+	}
+	return ""
+}
+
+// SwitchProjectTool changes the project the agent works in.
+type SwitchProjectTool struct {
+	projects []Project
+}
+
+// NewSwitchProjectTool creates a switch_project tool for the given projects.
+func NewSwitchProjectTool(projects []Project) *SwitchProjectTool {
+	return &SwitchProjectTool{projects: projects}
+}
+
+func (t *SwitchProjectTool) Name() string { return "switch_project" }
+
+func (t *SwitchProjectTool) Description() string {
+	return "Switch the working project for this conversation. Shell commands then run in the project directory " +
+		"and its AGENTS.md/README are loaded. Use \"default\" to return to the workspace; omit name to list projects."
+}
+
+func (t *SwitchProjectTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Project name, or \"default\" for the workspace",
+			},
+		},
+	}
+}
+
+func (t *SwitchProjectTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	name := strings.TrimSpace(GetString(params, "name", ""))
+	if name == "" {
+		return t.list(ctx), nil
+	}
+
+	state := ProjectFrom(ctx)
+	if state == nil {
+		return "Error: project switching is not available in this context", nil
+	}
+
+	if strings.EqualFold(name, "default") {
+		state.set(nil)
+		return "Switched back to the default workspace.", nil
+	}
+
+	for i := range t.projects {
+		p := t.projects[i]
+		if !strings.EqualFold(p.Name, name) {
+			continue
+		}
+		if info, err := os.Stat(p.Path); err != nil || !info.IsDir() {
+			return fmt.Sprintf("Error: project directory not found: %s", p.Path), nil
+		}
+		state.set(&p)
+		return fmt.Sprintf("Switched to project %s (%s). Shell commands now run in this directory.", p.Name, p.Path), nil
+	}
+	return fmt.Sprintf("Error: unknown project %q\n\n%s", name, t.list(ctx)), nil
+}
+
+func (t *SwitchProjectTool) list(ctx context.Context) string {
+	if len(t.projects) == 0 {
+		return "No projects are configured."
+	}
+	active := ""
+	if s := ProjectFrom(ctx); s != nil {
+		if p := s.Active(); p != nil {
+			active = p.Name
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Available projects:\n")
+	for _, p := range t.projects {
+		marker := "-"
+		if p.Name == active {
+			marker = "*"
+		}
+		fmt.Fprintf(&sb, "%s %s: %s", marker, p.Name, p.Path)
+		if p.Description != "" {
+			fmt.Fprintf(&sb, " (%s)", p.Description)
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
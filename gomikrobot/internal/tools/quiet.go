@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// QuietStore persists silent mode and quiet hours.
+type QuietStore interface {
+	SetQuiet(session, silent, quietHours string) error
+}
+
+// QuietTool lets admin chats switch silent mode and quiet hours, globally or
+// for one contact.
+type QuietTool struct {
+	store  QuietStore
+	admins []string
+}
+
+// NewQuietTool creates a set_quiet tool backed by store.
+func NewQuietTool(store QuietStore, admins []string) *QuietTool {
+	return &QuietTool{store: store, admins: admins}
+}
+
+func (t *QuietTool) Name() string { return "set_quiet" }
+
+func (t *QuietTool) Description() string {
+	return "Admin only: switch silent mode (no outbound messages at all) and quiet hours (messages are held and delivered when the hours end). " +
+		"Applies to all chats unless session names one conversation (channel:chat), e.g. when the admin says \"don't message Anna at night\"."
+}
+
+func (t *QuietTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"silent": map[string]any{
+				"type":        "string",
+				"enum":        []string{"on", "off", "default"},
+				"description": "Silent mode; \"default\" makes a conversation follow the global setting again",
+			},
+			"quiet_hours": map[string]any{
+				"type":        "string",
+				"description": "Daily window in local time like \"22:00-07:00\", \"off\", or \"default\" for a conversation to follow the global setting",
+			},
+			"session": map[string]any{
+				"type":        "string",
+				"description": "Conversation session key (default: all conversations)",
+			},
+		},
+	}
+}
+
+func (t *QuietTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	if !IsAdmin(t.admins, PolicySessionFrom(ctx)) {
+		return "Error: only admin chats can change silent mode and quiet hours", nil
+	}
+	silent := strings.TrimSpace(GetString(params, "silent", ""))
+	hours := strings.TrimSpace(GetString(params, "quiet_hours", ""))
+	if silent == "" && hours == "" {
+		return "Error: silent or quiet_hours is required", nil
+	}
+	session := strings.TrimSpace(GetString(params, "session", ""))
+	if err := t.store.SetQuiet(session, silent, hours); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	target := "all conversations"
+	if session != "" {
+		target = session
+	}
+	var changes []string
+	if silent != "" {
+		changes = append(changes, "silent mode "+silent)
+	}
+	if hours != "" {
+		changes = append(changes, "quiet hours "+hours)
+	}
+	return fmt.Sprintf("Set %s for %s.", strings.Join(changes, " and "), target), nil
+}
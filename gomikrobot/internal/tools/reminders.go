@@ -0,0 +1,234 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// maxReminderDelay bounds how far ahead a reminder may be set.
+const maxReminderDelay = 366 * 24 * time.Hour
+
+// ReminderStore persists reminders.
+type ReminderStore interface {
+	AddReminder(r *timeline.Reminder) error
+	ListReminders(sessionKey, status string, limit int) ([]timeline.Reminder, error)
+	CancelReminder(sessionKey string, id int64) error
+}
+
+// ReminderTools returns remind_me, list_reminders, and cancel_reminder.
+func ReminderTools(store ReminderStore) []Tool {
+	return []Tool{&RemindMeTool{store: store}, &ListRemindersTool{store: store}, &CancelReminderTool{store: store}}
+}
+
+// delayPart matches one "<number> <unit>" of a spoken delay.
+var delayPart = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(weeks?|w|days?|d|hours?|hrs?|h|minutes?|mins?|m|seconds?|secs?|s)\b`)
+
+// parseDelay reads delays such as "2h30m", "90 minutes", or
+// "1 day and 3 hours".
+func parseDelay(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "in "))
+	if d, err := time.ParseDuration(s); err == nil {
+		return d, nil
+	}
+	matches := delayPart.FindAllStringSubmatch(s, -1)
+	rest := strings.TrimSpace(delayPart.ReplaceAllString(s, ""))
+	rest = strings.Trim(strings.ReplaceAll(strings.ReplaceAll(rest, "and", ""), ",", ""), " ")
+	if len(matches) == 0 || rest != "" {
+		return 0, fmt.Errorf("cannot read delay %q; use e.g. 2h30m or \"90 minutes\"", s)
+	}
+	var d time.Duration
+	for _, m := range matches {
+		n, _ := strconv.ParseFloat(m[1], 64)
+		unit := time.Second
+		switch strings.ToLower(m[2])[0] {
+		case 'w':
+			unit = 7 * 24 * time.Hour
+		case 'd':
+			unit = 24 * time.Hour
+		case 'h':
+			unit = time.Hour
+		case 'm':
+			unit = time.Minute
+		}
+		d += time.Duration(n * float64(unit))
+	}
+	return d, nil
+}
+
+// parseReminderTime reads an absolute time. A bare clock time means its
+// next occurrence.
+func parseReminderTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, f := range []string{"15:04", "3:04pm", "3pm"} {
+		if t, err := time.ParseInLocation(f, strings.ReplaceAll(strings.ToLower(s), " ", ""), now.Location()); err == nil {
+			at := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+			if !at.After(now) {
+				at = at.AddDate(0, 0, 1)
+			}
+			return at, nil
+		}
+	}
+	t, allDay, err := parseCalendarTime(s, now.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	if allDay {
+		t = t.Add(9 * time.Hour) // A date alone means that morning
+	}
+	return t, nil
+}
+
+// RemindMeTool schedules a one-shot reminder in the current chat.
+type RemindMeTool struct {
+	store ReminderStore
+}
+
+func (t *RemindMeTool) Name() string { return "remind_me" }
+
+func (t *RemindMeTool) Description() string {
+	return "Send a reminder to this chat later. Give either in (a delay such as \"2h\" or \"20 minutes\") or at " +
+		"(a time such as \"15:30\", \"2026-03-14 09:00\", or a date for 9:00 that day). Times are local to the bot."
+}
+
+func (t *RemindMeTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"text": map[string]any{
+				"type":        "string",
+				"description": "What to remind about, e.g. \"take the bread out\"",
+			},
+			"in": map[string]any{
+				"type":        "string",
+				"description": "Delay from now, e.g. 2h, 90m, \"1 day 3 hours\"",
+			},
+			"at": map[string]any{
+				"type":        "string",
+				"description": "Time of the reminder, e.g. 15:30 or 2026-03-14 09:00",
+			},
+		},
+		"required": []string{"text"},
+	}
+}
+
+func (t *RemindMeTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	text := strings.TrimSpace(GetString(params, "text", ""))
+	if text == "" {
+		return "Error: text is required", nil
+	}
+	session := SessionKeyFrom(ctx)
+	channel, chatID, ok := strings.Cut(session, ":")
+	if !ok || chatID == "" {
+		return "Error: reminders need a chat to be delivered to", nil
+	}
+
+	now := time.Now()
+	var due time.Time
+	in, at := GetString(params, "in", ""), GetString(params, "at", "")
+	switch {
+	case in != "" && at != "":
+		return "Error: give either in or at, not both", nil
+	case in != "":
+		d, err := parseDelay(in)
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		due = now.Add(d)
+	case at != "":
+		var err error
+		if due, err = parseReminderTime(at, now); err != nil {
+			return "Error: " + err.Error(), nil
+		}
+	default:
+		return "Error: in or at is required", nil
+	}
+	if !due.After(now) {
+		return "Error: the reminder time is in the past", nil
+	}
+	if due.Sub(now) > maxReminderDelay {
+		return "Error: reminders can be set at most a year ahead", nil
+	}
+
+	r := &timeline.Reminder{SessionKey: session, Channel: channel, ChatID: chatID, Text: text, DueAt: due}
+	if err := t.store.AddReminder(r); err != nil {
+		return fmt.Sprintf("Error saving reminder: %v", err), nil
+	}
+	return fmt.Sprintf("Reminder #%d set for %s: %s", r.ID, due.Format("Mon 2006-01-02 15:04"), text), nil
+}
+
+// ListRemindersTool lists the pending reminders of the current chat.
+type ListRemindersTool struct {
+	store ReminderStore
+}
+
+func (t *ListRemindersTool) Name() string { return "list_reminders" }
+
+func (t *ListRemindersTool) Description() string {
+	return "List the pending reminders of this chat."
+}
+
+func (t *ListRemindersTool) Parameters() map[string]any {
+	return map[string]any{"type": "object", "properties": map[string]any{}}
+}
+
+func (t *ListRemindersTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	session := SessionKeyFrom(ctx)
+	if session == "" {
+		return "Error: no conversation", nil
+	}
+	list, err := t.store.ListReminders(session, timeline.ReminderPending, 100)
+	if err != nil {
+		return fmt.Sprintf("Error listing reminders: %v", err), nil
+	}
+	if len(list) == 0 {
+		return "No pending reminders.", nil
+	}
+	var sb strings.Builder
+	for _, r := range list {
+		fmt.Fprintf(&sb, "- #%d %s: %s\n", r.ID, r.DueAt.Format("Mon 2006-01-02 15:04"), r.Text)
+	}
+	return sb.String(), nil
+}
+
+// CancelReminderTool cancels a pending reminder of the current chat.
+type CancelReminderTool struct {
+	store ReminderStore
+}
+
+func (t *CancelReminderTool) Name() string { return "cancel_reminder" }
+
+func (t *CancelReminderTool) Description() string {
+	return "Cancel a pending reminder of this chat by its number from list_reminders."
+}
+
+func (t *CancelReminderTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{"type": "integer", "description": "Reminder number"},
+		},
+		"required": []string{"id"},
+	}
+}
+
+func (t *CancelReminderTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	session := SessionKeyFrom(ctx)
+	id := GetInt(params, "id", 0)
+	if session == "" || id <= 0 {
+		return "Error: id is required", nil
+	}
+	err := t.store.CancelReminder(session, int64(id))
+	if errors.Is(err, timeline.ErrReminderNotFound) {
+		return fmt.Sprintf("Error: no pending reminder #%d in this chat", id), nil
+	} else if err != nil {
+		return fmt.Sprintf("Error cancelling reminder: %v", err), nil
+	}
+	return fmt.Sprintf("Cancelled reminder #%d.", id), nil
+}
@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// DraftReviewer holds replies to supervised conversations until an admin
+// decides on them.
+type DraftReviewer interface {
+	SetReview(sessionKey string, on bool) error
+	ReviewSessions() ([]string, error)
+	PendingDrafts() ([]timeline.Draft, error)
+	ReviewDraft(id int64, action, content string) (*timeline.Draft, error)
+}
+
+// ReviewTool lets admins supervise the bot's replies to chosen contacts:
+// replies there are held as drafts to approve, edit or reject.
+type ReviewTool struct {
+	reviewer DraftReviewer
+	admins   []string
+}
+
+// NewReviewTool creates a review tool for the given admin sessions.
+func NewReviewTool(reviewer DraftReviewer, admins []string) *ReviewTool {
+	return &ReviewTool{reviewer: reviewer, admins: admins}
+}
+
+func (t *ReviewTool) Name() string { return "review" }
+
+func (t *ReviewTool) Description() string {
+	return "Review replies before they are sent: turn review on or off for a conversation (enable, disable), " +
+		"list pending drafts, and approve, edit or reject a draft by its number. Admin chats only."
+}
+
+func (t *ReviewTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "approve", "edit", "reject", "enable", "disable"},
+				"description": "What to do",
+			},
+			"draft": map[string]any{
+				"type":        "integer",
+				"description": "Draft number (approve, edit, reject)",
+			},
+			"text": map[string]any{
+				"type":        "string",
+				"description": "Replacement text to send instead of the draft (edit)",
+			},
+			"session": map[string]any{
+				"type":        "string",
+				"description": "Conversation session key, e.g. whatsapp:4917…@s.whatsapp.net (enable, disable)",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ReviewTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	if !IsAdmin(t.admins, PolicySessionFrom(ctx)) {
+		return "Error: review is only available in admin chats", nil
+	}
+
+	action := GetString(params, "action", "")
+	switch action {
+	case "list":
+		return t.list()
+	case "enable", "disable":
+		session := strings.TrimSpace(GetString(params, "session", ""))
+		if !strings.Contains(session, ":") {
+			return "Error: session must be a session key like whatsapp:4917…@s.whatsapp.net", nil
+		}
+		if err := t.reviewer.SetReview(session, action == "enable"); err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		if action == "enable" {
+			return fmt.Sprintf("Replies to %s are now held for review.", session), nil
+		}
+		return fmt.Sprintf("Replies to %s are sent directly again.", session), nil
+	case "approve", "edit", "reject":
+		id := int64(GetInt(params, "draft", 0))
+		if id <= 0 {
+			return "Error: draft number is required", nil
+		}
+		d, err := t.reviewer.ReviewDraft(id, action, GetString(params, "text", ""))
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err), nil
+		}
+		if action == "reject" {
+			return fmt.Sprintf("Rejected draft #%d; nothing was sent to %s:%s.", d.ID, d.Channel, d.ChatID), nil
+		}
+		return fmt.Sprintf("Sent draft #%d to %s:%s.", d.ID, d.Channel, d.ChatID), nil
+	default:
+		return "Error: action must be list, approve, edit, reject, enable or disable", nil
+	}
+}
+
+func (t *ReviewTool) list() (string, error) {
+	sessions, err := t.reviewer.ReviewSessions()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	drafts, err := t.reviewer.PendingDrafts()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	var b strings.Builder
+	if len(sessions) == 0 {
+		b.WriteString("No conversations are reviewed.\n")
+	} else {
+		fmt.Fprintf(&b, "Reviewed: %s\n", strings.Join(sessions, ", "))
+	}
+	if len(drafts) == 0 {
+		b.WriteString("No drafts are waiting.")
+		return b.String(), nil
+	}
+	b.WriteString("Waiting for review:\n")
+	for _, d := range drafts {
+		fmt.Fprintf(&b, "- #%d to %s:%s (%s): %s\n", d.ID, d.Channel, d.ChatID, d.CreatedAt.Local().Format("Mon 15:04"), d.Content)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Limits of run_code.
+const (
+	codeMaxChars    = 100000
+	codeOutputBytes = 64 << 10 // Captured per stream
+	codeMaxFileKB   = 10 << 10 // Largest file a snippet may write
+)
+
+// RunCodeOptions configures a RunCodeTool.
+type RunCodeOptions struct {
+	Timeout  time.Duration // Default 30s
+	Python   string        // Interpreter, default "python3"
+	Node     string        // Interpreter, default "node"
+	MemoryMB int           // Default 256
+	// Sandbox, if set, is exec's container sandbox. run_code cannot run in
+	// it, so it refuses the sessions the sandbox applies to.
+	Sandbox *SandboxOptions
+}
+
+// RunCodeTool runs short Python or JavaScript snippets in a subprocess on
+// the host, with its own temp directory, resource limits, and an environment
+// without the gateway's variables. It is not a sandbox: snippets can read
+// and write any file the gateway can and reach the network.
+type RunCodeTool struct {
+	opts RunCodeOptions
+}
+
+// NewRunCodeTool creates a run_code tool; zero options use the defaults.
+func NewRunCodeTool(opts RunCodeOptions) *RunCodeTool {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.Python == "" {
+		opts.Python = "python3"
+	}
+	if opts.Node == "" {
+		opts.Node = "node"
+	}
+	if opts.MemoryMB <= 0 {
+		opts.MemoryMB = 256
+	}
+	return &RunCodeTool{opts: opts}
+}
+
+func (t *RunCodeTool) Name() string { return "run_code" }
+
+func (t *RunCodeTool) Description() string {
+	return "Run a short Python or JavaScript program on the host and return what it prints. Use it for " +
+		"calculations, parsing, and data conversion. The program starts in an empty temp directory with " +
+		"CPU, memory, and time limits; pass input data via stdin or embed it in the code. Do not use it " +
+		"to read or change files outside that directory."
+}
+
+func (t *RunCodeTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"language": map[string]any{
+				"type": "string",
+				"enum": []string{"python", "javascript"},
+			},
+			"code": map[string]any{
+				"type":        "string",
+				"description": "The program; print results to stdout",
+			},
+			"stdin": map[string]any{
+				"type":        "string",
+				"description": "Optional text passed on standard input, e.g. CSV data",
+			},
+		},
+		"required": []string{"language", "code"},
+	}
+}
+
+func (t *RunCodeTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	if t.opts.Sandbox != nil && t.opts.Sandbox.appliesTo(PolicySessionFrom(ctx)) {
+		return "Error: run_code is not available in sandboxed sessions; use exec, which runs in the sandbox", nil
+	}
+	code := GetString(params, "code", "")
+	if strings.TrimSpace(code) == "" {
+		return "Error: code is required", nil
+	}
+	if len(code) > codeMaxChars {
+		return fmt.Sprintf("Error: code is longer than %d characters; write it to a file and use exec instead", codeMaxChars), nil
+	}
+
+	var interp, file string
+	var args []string
+	switch lang := strings.ToLower(GetString(params, "language", "")); lang {
+	case "python", "py":
+		// -I ignores PYTHON* variables and the user site directory.
+		interp, file, args = t.opts.Python, "main.py", []string{"-I", "-B"}
+	case "javascript", "js", "node":
+		interp, file, args = t.opts.Node, "main.js", []string{fmt.Sprintf("--max-old-space-size=%d", t.opts.MemoryMB)}
+	default:
+		return "Error: language must be python or javascript", nil
+	}
+	bin, err := exec.LookPath(interp)
+	if err != nil {
+		return fmt.Sprintf("Error: %s is not installed", interp), nil
+	}
+
+	dir, err := os.MkdirTemp("", "gomikrobot-code-")
+	if err != nil {
+		return fmt.Sprintf("Error creating temp dir: %v", err), nil
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(code), 0600); err != nil {
+		return fmt.Sprintf("Error writing code: %v", err), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.opts.Timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", append([]string{"-c", t.limits(file), bin}, append(args, file)...)...)
+	cmd.Dir = dir
+	cmd.Env = codeEnv(dir)
+	cmd.Stdin = strings.NewReader(GetString(params, "stdin", ""))
+	stdout := &cappedBuffer{max: codeOutputBytes}
+	stderr := &cappedBuffer{max: codeOutputBytes}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	cmd.WaitDelay = time.Second
+	err = cmd.Run()
+
+	var result strings.Builder
+	result.WriteString(stdout.String())
+	if stderr.buf.Len() > 0 {
+		if result.Len() > 0 {
+			result.WriteString("\n")
+		}
+		result.WriteString("STDERR:\n")
+		result.WriteString(stderr.String())
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Sprintf("Error: program timed out after %v\n%s", t.opts.Timeout, result.String()), nil
+	}
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			fmt.Fprintf(&result, "\nExit code: %d", exitErr.ExitCode())
+		} else {
+			return fmt.Sprintf("Error running program: %v", err), nil
+		}
+	}
+	if result.Len() == 0 {
+		return "(no output)", nil
+	}
+	return result.String(), nil
+}
+
+// limits returns the shell prelude that applies CPU, file size, and memory
+// limits before replacing itself with the interpreter. Node reserves far
+// more address space than it uses, so its heap is capped by flag instead.
+func (t *RunCodeTool) limits(file string) string {
+	cpu := int(t.opts.Timeout.Seconds()) + 1
+	prelude := fmt.Sprintf("ulimit -t %d; ulimit -f %d; ulimit -n 256; ", cpu, codeMaxFileKB)
+	if strings.HasSuffix(file, ".py") {
+		prelude += fmt.Sprintf("ulimit -v %d; ", t.opts.MemoryMB<<10)
+	}
+	return prelude + `exec "$0" "$@"`
+}
+
+// codeEnv is the minimal environment of a snippet: nothing from the
+// gateway's environment but PATH and the locale.
+func codeEnv(dir string) []string {
+	env := []string{"HOME=" + dir, "TMPDIR=" + dir, "PYTHONIOENCODING=utf-8"}
+	for _, name := range []string{"PATH", "LANG", "LC_ALL"} {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return env
+}
+
+// cappedBuffer keeps the first max bytes written and notes what it dropped.
+type cappedBuffer struct {
+	buf     bytes.Buffer
+	max     int
+	dropped int
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room < len(p) {
+		b.buf.Write(p[:max(room, 0)])
+		b.dropped += len(p) - max(room, 0)
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	if b.dropped > 0 {
+		return fmt.Sprintf("%s\n[%d more bytes not shown]", b.buf.String(), b.dropped)
+	}
+	return b.buf.String()
+}
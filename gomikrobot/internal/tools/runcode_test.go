@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCodeTool(t *testing.T) {
+	tool := NewRunCodeTool(RunCodeOptions{Timeout: 5 * time.Second})
+	t.Setenv("OPENAI_API_KEY", "sk-secret")
+
+	cases := []struct {
+		lang, interp, code, stdin, want string
+	}{
+		{"python", "python3", "import sys\nprint(sum(int(x) for x in sys.stdin.read().split(',')))", "1,2,3", "6"},
+		{"python", "python3", "import os\nprint(os.environ.get('OPENAI_API_KEY', 'none'))", "", "none"},
+		{"python", "python3", "raise SystemExit(3)", "", "Exit code: 3"},
+		{"javascript", "node", "console.log([1, 2, 3].map(x => x * 2).join(' '))", "", "2 4 6"},
+	}
+	for _, c := range cases {
+		if _, err := exec.LookPath(c.interp); err != nil {
+			t.Logf("%s not installed; skipping", c.interp)
+			continue
+		}
+		result, _ := tool.Execute(context.Background(), map[string]any{"language": c.lang, "code": c.code, "stdin": c.stdin})
+		if !strings.Contains(result, c.want) {
+			t.Errorf("%s %q: expected %q, got %q", c.lang, c.code, c.want, result)
+		}
+	}
+
+	if result, _ := tool.Execute(context.Background(), map[string]any{"language": "ruby", "code": "puts 1"}); !strings.HasPrefix(result, "Error") {
+		t.Errorf("expected unsupported language error, got %q", result)
+	}
+}
+
+func TestRunCodeToolTimeout(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	tool := NewRunCodeTool(RunCodeOptions{Timeout: 500 * time.Millisecond})
+	result, _ := tool.Execute(context.Background(), map[string]any{"language": "python", "code": "while True: pass"})
+	if !strings.Contains(result, "timed out") {
+		t.Errorf("expected timeout, got %q", result)
+	}
+}
+
+func TestRunCodeToolRefusesSandboxedSessions(t *testing.T) {
+	tool := NewRunCodeTool(RunCodeOptions{Sandbox: &SandboxOptions{Sessions: []string{"whatsapp:*"}}})
+	params := map[string]any{"language": "python", "code": "print(1)"}
+	result, _ := tool.Execute(WithSessionKey(context.Background(), "whatsapp:123"), params)
+	if !strings.Contains(result, "not available in sandboxed sessions") {
+		t.Errorf("sandboxed session got %q", result)
+	}
+	if _, err := exec.LookPath("python3"); err != nil {
+		return
+	}
+	if result, _ := tool.Execute(WithSessionKey(context.Background(), "cli:default"), params); strings.TrimSpace(result) != "1" {
+		t.Errorf("unsandboxed session got %q", result)
+	}
+}
@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ReasoningEfforts are the accepted reasoning_effort values.
+var ReasoningEfforts = []string{"low", "medium", "high"}
+
+// Sampling holds generation parameters. Unset fields (zero, or a nil
+// Temperature) leave the underlying value unchanged when merged.
+type Sampling struct {
+	MaxTokens       int      `json:"max_tokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            float64  `json:"top_p,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+}
+
+// Merge returns s with the fields set in o applied on top.
+func (s Sampling) Merge(o Sampling) Sampling {
+	if o.MaxTokens > 0 {
+		s.MaxTokens = o.MaxTokens
+	}
+	if o.Temperature != nil {
+		s.Temperature = o.Temperature
+	}
+	if o.TopP > 0 {
+		s.TopP = o.TopP
+	}
+	if o.ReasoningEffort != "" {
+		s.ReasoningEffort = o.ReasoningEffort
+	}
+	return s
+}
+
+// IsZero reports whether no field is set.
+func (s Sampling) IsZero() bool {
+	return s == Sampling{}
+}
+
+// String describes the set fields, e.g. "temperature 0.2, top_p 0.9".
+func (s Sampling) String() string {
+	var parts []string
+	if s.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature %g", *s.Temperature))
+	}
+	if s.TopP > 0 {
+		parts = append(parts, fmt.Sprintf("top_p %g", s.TopP))
+	}
+	if s.MaxTokens > 0 {
+		parts = append(parts, fmt.Sprintf("max_tokens %d", s.MaxTokens))
+	}
+	if s.ReasoningEffort != "" {
+		parts = append(parts, "reasoning_effort "+s.ReasoningEffort)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Validate checks the ranges of the set fields.
+func (s Sampling) Validate() error {
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if s.TopP < 0 || s.TopP > 1 {
+		return fmt.Errorf("top_p must be between 0 and 1")
+	}
+	if s.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must not be negative")
+	}
+	if s.ReasoningEffort != "" && !slices.Contains(ReasoningEfforts, s.ReasoningEffort) {
+		return fmt.Errorf("reasoning_effort must be one of %s", strings.Join(ReasoningEfforts, ", "))
+	}
+	return nil
+}
+
+// SessionSamplingKey is the settings key holding a session's sampling
+// overrides as JSON.
+func SessionSamplingKey(sessionKey string) string {
+	return "sampling:" + sessionKey
+}
+
+// SessionSampling returns the sampling overrides stored for sessionKey.
+func SessionSampling(store SettingStore, sessionKey string) Sampling {
+	var s Sampling
+	if raw, err := store.GetSetting(SessionSamplingKey(sessionKey)); err == nil && raw != "" {
+		_ = json.Unmarshal([]byte(raw), &s)
+	}
+	return s
+}
+
+// SetSamplingTool overrides generation parameters for the current conversation.
+type SetSamplingTool struct {
+	store SettingStore
+}
+
+// NewSetSamplingTool creates a set_sampling tool backed by store.
+func NewSetSamplingTool(store SettingStore) *SetSamplingTool {
+	return &SetSamplingTool{store: store}
+}
+
+func (t *SetSamplingTool) Name() string { return "set_sampling" }
+
+func (t *SetSamplingTool) Description() string {
+	return "Change generation settings for this conversation only, e.g. a lower temperature when the user wants precise answers " +
+		"or longer replies via max_tokens. Omitted settings keep their current value; reset goes back to the configured defaults. " +
+		"Takes effect from the next message."
+}
+
+func (t *SetSamplingTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"temperature": map[string]any{
+				"type":        "number",
+				"description": "Randomness between 0 and 2",
+			},
+			"top_p": map[string]any{
+				"type":        "number",
+				"description": "Nucleus sampling between 0 and 1",
+			},
+			"max_tokens": map[string]any{
+				"type":        "integer",
+				"description": "Maximum tokens per reply",
+			},
+			"reasoning_effort": map[string]any{
+				"type":        "string",
+				"enum":        ReasoningEfforts,
+				"description": "Reasoning effort for models that support it",
+			},
+			"reset": map[string]any{
+				"type":        "boolean",
+				"description": "Clear all overrides for this conversation",
+			},
+		},
+	}
+}
+
+func (t *SetSamplingTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	sessionKey := SessionKeyFrom(ctx)
+	if sessionKey == "" {
+		return "Error: no conversation to apply the settings to", nil
+	}
+	if GetBool(params, "reset", false) {
+		if err := t.store.SetSetting(SessionSamplingKey(sessionKey), ""); err != nil {
+			return fmt.Sprintf("Error: failed to save settings: %v", err), nil
+		}
+		return "This conversation uses the default generation settings from the next message on.", nil
+	}
+
+	var o Sampling
+	if v, ok := params["temperature"].(float64); ok {
+		o.Temperature = &v
+	}
+	o.TopP = GetFloat(params, "top_p", 0)
+	o.MaxTokens = GetInt(params, "max_tokens", 0)
+	o.ReasoningEffort = strings.ToLower(GetString(params, "reasoning_effort", ""))
+	if o.IsZero() {
+		return "Error: set at least one of temperature, top_p, max_tokens, reasoning_effort, or reset", nil
+	}
+	if err := o.Validate(); err != nil {
+		return "Error: " + err.Error(), nil
+	}
+
+	merged := SessionSampling(t.store, sessionKey).Merge(o)
+	raw, _ := json.Marshal(merged)
+	if err := t.store.SetSetting(SessionSamplingKey(sessionKey), string(raw)); err != nil {
+		return fmt.Sprintf("Error: failed to save settings: %v", err), nil
+	}
+	return fmt.Sprintf("This conversation now uses %s from the next message on.", merged), nil
+}
@@ -0,0 +1,111 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// sandboxWorkdir is where the workspace is mounted inside the container.
+const sandboxWorkdir = "/workspace"
+
+// SandboxOptions runs exec commands in a throwaway Docker or Podman
+// container with the workspace mounted, instead of on the host.
+type SandboxOptions struct {
+	Runtime string // "docker" (default) or "podman"
+	Image   string // Default "alpine:3"
+	// Network gives commands network access; the default is none.
+	Network bool
+	Memory  string // e.g. "512m"; empty means no limit
+	CPUs    string // e.g. "1"; empty means no limit
+	// Sessions selects the sessions that use the sandbox, with "channel:*"
+	// wildcards as for admins. Empty means every session.
+	Sessions []string
+}
+
+// appliesTo reports whether commands of sessionKey run in the sandbox.
+func (s *SandboxOptions) appliesTo(sessionKey string) bool {
+	return len(s.Sessions) == 0 || IsAdmin(s.Sessions, sessionKey)
+}
+
+// check refuses runs that cannot be mapped into the container.
+func (s *SandboxOptions) check(run execSpec) error {
+	if run.root == "" {
+		return fmt.Errorf("the sandbox needs a workspace to mount")
+	}
+	if _, err := s.containerDir(run); err != nil {
+		return err
+	}
+	return nil
+}
+
+// containerDir maps the host working directory into the container.
+func (s *SandboxOptions) containerDir(run execSpec) (string, error) {
+	root, err := filepath.Abs(run.root)
+	if err != nil {
+		return "", err
+	}
+	dir := root
+	if run.dir != "" {
+		if dir, err = filepath.Abs(run.dir); err != nil {
+			return "", err
+		}
+	}
+	if !withinDir(root, dir) {
+		return "", fmt.Errorf("in the sandbox the working directory must be within the workspace")
+	}
+	rel, _ := filepath.Rel(root, dir)
+	return filepath.ToSlash(filepath.Join(sandboxWorkdir, rel)), nil
+}
+
+// args returns the container runtime arguments for run; name identifies
+// the container so it can be killed.
+func (s *SandboxOptions) args(name string, run execSpec) []string {
+	root, _ := filepath.Abs(run.root)
+	dir, _ := s.containerDir(run)
+	image := s.Image
+	if image == "" {
+		image = "alpine:3"
+	}
+
+	args := []string{"run", "--rm", "-i", "--name", name,
+		"--cap-drop", "ALL", "--security-opt", "no-new-privileges", "--pids-limit", "256",
+		"-v", root + ":" + sandboxWorkdir, "-w", dir}
+	if !s.Network {
+		args = append(args, "--network", "none")
+	}
+	if s.Memory != "" {
+		args = append(args, "--memory", s.Memory)
+	}
+	if s.CPUs != "" {
+		args = append(args, "--cpus", s.CPUs)
+	}
+	// Files written to the workspace should belong to the gateway's user.
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, gid))
+	}
+	// Only per-command variables are passed; the host environment stays out.
+	for _, kv := range run.env {
+		args = append(args, "-e", kv)
+	}
+	// Images rarely ship the host's configured shell, so use sh.
+	return append(args, image, "sh", "-c", run.command)
+}
+
+// command builds the container invocation of run. Cancelling ctx kills the
+// container, not just the runtime client.
+func (s *SandboxOptions) command(ctx context.Context, run execSpec) *exec.Cmd {
+	runtime := s.Runtime
+	if runtime == "" {
+		runtime = "docker"
+	}
+	name := "gomikrobot-exec-" + newJobID()
+	cmd := exec.CommandContext(ctx, runtime, s.args(name, run)...)
+	cmd.Cancel = func() error {
+		_ = exec.Command(runtime, "kill", name).Run()
+		return cmd.Process.Kill()
+	}
+	return cmd
+}
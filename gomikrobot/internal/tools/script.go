@@ -0,0 +1,285 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const (
+	scriptManifest       = "tool.json"
+	scriptFile           = "tool.lua"
+	scriptDefaultTimeout = 10 * time.Second
+	scriptMaxTimeout     = 60 * time.Second
+	scriptMaxDepth       = 32
+	scriptOutputBytes    = 64 << 10 // Kept of what the script prints
+	scriptMaxRep         = 1 << 20  // Largest string string.rep may build
+)
+
+// scriptToolName is the form of script tool names.
+var scriptToolName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ScriptTool runs a Lua script from the workspace skills directory. A
+// script tool is skills/<dir>/tool.json, a manifest with name, description,
+// parameters (JSON schema), and an optional timeout in seconds, next to
+// skills/<dir>/tool.lua. The script defines run(args), which gets the
+// arguments as a table and returns a string or a table (sent as JSON);
+// text passed to print is returned when run returns nothing. Scripts only
+// have the base, string, table, and math libraries: no files, processes,
+// or network.
+type ScriptTool struct {
+	manifest scriptManifestData
+	script   string // Path of tool.lua; read on every call so edits apply at once
+}
+
+type scriptManifestData struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+	Timeout     int            `json:"timeout,omitempty"` // Seconds
+}
+
+// LoadScriptTools loads the script tools in skillsDir. Skills without a
+// manifest are skipped; invalid ones are returned as errors.
+func LoadScriptTools(skillsDir string) ([]*ScriptTool, []error) {
+	entries, err := os.ReadDir(skillsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, []error{err}
+	}
+	var loaded []*ScriptTool
+	var errs []error
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(skillsDir, e.Name())
+		if _, err := os.Stat(filepath.Join(dir, scriptManifest)); err != nil {
+			continue
+		}
+		t, err := loadScriptTool(dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("skill %s: %w", e.Name(), err))
+			continue
+		}
+		loaded = append(loaded, t)
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Name() < loaded[j].Name() })
+	return loaded, errs
+}
+
+func loadScriptTool(dir string) (*ScriptTool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, scriptManifest))
+	if err != nil {
+		return nil, err
+	}
+	var m scriptManifestData
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("%s: %w", scriptManifest, err)
+	}
+	if !scriptToolName.MatchString(m.Name) {
+		return nil, fmt.Errorf("%s: name %q must be lowercase letters, digits, and _", scriptManifest, m.Name)
+	}
+	if m.Description == "" {
+		return nil, fmt.Errorf("%s: description is required", scriptManifest)
+	}
+	if m.Parameters == nil {
+		m.Parameters = map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	t := &ScriptTool{manifest: m, script: filepath.Join(dir, scriptFile)}
+	if _, err := t.compile(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *ScriptTool) Name() string               { return t.manifest.Name }
+func (t *ScriptTool) Description() string        { return t.manifest.Description }
+func (t *ScriptTool) Parameters() map[string]any { return t.manifest.Parameters }
+
+func (t *ScriptTool) compile() (*lua.FunctionProto, error) {
+	src, err := os.ReadFile(t.script)
+	if err != nil {
+		return nil, err
+	}
+	chunk, err := parseLua(string(src), filepath.Base(t.script))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", scriptFile, err)
+	}
+	return chunk, nil
+}
+
+func (t *ScriptTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	chunk, err := t.compile()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	timeout := scriptDefaultTimeout
+	if t.manifest.Timeout > 0 {
+		timeout = min(time.Duration(t.manifest.Timeout)*time.Second, scriptMaxTimeout)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	L := newSandbox()
+	defer L.Close()
+	L.SetContext(ctx)
+	printed := &cappedBuffer{max: scriptOutputBytes}
+	L.SetGlobal("print", L.NewFunction(func(L *lua.LState) int {
+		for i := 1; i <= L.GetTop(); i++ {
+			if i > 1 {
+				io.WriteString(printed, "\t")
+			}
+			io.WriteString(printed, L.ToStringMeta(L.Get(i)).String())
+		}
+		io.WriteString(printed, "\n")
+		return 0
+	}))
+
+	L.Push(L.NewFunctionFromProto(chunk))
+	if err := L.PCall(0, 0, nil); err != nil {
+		return fmt.Sprintf("Error: %s: %v", t.Name(), scriptError(ctx, err)), nil
+	}
+	run, ok := L.GetGlobal("run").(*lua.LFunction)
+	if !ok {
+		return fmt.Sprintf("Error: %s does not define run(args)", scriptFile), nil
+	}
+	if err := L.CallByParam(lua.P{Fn: run, NRet: 1, Protect: true}, toLua(L, map[string]any(params))); err != nil {
+		return fmt.Sprintf("Error: %s: %v", t.Name(), scriptError(ctx, err)), nil
+	}
+	ret := L.Get(-1)
+	switch v := ret.(type) {
+	case lua.LString:
+		return string(v), nil
+	case *lua.LNilType:
+		return strings.TrimSuffix(printed.String(), "\n"), nil
+	}
+	out, err := json.Marshal(fromLua(ret, 0))
+	if err != nil {
+		return fmt.Sprintf("Error: %s returned an unsupported value: %v", t.Name(), err), nil
+	}
+	return string(out), nil
+}
+
+// scriptError reports a timeout instead of the interpreter's cancel error.
+func scriptError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out")
+	}
+	return err
+}
+
+// parseLua compiles src without running it.
+func parseLua(src, name string) (*lua.FunctionProto, error) {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	defer L.Close()
+	fn, err := L.Load(strings.NewReader(src), name)
+	if err != nil {
+		return nil, err
+	}
+	return fn.Proto, nil
+}
+
+// newSandbox returns a Lua state with only the pure libraries. Loading
+// code from files or strings is removed from the base library.
+func newSandbox() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true, CallStackSize: 200, RegistryMaxSize: 1 << 20})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range []string{"dofile", "loadfile", "load", "loadstring", "require", "module"} {
+		L.SetGlobal(name, lua.LNil)
+	}
+	// string.rep would otherwise build strings of any size in one call.
+	strlib := L.GetGlobal(lua.StringLibName).(*lua.LTable)
+	strlib.RawSetString("rep", L.NewFunction(func(L *lua.LState) int {
+		s, n := L.CheckString(1), L.CheckInt(2)
+		if n > 0 && len(s) > 0 && n > scriptMaxRep/len(s) {
+			L.RaiseError("string.rep: result larger than %d bytes", scriptMaxRep)
+		}
+		L.Push(lua.LString(strings.Repeat(s, max(n, 0))))
+		return 1
+	}))
+	return L
+}
+
+// toLua converts a decoded JSON value to Lua.
+func toLua(L *lua.LState, v any) lua.LValue {
+	switch v := v.(type) {
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case int:
+		return lua.LNumber(v)
+	case bool:
+		return lua.LBool(v)
+	case []any:
+		tbl := L.NewTable()
+		for _, item := range v {
+			tbl.Append(toLua(L, item))
+		}
+		return tbl
+	case map[string]any:
+		tbl := L.NewTable()
+		for k, item := range v {
+			tbl.RawSetString(k, toLua(L, item))
+		}
+		return tbl
+	}
+	return lua.LNil
+}
+
+// fromLua converts a Lua value to one json.Marshal accepts. Tables with
+// keys 1..n are arrays; other tables are objects with string keys. Tables
+// nested deeper than scriptMaxDepth, e.g. cycles, become null.
+func fromLua(v lua.LValue, depth int) any {
+	if depth > scriptMaxDepth {
+		return nil
+	}
+	switch v := v.(type) {
+	case lua.LString:
+		return string(v)
+	case lua.LNumber:
+		return float64(v)
+	case lua.LBool:
+		return bool(v)
+	case *lua.LTable:
+		n, count := v.MaxN(), 0
+		v.ForEach(func(lua.LValue, lua.LValue) { count++ })
+		if n > 0 && n == count {
+			list := make([]any, 0, n)
+			for i := 1; i <= n; i++ {
+				list = append(list, fromLua(v.RawGetInt(i), depth+1))
+			}
+			return list
+		}
+		obj := make(map[string]any, count)
+		v.ForEach(func(key, value lua.LValue) {
+			obj[key.String()] = fromLua(value, depth+1)
+		})
+		return obj
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSkill(t *testing.T, skills, dir, manifest, script string) {
+	t.Helper()
+	path := filepath.Join(skills, dir)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "tool.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(path, "tool.lua"), []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScriptTools(t *testing.T) {
+	skills := t.TempDir()
+	writeSkill(t, skills, "miles", `{"name": "miles_to_km", "description": "Convert miles to km",
+		"parameters": {"type": "object", "properties": {"miles": {"type": "number"}}}}`, `
+function run(args)
+  return string.format("%.1f km", args.miles * 1.609)
+end`)
+	writeSkill(t, skills, "split", `{"name": "split_words", "description": "Split text into words"}`, `
+function run(args)
+  local words = {}
+  for w in string.gmatch(args.text, "%S+") do table.insert(words, w) end
+  print("found", #words)
+  if args.table then return {words = words, count = #words} end
+end`)
+	writeSkill(t, skills, "escape", `{"name": "escape", "description": "Tries to leave the sandbox", "timeout": 1}`, `
+function run(args)
+  if args.loop then while true do end end
+  return io.open("/etc/passwd"):read("*a")
+end`)
+	writeSkill(t, skills, "broken", `{"name": "Broken!", "description": "x"}`, `function run(`)
+	os.MkdirAll(filepath.Join(skills, "docs_only"), 0755) // A SKILL.md skill without a tool
+
+	loaded, errs := LoadScriptTools(skills)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "broken") {
+		t.Errorf("expected one error for the broken skill, got %v", errs)
+	}
+	byName := map[string]*ScriptTool{}
+	for _, tool := range loaded {
+		byName[tool.Name()] = tool
+	}
+	if len(byName) != 3 {
+		t.Fatalf("expected 3 script tools, got %v", byName)
+	}
+
+	ctx := context.Background()
+	if out, _ := byName["miles_to_km"].Execute(ctx, map[string]any{"miles": float64(10)}); out != "16.1 km" {
+		t.Errorf("unexpected conversion %q", out)
+	}
+	if out, _ := byName["split_words"].Execute(ctx, map[string]any{"text": "a b c"}); out != "found\t3" {
+		t.Errorf("printed output should be the result when run returns nothing, got %q", out)
+	}
+	if out, _ := byName["split_words"].Execute(ctx, map[string]any{"text": "a b", "table": true}); out != `{"count":2,"words":["a","b"]}` {
+		t.Errorf("tables should be returned as JSON, got %q", out)
+	}
+	if out, _ := byName["escape"].Execute(ctx, nil); !strings.HasPrefix(out, "Error:") {
+		t.Errorf("io should not be available, got %q", out)
+	}
+	if out, _ := byName["escape"].Execute(ctx, map[string]any{"loop": true}); !strings.Contains(out, "timed out") {
+		t.Errorf("endless loop should time out, got %q", out)
+	}
+
+	// Edits apply on the next call.
+	os.WriteFile(filepath.Join(skills, "miles", "tool.lua"), []byte(`function run(args) return "edited" end`), 0644)
+	if out, _ := byName["miles_to_km"].Execute(ctx, map[string]any{"miles": float64(1)}); out != "edited" {
+		t.Errorf("script not reloaded, got %q", out)
+	}
+}
+
+func TestScriptToolOutputIsBounded(t *testing.T) {
+	skills := t.TempDir()
+	writeSkill(t, skills, "noisy", `{"name": "noisy", "description": "Prints a lot", "timeout": 5}`, `
+function run(args)
+  if args.rep then return string.rep("x", 1e12) end
+  if args.method then return ("x"):rep(1e12) end
+  for i = 1, 100000 do print("line", i) end
+end`)
+	loaded, errs := LoadScriptTools(skills)
+	if len(errs) > 0 || len(loaded) != 1 {
+		t.Fatalf("load: %v %v", loaded, errs)
+	}
+	tool, ctx := loaded[0], context.Background()
+
+	out, _ := tool.Execute(ctx, nil)
+	if len(out) > scriptOutputBytes+100 || !strings.Contains(out, "more bytes not shown") {
+		t.Errorf("printed output should be capped, got %d bytes", len(out))
+	}
+	for _, args := range []map[string]any{{"rep": true}, {"method": true}} {
+		if out, _ := tool.Execute(ctx, args); !strings.Contains(out, "string.rep: result larger") {
+			t.Errorf("%v: expected string.rep to be refused, got %.100q", args, out)
+		}
+	}
+}
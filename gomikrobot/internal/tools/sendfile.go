@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileSender delivers a file to the chat of a session.
+type FileSender interface {
+	SendFile(ctx context.Context, sessionKey, path, caption string) error
+}
+
+// maxFileBytes is the largest attachment each channel accepts; channels
+// not listed cannot send files. Telegram's limit is the Bot API's, Discord's
+// that of servers without boosts.
+var maxFileBytes = map[string]int64{
+	"whatsapp": 100 << 20,
+	"signal":   100 << 20,
+	"slack":    1 << 30,
+	"telegram": 50 << 20,
+	"discord":  10 << 20,
+}
+
+// SendFileTool sends a workspace file, e.g. a chart, a PDF, or a photo, to
+// the current chat.
+type SendFileTool struct {
+	sender    FileSender
+	workspace string
+}
+
+// NewSendFileTool creates a send_file tool for files in workspace.
+func NewSendFileTool(sender FileSender, workspace string) *SendFileTool {
+	return &SendFileTool{sender: sender, workspace: workspace}
+}
+
+func (t *SendFileTool) Name() string { return "send_file" }
+
+func (t *SendFileTool) Description() string {
+	return "Send a file from the workspace to this chat, e.g. a generated chart, a PDF, or a photo. " +
+		"Images, video, and audio are shown inline where the channel supports it; other files are sent as documents."
+}
+
+func (t *SendFileTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Path of the file, relative to the workspace",
+			},
+			"caption": map[string]any{
+				"type":        "string",
+				"description": "Short text sent with the file",
+			},
+		},
+		"required": []string{"path"},
+	}
+}
+
+func (t *SendFileTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	path := strings.TrimSpace(GetString(params, "path", ""))
+	if path == "" {
+		return "Error: path is required", nil
+	}
+	session := SessionKeyFrom(ctx)
+	channel, chatID, ok := strings.Cut(session, ":")
+	if !ok || chatID == "" {
+		return "Error: files need a chat to be sent to", nil
+	}
+	limit, ok := maxFileBytes[channel]
+	if !ok {
+		return fmt.Sprintf("Error: files cannot be sent on %s", channel), nil
+	}
+
+	root, err := filepath.Abs(t.workspace)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	root = realPath(root)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = realPath(filepath.Clean(path))
+	if !withinDir(root, path) {
+		return "Error: only files in the workspace can be sent", nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Sprintf("Error: file not found: %s", GetString(params, "path", "")), nil
+		}
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if !info.Mode().IsRegular() {
+		return "Error: only regular files can be sent", nil
+	}
+	if info.Size() == 0 {
+		return "Error: the file is empty", nil
+	}
+	if info.Size() > limit {
+		return fmt.Sprintf("Error: %s is %s; %s accepts at most %s", filepath.Base(path),
+			formatSize(info.Size()), channel, formatSize(limit)), nil
+	}
+
+	if err := t.sender.SendFile(ctx, session, path, GetString(params, "caption", "")); err != nil {
+		return fmt.Sprintf("Error sending file: %v", err), nil
+	}
+	return fmt.Sprintf("Sent %s (%s) to this chat.", filepath.Base(path), formatSize(info.Size())), nil
+}
+
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
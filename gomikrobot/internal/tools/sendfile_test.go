@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type recordingSender struct {
+	session, path, caption string
+}
+
+func (s *recordingSender) SendFile(ctx context.Context, sessionKey, path, caption string) error {
+	s.session, s.path, s.caption = sessionKey, path, caption
+	return nil
+}
+
+func TestSendFileTool(t *testing.T) {
+	workspace := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspace, "chart.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(workspace, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := &recordingSender{}
+	tool := NewSendFileTool(sender, workspace)
+	ctx := WithSessionKey(context.Background(), "whatsapp:49170@s.whatsapp.net")
+
+	out, _ := tool.Execute(ctx, map[string]any{"path": "chart.png", "caption": "Sales"})
+	if !strings.HasPrefix(out, "Sent chart.png") {
+		t.Fatalf("Execute = %q", out)
+	}
+	if sender.session != "whatsapp:49170@s.whatsapp.net" || filepath.Base(sender.path) != "chart.png" || sender.caption != "Sales" {
+		t.Errorf("sent %+v", sender)
+	}
+
+	for _, tt := range []struct {
+		ctx  context.Context
+		path string
+		want string
+	}{
+		{ctx, outside, "only files in the workspace"},
+		{ctx, "link.txt", "only files in the workspace"},
+		{ctx, "missing.pdf", "file not found"},
+		{ctx, ".", "only regular files"},
+		{WithSessionKey(context.Background(), "voice:call-1"), "chart.png", "cannot be sent on voice"},
+		{context.Background(), "chart.png", "need a chat"},
+	} {
+		if out, _ := tool.Execute(tt.ctx, map[string]any{"path": tt.path}); !strings.Contains(out, tt.want) {
+			t.Errorf("Execute(%s) = %q, want %q", tt.path, out, tt.want)
+		}
+	}
+}
+
+func TestSendFileToolChecksChannelLimit(t *testing.T) {
+	workspace := t.TempDir()
+	path := filepath.Join(workspace, "video.mp4")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sparse file just over Discord's limit.
+	if err := f.Truncate(maxFileBytes["discord"] + 1); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tool := NewSendFileTool(&recordingSender{}, workspace)
+	out, _ := tool.Execute(WithSessionKey(context.Background(), "discord:123"), map[string]any{"path": "video.mp4"})
+	if !strings.Contains(out, "discord accepts at most 10.0 MB") {
+		t.Errorf("Execute = %q", out)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
-	"path/filepath"
+	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -30,12 +32,38 @@ var DenyPatterns = []string{
 	`\bsystemctl\s+(start|stop|restart|enable|disable)\b`, // systemd control
 }
 
-// PathPatterns for detecting path traversal attempts.
-var PathPatterns = []string{
-	`\.\.\/`, // ../
-	`\.\.\\`, // ..\
-	`\/\.\.`, // /..
-	`\\\.\.`, // \..
+// DefaultEnvDeny lists environment variables that commands do not inherit
+// unless ExecOptions.EnvDeny says otherwise, so the gateway's provider and
+// channel credentials never reach child processes.
+var DefaultEnvDeny = []string{
+	"MIKROBOT_*", "OPENAI_*", "OPENROUTER_*", "ANTHROPIC_*", "GROQ_*",
+	"*_API_KEY", "*_APIKEY", "*_TOKEN", "*SECRET*", "*PASSWORD*", "*PASSWD*", "*CREDENTIAL*",
+}
+
+// blockedEnvInjection lists variables the model may not set per command,
+// since they change how every binary loads.
+var blockedEnvInjection = []string{"LD_*", "DYLD_*", "BASH_ENV", "ENV", "IFS"}
+
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ExecOptions configures an ExecTool.
+type ExecOptions struct {
+	Timeout             time.Duration
+	RestrictToWorkspace bool
+	WorkDir             string
+	// Shell runs commands as `<Shell> -c <command>` (default "sh").
+	Shell string
+	// EnvAllow, if set, limits inherited variables to these names; EnvDeny
+	// removes names from what is left (nil means DefaultEnvDeny). Both
+	// accept * wildcards.
+	EnvAllow []string
+	EnvDeny  []string
+	// Jobs enables run_in_background; nil runs every command in the
+	// foreground.
+	Jobs *JobManager
+	// Sandbox, if set, runs the commands of matching sessions in a
+	// container instead of on the host.
+	Sandbox *SandboxOptions
 }
 
 // ExecTool executes shell commands.
@@ -43,12 +71,22 @@ type ExecTool struct {
 	Timeout             time.Duration
 	RestrictToWorkspace bool
 	WorkDir             string
+	shell               string
+	envAllow            []string
+	envDeny             []string
+	jobs                *JobManager
+	sandbox             *SandboxOptions
 	denyRegexes         []*regexp.Regexp
-	pathRegexes         []*regexp.Regexp
 }
 
-// NewExecTool creates a new ExecTool.
+// NewExecTool creates a new ExecTool with the default shell and
+// environment policy.
 func NewExecTool(timeout time.Duration, restrictToWorkspace bool, workDir string) *ExecTool {
+	return NewExecToolWithOptions(ExecOptions{Timeout: timeout, RestrictToWorkspace: restrictToWorkspace, WorkDir: workDir})
+}
+
+// NewExecToolWithOptions creates an ExecTool from opts.
+func NewExecToolWithOptions(opts ExecOptions) *ExecTool {
 	// Compile deny patterns
 	denyRegexes := make([]*regexp.Regexp, 0, len(DenyPatterns))
 	for _, pattern := range DenyPatterns {
@@ -57,20 +95,25 @@ func NewExecTool(timeout time.Duration, restrictToWorkspace bool, workDir string
 		}
 	}
 
-	// Compile path patterns
-	pathRegexes := make([]*regexp.Regexp, 0, len(PathPatterns))
-	for _, pattern := range PathPatterns {
-		if re, err := regexp.Compile(pattern); err == nil {
-			pathRegexes = append(pathRegexes, re)
-		}
+	shell := opts.Shell
+	if shell == "" {
+		shell = "sh"
+	}
+	envDeny := opts.EnvDeny
+	if envDeny == nil {
+		envDeny = DefaultEnvDeny
 	}
 
 	return &ExecTool{
-		Timeout:             timeout,
-		RestrictToWorkspace: restrictToWorkspace,
-		WorkDir:             workDir,
+		Timeout:             opts.Timeout,
+		RestrictToWorkspace: opts.RestrictToWorkspace,
+		WorkDir:             opts.WorkDir,
+		shell:               shell,
+		envAllow:            opts.EnvAllow,
+		envDeny:             envDeny,
+		jobs:                opts.Jobs,
+		sandbox:             opts.Sandbox,
 		denyRegexes:         denyRegexes,
-		pathRegexes:         pathRegexes,
 	}
 }
 
@@ -92,6 +135,15 @@ func (t *ExecTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Optional working directory for the command",
 			},
+			"env": map[string]any{
+				"type":                 "object",
+				"description":          "Extra environment variables for this command, e.g. {\"GOOS\": \"linux\"}",
+				"additionalProperties": map[string]any{"type": "string"},
+			},
+			"run_in_background": map[string]any{
+				"type":        "boolean",
+				"description": "Start a long-running command (server, build, watch) and return a job ID at once; use the job tool to read output, send input, or kill it",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -99,17 +151,38 @@ func (t *ExecTool) Parameters() map[string]any {
 
 func (t *ExecTool) Execute(ctx context.Context, params map[string]any) (string, error) {
 	command := GetString(params, "command", "")
-	workingDir := GetString(params, "working_dir", t.WorkDir)
+	// The active project, if any, replaces the workspace as the root.
+	root := t.WorkDir
+	if dir := projectDir(ctx); dir != "" {
+		root = dir
+	}
+	workingDir := GetString(params, "working_dir", root)
 
 	if command == "" {
 		return "Error: command is required", nil
 	}
 
 	// Security checks
-	if err := t.guardCommand(command, workingDir); err != nil {
+	if err := t.guardCommand(command, workingDir, root); err != nil {
 		return err.Error(), nil
 	}
 
+	extra, err := parseEnv(params["env"])
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	run := execSpec{session: SessionKeyFrom(ctx), command: command, dir: workingDir, root: root, env: extra}
+	run.sandboxed = t.sandbox != nil && t.sandbox.appliesTo(PolicySessionFrom(ctx))
+	if run.sandboxed {
+		if err := t.sandbox.check(run); err != nil {
+			return "Error: " + err.Error(), nil
+		}
+	}
+
+	if GetBool(params, "run_in_background", false) {
+		return t.startJob(run), nil
+	}
+
 	// Create command with timeout
 	timeout := t.Timeout
 	if timeout == 0 {
@@ -119,16 +192,12 @@ func (t *ExecTool) Execute(ctx context.Context, params map[string]any) (string,
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "sh", "-c", command)
-	if workingDir != "" {
-		cmd.Dir = workingDir
-	}
-
+	cmd := t.newCmd(ctx, run)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	err := cmd.Run()
+	err = cmd.Run()
 
 	// Build result
 	var result strings.Builder
@@ -162,7 +231,99 @@ func (t *ExecTool) Execute(ctx context.Context, params map[string]any) (string,
 	return result.String(), nil
 }
 
-func (t *ExecTool) guardCommand(command, workingDir string) error {
+// execSpec is one command to run.
+type execSpec struct {
+	session string
+	command string
+	dir     string // Working directory on the host
+	root    string // Workspace or active project
+	env     []string
+	// sandboxed runs the command in the sandbox.
+	sandboxed bool
+}
+
+// newCmd builds the command for run, inside the sandbox if run is
+// sandboxed and on the host otherwise.
+func (t *ExecTool) newCmd(ctx context.Context, run execSpec) *exec.Cmd {
+	if run.sandboxed {
+		return t.sandbox.command(ctx, run)
+	}
+	cmd := exec.CommandContext(ctx, t.shell, "-c", run.command)
+	cmd.Dir = run.dir
+	cmd.Env = append(t.environ(), run.env...)
+	return cmd
+}
+
+// startJob runs a command as a background job of its session.
+func (t *ExecTool) startJob(run execSpec) string {
+	if t.jobs == nil {
+		return "Error: background jobs are not available here; run the command without run_in_background"
+	}
+	job, err := t.jobs.Start(run.session, run.command, run.dir, func(jobCtx context.Context) *exec.Cmd {
+		return t.newCmd(jobCtx, run)
+	})
+	if err != nil {
+		return fmt.Sprintf("Error starting background job: %v", err)
+	}
+	return fmt.Sprintf("Started background job %s (pid %d). Use the job tool with id %s to read its output, send input, or kill it.", job.ID, job.PID, job.ID)
+}
+
+// environ returns the process environment filtered by the allow and deny
+// lists.
+func (t *ExecTool) environ() []string {
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if len(t.envAllow) > 0 && !matchesEnv(t.envAllow, name) || matchesEnv(t.envDeny, name) {
+			continue
+		}
+		env = append(env, kv)
+	}
+	return env
+}
+
+// parseEnv validates the variables the model passed for one command and
+// returns them as NAME=value pairs.
+func parseEnv(v any) ([]string, error) {
+	vars, ok := v.(map[string]any)
+	if v != nil && !ok {
+		return nil, fmt.Errorf("env must be an object of strings")
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var env []string
+	for _, name := range names {
+		value, ok := vars[name].(string)
+		if !ok {
+			return nil, fmt.Errorf("env %s must be a string", name)
+		}
+		if !envName.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment variable name %q", name)
+		}
+		if matchesEnv(blockedEnvInjection, name) {
+			return nil, fmt.Errorf("setting %s is not allowed", name)
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// matchesEnv reports whether name matches one of the wildcard patterns,
+// ignoring case.
+func matchesEnv(patterns []string, name string) bool {
+	name = strings.ToUpper(name)
+	for _, p := range patterns {
+		if ok, _ := path.Match(strings.ToUpper(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ExecTool) guardCommand(command, workingDir, root string) error {
 	// Check deny patterns
 	for _, re := range t.denyRegexes {
 		if re.MatchString(command) {
@@ -170,22 +331,9 @@ func (t *ExecTool) guardCommand(command, workingDir string) error {
 		}
 	}
 
-	// Check path traversal if workspace restricted
-	if t.RestrictToWorkspace && t.WorkDir != "" {
-		for _, re := range t.pathRegexes {
-			if re.MatchString(command) {
-				return fmt.Errorf("Error: path traversal not allowed")
-			}
-		}
-
-		// Additional check: command shouldn't reference paths outside workspace
-		absWorkDir, _ := filepath.Abs(t.WorkDir)
-		if workingDir != "" && workingDir != t.WorkDir {
-			absWorkingDir, _ := filepath.Abs(workingDir)
-			if !strings.HasPrefix(absWorkingDir, absWorkDir) {
-				return fmt.Errorf("Error: working directory must be within workspace")
-			}
-		}
+	// Paths in the command must resolve inside the workspace
+	if t.RestrictToWorkspace && root != "" {
+		return guardPaths(command, workingDir, root)
 	}
 
 	return nil
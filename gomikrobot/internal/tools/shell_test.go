@@ -2,6 +2,8 @@ package tools
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -69,6 +71,51 @@ func TestExecTool_PathTraversal(t *testing.T) {
 	}
 }
 
+func TestExecTool_PathsResolveInsideWorkspace(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewExecTool(5*time.Second, true, root)
+
+	blocked := []string{
+		"cat /etc/passwd",
+		"cat escape/secret",
+		"ls sub/../../",
+		"cp x --target-directory=" + outside,
+		"cat ~/.ssh/id_rsa",
+		"cat $SOME_DIR/file",
+	}
+	for _, cmd := range blocked {
+		if err := tool.guardCommand(cmd, root, root); err == nil {
+			t.Errorf("expected %q to be blocked", cmd)
+		}
+	}
+
+	allowed := []string{
+		`grep -r "a/b" .`,
+		"ls ./sub && cat sub/new.txt",
+		"head -c 8 /dev/urandom",
+		"/usr/bin/env ls",
+		"curl -s https://example.com/a/b",
+		"cat " + filepath.Join(root, "sub"),
+	}
+	for _, cmd := range allowed {
+		if err := tool.guardCommand(cmd, root, root); err != nil {
+			t.Errorf("expected %q to be allowed, got %v", cmd, err)
+		}
+	}
+
+	// A sibling directory sharing the workspace's name prefix is outside.
+	if err := tool.guardCommand("ls", root+"2", root); err == nil {
+		t.Error("expected sibling working directory to be rejected")
+	}
+}
+
 func TestExecTool_WorkingDir(t *testing.T) {
 	tmpDir := t.TempDir()
 	tool := NewExecTool(5*time.Second, false, tmpDir)
@@ -111,3 +158,97 @@ func TestExecTool_ExitCode(t *testing.T) {
 		t.Errorf("expected 'Exit code: 42' in output, got '%s'", result)
 	}
 }
+
+func TestExecTool_Environment(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-secret")
+	t.Setenv("MIKROBOT_GATEWAY_API_TOKEN", "tok")
+	t.Setenv("BUILD_FLAVOR", "release")
+
+	tool := NewExecTool(5*time.Second, false, "")
+	result, _ := tool.Execute(context.Background(), map[string]any{
+		"command": "echo key=$OPENAI_API_KEY tok=$MIKROBOT_GATEWAY_API_TOKEN flavor=$BUILD_FLAVOR extra=$GREETING",
+		"env":     map[string]any{"GREETING": "hi"},
+	})
+	if !strings.Contains(result, "key= tok= flavor=release extra=hi") {
+		t.Errorf("unexpected environment: %q", result)
+	}
+
+	result, _ = tool.Execute(context.Background(), map[string]any{
+		"command": "true",
+		"env":     map[string]any{"LD_PRELOAD": "/tmp/x.so"},
+	})
+	if !strings.Contains(result, "not allowed") {
+		t.Errorf("expected LD_PRELOAD to be refused, got %q", result)
+	}
+
+	allowOnly := NewExecToolWithOptions(ExecOptions{Timeout: 5 * time.Second, EnvAllow: []string{"PATH"}})
+	result, _ = allowOnly.Execute(context.Background(), map[string]any{"command": "echo flavor=$BUILD_FLAVOR"})
+	if !strings.Contains(result, "flavor=\n") {
+		t.Errorf("expected allowlist to drop BUILD_FLAVOR, got %q", result)
+	}
+}
+
+func TestExecTool_BackgroundJobs(t *testing.T) {
+	jobs := NewJobManager(nil)
+	tool := NewExecToolWithOptions(ExecOptions{Timeout: time.Second, Jobs: jobs})
+	jobTool := NewJobTool(jobs)
+	ctx := WithSessionKey(context.Background(), "cli:default")
+
+	out, _ := tool.Execute(ctx, map[string]any{"command": "read line; echo got $line", "run_in_background": true})
+	if !strings.HasPrefix(out, "Started background job") {
+		t.Fatalf("unexpected start result %q", out)
+	}
+	id := jobs.List("cli:default")[0].ID
+
+	if out, _ := jobTool.Execute(ctx, map[string]any{"action": "input", "id": id, "input": "hi\n"}); !strings.HasPrefix(out, "Sent") {
+		t.Fatalf("input: %q", out)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		out, _ = jobTool.Execute(ctx, map[string]any{"action": "output", "id": id})
+		if strings.Contains(out, "exited 0") || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if !strings.Contains(out, "got hi") || !strings.Contains(out, "exited 0") {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	// Background jobs outlive the foreground timeout until killed.
+	tool.Execute(ctx, map[string]any{"command": "sleep 30", "run_in_background": true})
+	sleeper := jobs.List("cli:default")[0]
+	time.Sleep(1200 * time.Millisecond)
+	if out, _ := jobTool.Execute(WithSessionKey(context.Background(), "cli:other"), map[string]any{"action": "kill", "id": sleeper.ID}); !strings.Contains(out, "not found") {
+		t.Errorf("other sessions must not see the job, got %q", out)
+	}
+	if out, _ := jobTool.Execute(ctx, map[string]any{"action": "kill", "id": sleeper.ID}); !strings.HasPrefix(out, "Killed") {
+		t.Errorf("kill: %q", out)
+	}
+	if out, _ := jobTool.Execute(ctx, map[string]any{"action": "list"}); !strings.Contains(out, "[killed]") {
+		t.Errorf("list after kill: %q", out)
+	}
+}
+
+func TestSandboxArgs(t *testing.T) {
+	root := t.TempDir()
+	sb := &SandboxOptions{Image: "busybox", Memory: "256m", Sessions: []string{"whatsapp:*"}}
+	if sb.appliesTo("cli:default") || !sb.appliesTo("whatsapp:123@s.whatsapp.net") {
+		t.Error("sandbox should apply to whatsapp sessions only")
+	}
+
+	run := execSpec{command: "ls", root: root, dir: root + "/sub", env: []string{"FOO=bar"}}
+	args := strings.Join(sb.args("box", run), " ")
+	for _, want := range []string{
+		"run --rm -i --name box", "--network none", "--memory 256m",
+		"-v " + root + ":/workspace", "-w /workspace/sub", "-e FOO=bar", "busybox sh -c ls",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("expected %q in %q", want, args)
+		}
+	}
+
+	if err := sb.check(execSpec{command: "ls", root: root, dir: "/etc"}); err == nil {
+		t.Error("expected a working directory outside the workspace to be refused")
+	}
+}
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+// TaskSpawner starts background sub-agent tasks.
+type TaskSpawner interface {
+	// SpawnTask queues prompt as a task and returns its id. The session in
+	// ctx is notified when it finishes.
+	SpawnTask(ctx context.Context, title, prompt string) (string, error)
+}
+
+// SpawnTaskTool hands long-running work to a background sub-agent.
+type SpawnTaskTool struct {
+	spawner TaskSpawner
+}
+
+// NewSpawnTaskTool creates a spawn_task tool.
+func NewSpawnTaskTool(spawner TaskSpawner) *SpawnTaskTool {
+	return &SpawnTaskTool{spawner: spawner}
+}
+
+func (t *SpawnTaskTool) Name() string { return "spawn_task" }
+
+func (t *SpawnTaskTool) Description() string {
+	return "Start a long-running task in the background, e.g. \"research X and write a report to reports/x.md\". " +
+		"A separate agent with its own conversation does the work; this chat is notified when it finishes. " +
+		"The task prompt must be self-contained because the sub-agent cannot see this conversation."
+}
+
+func (t *SpawnTaskTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"title": map[string]any{
+				"type":        "string",
+				"description": "Short title shown in notifications",
+			},
+			"task": map[string]any{
+				"type":        "string",
+				"description": "Complete instructions for the sub-agent, including where to write results",
+			},
+		},
+		"required": []string{"task"},
+	}
+}
+
+func (t *SpawnTaskTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	prompt := GetString(params, "task", "")
+	if prompt == "" {
+		return "Error: task is required", nil
+	}
+	title := GetString(params, "title", "")
+	if title == "" {
+		title = prompt
+		if len(title) > 60 {
+			title = title[:60] + "..."
+		}
+	}
+	id, err := t.spawner.SpawnTask(ctx, title, prompt)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return fmt.Sprintf("Started task %s (%s). You will be notified here when it finishes.", id, title), nil
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
+)
+
+// TimelineSearcher runs full-text queries over the timeline.
+type TimelineSearcher interface {
+	Search(query string, filter timeline.FilterArgs) ([]timeline.SearchResult, error)
+}
+
+// TimelineSearchTool lets the agent find past conversations by keyword.
+type TimelineSearchTool struct {
+	timeline TimelineSearcher
+}
+
+// NewTimelineSearchTool creates a timeline_search tool.
+func NewTimelineSearchTool(tl TimelineSearcher) *TimelineSearchTool {
+	return &TimelineSearchTool{timeline: tl}
+}
+
+func (t *TimelineSearchTool) Name() string { return "timeline_search" }
+
+func (t *TimelineSearchTool) Description() string {
+	return "Search past messages across all conversations by keyword. All words must match; end a word with * for a prefix match."
+}
+
+func (t *TimelineSearchTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "Keywords to search for",
+			},
+			"sender_id": map[string]any{
+				"type":        "string",
+				"description": "Optional sender to restrict the search to",
+			},
+			"limit": map[string]any{
+				"type":        "integer",
+				"description": "Maximum results (default 10)",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *TimelineSearchTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	query := GetString(params, "query", "")
+	if strings.TrimSpace(query) == "" {
+		return "Error: query is required", nil
+	}
+
+	authorized := true
+	results, err := t.timeline.Search(query, timeline.FilterArgs{
+		SenderID:       GetString(params, "sender_id", ""),
+		Limit:          GetInt(params, "limit", 10),
+		AuthorizedOnly: &authorized,
+	})
+	if err != nil {
+		return fmt.Sprintf("Error: search failed: %v", err), nil
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("No messages found for %q.", query), nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d messages:\n", len(results))
+	for _, r := range results {
+		name := r.SenderName
+		if name == "" {
+			name = r.SenderID
+		}
+		fmt.Fprintf(&sb, "- %s %s (%s): %s\n", r.Timestamp.Format("2006-01-02 15:04"), name, r.SenderID, r.Snippet)
+	}
+	return sb.String(), nil
+}
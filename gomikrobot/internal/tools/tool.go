@@ -4,6 +4,8 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
 )
 
 // Tool is the interface that all agent tools must implement.
@@ -20,8 +22,10 @@ type Tool interface {
 }
 
 // Registry manages tool registration and execution.
+// It is safe for concurrent use.
 type Registry struct {
 	tools map[string]Tool
+	mu    sync.RWMutex
 }
 
 // NewRegistry creates a new tool registry.
@@ -31,30 +35,59 @@ func NewRegistry() *Registry {
 	}
 }
 
+// RegisterDefaults adds the file, document, git, shell, and code tools, rooted at
+// workspace.
+func RegisterDefaults(r *Registry, workspace string) {
+	r.Register(NewReadFileTool())
+	r.Register(NewWriteFileTool())
+	r.Register(NewEditFileTool())
+	r.Register(NewListDirTool())
+	r.Register(NewTreeTool())
+	r.Register(NewReadDocumentTool(workspace))
+	r.Register(NewGitStatusTool(workspace))
+	r.Register(NewGitDiffTool(workspace))
+	r.Register(NewGitLogTool(workspace))
+	r.Register(NewGitCommitTool(workspace))
+	r.Register(NewExecTool(0, true, workspace))
+	r.Register(NewRunCodeTool(RunCodeOptions{}))
+	r.Register(NewReadArtifactTool(workspace))
+	r.Register(NewRenderChartTool(workspace))
+}
+
 // Register adds a tool to the registry.
 func (r *Registry) Register(tool Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	r.tools[tool.Name()] = tool
 }
 
 // Get returns a tool by name.
 func (r *Registry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	tool, ok := r.tools[name]
 	return tool, ok
 }
 
-// List returns all registered tools.
+// List returns all registered tools, sorted by name.
 func (r *Registry) List() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	result := make([]Tool, 0, len(r.tools))
 	for _, tool := range r.tools {
 		result = append(result, tool)
 	}
+	// A stable order keeps prompts identical across requests, which
+	// prompt caching depends on.
+	sort.Slice(result, func(i, j int) bool { return result[i].Name() < result[j].Name() })
 	return result
 }
 
 // Definitions returns tool definitions in OpenAI format.
 func (r *Registry) Definitions() []map[string]any {
-	result := make([]map[string]any, 0, len(r.tools))
-	for _, tool := range r.tools {
+	tools := r.List()
+	result := make([]map[string]any, 0, len(tools))
+	for _, tool := range tools {
 		result = append(result, map[string]any{
 			"type": "function",
 			"function": map[string]any{
@@ -68,11 +101,17 @@ func (r *Registry) Definitions() []map[string]any {
 }
 
 // Execute runs a tool by name with the given parameters.
+// If ctx carries a Budget, one call is charged and exhausted budgets fail.
 func (r *Registry) Execute(ctx context.Context, name string, params map[string]any) (string, error) {
-	tool, ok := r.tools[name]
+	tool, ok := r.Get(name)
 	if !ok {
 		return "", fmt.Errorf("tool not found: %s", name)
 	}
+	if b := BudgetFrom(ctx); b != nil {
+		if err := b.take(); err != nil {
+			return "", err
+		}
+	}
 	return tool.Execute(ctx, params)
 }
 
@@ -99,6 +138,19 @@ func GetInt(params map[string]any, key string, defaultVal int) int {
 	return defaultVal
 }
 
+// GetFloat extracts a number parameter with a default value.
+func GetFloat(params map[string]any, key string, defaultVal float64) float64 {
+	if v, ok := params[key]; ok {
+		switch n := v.(type) {
+		case float64:
+			return n
+		case int:
+			return float64(n)
+		}
+	}
+	return defaultVal
+}
+
 // GetBool extracts a bool parameter with a default value.
 func GetBool(params map[string]any, key string, defaultVal bool) bool {
 	if v, ok := params[key]; ok {
@@ -1,11 +1,20 @@
 package tools
 
 import (
+	"archive/zip"
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/timeline"
 )
 
 func TestRegistry(t *testing.T) {
@@ -72,6 +81,40 @@ func TestReadFileTool(t *testing.T) {
 	}
 }
 
+func TestReadFileToolLineRanges(t *testing.T) {
+	tool := NewReadFileTool()
+	path := filepath.Join(t.TempDir(), "app.log")
+	var sb strings.Builder
+	for i := 1; i <= 12; i++ {
+		sb.WriteString("line " + strconv.Itoa(i) + "\n")
+	}
+	os.WriteFile(path, []byte(sb.String()), 0644)
+
+	tests := []struct {
+		params map[string]any
+		want   []string
+		not    string
+	}{
+		{map[string]any{"start_line": float64(3), "end_line": float64(4)}, []string{"[Lines 3–4 of 12]", "3| line 3\n4| line 4"}, "line 5"},
+		{map[string]any{"head": float64(2)}, []string{"[Lines 1–2 of 12]", "1| line 1"}, "line 3"},
+		{map[string]any{"tail": float64(2)}, []string{"[Lines 11–12 of 12]", "12| line 12"}, "line 10"},
+		{map[string]any{"start_line": float64(20)}, []string{"the file has 12 lines"}, "|"},
+		{map[string]any{"head": float64(2), "tail": float64(2)}, []string{"Error"}, "|"},
+	}
+	for _, tt := range tests {
+		tt.params["path"] = path
+		got, _ := tool.Execute(context.Background(), tt.params)
+		for _, w := range tt.want {
+			if !strings.Contains(got, w) {
+				t.Errorf("%v: expected %q in %q", tt.params, w, got)
+			}
+		}
+		if strings.Contains(got, tt.not) {
+			t.Errorf("%v: unexpected %q in %q", tt.params, tt.not, got)
+		}
+	}
+}
+
 func TestWriteFileTool(t *testing.T) {
 	tool := NewWriteFileTool()
 	tmpDir := t.TempDir()
@@ -96,6 +139,38 @@ func TestWriteFileTool(t *testing.T) {
 	}
 }
 
+func TestExpectedSHA256RefusesStaleWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	os.WriteFile(path, []byte("v1"), 0644)
+
+	read, _ := NewReadFileTool().Execute(context.Background(), map[string]any{"path": path, "include_sha256": true})
+	sum := strings.TrimSuffix(strings.TrimPrefix(strings.SplitN(read, "\n", 2)[0], "[sha256 "), "]")
+	if len(sum) != 64 {
+		t.Fatalf("expected a checksum header, got %q", read)
+	}
+
+	// Another session changes the file in between.
+	os.WriteFile(path, []byte("v2"), 0644)
+
+	out, _ := NewWriteFileTool().Execute(context.Background(), map[string]any{"path": path, "content": "mine", "expected_sha256": sum})
+	if !strings.Contains(out, "changed since it was read") {
+		t.Errorf("write_file: expected a conflict, got %q", out)
+	}
+	out, _ = NewEditFileTool().Execute(context.Background(), map[string]any{"path": path, "old_text": "v", "new_text": "w", "expected_sha256": sum})
+	if !strings.Contains(out, "changed since it was read") {
+		t.Errorf("edit_file: expected a conflict, got %q", out)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "v2" {
+		t.Errorf("file was overwritten: %q", data)
+	}
+
+	fresh, _ := fileSHA256(path)
+	out, _ = NewEditFileTool().Execute(context.Background(), map[string]any{"path": path, "old_text": "v", "new_text": "w", "expected_sha256": fresh})
+	if !strings.Contains(out, "Successfully edited") || !strings.Contains(out, contentSHA256([]byte("w2"))) {
+		t.Errorf("edit_file with current checksum: got %q", out)
+	}
+}
+
 func TestEditFileTool(t *testing.T) {
 	tool := NewEditFileTool()
 	tmpDir := t.TempDir()
@@ -188,3 +263,354 @@ func TestGetHelpers(t *testing.T) {
 		t.Error("GetBool default failed")
 	}
 }
+
+func TestRegistryEnforcesBudget(t *testing.T) {
+	r := NewRegistry()
+	r.Register(NewListDirTool())
+	ctx := WithBudget(context.Background(), NewBudget(1, 0))
+
+	if _, err := r.Execute(ctx, "list_dir", map[string]any{"path": t.TempDir()}); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if _, err := r.Execute(ctx, "list_dir", map[string]any{"path": t.TempDir()}); !errors.Is(err, ErrBudgetCalls) {
+		t.Errorf("expected ErrBudgetCalls, got %v", err)
+	}
+
+	expired := WithBudget(context.Background(), NewBudget(0, time.Nanosecond))
+	time.Sleep(time.Millisecond)
+	if _, err := r.Execute(expired, "list_dir", map[string]any{"path": t.TempDir()}); !errors.Is(err, ErrBudgetTime) {
+		t.Errorf("expected ErrBudgetTime, got %v", err)
+	}
+}
+
+func TestSwitchProjectChangesExecRoot(t *testing.T) {
+	blog := t.TempDir()
+	tool := NewSwitchProjectTool([]Project{{Name: "blog", Path: blog}})
+	state := NewProjectState(nil)
+	ctx := WithProject(context.Background(), state)
+
+	result, _ := tool.Execute(ctx, map[string]any{"name": "nope"})
+	if !strings.Contains(result, "unknown project") || !strings.Contains(result, "blog") {
+		t.Errorf("expected unknown project with list, got %q", result)
+	}
+
+	if result, _ = tool.Execute(ctx, map[string]any{"name": "Blog"}); strings.HasPrefix(result, "Error") {
+		t.Fatalf("switch failed: %s", result)
+	}
+	if p := state.Active(); p == nil || p.Name != "blog" {
+		t.Fatalf("expected blog to be active, got %+v", p)
+	}
+
+	exec := NewExecTool(0, true, t.TempDir())
+	out, _ := exec.Execute(ctx, map[string]any{"command": "pwd"})
+	if got, _ := filepath.EvalSymlinks(strings.TrimSpace(out)); got != mustEval(t, blog) {
+		t.Errorf("exec ran in %q, want %q", out, blog)
+	}
+
+	tool.Execute(ctx, map[string]any{"name": "default"})
+	if state.Active() != nil {
+		t.Error("expected default workspace after switching back")
+	}
+}
+
+func mustEval(t *testing.T, path string) string {
+	t.Helper()
+	p, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+type memSettings map[string]string
+
+func (m memSettings) GetSetting(key string) (string, error) { return m[key], nil }
+func (m memSettings) SetSetting(key, value string) error    { m[key] = value; return nil }
+
+func TestSetModelToolStoresOverride(t *testing.T) {
+	store := memSettings{}
+	tool := NewSetModelTool(store, []string{"gpt-4o-mini"})
+	ctx := WithSessionKey(context.Background(), "whatsapp:123")
+
+	if result, _ := tool.Execute(ctx, map[string]any{"model": "gpt-4o-mini"}); strings.HasPrefix(result, "Error") {
+		t.Fatalf("set_model failed: %s", result)
+	}
+	if got := store[SessionModelKey("whatsapp:123")]; got != "gpt-4o-mini" {
+		t.Errorf("expected override gpt-4o-mini, got %q", got)
+	}
+
+	if result, _ := tool.Execute(ctx, map[string]any{"model": "gpt-4o-mnii"}); !strings.HasPrefix(result, "Error") {
+		t.Errorf("expected a model off the list to be refused, got %q", result)
+	}
+	if got := store[SessionModelKey("whatsapp:123")]; got != "gpt-4o-mini" {
+		t.Errorf("a refused model changed the override to %q", got)
+	}
+
+	tool.Execute(ctx, map[string]any{"model": "default"})
+	if got := store[SessionModelKey("whatsapp:123")]; got != "" {
+		t.Errorf("expected override cleared, got %q", got)
+	}
+
+	if result, _ := tool.Execute(context.Background(), map[string]any{"model": "x"}); !strings.HasPrefix(result, "Error") {
+		t.Errorf("expected error without a session, got %q", result)
+	}
+}
+
+func TestReadDocumentDOCX(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "report.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("word/document.xml")
+	w.Write([]byte(`<w:document xmlns:w="w"><w:body>` +
+		`<w:p><w:r><w:t>Quarterly</w:t></w:r><w:r><w:t xml:space="preserve"> report</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:br w:type="page"/></w:r></w:p>` +
+		`<w:p><w:r><w:t>Revenue up 12%</w:t></w:r></w:p>` +
+		`</w:body></w:document>`))
+	zw.Close()
+	f.Close()
+
+	tool := NewReadDocumentTool(dir)
+	out, _ := tool.Execute(context.Background(), map[string]any{"path": "report.docx"})
+	for _, want := range []string{"(2 pages)", "--- Page 1 ---\nQuarterly report", "--- Page 2 ---\nRevenue up 12%"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]any{"path": "report.docx", "chunk": 3})
+	if !strings.HasPrefix(out, "Error:") {
+		t.Errorf("expected error for out-of-range chunk, got %q", out)
+	}
+}
+
+func TestHTTPRequestToolAllowlist(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)+"/state", http.StatusFound)
+		case "/state":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"auth":"` + r.Header.Get("Authorization") + `","padding":"` + strings.Repeat("x", 100) + `"}`))
+		}
+	}))
+	defer srv.Close()
+
+	tool := NewHTTPRequestTool(HTTPRequestOptions{
+		AllowedDomains:   []string{"127.0.0.1"},
+		MaxResponseBytes: 64,
+		Headers:          map[string]map[string]string{"127.0.0.1": {"Authorization": "Bearer ha"}},
+	})
+	ctx := context.Background()
+
+	out, _ := tool.Execute(ctx, map[string]any{"url": srv.URL + "/state"})
+	if !strings.Contains(out, "HTTP 200 OK") || !strings.Contains(out, `"auth":"Bearer ha"`) || !strings.Contains(out, "[response truncated at 64 bytes]") {
+		t.Errorf("unexpected response:\n%s", out)
+	}
+
+	out, _ = tool.Execute(ctx, map[string]any{"url": strings.Replace(srv.URL, "127.0.0.1", "localhost", 1) + "/state"})
+	if !strings.Contains(out, "not in the allowlist") {
+		t.Errorf("expected allowlist rejection, got %q", out)
+	}
+
+	out, _ = tool.Execute(ctx, map[string]any{"url": srv.URL + "/redirect"})
+	if !strings.HasPrefix(out, "Error: request failed") {
+		t.Errorf("expected redirect off the allowlist to fail, got %q", out)
+	}
+}
+
+func TestHTTPRequestToolKeepsCredentialsOnTheirHost(t *testing.T) {
+	var gotKey string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, strings.Replace("http://"+r.Host, "127.0.0.1", "localhost", 1)+"/state", http.StatusFound)
+		case "/state":
+			gotKey = r.Header.Get("X-Api-Key")
+		}
+	}))
+	defer srv.Close()
+
+	tool := NewHTTPRequestTool(HTTPRequestOptions{
+		AllowedDomains: []string{"127.0.0.1", "localhost"},
+		Headers:        map[string]map[string]string{"127.0.0.1": {"X-Api-Key": "secret"}},
+	})
+	out, _ := tool.Execute(context.Background(), map[string]any{"url": srv.URL + "/redirect"})
+	if !strings.Contains(out, "HTTP 200 OK") {
+		t.Fatalf("unexpected response:\n%s", out)
+	}
+	if gotKey != "" {
+		t.Errorf("the redirect target received the key %q of another entry", gotKey)
+	}
+}
+
+func TestGitCommitTool(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v %s", err, out)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.md"), []byte("hello\n"), 0644)
+	ctx := context.Background()
+
+	out, _ := NewGitStatusTool(dir).Execute(ctx, nil)
+	if !strings.Contains(out, "?? notes.md") {
+		t.Errorf("expected untracked file in status, got %q", out)
+	}
+
+	out, _ = NewGitCommitTool(dir).Execute(ctx, map[string]any{"message": "Add notes"})
+	if !strings.HasPrefix(out, "Committed ") || !strings.Contains(out, "notes.md") {
+		t.Fatalf("unexpected commit result %q", out)
+	}
+
+	out, _ = NewGitLogTool(dir).Execute(ctx, map[string]any{})
+	if !strings.Contains(out, "GoMikroBot: Add notes") {
+		t.Errorf("expected agent-authored commit in log, got %q", out)
+	}
+
+	out, _ = NewGitDiffTool(dir).Execute(ctx, map[string]any{"paths": []any{"../etc/passwd"}})
+	if !strings.HasPrefix(out, "Error:") {
+		t.Errorf("expected path outside the repository to be rejected, got %q", out)
+	}
+}
+
+type memEvents []*timeline.TimelineEvent
+
+func (m *memEvents) AddEvent(e *timeline.TimelineEvent) error { *m = append(*m, e); return nil }
+
+func TestUpdateIdentityProposeApply(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "SOUL.md"), []byte("Be concise.\nBe kind.\n"), 0600)
+	var events memEvents
+	tool := NewUpdateIdentityTool(dir, []string{"whatsapp:*"}, &events)
+
+	out, _ := tool.Execute(WithSessionKey(context.Background(), "slack:C1"), map[string]any{"action": "propose"})
+	if !strings.Contains(out, "admin chats") {
+		t.Fatalf("expected non-admin session to be refused, got %q", out)
+	}
+
+	session := WithSessionKey(context.Background(), "whatsapp:4917@s.whatsapp.net")
+	turn := WithTurn(session)
+	out, _ = tool.Execute(turn, map[string]any{"action": "propose", "file": "SOUL.md", "content": "Be concise.\nBe warm.\n", "reason": "user feedback"})
+	if !strings.Contains(out, "-Be kind.") || !strings.Contains(out, "+Be warm.") {
+		t.Fatalf("expected diff in proposal, got %q", out)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "SOUL.md")); string(data) != "Be concise.\nBe kind.\n" {
+		t.Fatal("proposal must not change the file")
+	}
+
+	out, _ = tool.Execute(turn, map[string]any{"action": "apply", "id": "id-1"})
+	if !strings.Contains(out, "later message") {
+		t.Fatalf("expected apply in the proposing turn to be refused, got %q", out)
+	}
+
+	ctx := WithTurn(session)
+	out, _ = tool.Execute(ctx, map[string]any{"action": "apply", "id": "id-1"})
+	if !strings.HasPrefix(out, "Updated SOUL.md") {
+		t.Fatalf("unexpected apply result %q", out)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "SOUL.md")); string(data) != "Be concise.\nBe warm.\n" {
+		t.Errorf("file not updated: %q", data)
+	}
+	if len(events) != 1 || events[0].EventType != "SYSTEM" || !strings.Contains(events[0].ContentText, "user feedback") {
+		t.Errorf("expected one SYSTEM timeline event, got %+v", events)
+	}
+
+	out, _ = tool.Execute(ctx, map[string]any{"action": "apply", "id": "id-1"})
+	if !strings.HasPrefix(out, "Error:") {
+		t.Errorf("expected applied proposal to be gone, got %q", out)
+	}
+}
+
+func TestContactToolScopesToSender(t *testing.T) {
+	tl, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Close()
+	tool := NewContactTool(tl, []string{"whatsapp:admin@s.whatsapp.net"})
+
+	user := WithSender(WithSessionKey(context.Background(), "whatsapp:111@s.whatsapp.net"), "111")
+	out, _ := tool.Execute(user, map[string]any{"action": "update", "name": "Alice", "add_note": "prefers short answers", "language": "German"})
+	if !strings.Contains(out, "Saved contact 111") {
+		t.Fatalf("update: %s", out)
+	}
+	for _, params := range []map[string]any{
+		{"action": "get", "sender_id": "222"},
+		{"action": "update", "authorized": true},
+		{"action": "list"},
+	} {
+		if out, _ := tool.Execute(user, params); !strings.HasPrefix(out, "Error:") {
+			t.Errorf("non-admin %v allowed: %s", params, out)
+		}
+	}
+
+	admin := WithSessionKey(context.Background(), "whatsapp:admin@s.whatsapp.net")
+	if out, _ := tool.Execute(admin, map[string]any{"action": "update", "sender_id": "111", "authorized": true, "tags": "family, ,vip"}); strings.HasPrefix(out, "Error:") {
+		t.Fatalf("admin update: %s", out)
+	}
+	c, err := tl.GetContact("111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Name != "Alice" || c.Notes != "- prefers short answers" || c.Language != "German" || !c.Authorized || len(c.Tags) != 2 {
+		t.Errorf("unexpected contact %+v", c)
+	}
+	if vip, _ := tl.ListContacts("vip"); len(vip) != 1 {
+		t.Errorf("tag filter returned %d contacts", len(vip))
+	}
+	if fam, _ := tl.ListContacts("fam"); len(fam) != 0 {
+		t.Errorf("partial tag matched %d contacts", len(fam))
+	}
+}
+
+func TestRemindMeSchedulesInCurrentChat(t *testing.T) {
+	svc, err := timeline.NewTimelineService(filepath.Join(t.TempDir(), "timeline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+	rt := ReminderTools(svc)
+	remind, list, cancel := rt[0], rt[1], rt[2]
+	ctx := WithSessionKey(context.Background(), "whatsapp:4917012345")
+
+	for delay, want := range map[string]time.Duration{
+		"2h30m":              150 * time.Minute,
+		"90 minutes":         90 * time.Minute,
+		"in 1 day and 3 hrs": 27 * time.Hour,
+	} {
+		if d, err := parseDelay(delay); err != nil || d != want {
+			t.Errorf("parseDelay(%q) = %v, %v; want %v", delay, d, err, want)
+		}
+	}
+	if _, err := parseDelay("2 months"); err == nil {
+		t.Error("expected months to be rejected")
+	}
+
+	out, _ := remind.Execute(ctx, map[string]any{"text": "take the bread out", "in": "2 hours"})
+	if !strings.HasPrefix(out, "Reminder #1 set") {
+		t.Fatalf("unexpected result: %s", out)
+	}
+	if out, _ := list.Execute(ctx, nil); !strings.Contains(out, "take the bread out") {
+		t.Errorf("reminder not listed: %s", out)
+	}
+	due, _ := svc.DueReminders(time.Now().Add(3 * time.Hour))
+	if len(due) != 1 || due[0].Channel != "whatsapp" || due[0].ChatID != "4917012345" {
+		t.Errorf("unexpected due reminders: %+v", due)
+	}
+
+	other := WithSessionKey(context.Background(), "telegram:1")
+	if out, _ := cancel.Execute(other, map[string]any{"id": float64(1)}); !strings.HasPrefix(out, "Error") {
+		t.Errorf("another chat cancelled the reminder: %s", out)
+	}
+	if out, _ := cancel.Execute(ctx, map[string]any{"id": float64(1)}); !strings.HasPrefix(out, "Cancelled") {
+		t.Errorf("cancel failed: %s", out)
+	}
+	if due, _ := svc.DueReminders(time.Now().Add(3 * time.Hour)); len(due) != 0 {
+		t.Errorf("cancelled reminder is still due: %+v", due)
+	}
+}
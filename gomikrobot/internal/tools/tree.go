@@ -0,0 +1,282 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Limits of the tree tool.
+const (
+	treeDefaultDepth   = 3
+	treePatternDepth   = 20
+	treeDefaultEntries = 200
+	treeMaxEntries     = 2000
+)
+
+// TreeTool lists a directory recursively, optionally filtered by a glob.
+type TreeTool struct{}
+
+// NewTreeTool creates a tree tool.
+func NewTreeTool() *TreeTool { return &TreeTool{} }
+
+func (t *TreeTool) Name() string { return "tree" }
+
+func (t *TreeTool) Description() string {
+	return "Show a directory tree, or find files by glob pattern such as \"**/*.go\" or \"cmd/**/main.go\", in one call. " +
+		"Entries ignored by .gitignore, hidden files, and .git are skipped unless all is true."
+}
+
+func (t *TreeTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Root directory (default: current directory)",
+			},
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Glob relative to path; ** matches any number of directories. A pattern without / matches file names at any depth.",
+			},
+			"max_depth": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("How many levels to descend (default %d, or %d with a pattern)", treeDefaultDepth, treePatternDepth),
+			},
+			"max_entries": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Stop after this many entries (default %d, max %d)", treeDefaultEntries, treeMaxEntries),
+			},
+			"all": map[string]any{
+				"type":        "boolean",
+				"description": "Include hidden and git-ignored entries",
+			},
+		},
+	}
+}
+
+func (t *TreeTool) Execute(ctx context.Context, params map[string]any) (string, error) {
+	root := GetString(params, "path", ".")
+	if strings.HasPrefix(root, "~") {
+		home, _ := os.UserHomeDir()
+		root = filepath.Join(home, root[1:])
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Sprintf("Error: directory not found: %s", root), nil
+	}
+
+	pattern := strings.Trim(filepath.ToSlash(GetString(params, "pattern", "")), "/")
+	if pattern != "" {
+		if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+			return fmt.Sprintf("Error: invalid pattern %q: %v", pattern, err), nil
+		}
+	}
+	depth := GetInt(params, "max_depth", 0)
+	if depth <= 0 {
+		depth = treeDefaultDepth
+		if pattern != "" {
+			depth = treePatternDepth
+		}
+	}
+	limit := GetInt(params, "max_entries", treeDefaultEntries)
+	if limit <= 0 || limit > treeMaxEntries {
+		limit = treeMaxEntries
+	}
+
+	w := &treeWalker{ctx: ctx, root: root, pattern: pattern, maxDepth: depth, limit: limit, all: GetBool(params, "all", false)}
+	w.walk("", 0, nil)
+
+	var sb strings.Builder
+	switch {
+	case pattern != "":
+		fmt.Fprintf(&sb, "Files under %s matching %s:\n", root, pattern)
+		if w.count == 0 {
+			sb.WriteString("  (none)\n")
+		}
+	default:
+		fmt.Fprintf(&sb, "%s/\n", strings.TrimSuffix(root, "/"))
+	}
+	sb.WriteString(w.out.String())
+	if w.truncated {
+		fmt.Fprintf(&sb, "[Stopped after %d entries; narrow the path or pattern, or raise max_entries]\n", limit)
+	}
+	return sb.String(), nil
+}
+
+// treeWalker accumulates the listing of one tree call.
+type treeWalker struct {
+	ctx       context.Context
+	root      string
+	pattern   string
+	maxDepth  int
+	limit     int
+	all       bool
+	out       strings.Builder
+	count     int
+	truncated bool
+}
+
+// walk lists the directory rel (slash-separated, relative to root) at the
+// given depth. rules are the .gitignore rules of its ancestors.
+func (w *treeWalker) walk(rel string, depth int, rules []ignoreRule) {
+	if w.truncated || w.ctx.Err() != nil {
+		return
+	}
+	dir := filepath.Join(w.root, filepath.FromSlash(rel))
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	if !w.all {
+		rules = append(slices.Clip(rules), loadGitignore(dir, rel)...)
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		child := path.Join(rel, name)
+		isDir := e.IsDir()
+		if name == ".git" || !w.all && (strings.HasPrefix(name, ".") || gitignored(rules, child, isDir)) {
+			continue
+		}
+
+		if w.pattern == "" {
+			if !w.emit(fmt.Sprintf("%s%s", strings.Repeat("  ", depth+1), entryLabel(e))) {
+				return
+			}
+		} else if !isDir && matchGlob(w.pattern, child) {
+			if !w.emit("  " + child) {
+				return
+			}
+		}
+		if isDir && depth+1 < w.maxDepth {
+			w.walk(child, depth+1, rules)
+		}
+	}
+}
+
+// emit adds a line unless the entry limit is reached.
+func (w *treeWalker) emit(line string) bool {
+	if w.count >= w.limit {
+		w.truncated = true
+		return false
+	}
+	w.count++
+	w.out.WriteString(line + "\n")
+	return true
+}
+
+func entryLabel(e os.DirEntry) string {
+	if e.IsDir() {
+		return e.Name() + "/"
+	}
+	if info, err := e.Info(); err == nil {
+		return fmt.Sprintf("%s (%d bytes)", e.Name(), info.Size())
+	}
+	return e.Name()
+}
+
+// matchGlob reports whether the slash-separated path name matches pattern.
+// ** matches zero or more directories; a pattern without a slash matches
+// the base name at any depth.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pat, parts []string) bool {
+	for len(pat) > 0 {
+		if pat[0] == "**" {
+			pat = pat[1:]
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pat, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pat[0], parts[0]); !ok {
+			return false
+		}
+		pat, parts = pat[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
+
+// ignoreRule is one line of a .gitignore file.
+type ignoreRule struct {
+	base     string // Directory of the .gitignore, relative to the walk root
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool // Pattern contains a slash and matches relative to base
+}
+
+// loadGitignore reads the .gitignore in dir, whose path relative to the
+// walk root is rel. Missing files yield no rules.
+func loadGitignore(dir, rel string) []ignoreRule {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), " \t")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := ignoreRule{base: rel}
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimSuffix(line, "/")
+		}
+		r.anchored = strings.Contains(line, "/")
+		r.pattern = strings.TrimPrefix(line, "/")
+		if r.pattern != "" {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// gitignored applies rules to the path name; the last matching rule wins.
+func gitignored(rules []ignoreRule, name string, isDir bool) bool {
+	ignored := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel := name
+		if r.base != "" {
+			if !strings.HasPrefix(name, r.base+"/") {
+				continue
+			}
+			rel = strings.TrimPrefix(name, r.base+"/")
+		}
+		var ok bool
+		if r.anchored {
+			ok = matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+		} else {
+			ok, _ = path.Match(r.pattern, path.Base(rel))
+		}
+		if ok {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,55 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTreeToolGlobAndGitignore(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":            "build/\n*.log\n!keep.log\n",
+		"main.go":               "package main",
+		"cmd/app/main.go":       "package main",
+		"cmd/app/app.log":       "noise",
+		"cmd/app/keep.log":      "kept",
+		"build/out.go":          "generated",
+		"internal/x/x.go":       "package x",
+		"internal/x/.secret.go": "hidden",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		os.WriteFile(p, []byte(content), 0644)
+	}
+	tool := NewTreeTool()
+
+	out, _ := tool.Execute(context.Background(), map[string]any{"path": root, "pattern": "**/*.go"})
+	for _, want := range []string{"  main.go\n", "  cmd/app/main.go\n", "  internal/x/x.go\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+	for _, not := range []string{"build/out.go", ".secret.go"} {
+		if strings.Contains(out, not) {
+			t.Errorf("unexpected %q in:\n%s", not, out)
+		}
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]any{"path": root, "pattern": "*.log"})
+	if !strings.Contains(out, "cmd/app/keep.log") || strings.Contains(out, "app.log") {
+		t.Errorf("negated gitignore rule not applied:\n%s", out)
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]any{"path": root, "max_depth": float64(1)})
+	if !strings.Contains(out, "  cmd/\n") || strings.Contains(out, "app/") {
+		t.Errorf("max_depth 1 should list only the top level:\n%s", out)
+	}
+
+	out, _ = tool.Execute(context.Background(), map[string]any{"path": root, "pattern": "**/*", "all": true, "max_entries": float64(2)})
+	if !strings.Contains(out, "[Stopped after 2 entries") {
+		t.Errorf("expected truncation note:\n%s", out)
+	}
+}
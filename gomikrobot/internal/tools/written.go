@@ -0,0 +1,50 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+)
+
+// WrittenFiles collects the files tools write while handling one request,
+// so the caller can report them without guessing from modification times.
+// It is safe for concurrent use.
+type WrittenFiles struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+type writtenFilesKey struct{}
+
+// WithWrittenFiles makes tools running under ctx record the files they
+// write in w.
+func WithWrittenFiles(ctx context.Context, w *WrittenFiles) context.Context {
+	return context.WithValue(ctx, writtenFilesKey{}, w)
+}
+
+// recordWritten adds path to the collector attached to ctx, if any.
+func recordWritten(ctx context.Context, path string) {
+	w, _ := ctx.Value(writtenFilesKey{}).(*WrittenFiles)
+	if w == nil {
+		return
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, p := range w.paths {
+		if p == path {
+			return
+		}
+	}
+	w.paths = append(w.paths, path)
+}
+
+// Paths returns the absolute paths written so far, in the order they were
+// first written.
+func (w *WrittenFiles) Paths() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.paths...)
+}
@@ -0,0 +1,273 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/metrics"
+)
+
+const (
+	// queueSize bounds spans waiting for export; more are dropped.
+	queueSize = 2048
+	// batchSize is the most spans sent in one request.
+	batchSize = 256
+	// flushInterval is how long a partial batch waits.
+	flushInterval = 5 * time.Second
+)
+
+var spansDropped = metrics.Default.Counter("gomikrobot_trace_spans_dropped_total", "Spans dropped because the export queue was full or the collector failed.")
+
+// Config configures the exporter.
+type Config struct {
+	// Endpoint is the OTLP/HTTP base URL, e.g. http://localhost:4318.
+	// "/v1/traces" is appended unless already present.
+	Endpoint    string
+	ServiceName string
+	Headers     map[string]string
+	// SampleRatio is the share of new traces recorded, 0 < ratio <= 1.
+	// Child spans follow their parent's decision.
+	SampleRatio float64
+}
+
+// Tracer batches ended spans and sends them to the collector.
+type Tracer struct {
+	url     string
+	service string
+	headers map[string]string
+	ratio   float64
+	client  *http.Client
+
+	queue chan *Span
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// Setup starts exporting spans to cfg.Endpoint. It returns a function that
+// flushes pending spans and turns tracing off. With no endpoint, tracing
+// stays off and the returned function does nothing.
+func Setup(cfg Config) (shutdown func(context.Context) error, err error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if !strings.HasPrefix(cfg.Endpoint, "http://") && !strings.HasPrefix(cfg.Endpoint, "https://") {
+		return nil, fmt.Errorf("tracing endpoint %q is not an http(s) URL", cfg.Endpoint)
+	}
+	url := strings.TrimRight(cfg.Endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	t := &Tracer{
+		url:     url,
+		service: cfg.ServiceName,
+		headers: cfg.Headers,
+		ratio:   cfg.SampleRatio,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		queue:   make(chan *Span, queueSize),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if t.service == "" {
+		t.service = "gomikrobot"
+	}
+	if t.ratio <= 0 || t.ratio > 1 {
+		t.ratio = 1
+	}
+	go t.run()
+	active.Store(t)
+
+	return func(ctx context.Context) error {
+		if !active.CompareAndSwap(t, nil) {
+			return nil
+		}
+		close(t.stop)
+		select {
+		case <-t.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, nil
+}
+
+// sample decides whether a new trace is recorded. The decision depends on
+// the trace ID only, so it is the same in every process.
+func (t *Tracer) sample(id [16]byte) bool {
+	if t.ratio >= 1 {
+		return true
+	}
+	return float64(binary.BigEndian.Uint64(id[8:])>>11)/(1<<53) < t.ratio
+}
+
+func (t *Tracer) enqueue(s *Span) {
+	select {
+	case t.queue <- s:
+	default:
+		spansDropped.Inc()
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	var batch []*Span
+	for {
+		select {
+		case s := <-t.queue:
+			if batch = append(batch, s); len(batch) >= batchSize {
+				t.export(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				t.export(batch)
+				batch = nil
+			}
+		case <-t.stop:
+			for len(t.queue) > 0 {
+				batch = append(batch, <-t.queue)
+			}
+			for len(batch) > 0 {
+				n := min(len(batch), batchSize)
+				t.export(batch[:n])
+				batch = batch[n:]
+			}
+			return
+		}
+	}
+}
+
+func (t *Tracer) export(spans []*Span) {
+	body, err := json.Marshal(t.encode(spans))
+	if err == nil {
+		err = t.post(body)
+	}
+	if err != nil {
+		spansDropped.Add(float64(len(spans)))
+		fmt.Printf("⚠️ Trace export failed (%d spans): %v\n", len(spans), err)
+	}
+}
+
+func (t *Tracer) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.New("collector returned " + resp.Status + ": " + strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// OTLP/JSON request body, see opentelemetry-proto trace/v1.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttr `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpSpan struct {
+		TraceID      string     `json:"traceId"`
+		SpanID       string     `json:"spanId"`
+		ParentSpanID string     `json:"parentSpanId,omitempty"`
+		Name         string     `json:"name"`
+		Kind         Kind       `json:"kind"`
+		Start        string     `json:"startTimeUnixNano"`
+		End          string     `json:"endTimeUnixNano"`
+		Attributes   []otlpAttr `json:"attributes,omitempty"`
+		Status       struct {
+			Code    int    `json:"code,omitempty"` // 1 ok, 2 error
+			Message string `json:"message,omitempty"`
+		} `json:"status"`
+	}
+	otlpAttr struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+func (t *Tracer) encode(spans []*Span) otlpRequest {
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, 0, len(spans))}
+	scope.Scope.Name = "github.com/kamir/gomikrobot"
+	for _, s := range spans {
+		s.mu.Lock()
+		o := otlpSpan{
+			TraceID: hex.EncodeToString(s.sc.TraceID[:]),
+			SpanID:  hex.EncodeToString(s.sc.SpanID[:]),
+			Name:    s.name,
+			Kind:    s.kind,
+			Start:   strconv.FormatInt(s.start.UnixNano(), 10),
+			End:     strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parent != [8]byte{} {
+			o.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		for _, a := range s.attrs {
+			o.Attributes = append(o.Attributes, otlpAttr{a.key, attrValue(a.value)})
+		}
+		if s.failed {
+			o.Status.Code, o.Status.Message = 2, s.errMsg
+		}
+		s.mu.Unlock()
+		scope.Spans = append(scope.Spans, o)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpAttr{{"service.name", attrValue(t.service)}}},
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}}
+}
+
+// attrValue encodes v as an OTLP AnyValue. 64-bit integers are strings in
+// the JSON mapping.
+func attrValue(v any) map[string]any {
+	switch v := v.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.FormatInt(int64(v), 10)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	case time.Duration:
+		return map[string]any{"intValue": strconv.FormatInt(v.Milliseconds(), 10)}
+	case error:
+		return map[string]any{"stringValue": v.Error()}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
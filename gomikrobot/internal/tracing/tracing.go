@@ -0,0 +1,232 @@
+// Package tracing records spans and exports them as OTLP/JSON to an
+// OpenTelemetry collector. Trace context travels in contexts and, between
+// processes and across the message bus, as W3C traceparent strings.
+//
+// This is intentionally dependency-free (no OpenTelemetry SDK).
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kind is the OTLP span kind.
+type Kind int
+
+const (
+	KindInternal Kind = 1
+	KindServer   Kind = 2
+	KindClient   Kind = 3
+	KindProducer Kind = 4
+	KindConsumer Kind = 5
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// IsValid reports whether the trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Span is one timed operation. All methods are safe on a nil span, which
+// is what Start returns while tracing is off.
+type Span struct {
+	tracer *Tracer // nil for remote parents and unsampled spans
+	sc     SpanContext
+	parent [8]byte
+	name   string
+	kind   Kind
+	start  time.Time
+
+	mu     sync.Mutex
+	end    time.Time
+	attrs  []attr
+	errMsg string
+	failed bool
+	ended  bool
+}
+
+type attr struct {
+	key   string
+	value any
+}
+
+type spanKey struct{}
+
+var active atomic.Pointer[Tracer]
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool { return active.Load() != nil }
+
+// Start begins an internal span as a child of the span in ctx. kv are
+// attribute key-value pairs as in log/slog. The span must be ended with End.
+func Start(ctx context.Context, name string, kv ...any) (context.Context, *Span) {
+	return StartKind(ctx, KindInternal, name, kv...)
+}
+
+// StartKind is Start with an explicit span kind.
+func StartKind(ctx context.Context, kind Kind, name string, kv ...any) (context.Context, *Span) {
+	t := active.Load()
+	if t == nil {
+		return ctx, nil
+	}
+	s := &Span{name: name, kind: kind, start: time.Now()}
+	if p := FromContext(ctx); p != nil && p.sc.IsValid() {
+		s.sc.TraceID, s.sc.Sampled, s.parent = p.sc.TraceID, p.sc.Sampled, p.sc.SpanID
+	} else {
+		rand.Read(s.sc.TraceID[:])
+		s.sc.Sampled = t.sample(s.sc.TraceID)
+	}
+	rand.Read(s.sc.SpanID[:])
+	if s.sc.Sampled {
+		s.tracer = t
+		s.SetAttr(kv...)
+	}
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// FromContext returns the current span of ctx, or nil.
+func FromContext(ctx context.Context) *Span {
+	s, _ := ctx.Value(spanKey{}).(*Span)
+	return s
+}
+
+// SpanContext returns the span's identifiers.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.sc
+}
+
+// SetAttr sets attribute key-value pairs, replacing earlier values of the
+// same keys.
+func (s *Span) SetAttr(kv ...any) {
+	if s == nil || s.tracer == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			continue
+		}
+		if j := slices.IndexFunc(s.attrs, func(a attr) bool { return a.key == key }); j >= 0 {
+			s.attrs[j].value = kv[i+1]
+		} else {
+			s.attrs = append(s.attrs, attr{key, kv[i+1]})
+		}
+	}
+}
+
+// RecordError marks the span as failed. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.Fail(err.Error())
+}
+
+// Fail marks the span as failed with the given message.
+func (s *Span) Fail(msg string) {
+	if s == nil || s.tracer == nil {
+		return
+	}
+	s.mu.Lock()
+	s.failed, s.errMsg = true, msg
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil || s.tracer == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended, s.end = true, time.Now()
+	s.mu.Unlock()
+	s.tracer.enqueue(s)
+}
+
+// Traceparent returns the W3C traceparent header value of the span in ctx,
+// or "" if there is none.
+func Traceparent(ctx context.Context) string {
+	s := FromContext(ctx)
+	if s == nil || !s.sc.IsValid() {
+		return ""
+	}
+	flags := "00"
+	if s.sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(s.sc.TraceID[:]) + "-" + hex.EncodeToString(s.sc.SpanID[:]) + "-" + flags
+}
+
+// ParseTraceparent reads a W3C traceparent header value.
+func ParseTraceparent(v string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("malformed traceparent %q", v)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, fmt.Errorf("malformed traceparent %q", v)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, fmt.Errorf("malformed traceparent %q", v)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, fmt.Errorf("malformed traceparent %q", v)
+	}
+	if !sc.IsValid() {
+		return sc, errors.New("traceparent has zero IDs")
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
+
+// ContextWithRemote returns ctx with the span described by traceparent as
+// the parent of new spans. An empty or invalid traceparent leaves ctx as is.
+func ContextWithRemote(ctx context.Context, traceparent string) context.Context {
+	if traceparent == "" || !Enabled() {
+		return ctx
+	}
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, &Span{sc: sc})
+}
+
+// Inject sets the traceparent header of an outgoing request.
+func Inject(ctx context.Context, h http.Header) {
+	if tp := Traceparent(ctx); tp != "" {
+		h.Set("traceparent", tp)
+	}
+}
+
+// Extract returns ctx with the traceparent header of an incoming request as
+// the remote parent.
+func Extract(ctx context.Context, h http.Header) context.Context {
+	return ContextWithRemote(ctx, h.Get("traceparent"))
+}
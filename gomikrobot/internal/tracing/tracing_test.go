@@ -0,0 +1,109 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDisabledIsNoop(t *testing.T) {
+	ctx, span := Start(context.Background(), "op", "k", "v")
+	span.SetAttr("a", 1)
+	span.RecordError(errors.New("boom"))
+	span.End()
+	if span != nil || Traceparent(ctx) != "" {
+		t.Errorf("expected no span while tracing is off, got %v / %q", span, Traceparent(ctx))
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	const tp = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, err := ParseTraceparent(tp)
+	if err != nil || !sc.Sampled {
+		t.Fatalf("ParseTraceparent() = %+v, %v", sc, err)
+	}
+	ctx := context.WithValue(context.Background(), spanKey{}, &Span{sc: sc})
+	if got := Traceparent(ctx); got != tp {
+		t.Errorf("Traceparent() = %q, want %q", got, tp)
+	}
+	for _, bad := range []string{"", "00-xyz-00f067aa0ba902b7-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"} {
+		if _, err := ParseTraceparent(bad); err == nil {
+			t.Errorf("ParseTraceparent(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestExportToCollector(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		got  otlpRequest
+		path string
+		auth string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		var req otlpRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+		got.ResourceSpans = append(got.ResourceSpans, req.ResourceSpans...)
+	}))
+	defer srv.Close()
+
+	shutdown, err := Setup(Config{Endpoint: srv.URL, ServiceName: "test", Headers: map[string]string{"Authorization": "Bearer t"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx, parent := StartKind(ContextWithRemote(context.Background(), remote), KindServer, "turn", "session", "cli:1")
+	_, child := Start(ctx, "tool exec", "tokens", 42)
+	child.Fail("Error: denied")
+	child.End()
+	parent.End()
+
+	ctx2, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx2); err != nil {
+		t.Fatal(err)
+	}
+	if Enabled() {
+		t.Error("tracing still enabled after shutdown")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if path != "/v1/traces" || auth != "Bearer t" {
+		t.Errorf("unexpected request to %s with auth %q", path, auth)
+	}
+	if len(got.ResourceSpans) != 1 || len(got.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("unexpected body: %+v", got)
+	}
+	if a := got.ResourceSpans[0].Resource.Attributes; len(a) != 1 || a[0].Value["stringValue"] != "test" {
+		t.Errorf("unexpected resource: %+v", a)
+	}
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if p.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || p.ParentSpanID != "00f067aa0ba902b7" || p.Kind != KindServer {
+		t.Errorf("parent span does not continue the remote trace: %+v", p)
+	}
+	if c.TraceID != p.TraceID || c.ParentSpanID != p.SpanID {
+		t.Errorf("child span is not linked to its parent: %+v", c)
+	}
+	if c.Status.Code != 2 || c.Status.Message != "Error: denied" {
+		t.Errorf("unexpected child status: %+v", c.Status)
+	}
+	if len(c.Attributes) != 1 || c.Attributes[0].Value["intValue"] != "42" {
+		t.Errorf("unexpected child attributes: %+v", c.Attributes)
+	}
+}
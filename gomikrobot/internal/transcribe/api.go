@@ -0,0 +1,90 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// API transcribes with an OpenAI-compatible /audio/transcriptions endpoint.
+// It serves both OpenAI and Groq.
+type API struct {
+	Provider string // openai or groq, for logs
+	BaseURL  string
+	APIKey   string
+	Model    string
+	Language string
+	// Client defaults to one with a two-minute timeout.
+	Client *http.Client
+}
+
+var defaultAPIClient = &http.Client{Timeout: 120 * time.Second}
+
+func (a *API) Name() string { return a.Provider }
+
+// Transcribe uploads the file and returns the recognized text.
+func (a *API) Transcribe(ctx context.Context, path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open audio file: %w", err)
+	}
+	defer file.Close()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", fmt.Errorf("create form file: %w", err)
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return "", fmt.Errorf("copy file to form: %w", err)
+	}
+	_ = writer.WriteField("model", a.Model)
+	if a.Language != "" {
+		_ = writer.WriteField("language", a.Language)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("close form writer: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(a.BaseURL, "/")+"/audio/transcriptions", body)
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+a.APIKey)
+
+	client := a.Client
+	if client == nil {
+		client = defaultAPIClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s transcription error (status %d): %s", a.Provider, resp.StatusCode, string(respBody))
+	}
+
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return "", fmt.Errorf("parse response: %w", err)
+	}
+	return strings.TrimSpace(out.Text), nil
+}
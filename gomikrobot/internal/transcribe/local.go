@@ -0,0 +1,73 @@
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Local runs a whisper binary on the machine. It supports the openai-whisper
+// CLI and whisper.cpp; the latter is detected by a ggml model file (*.bin)
+// or a whisper-cli/whisper-cpp binary name.
+type Local struct {
+	Binary   string
+	Model    string
+	Language string
+}
+
+func (l *Local) Name() string { return "local" }
+
+// Transcribe runs the binary and reads the text file it writes.
+func (l *Local) Transcribe(ctx context.Context, path string) (string, error) {
+	tmpDir, err := os.MkdirTemp("", "whisper-")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var args []string
+	var txtPath string
+	if l.isCpp() {
+		// whisper-cli -m model.bin -f file -otxt -of <dir>/out
+		out := filepath.Join(tmpDir, "out")
+		args = []string{"-m", l.Model, "-f", path, "-otxt", "-of", out, "-np"}
+		if l.Language != "" {
+			args = append(args, "-l", l.Language)
+		}
+		txtPath = out + ".txt"
+	} else {
+		// whisper <file> --model <model> --output_dir <dir> --output_format txt
+		args = []string{
+			path,
+			"--model", l.Model,
+			"--output_dir", tmpDir,
+			"--output_format", "txt",
+			"--verbose", "False",
+		}
+		if l.Language != "" {
+			args = append(args, "--language", l.Language)
+		}
+		base := filepath.Base(path)
+		txtPath = filepath.Join(tmpDir, strings.TrimSuffix(base, filepath.Ext(base))+".txt")
+	}
+
+	cmd := exec.CommandContext(ctx, l.Binary, args...)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("whisper command failed: %w (output: %s)", err, string(output))
+	}
+
+	data, err := os.ReadFile(txtPath)
+	if err != nil {
+		return "", fmt.Errorf("read transcription output: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func (l *Local) isCpp() bool {
+	bin := filepath.Base(l.Binary)
+	return strings.HasSuffix(l.Model, ".bin") ||
+		strings.Contains(bin, "whisper-cli") || strings.Contains(bin, "whisper-cpp")
+}
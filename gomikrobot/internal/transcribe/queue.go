@@ -0,0 +1,104 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by Submit when every worker is busy and the
+// backlog is at capacity.
+var ErrQueueFull = errors.New("transcription queue is full")
+
+// ErrQueueClosed is returned by Submit after Close.
+var ErrQueueClosed = errors.New("transcription queue is closed")
+
+type job struct {
+	path string
+	done func(string, error)
+}
+
+// Queue runs transcriptions on a fixed pool of workers so callers, such as
+// channel event handlers, can hand off audio and return immediately.
+type Queue struct {
+	t       Transcriber
+	timeout time.Duration
+	jobs    chan job
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewQueue starts workers goroutines transcribing with t. Each job is
+// limited to timeout (0 = no limit). With no workers, Submit transcribes
+// inline on the caller's goroutine.
+func NewQueue(t Transcriber, workers, size int, timeout time.Duration) *Queue {
+	q := &Queue{t: t, timeout: timeout}
+	if workers <= 0 {
+		return q
+	}
+	q.jobs = make(chan job, max(size, 0))
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Name reports the backend used by the queue.
+func (q *Queue) Name() string { return q.t.Name() }
+
+// Submit queues path for transcription. done runs on a worker goroutine
+// with the transcript or an error.
+func (q *Queue) Submit(path string, done func(string, error)) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if q.jobs == nil {
+		q.run(job{path: path, done: done})
+		return nil
+	}
+	select {
+	case q.jobs <- job{path: path, done: done}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting jobs and waits for queued ones to finish.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	if q.jobs != nil {
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for j := range q.jobs {
+		q.run(j)
+	}
+}
+
+func (q *Queue) run(j job) {
+	ctx := context.Background()
+	if q.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, q.timeout)
+		defer cancel()
+	}
+	text, err := q.t.Transcribe(ctx, j.path)
+	j.done(text, err)
+}
@@ -0,0 +1,78 @@
+// Package transcribe turns audio files into text using a local whisper
+// binary or an OpenAI-compatible audio API.
+package transcribe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kamir/gomikrobot/internal/config"
+)
+
+// Transcriber converts an audio file to text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, path string) (string, error)
+	// Name identifies the backend in logs.
+	Name() string
+}
+
+const (
+	groqAPIBase   = "https://api.groq.com/openai/v1"
+	groqModel     = "whisper-large-v3"
+	openAIAPIBase = "https://api.openai.com/v1"
+	openAIModel   = "whisper-1"
+)
+
+// Backend resolves the configured backend name, applying the default.
+func Backend(cfg *config.Config) string {
+	if b := strings.ToLower(cfg.Transcription.Backend); b != "" {
+		return b
+	}
+	if cfg.Providers.LocalWhisper.Enabled {
+		return "local"
+	}
+	return "openai"
+}
+
+// New builds the transcriber selected by cfg.
+func New(cfg *config.Config) (Transcriber, error) {
+	tr := cfg.Transcription
+	switch backend := Backend(cfg); backend {
+	case "local":
+		lw := cfg.Providers.LocalWhisper
+		return &Local{Binary: lw.BinaryPath, Model: lw.Model, Language: tr.Language}, nil
+	case "openai":
+		p := cfg.Providers.OpenAI
+		return &API{
+			Provider: backend,
+			BaseURL:  firstNonEmpty(p.APIBase, openAIAPIBase),
+			APIKey:   p.APIKey,
+			Model:    firstNonEmpty(tr.Model, openAIModel),
+			Language: tr.Language,
+		}, nil
+	case "groq":
+		p := cfg.Providers.Groq
+		if p.APIKey == "" {
+			return nil, fmt.Errorf("groq transcription needs providers.groq.apiKey")
+		}
+		return &API{
+			Provider: backend,
+			BaseURL:  firstNonEmpty(p.APIBase, groqAPIBase),
+			APIKey:   p.APIKey,
+			Model:    firstNonEmpty(tr.Model, groqModel),
+			Language: tr.Language,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown transcription backend %q", backend)
+	}
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kamir/gomikrobot/internal/config"
+)
+
+func writeAudio(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "note.ogg")
+	if err := os.WriteFile(path, []byte("OggS"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAPITranscribe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer gsk_test" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("model") != "whisper-large-v3" || r.FormValue("language") != "de" {
+			http.Error(w, "bad form", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"text":" Hallo Welt "}`))
+	}))
+	defer srv.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.Transcription.Backend = "groq"
+	cfg.Providers.Groq = config.ProviderConfig{APIKey: "gsk_test", APIBase: srv.URL}
+	tr, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.Name() != "groq" {
+		t.Errorf("expected groq backend, got %s", tr.Name())
+	}
+	text, err := tr.Transcribe(context.Background(), writeAudio(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Hallo Welt" {
+		t.Errorf("unexpected transcript %q", text)
+	}
+}
+
+func TestBackendDefault(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if got := Backend(cfg); got != "local" {
+		t.Errorf("expected local with localWhisper enabled, got %s", got)
+	}
+	cfg.Providers.LocalWhisper.Enabled = false
+	if got := Backend(cfg); got != "openai" {
+		t.Errorf("expected openai fallback, got %s", got)
+	}
+	cfg.Transcription.Backend = "bogus"
+	if _, err := New(cfg); err == nil {
+		t.Error("expected error for unknown backend")
+	}
+}
+
+type fakeTranscriber struct {
+	release chan struct{}
+}
+
+func (f *fakeTranscriber) Name() string { return "fake" }
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, path string) (string, error) {
+	select {
+	case <-f.release:
+		return "text:" + filepath.Base(path), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestQueueRunsJobsOffCallerGoroutine(t *testing.T) {
+	f := &fakeTranscriber{release: make(chan struct{})}
+	q := NewQueue(f, 1, 1, time.Second)
+
+	var mu sync.Mutex
+	var got []string
+	done := func(text string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			got = append(got, err.Error())
+			return
+		}
+		got = append(got, text)
+	}
+
+	// The worker blocks on the first job; the second fills the backlog.
+	if err := q.Submit("a.ogg", done); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for q.Submit("b.ogg", done) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("second job was never accepted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := q.Submit("c.ogg", done); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	close(f.release)
+	q.Close()
+	if err := q.Submit("d.ogg", done); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("expected ErrQueueClosed, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0] != "text:a.ogg" || got[1] != "text:b.ogg" {
+		t.Errorf("unexpected results %v", got)
+	}
+}
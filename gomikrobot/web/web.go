@@ -0,0 +1,63 @@
+// Package web holds the dashboard pages, embedded into the binary so the
+// gateway works from any working directory.
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+//go:embed *.html
+var files embed.FS
+
+// Site serves dashboard pages from the embedded files or, during
+// development, from a directory on disk.
+type Site struct {
+	dir   string
+	etags map[string]string
+}
+
+// New creates a Site. If dir is set, pages are read from it on every
+// request so edits show up without a rebuild.
+func New(dir string) *Site {
+	s := &Site{dir: dir, etags: make(map[string]string)}
+	entries, _ := fs.ReadDir(files, ".")
+	for _, e := range entries {
+		data, err := files.ReadFile(e.Name())
+		if err != nil {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		s.etags[e.Name()] = `"` + hex.EncodeToString(sum[:8]) + `"`
+	}
+	return s
+}
+
+// Page returns a handler that serves the named file.
+func (s *Site) Page(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.dir != "" {
+			// Never cache development files.
+			w.Header().Set("Cache-Control", "no-store")
+			http.ServeFile(w, r, filepath.Join(s.dir, name))
+			return
+		}
+		data, err := files.ReadFile(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		// Embedded files change only with the binary: browsers revalidate
+		// with the ETag and get 304 until an upgrade.
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", s.etags[name])
+		http.ServeContent(w, r, path.Base(name), time.Time{}, bytes.NewReader(data))
+	}
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPageEmbedded(t *testing.T) {
+	h := New("").Page("timeline.html")
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/timeline", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<html") {
+		t.Fatalf("got %d, body %.40q", rec.Code, rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" || rec.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("missing cache headers: %v", rec.Header())
+	}
+
+	req := httptest.NewRequest("GET", "/timeline", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("revalidation: got %d, want 304", rec.Code)
+	}
+}
+
+func TestPageOverrideDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "timeline.html"), []byte("dev build"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	New(dir).Page("timeline.html")(rec, httptest.NewRequest("GET", "/timeline", nil))
+	if rec.Body.String() != "dev build" || rec.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("got %q with headers %v", rec.Body.String(), rec.Header())
+	}
+}